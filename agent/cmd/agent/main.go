@@ -9,10 +9,11 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
-	"regexp"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"syscall"
+	"time"
 	dnsadapter "wirety/agent/internal/adapters/dns"
 	"wirety/agent/internal/adapters/firewall"
 	"wirety/agent/internal/adapters/wg"
@@ -22,6 +23,7 @@ import (
 	dom "wirety/agent/internal/domain/dns"
 
 	"github.com/gorilla/websocket"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 )
@@ -44,6 +46,24 @@ func main() {
 	portalURL := envOr("CAPTIVE_PORTAL_URL", "")
 	serverHost := envOr("SERVER_HOST", "")                  // optional Host header override for reverse-proxy setups
 	skipTLSVerify := envOr("SKIP_TLS_VERIFY", "") == "true" // skip TLS certificate verification
+	metricsPort := envOr("METRICS_PORT", "0")               // 0 = metrics/health HTTP server disabled
+	metricsBind := envOr("METRICS_BIND_ADDRESS", "0.0.0.0")
+	firewallBackend := envOr("FIREWALL_BACKEND", "iptables")
+	firewallFlush := envOr("FIREWALL_FLUSH", "false") == "true"
+	roamingWindow := envOr("ROAMING_STABILITY_WINDOW", "")
+	roamingFlips := envOr("ROAMING_TAKEOVER_FLIPS", "")
+	staticWindow := envOr("STATIC_STABILITY_WINDOW", "")
+	staticFlips := envOr("STATIC_TAKEOVER_FLIPS", "")
+	reconnectBase := envOr("RECONNECT_BACKOFF_BASE", "")
+	reconnectMax := envOr("RECONNECT_BACKOFF_MAX", "")
+	reconnectMultiplier := envOr("RECONNECT_BACKOFF_MULTIPLIER", "")
+	reconnectResetAfter := envOr("RECONNECT_BACKOFF_RESET_AFTER", "")
+	dnsCache := envOr("DNS_CACHE", "true") != "false"
+	dnsCacheSize := envOr("DNS_CACHE_SIZE", strconv.Itoa(dnsadapter.DefaultCacheSize))
+	privateKeyFile := envOr("PRIVATE_KEY_FILE", "") // for peers whose key pair was generated on the device
+	dnsListen := envOr("DNS_LISTEN", ":53")         // empty host = the WireGuard interface address(es)
+	ifaceNameMaxLen := envOr("INTERFACE_NAME_MAX_LEN", strconv.Itoa(wg.DefaultInterfaceNameLen))
+	ifaceNameCollision := envOr("INTERFACE_NAME_COLLISION", wg.CollisionHash)
 
 	flag.StringVar(&logLevel, "log-level", logLevel, "Log verbosity: trace|debug|info|warn|error|fatal (env: LOG_LEVEL)")
 	flag.StringVar(&logFormat, "log-format", logFormat, "Log output format: text|json (env: LOG_FORMAT)")
@@ -51,11 +71,29 @@ func main() {
 	flag.StringVar(&server, "server", server, "Server base URL (no trailing /)")
 	flag.StringVar(&token, "token", token, "Enrollment token")
 	flag.StringVar(&configPath, "config", configPath, "Path to wireguard config file")
-	flag.StringVar(&applyMethod, "apply", applyMethod, "Apply method: wg-quick|syncconf")
+	flag.StringVar(&applyMethod, "apply", applyMethod, "Apply method: wg-quick|syncconf (kernel module) or wireguard-go|boringtun (userspace, for hosts without the module)")
 	flag.StringVar(&natIfacesStr, "nat-interfaces", natIfacesStr, "Comma-separated NAT interfaces (empty = auto-detect all egress interfaces)")
 	flag.StringVar(&portalURL, "portal-url", portalURL, "Captive portal page URL (default: <server>/captive-portal)")
 	flag.StringVar(&serverHost, "server-host", serverHost, "Override HTTP Host header for all requests to the server (useful when accessing via IP behind a reverse proxy)")
 	flag.BoolVar(&skipTLSVerify, "skip-tls-verify", skipTLSVerify, "Skip TLS certificate verification (insecure — use only with self-signed certificates in trusted environments)")
+	flag.StringVar(&firewallBackend, "firewall-backend", firewallBackend, "Firewall backend: iptables|nft (nft for hosts without the iptables CLI) (env: FIREWALL_BACKEND)")
+	flag.BoolVar(&firewallFlush, "firewall-flush", firewallFlush, "Rebuild firewall chains by flushing them on every sync instead of applying only the changed rules (env: FIREWALL_FLUSH)")
+	flag.StringVar(&metricsPort, "metrics-port", metricsPort, "Port of the HTTP server exposing /metrics and /healthz (0 = disabled) (env: METRICS_PORT)")
+	flag.StringVar(&metricsBind, "metrics-bind-address", metricsBind, "IP address or host name the metrics server listens on, e.g. 127.0.0.1 (default: all interfaces) (env: METRICS_BIND_ADDRESS)")
+	flag.StringVar(&roamingWindow, "roaming-stability-window", roamingWindow, "How long a roaming (agent-managed) peer's new endpoint must hold before it is whitelisted again, e.g. 3s (env: ROAMING_STABILITY_WINDOW)")
+	flag.StringVar(&roamingFlips, "roaming-takeover-flips", roamingFlips, "Endpoint flips within a minute before a roaming peer's foreign source is reported as a takeover (env: ROAMING_TAKEOVER_FLIPS)")
+	flag.StringVar(&staticWindow, "static-stability-window", staticWindow, "How long a static peer's new endpoint must hold before it is whitelisted again, e.g. 10s (env: STATIC_STABILITY_WINDOW)")
+	flag.StringVar(&staticFlips, "static-takeover-flips", staticFlips, "Endpoint flips within a minute before a static peer's foreign source is reported as a takeover (env: STATIC_TAKEOVER_FLIPS)")
+	flag.StringVar(&reconnectBase, "reconnect-backoff-base", reconnectBase, "Initial delay before reconnecting to the server, e.g. 1s (env: RECONNECT_BACKOFF_BASE)")
+	flag.StringVar(&reconnectMax, "reconnect-backoff-max", reconnectMax, "Maximum delay between reconnect attempts, e.g. 30s (env: RECONNECT_BACKOFF_MAX)")
+	flag.StringVar(&reconnectMultiplier, "reconnect-backoff-multiplier", reconnectMultiplier, "Factor the reconnect delay grows by after each attempt, e.g. 2 (env: RECONNECT_BACKOFF_MULTIPLIER)")
+	flag.StringVar(&reconnectResetAfter, "reconnect-backoff-reset-after", reconnectResetAfter, "How long a connection must stay up before the reconnect delay resets, e.g. 1m (env: RECONNECT_BACKOFF_RESET_AFTER)")
+	flag.BoolVar(&dnsCache, "dns-cache", dnsCache, "Cache upstream DNS answers on the jump DNS server; disable for debugging (env: DNS_CACHE)")
+	flag.StringVar(&dnsCacheSize, "dns-cache-size", dnsCacheSize, "Maximum number of cached DNS answers (env: DNS_CACHE_SIZE)")
+	flag.StringVar(&privateKeyFile, "private-key-file", privateKeyFile, "WireGuard private key file, used when the peer was created with an imported public key (env: PRIVATE_KEY_FILE)")
+	flag.StringVar(&dnsListen, "dns-listen", dnsListen, "Address of the jump DNS server as [ip]:port; without an IP it binds the WireGuard interface address(es) only (env: DNS_LISTEN)")
+	flag.StringVar(&ifaceNameMaxLen, "interface-name-max-len", ifaceNameMaxLen, "Longest WireGuard interface name derived from the peer name; 15 on Linux (env: INTERFACE_NAME_MAX_LEN)")
+	flag.StringVar(&ifaceNameCollision, "interface-name-collision", ifaceNameCollision, "When a truncated interface name is used by another peer's managed interface: hash (add a short hash suffix) or none (env: INTERFACE_NAME_COLLISION)")
 	flag.Parse()
 
 	// Apply log settings now that flags are resolved.
@@ -106,28 +144,56 @@ func main() {
 	}
 	log.Info().Str("ipv4", wgIP).Str("ipv6", wgIPv6).Msg("parsed WireGuard interface addresses")
 	dnsServer := dnsadapter.NewServer("", []dom.DNSPeer{})
-	if wgIP != "" {
-		dnsListenAddr := net.JoinHostPort(wgIP, "53")
-		log.Info().Str("addr", dnsListenAddr).Msg("starting DNS server (IPv4)")
-		go func() {
-			if err := dnsServer.Start(dnsListenAddr); err != nil {
-				log.Error().Err(err).Msg("dns server (IPv4) exited")
-			}
-		}()
+	if !dnsCache {
+		dnsServer.SetCacheSize(0)
+	} else if n, err := strconv.Atoi(dnsCacheSize); err != nil || n < 1 {
+		log.Warn().Str("dns_cache_size", dnsCacheSize).Msg("invalid DNS cache size, using default")
+	} else {
+		dnsServer.SetCacheSize(n)
 	}
-	if wgIPv6 != "" {
-		dnsListenAddr6 := net.JoinHostPort(wgIPv6, "53")
-		log.Info().Str("addr", dnsListenAddr6).Msg("starting DNS server (IPv6)")
+	dnsAddrs, dnsPort, err := dnsListenAddrs(dnsListen, wgIP, wgIPv6)
+	if err != nil {
+		log.Fatal().Err(err).Msg("invalid DNS listen address")
+	}
+	for _, addr := range dnsAddrs {
+		// Bind before going on so a port already in use stops the agent
+		// instead of leaving the jump without a resolver.
+		pc, err := dnsadapter.Listen(addr)
+		if err != nil {
+			log.Fatal().Err(err).Msg("failed to start DNS server")
+		}
 		go func() {
-			if err := dnsServer.Start(dnsListenAddr6); err != nil {
-				log.Error().Err(err).Msg("dns server (IPv6) exited")
+			if err := dnsServer.Serve(pc); err != nil {
+				log.Error().Err(err).Str("addr", pc.LocalAddr().String()).Msg("dns server exited")
 			}
 		}()
 	}
 
 	// Use peer name as interface name - sanitize for valid interface names
-	iface := sanitizeInterfaceName(peerName)
+	configDir := "/etc/wireguard"
+	if configPath != "" {
+		configDir = filepath.Dir(configPath)
+	}
+	maxLen, err := strconv.Atoi(ifaceNameMaxLen)
+	if err != nil {
+		log.Fatal().Str("interface_name_max_len", ifaceNameMaxLen).Msg("invalid interface name length")
+	}
+	namer, err := wg.NewInterfaceNamer(maxLen, ifaceNameCollision, wg.TakenByOtherPeer(configDir, peerID))
+	if err != nil {
+		log.Fatal().Err(err).Msg("invalid interface naming")
+	}
+	iface := namer.Name(peerName)
 	writer := wg.NewWriter(configPath, iface, applyMethod)
+	writer.PeerID = peerID
+	if privateKeyFile != "" {
+		key, err := os.ReadFile(privateKeyFile) // #nosec G304 - path given by the operator
+		if err != nil {
+			log.Fatal().Err(err).Msg("failed to read private key file")
+		}
+		writer.PrivateKey = strings.TrimSpace(string(key))
+	} else if !wg.HasPrivateKey(cfg) {
+		log.Warn().Msg("the server sent no private key for this peer; pass it with --private-key-file")
+	}
 
 	// Clean up any old Wirety-managed configs that don't match current peer
 	log.Info().Msg("cleaning up old Wirety configurations")
@@ -169,7 +235,12 @@ func main() {
 	// Initialize firewall adapter with proxy ports
 	fwAdapter := firewall.NewAdapter(iface, natIfaces)
 	fwAdapter.SetProxyPorts(httpPortInt, httpsPortInt)
+	fwAdapter.SetDNSPort(dnsPort)
 	fwAdapter.SetServerURL(server) // Allow peers to reach Wirety server before authentication
+	fwAdapter.SetFlush(firewallFlush)
+	if err := fwAdapter.SetBackend(firewallBackend); err != nil {
+		log.Fatal().Err(err).Msg("invalid firewall backend")
+	}
 
 	// Load required kernel modules (nf_conntrack, xt_string) before the first
 	// iptables sync. Best-effort: failures are logged and the agent continues with
@@ -191,8 +262,39 @@ func main() {
 	runner.SetHeaders(wsHeaders)
 	runner.SetCaptivePortal(server, token, portalURL, httpClient)
 
+	// Report the host's firewall backend so the server can deliver rules in a
+	// format this agent can apply
+	fwBackend := firewall.DetectBackend()
+	if fwAdapter.Backend() == firewall.BackendNftables {
+		fwBackend = firewall.BackendNftables
+	}
+	log.Info().Str("firewall_backend", fwBackend).Msg("detected firewall backend")
+	runner.SetFirewallBackend(fwBackend)
+
+	// Roaming (agent-managed) peers change endpoints far more often than
+	// static ones, so endpoint-change detection is tuned per peer type
+	roamingSens, staticSens := app.DefaultEndpointSensitivity()
+	runner.SetEndpointSensitivity(
+		parseEndpointSensitivity("roaming", roamingWindow, roamingFlips, roamingSens),
+		parseEndpointSensitivity("static", staticWindow, staticFlips, staticSens),
+	)
+
+	runner.SetReconnectBackoff(parseReconnectBackoff(reconnectBase, reconnectMax, reconnectMultiplier, reconnectResetAfter, app.DefaultReconnectBackoff()))
+
 	// Set the initial peer name in the runner
 	runner.SetCurrentPeerName(peerName)
+	runner.SetInterfaceNamer(namer.Name)
+
+	// The initial config was applied above, before the runner existed
+	runner.RecordConfigApply(cfg, nil)
+	if p, err := strconv.Atoi(metricsPort); err != nil {
+		log.Warn().Str("metrics_port", metricsPort).Msg("invalid metrics port, metrics server disabled")
+	} else if p > 0 {
+		if !validBindAddress(metricsBind) {
+			log.Fatal().Str("metrics_bind_address", metricsBind).Msg("invalid metrics bind address, expected an IP address such as 127.0.0.1 or a host name, without a port")
+		}
+		startMetricsServer(metricsBind, p, runner.Health)
+	}
 
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
@@ -210,9 +312,91 @@ func main() {
 	log.Info().Msg("agent stopped")
 }
 
+// newMetricsHandler serves Prometheus metrics on /metrics and the agent health
+// on /healthz: 200 when healthy, 503 with the reason otherwise.
+func newMetricsHandler(health func() error) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, _ *http.Request) {
+		if err := health(); err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte("ok\n"))
+	})
+	return mux
+}
+
+// validBindAddress reports whether addr is an IP address or a host name a
+// server can listen on.  It follows the server's BIND_ADDRESS validation.
+func validBindAddress(addr string) bool {
+	if net.ParseIP(addr) != nil {
+		return true
+	}
+	if addr == "" || len(addr) > 253 {
+		return false
+	}
+	for _, label := range strings.Split(addr, ".") {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for _, c := range label {
+			if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// startMetricsServer serves newMetricsHandler on the given address and port in
+// the background.
+func startMetricsServer(host string, port int, health func() error) {
+	addr := net.JoinHostPort(host, strconv.Itoa(port))
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           newMetricsHandler(health),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	log.Info().Str("addr", addr).Msg("starting metrics server")
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Error().Err(err).Msg("metrics server exited")
+		}
+	}()
+}
+
 // configureLogger sets the global zerolog level and output format.
 // level: trace|debug|info|warn|error|fatal (default: info)
 // format: json|text (default: text — coloured console writer)
+// dnsListenAddrs returns the addresses the DNS server listens on for the
+// --dns-listen value listen, and its port.  Without an IP, listen binds each
+// address of the WireGuard interface, so the resolver is not exposed on the
+// host's other interfaces.
+func dnsListenAddrs(listen, wgIP, wgIPv6 string) ([]string, int, error) {
+	host, portStr, err := net.SplitHostPort(listen)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%q is not [ip]:port: %w", listen, err)
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil || port < 1 || port > 65535 {
+		return nil, 0, fmt.Errorf("%q: port must be 1-65535", listen)
+	}
+	if host != "" {
+		if net.ParseIP(host) == nil {
+			return nil, 0, fmt.Errorf("%q: %q is not an IP address", listen, host)
+		}
+		return []string{listen}, port, nil
+	}
+	var addrs []string
+	for _, ip := range []string{wgIP, wgIPv6} {
+		if ip != "" {
+			addrs = append(addrs, net.JoinHostPort(ip, portStr))
+		}
+	}
+	return addrs, port, nil
+}
+
 func configureLogger(level, format string) {
 	zerolog.TimeFieldFormat = zerolog.TimeFormatUnix
 
@@ -229,37 +413,62 @@ func configureLogger(level, format string) {
 	}
 }
 
-func envOr(k, def string) string {
-	v := os.Getenv(k)
-	if v == "" {
-		return def
+// parseEndpointSensitivity overrides def with the given window and flip count
+// when they are set.  Invalid values are logged and ignored.
+func parseEndpointSensitivity(peerType, window, flips string, def app.EndpointSensitivity) app.EndpointSensitivity {
+	if window != "" {
+		if d, err := time.ParseDuration(window); err != nil || d < 0 {
+			log.Warn().Str("peer_type", peerType).Str("stability_window", window).Msg("invalid stability window, using default")
+		} else {
+			def.StabilityWindow = d
+		}
 	}
-	return v
+	if flips != "" {
+		if n, err := strconv.Atoi(flips); err != nil || n < 1 {
+			log.Warn().Str("peer_type", peerType).Str("takeover_flips", flips).Msg("invalid takeover flip count, using default")
+		} else {
+			def.FlipsRequired = n
+		}
+	}
+	return def
 }
 
-// sanitizeInterfaceName converts a peer name to a valid WireGuard interface name
-// Interface names must be alphanumeric, underscore, or dash, max 15 chars
-func sanitizeInterfaceName(peerName string) string {
-	// Convert to lowercase for consistency first
-	sanitized := strings.ToLower(peerName)
-
-	// Replace invalid characters with underscores
-	re := regexp.MustCompile(`[^a-z0-9_-]`)
-	sanitized = re.ReplaceAllString(sanitized, "_")
-
-	// Truncate to max 15 characters (Linux interface name limit)
-	if len(sanitized) > 15 {
-		sanitized = sanitized[:15]
-		// Remove trailing underscores or dashes after truncation
-		sanitized = strings.TrimRight(sanitized, "_-")
+// parseReconnectBackoff overrides def with the given settings when they are
+// set.  Invalid values are logged and ignored.
+func parseReconnectBackoff(base, maxDelay, multiplier, resetAfter string, def app.ReconnectBackoff) app.ReconnectBackoff {
+	parse := func(name, value string, dst *time.Duration) {
+		if value == "" {
+			return
+		}
+		if d, err := time.ParseDuration(value); err != nil || d <= 0 {
+			log.Warn().Str(name, value).Msg("invalid reconnect backoff duration, using default")
+		} else {
+			*dst = d
+		}
 	}
-
-	// If empty after sanitization, use default
-	if sanitized == "" {
-		sanitized = "wg0"
+	parse("reconnect_backoff_base", base, &def.Base)
+	parse("reconnect_backoff_max", maxDelay, &def.Max)
+	parse("reconnect_backoff_reset_after", resetAfter, &def.ResetAfter)
+	if multiplier != "" {
+		if m, err := strconv.ParseFloat(multiplier, 64); err != nil || m < 1 {
+			log.Warn().Str("reconnect_backoff_multiplier", multiplier).Msg("invalid reconnect backoff multiplier, using default")
+		} else {
+			def.Multiplier = m
+		}
 	}
+	if def.Max < def.Base {
+		log.Warn().Dur("base", def.Base).Dur("max", def.Max).Msg("reconnect backoff max is below base, raising it to base")
+		def.Max = def.Base
+	}
+	return def
+}
 
-	return sanitized
+func envOr(k, def string) string {
+	v := os.Getenv(k)
+	if v == "" {
+		return def
+	}
+	return v
 }
 
 // hostOverrideTransport is an http.RoundTripper that sets the HTTP Host header
@@ -1,42 +1,88 @@
 package main
 
 import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
-func TestSanitizeInterfaceName(t *testing.T) {
-	tests := []struct {
-		input    string
-		expected string
-	}{
-		{"simple-peer", "simple-peer"},
-		{"Peer_123", "peer_123"},
-		{"peer@home", "peer_home"},
-		{"peer with spaces", "peer_with_space"},       // truncated to 15 chars
-		{"peer/with/slashes", "peer_with_slash"},      // truncated to 15 chars
-		{"verylongpeernametotest", "verylongpeernam"}, // truncated to 15 chars
-		{"", "wg0"}, // default if empty
-		{"peer-name-with.dots", "peer-name-with"}, // truncated to 15 chars (trailing _ removed)
-		{"123peer", "123peer"},                    // numbers are allowed
-		{"特殊字符", "____"},                          // unicode chars replaced with underscores (4 chars)
+func TestMetricsHandler(t *testing.T) {
+	var health error
+	handler := newMetricsHandler(func() error { return health })
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
 	}
 
-	for _, tt := range tests {
-		t.Run(tt.input, func(t *testing.T) {
-			result := sanitizeInterfaceName(tt.input)
-			if result != tt.expected {
-				t.Errorf("sanitizeInterfaceName(%q) = %q, want %q", tt.input, result, tt.expected)
-			}
+	if rec := get("/healthz"); rec.Code != http.StatusOK {
+		t.Errorf("/healthz when healthy = %d, want 200", rec.Code)
+	}
 
-			// Verify result is valid length
-			if len(result) > 15 {
-				t.Errorf("sanitizeInterfaceName(%q) = %q is too long (%d chars)", tt.input, result, len(result))
-			}
+	health = errors.New("websocket not connected")
+	if rec := get("/healthz"); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("/healthz when unhealthy = %d, want 503", rec.Code)
+	}
 
-			// Verify result is not empty (should default to wg0)
-			if result == "" {
-				t.Errorf("sanitizeInterfaceName(%q) returned empty string", tt.input)
+	rec := get("/metrics")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("/metrics = %d, want 200", rec.Code)
+	}
+	for _, name := range []string{"wirety_agent_config_apply_failures_total", "wirety_agent_websocket_reconnects_total", "wirety_agent_peers", "wirety_agent_last_config_apply_timestamp_seconds"} {
+		if !strings.Contains(rec.Body.String(), name) {
+			t.Errorf("/metrics is missing %s", name)
+		}
+	}
+}
+
+func TestValidBindAddress(t *testing.T) {
+	for addr, want := range map[string]bool{
+		"0.0.0.0":        true,
+		"127.0.0.1":      true,
+		"::1":            true,
+		"localhost":      true,
+		"metrics.lan":    true,
+		"":               false,
+		"127.0.0.1:9100": false,
+		"-metrics":       false,
+		"metrics..lan":   false,
+		"metrics_lan":    false,
+	} {
+		if got := validBindAddress(addr); got != want {
+			t.Errorf("validBindAddress(%q) = %v, want %v", addr, got, want)
+		}
+	}
+}
+
+func TestDNSListenAddrs(t *testing.T) {
+	tests := []struct {
+		listen    string
+		wantAddrs []string
+		wantPort  int
+		wantErr   bool
+	}{
+		{listen: ":53", wantAddrs: []string{"10.0.0.1:53", "[fd00::1]:53"}, wantPort: 53},
+		{listen: ":5353", wantAddrs: []string{"10.0.0.1:5353", "[fd00::1]:5353"}, wantPort: 5353},
+		{listen: "127.0.0.1:5353", wantAddrs: []string{"127.0.0.1:5353"}, wantPort: 5353},
+		{listen: "[::1]:53", wantAddrs: []string{"[::1]:53"}, wantPort: 53},
+		{listen: "5353", wantErr: true},
+		{listen: ":0", wantErr: true},
+		{listen: ":dns", wantErr: true},
+		{listen: "wg0:53", wantErr: true},
+	}
+	for _, tt := range tests {
+		addrs, port, err := dnsListenAddrs(tt.listen, "10.0.0.1", "fd00::1")
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("dnsListenAddrs(%q) = %v, want an error", tt.listen, addrs)
 			}
-		})
+			continue
+		}
+		if err != nil || port != tt.wantPort || strings.Join(addrs, ",") != strings.Join(tt.wantAddrs, ",") {
+			t.Errorf("dnsListenAddrs(%q) = %v, %d, %v; want %v, %d", tt.listen, addrs, port, err, tt.wantAddrs, tt.wantPort)
+		}
 	}
 }
@@ -0,0 +1,164 @@
+package dnsadapter
+
+import (
+	"container/list"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// DefaultCacheSize is the number of upstream answers a Server caches unless
+// SetCacheSize says otherwise.
+const DefaultCacheSize = 1000
+
+// negativeCacheTTL caps how long an NXDOMAIN answer is cached, so a name
+// created upstream is not hidden for long.
+const negativeCacheTTL = 30 * time.Second
+
+type cacheKey struct {
+	name  string
+	qtype uint16
+}
+
+type cacheEntry struct {
+	key     cacheKey
+	msg     *dns.Msg
+	stored  time.Time
+	expires time.Time
+}
+
+// responseCache is an LRU cache of upstream answers keyed by question name
+// and type.  Entries live as long as the smallest TTL of their records.
+type responseCache struct {
+	max     int
+	now     func() time.Time
+	mu      sync.Mutex
+	order   *list.List // most recently used first
+	entries map[cacheKey]*list.Element
+}
+
+func newResponseCache(max int) *responseCache {
+	return &responseCache{
+		max:     max,
+		now:     time.Now,
+		order:   list.New(),
+		entries: make(map[cacheKey]*list.Element),
+	}
+}
+
+func questionKey(q dns.Question) cacheKey {
+	return cacheKey{name: strings.ToLower(q.Name), qtype: q.Qtype}
+}
+
+// get returns a copy of the cached answer to q with its TTLs lowered by the
+// time spent in the cache, or nil when there is none or it expired.
+func (c *responseCache) get(q dns.Question) *dns.Msg {
+	key := questionKey(q)
+	now := c.now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+	entry := elem.Value.(*cacheEntry)
+	if !now.Before(entry.expires) {
+		c.order.Remove(elem)
+		delete(c.entries, key)
+		return nil
+	}
+	c.order.MoveToFront(elem)
+
+	msg := entry.msg.Copy()
+	elapsed := uint32(now.Sub(entry.stored) / time.Second)
+	forEachRR(msg, func(rr dns.RR) {
+		hdr := rr.Header()
+		if hdr.Ttl > elapsed {
+			hdr.Ttl -= elapsed
+		} else {
+			hdr.Ttl = 0
+		}
+	})
+	return msg
+}
+
+// put caches an upstream answer to q.  Only successful answers with records
+// and NXDOMAIN answers are cached; errors and truncated answers are not.
+func (c *responseCache) put(q dns.Question, msg *dns.Msg) {
+	ttl, ok := cacheTTL(msg)
+	if !ok || ttl <= 0 {
+		return
+	}
+	key := questionKey(q)
+	now := c.now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := &cacheEntry{key: key, msg: msg.Copy(), stored: now, expires: now.Add(ttl)}
+	if elem, exists := c.entries[key]; exists {
+		elem.Value = entry
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.entries[key] = c.order.PushFront(entry)
+	for c.order.Len() > c.max {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+// flush drops every cached answer, e.g. after the upstreams changed.
+func (c *responseCache) flush() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.order.Init()
+	c.entries = make(map[cacheKey]*list.Element)
+}
+
+// cacheTTL returns how long msg may be cached: the smallest TTL of its
+// records, at most negativeCacheTTL for NXDOMAIN.
+func cacheTTL(msg *dns.Msg) (time.Duration, bool) {
+	if msg.Truncated {
+		return 0, false
+	}
+	switch msg.Rcode {
+	case dns.RcodeSuccess:
+		if len(msg.Answer) == 0 {
+			return 0, false
+		}
+	case dns.RcodeNameError:
+	default:
+		return 0, false
+	}
+
+	minTTL := int64(-1)
+	forEachRR(msg, func(rr dns.RR) {
+		if ttl := int64(rr.Header().Ttl); minTTL < 0 || ttl < minTTL {
+			minTTL = ttl
+		}
+	})
+	ttl := time.Duration(minTTL) * time.Second
+	if msg.Rcode == dns.RcodeNameError && (minTTL < 0 || ttl > negativeCacheTTL) {
+		ttl = negativeCacheTTL
+	}
+	return ttl, ttl > 0
+}
+
+// forEachRR calls fn with every record of msg except the EDNS OPT
+// pseudo-record, whose TTL field holds flags.
+func forEachRR(msg *dns.Msg, fn func(dns.RR)) {
+	for _, section := range [][]dns.RR{msg.Answer, msg.Ns, msg.Extra} {
+		for _, rr := range section {
+			if rr.Header().Rrtype == dns.TypeOPT {
+				continue
+			}
+			fn(rr)
+		}
+	}
+}
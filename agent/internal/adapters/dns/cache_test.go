@@ -0,0 +1,149 @@
+package dnsadapter
+
+import (
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+func answerMsg(name string, ttl uint32, ip string) *dns.Msg {
+	m := new(dns.Msg)
+	m.SetQuestion(dns.Fqdn(name), dns.TypeA)
+	m.Response = true
+	m.Answer = append(m.Answer, &dns.A{
+		Hdr: dns.RR_Header{Name: dns.Fqdn(name), Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: ttl},
+		A:   net.ParseIP(ip),
+	})
+	return m
+}
+
+func TestResponseCache_HonoursTTL(t *testing.T) {
+	now := time.Unix(1000, 0)
+	c := newResponseCache(10)
+	c.now = func() time.Time { return now }
+
+	msg := answerMsg("example.org", 60, "203.0.113.1")
+	c.put(msg.Question[0], msg)
+
+	now = now.Add(20 * time.Second)
+	got := c.get(dns.Question{Name: "EXAMPLE.org.", Qtype: dns.TypeA, Qclass: dns.ClassINET})
+	if got == nil {
+		t.Fatal("expected a cached answer")
+	}
+	if ttl := got.Answer[0].Header().Ttl; ttl != 40 {
+		t.Errorf("expected the TTL lowered to 40, got %d", ttl)
+	}
+	if c.get(dns.Question{Name: "example.org.", Qtype: dns.TypeAAAA}) != nil {
+		t.Error("the query type is part of the key")
+	}
+
+	now = now.Add(40 * time.Second)
+	if c.get(msg.Question[0]) != nil {
+		t.Error("expected the answer to expire with its TTL")
+	}
+}
+
+func TestResponseCache_NegativeAndUncacheable(t *testing.T) {
+	now := time.Unix(1000, 0)
+	c := newResponseCache(10)
+	c.now = func() time.Time { return now }
+
+	nx := new(dns.Msg)
+	nx.SetQuestion("missing.example.org.", dns.TypeA)
+	nx.Rcode = dns.RcodeNameError
+	nx.Ns = append(nx.Ns, &dns.SOA{
+		Hdr: dns.RR_Header{Name: "example.org.", Rrtype: dns.TypeSOA, Class: dns.ClassINET, Ttl: 3600},
+		Ns:  "ns.example.org.", Mbox: "hostmaster.example.org.", Minttl: 3600,
+	})
+	c.put(nx.Question[0], nx)
+	if c.get(nx.Question[0]) == nil {
+		t.Fatal("expected NXDOMAIN to be cached")
+	}
+	now = now.Add(negativeCacheTTL)
+	if c.get(nx.Question[0]) != nil {
+		t.Error("expected NXDOMAIN to be cached only briefly")
+	}
+
+	servfail := new(dns.Msg)
+	servfail.SetQuestion("broken.example.org.", dns.TypeA)
+	servfail.Rcode = dns.RcodeServerFailure
+	c.put(servfail.Question[0], servfail)
+
+	truncated := answerMsg("big.example.org", 60, "203.0.113.2")
+	truncated.Truncated = true
+	c.put(truncated.Question[0], truncated)
+
+	if len(c.entries) != 0 {
+		t.Errorf("expected SERVFAIL and truncated answers not to be cached, got %d entries", len(c.entries))
+	}
+}
+
+func TestResponseCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	c := newResponseCache(2)
+	a := answerMsg("a.example.org", 60, "203.0.113.1")
+	b := answerMsg("b.example.org", 60, "203.0.113.2")
+	d := answerMsg("d.example.org", 60, "203.0.113.4")
+
+	c.put(a.Question[0], a)
+	c.put(b.Question[0], b)
+	c.get(a.Question[0]) // a is now more recent than b
+	c.put(d.Question[0], d)
+
+	if c.get(b.Question[0]) != nil {
+		t.Error("expected the least recently used answer to be evicted")
+	}
+	if c.get(a.Question[0]) == nil || c.get(d.Question[0]) == nil {
+		t.Error("expected the recently used answers to stay cached")
+	}
+}
+
+func TestForwardToUpstream_ServesCachedAnswers(t *testing.T) {
+	var queries atomic.Int32
+	upstream := startTestServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		queries.Add(1)
+		m := new(dns.Msg)
+		m.SetReply(r)
+		m.Answer = answerMsg("www.example.org", 300, "203.0.113.1").Answer
+		_ = w.WriteMsg(m)
+	})
+
+	query := func(addr string) *dns.Msg {
+		t.Helper()
+		m := new(dns.Msg)
+		m.SetQuestion("www.example.org.", dns.TypeA)
+		resp, _, err := new(dns.Client).Exchange(m, addr)
+		if err != nil {
+			t.Fatalf("query: %v", err)
+		}
+		if resp.Id != m.Id || len(resp.Answer) != 1 {
+			t.Fatalf("unexpected response %v", resp)
+		}
+		return resp
+	}
+
+	server := NewServer("mynet.internal", nil)
+	server.SetUpstreamServers([]string{upstream})
+	addr := startTestServer(t, server.handleDNS)
+	for i := 0; i < 3; i++ {
+		query(addr)
+	}
+	if n := queries.Load(); n != 1 {
+		t.Errorf("expected a single upstream query, got %d", n)
+	}
+
+	// Disabled: every query goes upstream
+	queries.Store(0)
+	uncached := NewServer("mynet.internal", nil)
+	uncached.SetUpstreamServers([]string{upstream})
+	uncached.SetCacheSize(0)
+	addr = startTestServer(t, uncached.handleDNS)
+	for i := 0; i < 3; i++ {
+		query(addr)
+	}
+	if n := queries.Load(); n != 3 {
+		t.Errorf("expected every query upstream with the cache disabled, got %d", n)
+	}
+}
@@ -0,0 +1,45 @@
+package dnsadapter
+
+import (
+	"sync"
+	dom "wirety/agent/internal/domain/dns"
+)
+
+// DefaultQueryLogSize is the number of queries kept between two drains when
+// query logging is on.  Older ones are overwritten.
+const DefaultQueryLogSize = 512
+
+// queryLog is a ring buffer of the latest queries.
+type queryLog struct {
+	mu      sync.Mutex
+	entries []dom.QueryLogEntry
+	next    int  // slot the next entry goes to
+	full    bool // whether entries wrapped around
+}
+
+func newQueryLog(size int) *queryLog {
+	return &queryLog{entries: make([]dom.QueryLogEntry, size)}
+}
+
+func (l *queryLog) add(e dom.QueryLogEntry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries[l.next] = e
+	l.next = (l.next + 1) % len(l.entries)
+	if l.next == 0 {
+		l.full = true
+	}
+}
+
+// drain returns the logged queries, oldest first, and empties the log.
+func (l *queryLog) drain() []dom.QueryLogEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var out []dom.QueryLogEntry
+	if l.full {
+		out = append(out, l.entries[l.next:]...)
+	}
+	out = append(out, l.entries[:l.next]...)
+	l.next, l.full = 0, false
+	return out
+}
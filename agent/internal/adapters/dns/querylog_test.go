@@ -0,0 +1,60 @@
+package dnsadapter
+
+import (
+	"fmt"
+	"net"
+	"testing"
+	dom "wirety/agent/internal/domain/dns"
+
+	"github.com/miekg/dns"
+)
+
+func TestQueryLog_KeepsLatestOldestFirst(t *testing.T) {
+	l := newQueryLog(3)
+	for i := 0; i < 5; i++ {
+		l.add(dom.QueryLogEntry{Name: fmt.Sprintf("q%d", i)})
+	}
+
+	got := l.drain()
+	if len(got) != 3 || got[0].Name != "q2" || got[1].Name != "q3" || got[2].Name != "q4" {
+		t.Fatalf("drain = %+v, want q2..q4", got)
+	}
+	if again := l.drain(); len(again) != 0 {
+		t.Errorf("second drain = %+v, want empty", again)
+	}
+
+	l.add(dom.QueryLogEntry{Name: "q5"})
+	if got := l.drain(); len(got) != 1 || got[0].Name != "q5" {
+		t.Errorf("drain after refill = %+v, want [q5]", got)
+	}
+}
+
+func TestServer_QueryLogOptIn(t *testing.T) {
+	server := NewServer("test.com", []dom.DNSPeer{{Name: "peer1", IP: "10.0.0.1"}})
+	query := func() {
+		m := new(dns.Msg)
+		m.SetQuestion("peer1.test.com.", dns.TypeA)
+		server.handleDNS(&mockResponseWriter{remoteAddr: &net.UDPAddr{IP: net.ParseIP("10.0.0.7"), Port: 5353}}, m)
+	}
+
+	query()
+	if got := server.DrainQueryLog(); got != nil {
+		t.Fatalf("queries logged while logging is off: %+v", got)
+	}
+
+	server.SetQueryLog(true)
+	query()
+	got := server.DrainQueryLog()
+	if len(got) != 1 {
+		t.Fatalf("DrainQueryLog() = %+v, want one query", got)
+	}
+	if got[0].Name != "peer1.test.com" || got[0].Type != "A" || got[0].ClientIP != "10.0.0.7" || got[0].Timestamp.IsZero() {
+		t.Errorf("logged query = %+v", got[0])
+	}
+
+	query()
+	server.SetQueryLog(false)
+	if got := server.DrainQueryLog(); got != nil {
+		t.Errorf("disabling should drop logged queries, got %+v", got)
+	}
+}
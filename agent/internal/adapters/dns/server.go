@@ -5,13 +5,14 @@ import (
 	"net"
 	"strings"
 	"sync"
+	"time"
 	dom "wirety/agent/internal/domain/dns"
 
 	"github.com/miekg/dns"
 	"github.com/rs/zerolog/log"
 )
 
-// Server implements DNSStarterPort for serving A records.
+// Server implements DNSStarterPort for serving A, AAAA, CNAME and TXT records.
 // It is constructed from domain + list of domain peers.
 
 // captiveProbeHosts mirrors the set in the captiveportal package.
@@ -61,6 +62,18 @@ type Server struct {
 	// jump peer's iptables rules anyway.
 	peerRoutes map[string][]string
 
+	// conditionalForwarders maps lowercase domains to the upstreams that
+	// answer them and their subdomains instead of upstreamServers.
+	conditionalForwarders map[string][]string
+	// searchDomains qualify single-label queries ("nas") that match no
+	// record, for clients that send them unqualified.
+	searchDomains []string
+	// cache holds upstream answers; nil when caching is disabled.
+	cache *responseCache
+	// queryLog records the latest queries for troubleshooting; nil unless
+	// the network opted in to query logging.
+	queryLog *queryLog
+
 	mu sync.RWMutex
 }
 
@@ -94,6 +107,7 @@ func NewServer(domain string, peers []dom.DNSPeer) *Server {
 		domain:              domain,
 		peers:               peers,
 		upstreamServers:     []string{"8.8.8.8:53", "1.1.1.1:53"}, // Default upstream DNS
+		cache:               newResponseCache(DefaultCacheSize),
 		routeDomainSuffixes: computeRouteDomainSuffixes(peers),
 		peerRoutes:          make(map[string][]string),
 	}
@@ -203,25 +217,183 @@ func (s *Server) SetUpstreamServers(servers []string) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	// Add port 53 if not specified
-	s.upstreamServers = make([]string, 0, len(servers))
+	s.upstreamServers = withDNSPort(servers)
+	s.flushCache()
+
+	log.Info().Strs("upstream_servers", s.upstreamServers).Msg("DNS upstream servers updated")
+}
+
+// SetConditionalForwarders replaces the split-horizon forwarders: queries
+// for a domain or one of its subdomains go to that domain's servers instead
+// of the upstream servers.  When several domains match, the longest wins.
+func (s *Server) SetConditionalForwarders(forwarders map[string][]string) {
+	cp := make(map[string][]string, len(forwarders))
+	for domain, servers := range forwarders {
+		domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+		if domain == "" || len(servers) == 0 {
+			continue
+		}
+		cp[domain] = withDNSPort(servers)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.conditionalForwarders = cp
+	s.flushCache()
+
+	if len(cp) > 0 {
+		log.Info().Int("domain_count", len(cp)).Msg("DNS conditional forwarders updated")
+	}
+}
+
+// SetSearchDomains sets the domains single-label queries are qualified
+// with, tried in order, when the name matches no record as is.
+func (s *Server) SetSearchDomains(domains []string) {
+	cp := make([]string, 0, len(domains))
+	for _, d := range domains {
+		if d = strings.ToLower(strings.Trim(d, ".")); d != "" {
+			cp = append(cp, d)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.searchDomains = cp
+}
+
+// qualify returns the name a single-label query resolves as: the first
+// search domain under which a record exists, or name itself.
+func (s *Server) qualify(name string) string {
+	if name == "" || strings.Contains(name, ".") {
+		return name
+	}
+	s.mu.RLock()
+	domains := s.searchDomains
+	s.mu.RUnlock()
+
+	for _, d := range domains {
+		candidate := name + "." + d
+		if ipv4, ipv6 := s.lookupPeerAddresses(candidate); ipv4 != "" || ipv6 != "" {
+			return candidate
+		}
+		if s.lookupCNAME(candidate) != "" || len(s.lookupTXT(candidate)) > 0 {
+			return candidate
+		}
+	}
+	return name
+}
+
+// SetCacheSize sets how many upstream answers are cached, dropping the
+// current ones.  0 disables caching.
+func (s *Server) SetCacheSize(size int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if size <= 0 {
+		s.cache = nil
+		log.Info().Msg("DNS cache disabled")
+		return
+	}
+	s.cache = newResponseCache(size)
+}
+
+// SetQueryLog turns query logging on or off.  Turning it off drops the
+// queries logged so far.
+func (s *Server) SetQueryLog(enabled bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch {
+	case enabled && s.queryLog == nil:
+		s.queryLog = newQueryLog(DefaultQueryLogSize)
+		log.Info().Msg("DNS query logging enabled")
+	case !enabled && s.queryLog != nil:
+		s.queryLog = nil
+		log.Info().Msg("DNS query logging disabled")
+	}
+}
+
+// DrainQueryLog returns the queries received since the previous call, oldest
+// first, or nil when query logging is off.
+func (s *Server) DrainQueryLog() []dom.QueryLogEntry {
+	s.mu.RLock()
+	ql := s.queryLog
+	s.mu.RUnlock()
+	if ql == nil {
+		return nil
+	}
+	return ql.drain()
+}
+
+// flushCache drops the cached answers, which may come from upstreams no
+// longer in use.  Callers must hold s.mu.
+func (s *Server) flushCache() {
+	if s.cache != nil {
+		s.cache.flush()
+	}
+}
+
+// upstreamsFor returns the servers to forward a query for name to: those of
+// the longest conditional forwarder domain matching it, or the upstream
+// servers.
+func (s *Server) upstreamsFor(name string) []string {
+	name = strings.ToLower(strings.TrimSuffix(name, "."))
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	best := ""
+	for domain := range s.conditionalForwarders {
+		if len(domain) > len(best) && (name == domain || strings.HasSuffix(name, "."+domain)) {
+			best = domain
+		}
+	}
+	if best != "" {
+		return s.conditionalForwarders[best]
+	}
+	return s.upstreamServers
+}
+
+// withDNSPort adds port 53 to the servers that do not specify a port.
+func withDNSPort(servers []string) []string {
+	out := make([]string, 0, len(servers))
 	for _, server := range servers {
-		if !strings.Contains(server, ":") {
+		if ip := net.ParseIP(server); ip != nil {
+			server = net.JoinHostPort(server, "53")
+		} else if !strings.Contains(server, ":") {
 			server = server + ":53"
 		}
-		s.upstreamServers = append(s.upstreamServers, server)
+		out = append(out, server)
 	}
-
-	log.Info().Strs("upstream_servers", s.upstreamServers).Msg("DNS upstream servers updated")
+	return out
 }
 
+// Start listens on addr (UDP) and serves DNS until the listener fails.
 func (s *Server) Start(addr string) error {
-	// Register handler for all DNS queries (not just s.domain)
-	// This allows us to handle both peer domains and route domains with different suffixes
-	dns.HandleFunc(".", s.handleDNS)
-	server := &dns.Server{Addr: addr, Net: "udp"}
-	log.Info().Str("addr", addr).Strs("upstream", s.upstreamServers).Str("domain", s.domain).Int("peer_count", len(s.peers)).Msg("starting DNS server")
-	return server.ListenAndServe()
+	pc, err := Listen(addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(pc)
+}
+
+// Listen binds the UDP address the DNS server will serve on.  Binding
+// separately from Serve lets the caller fail fast when the port is taken.
+func Listen(addr string) (net.PacketConn, error) {
+	pc, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("DNS server cannot listen on %s: %w", addr, err)
+	}
+	return pc, nil
+}
+
+// Serve answers DNS queries received on pc until it is closed.
+func (s *Server) Serve(pc net.PacketConn) error {
+	// Handle all DNS queries (not just s.domain), so both peer domains and
+	// route domains with different suffixes are answered
+	server := &dns.Server{PacketConn: pc, Handler: dns.HandlerFunc(s.handleDNS)}
+	s.mu.RLock()
+	log.Info().Str("addr", pc.LocalAddr().String()).Strs("upstream", s.upstreamServers).Str("domain", s.domain).Int("peer_count", len(s.peers)).Msg("starting DNS server")
+	s.mu.RUnlock()
+	return server.ActivateAndServe()
 }
 
 func (s *Server) handleDNS(w dns.ResponseWriter, r *dns.Msg) {
@@ -241,18 +413,68 @@ func (s *Server) handleDNS(w dns.ResponseWriter, r *dns.Msg) {
 	authFn := s.isAuthenticated
 	exclusions := s.redirectExclusions
 	routeSuffixes := s.routeDomainSuffixes
+	ql := s.queryLog
 	s.mu.RUnlock()
 
+	if ql != nil {
+		now := time.Now()
+		for _, q := range r.Question {
+			ql.add(dom.QueryLogEntry{
+				Name:      strings.TrimSuffix(q.Name, "."),
+				Type:      dns.TypeToString[q.Qtype],
+				ClientIP:  peerIP,
+				Timestamp: now,
+			})
+		}
+	}
+
 	// Is this peer unauthenticated and should internal domains be redirected?
 	redirectInternal := portalIP != "" && authFn != nil && peerIP != "" && !authFn(peerIP)
 
 	resolved := false
 	for _, q := range r.Question {
-		// Only handle A and AAAA; forward everything else to upstream.
-		if q.Qtype != dns.TypeA && q.Qtype != dns.TypeAAAA {
+		name := s.qualify(strings.TrimSuffix(q.Name, "."))
+
+		// TXT records (ACME dns-01 challenges, SPF) are answered as-is; there
+		// is nothing to redirect for unauthenticated peers.
+		if q.Qtype == dns.TypeTXT {
+			if txts := s.lookupTXT(name); len(txts) > 0 {
+				for _, txt := range txts {
+					m.Answer = append(m.Answer, &dns.TXT{
+						Hdr: dns.RR_Header{Name: q.Name, Rrtype: dns.TypeTXT, Class: dns.ClassINET, Ttl: 60},
+						Txt: splitTXT(txt),
+					})
+				}
+				resolved = true
+			}
+			continue
+		}
+
+		// Only handle A, AAAA and CNAME below; forward everything else to upstream.
+		if q.Qtype != dns.TypeA && q.Qtype != dns.TypeAAAA && q.Qtype != dns.TypeCNAME {
+			continue
+		}
+
+		// 0. Aliases.  The CNAME is always returned; for A/AAAA queries a
+		// target we serve ourselves is chased here so the client gets the
+		// address in the same answer, with the captive portal rules below
+		// applied to the target.  An external target is left to the client's
+		// resolver.
+		owner := q.Name
+		if target := s.lookupCNAME(name); target != "" {
+			m.Answer = append(m.Answer, &dns.CNAME{
+				Hdr:    dns.RR_Header{Name: q.Name, Rrtype: dns.TypeCNAME, Class: dns.ClassINET, Ttl: 60},
+				Target: dns.Fqdn(target),
+			})
+			resolved = true
+			if q.Qtype == dns.TypeCNAME {
+				continue
+			}
+			owner = dns.Fqdn(target)
+			name = target
+		} else if q.Qtype == dns.TypeCNAME {
 			continue
 		}
-		name := strings.TrimSuffix(q.Name, ".")
 
 		// 1. Internal VPN domain records (peer names, route FQDNs).
 		//
@@ -282,7 +504,7 @@ func (s *Server) handleDNS(w dns.ResponseWriter, r *dns.Msg) {
 					ttl = 1 // TTL=1s so the browser re-queries after auth
 				}
 				m.Answer = append(m.Answer, &dns.A{
-					Hdr: dns.RR_Header{Name: q.Name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: ttl},
+					Hdr: dns.RR_Header{Name: owner, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: ttl},
 					A:   net.ParseIP(resolvedIP),
 				})
 			} else if q.Qtype == dns.TypeAAAA {
@@ -292,7 +514,7 @@ func (s *Server) handleDNS(w dns.ResponseWriter, r *dns.Msg) {
 						Msg("DNS: unauthenticated peer — suppressing AAAA for internal domain (forcing IPv4 captive portal)")
 				} else if ipv6 != "" {
 					m.Answer = append(m.Answer, &dns.AAAA{
-						Hdr:  dns.RR_Header{Name: q.Name, Rrtype: dns.TypeAAAA, Class: dns.ClassINET, Ttl: 60},
+						Hdr:  dns.RR_Header{Name: owner, Rrtype: dns.TypeAAAA, Class: dns.ClassINET, Ttl: 60},
 						AAAA: net.ParseIP(ipv6),
 					})
 				}
@@ -324,7 +546,7 @@ func (s *Server) handleDNS(w dns.ResponseWriter, r *dns.Msg) {
 				if q.Qtype == dns.TypeA {
 					log.Debug().Str("domain", name).Str("ip", portalIP).Msg("DNS: intercepting captive portal probe")
 					m.Answer = append(m.Answer, &dns.A{
-						Hdr: dns.RR_Header{Name: q.Name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 10},
+						Hdr: dns.RR_Header{Name: owner, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 10},
 						A:   net.ParseIP(portalIP),
 					})
 				} else {
@@ -357,7 +579,7 @@ func (s *Server) handleDNS(w dns.ResponseWriter, r *dns.Msg) {
 						log.Debug().Str("domain", name).Str("peer", peerIP).Str("portal_ip", portalIP).
 							Msg("DNS: unauthenticated peer — intercepting route domain A query for captive portal")
 						m.Answer = append(m.Answer, &dns.A{
-							Hdr: dns.RR_Header{Name: q.Name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 5},
+							Hdr: dns.RR_Header{Name: owner, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 5},
 							A:   net.ParseIP(portalIP),
 						})
 					} else {
@@ -395,7 +617,7 @@ func (s *Server) handleDNS(w dns.ResponseWriter, r *dns.Msg) {
 					log.Debug().Str("domain", name).Str("peer", peerIP).Str("portal_ip", portalIP).
 						Msg("DNS: full-tunnel unauthenticated peer — redirecting external A to captive portal")
 					m.Answer = append(m.Answer, &dns.A{
-						Hdr: dns.RR_Header{Name: q.Name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 5},
+						Hdr: dns.RR_Header{Name: owner, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 5},
 						A:   net.ParseIP(portalIP),
 					})
 				} else {
@@ -416,11 +638,24 @@ func (s *Server) handleDNS(w dns.ResponseWriter, r *dns.Msg) {
 	s.forwardToUpstream(w, r)
 }
 
-// forwardToUpstream forwards DNS queries to upstream DNS servers
+// forwardToUpstream forwards DNS queries to upstream DNS servers, or to the
+// conditional forwarder of the queried domain
 func (s *Server) forwardToUpstream(w dns.ResponseWriter, r *dns.Msg) {
+	upstreams := s.upstreamsFor(r.Question[0].Name)
+
 	s.mu.RLock()
-	upstreams := s.upstreamServers
+	cache := s.cache
 	s.mu.RUnlock()
+	cacheable := cache != nil && len(r.Question) == 1
+	if cacheable {
+		if cached := cache.get(r.Question[0]); cached != nil {
+			cached.Id = r.Id
+			cached.Question = r.Question
+			log.Debug().Str("query", r.Question[0].Name).Int("answers", len(cached.Answer)).Msg("answered DNS query from cache")
+			_ = w.WriteMsg(cached)
+			return
+		}
+	}
 
 	// Try each upstream server until one responds
 	for _, upstream := range upstreams {
@@ -444,6 +679,9 @@ func (s *Server) forwardToUpstream(w dns.ResponseWriter, r *dns.Msg) {
 			Int("answers", len(resp.Answer)).
 			Msg("forwarded DNS query to upstream")
 
+		if cacheable {
+			cache.put(r.Question[0], resp)
+		}
 		_ = w.WriteMsg(resp)
 		return
 	}
@@ -495,14 +733,10 @@ func (s *Server) lookupPeerAddresses(name string) (ipv4, ipv6 string) {
 	bestWildcardSpecificity := -1 // number of labels in the wildcard suffix
 
 	for _, p := range s.peers {
-		var fqdn string
-		if strings.Contains(p.Name, ".") {
-			// Route DNS mapping stored with full FQDN (e.g. "*.api.mynet.internal")
-			fqdn = p.Name
-		} else {
-			// Peer name — construct FQDN (e.g. "peer1.mynet.internal")
-			fqdn = fmt.Sprintf("%s.%s", p.Name, s.domain)
+		if !p.IsAddress() {
+			continue
 		}
+		fqdn := s.recordFQDN(p.Name)
 
 		// 1. Exact match → highest priority, return immediately.
 		if name == fqdn {
@@ -532,6 +766,62 @@ func (s *Server) lookupPeerAddresses(name string) (ipv4, ipv6 string) {
 	return bestWildcardIPv4, bestWildcardIPv6
 }
 
+// recordFQDN returns the name a record answers for.  Route DNS mappings are
+// stored with their full FQDN (e.g. "*.api.mynet.internal"); peer names are
+// relative to the server's domain (e.g. "peer1" → "peer1.mynet.internal").
+// Callers must hold s.mu.
+func (s *Server) recordFQDN(name string) string {
+	if strings.Contains(name, ".") {
+		return name
+	}
+	return fmt.Sprintf("%s.%s", name, s.domain)
+}
+
+// lookupCNAME returns the alias target for the given hostname (FQDN), or an
+// empty string if there is no CNAME record for it.  Aliases match exactly;
+// wildcards only apply to address records.
+func (s *Server) lookupCNAME(name string) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, p := range s.peers {
+		if p.Type == dom.RecordTypeCNAME && p.Value != "" && s.recordFQDN(p.Name) == name {
+			return s.recordFQDN(strings.TrimSuffix(p.Value, "."))
+		}
+	}
+	return ""
+}
+
+// lookupTXT returns the text of every TXT record for the given hostname
+// (FQDN).  Several records may share a name, e.g. concurrent ACME challenges.
+func (s *Server) lookupTXT(name string) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var out []string
+	for _, p := range s.peers {
+		if p.Type == dom.RecordTypeTXT && s.recordFQDN(p.Name) == name {
+			out = append(out, p.Value)
+		}
+	}
+	return out
+}
+
+// splitTXT splits a TXT value into the 255-byte character-strings a TXT
+// RDATA is made of, so long values such as DKIM keys can be served.
+func splitTXT(value string) []string {
+	const maxLen = 255
+	if len(value) <= maxLen {
+		return []string{value}
+	}
+	var parts []string
+	for len(value) > maxLen {
+		parts = append(parts, value[:maxLen])
+		value = value[maxLen:]
+	}
+	return append(parts, value)
+}
+
 // Update updates the DNS server configuration with new domain, peers, and upstream servers
 func (s *Server) Update(domain string, peers []dom.DNSPeer) {
 	suffixes := computeRouteDomainSuffixes(peers)
@@ -2,6 +2,7 @@ package dnsadapter
 
 import (
 	"net"
+	"strings"
 	"testing"
 	dom "wirety/agent/internal/domain/dns"
 
@@ -179,6 +180,37 @@ func TestHandleDNSWithLocalRecords(t *testing.T) {
 	}
 }
 
+func TestHandleDNS_SearchDomainsQualifyShortNames(t *testing.T) {
+	server := NewServer("mynet.internal", []dom.DNSPeer{
+		{Name: "peer1", IP: "10.0.0.1"},
+		{Name: "db.corp.internal", IP: "10.1.0.5"},
+	})
+	if got := server.qualify("db"); got != "db" {
+		t.Fatalf("without search domains qualify(db) = %q, want it unchanged", got)
+	}
+	server.SetSearchDomains([]string{"mynet.internal", "Corp.Internal."})
+
+	for name, want := range map[string]string{"peer1": "10.0.0.1", "db": "10.1.0.5"} {
+		m := new(dns.Msg)
+		m.SetQuestion(dns.Fqdn(name), dns.TypeA)
+		w := &mockResponseWriter{}
+		server.handleDNS(w, m)
+		if w.msg == nil || len(w.msg.Answer) != 1 {
+			t.Fatalf("%s: expected one answer, got %v", name, w.msg)
+		}
+		a, ok := w.msg.Answer[0].(*dns.A)
+		if !ok || a.A.String() != want || a.Hdr.Name != dns.Fqdn(name) {
+			t.Errorf("%s: answer = %v, want %s for the name queried", name, w.msg.Answer[0], want)
+		}
+	}
+	if got := server.qualify("printer"); got != "printer" {
+		t.Errorf("qualify(printer) = %q, want it unchanged when no record matches", got)
+	}
+	if got := server.qualify("db.example.com"); got != "db.example.com" {
+		t.Errorf("qualify(db.example.com) = %q, want qualified names unchanged", got)
+	}
+}
+
 // TestProbeDomainInterceptionGatedOnAuth locks in the fix for the HSTS bug:
 // well-known captive-portal probe hosts (which include real, HSTS-preloaded
 // sites like www.apple.com) must be redirected to the portal ONLY while the
@@ -313,6 +345,21 @@ func TestStartServer(t *testing.T) {
 	t.Skip("Skipping actual server start test to avoid port conflicts")
 }
 
+func TestListen_PortInUse(t *testing.T) {
+	taken, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = taken.Close() }()
+
+	if pc, err := Listen(taken.LocalAddr().String()); err == nil {
+		_ = pc.Close()
+		t.Fatal("expected an error for a port already in use")
+	} else if !strings.Contains(err.Error(), taken.LocalAddr().String()) {
+		t.Errorf("error %q does not name the address", err)
+	}
+}
+
 // Mock response writer for testing
 
 type mockResponseWriter struct {
@@ -484,3 +531,228 @@ func TestConcurrentAccess(t *testing.T) {
 
 	// Should not panic or race
 }
+
+// startTestServer serves handler on a random loopback UDP port and returns
+// its address.
+func startTestServer(t *testing.T, handler dns.HandlerFunc) string {
+	t.Helper()
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	started := make(chan struct{})
+	srv := &dns.Server{PacketConn: pc, Handler: handler, NotifyStartedFunc: func() { close(started) }}
+	go func() { _ = srv.ActivateAndServe() }()
+	<-started
+	t.Cleanup(func() { _ = srv.Shutdown() })
+	return pc.LocalAddr().String()
+}
+
+func TestHandleDNS_RecordTypes(t *testing.T) {
+	// A fake upstream that answers MX and TXT queries, to check that
+	// whatever the server does not serve itself is still forwarded.
+	upstream := startTestServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		q := r.Question[0]
+		hdr := dns.RR_Header{Name: q.Name, Rrtype: q.Qtype, Class: dns.ClassINET, Ttl: 60}
+		switch q.Qtype {
+		case dns.TypeMX:
+			m.Answer = append(m.Answer, &dns.MX{Hdr: hdr, Preference: 10, Mx: "mail.example.org."})
+		case dns.TypeTXT:
+			m.Answer = append(m.Answer, &dns.TXT{Hdr: hdr, Txt: []string{"from-upstream"}})
+		}
+		_ = w.WriteMsg(m)
+	})
+
+	longTXT := strings.Repeat("k", 300)
+	server := NewServer("mynet.internal", []dom.DNSPeer{
+		{Name: "web", IP: "10.0.0.10", IPv6: "fd00::10"},
+		{Name: "www", Type: dom.RecordTypeCNAME, Value: "web"},
+		{Name: "docs.mynet.internal", Type: dom.RecordTypeCNAME, Value: "docs.example.org."},
+		{Name: "_acme-challenge.web.mynet.internal", Type: dom.RecordTypeTXT, Value: "token-1"},
+		{Name: "_acme-challenge.web.mynet.internal", Type: dom.RecordTypeTXT, Value: "token-2"},
+		{Name: "web", Type: dom.RecordTypeTXT, Value: longTXT},
+	})
+	server.SetUpstreamServers([]string{upstream})
+	addr := startTestServer(t, server.handleDNS)
+
+	query := func(name string, qtype uint16) *dns.Msg {
+		t.Helper()
+		m := new(dns.Msg)
+		m.SetQuestion(dns.Fqdn(name), qtype)
+		resp, _, err := new(dns.Client).Exchange(m, addr)
+		if err != nil {
+			t.Fatalf("query %s %s: %v", name, dns.TypeToString[qtype], err)
+		}
+		return resp
+	}
+
+	t.Run("A", func(t *testing.T) {
+		resp := query("web.mynet.internal", dns.TypeA)
+		if len(resp.Answer) != 1 {
+			t.Fatalf("expected 1 answer, got %v", resp.Answer)
+		}
+		if a, ok := resp.Answer[0].(*dns.A); !ok || !a.A.Equal(net.ParseIP("10.0.0.10")) {
+			t.Errorf("expected A 10.0.0.10, got %v", resp.Answer[0])
+		}
+	})
+
+	t.Run("CNAME", func(t *testing.T) {
+		resp := query("www.mynet.internal", dns.TypeCNAME)
+		if len(resp.Answer) != 1 {
+			t.Fatalf("expected 1 answer, got %v", resp.Answer)
+		}
+		if c, ok := resp.Answer[0].(*dns.CNAME); !ok || c.Target != "web.mynet.internal." {
+			t.Errorf("expected CNAME web.mynet.internal., got %v", resp.Answer[0])
+		}
+	})
+
+	t.Run("A via local CNAME", func(t *testing.T) {
+		resp := query("www.mynet.internal", dns.TypeA)
+		if len(resp.Answer) != 2 {
+			t.Fatalf("expected CNAME + A, got %v", resp.Answer)
+		}
+		if _, ok := resp.Answer[0].(*dns.CNAME); !ok {
+			t.Errorf("expected CNAME first, got %v", resp.Answer[0])
+		}
+		a, ok := resp.Answer[1].(*dns.A)
+		if !ok || a.Hdr.Name != "web.mynet.internal." || !a.A.Equal(net.ParseIP("10.0.0.10")) {
+			t.Errorf("expected web.mynet.internal. A 10.0.0.10, got %v", resp.Answer[1])
+		}
+	})
+
+	t.Run("AAAA via local CNAME", func(t *testing.T) {
+		resp := query("www.mynet.internal", dns.TypeAAAA)
+		if len(resp.Answer) != 2 {
+			t.Fatalf("expected CNAME + AAAA, got %v", resp.Answer)
+		}
+		if aaaa, ok := resp.Answer[1].(*dns.AAAA); !ok || !aaaa.AAAA.Equal(net.ParseIP("fd00::10")) {
+			t.Errorf("expected AAAA fd00::10, got %v", resp.Answer[1])
+		}
+	})
+
+	t.Run("A via external CNAME", func(t *testing.T) {
+		resp := query("docs.mynet.internal", dns.TypeA)
+		if len(resp.Answer) != 1 {
+			t.Fatalf("expected only the CNAME, got %v", resp.Answer)
+		}
+		if c, ok := resp.Answer[0].(*dns.CNAME); !ok || c.Target != "docs.example.org." {
+			t.Errorf("expected CNAME docs.example.org., got %v", resp.Answer[0])
+		}
+	})
+
+	t.Run("TXT", func(t *testing.T) {
+		resp := query("_acme-challenge.web.mynet.internal", dns.TypeTXT)
+		if len(resp.Answer) != 2 {
+			t.Fatalf("expected 2 TXT answers, got %v", resp.Answer)
+		}
+		got := map[string]bool{}
+		for _, rr := range resp.Answer {
+			if txt, ok := rr.(*dns.TXT); ok {
+				got[strings.Join(txt.Txt, "")] = true
+			}
+		}
+		if !got["token-1"] || !got["token-2"] {
+			t.Errorf("expected token-1 and token-2, got %v", resp.Answer)
+		}
+	})
+
+	t.Run("long TXT", func(t *testing.T) {
+		resp := query("web.mynet.internal", dns.TypeTXT)
+		if len(resp.Answer) != 1 {
+			t.Fatalf("expected 1 TXT answer, got %v", resp.Answer)
+		}
+		txt, ok := resp.Answer[0].(*dns.TXT)
+		if !ok || len(txt.Txt) != 2 || strings.Join(txt.Txt, "") != longTXT {
+			t.Errorf("expected the 300-byte value split in two strings, got %v", resp.Answer[0])
+		}
+	})
+
+	t.Run("unsupported type is forwarded", func(t *testing.T) {
+		resp := query("web.mynet.internal", dns.TypeMX)
+		if len(resp.Answer) != 1 {
+			t.Fatalf("expected the upstream's MX answer, got %v", resp.Answer)
+		}
+		if mx, ok := resp.Answer[0].(*dns.MX); !ok || mx.Mx != "mail.example.org." {
+			t.Errorf("expected MX mail.example.org., got %v", resp.Answer[0])
+		}
+	})
+
+	t.Run("TXT for unknown name is forwarded", func(t *testing.T) {
+		resp := query("nothing.mynet.internal", dns.TypeTXT)
+		if len(resp.Answer) != 1 {
+			t.Fatalf("expected the upstream's TXT answer, got %v", resp.Answer)
+		}
+		if txt, ok := resp.Answer[0].(*dns.TXT); !ok || txt.Txt[0] != "from-upstream" {
+			t.Errorf("expected TXT from-upstream, got %v", resp.Answer[0])
+		}
+	})
+}
+
+func TestUpstreamsFor_LongestSuffixMatch(t *testing.T) {
+	server := NewServer("mynet.internal", nil)
+	server.SetUpstreamServers([]string{"1.1.1.1"})
+	server.SetConditionalForwarders(map[string][]string{
+		"Corp.Example.com.":    {"10.1.0.53"},
+		"lab.corp.example.com": {"10.2.0.53:5353"},
+		"fd.example.net":       {"fd00::53"},
+	})
+
+	tests := map[string]string{
+		"corp.example.com.":          "10.1.0.53:53",
+		"git.corp.example.com.":      "10.1.0.53:53",
+		"GIT.CORP.EXAMPLE.COM.":      "10.1.0.53:53",
+		"lab.corp.example.com.":      "10.2.0.53:5353",
+		"host.lab.corp.example.com.": "10.2.0.53:5353",
+		"notcorp.example.com.":       "1.1.1.1:53",
+		"example.com.":               "1.1.1.1:53",
+		"x.fd.example.net.":          "[fd00::53]:53",
+	}
+	for name, want := range tests {
+		if got := server.upstreamsFor(name); len(got) != 1 || got[0] != want {
+			t.Errorf("upstreamsFor(%q) = %v, want [%s]", name, got, want)
+		}
+	}
+
+	server.SetConditionalForwarders(nil)
+	if got := server.upstreamsFor("git.corp.example.com."); len(got) != 1 || got[0] != "1.1.1.1:53" {
+		t.Errorf("expected the default upstreams once forwarders are cleared, got %v", got)
+	}
+}
+
+func TestHandleDNS_ConditionalForwarding(t *testing.T) {
+	answering := func(ip string) dns.HandlerFunc {
+		return func(w dns.ResponseWriter, r *dns.Msg) {
+			m := new(dns.Msg)
+			m.SetReply(r)
+			m.Answer = append(m.Answer, &dns.A{
+				Hdr: dns.RR_Header{Name: r.Question[0].Name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 60},
+				A:   net.ParseIP(ip),
+			})
+			_ = w.WriteMsg(m)
+		}
+	}
+	public := startTestServer(t, answering("203.0.113.1"))
+	corporate := startTestServer(t, answering("10.1.2.3"))
+
+	server := NewServer("mynet.internal", nil)
+	server.SetUpstreamServers([]string{public})
+	server.SetConditionalForwarders(map[string][]string{"corp.example.com": {corporate}})
+	addr := startTestServer(t, server.handleDNS)
+
+	for name, want := range map[string]string{
+		"intranet.corp.example.com": "10.1.2.3",
+		"www.example.org":           "203.0.113.1",
+	} {
+		m := new(dns.Msg)
+		m.SetQuestion(dns.Fqdn(name), dns.TypeA)
+		resp, _, err := new(dns.Client).Exchange(m, addr)
+		if err != nil {
+			t.Fatalf("query %s: %v", name, err)
+		}
+		if len(resp.Answer) != 1 || !resp.Answer[0].(*dns.A).A.Equal(net.ParseIP(want)) {
+			t.Errorf("%s: expected A %s, got %v", name, want, resp.Answer)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package firewall
+
+import (
+	"os/exec"
+	"strings"
+)
+
+// Firewall backends reported to the server in the heartbeat.  The values match
+// the server's network.FirewallBackend* constants.
+const (
+	BackendIPTables    = "iptables"     // legacy xtables
+	BackendIPTablesNft = "iptables-nft" // iptables CLI on top of nf_tables
+	BackendNftables    = "nftables"     // nft only, no iptables CLI available
+)
+
+// DetectBackend reports which firewall backend is available on this host.
+// `iptables --version` prints "(nf_tables)" or "(legacy)" on modern distros;
+// when the iptables CLI is missing but nft is present the host is nft-only.
+// Returns an empty string when neither tool can be found.
+func DetectBackend() string {
+	if out, err := exec.Command("iptables", "--version").Output(); err == nil { // #nosec G204 - static command
+		return parseIPTablesVersion(string(out))
+	}
+	if _, err := exec.LookPath("nft"); err == nil {
+		return BackendNftables
+	}
+	return ""
+}
+
+// parseIPTablesVersion maps `iptables --version` output to a backend name.
+// Versions older than 1.8 print no variant and are always legacy.
+func parseIPTablesVersion(out string) string {
+	if strings.Contains(out, "nf_tables") {
+		return BackendIPTablesNft
+	}
+	return BackendIPTables
+}
@@ -0,0 +1,19 @@
+package firewall
+
+import "testing"
+
+func TestParseIPTablesVersion(t *testing.T) {
+	tests := []struct {
+		out  string
+		want string
+	}{
+		{"iptables v1.8.9 (nf_tables)\n", BackendIPTablesNft},
+		{"iptables v1.8.7 (legacy)\n", BackendIPTables},
+		{"iptables v1.6.1\n", BackendIPTables},
+	}
+	for _, tt := range tests {
+		if got := parseIPTablesVersion(tt.out); got != tt.want {
+			t.Errorf("parseIPTablesVersion(%q) = %q, want %q", tt.out, got, tt.want)
+		}
+	}
+}
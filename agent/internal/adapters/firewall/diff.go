@@ -0,0 +1,267 @@
+package firewall
+
+import (
+	"fmt"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ruleLister is implemented by runners that can read the rules installed in a
+// chain.  Sync diffs against them; runners without it always flush.
+type ruleLister interface {
+	// list returns the rules of chain, each as the iptables arguments that
+	// follow `-A CHAIN`, in chain order.
+	list(ipv6 bool, table, chain string) ([][]string, error)
+}
+
+// stagedChain holds the rules a sync wants in one chain until commitChains
+// applies the difference with the installed ones.
+type stagedChain struct {
+	ipv6  bool
+	table string // "" for the filter table
+	chain string
+	rules [][]string // arguments following `-A CHAIN`
+}
+
+// withTable prefixes args with `-t table` unless table is the default one.
+func withTable(table string, args ...string) []string {
+	if table == "" {
+		return args
+	}
+	return append([]string{"-t", table}, args...)
+}
+
+// resetChain creates chain if needed and starts rebuilding it.  In flush mode,
+// or when the runner cannot list rules, the chain is flushed right away and the
+// rules appended next go straight to the kernel.  Otherwise they are staged and
+// commitChains applies only what changed, so the chain is never left empty.
+func (a *Adapter) resetChain(ipv6 bool, table, chain string) {
+	_ = a.rules.run(ipv6, withTable(table, "-N", chain)...)
+	if _, ok := a.rules.(ruleLister); a.flush || !ok {
+		_ = a.rules.run(ipv6, withTable(table, "-F", chain)...)
+		return
+	}
+	a.staged = append(a.staged, &stagedChain{ipv6: ipv6, table: table, chain: chain})
+}
+
+// stage records args when they append a rule to a staged chain.
+func (a *Adapter) stage(ipv6 bool, args []string) bool {
+	table, rest := "", args
+	if len(rest) >= 2 && rest[0] == "-t" {
+		table, rest = rest[1], rest[2:]
+	}
+	if len(rest) < 2 || rest[0] != "-A" {
+		return false
+	}
+	for _, c := range a.staged {
+		if c.ipv6 == ipv6 && c.table == table && c.chain == rest[1] {
+			c.rules = append(c.rules, append([]string(nil), rest[2:]...))
+			return true
+		}
+	}
+	return false
+}
+
+// commitChains applies the staged chains and clears them.
+func (a *Adapter) commitChains() {
+	staged := a.staged
+	a.staged = nil
+	for _, c := range staged {
+		if err := a.commitChain(c); err != nil {
+			log.Warn().Err(err).Str("chain", c.chain).Bool("ipv6", c.ipv6).Msg("firewall diff failed, flushing chain")
+			a.flushChain(c)
+		}
+	}
+}
+
+// commitChain deletes the installed rules of c that are not wanted and inserts
+// the missing ones at their position, keeping the rules common to both.
+func (a *Adapter) commitChain(c *stagedChain) error {
+	current, err := a.rules.(ruleLister).list(c.ipv6, c.table, c.chain)
+	if err != nil {
+		return err
+	}
+	removed, added := diffRules(ruleKeys(current, c.ipv6), ruleKeys(c.rules, c.ipv6))
+	if len(removed) == 0 && len(added) == 0 {
+		log.Debug().Str("chain", c.chain).Bool("ipv6", c.ipv6).Msg("firewall chain unchanged")
+		return nil
+	}
+
+	// Delete from the bottom so the positions of the remaining rules hold.
+	for i := len(removed) - 1; i >= 0; i-- {
+		pos := strconv.Itoa(removed[i] + 1)
+		if err := a.rules.run(c.ipv6, withTable(c.table, "-D", c.chain, pos)...); err != nil {
+			return err
+		}
+	}
+	// Rules before each insertion are in place by then, kept or inserted.
+	for _, i := range added {
+		args := withTable(c.table, "-I", c.chain, strconv.Itoa(i+1))
+		if err := a.rules.run(c.ipv6, append(args, c.rules[i]...)...); err != nil {
+			return err
+		}
+	}
+
+	log.Info().
+		Str("chain", c.chain).
+		Bool("ipv6", c.ipv6).
+		Strs("removed", joinRules(current, removed)).
+		Strs("added", joinRules(c.rules, added)).
+		Msg("firewall diff applied")
+	return nil
+}
+
+// flushChain rebuilds c by flushing it and appending every staged rule.
+func (a *Adapter) flushChain(c *stagedChain) {
+	_ = a.rules.run(c.ipv6, withTable(c.table, "-F", c.chain)...)
+	for _, rule := range c.rules {
+		args := append(withTable(c.table, "-A", c.chain), rule...)
+		if err := a.rules.run(c.ipv6, args...); err != nil {
+			log.Warn().Err(err).Strs("args", args).Msg("failed to add firewall rule")
+		}
+	}
+}
+
+func joinRules(rules [][]string, idx []int) []string {
+	out := make([]string, 0, len(idx))
+	for _, i := range idx {
+		out = append(out, strings.Join(rules[i], " "))
+	}
+	return out
+}
+
+// diffRules compares two rule lists keyed by ruleKey.  It keeps their longest
+// common subsequence and returns the indexes of the current rules to delete
+// and of the desired rules to insert, both ascending.
+func diffRules(current, desired []string) (removed, added []int) {
+	n, m := len(current), len(desired)
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if current[i] == desired[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case current[i] == desired[j]:
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			removed = append(removed, i)
+			i++
+		default:
+			added = append(added, j)
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		removed = append(removed, i)
+	}
+	for ; j < m; j++ {
+		added = append(added, j)
+	}
+	return removed, added
+}
+
+func ruleKeys(rules [][]string, ipv6 bool) []string {
+	keys := make([]string, len(rules))
+	for i, rule := range rules {
+		keys[i] = ruleKey(rule, ipv6)
+	}
+	return keys
+}
+
+// ruleKey renders a rule in a canonical form, so a rule built by the adapter
+// matches the same rule printed back by `iptables -S`: the options are sorted,
+// addresses get a prefix length, implicit protocol matches (`-m tcp`) are
+// dropped and conntrack states are sorted.
+func ruleKey(rule []string, ipv6 bool) string {
+	hostBits := "/32"
+	if ipv6 {
+		hostBits = "/128"
+	}
+	opts := make([]string, 0, len(rule))
+	for i := 0; i < len(rule); i++ {
+		flag, value := rule[i], ""
+		if i+1 < len(rule) && !strings.HasPrefix(rule[i+1], "-") {
+			value = rule[i+1]
+			i++
+		}
+		switch flag {
+		case "-m":
+			if value == "tcp" || value == "udp" {
+				continue
+			}
+		case "-s", "-d":
+			if !strings.Contains(value, "/") {
+				value += hostBits
+			}
+		case "--to-port":
+			flag = "--to-ports"
+		case "--ctstate", "--state":
+			states := strings.Split(value, ",")
+			sort.Strings(states)
+			value = strings.Join(states, ",")
+		}
+		opts = append(opts, flag+" "+value)
+	}
+	sort.Strings(opts)
+	return strings.Join(opts, " ")
+}
+
+func (iptablesRunner) list(ipv6 bool, table, chain string) ([][]string, error) {
+	name := iptablesCommand(ipv6)
+	out, err := exec.Command(name, withTable(table, "-S", chain)...).Output() // #nosec G204
+	if err != nil {
+		return nil, fmt.Errorf("%s -S %s failed: %w", name, chain, err)
+	}
+	var rules [][]string
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := splitRuleLine(line)
+		if len(fields) < 2 || fields[0] != "-A" || fields[1] != chain {
+			continue
+		}
+		rules = append(rules, fields[2:])
+	}
+	return rules, nil
+}
+
+// splitRuleLine splits a line of `iptables -S` output into arguments,
+// keeping double-quoted values such as log prefixes whole.
+func splitRuleLine(line string) []string {
+	var fields []string
+	var cur strings.Builder
+	quoted, inField := false, false
+	for _, r := range line {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			inField = true
+		case (r == ' ' || r == '\t') && !quoted:
+			if inField {
+				fields = append(fields, cur.String())
+				cur.Reset()
+				inField = false
+			}
+		default:
+			cur.WriteRune(r)
+			inField = true
+		}
+	}
+	if inField {
+		fields = append(fields, cur.String())
+	}
+	return fields
+}
@@ -0,0 +1,127 @@
+package firewall
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fakeRunner records commands and serves listed rules from `iptables -S` lines.
+type fakeRunner struct {
+	listed map[string][]string // chain -> -S output lines
+	cmds   []string
+}
+
+func (f *fakeRunner) run(ipv6 bool, args ...string) error {
+	f.cmds = append(f.cmds, strings.Join(args, " "))
+	return nil
+}
+
+func (f *fakeRunner) exists(ipv6 bool, args ...string) bool { return false }
+
+func (f *fakeRunner) list(ipv6 bool, table, chain string) ([][]string, error) {
+	var rules [][]string
+	for _, line := range f.listed[chain] {
+		rules = append(rules, splitRuleLine(line)[2:])
+	}
+	return rules, nil
+}
+
+func TestDiffRules(t *testing.T) {
+	removed, added := diffRules([]string{"a", "b", "c", "d"}, []string{"a", "x", "c", "d", "y"})
+	if !reflect.DeepEqual(removed, []int{1}) {
+		t.Errorf("removed = %v, want [1]", removed)
+	}
+	if !reflect.DeepEqual(added, []int{1, 4}) {
+		t.Errorf("added = %v, want [1 4]", added)
+	}
+
+	removed, added = diffRules([]string{"a", "b"}, []string{"a", "b"})
+	if len(removed) != 0 || len(added) != 0 {
+		t.Errorf("identical lists: removed %v added %v, want none", removed, added)
+	}
+}
+
+func TestRuleKeyMatchesIPTablesOutput(t *testing.T) {
+	tests := []struct {
+		built  []string
+		listed string
+		ipv6   bool
+	}{
+		{
+			built:  []string{"-m", "conntrack", "--ctstate", "ESTABLISHED,RELATED", "-j", "ACCEPT"},
+			listed: "-A WIRETY_JUMP -m conntrack --ctstate RELATED,ESTABLISHED -j ACCEPT",
+		},
+		{
+			built:  []string{"-i", "wg0", "-d", "1.2.3.4", "-p", "tcp", "--dport", "443", "-j", "ACCEPT"},
+			listed: "-A WIRETY_JUMP -d 1.2.3.4/32 -i wg0 -p tcp -m tcp --dport 443 -j ACCEPT",
+		},
+		{
+			built:  []string{"-p", "tcp", "--dport", "80", "-j", "REDIRECT", "--to-port", "80"},
+			listed: "-A WIRETY_REDIR -p tcp -m tcp --dport 80 -j REDIRECT --to-ports 80",
+		},
+		{
+			built:  []string{"-i", "wg0", "-s", "fd00::2", "-j", "WIRETY6_POLICY"},
+			listed: "-A WIRETY6_JUMP -s fd00::2/128 -i wg0 -j WIRETY6_POLICY",
+			ipv6:   true,
+		},
+	}
+	for _, tt := range tests {
+		listed := splitRuleLine(tt.listed)[2:]
+		if got, want := ruleKey(listed, tt.ipv6), ruleKey(tt.built, tt.ipv6); got != want {
+			t.Errorf("key of %q = %q, want %q", tt.listed, got, want)
+		}
+	}
+}
+
+func TestResetChainAppliesOnlyTheDiff(t *testing.T) {
+	f := &fakeRunner{listed: map[string][]string{
+		"WIRETY_JUMP": {
+			"-A WIRETY_JUMP -m conntrack --ctstate RELATED,ESTABLISHED -j ACCEPT",
+			"-A WIRETY_JUMP -s 10.0.0.2/32 -i wg0 -j WIRETY_POLICY",
+			"-A WIRETY_JUMP -s 10.0.0.3/32 -i wg0 -j WIRETY_POLICY",
+			"-A WIRETY_JUMP -i wg0 -j DROP",
+		},
+	}}
+	a := NewAdapter("wg0", nil)
+	a.rules = f
+
+	a.resetChain(false, "", "WIRETY_JUMP")
+	_ = a.run("-A", "WIRETY_JUMP", "-m", "conntrack", "--ctstate", "ESTABLISHED,RELATED", "-j", "ACCEPT")
+	_ = a.run("-A", "WIRETY_JUMP", "-i", "wg0", "-s", "10.0.0.2", "-j", "WIRETY_POLICY")
+	_ = a.run("-A", "WIRETY_JUMP", "-i", "wg0", "-s", "10.0.0.4", "-j", "WIRETY_POLICY")
+	_ = a.run("-A", "WIRETY_JUMP", "-i", "wg0", "-j", "DROP")
+	a.commitChains()
+
+	want := []string{
+		"-N WIRETY_JUMP",
+		"-D WIRETY_JUMP 3",
+		"-I WIRETY_JUMP 3 -i wg0 -s 10.0.0.4 -j WIRETY_POLICY",
+	}
+	if !reflect.DeepEqual(f.cmds, want) {
+		t.Errorf("commands = %q, want %q", f.cmds, want)
+	}
+	if len(a.staged) != 0 {
+		t.Errorf("staged chains left after commit: %d", len(a.staged))
+	}
+}
+
+func TestResetChainFlushMode(t *testing.T) {
+	f := &fakeRunner{}
+	a := NewAdapter("wg0", nil)
+	a.rules = f
+	a.SetFlush(true)
+
+	a.resetChain(false, "nat", "WIRETY_REDIR")
+	_ = a.run("-t", "nat", "-A", "WIRETY_REDIR", "-p", "tcp", "--dport", "80", "-j", "REDIRECT", "--to-port", "80")
+	a.commitChains()
+
+	want := []string{
+		"-t nat -N WIRETY_REDIR",
+		"-t nat -F WIRETY_REDIR",
+		"-t nat -A WIRETY_REDIR -p tcp --dport 80 -j REDIRECT --to-port 80",
+	}
+	if !reflect.DeepEqual(f.cmds, want) {
+		t.Errorf("commands = %q, want %q", f.cmds, want)
+	}
+}
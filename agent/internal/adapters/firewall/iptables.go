@@ -15,14 +15,20 @@ import (
 
 // Adapter implements dynamic filtering using iptables commands (still kernel-level
 // but managed by agent rather than embedded in wg config).
-// Simplified: rules applied each sync by flushing dedicated chain.
+// Each sync rebuilds the dedicated chains: by default only the rules that
+// changed are deleted or inserted (see commitChains); SetFlush restores the
+// flush-and-append behaviour.
 
 type Adapter struct {
 	iface         string
 	natInterfaces []string // explicit override; nil means auto-detect
 	httpPort      int
 	httpsPort     int
-	serverURL     string // Wirety server URL — peers must always be able to reach it
+	dnsPort       int        // port the jump DNS server listens on
+	serverURL     string     // Wirety server URL — peers must always be able to reach it
+	rules         ruleRunner // applies the iptables-syntax rules built below (see SetBackend)
+	flush         bool       // rebuild chains by flushing them instead of diffing
+	staged        []*stagedChain
 }
 
 // NewAdapter creates a new firewall adapter.
@@ -34,15 +40,54 @@ func NewAdapter(wgIface string, natIfaces []string) *Adapter {
 		natInterfaces: natIfaces,
 		httpPort:      3128,
 		httpsPort:     3129,
+		dnsPort:       53,
+		rules:         iptablesRunner{},
 	}
 }
 
+// SetBackend selects how rules reach the kernel: "iptables" (default) runs
+// the iptables/ip6tables CLIs, "nft" translates every rule to `nft` commands
+// in a dedicated inet table for hosts without the iptables CLI.
+func (a *Adapter) SetBackend(backend string) error {
+	switch backend {
+	case BackendIPTables:
+		a.rules = iptablesRunner{}
+	case "nft", BackendNftables:
+		a.rules = newNftRunner()
+	default:
+		return fmt.Errorf("unknown firewall backend %q (want iptables or nft)", backend)
+	}
+	return nil
+}
+
+// Backend reports the backend in use, as a heartbeat backend name:
+// BackendNftables for the nft backend, BackendIPTables otherwise.
+func (a *Adapter) Backend() string {
+	if _, ok := a.rules.(*nftRunner); ok {
+		return BackendNftables
+	}
+	return BackendIPTables
+}
+
 // SetProxyPorts sets the HTTP and HTTPS proxy ports
 func (a *Adapter) SetProxyPorts(httpPort, httpsPort int) {
 	a.httpPort = httpPort
 	a.httpsPort = httpsPort
 }
 
+// SetFlush makes Sync rebuild its chains by flushing them and appending every
+// rule again, instead of applying only the difference with the installed rules.
+// Flushing briefly leaves the chains empty; use it where diffing is unreliable.
+func (a *Adapter) SetFlush(flush bool) {
+	a.flush = flush
+}
+
+// SetDNSPort sets the port the jump DNS server listens on, opened to the
+// peers on the WireGuard interface.
+func (a *Adapter) SetDNSPort(port int) {
+	a.dnsPort = port
+}
+
 // EnsureKernelModules loads the kernel modules required for Wirety's iptables rules.
 // It is best-effort: a missing module degrades functionality (logged as a warning)
 // but never prevents the agent from starting.
@@ -285,6 +330,11 @@ func (a *Adapter) detectNATInterfacesIPv6() []string {
 	return ifaces
 }
 
+// NATInterfaces returns the interfaces peers' traffic is masqueraded on.
+func (a *Adapter) NATInterfaces() []string {
+	return a.getNATInterfaces()
+}
+
 // getNATInterfaces returns the NAT interfaces to use: the explicit override list
 // if configured, otherwise all auto-detected egress interfaces.
 func (a *Adapter) getNATInterfaces() []string {
@@ -312,22 +362,22 @@ func (a *Adapter) EnableDebugLogging() error {
 }
 
 func (a *Adapter) run(args ...string) error {
-	cmd := exec.Command("iptables", args...) // #nosec G204
-	out, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("iptables %v failed: %v output=%s", args, err, string(out))
-	}
-	return nil
+	return a.runFamily(false, args...)
 }
 
 // runIPv6 runs an ip6tables command (mirrors run for IPv6).
 func (a *Adapter) runIPv6(args ...string) error {
-	cmd := exec.Command("ip6tables", args...) // #nosec G204
-	out, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("ip6tables %v failed: %v output=%s", args, err, string(out))
+	return a.runFamily(true, args...)
+}
+
+// runFamily runs an iptables (ipv6 false) or ip6tables command.  Rules
+// appended to a chain staged by resetChain are recorded instead, and applied
+// by commitChains.
+func (a *Adapter) runFamily(ipv6 bool, args ...string) error {
+	if a.stage(ipv6, args) {
+		return nil
 	}
-	return nil
+	return a.rules.run(ipv6, args...)
 }
 
 // runIfNotExists runs an iptables command only if the exact rule doesn't already
@@ -336,8 +386,7 @@ func (a *Adapter) runIPv6(args ...string) error {
 // and target. This avoids the false-positive bug where a rule like
 // `-o ens2 -j MASQUERADE` would mask a distinct rule `-o ens6 -j MASQUERADE`.
 func (a *Adapter) runIfNotExists(args ...string) error {
-	checkArgs := toCheckArgs(args)
-	if exec.Command("iptables", checkArgs...).Run() == nil { // #nosec G204
+	if a.rules.exists(false, args...) {
 		return nil // exact rule already present
 	}
 	return a.run(args...)
@@ -345,13 +394,45 @@ func (a *Adapter) runIfNotExists(args ...string) error {
 
 // runIPv6IfNotExists is the ip6tables equivalent of runIfNotExists.
 func (a *Adapter) runIPv6IfNotExists(args ...string) error {
-	checkArgs := toCheckArgs(args)
-	if exec.Command("ip6tables", checkArgs...).Run() == nil { // #nosec G204
+	if a.rules.exists(true, args...) {
 		return nil // exact rule already present
 	}
 	return a.runIPv6(args...)
 }
 
+// ruleRunner applies rules written as iptables arguments.  The adapter builds
+// every rule in iptables syntax; the runner decides how it reaches the kernel.
+type ruleRunner interface {
+	// run applies args to the IPv4 (ipv6 false) or IPv6 ruleset.
+	run(ipv6 bool, args ...string) error
+	// exists reports whether the exact rule is already installed.
+	exists(ipv6 bool, args ...string) bool
+}
+
+// iptablesRunner is the default ruleRunner: it runs iptables / ip6tables.
+type iptablesRunner struct{}
+
+func iptablesCommand(ipv6 bool) string {
+	if ipv6 {
+		return "ip6tables"
+	}
+	return "iptables"
+}
+
+func (iptablesRunner) run(ipv6 bool, args ...string) error {
+	name := iptablesCommand(ipv6)
+	cmd := exec.Command(name, args...) // #nosec G204
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s %v failed: %v output=%s", name, args, err, string(out))
+	}
+	return nil
+}
+
+func (iptablesRunner) exists(ipv6 bool, args ...string) bool {
+	return exec.Command(iptablesCommand(ipv6), toCheckArgs(args)...).Run() == nil // #nosec G204
+}
+
 // toCheckArgs converts -A/-I arguments to their -C (check) equivalent.
 // `iptables -C` does not accept a position number, so for `-I CHAIN N …` the
 // position N is dropped, producing `-C CHAIN …`.
@@ -429,7 +510,7 @@ func (a *Adapter) applyIPTablesRule(chain, rule, family string) error {
 	foundChain := false
 	for i := startIdx; i < len(tokens); i++ {
 		if tokens[i] == "-A" || tokens[i] == "-I" {
-			args = append(args, "-A") // Always append (the chain is freshly flushed each sync)
+			args = append(args, "-A") // Always append (the chain is rebuilt each sync)
 			if i+1 < len(tokens) {
 				i++ // skip the original chain name
 				foundChain = true
@@ -459,6 +540,44 @@ func (a *Adapter) applyIPTablesRule(chain, rule, family string) error {
 	return nil
 }
 
+// applyPolicyRule applies a backend-neutral policy rule of the given family to
+// chain.  Comment rules and rules of the other family are skipped.
+func (a *Adapter) applyPolicyRule(chain string, rule dom.Rule, ipv6 bool) error {
+	if rule.Action == "" || (rule.Family == dom.FamilyIPv6) != ipv6 {
+		return nil
+	}
+	args := policyRuleArgs(chain, rule)
+	if err := a.runFamily(ipv6, args...); err != nil {
+		return fmt.Errorf("failed to apply rule: %w", err)
+	}
+	log.Debug().Strs("args", args).Bool("ipv6", ipv6).Msg("applied policy rule")
+	return nil
+}
+
+// policyRuleArgs renders a policy rule as iptables arguments appended to chain.
+func policyRuleArgs(chain string, rule dom.Rule) []string {
+	args := []string{"-A", chain}
+	if rule.Source != "" {
+		args = append(args, "-s", rule.Source)
+	}
+	if rule.Destination != "" {
+		args = append(args, "-d", rule.Destination)
+	}
+	if rule.Protocol != "" {
+		args = append(args, "-p", rule.Protocol)
+	}
+	if rule.SourcePort != "" {
+		args = append(args, "--sport", rule.SourcePort)
+	}
+	if rule.DestPort != "" {
+		args = append(args, "--dport", rule.DestPort)
+	}
+	if rule.State != "" {
+		args = append(args, "-m", "conntrack", "--ctstate", strings.ToUpper(rule.State))
+	}
+	return append(args, "-j", strings.ToUpper(rule.Action))
+}
+
 // splitByFamily partitions a slice of IP addresses into IPv4 and IPv6 slices.
 // Addresses that don't parse are silently dropped.
 func splitByFamily(ips []string) (ipv4s, ipv6s []string) {
@@ -544,10 +663,8 @@ func (a *Adapter) Sync(req ports.SyncRequest) error {
 	chain := "WIRETY_JUMP"
 	policyChain := "WIRETY_POLICY"
 
-	_ = a.run("-N", chain)
-	_ = a.run("-F", chain)
-	_ = a.run("-N", policyChain)
-	_ = a.run("-F", policyChain)
+	a.resetChain(false, "", chain)
+	a.resetChain(false, "", policyChain)
 
 	// Rule 0: allow packets belonging to already-established connections.
 	// Required because string matching (SNI / Host header) only works on the first
@@ -598,7 +715,8 @@ func (a *Adapter) Sync(req ports.SyncRequest) error {
 	// already had a stale whitelist entry from being treated as authenticated
 	// in the brief window after their auth is revoked but before the next
 	// server push.
-	for _, ip := range req.QuarantinedIPs {
+	quarantineIPv4, _ := splitByFamily(req.QuarantinedIPs)
+	for _, ip := range quarantineIPv4 {
 		if err := a.run("-A", chain, "-i", a.iface, "-s", ip, "-j", "DROP"); err != nil {
 			log.Warn().Err(err).Str("ip", ip).Msg("failed to add quarantine DROP rule")
 		}
@@ -658,7 +776,16 @@ func (a *Adapter) Sync(req ports.SyncRequest) error {
 	//
 	// When no policy rules are present we add a catch-all ACCEPT to preserve
 	// backward-compat behaviour: being on the whitelist implies full access.
-	if len(p.IPTablesRules) > 0 {
+	if len(p.Rules) > 0 {
+		log.Info().Int("rule_count", len(p.Rules)).Msg("applying policy rules (IPv4)")
+		for i, rule := range p.Rules {
+			// IPv6 rules are applied by syncIPv6 against the WIRETY6_POLICY chain.
+			if err := a.applyPolicyRule(policyChain, rule, false); err != nil {
+				log.Error().Err(err).Int("rule_index", i).Msg("failed to apply policy rule")
+			}
+		}
+		log.Debug().Msg("policy rules applied; default verdict determined by policy")
+	} else if len(p.IPTablesRules) > 0 {
 		log.Info().Int("rule_count", len(p.IPTablesRules)).Msg("applying policy-based iptables rules (IPv4)")
 		for i, rule := range p.IPTablesRules {
 			// Family="iptables" — silently skip ip6tables-prefixed rules (they
@@ -695,8 +822,7 @@ func (a *Adapter) Sync(req ports.SyncRequest) error {
 	// We rebuild the WIRETY_REDIR chain idempotently on each sync so the
 	// authenticated-peer exclusions stay current.
 	redirChain := "WIRETY_REDIR"
-	_ = a.run("-t", "nat", "-N", redirChain)
-	_ = a.run("-t", "nat", "-F", redirChain)
+	a.resetChain(false, "nat", redirChain)
 	// Exclude authenticated peers — their HTTP traffic must be forwarded, not redirected.
 	for _, ip := range whitelistIPv4 {
 		_ = a.run("-t", "nat", "-A", redirChain, "-s", ip, "-j", "RETURN")
@@ -716,14 +842,15 @@ func (a *Adapter) Sync(req ports.SyncRequest) error {
 	// are silently dropped before reaching the HTTP or DNS server.
 	//
 	//   Port 80  — captive portal HTTP server (redirect / probe-success)
-	//   Port 53  — DNS server (probe domain interception + peer name resolution)
+	//   Port 53  — DNS server (probe domain interception + peer name resolution);
+	//              dnsPort when the DNS server listens elsewhere
 	//
 	// These rules are inserted idempotently and must come before any DROP rule
 	// that the host firewall may have added to the INPUT chain.
 	_ = a.runIfNotExists("-I", "INPUT", "1", "-i", a.iface, "-p", "tcp", "--dport", "80", "-j", "ACCEPT")
 	_ = a.runIfNotExists("-I", "INPUT", "1", "-i", a.iface, "-p", "tcp", "--dport", "443", "-j", "ACCEPT")
-	_ = a.runIfNotExists("-I", "INPUT", "1", "-i", a.iface, "-p", "udp", "--dport", "53", "-j", "ACCEPT")
-	_ = a.runIfNotExists("-I", "INPUT", "1", "-i", a.iface, "-p", "tcp", "--dport", "53", "-j", "ACCEPT")
+	_ = a.runIfNotExists("-I", "INPUT", "1", "-i", a.iface, "-p", "udp", "--dport", strconv.Itoa(a.dnsPort), "-j", "ACCEPT")
+	_ = a.runIfNotExists("-I", "INPUT", "1", "-i", a.iface, "-p", "tcp", "--dport", strconv.Itoa(a.dnsPort), "-j", "ACCEPT")
 
 	// MASQUERADE on every egress interface so that forwarded traffic is NATed
 	// regardless of which interface the routing table selects for a given destination.
@@ -762,12 +889,13 @@ func (a *Adapter) Sync(req ports.SyncRequest) error {
 	// peer's stored endpoint is never overwritten.
 	a.syncWireGuardDenylist(req.EndpointDenylist, req.WireGuardListenPort)
 
+	a.commitChains()
 	return nil
 }
 
 // WIRETY_WGDENY is the chain that holds the per-source DROP rules for rogue
-// WireGuard UDP packets.  Maintained idempotently: each Sync flushes and
-// rebuilds it from the current denylist set.
+// WireGuard UDP packets.  Maintained idempotently: each Sync rebuilds it from
+// the current denylist set.
 const wgDenyChain = "WIRETY_WGDENY"
 
 // syncWireGuardDenylist (re)builds the WIRETY_WGDENY chain on the INPUT path
@@ -781,19 +909,15 @@ const wgDenyChain = "WIRETY_WGDENY"
 func (a *Adapter) syncWireGuardDenylist(entries []ports.DenylistEntry, wgListenPort int) {
 	if wgListenPort <= 0 {
 		log.Debug().Msg("WireGuard denylist: listen port unknown, skipping")
-		// Still flush the chain so stale rules from a previous sync don't linger.
-		_ = a.run("-N", wgDenyChain)
-		_ = a.run("-F", wgDenyChain)
-		_ = a.runIPv6("-N", wgDenyChain)
-		_ = a.runIPv6("-F", wgDenyChain)
+		// Still empty the chain so stale rules from a previous sync don't linger.
+		a.resetChain(false, "", wgDenyChain)
+		a.resetChain(true, "", wgDenyChain)
 		return
 	}
 
-	// Create + flush idempotently.
-	_ = a.run("-N", wgDenyChain)
-	_ = a.run("-F", wgDenyChain)
-	_ = a.runIPv6("-N", wgDenyChain)
-	_ = a.runIPv6("-F", wgDenyChain)
+	// Create + rebuild idempotently.
+	a.resetChain(false, "", wgDenyChain)
+	a.resetChain(true, "", wgDenyChain)
 
 	wgPortStr := strconv.Itoa(wgListenPort)
 
@@ -835,10 +959,8 @@ func (a *Adapter) syncIPv6(p *dom.JumpPolicy, whitelistIPv6 []string, endpoint s
 	chain6 := "WIRETY6_JUMP"
 	policy6 := "WIRETY6_POLICY"
 
-	_ = a.runIPv6("-N", chain6)
-	_ = a.runIPv6("-F", chain6)
-	_ = a.runIPv6("-N", policy6)
-	_ = a.runIPv6("-F", policy6)
+	a.resetChain(true, "", chain6)
+	a.resetChain(true, "", policy6)
 
 	// Rule 0: ESTABLISHED/RELATED → ACCEPT
 	_ = a.runIPv6("-A", chain6, "-m", "conntrack", "--ctstate", "ESTABLISHED,RELATED", "-j", "ACCEPT")
@@ -894,7 +1016,14 @@ func (a *Adapter) syncIPv6(p *dom.JumpPolicy, whitelistIPv6 []string, endpoint s
 	// "ip6tables …" prefix). We dispatch each rule through applyIPTablesRule with
 	// family="ip6tables" so only the ip6tables-prefixed ones land here — IPv4
 	// rules are silently skipped (they're applied by Sync's IPv4 path).
-	if len(p.IPTablesRules) > 0 {
+	if len(p.Rules) > 0 {
+		log.Info().Int("rule_count", len(p.Rules)).Msg("applying policy rules (IPv6)")
+		for i, rule := range p.Rules {
+			if err := a.applyPolicyRule(policy6, rule, true); err != nil {
+				log.Debug().Err(err).Int("rule_index", i).Msg("ip6tables policy rule skipped")
+			}
+		}
+	} else if len(p.IPTablesRules) > 0 {
 		log.Info().Int("rule_count", len(p.IPTablesRules)).Msg("applying policy-based iptables rules (IPv6)")
 		for i, rule := range p.IPTablesRules {
 			if err := a.applyIPTablesRule(policy6, rule, "ip6tables"); err != nil {
@@ -912,8 +1041,7 @@ func (a *Adapter) syncIPv6(p *dom.JumpPolicy, whitelistIPv6 []string, endpoint s
 	// ip6tables nat PREROUTING redirects port-80 from the WireGuard interface to
 	// the local captive portal HTTP server.  Authenticated peers are excluded.
 	redir6Chain := "WIRETY6_REDIR"
-	_ = a.runIPv6("-t", "nat", "-N", redir6Chain)
-	_ = a.runIPv6("-t", "nat", "-F", redir6Chain)
+	a.resetChain(true, "nat", redir6Chain)
 	for _, ip := range whitelistIPv6 {
 		_ = a.runIPv6("-t", "nat", "-A", redir6Chain, "-s", ip, "-j", "RETURN")
 	}
@@ -923,8 +1051,8 @@ func (a *Adapter) syncIPv6(p *dom.JumpPolicy, whitelistIPv6 []string, endpoint s
 	// Allow jump-peer services on the WireGuard interface INPUT chain (IPv6).
 	_ = a.runIPv6IfNotExists("-I", "INPUT", "1", "-i", a.iface, "-p", "tcp", "--dport", "80", "-j", "ACCEPT")
 	_ = a.runIPv6IfNotExists("-I", "INPUT", "1", "-i", a.iface, "-p", "tcp", "--dport", "443", "-j", "ACCEPT")
-	_ = a.runIPv6IfNotExists("-I", "INPUT", "1", "-i", a.iface, "-p", "udp", "--dport", "53", "-j", "ACCEPT")
-	_ = a.runIPv6IfNotExists("-I", "INPUT", "1", "-i", a.iface, "-p", "tcp", "--dport", "53", "-j", "ACCEPT")
+	_ = a.runIPv6IfNotExists("-I", "INPUT", "1", "-i", a.iface, "-p", "udp", "--dport", strconv.Itoa(a.dnsPort), "-j", "ACCEPT")
+	_ = a.runIPv6IfNotExists("-I", "INPUT", "1", "-i", a.iface, "-p", "tcp", "--dport", strconv.Itoa(a.dnsPort), "-j", "ACCEPT")
 
 	// IPv6 MASQUERADE on egress interfaces with global IPv6 addresses.
 	natIfacesIPv6 := a.detectNATInterfacesIPv6()
@@ -198,6 +198,46 @@ func TestSyncWithPolicyRules(t *testing.T) {
 	t.Logf("Sync with policy rules returned: %v", err)
 }
 
+// recordingRunner records the rules the adapter applies, per family.
+type recordingRunner struct {
+	ipv4, ipv6 []string
+}
+
+func (r *recordingRunner) run(ipv6 bool, args ...string) error {
+	if ipv6 {
+		r.ipv6 = append(r.ipv6, strings.Join(args, " "))
+	} else {
+		r.ipv4 = append(r.ipv4, strings.Join(args, " "))
+	}
+	return nil
+}
+
+func (r *recordingRunner) exists(ipv6 bool, args ...string) bool { return false }
+
+func TestSyncQuarantineDropsPerFamily(t *testing.T) {
+	rec := &recordingRunner{}
+	adapter := NewAdapter("wg0", []string{"eth0"})
+	adapter.rules = rec
+
+	policy := &dom.JumpPolicy{IP: "10.0.0.1"}
+	_ = adapter.Sync(ports.SyncRequest{Policy: policy, SelfIP: "10.0.0.1", QuarantinedIPs: []string{"10.0.0.9", "fd00::9"}})
+
+	hasDrop := func(rules []string, ip string) bool {
+		for _, r := range rules {
+			if strings.Contains(r, "-s "+ip+" -j DROP") {
+				return true
+			}
+		}
+		return false
+	}
+	if !hasDrop(rec.ipv4, "10.0.0.9") || hasDrop(rec.ipv4, "fd00::9") {
+		t.Errorf("iptables quarantine rules should drop only 10.0.0.9: %q", rec.ipv4)
+	}
+	if !hasDrop(rec.ipv6, "fd00::9") || hasDrop(rec.ipv6, "10.0.0.9") {
+		t.Errorf("ip6tables quarantine rules should drop only fd00::9: %q", rec.ipv6)
+	}
+}
+
 func TestRun(t *testing.T) {
 	adapter := NewAdapter("wg0", []string{"eth0"})
 
@@ -0,0 +1,253 @@
+package firewall
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// nftTable is the inet table holding every wirety chain on the nft backend.
+// IPv4 and IPv6 rules share it; rules without an address match are pinned to
+// their family with `meta nfproto`.
+const nftTable = "wirety"
+
+// nftBaseChains are the hooked chains standing in for the iptables built-ins.
+// Built-in chain names used by the adapter (FORWARD, POSTROUTING, ...) map to
+// these lowercase names.
+const nftBaseChains = `
+add table inet wirety
+add chain inet wirety input { type filter hook input priority 0 ; policy accept ; }
+add chain inet wirety forward { type filter hook forward priority 0 ; policy accept ; }
+add chain inet wirety output { type filter hook output priority 0 ; policy accept ; }
+add chain inet wirety prerouting { type nat hook prerouting priority -100 ; policy accept ; }
+add chain inet wirety postrouting { type nat hook postrouting priority 100 ; policy accept ; }
+`
+
+// nftRunner is the ruleRunner for nftables-only hosts.  It translates the
+// adapter's iptables arguments into nft commands.  The table is recreated on
+// first use, so rules added to the base chains only need to be tracked for
+// the lifetime of the process to stay idempotent.
+type nftRunner struct {
+	mu       sync.Mutex
+	ready    bool
+	inserted map[string]bool // nft rule expressions added to base chains
+}
+
+func newNftRunner() *nftRunner {
+	return &nftRunner{inserted: make(map[string]bool)}
+}
+
+func (r *nftRunner) setup() error {
+	if r.ready {
+		return nil
+	}
+	_ = exec.Command("nft", "delete", "table", "inet", nftTable).Run() // #nosec G204 - static command
+	cmd := exec.Command("nft", "-f", "-")                              // #nosec G204 - static command
+	cmd.Stdin = strings.NewReader(nftBaseChains)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("nft table setup failed: %v output=%s", err, string(out))
+	}
+	r.ready = true
+	return nil
+}
+
+func (r *nftRunner) run(ipv6 bool, args ...string) error {
+	nftArgs, err := translateIPTablesArgs(ipv6, args)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.setup(); err != nil {
+		return err
+	}
+	out, err := exec.Command("nft", nftArgs...).CombinedOutput() // #nosec G204
+	if err != nil {
+		return fmt.Errorf("nft %v failed: %v output=%s", nftArgs, err, string(out))
+	}
+	if nftArgs[1] == "rule" {
+		r.inserted[strings.Join(nftArgs[2:], " ")] = true
+	}
+	return nil
+}
+
+func (r *nftRunner) exists(ipv6 bool, args ...string) bool {
+	nftArgs, err := translateIPTablesArgs(ipv6, args)
+	if err != nil || nftArgs[1] != "rule" {
+		return false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.inserted[strings.Join(nftArgs[2:], " ")]
+}
+
+// nftLogLevels maps iptables' numeric --log-level to nft level names.
+var nftLogLevels = []string{"emerg", "alert", "crit", "err", "warn", "notice", "info", "debug"}
+
+// translateIPTablesArgs converts the arguments of one iptables/ip6tables
+// command, as built by the adapter, into nft command arguments.  The table
+// (-t) is ignored since every chain lives in nftTable.
+func translateIPTablesArgs(ipv6 bool, args []string) ([]string, error) {
+	var op, chain string
+	var iface, addrs, l4, state, verdict []string
+	family, nfproto := "ip", "ipv4"
+	if ipv6 {
+		family, nfproto = "ip6", "ipv6"
+	}
+
+	next := func(i int) (string, error) {
+		if i+1 >= len(args) {
+			return "", fmt.Errorf("missing value for %s", args[i])
+		}
+		return args[i+1], nil
+	}
+
+	proto := ""
+	var sport, dport string
+	for i := 0; i < len(args); i++ {
+		flag := args[i]
+		value, err := next(i)
+		if err != nil {
+			return nil, err
+		}
+		i++
+		switch flag {
+		case "-t":
+			// All chains live in the same inet table.
+		case "-N", "-F", "-X", "-A", "-I":
+			op, chain = flag, nftChain(value)
+			if flag == "-I" && i+1 < len(args) && isPositiveInt(args[i+1]) {
+				i++ // nft inserts at the top of the chain
+			}
+		case "-i":
+			iface = append(iface, "iifname", strconv.Quote(value))
+		case "-o":
+			iface = append(iface, "oifname", strconv.Quote(value))
+		case "-s":
+			addrs = append(addrs, family, "saddr", value)
+		case "-d":
+			addrs = append(addrs, family, "daddr", value)
+		case "-p":
+			proto = value
+		case "--sport":
+			sport = strings.Replace(value, ":", "-", 1) // nft range syntax
+		case "--dport":
+			dport = strings.Replace(value, ":", "-", 1)
+		case "-m":
+			if value != "conntrack" && value != "state" {
+				return nil, fmt.Errorf("unsupported match module %s", value)
+			}
+		case "--ctstate", "--state":
+			state = []string{"ct", "state", strings.ToLower(value)}
+		case "-j":
+			v, consumed, err := nftVerdict(value, args[i+1:])
+			if err != nil {
+				return nil, err
+			}
+			verdict = v
+			i += consumed
+		default:
+			return nil, fmt.Errorf("unsupported option %s", flag)
+		}
+	}
+
+	switch op {
+	case "-N":
+		return []string{"add", "chain", "inet", nftTable, chain}, nil
+	case "-F":
+		return []string{"flush", "chain", "inet", nftTable, chain}, nil
+	case "-X":
+		return []string{"delete", "chain", "inet", nftTable, chain}, nil
+	case "":
+		return nil, fmt.Errorf("no supported command in %v", args)
+	}
+	if verdict == nil {
+		return nil, fmt.Errorf("rule has no target")
+	}
+	if (sport != "" || dport != "") && proto == "" {
+		return nil, fmt.Errorf("port match without protocol")
+	}
+	if proto != "" {
+		if sport == "" && dport == "" {
+			l4 = append(l4, "meta", "l4proto", proto)
+		}
+		if sport != "" {
+			l4 = append(l4, proto, "sport", sport)
+		}
+		if dport != "" {
+			l4 = append(l4, proto, "dport", dport)
+		}
+	}
+
+	out := []string{"add", "rule", "inet", nftTable, chain}
+	if op == "-I" {
+		out[0] = "insert"
+	}
+	out = append(out, iface...)
+	if len(addrs) == 0 {
+		out = append(out, "meta", "nfproto", nfproto)
+	}
+	out = append(out, addrs...)
+	out = append(out, l4...)
+	out = append(out, state...)
+	return append(out, verdict...), nil
+}
+
+// nftChain maps iptables built-in chain names to the nft base chains; custom
+// chains keep their name.
+func nftChain(name string) string {
+	switch name {
+	case "INPUT", "FORWARD", "OUTPUT", "PREROUTING", "POSTROUTING":
+		return strings.ToLower(name)
+	}
+	return name
+}
+
+// nftVerdict translates an iptables target and its options (the arguments
+// following the target) into an nft statement.  It returns how many option
+// arguments it consumed.
+func nftVerdict(target string, opts []string) ([]string, int, error) {
+	switch target {
+	case "ACCEPT", "DROP", "RETURN", "MASQUERADE":
+		return []string{strings.ToLower(target)}, 0, nil
+	case "REJECT":
+		if len(opts) >= 2 && opts[0] == "--reject-with" {
+			if opts[1] != "tcp-reset" {
+				return nil, 0, fmt.Errorf("unsupported reject type %s", opts[1])
+			}
+			return []string{"reject", "with", "tcp", "reset"}, 2, nil
+		}
+		return []string{"reject"}, 0, nil
+	case "REDIRECT":
+		if len(opts) < 2 || opts[0] != "--to-port" {
+			return nil, 0, fmt.Errorf("REDIRECT without --to-port")
+		}
+		return []string{"redirect", "to", ":" + opts[1]}, 2, nil
+	case "LOG":
+		out := []string{"log"}
+		consumed := 0
+		for consumed+1 < len(opts) {
+			switch opts[consumed] {
+			case "--log-prefix":
+				out = append(out, "prefix", strconv.Quote(opts[consumed+1]))
+			case "--log-level":
+				level, err := strconv.Atoi(opts[consumed+1])
+				if err != nil || level < 0 || level >= len(nftLogLevels) {
+					return nil, 0, fmt.Errorf("unsupported log level %s", opts[consumed+1])
+				}
+				out = append(out, "level", nftLogLevels[level])
+			default:
+				return out, consumed, nil
+			}
+			consumed += 2
+		}
+		return out, consumed, nil
+	}
+	if strings.HasPrefix(target, "-") {
+		return nil, 0, fmt.Errorf("missing target")
+	}
+	return []string{"jump", target}, 0, nil
+}
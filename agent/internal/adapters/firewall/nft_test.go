@@ -0,0 +1,121 @@
+package firewall
+
+import (
+	"strings"
+	"testing"
+
+	dom "wirety/agent/internal/domain/policy"
+)
+
+func TestTranslateIPTablesArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		ipv6 bool
+		args []string
+		want string
+	}{
+		{
+			name: "create chain",
+			args: []string{"-N", "WIRETY_JUMP"},
+			want: "add chain inet wirety WIRETY_JUMP",
+		},
+		{
+			name: "flush nat chain",
+			args: []string{"-t", "nat", "-F", "WIRETY_CAPTIVE"},
+			want: "flush chain inet wirety WIRETY_CAPTIVE",
+		},
+		{
+			name: "conntrack accept",
+			args: []string{"-A", "WIRETY_JUMP", "-m", "conntrack", "--ctstate", "ESTABLISHED,RELATED", "-j", "ACCEPT"},
+			want: "add rule inet wirety WIRETY_JUMP meta nfproto ipv4 ct state established,related accept",
+		},
+		{
+			name: "jump inserted into built-in chain",
+			args: []string{"-I", "FORWARD", "1", "-j", "WIRETY_JUMP"},
+			want: "insert rule inet wirety forward meta nfproto ipv4 jump WIRETY_JUMP",
+		},
+		{
+			name: "ipv6 source jump",
+			ipv6: true,
+			args: []string{"-A", "WIRETY6_JUMP", "-i", "wg0", "-s", "fd00::2", "-j", "WIRETY6_POLICY"},
+			want: `add rule inet wirety WIRETY6_JUMP iifname "wg0" ip6 saddr fd00::2 jump WIRETY6_POLICY`,
+		},
+		{
+			name: "reject with tcp reset",
+			args: []string{"-A", "WIRETY_JUMP", "-i", "wg0", "-d", "10.0.0.0/8", "-p", "tcp", "--dport", "443", "-j", "REJECT", "--reject-with", "tcp-reset"},
+			want: `add rule inet wirety WIRETY_JUMP iifname "wg0" ip daddr 10.0.0.0/8 tcp dport 443 reject with tcp reset`,
+		},
+		{
+			name: "port range",
+			args: []string{"-A", "WIRETY_POLICY", "-s", "10.0.0.2", "-d", "10.1.0.0/24", "-p", "udp", "--dport", "8000:8100", "-j", "ACCEPT"},
+			want: "add rule inet wirety WIRETY_POLICY ip saddr 10.0.0.2 ip daddr 10.1.0.0/24 udp dport 8000-8100 accept",
+		},
+		{
+			name: "masquerade",
+			args: []string{"-t", "nat", "-A", "POSTROUTING", "-o", "eth0", "-j", "MASQUERADE"},
+			want: `add rule inet wirety postrouting oifname "eth0" meta nfproto ipv4 masquerade`,
+		},
+		{
+			name: "redirect",
+			args: []string{"-t", "nat", "-A", "WIRETY_REDIRECT", "-p", "tcp", "--dport", "80", "-j", "REDIRECT", "--to-port", "80"},
+			want: "add rule inet wirety WIRETY_REDIRECT meta nfproto ipv4 tcp dport 80 redirect to :80",
+		},
+		{
+			name: "log",
+			args: []string{"-I", "WIRETY_JUMP", "1", "-j", "LOG", "--log-prefix", "WIRETY-DEBUG: ", "--log-level", "4"},
+			want: `insert rule inet wirety WIRETY_JUMP meta nfproto ipv4 log prefix "WIRETY-DEBUG: " level warn`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := translateIPTablesArgs(tt.ipv6, tt.args)
+			if err != nil {
+				t.Fatalf("translateIPTablesArgs(%v): %v", tt.args, err)
+			}
+			if strings.Join(got, " ") != tt.want {
+				t.Errorf("translateIPTablesArgs(%v) = %q, want %q", tt.args, strings.Join(got, " "), tt.want)
+			}
+		})
+	}
+
+	for _, args := range [][]string{
+		{"-A", "WIRETY_JUMP", "-m", "string", "--string", "example.com", "-j", "ACCEPT"},
+		{"-t", "nat", "-D", "PREROUTING", "-i", "wg0", "-j", "WIRETY_CAPTIVE"},
+		{"-A", "WIRETY_JUMP", "--dport", "53", "-j", "ACCEPT"},
+	} {
+		if _, err := translateIPTablesArgs(false, args); err == nil {
+			t.Errorf("translateIPTablesArgs(%v) should fail", args)
+		}
+	}
+}
+
+func TestPolicyRuleArgs(t *testing.T) {
+	rule := dom.Rule{Family: dom.FamilyIPv4, Chain: "forward", Source: "10.0.1.0/24", Destination: "10.0.0.2", State: "related,established", Action: "accept"}
+	want := "-A WIRETY_POLICY -s 10.0.1.0/24 -d 10.0.0.2 -m conntrack --ctstate RELATED,ESTABLISHED -j ACCEPT"
+	if got := strings.Join(policyRuleArgs("WIRETY_POLICY", rule), " "); got != want {
+		t.Errorf("policyRuleArgs = %q, want %q", got, want)
+	}
+
+	nft, err := translateIPTablesArgs(false, policyRuleArgs("WIRETY_POLICY", rule))
+	if err != nil {
+		t.Fatalf("translate policy rule: %v", err)
+	}
+	want = "add rule inet wirety WIRETY_POLICY ip saddr 10.0.1.0/24 ip daddr 10.0.0.2 ct state related,established accept"
+	if got := strings.Join(nft, " "); got != want {
+		t.Errorf("nft policy rule = %q, want %q", got, want)
+	}
+}
+
+func TestSetBackend(t *testing.T) {
+	adapter := NewAdapter("wg0", nil)
+	if adapter.Backend() != BackendIPTables {
+		t.Errorf("default backend = %q, want %q", adapter.Backend(), BackendIPTables)
+	}
+	if err := adapter.SetBackend("nft"); err != nil || adapter.Backend() != BackendNftables {
+		t.Errorf("SetBackend(nft) = %v, backend %q", err, adapter.Backend())
+	}
+	if err := adapter.SetBackend("pf"); err == nil {
+		t.Error("SetBackend(pf) should fail")
+	}
+}
@@ -0,0 +1,123 @@
+package wg
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ApplyPeerDelta updates single peers of the running interface with `wg set`
+// instead of re-syncing the whole config, so handshakes with the other peers
+// are left alone.  upserts are [Peer] sections to add or replace, removed the
+// public keys of peers to drop.  The routes of the changed AllowedIPs are
+// updated as after syncconf and cfg, the full resulting config, is written
+// out for the next start.  Any error leaves it to the caller to apply the
+// full config.
+func (w *Writer) ApplyPeerDelta(cfg string, upserts []string, removed []string) error {
+	if err := w.CheckOwnership(); err != nil {
+		return fmt.Errorf("ownership check failed: %w", err)
+	}
+	if !w.interfaceExists() {
+		return fmt.Errorf("interface %s does not exist", w.Interface)
+	}
+
+	oldRoutes, err := w.getCurrentPeerRoutes()
+	if err != nil {
+		log.Warn().Err(err).Msg("failed to get current peer routes, continuing anyway")
+		oldRoutes = make(map[string]bool)
+	}
+
+	for _, publicKey := range removed {
+		if err := run("wg", "set", w.Interface, "peer", publicKey, "remove"); err != nil {
+			return fmt.Errorf("remove peer: %w", err)
+		}
+	}
+	for _, section := range upserts {
+		if err := w.setPeer(section); err != nil {
+			return err
+		}
+	}
+
+	if err := w.updatePeerRoutes(oldRoutes); err != nil {
+		log.Error().Err(err).Msg("failed to update peer routes after peer delta")
+	}
+
+	if err := w.writeAtomic(w.addMarkerToConfig(w.withPrivateKey(cfg))); err != nil {
+		return fmt.Errorf("write config: %w", err)
+	}
+	return nil
+}
+
+// setPeer adds or replaces the peer of a [Peer] section with `wg set`.  The
+// preshared key is handed over in a temporary file since wg only reads it
+// from one.
+func (w *Writer) setPeer(section string) error {
+	settings := parsePeerSection(section)
+	if settings["PublicKey"] == "" {
+		return fmt.Errorf("peer section has no PublicKey")
+	}
+
+	pskFile := "/dev/null" // clears a preshared key the peer no longer has
+	if psk := settings["PresharedKey"]; psk != "" {
+		f, err := os.CreateTemp(filepath.Dir(w.Path), ".psk-*")
+		if err != nil {
+			return fmt.Errorf("write preshared key: %w", err)
+		}
+		defer func() { _ = os.Remove(f.Name()) }()
+		_, err = f.WriteString(psk + "\n")
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return fmt.Errorf("write preshared key: %w", err)
+		}
+		pskFile = f.Name()
+	}
+
+	if err := run("wg", peerSetArgs(w.Interface, settings, pskFile)...); err != nil {
+		return fmt.Errorf("set peer: %w", err)
+	}
+	return nil
+}
+
+// peerSetArgs builds the `wg set` arguments for a parsed [Peer] section.
+// Settings missing from the section are reset, except Endpoint which wg
+// cannot unset and which the peer roams anyway.
+func peerSetArgs(iface string, settings map[string]string, pskFile string) []string {
+	allowedIPs := strings.Join(strings.Fields(strings.ReplaceAll(settings["AllowedIPs"], ",", " ")), ",")
+	keepalive := settings["PersistentKeepalive"]
+	if keepalive == "" {
+		keepalive = "off"
+	}
+	args := []string{
+		"set", iface, "peer", settings["PublicKey"],
+		"preshared-key", pskFile,
+		"persistent-keepalive", keepalive,
+		"allowed-ips", allowedIPs,
+	}
+	if endpoint := settings["Endpoint"]; endpoint != "" {
+		args = append(args, "endpoint", endpoint)
+	}
+	return args
+}
+
+// parsePeerSection returns the settings of a [Peer] section, skipping the
+// header and comments.
+func parsePeerSection(section string) map[string]string {
+	settings := make(map[string]string)
+	for _, line := range strings.Split(section, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "[") {
+			continue
+		}
+		key, value, found := strings.Cut(line, "=")
+		if !found {
+			continue
+		}
+		settings[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	return settings
+}
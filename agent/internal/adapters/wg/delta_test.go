@@ -0,0 +1,33 @@
+package wg
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPeerSetArgs(t *testing.T) {
+	section := "[Peer]\n# Name: jump\nPublicKey = pk-jump=\nPresharedKey = psk=\nAllowedIPs = 10.0.0.0/24, 192.168.1.0/24\nEndpoint = vpn.example.com:51820\nPersistentKeepalive = 25"
+	got := peerSetArgs("wg0", parsePeerSection(section), "/tmp/psk")
+	want := []string{
+		"set", "wg0", "peer", "pk-jump=",
+		"preshared-key", "/tmp/psk",
+		"persistent-keepalive", "25",
+		"allowed-ips", "10.0.0.0/24,192.168.1.0/24",
+		"endpoint", "vpn.example.com:51820",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("peerSetArgs = %q, want %q", got, want)
+	}
+
+	// Settings the section no longer has are reset
+	got = peerSetArgs("wg0", parsePeerSection("[Peer]\nPublicKey = pk-db=\nAllowedIPs = 10.0.0.3/32"), "/dev/null")
+	want = []string{
+		"set", "wg0", "peer", "pk-db=",
+		"preshared-key", "/dev/null",
+		"persistent-keepalive", "off",
+		"allowed-ips", "10.0.0.3/32",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("peerSetArgs = %q, want %q", got, want)
+	}
+}
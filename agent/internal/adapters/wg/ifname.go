@@ -0,0 +1,121 @@
+package wg
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// DefaultInterfaceNameLen is the longest interface name Linux accepts
+// (IFNAMSIZ - 1).
+const DefaultInterfaceNameLen = 15
+
+// Collision strategies of InterfaceNamer, applied when a truncated name is
+// already used by another peer's managed interface.
+const (
+	CollisionHash = "hash" // replace the end of the name with a hash of the full name
+	CollisionNone = "none" // keep the truncated name
+)
+
+// hashSuffixLen is the length of "-" plus the hex digits of the hash suffix.
+const hashSuffixLen = 7
+
+// peerIDHeader prefixes the line of the config header naming the peer the
+// interface belongs to.
+const peerIDHeader = "# Peer ID: "
+
+var invalidInterfaceChars = regexp.MustCompile(`[^a-z0-9_-]`)
+
+// InterfaceNamer derives WireGuard interface names from peer names.
+type InterfaceNamer struct {
+	maxLen    int
+	collision string
+	taken     func(iface string) bool
+}
+
+// NewInterfaceNamer returns a namer truncating names to maxLen characters
+// (DefaultInterfaceNameLen when zero).  With CollisionHash, a truncated name
+// for which taken reports true gets a short hash suffix instead; taken may be
+// nil.
+func NewInterfaceNamer(maxLen int, collision string, taken func(iface string) bool) (*InterfaceNamer, error) {
+	if maxLen == 0 {
+		maxLen = DefaultInterfaceNameLen
+	}
+	switch collision {
+	case CollisionHash:
+		if maxLen <= hashSuffixLen {
+			return nil, fmt.Errorf("interface name length %d leaves no room for a hash suffix (need more than %d)", maxLen, hashSuffixLen)
+		}
+	case CollisionNone:
+		if maxLen < 1 {
+			return nil, fmt.Errorf("invalid interface name length %d", maxLen)
+		}
+	default:
+		return nil, fmt.Errorf("unknown interface name collision strategy %q (want hash or none)", collision)
+	}
+	return &InterfaceNamer{maxLen: maxLen, collision: collision, taken: taken}, nil
+}
+
+// Name converts a peer name to a valid interface name: lowercase letters,
+// digits, underscores and dashes, at most maxLen characters, "wg0" when
+// nothing is left.
+func (n *InterfaceNamer) Name(peerName string) string {
+	sanitized := invalidInterfaceChars.ReplaceAllString(strings.ToLower(peerName), "_")
+	if len(sanitized) <= n.maxLen {
+		if sanitized == "" {
+			return "wg0"
+		}
+		return sanitized
+	}
+
+	// Remove trailing underscores or dashes after truncation
+	name := strings.TrimRight(sanitized[:n.maxLen], "_-")
+	if n.collision != CollisionHash || n.taken == nil || !n.taken(name) {
+		if name == "" {
+			return "wg0"
+		}
+		return name
+	}
+	sum := sha256.Sum256([]byte(sanitized))
+	return sanitized[:n.maxLen-hashSuffixLen] + "-" + hex.EncodeToString(sum[:])[:hashSuffixLen-1]
+}
+
+// TakenByOtherPeer returns a check for InterfaceNamer reporting whether dir
+// holds the Wirety-managed config of an interface belonging to a peer other
+// than peerID.  Configs written before the peer ID was recorded are assumed
+// to be this peer's.
+func TakenByOtherPeer(dir, peerID string) func(iface string) bool {
+	return func(iface string) bool {
+		owner, managed := managedConfigPeer(filepath.Join(dir, iface+".conf"))
+		return managed && owner != "" && owner != peerID
+	}
+}
+
+// managedConfigPeer reads the peer ID recorded in the header of a managed
+// config, and whether path is a Wirety-managed config at all.
+func managedConfigPeer(path string) (peerID string, managed bool) {
+	f, err := os.Open(path) // #nosec G304 - path built from the config directory
+	if err != nil {
+		return "", false
+	}
+	defer func() { _ = f.Close() }()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "#") {
+			break // the header is over
+		}
+		if line == WiretyMarker {
+			managed = true
+		}
+		if id, ok := strings.CutPrefix(line, peerIDHeader); ok {
+			peerID = strings.TrimSpace(id)
+		}
+	}
+	return peerID, managed
+}
@@ -0,0 +1,131 @@
+package wg
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInterfaceNamerDefaults(t *testing.T) {
+	namer, err := NewInterfaceNamer(0, CollisionHash, nil)
+	if err != nil {
+		t.Fatalf("NewInterfaceNamer: %v", err)
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"simple-peer", "simple-peer"},
+		{"Peer_123", "peer_123"},
+		{"peer@home", "peer_home"},
+		{"peer with spaces", "peer_with_space"},       // truncated to 15 chars
+		{"peer/with/slashes", "peer_with_slash"},      // truncated to 15 chars
+		{"verylongpeernametotest", "verylongpeernam"}, // truncated to 15 chars
+		{"", "wg0"}, // default if empty
+		{"peer-name-with.dots", "peer-name-with"}, // truncated to 15 chars (trailing _ removed)
+		{"123peer", "123peer"},                    // numbers are allowed
+		{"特殊字符", "____"},                          // unicode chars replaced with underscores (4 chars)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			result := namer.Name(tt.input)
+			if result != tt.expected {
+				t.Errorf("Name(%q) = %q, want %q", tt.input, result, tt.expected)
+			}
+			if len(result) > DefaultInterfaceNameLen {
+				t.Errorf("Name(%q) = %q is too long (%d chars)", tt.input, result, len(result))
+			}
+		})
+	}
+}
+
+func TestInterfaceNamerMaxLen(t *testing.T) {
+	namer, err := NewInterfaceNamer(31, CollisionNone, nil)
+	if err != nil {
+		t.Fatalf("NewInterfaceNamer: %v", err)
+	}
+	if got := namer.Name("verylongpeernametotest"); got != "verylongpeernametotest" {
+		t.Errorf("Name = %q, want the untruncated name", got)
+	}
+}
+
+func TestInterfaceNamerCollisions(t *testing.T) {
+	taken := map[string]bool{"office-gateway-": true, "office-gateway": true}
+	namer, err := NewInterfaceNamer(0, CollisionHash, func(iface string) bool { return taken[iface] })
+	if err != nil {
+		t.Fatalf("NewInterfaceNamer: %v", err)
+	}
+
+	paris := namer.Name("office-gateway-paris")
+	berlin := namer.Name("office-gateway-berlin")
+	if paris == berlin {
+		t.Fatalf("peers with a shared prefix got the same interface %q", paris)
+	}
+	for _, name := range []string{paris, berlin} {
+		if len(name) > DefaultInterfaceNameLen {
+			t.Errorf("%q is longer than %d chars", name, DefaultInterfaceNameLen)
+		}
+		if !strings.HasPrefix(name, "office-g-") {
+			t.Errorf("%q does not keep the start of the peer name", name)
+		}
+	}
+	if again := namer.Name("office-gateway-paris"); again != paris {
+		t.Errorf("name is not stable: %q then %q", paris, again)
+	}
+
+	// Names short enough are never suffixed, taken or not.
+	if got := namer.Name("office-gateway"); got != "office-gateway" {
+		t.Errorf("untruncated name = %q, want office-gateway", got)
+	}
+
+	// Without the hash strategy the truncated names collide.
+	plain, _ := NewInterfaceNamer(0, CollisionNone, func(iface string) bool { return taken[iface] })
+	if plain.Name("office-gateway-paris") != plain.Name("office-gateway-berlin") {
+		t.Error("expected the none strategy to keep the colliding truncated name")
+	}
+}
+
+func TestNewInterfaceNamerValidation(t *testing.T) {
+	if _, err := NewInterfaceNamer(15, "random", nil); err == nil {
+		t.Error("expected an error for an unknown strategy")
+	}
+	if _, err := NewInterfaceNamer(6, CollisionHash, nil); err == nil {
+		t.Error("expected an error when the hash suffix does not fit")
+	}
+	if _, err := NewInterfaceNamer(-1, CollisionNone, nil); err == nil {
+		t.Error("expected an error for a negative length")
+	}
+}
+
+func TestTakenByOtherPeer(t *testing.T) {
+	dir := t.TempDir()
+	write := func(iface, peerID string) {
+		w := NewWriter(filepath.Join(dir, iface+".conf"), iface, "")
+		w.PeerID = peerID
+		if err := os.WriteFile(w.Path, []byte(w.addMarkerToConfig("[Interface]\n")), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("office-gateway", "peer-1")
+	write("legacy", "")
+	if err := os.WriteFile(filepath.Join(dir, "manual.conf"), []byte("[Interface]\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	taken := TakenByOtherPeer(dir, "peer-2")
+	if !taken("office-gateway") {
+		t.Error("interface of another peer should be taken")
+	}
+	if TakenByOtherPeer(dir, "peer-1")("office-gateway") {
+		t.Error("a peer's own interface should not be taken")
+	}
+	if taken("legacy") {
+		t.Error("configs without a peer ID should not be taken")
+	}
+	if taken("manual") || taken("missing") {
+		t.Error("unmanaged or missing configs should not be taken")
+	}
+}
@@ -0,0 +1,140 @@
+package wg
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// wireguardModulePath exists whenever the kernel WireGuard module is loaded
+// or built in.
+var wireguardModulePath = "/sys/module/wireguard"
+
+// kernelModuleMissing reports whether the kernel lacks WireGuard support.
+// It is only consulted after a kernel apply method failed: before the first
+// interface is created a loadable module may simply not be loaded yet.
+func kernelModuleMissing() bool {
+	_, err := os.Stat(wireguardModulePath)
+	return os.IsNotExist(err)
+}
+
+// userspaceCommand returns the daemon that creates the TUN device and
+// serves the WireGuard UAPI socket for the writer's userspace apply method.
+// Both daemonize on their own.  boringtun drops privileges by default, which
+// stops wg from reaching the socket afterwards, hence WG_SUDO.
+func (w *Writer) userspaceCommand() *exec.Cmd {
+	if w.ApplyMethod == ApplyBoringtun {
+		cmd := exec.Command("boringtun-cli", w.Interface) // #nosec G204 - w.Interface is sanitized and controlled
+		cmd.Env = append(os.Environ(), "WG_SUDO=1")
+		return cmd
+	}
+	return exec.Command("wireguard-go", w.Interface) // #nosec G204 - w.Interface is sanitized and controlled
+}
+
+// userspace applies the configuration through a userspace WireGuard
+// implementation.  An existing interface is updated in place like
+// syncconf; otherwise the daemon is started and the interface is set up by
+// hand, doing what wg-quick up would do after creating a kernel interface:
+// load the keys and peers, assign the addresses and MTU, bring the link up
+// and route every peer's AllowedIPs through it.
+func (w *Writer) userspace() error {
+	if w.interfaceExists() {
+		return w.syncExisting()
+	}
+
+	cmd := w.userspaceCommand()
+	log.Info().Str("interface", w.Interface).Str("implementation", cmd.Path).Msg("interface doesn't exist, starting userspace WireGuard")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to start %s: %v output=%s", cmd.Path, err, strings.TrimSpace(string(out)))
+	}
+
+	if err := w.wgFromStrippedConfig("setconf"); err != nil {
+		return err
+	}
+
+	addresses, mtu, err := readInterfaceSettings(w.Path)
+	if err != nil {
+		return err
+	}
+	for _, addr := range addresses {
+		if err := run("ip", "address", "add", addr, "dev", w.Interface); err != nil {
+			return fmt.Errorf("failed to assign address: %w", err)
+		}
+	}
+	if mtu != "" {
+		if err := run("ip", "link", "set", "mtu", mtu, "up", "dev", w.Interface); err != nil {
+			return fmt.Errorf("failed to bring interface up: %w", err)
+		}
+	} else if err := run("ip", "link", "set", "up", "dev", w.Interface); err != nil {
+		return fmt.Errorf("failed to bring interface up: %w", err)
+	}
+
+	if err := w.updatePeerRoutes(map[string]bool{}); err != nil {
+		log.Error().Err(err).Msg("failed to add peer routes for userspace interface")
+	}
+
+	log.Info().Str("interface", w.Interface).Str("implementation", cmd.Path).Msg("userspace WireGuard interface up")
+	return nil
+}
+
+// readInterfaceSettings extracts the wg-quick-only Address and MTU settings
+// of the [Interface] section, which `wg setconf` does not understand.
+func readInterfaceSettings(path string) (addresses []string, mtu string, err error) {
+	content, err := os.ReadFile(path) // #nosec G304 - path is the agent's own config file
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read config file %s: %w", path, err)
+	}
+
+	scanInterfaceSection(string(content), func(key, value string) {
+		switch key {
+		case "Address":
+			for _, addr := range strings.Split(value, ",") {
+				if addr = strings.TrimSpace(addr); addr != "" {
+					addresses = append(addresses, addr)
+				}
+			}
+		case "MTU":
+			mtu = value
+		}
+	})
+	return addresses, mtu, nil
+}
+
+// readRoutingTable returns the wg-quick Table setting of the config file;
+// "" when it is unset or the file cannot be read.
+func readRoutingTable(path string) string {
+	content, err := os.ReadFile(path) // #nosec G304 - path is the agent's own config file
+	if err != nil {
+		return ""
+	}
+	var table string
+	scanInterfaceSection(string(content), func(key, value string) {
+		if key == "Table" {
+			table = value
+		}
+	})
+	return table
+}
+
+// scanInterfaceSection calls fn with each trimmed key and value of the
+// [Interface] section of a config.
+func scanInterfaceSection(content string, fn func(key, value string)) {
+	inInterface := false
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") {
+			inInterface = strings.EqualFold(line, "[Interface]")
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !inInterface || !ok {
+			continue
+		}
+		fn(strings.TrimSpace(key), strings.TrimSpace(value))
+	}
+}
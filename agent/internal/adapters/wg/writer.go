@@ -18,11 +18,29 @@ const (
 	WiretyMarker = "# This file is managed by Wirety Agent - DO NOT EDIT MANUALLY"
 )
 
+// Apply methods.  wg-quick and syncconf drive the kernel WireGuard module;
+// wireguard-go and boringtun run a userspace implementation on a TUN device
+// for hosts without it (see userspace.go).
+const (
+	ApplyWGQuick     = "wg-quick"
+	ApplySyncconf    = "syncconf"
+	ApplyWireGuardGo = "wireguard-go"
+	ApplyBoringtun   = "boringtun"
+)
+
 // Writer handles writing WireGuard config files atomically and applying them.
 type Writer struct {
 	Path        string
 	Interface   string
 	ApplyMethod string
+
+	// PrivateKey is written into configs that have none: the server sends
+	// none for a peer whose key pair was generated on the device.
+	PrivateKey string
+
+	// PeerID is recorded in the config header, so agents of other peers
+	// naming their interface can tell it is taken (see TakenByOtherPeer).
+	PeerID string
 }
 
 func NewWriter(path, iface, method string) *Writer {
@@ -30,7 +48,7 @@ func NewWriter(path, iface, method string) *Writer {
 		path = fmt.Sprintf("/etc/wireguard/%s.conf", iface)
 	}
 	if method == "" {
-		method = "wg-quick"
+		method = ApplyWGQuick
 	}
 	return &Writer{Path: path, Interface: iface, ApplyMethod: method}
 }
@@ -85,24 +103,80 @@ func (w *Writer) addMarkerToConfig(cfg string) string {
 
 	// Add marker at the beginning with timestamp
 	timestamp := time.Now().Format(time.RFC3339)
-	header := fmt.Sprintf("%s\n# Generated on: %s\n# Interface: %s\n\n", WiretyMarker, timestamp, w.Interface)
+	header := fmt.Sprintf("%s\n# Generated on: %s\n# Interface: %s\n", WiretyMarker, timestamp, w.Interface)
+	if w.PeerID != "" {
+		header += peerIDHeader + w.PeerID + "\n"
+	}
+	header += "\n"
 
 	return header + cfg
 }
 
+// HasPrivateKey reports whether the [Interface] section of cfg sets a
+// PrivateKey.  Comments, like the placeholder the server writes when it
+// keeps private keys out of agent configs, do not count.
+func HasPrivateKey(cfg string) bool {
+	hasKey := false
+	scanInterfaceSection(cfg, func(key, _ string) {
+		hasKey = hasKey || key == "PrivateKey"
+	})
+	return hasKey
+}
+
+// withPrivateKey adds the PrivateKey line to the [Interface] section of cfg
+// when it has none and the writer was given a private key.
+func (w *Writer) withPrivateKey(cfg string) string {
+	if w.PrivateKey == "" || HasPrivateKey(cfg) {
+		return cfg
+	}
+	const header = "[Interface]\n"
+	i := strings.Index(cfg, header)
+	if i < 0 {
+		return cfg
+	}
+	i += len(header)
+	return cfg[:i] + "PrivateKey = " + w.PrivateKey + "\n" + cfg[i:]
+}
+
+// WriteAndApply writes cfg and applies it to the interface.  If applying
+// fails, the previous config is written back and re-applied so a bad config
+// (e.g. an invalid key) does not leave the interface broken; the original
+// apply error is returned either way.
 func (w *Writer) WriteAndApply(cfg string) error {
 	// First, check if we own this config file
 	if err := w.CheckOwnership(); err != nil {
 		return fmt.Errorf("ownership check failed: %w", err)
 	}
 
+	// Snapshot the current config.  A failed apply always restores it, so
+	// what is on disk is the last config that applied successfully.
+	previous, err := os.ReadFile(w.Path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("read current config: %w", err)
+	}
+
 	// Add marker to config
-	markedConfig := w.addMarkerToConfig(cfg)
+	markedConfig := w.addMarkerToConfig(w.withPrivateKey(cfg))
 
 	if err := w.writeAtomic(markedConfig); err != nil {
 		return fmt.Errorf("write config: %w", err)
 	}
-	return w.apply()
+	applyErr := w.apply()
+	if applyErr == nil || previous == nil {
+		return applyErr
+	}
+
+	log.Warn().Err(applyErr).Str("interface", w.Interface).Msg("applying new config failed, rolling back to the previous config")
+	if err := w.writeAtomic(string(previous)); err != nil {
+		log.Error().Err(err).Str("path", w.Path).Msg("failed to restore previous config")
+		return applyErr
+	}
+	if err := w.apply(); err != nil {
+		log.Error().Err(err).Str("interface", w.Interface).Msg("failed to re-apply previous config")
+		return applyErr
+	}
+	log.Info().Str("interface", w.Interface).Msg("previous config restored")
+	return applyErr
 }
 
 func (w *Writer) writeAtomic(cfg string) error {
@@ -118,26 +192,32 @@ func (w *Writer) writeAtomic(cfg string) error {
 }
 
 func (w *Writer) apply() error {
+	var err error
 	switch w.ApplyMethod {
-	case "wg-quick":
+	case ApplyWGQuick:
 		_ = run("wg-quick", "down", w.Path) // ignore error
-		return run("wg-quick", "up", w.Path)
-	case "syncconf":
+		err = run("wg-quick", "up", w.Path)
+	case ApplySyncconf:
 		// Use wg syncconf with wg-quick strip to update config without recreating interface
 		// This is equivalent to: wg syncconf <interface> <(wg-quick strip <config>)
-		return w.syncconf()
+		err = w.syncconf()
+	case ApplyWireGuardGo, ApplyBoringtun:
+		return w.userspace()
 	default:
 		return fmt.Errorf("unknown apply method: %s", w.ApplyMethod)
 	}
+	if err != nil && kernelModuleMissing() {
+		log.Error().Err(err).Str("apply_method", w.ApplyMethod).
+			Msg("the kernel has no WireGuard module; use a userspace backend instead: --apply wireguard-go or --apply boringtun")
+	}
+	return err
 }
 
 // syncconf applies configuration using wg syncconf with wg-quick strip
 // This updates the interface without bringing it down and manually manages routes
 func (w *Writer) syncconf() error {
 	// First, ensure the interface exists (create it if needed)
-	// Check if interface exists
-	checkCmd := exec.Command("ip", "link", "show", w.Interface) // #nosec G204 - w.Interface is sanitized and controlled
-	if err := checkCmd.Run(); err != nil {
+	if !w.interfaceExists() {
 		// Interface doesn't exist, create it with wg-quick up
 		log.Info().Str("interface", w.Interface).Msg("interface doesn't exist, creating with wg-quick up")
 		if err := run("wg-quick", "up", w.Path); err != nil {
@@ -145,7 +225,18 @@ func (w *Writer) syncconf() error {
 		}
 		return nil
 	}
+	return w.syncExisting()
+}
+
+// interfaceExists reports whether the WireGuard interface is present.
+func (w *Writer) interfaceExists() bool {
+	checkCmd := exec.Command("ip", "link", "show", w.Interface) // #nosec G204 - w.Interface is sanitized and controlled
+	return checkCmd.Run() == nil
+}
 
+// syncExisting updates an existing interface in place with wg syncconf and
+// adds/removes the peer routes syncconf does not manage.
+func (w *Writer) syncExisting() error {
 	// Get current peer routes before updating config
 	oldRoutes, err := w.getCurrentPeerRoutes()
 	if err != nil {
@@ -154,9 +245,26 @@ func (w *Writer) syncconf() error {
 	}
 
 	// Interface exists, use syncconf to update it
-	// Run: wg-quick strip <config> | wg syncconf <interface> /dev/stdin
-	stripCmd := exec.Command("wg-quick", "strip", w.Path)                // #nosec G204 - w.Path is controlled by agent
-	syncCmd := exec.Command("wg", "syncconf", w.Interface, "/dev/stdin") // #nosec G204 - w.Interface is sanitized and controlled
+	if err := w.wgFromStrippedConfig("syncconf"); err != nil {
+		return err
+	}
+
+	// After syncconf, manually manage routes since syncconf doesn't handle them
+	if err := w.updatePeerRoutes(oldRoutes); err != nil {
+		log.Error().Err(err).Msg("failed to update peer routes after syncconf")
+		// Don't fail the entire operation, but log the error
+	}
+
+	log.Debug().Str("interface", w.Interface).Msg("configuration synced successfully with route management")
+	return nil
+}
+
+// wgFromStrippedConfig runs `wg-quick strip <config> | wg <verb> <interface> /dev/stdin`,
+// i.e. loads the WireGuard-only part of the config (no Address, MTU, DNS…)
+// into the interface with `wg syncconf` or `wg setconf`.
+func (w *Writer) wgFromStrippedConfig(verb string) error {
+	stripCmd := exec.Command("wg-quick", "strip", w.Path)          // #nosec G204 - w.Path is controlled by agent
+	syncCmd := exec.Command("wg", verb, w.Interface, "/dev/stdin") // #nosec G204 - w.Interface is sanitized and controlled
 
 	// Pipe strip output to syncconf input
 	pipe, err := stripCmd.StdoutPipe()
@@ -175,7 +283,7 @@ func (w *Writer) syncconf() error {
 		return fmt.Errorf("failed to start wg-quick strip: %w", err)
 	}
 	if err := syncCmd.Start(); err != nil {
-		return fmt.Errorf("failed to start wg syncconf: %w", err)
+		return fmt.Errorf("failed to start wg %s: %w", verb, err)
 	}
 
 	// Wait for both to complete
@@ -183,20 +291,15 @@ func (w *Writer) syncconf() error {
 		return fmt.Errorf("wg-quick strip failed: %v stderr=%s", err, stripErr.String())
 	}
 	if err := syncCmd.Wait(); err != nil {
-		return fmt.Errorf("wg syncconf failed: %v stderr=%s", err, syncErr.String())
-	}
-
-	// After syncconf, manually manage routes since syncconf doesn't handle them
-	if err := w.updatePeerRoutes(oldRoutes); err != nil {
-		log.Error().Err(err).Msg("failed to update peer routes after syncconf")
-		// Don't fail the entire operation, but log the error
+		return fmt.Errorf("wg %s failed: %v stderr=%s", verb, err, syncErr.String())
 	}
-
-	log.Debug().Str("interface", w.Interface).Msg("configuration synced successfully with route management")
 	return nil
 }
 
-func run(cmd string, args ...string) error {
+// run executes a command; a variable so tests can stand in for wg-quick.
+var run = runCommand
+
+func runCommand(cmd string, args ...string) error {
 	c := exec.Command(cmd, args...) // #nosec G204
 	var out, errBuf bytes.Buffer
 	c.Stdout = &out
@@ -426,8 +529,16 @@ func (w *Writer) getCurrentPeerRoutes() (map[string]bool, error) {
 	return routes, nil
 }
 
-// updatePeerRoutes manages routes for WireGuard peers after syncconf
+// updatePeerRoutes manages routes for WireGuard peers after syncconf.  Like
+// wg-quick, it leaves routing alone for Table = off and installs the routes
+// in the numbered table the config names, if any.
 func (w *Writer) updatePeerRoutes(oldRoutes map[string]bool) error {
+	table := readRoutingTable(w.Path)
+	if table == "off" {
+		log.Debug().Str("interface", w.Interface).Msg("Table = off, peer routes are left to the host")
+		return nil
+	}
+
 	// Get new peer routes after syncconf
 	newRoutes, err := w.getCurrentPeerRoutes()
 	if err != nil {
@@ -437,7 +548,7 @@ func (w *Writer) updatePeerRoutes(oldRoutes map[string]bool) error {
 	// Remove routes that are no longer needed
 	for oldRoute := range oldRoutes {
 		if !newRoutes[oldRoute] {
-			if err := w.removeRoute(oldRoute); err != nil {
+			if err := w.removeRoute(oldRoute, table); err != nil {
 				log.Warn().Err(err).Str("route", oldRoute).Msg("failed to remove old route")
 			} else {
 				log.Debug().Str("route", oldRoute).Str("interface", w.Interface).Msg("removed old peer route")
@@ -448,7 +559,7 @@ func (w *Writer) updatePeerRoutes(oldRoutes map[string]bool) error {
 	// Add new routes
 	for newRoute := range newRoutes {
 		if !oldRoutes[newRoute] {
-			if err := w.addRoute(newRoute); err != nil {
+			if err := w.addRoute(newRoute, table); err != nil {
 				log.Warn().Err(err).Str("route", newRoute).Msg("failed to add new route")
 			} else {
 				log.Debug().Str("route", newRoute).Str("interface", w.Interface).Msg("added new peer route")
@@ -467,18 +578,14 @@ func (w *Writer) updatePeerRoutes(oldRoutes map[string]bool) error {
 // earlier version that did `strings.Contains(err.Error(), "File exists")` never
 // matched and produced a "failed to add route" warning on every sync once the
 // route was actually installed.
-func (w *Writer) addRoute(allowedIP string) error {
+func (w *Writer) addRoute(allowedIP, table string) error {
 	// Skip routes that are not single host routes (e.g., 0.0.0.0/0, ::/0)
 	if strings.HasSuffix(allowedIP, "/0") {
 		log.Debug().Str("allowed_ip", allowedIP).Msg("skipping default route")
 		return nil
 	}
 
-	args := []string{"route", "add", allowedIP, "dev", w.Interface}
-	if strings.Contains(allowedIP, ":") {
-		args = append([]string{"-6"}, args...) // IPv6
-	}
-
+	args := routeArgs("add", allowedIP, w.Interface, table)
 	cmd := exec.Command("ip", args...) // #nosec G204 - parameters are controlled
 	var stderr bytes.Buffer
 	cmd.Stderr = &stderr
@@ -496,17 +603,13 @@ func (w *Writer) addRoute(allowedIP string) error {
 
 // removeRoute removes a route for a peer's allowed IP.  Same family-aware
 // dispatch and stderr-based idempotency check as addRoute.
-func (w *Writer) removeRoute(allowedIP string) error {
+func (w *Writer) removeRoute(allowedIP, table string) error {
 	if strings.HasSuffix(allowedIP, "/0") {
 		log.Debug().Str("allowed_ip", allowedIP).Msg("skipping default route removal")
 		return nil
 	}
 
-	args := []string{"route", "del", allowedIP, "dev", w.Interface}
-	if strings.Contains(allowedIP, ":") {
-		args = append([]string{"-6"}, args...) // IPv6
-	}
-
+	args := routeArgs("del", allowedIP, w.Interface, table)
 	cmd := exec.Command("ip", args...) // #nosec G204 - parameters are controlled
 	var stderr bytes.Buffer
 	cmd.Stderr = &stderr
@@ -526,6 +629,20 @@ func (w *Writer) removeRoute(allowedIP string) error {
 	return nil
 }
 
+// routeArgs builds the `ip route` arguments adding or deleting the route to
+// allowedIP through iface.  A numbered table selects it; "" and "auto" use
+// the main table.
+func routeArgs(verb, allowedIP, iface, table string) []string {
+	args := []string{"route", verb, allowedIP, "dev", iface}
+	if table != "" && table != "auto" {
+		args = append(args, "table", table)
+	}
+	if strings.Contains(allowedIP, ":") {
+		args = append([]string{"-6"}, args...) // IPv6
+	}
+	return args
+}
+
 // RedactKeys redacts PrivateKey values for logging.
 func RedactKeys(cfg string) string {
 	scanner := bufio.NewScanner(strings.NewReader(cfg))
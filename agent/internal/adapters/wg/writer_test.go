@@ -3,6 +3,7 @@ package wg
 import (
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -89,6 +90,36 @@ func TestAddMarkerToConfig(t *testing.T) {
 	}
 }
 
+func TestWithPrivateKey(t *testing.T) {
+	writer := NewWriter("/test/path", "wg0", "wg-quick")
+	config := "[Interface]\n# Name: laptop\nAddress = 10.0.0.2/32\n\n[Peer]\nPublicKey = jump\n"
+
+	if got := writer.withPrivateKey(config); got != config {
+		t.Errorf("expected the config unchanged without a private key, got:\n%s", got)
+	}
+
+	writer.PrivateKey = "device-key"
+	want := "[Interface]\nPrivateKey = device-key\n# Name: laptop\nAddress = 10.0.0.2/32\n\n[Peer]\nPublicKey = jump\n"
+	if got := writer.withPrivateKey(config); got != want {
+		t.Errorf("expected the private key added to [Interface], got:\n%s", got)
+	}
+
+	withKey := "[Interface]\nPrivateKey = server-key\n\n[Peer]\nPublicKey = jump\n"
+	if got := writer.withPrivateKey(withKey); got != withKey {
+		t.Errorf("expected the server's private key kept, got:\n%s", got)
+	}
+
+	// The server's placeholder comment is not a key
+	placeholder := "[Interface]\n# PrivateKey omitted: supplied by the device\nAddress = 10.0.0.2/32\n"
+	want = "[Interface]\nPrivateKey = device-key\n# PrivateKey omitted: supplied by the device\nAddress = 10.0.0.2/32\n"
+	if got := writer.withPrivateKey(placeholder); got != want {
+		t.Errorf("expected the private key added next to the placeholder, got:\n%s", got)
+	}
+	if HasPrivateKey(placeholder) {
+		t.Error("HasPrivateKey() = true for a config with only the placeholder")
+	}
+}
+
 func TestIsWiretyManaged(t *testing.T) {
 	writer := NewWriter("/test/path", "wg0", "wg-quick")
 
@@ -436,20 +467,20 @@ func TestAddAndRemoveRoute(t *testing.T) {
 	writer := NewWriter("/test/path", "wg0", "wg-quick")
 
 	// Test adding route (will likely fail due to permissions, but shouldn't panic)
-	err := writer.addRoute("10.0.0.1/32")
+	err := writer.addRoute("10.0.0.1/32", "")
 	t.Logf("addRoute returned error: %v", err)
 
 	// Test removing route (will likely fail due to permissions, but shouldn't panic)
-	err = writer.removeRoute("10.0.0.1/32")
+	err = writer.removeRoute("10.0.0.1/32", "")
 	t.Logf("removeRoute returned error: %v", err)
 
 	// Test with default route (should be skipped)
-	err = writer.addRoute("0.0.0.0/0")
+	err = writer.addRoute("0.0.0.0/0", "")
 	if err != nil {
 		t.Errorf("Expected no error for default route (should be skipped), got: %v", err)
 	}
 
-	err = writer.removeRoute("0.0.0.0/0")
+	err = writer.removeRoute("0.0.0.0/0", "")
 	if err != nil {
 		t.Errorf("Expected no error for default route removal (should be skipped), got: %v", err)
 	}
@@ -533,6 +564,11 @@ func TestApply(t *testing.T) {
 	err = writer.apply()
 	t.Logf("apply with syncconf returned: %v", err)
 
+	// Test userspace method
+	writer.ApplyMethod = ApplyWireGuardGo
+	err = writer.apply()
+	t.Logf("apply with wireguard-go returned: %v", err)
+
 	// Test invalid method
 	writer.ApplyMethod = "invalid"
 	err = writer.apply()
@@ -667,3 +703,140 @@ func TestRedactKeysWithOnlyPrivateKeys(t *testing.T) {
 		t.Error("Expected secrets to be redacted")
 	}
 }
+
+func TestReadInterfaceSettings(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "wg0.conf")
+	config := WiretyMarker + `
+[Interface]
+PrivateKey = test
+Address = 10.0.0.2/32, fd00::2/128
+MTU = 1380
+DNS = 10.0.0.1
+
+[Peer]
+PublicKey = peer
+Address = 10.9.9.9
+AllowedIPs = 10.0.0.0/24
+`
+	if err := os.WriteFile(configPath, []byte(config), 0600); err != nil {
+		t.Fatalf("Failed to create config file: %v", err)
+	}
+
+	addresses, mtu, err := readInterfaceSettings(configPath)
+	if err != nil {
+		t.Fatalf("readInterfaceSettings returned error: %v", err)
+	}
+	if len(addresses) != 2 || addresses[0] != "10.0.0.2/32" || addresses[1] != "fd00::2/128" {
+		t.Errorf("Expected the two [Interface] addresses, got %v", addresses)
+	}
+	if mtu != "1380" {
+		t.Errorf("Expected MTU '1380', got '%s'", mtu)
+	}
+
+	if _, _, err := readInterfaceSettings(filepath.Join(t.TempDir(), "missing.conf")); err == nil {
+		t.Error("Expected error for missing config file")
+	}
+}
+
+func TestReadRoutingTable(t *testing.T) {
+	dir := t.TempDir()
+	for name, tc := range map[string]struct{ config, want string }{
+		"off":    {"[Interface]\nPrivateKey = test\nTable = off\n\n[Peer]\nPublicKey = peer\n", "off"},
+		"number": {"[Interface]\nTable = 1234\n", "1234"},
+		"unset":  {"[Interface]\nPrivateKey = test\n\n[Peer]\nTable = 99\n", ""},
+	} {
+		configPath := filepath.Join(dir, name+".conf")
+		if err := os.WriteFile(configPath, []byte(tc.config), 0600); err != nil {
+			t.Fatalf("Failed to create config file: %v", err)
+		}
+		if got := readRoutingTable(configPath); got != tc.want {
+			t.Errorf("%s: readRoutingTable = %q, want %q", name, got, tc.want)
+		}
+	}
+
+	if got := readRoutingTable(filepath.Join(dir, "missing.conf")); got != "" {
+		t.Errorf("Expected no table for a missing config file, got %q", got)
+	}
+}
+
+func TestRouteArgs(t *testing.T) {
+	tests := []struct {
+		allowedIP, table string
+		want             []string
+	}{
+		{"10.0.0.2/32", "", []string{"route", "add", "10.0.0.2/32", "dev", "wg0"}},
+		{"10.0.0.2/32", "auto", []string{"route", "add", "10.0.0.2/32", "dev", "wg0"}},
+		{"10.0.0.2/32", "1234", []string{"route", "add", "10.0.0.2/32", "dev", "wg0", "table", "1234"}},
+		{"fd00::2/128", "1234", []string{"-6", "route", "add", "fd00::2/128", "dev", "wg0", "table", "1234"}},
+	}
+	for _, tc := range tests {
+		if got := routeArgs("add", tc.allowedIP, "wg0", tc.table); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("routeArgs(%q, %q) = %v, want %v", tc.allowedIP, tc.table, got, tc.want)
+		}
+	}
+}
+
+func TestKernelModuleMissing(t *testing.T) {
+	orig := wireguardModulePath
+	t.Cleanup(func() { wireguardModulePath = orig })
+
+	wireguardModulePath = t.TempDir()
+	if kernelModuleMissing() {
+		t.Error("Expected module to be reported present")
+	}
+
+	wireguardModulePath = filepath.Join(t.TempDir(), "wireguard")
+	if !kernelModuleMissing() {
+		t.Error("Expected module to be reported missing")
+	}
+}
+
+func TestWriteAndApplyRollsBackOnFailure(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "wg0.conf")
+	writer := NewWriter(configPath, "wg0", ApplyWGQuick)
+
+	// Stand in for wg-quick: "up" loads the config file into the interface
+	// unless it carries an invalid key.
+	var iface string
+	origRun := run
+	t.Cleanup(func() { run = origRun })
+	run = func(cmd string, args ...string) error {
+		if cmd != "wg-quick" || len(args) != 2 || args[0] != "up" {
+			return nil
+		}
+		content, err := os.ReadFile(args[1])
+		if err != nil {
+			return err
+		}
+		if strings.Contains(string(content), "PrivateKey = invalid") {
+			return &os.PathError{Op: "wg-quick up", Path: args[1], Err: os.ErrInvalid}
+		}
+		iface = string(content)
+		return nil
+	}
+
+	good := "[Interface]\nPrivateKey = good\n"
+	if err := writer.WriteAndApply(good); err != nil {
+		t.Fatalf("WriteAndApply(good) returned error: %v", err)
+	}
+	applied := iface
+
+	err := writer.WriteAndApply("[Interface]\nPrivateKey = invalid\n")
+	if err == nil {
+		t.Fatal("Expected WriteAndApply to return the apply error for a malformed config")
+	}
+	if !strings.Contains(err.Error(), "wg-quick up") {
+		t.Errorf("Expected the original apply error, got: %v", err)
+	}
+
+	if iface != applied {
+		t.Errorf("Expected the interface to be restored to the previous config, got:\n%s", iface)
+	}
+	content, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatalf("Failed to read config file: %v", err)
+	}
+	if string(content) != applied {
+		t.Errorf("Expected the previous config on disk, got:\n%s", content)
+	}
+}
@@ -0,0 +1,62 @@
+package agent
+
+import (
+	"math/rand/v2"
+	"time"
+)
+
+// ReconnectBackoff controls how long the agent waits between WebSocket
+// connection attempts.  The delay starts at Base and is multiplied by
+// Multiplier after every attempt, up to Max.  Each wait is jittered to
+// between half and all of the current delay so that agents disconnected by
+// the same server restart do not reconnect in lockstep.
+type ReconnectBackoff struct {
+	Base       time.Duration
+	Max        time.Duration
+	Multiplier float64
+	// ResetAfter is how long a connection must stay up before the delay
+	// drops back to Base.  Shorter-lived connections keep growing it, so a
+	// server that accepts and immediately drops the agent is not hammered.
+	ResetAfter time.Duration
+}
+
+// DefaultReconnectBackoff returns the built-in reconnect backoff.
+func DefaultReconnectBackoff() ReconnectBackoff {
+	return ReconnectBackoff{
+		Base:       time.Second,
+		Max:        30 * time.Second,
+		Multiplier: 2,
+		ResetAfter: time.Minute,
+	}
+}
+
+// next returns the delay that follows d.
+func (b ReconnectBackoff) next(d time.Duration) time.Duration {
+	n := time.Duration(float64(d) * b.Multiplier)
+	if n > b.Max || n <= 0 {
+		return b.Max
+	}
+	return n
+}
+
+// jitter returns a random wait in [d/2, d].
+func (b ReconnectBackoff) jitter(d time.Duration) time.Duration {
+	half := d / 2
+	if half <= 0 {
+		return d
+	}
+	return half + rand.N(half+1)
+}
+
+// sleepOrStop waits for d and reports true, or returns false as soon as stop
+// is closed.
+func sleepOrStop(stop <-chan struct{}, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-stop:
+		return false
+	case <-t.C:
+		return true
+	}
+}
@@ -0,0 +1,61 @@
+package agent
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReconnectBackoffNext(t *testing.T) {
+	b := ReconnectBackoff{Base: time.Second, Max: 10 * time.Second, Multiplier: 3}
+
+	tests := []struct {
+		in, want time.Duration
+	}{
+		{time.Second, 3 * time.Second},
+		{3 * time.Second, 9 * time.Second},
+		{9 * time.Second, 10 * time.Second}, // capped
+		{10 * time.Second, 10 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := b.next(tt.in); got != tt.want {
+			t.Errorf("next(%v) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestReconnectBackoffJitter(t *testing.T) {
+	b := DefaultReconnectBackoff()
+	d := 8 * time.Second
+	for i := 0; i < 1000; i++ {
+		got := b.jitter(d)
+		if got < d/2 || got > d {
+			t.Fatalf("jitter(%v) = %v, want within [%v, %v]", d, got, d/2, d)
+		}
+	}
+	if got := b.jitter(0); got != 0 {
+		t.Errorf("jitter(0) = %v, want 0", got)
+	}
+}
+
+func TestStartStopInterruptsBackoff(t *testing.T) {
+	wsClient := &mockWebSocketClient{connectErr: &mockError{"connection refused"}}
+	runner := NewRunner(wsClient, &mockConfigWriter{}, &mockDNSServer{}, &mockFirewall{}, "ws://localhost:8080", "wg0", "", "")
+	runner.SetReconnectBackoff(ReconnectBackoff{Base: time.Hour, Max: time.Hour, Multiplier: 2, ResetAfter: time.Minute})
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		runner.Start(stop)
+		close(done)
+	}()
+
+	// Let the first attempt fail so the runner is sleeping
+	time.Sleep(20 * time.Millisecond)
+	close(stop)
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Start did not return after stop was closed during backoff")
+	}
+}
@@ -0,0 +1,145 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// jumpHealthProbeAddr is dialled through each NAT interface to check the
+// jump's internet access.
+const jumpHealthProbeAddr = "1.1.1.1:443"
+
+const jumpHealthDialTimeout = 3 * time.Second
+
+// JumpHealthReport is sent back to the server when it asks a jump agent for
+// a health check.
+type JumpHealthReport struct {
+	Type         string `json:"type"` // always "jump_health"
+	ListenPortOK bool   `json:"listen_port_ok"`
+	InternetOK   bool   `json:"internet_ok"`
+	Reason       string `json:"reason,omitempty"`
+}
+
+// jumpHealthChecker runs the jump health checks.  The probes are fields so
+// tests can replace them.
+type jumpHealthChecker struct {
+	listenPort    func(iface string) int
+	bindUDP       func(port int) error
+	dialInterface func(iface string) error
+}
+
+func newJumpHealthChecker() *jumpHealthChecker {
+	return &jumpHealthChecker{
+		listenPort:    getWireGuardListenPort,
+		bindUDP:       bindUDP,
+		dialInterface: dialFromInterface,
+	}
+}
+
+// check reports whether WireGuard holds the listen port of iface and whether
+// the internet is reachable through any of natIfaces.
+func (c *jumpHealthChecker) check(iface string, natIfaces []string) JumpHealthReport {
+	report := JumpHealthReport{Type: "jump_health"}
+	var reasons []string
+
+	switch port := c.listenPort(iface); {
+	case port == 0:
+		reasons = append(reasons, fmt.Sprintf("%s has no listen port", iface))
+	case c.bindUDP(port) == nil:
+		// Nothing holds the port, so WireGuard is not listening on it.
+		reasons = append(reasons, fmt.Sprintf("UDP port %d is not bound by %s", port, iface))
+	default:
+		report.ListenPortOK = true
+	}
+
+	if len(natIfaces) == 0 {
+		reasons = append(reasons, "no NAT interface")
+	}
+	var dialErrs []string
+	for _, nat := range natIfaces {
+		err := c.dialInterface(nat)
+		if err == nil {
+			report.InternetOK = true
+			break
+		}
+		dialErrs = append(dialErrs, fmt.Sprintf("%s: %v", nat, err))
+	}
+	if !report.InternetOK && len(dialErrs) > 0 {
+		reasons = append(reasons, "no internet access via "+strings.Join(dialErrs, ", "))
+	}
+
+	report.Reason = strings.Join(reasons, "; ")
+	return report
+}
+
+// bindUDP binds and releases a UDP port; it fails when the port is in use.
+func bindUDP(port int) error {
+	conn, err := net.ListenPacket("udp", ":"+strconv.Itoa(port))
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// dialFromInterface opens a TCP connection to jumpHealthProbeAddr from the
+// IPv4 address of iface.
+func dialFromInterface(iface string) error {
+	ifi, err := net.InterfaceByName(iface)
+	if err != nil {
+		return err
+	}
+	addrs, err := ifi.Addrs()
+	if err != nil {
+		return err
+	}
+	var local net.IP
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.To4() != nil {
+			local = ipNet.IP
+			break
+		}
+	}
+	if local == nil {
+		return fmt.Errorf("no IPv4 address")
+	}
+	dialer := net.Dialer{LocalAddr: &net.TCPAddr{IP: local}, Timeout: jumpHealthDialTimeout}
+	conn, err := dialer.Dial("tcp4", jumpHealthProbeAddr)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// runJumpHealthCheck answers a health check request from the server.  The
+// checks can take seconds, so they run outside the read loop and the report
+// is handed to the heartbeat goroutine, the connection's only writer.
+func (r *Runner) runJumpHealthCheck() {
+	type natInterfacer interface {
+		NATInterfaces() []string
+	}
+	var natIfaces []string
+	if fw, ok := r.fwAdapter.(natInterfacer); ok {
+		natIfaces = fw.NATInterfaces()
+	}
+
+	report := r.jumpHealth.check(r.getInterface(), natIfaces)
+	if report.Reason != "" {
+		log.Warn().Str("reason", report.Reason).Msg("jump health check failed")
+	}
+	data, err := json.Marshal(report)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to marshal jump health report")
+		return
+	}
+	select {
+	case r.outgoing <- data:
+	default:
+		log.Debug().Msg("previous jump health report not sent yet; dropping this one")
+	}
+}
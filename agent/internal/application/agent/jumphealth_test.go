@@ -0,0 +1,75 @@
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestJumpHealthChecker(t *testing.T) {
+	errInUse := errors.New("address already in use")
+	tests := []struct {
+		name         string
+		port         int
+		bindErr      error
+		reachable    map[string]bool
+		natIfaces    []string
+		wantPortOK   bool
+		wantInternet bool
+		wantReason   string
+	}{
+		{name: "healthy", port: 51820, bindErr: errInUse, reachable: map[string]bool{"eth1": true}, natIfaces: []string{"eth0", "eth1"}, wantPortOK: true, wantInternet: true},
+		{name: "interface down", port: 0, reachable: map[string]bool{"eth0": true}, natIfaces: []string{"eth0"}, wantInternet: true, wantReason: "has no listen port"},
+		{name: "port not held by wireguard", port: 51820, reachable: map[string]bool{"eth0": true}, natIfaces: []string{"eth0"}, wantInternet: true, wantReason: "UDP port 51820 is not bound"},
+		{name: "no internet", port: 51820, bindErr: errInUse, natIfaces: []string{"eth0"}, wantPortOK: true, wantReason: "no internet access via eth0"},
+		{name: "no NAT interface", port: 51820, bindErr: errInUse, wantPortOK: true, wantReason: "no NAT interface"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &jumpHealthChecker{
+				listenPort: func(string) int { return tt.port },
+				bindUDP:    func(int) error { return tt.bindErr },
+				dialInterface: func(iface string) error {
+					if tt.reachable[iface] {
+						return nil
+					}
+					return errors.New("timeout")
+				},
+			}
+			got := c.check("wg0", tt.natIfaces)
+			if got.Type != "jump_health" || got.ListenPortOK != tt.wantPortOK || got.InternetOK != tt.wantInternet {
+				t.Errorf("got %+v, want listen_port_ok=%v internet_ok=%v", got, tt.wantPortOK, tt.wantInternet)
+			}
+			if tt.wantReason == "" && got.Reason != "" || !strings.Contains(got.Reason, tt.wantReason) {
+				t.Errorf("reason = %q, want it to mention %q", got.Reason, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestRunJumpHealthCheck_QueuesReport(t *testing.T) {
+	r := NewRunner(nil, nil, nil, nil, "", "wg0", "", "")
+	r.jumpHealth = &jumpHealthChecker{
+		listenPort:    func(string) int { return 51820 },
+		bindUDP:       func(int) error { return errors.New("in use") },
+		dialInterface: func(string) error { return nil },
+	}
+
+	r.runJumpHealthCheck()
+	var report JumpHealthReport
+	if err := json.Unmarshal(<-r.outgoing, &report); err != nil {
+		t.Fatalf("unmarshal report: %v", err)
+	}
+	// No firewall adapter means no NAT interface to check.
+	if !report.ListenPortOK || report.InternetOK || report.Reason != "no NAT interface" {
+		t.Errorf("unexpected report %+v", report)
+	}
+
+	// A report still waiting to be sent is not piled up on.
+	r.runJumpHealthCheck()
+	r.runJumpHealthCheck()
+	if len(r.outgoing) != 1 {
+		t.Errorf("expected one queued report, got %d", len(r.outgoing))
+	}
+}
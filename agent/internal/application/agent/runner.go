@@ -2,6 +2,8 @@ package agent
 
 import (
 	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"net"
@@ -10,10 +12,12 @@ import (
 	"regexp"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 	"wirety/agent/internal/adapters/captiveportal"
 	dom "wirety/agent/internal/domain/dns"
 	pol "wirety/agent/internal/domain/policy"
+	"wirety/agent/internal/metrics"
 	"wirety/agent/internal/ports"
 
 	"github.com/rs/zerolog/log"
@@ -29,6 +33,7 @@ import (
 
 type WSMessage struct {
 	Config      string          `json:"config"`
+	PeerDelta   *PeerDelta      `json:"peer_delta,omitempty"`
 	DNS         *dom.DNSConfig  `json:"dns,omitempty"`
 	Policy      *pol.JumpPolicy `json:"policy,omitempty"`
 	PeerID      string          `json:"peer_id,omitempty"`
@@ -41,6 +46,20 @@ type WSMessage struct {
 	EndpointDenylist []EndpointDenylistEntry `json:"endpoint_denylist,omitempty"`
 	Quarantined      []string                `json:"quarantined,omitempty"`
 	PeerRoutes       map[string][]string     `json:"peer_routes,omitempty"` // wgIP -> AllowedIPs
+
+	// HealthCheck asks a jump agent to check its listen port and internet
+	// access and reply with a JumpHealthReport.
+	HealthCheck bool `json:"health_check,omitempty"`
+}
+
+// PeerDelta mirrors the server-side type: the [Peer] sections that changed
+// since the config the server sent before, hashed in Base.  Config still
+// carries the full result.
+type PeerDelta struct {
+	Base    string   `json:"base"`
+	Added   []string `json:"added,omitempty"`
+	Changed []string `json:"changed,omitempty"`
+	Removed []string `json:"removed,omitempty"` // public keys
 }
 
 // PendingAuthEntry mirrors the server-side type: a peer that has been issued a
@@ -88,6 +107,48 @@ const tunnelPollInterval = 15 * time.Second
 //     phase, forcing both to re-authenticate via the captive portal.
 const endpointStabilityWindow = 10 * time.Second
 
+// roamingStabilityWindow is the stability window applied to roaming peers.
+// Mobile devices hop between Wi-Fi and cellular all day; holding them out of
+// the whitelist for the full endpointStabilityWindow on every hop would make
+// the VPN feel broken.
+const roamingStabilityWindow = 3 * time.Second
+
+// roamingFlipsRequired is how many stored→foreign flips a roaming peer must
+// make within its flip window before the foreign source is reported as a
+// takeover.  A phone moving back and forth between two networks legitimately
+// produces a couple of flips, so roaming peers need more evidence than static
+// ones.
+const roamingFlipsRequired = 4
+
+// EndpointSensitivity controls how strictly the jump peer reacts to a peer's
+// endpoint changes.
+type EndpointSensitivity struct {
+	// StabilityWindow is how long a new endpoint must hold before the peer is
+	// re-admitted to the iptables whitelist.
+	StabilityWindow time.Duration
+	// FlipsRequired is how many stored→foreign flips within FlipWindow are
+	// treated as a takeover.
+	FlipsRequired int
+	// FlipWindow is how long counted flips are remembered.
+	FlipWindow time.Duration
+}
+
+// DefaultEndpointSensitivity returns the built-in sensitivities for roaming
+// (agent-managed) peers and static peers.
+func DefaultEndpointSensitivity() (roaming, static EndpointSensitivity) {
+	roaming = EndpointSensitivity{
+		StabilityWindow: roamingStabilityWindow,
+		FlipsRequired:   roamingFlipsRequired,
+		FlipWindow:      flipDetectionWindow,
+	}
+	static = EndpointSensitivity{
+		StabilityWindow: endpointStabilityWindow,
+		FlipsRequired:   flipsRequiredForDenylist,
+		FlipWindow:      flipDetectionWindow,
+	}
+	return roaming, static
+}
+
 type Runner struct {
 	wsClient          ports.WebSocketClientPort
 	cfgWriter         ports.ConfigWriterPort
@@ -100,13 +161,20 @@ type Runner struct {
 	wgIP              string // WireGuard interface IPv4 of this peer
 	wgIPv6            string // WireGuard interface IPv6 of this peer (optional, dual-stack)
 	currentPeerName   string // Track current peer name to detect changes
+	interfaceNamer    func(peerName string) string
 	peerID            string // for audit logging
 	networkID         string // for audit logging
+	firewallBackend   string // reported in heartbeats so the server can format rules
 	// peerNames maps WireGuard public key → peer name (updated on each WSMessage).
 	peerNames   map[string]string
 	peerNamesMu sync.RWMutex
-	backoffBase       time.Duration
-	backoffMax        time.Duration
+	// lastConfig is the last WireGuard config applied from the server; DNS-only
+	// updates match their peers against it.  Only touched by the read loop.
+	lastConfig        string
+	// isJump is set once the server sends a DNS config, which only jump peers
+	// receive.  Jump agents measure the latency to their peers.
+	isJump            atomic.Bool
+	backoff           ReconnectBackoff
 	heartbeatInterval time.Duration
 	// Captive portal HTTP server (jump peer only)
 	serverURL        string
@@ -143,6 +211,13 @@ type Runner struct {
 	// respective public IP:port combinations.
 	endpointChangedAt   map[string]time.Time
 	endpointChangedMu   sync.RWMutex
+	// roamingPeers holds the WireGuard IPs of agent-managed peers, taken from
+	// the jump policy.  They get roamingSensitivity instead of
+	// staticSensitivity when their endpoint changes.
+	roamingPeers       map[string]bool
+	roamingPeersMu     sync.RWMutex
+	roamingSensitivity EndpointSensitivity
+	staticSensitivity  EndpointSensitivity
 	// captivePortalSrv is the running captive portal HTTP server (jump peer only).
 	// Set once by startCaptivePortalServer; protected by captivePortalSrvMu.
 	captivePortalSrv   *captiveportal.Server
@@ -188,6 +263,20 @@ type Runner struct {
 	// server can decide whether to redirect external queries from this peer.
 	localAllowedIPs   []string
 	localAllowedIPsMu sync.RWMutex
+	// wsConnected / lastApplyErr back the /healthz endpoint: the agent is
+	// healthy while the WebSocket is up and the last config apply succeeded.
+	wsConnected  bool
+	lastApplyErr error
+	healthMu     sync.RWMutex
+
+	// jumpHealth runs the checks the server requests from jump agents; the
+	// reports wait in outgoing until the heartbeat goroutine writes them.
+	jumpHealth *jumpHealthChecker
+	outgoing   chan []byte
+
+	// configAcks holds the ack of the latest applied config until the
+	// heartbeat goroutine writes it.
+	configAcks chan []byte
 }
 
 // endpointTakeoverReport is the agent-internal mirror of
@@ -243,26 +332,32 @@ type takeoverFlipState struct {
 }
 
 func NewRunner(wsClient ports.WebSocketClientPort, writer ports.ConfigWriterPort, dnsServer ports.DNSStarterPort, fwAdapter ports.FirewallPort, wsURL string, wgInterface string, peerID string, networkID string) *Runner {
+	roaming, static := DefaultEndpointSensitivity()
 	return &Runner{
-		wsClient:          wsClient,
-		cfgWriter:         writer,
-		dnsServer:         dnsServer,
-		fwAdapter:         fwAdapter,
-		wsURL:             wsURL,
-		wgInterface:       wgInterface,
-		currentPeerName:   "",
-		peerID:            peerID,
-		networkID:         networkID,
-		peerNames:         make(map[string]string),
-		whitelist:         make(map[string]string),
-		ipv4ToIPv6:        make(map[string]string),
-		wgIPToEndpoint:    make(map[string]string),
-		endpointChangedAt: make(map[string]time.Time),
-		reportedTakeovers: make(map[string]time.Time),
-		takeoverFlips:     make(map[string]*takeoverFlipState),
-		backoffBase:       time.Second,
-		backoffMax:        30 * time.Second,
-		heartbeatInterval: 30 * time.Second,
+		wsClient:           wsClient,
+		cfgWriter:          writer,
+		dnsServer:          dnsServer,
+		fwAdapter:          fwAdapter,
+		wsURL:              wsURL,
+		wgInterface:        wgInterface,
+		currentPeerName:    "",
+		peerID:             peerID,
+		networkID:          networkID,
+		peerNames:          make(map[string]string),
+		whitelist:          make(map[string]string),
+		ipv4ToIPv6:         make(map[string]string),
+		wgIPToEndpoint:     make(map[string]string),
+		endpointChangedAt:  make(map[string]time.Time),
+		reportedTakeovers:  make(map[string]time.Time),
+		takeoverFlips:      make(map[string]*takeoverFlipState),
+		roamingPeers:       make(map[string]bool),
+		roamingSensitivity: roaming,
+		staticSensitivity:  static,
+		backoff:            DefaultReconnectBackoff(),
+		heartbeatInterval:  30 * time.Second,
+		jumpHealth:         newJumpHealthChecker(),
+		outgoing:           make(chan []byte, 1),
+		configAcks:         make(chan []byte, 1),
 	}
 }
 
@@ -279,6 +374,43 @@ func (r *Runner) SetWGIPv6(ip string) {
 	r.wgIPv6 = ip
 }
 
+// SetEndpointSensitivity overrides the endpoint-change sensitivity for roaming
+// (agent-managed) and static peers.  Must be called before Start.
+func (r *Runner) SetEndpointSensitivity(roaming, static EndpointSensitivity) {
+	r.roamingSensitivity = roaming
+	r.staticSensitivity = static
+}
+
+// SetReconnectBackoff overrides the WebSocket reconnect backoff.  Must be
+// called before Start.
+func (r *Runner) SetReconnectBackoff(b ReconnectBackoff) {
+	r.backoff = b
+}
+
+// updateRoamingPeers records which peers of the jump policy are agent-managed.
+func (r *Runner) updateRoamingPeers(peers []pol.Peer) {
+	m := make(map[string]bool, len(peers))
+	for _, p := range peers {
+		if p.UseAgent && p.IP != "" {
+			m[p.IP] = true
+		}
+	}
+	r.roamingPeersMu.Lock()
+	r.roamingPeers = m
+	r.roamingPeersMu.Unlock()
+}
+
+// sensitivityFor returns the endpoint-change sensitivity for a WireGuard IP.
+func (r *Runner) sensitivityFor(wgIP string) EndpointSensitivity {
+	r.roamingPeersMu.RLock()
+	roaming := r.roamingPeers[wgIP]
+	r.roamingPeersMu.RUnlock()
+	if roaming {
+		return r.roamingSensitivity
+	}
+	return r.staticSensitivity
+}
+
 // extractEndpointIP returns the host portion of an "ip:port" or "[ipv6]:port"
 // endpoint string, or the input unchanged if it doesn't parse.  Used to compare
 // peer endpoints at IP granularity only — NAT port rebinds shouldn't kick a
@@ -385,7 +517,7 @@ func (r *Runner) updateWGIPEndpointMap() {
 				Str("wg_ip", ip).
 				Str("old_endpoint", oldEP).
 				Str("new_endpoint", newEP).
-				Dur("stability_window", endpointStabilityWindow).
+				Dur("stability_window", r.sensitivityFor(ip).StabilityWindow).
 				Msg("WireGuard endpoint changed — stability window started; peer temporarily removed from iptables whitelist")
 
 			// If this wgIP is currently authenticated AND the new endpoint is
@@ -465,7 +597,8 @@ func (r *Runner) updateWhitelist(ips []string) {
 //     compared: NAT rebinds change the source port frequently and a legitimate
 //     peer would otherwise lose its whitelist entry on every reconnect.
 //  2. Stability window — even when the IP matches, the peer is excluded if
-//     its endpoint (IP or port) changed within the last endpointStabilityWindow.
+//     its endpoint (IP or port) changed within the last stability window
+//     (endpointStabilityWindow for static peers, shorter for roaming ones).
 //     This prevents two devices sharing the same WireGuard private key from
 //     getting intermittent iptables access: while they oscillate the jump
 //     peer's recorded endpoint every ~25 s (WireGuard keepalive interval),
@@ -503,16 +636,18 @@ func (r *Runner) filterWhitelistByEndpoint(entries []string) []string {
 			continue
 		}
 
-		// Check 2: endpoint must have been stable for at least endpointStabilityWindow.
+		// Check 2: endpoint must have been stable for at least the peer's
+		// stability window.
+		window := r.sensitivityFor(wgIP).StabilityWindow
 		r.endpointChangedMu.RLock()
 		changedAt, hasRecentChange := r.endpointChangedAt[wgIP]
 		r.endpointChangedMu.RUnlock()
-		if hasRecentChange && now.Sub(changedAt) < endpointStabilityWindow {
+		if hasRecentChange && now.Sub(changedAt) < window {
 			log.Debug().
 				Str("wg_ip", wgIP).
 				Str("endpoint", currentEP).
 				Dur("stable_for", now.Sub(changedAt)).
-				Dur("required", endpointStabilityWindow).
+				Dur("required", window).
 				Msg("endpoint recently changed — holding peer out of iptables whitelist until stable")
 			continue
 		}
@@ -605,7 +740,10 @@ func (r *Runner) SetCaptivePortal(serverURL, authToken, captivePortalURL string,
 }
 
 func (r *Runner) Start(stop <-chan struct{}) {
-	backoff := r.backoffBase
+	delay := r.backoff.Base
+	attempt := 0
+	connectedBefore := false
+	defer r.setWSConnected(false)
 	for {
 		select {
 		case <-stop:
@@ -613,21 +751,24 @@ func (r *Runner) Start(stop <-chan struct{}) {
 			return
 		default:
 		}
+		attempt++
 		if err := r.wsClient.Connect(r.wsURL, r.wsHeaders); err != nil {
-			log.Error().Err(err).Dur("retry", backoff).Msg("websocket connect failed")
-			select {
-			case <-stop:
+			wait := r.backoff.jitter(delay)
+			log.Error().Err(err).Int("attempt", attempt).Dur("retry", wait).Msg("websocket connect failed")
+			if !sleepOrStop(stop, wait) {
+				log.Info().Msg("agent runner stopping")
 				return
-			case <-time.After(backoff):
-			}
-			backoff *= 2
-			if backoff > r.backoffMax {
-				backoff = r.backoffMax
 			}
+			delay = r.backoff.next(delay)
 			continue
 		}
-		backoff = r.backoffBase
-		log.Info().Str("url", r.wsURL).Msg("websocket connected")
+		connectedAt := time.Now()
+		log.Info().Str("url", r.wsURL).Int("attempt", attempt).Msg("websocket connected")
+		if connectedBefore {
+			metrics.WebSocketReconnects.Inc()
+		}
+		connectedBefore = true
+		r.setWSConnected(true)
 
 		// Reset the in-memory whitelist and the policy-received flag on every new
 		// WebSocket connection. The server will push the current state in the first
@@ -696,6 +837,14 @@ func (r *Runner) Start(stop <-chan struct{}) {
 					if err := r.wsClient.Ping(); err != nil {
 						log.Debug().Err(err).Msg("keepalive ping failed (will retry)")
 					}
+				case data := <-r.outgoing:
+					if err := r.wsClient.WriteMessage(data); err != nil {
+						log.Debug().Err(err).Msg("failed to send jump health report")
+					}
+				case data := <-r.configAcks:
+					if err := r.wsClient.WriteMessage(data); err != nil {
+						log.Debug().Err(err).Msg("failed to send config ack")
+					}
 				case <-heartbeatTicker.C:
 					// Regular heartbeat every 30 seconds
 					r.sendHeartbeat()
@@ -770,6 +919,7 @@ func (r *Runner) Start(stop <-chan struct{}) {
 			msgBytes, err := r.wsClient.ReadMessage()
 			if err != nil {
 				log.Error().Err(err).Msg("websocket read error; reconnecting")
+				r.setWSConnected(false)
 				close(heartbeatDone)
 				heartbeatWg.Wait() // Wait for heartbeat goroutine to finish
 				_ = r.wsClient.Close()
@@ -781,6 +931,12 @@ func (r *Runner) Start(stop <-chan struct{}) {
 				continue
 			}
 
+			// A health check request carries nothing else.
+			if payload.HealthCheck {
+				go r.runJumpHealthCheck()
+				continue
+			}
+
 			// Handle peer name changes
 			if payload.PeerName != "" {
 				if err := r.handlePeerNameChange(payload.PeerName); err != nil {
@@ -789,15 +945,31 @@ func (r *Runner) Start(stop <-chan struct{}) {
 				}
 			}
 
+			// A message without a WireGuard config is a DNS-only update sent
+			// when just the jump's DNS records changed; the interface is left
+			// untouched and names are matched against the last applied config.
+			wgConfig := payload.Config
+			dnsOnly := wgConfig == ""
+			if dnsOnly {
+				wgConfig = r.lastConfig
+			}
+
 			// Update pubkey → name map and IPv4→IPv6 address mapping from DNS peers.
 			if payload.DNS != nil {
-				r.updatePeerNames(payload.Config, payload.DNS.Peers)
+				r.isJump.Store(true)
+				r.updatePeerNames(wgConfig, payload.DNS.Peers)
 				r.updateIPv4ToIPv6Map(payload.DNS.Peers)
 			}
 
-			if err := r.cfgWriter.WriteAndApply(payload.Config); err != nil {
-				log.Error().Err(err).Msg("failed applying config")
+			if dnsOnly {
+				log.Debug().Msg("DNS-only update; WireGuard config unchanged")
+			} else if applyErr := r.applyConfig(payload.Config, payload.PeerDelta); applyErr != nil {
+				r.RecordConfigApply(payload.Config, applyErr)
+				log.Error().Err(applyErr).Msg("failed applying config")
 			} else {
+				r.RecordConfigApply(payload.Config, nil)
+				r.lastConfig = payload.Config
+				r.sendConfigAck(payload.Config)
 				log.Debug().Msg("config applied")
 				// Refresh the local AllowedIPs cache so the next heartbeat
 				// reports them to the server (used by the jump peer's DNS to
@@ -842,6 +1014,22 @@ func (r *Runner) Start(stop <-chan struct{}) {
 						r.dnsServer.SetUpstreamServers(payload.DNS.UpstreamServers)
 					}
 				}
+				// Always replaced, so forwarders removed on the server go away
+				type dnsForwarderConfigurer interface {
+					SetConditionalForwarders(map[string][]string)
+				}
+				if fw, ok := r.dnsServer.(dnsForwarderConfigurer); ok {
+					fw.SetConditionalForwarders(payload.DNS.ConditionalForwarders)
+				}
+				type dnsSearchConfigurer interface {
+					SetSearchDomains([]string)
+				}
+				if sd, ok := r.dnsServer.(dnsSearchConfigurer); ok {
+					sd.SetSearchDomains(payload.DNS.SearchDomains)
+				}
+				if ql, ok := r.dnsServer.(dnsQueryLogger); ok {
+					ql.SetQueryLog(payload.DNS.QueryLog)
+				}
 				r.dnsServerMu.Unlock()
 			}
 
@@ -896,6 +1084,8 @@ func (r *Runner) Start(stop <-chan struct{}) {
 				// Cache the policy + raw whitelist so resyncFirewall() (called from
 				// the 300 ms ticker) can re-apply iptables when a peer's endpoint
 				// changes between server pushes.
+				r.updateRoamingPeers(payload.Policy.Peers)
+
 				r.lastSyncMu.Lock()
 				r.lastPolicy = payload.Policy
 				r.lastWhitelistRaw = whitelistedIPs
@@ -975,6 +1165,21 @@ func (r *Runner) Start(stop <-chan struct{}) {
 				}
 			}
 		}
+
+		// Back off before reconnecting too: when the server restarts every
+		// agent loses its connection at once.  A connection that stayed up
+		// long enough counts as healthy and starts over from the base delay.
+		if time.Since(connectedAt) >= r.backoff.ResetAfter {
+			delay = r.backoff.Base
+			attempt = 0
+		}
+		wait := r.backoff.jitter(delay)
+		log.Info().Int("attempt", attempt+1).Dur("delay", wait).Msg("websocket reconnecting")
+		if !sleepOrStop(stop, wait) {
+			log.Info().Msg("agent runner stopping")
+			return
+		}
+		delay = r.backoff.next(delay)
 	}
 }
 
@@ -1003,7 +1208,8 @@ func (r *Runner) Start(stop <-chan struct{}) {
 // transitions inside flipDetectionWindow (60 s), the most recent foreign
 // endpoint is queued as a takeover report.  Then we reset and start fresh —
 // if a NEW rogue source shows up later it has to earn its denylist entry the
-// same way.
+// same way.  Roaming (agent-managed) peers use their own, more tolerant
+// thresholds — see EndpointSensitivity.
 //
 // Must be called with r.endpointChangedMu held.
 func (r *Runner) queueTakeoverIfRogue(wgIP, newEP string) {
@@ -1016,6 +1222,8 @@ func (r *Runner) queueTakeoverIfRogue(wgIP, newEP string) {
 		return
 	}
 
+	sens := r.sensitivityFor(wgIP)
+
 	r.takeoverFlipsMu.Lock()
 	state, ok := r.takeoverFlips[wgIP]
 	if !ok {
@@ -1030,7 +1238,7 @@ func (r *Runner) queueTakeoverIfRogue(wgIP, newEP string) {
 	// Forget stale flips: if the first counted flip was longer ago than the
 	// detection window, reset the counter — a rebind that happened a minute
 	// ago and has held since is, by definition, a legitimate roam.
-	if !state.firstFlipAt.IsZero() && now.Sub(state.firstFlipAt) > flipDetectionWindow {
+	if !state.firstFlipAt.IsZero() && now.Sub(state.firstFlipAt) > sens.FlipWindow {
 		state.flipsToForeign = 0
 		state.firstFlipAt = time.Time{}
 	}
@@ -1058,13 +1266,13 @@ func (r *Runner) queueTakeoverIfRogue(wgIP, newEP string) {
 			Str("authenticated_endpoint", storedEP).
 			Str("observed_endpoint", newEP).
 			Int("flips_to_foreign", state.flipsToForeign).
-			Int("required", flipsRequiredForDenylist).
+			Int("required", sens.FlipsRequired).
 			Msg("endpoint flipped from authenticated to foreign — counting toward takeover threshold")
 	}
 	state.lastWasStored = false
 	state.lastForeignEP = newEP
 
-	if state.flipsToForeign < flipsRequiredForDenylist {
+	if state.flipsToForeign < sens.FlipsRequired {
 		// One flip: indistinguishable from a NAT rebind / roam.  Don't denylist.
 		// The stability window (10 s) plus the captive portal flow take care
 		// of the rest: if the legitimate user just moved networks they will
@@ -1130,6 +1338,25 @@ func (r *Runner) applyPeerRoutesToDNS(routes map[string][]string) {
 	}
 }
 
+// dnsQueryLogger is implemented by DNS servers that can record the queries
+// they receive.
+type dnsQueryLogger interface {
+	SetQueryLog(enabled bool)
+	DrainQueryLog() []dom.QueryLogEntry
+}
+
+// drainDNSQueries returns the queries the DNS server logged since the
+// previous heartbeat; nil when this agent serves no DNS or does not log.
+func (r *Runner) drainDNSQueries() []dom.QueryLogEntry {
+	r.dnsServerMu.Lock()
+	dns := r.dnsServer
+	r.dnsServerMu.Unlock()
+	if ql, ok := dns.(dnsQueryLogger); ok {
+		return ql.DrainQueryLog()
+	}
+	return nil
+}
+
 // drainPendingTakeovers atomically returns and clears the takeover queue.
 func (r *Runner) drainPendingTakeovers() []endpointTakeoverReport {
 	r.pendingTakeoversMu.Lock()
@@ -1142,6 +1369,59 @@ func (r *Runner) drainPendingTakeovers() []endpointTakeoverReport {
 	return out
 }
 
+// SetFirewallBackend records the firewall backend detected on this host
+// (see firewall.DetectBackend).  It is reported in every heartbeat.
+func (r *Runner) SetFirewallBackend(backend string) {
+	r.firewallBackend = backend
+}
+
+// RecordConfigApply publishes the outcome of a WireGuard config apply to the
+// agent metrics and the health state.  main calls it for the initial config
+// applied before the runner starts.
+func (r *Runner) RecordConfigApply(cfg string, err error) {
+	r.healthMu.Lock()
+	r.lastApplyErr = err
+	r.healthMu.Unlock()
+
+	if err != nil {
+		metrics.ApplyFailures.Inc()
+		return
+	}
+	metrics.LastApplyTimestamp.SetToCurrentTime()
+	metrics.Peers.Set(float64(countConfigPeers(cfg)))
+}
+
+func (r *Runner) setWSConnected(connected bool) {
+	r.healthMu.Lock()
+	r.wsConnected = connected
+	r.healthMu.Unlock()
+}
+
+// Health returns nil when the WebSocket to the server is connected and the
+// last config apply succeeded, otherwise an error describing the problem.
+func (r *Runner) Health() error {
+	r.healthMu.RLock()
+	defer r.healthMu.RUnlock()
+	if !r.wsConnected {
+		return fmt.Errorf("websocket not connected")
+	}
+	if r.lastApplyErr != nil {
+		return fmt.Errorf("last config apply failed: %w", r.lastApplyErr)
+	}
+	return nil
+}
+
+// countConfigPeers returns the number of [Peer] sections in a WireGuard config.
+func countConfigPeers(cfg string) int {
+	count := 0
+	for _, line := range strings.Split(cfg, "\n") {
+		if strings.EqualFold(strings.TrimSpace(line), "[Peer]") {
+			count++
+		}
+	}
+	return count
+}
+
 // SetLocalAllowedIPs records this peer's locally-configured WireGuard AllowedIPs
 // so they can be reported in every heartbeat.  Called after each successful
 // config apply by parseLocalAllowedIPsFromConfig.
@@ -1234,12 +1514,32 @@ func (r *Runner) sendHeartbeat() {
 		heartbeat["peer_handshakes"] = handshakeUnix
 	}
 
+	// Transfer counters let the server report per-peer bandwidth usage.
+	if transfer := GetWireGuardTransfer(r.getInterface()); len(transfer) > 0 {
+		heartbeat["peer_transfer"] = transfer
+	}
+
+	// Round-trip times to each peer, for the troubleshooting dashboard.
+	if r.isJump.Load() {
+		if latency := MeasurePeerLatency(r.getInterface()); len(latency) > 0 {
+			heartbeat["peer_latency"] = latency
+		}
+	}
+
 	if local := r.getLocalAllowedIPs(); len(local) > 0 {
 		heartbeat["local_allowed_ips"] = local
 	}
 	if len(takeoverWire) > 0 {
 		heartbeat["endpoint_takeovers"] = takeoverWire
 	}
+	if r.firewallBackend != "" {
+		heartbeat["firewall_backend"] = r.firewallBackend
+	}
+	// Logged DNS queries are best-effort troubleshooting data: they are not
+	// re-queued when the heartbeat fails to send.
+	if queries := r.drainDNSQueries(); len(queries) > 0 {
+		heartbeat["dns_queries"] = queries
+	}
 
 	data, err := json.Marshal(heartbeat)
 	if err != nil {
@@ -1266,9 +1566,19 @@ func (r *Runner) sendHeartbeat() {
 	}
 }
 
+// SetInterfaceNamer sets how interface names are derived from peer names when
+// the peer is renamed.  Without it, names are truncated to 15 chars.
+func (r *Runner) SetInterfaceNamer(name func(peerName string) string) {
+	r.interfaceNamer = name
+}
+
 // sanitizeInterfaceName converts a peer name to a valid WireGuard interface name
 // Interface names must be alphanumeric, underscore, or dash, max 15 chars
 func (r *Runner) sanitizeInterfaceName(peerName string) string {
+	if r.interfaceNamer != nil {
+		return r.interfaceNamer(peerName)
+	}
+
 	// Replace invalid characters with underscores
 	re := regexp.MustCompile(`[^a-zA-Z0-9_-]`)
 	sanitized := re.ReplaceAllString(peerName, "_")
@@ -1308,6 +1618,58 @@ func (r *Runner) setInterface(iface string) {
 	r.wgInterface = iface
 }
 
+// applyConfig applies a config from the server.  When the message carries a
+// peer delta against the config last applied here and the writer can patch a
+// live interface, only the changed peers are touched; otherwise, or if
+// patching fails, the full config is applied.
+func (r *Runner) applyConfig(cfg string, delta *PeerDelta) error {
+	patcher, ok := r.cfgWriter.(ports.PeerDeltaPort)
+	if ok && delta != nil && r.lastConfig != "" && delta.Base == configHash(r.lastConfig) {
+		upserts := append(append([]string{}, delta.Added...), delta.Changed...)
+		err := patcher.ApplyPeerDelta(cfg, upserts, delta.Removed)
+		if err == nil {
+			log.Debug().
+				Int("added", len(delta.Added)).
+				Int("changed", len(delta.Changed)).
+				Int("removed", len(delta.Removed)).
+				Msg("peer delta applied")
+			return nil
+		}
+		log.Warn().Err(err).Msg("applying peer delta failed; applying the full config")
+	}
+	return r.cfgWriter.WriteAndApply(cfg)
+}
+
+// configHash matches the server's hash of a config, the base of a peer delta.
+func configHash(cfg string) string {
+	sum := sha256.Sum256([]byte(cfg))
+	return hex.EncodeToString(sum[:])
+}
+
+// ConfigAck tells the server which config the agent applied, so it can
+// confirm the agent converged.
+type ConfigAck struct {
+	Type       string `json:"type"` // always "config_ack"
+	ConfigHash string `json:"config_hash"`
+}
+
+// sendConfigAck acknowledges an applied config.  Like jump health reports,
+// the ack is handed to the heartbeat goroutine, the connection's only writer.
+// An ack not sent yet is superseded: only the latest config matters to the
+// server.  The read loop is the only sender, so the put never blocks.
+func (r *Runner) sendConfigAck(cfg string) {
+	data, err := json.Marshal(ConfigAck{Type: "config_ack", ConfigHash: configHash(cfg)})
+	if err != nil {
+		log.Error().Err(err).Msg("failed to marshal config ack")
+		return
+	}
+	select {
+	case <-r.configAcks:
+	default:
+	}
+	r.configAcks <- data
+}
+
 // handlePeerNameChange detects if the peer name has changed and handles interface transition
 func (r *Runner) handlePeerNameChange(newPeerName string) error {
 	// Skip if no name provided or same as current
@@ -1367,6 +1729,9 @@ func (r *Runner) updatePeerNames(wgConfig string, dnsPeers []dom.DNSPeer) {
 	// Build IP → name from DNS peers.
 	ipToName := make(map[string]string, len(dnsPeers))
 	for _, p := range dnsPeers {
+		if !p.IsAddress() {
+			continue
+		}
 		ip := strings.TrimSuffix(p.IP, "/32")
 		ipToName[ip] = p.Name
 	}
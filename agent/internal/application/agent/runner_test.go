@@ -2,6 +2,7 @@ package agent
 
 import (
 	"encoding/json"
+	"errors"
 	net_http "net/http"
 	"sync"
 	"testing"
@@ -13,10 +14,15 @@ import (
 
 // Mock implementations for testing
 
+// mockWebSocketClient serves the preloaded messages to ReadMessage.  Written
+// messages are appended to messages too, but counted in written so that they
+// are never read back; the runner reads and writes from different goroutines.
 type mockWebSocketClient struct {
+	mu         sync.Mutex
 	url        string
 	connected  bool
 	messages   [][]byte
+	written    int
 	readIndex  int
 	closed     bool
 	connectErr error
@@ -28,6 +34,8 @@ func (m *mockWebSocketClient) Connect(url string, _ net_http.Header) error {
 	if m.connectErr != nil {
 		return m.connectErr
 	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	m.url = url
 	m.connected = true
 	return nil
@@ -37,11 +45,14 @@ func (m *mockWebSocketClient) ReadMessage() ([]byte, error) {
 	if m.readErr != nil {
 		return nil, m.readErr
 	}
-	if m.readIndex < len(m.messages) {
+	m.mu.Lock()
+	if m.readIndex < len(m.messages)-m.written {
 		msg := m.messages[m.readIndex]
 		m.readIndex++
+		m.mu.Unlock()
 		return msg, nil
 	}
+	m.mu.Unlock()
 	// Block indefinitely if no more messages (simulating real WebSocket behavior)
 	select {}
 }
@@ -50,7 +61,10 @@ func (m *mockWebSocketClient) WriteMessage(data []byte) error {
 	if m.writeErr != nil {
 		return m.writeErr
 	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	m.messages = append(m.messages, data)
+	m.written++
 	return nil
 }
 
@@ -62,6 +76,8 @@ func (m *mockWebSocketClient) Ping() error {
 }
 
 func (m *mockWebSocketClient) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	m.closed = true
 	m.connected = false
 	return nil
@@ -248,12 +264,12 @@ func TestNewRunner(t *testing.T) {
 		t.Errorf("Expected wgInterface 'wg0', got '%s'", runner.wgInterface)
 	}
 
-	if runner.backoffBase != time.Second {
-		t.Errorf("Expected backoffBase 1s, got %v", runner.backoffBase)
+	if runner.backoff.Base != time.Second {
+		t.Errorf("Expected backoff base 1s, got %v", runner.backoff.Base)
 	}
 
-	if runner.backoffMax != 30*time.Second {
-		t.Errorf("Expected backoffMax 30s, got %v", runner.backoffMax)
+	if runner.backoff.Max != 30*time.Second {
+		t.Errorf("Expected backoff max 30s, got %v", runner.backoff.Max)
 	}
 
 	if runner.heartbeatInterval != 30*time.Second {
@@ -519,6 +535,44 @@ func TestProcessWSMessage(t *testing.T) {
 	}
 }
 
+func TestProcessWSMessageDNSOnly(t *testing.T) {
+	wsClient := &mockWebSocketClient{}
+	writer := &mockConfigWriter{}
+	dnsServer := &mockDNSServer{}
+
+	runner := NewRunner(wsClient, writer, dnsServer, nil, "ws://localhost:8080", "wg0", "", "")
+
+	// DNS-only updates carry no WireGuard config.
+	msg := WSMessage{
+		PeerID: "jump",
+		DNS: &dom.DNSConfig{
+			IP:     "10.0.0.1",
+			Domain: "example.com",
+			Peers:  []dom.DNSPeer{{Name: "peer1", IP: "10.0.0.2"}, {Name: "api.example.com", IP: "192.168.1.10"}},
+		},
+	}
+	msgBytes, err := json.Marshal(msg)
+	if err != nil {
+		t.Fatalf("Failed to marshal test message: %v", err)
+	}
+	wsClient.messages = [][]byte{msgBytes}
+
+	stop := make(chan struct{})
+	go runner.Start(stop)
+	time.Sleep(50 * time.Millisecond)
+	close(stop)
+
+	if writer.Applied() {
+		t.Error("Expected a DNS-only update not to rewrite the WireGuard config")
+	}
+	if dnsServer.Domain() != "example.com" {
+		t.Errorf("Expected DNS domain 'example.com', got '%s'", dnsServer.Domain())
+	}
+	if len(dnsServer.Peers()) != 2 {
+		t.Errorf("Expected 2 DNS peers, got %d", len(dnsServer.Peers()))
+	}
+}
+
 func TestProcessWSMessageWithErrors(t *testing.T) {
 	wsClient := &mockWebSocketClient{}
 	writer := &mockConfigWriter{writeErr: &mockError{"write failed"}}
@@ -583,3 +637,204 @@ func TestStartWithConnectionError(t *testing.T) {
 
 	// Should not panic despite connection errors
 }
+
+func TestHealth(t *testing.T) {
+	msgBytes, err := json.Marshal(WSMessage{Config: "[Interface]\nPrivateKey = test\n\n[Peer]\nPublicKey = a\n\n[Peer]\nPublicKey = b\n"})
+	if err != nil {
+		t.Fatalf("Failed to marshal test message: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		writer  *mockConfigWriter
+		wantErr string
+	}{
+		{name: "connected and applied", writer: &mockConfigWriter{}},
+		{name: "apply failed", writer: &mockConfigWriter{writeErr: &mockError{"write failed"}}, wantErr: "last config apply failed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wsClient := &mockWebSocketClient{messages: [][]byte{msgBytes}}
+			runner := NewRunner(wsClient, tt.writer, &mockDNSServer{}, &mockFirewall{}, "ws://localhost:8080", "wg0", "", "")
+
+			if err := runner.Health(); err == nil || !contains(err.Error(), "not connected") {
+				t.Fatalf("Expected unhealthy before connecting, got %v", err)
+			}
+
+			stop := make(chan struct{})
+			go runner.Start(stop)
+			time.Sleep(50 * time.Millisecond)
+
+			err := runner.Health()
+			close(stop)
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("Expected healthy runner, got %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !contains(err.Error(), tt.wantErr)) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestCountConfigPeers(t *testing.T) {
+	cfg := "[Interface]\nPrivateKey = x\n\n[Peer]\nPublicKey = a\n\n[peer]\nPublicKey = b\n"
+	if got := countConfigPeers(cfg); got != 2 {
+		t.Errorf("countConfigPeers() = %d, want 2", got)
+	}
+	if got := countConfigPeers(""); got != 0 {
+		t.Errorf("countConfigPeers(\"\") = %d, want 0", got)
+	}
+}
+
+func TestEndpointSensitivityByPeerType(t *testing.T) {
+	const (
+		roamingIP = "10.0.0.2"
+		staticIP  = "10.0.0.3"
+		storedEP  = "203.0.113.1:51820"
+		foreignEP = "198.51.100.7:40000"
+	)
+	runner := NewRunner(&mockWebSocketClient{}, &mockConfigWriter{}, &mockDNSServer{}, nil, "ws://test", "wg0", "", "")
+	runner.updateRoamingPeers([]pol.Peer{
+		{ID: "phone", IP: roamingIP, UseAgent: true},
+		{ID: "server", IP: staticIP},
+	})
+	runner.updateWhitelist([]string{roamingIP + "@" + storedEP, staticIP + "@" + storedEP})
+
+	// Bounce each peer's endpoint between the authenticated and a foreign
+	// source; every bounce is one stored→foreign flip.
+	flip := func(wgIP string, times int) {
+		for i := 0; i < times; i++ {
+			runner.queueTakeoverIfRogue(wgIP, foreignEP)
+			runner.queueTakeoverIfRogue(wgIP, storedEP)
+		}
+	}
+	reported := func(wgIP string) bool {
+		runner.pendingTakeoversMu.Lock()
+		defer runner.pendingTakeoversMu.Unlock()
+		for _, rep := range runner.pendingTakeovers {
+			if rep.WgIP == wgIP {
+				return true
+			}
+		}
+		return false
+	}
+
+	flip(staticIP, flipsRequiredForDenylist)
+	if !reported(staticIP) {
+		t.Errorf("static peer should be reported after %d flips", flipsRequiredForDenylist)
+	}
+	flip(roamingIP, flipsRequiredForDenylist)
+	if reported(roamingIP) {
+		t.Errorf("roaming peer should tolerate %d flips", flipsRequiredForDenylist)
+	}
+	flip(roamingIP, roamingFlipsRequired-flipsRequiredForDenylist)
+	if !reported(roamingIP) {
+		t.Errorf("roaming peer should be reported after %d flips", roamingFlipsRequired)
+	}
+
+	// An endpoint change 5s ago holds a static peer out of the whitelist but
+	// not a roaming one.
+	runner.wgIPToEndpoint = map[string]string{roamingIP: storedEP, staticIP: storedEP}
+	changed := time.Now().Add(-5 * time.Second)
+	runner.endpointChangedAt[roamingIP] = changed
+	runner.endpointChangedAt[staticIP] = changed
+	got := runner.filterWhitelistByEndpoint([]string{roamingIP + "@" + storedEP, staticIP + "@" + storedEP})
+	if len(got) != 1 || got[0] != roamingIP {
+		t.Errorf("expected only the roaming peer to be whitelisted, got %v", got)
+	}
+
+	// Custom sensitivities replace the defaults.
+	roaming, static := DefaultEndpointSensitivity()
+	roaming.StabilityWindow = time.Minute
+	runner.SetEndpointSensitivity(roaming, static)
+	if got := runner.filterWhitelistByEndpoint([]string{roamingIP + "@" + storedEP}); len(got) != 0 {
+		t.Errorf("expected the longer roaming window to hold the peer out, got %v", got)
+	}
+}
+
+// mockDeltaWriter is a config writer that can also patch single peers.
+type mockDeltaWriter struct {
+	mockConfigWriter
+	deltaErr error
+	upserts  []string
+	removed  []string
+}
+
+func (m *mockDeltaWriter) ApplyPeerDelta(cfg string, upserts []string, removed []string) error {
+	if m.deltaErr != nil {
+		return m.deltaErr
+	}
+	m.upserts, m.removed = upserts, removed
+	return nil
+}
+
+func TestApplyConfigPeerDelta(t *testing.T) {
+	previous := "[Interface]\nPrivateKey = test\n\n[Peer]\nPublicKey = pk-db=\n"
+	next := "[Interface]\nPrivateKey = test\n\n[Peer]\nPublicKey = pk-web=\n"
+	delta := &PeerDelta{Base: configHash(previous), Added: []string{"[Peer]\nPublicKey = pk-web="}, Removed: []string{"pk-db="}}
+
+	writer := &mockDeltaWriter{}
+	runner := NewRunner(&mockWebSocketClient{}, writer, nil, nil, "ws://localhost:8080", "wg0", "", "")
+
+	// Nothing applied yet: the delta has no base here
+	if err := runner.applyConfig(next, delta); err != nil {
+		t.Fatal(err)
+	}
+	if !writer.Applied() || writer.upserts != nil {
+		t.Fatal("expected the full config to be applied without a previous config")
+	}
+
+	writer = &mockDeltaWriter{}
+	runner.cfgWriter = writer
+	runner.lastConfig = previous
+	if err := runner.applyConfig(next, delta); err != nil {
+		t.Fatal(err)
+	}
+	if writer.Applied() {
+		t.Error("expected the delta, not the full config, to be applied")
+	}
+	if len(writer.upserts) != 1 || len(writer.removed) != 1 || writer.removed[0] != "pk-db=" {
+		t.Errorf("unexpected delta applied: upserts %q, removed %q", writer.upserts, writer.removed)
+	}
+
+	// A delta against another config falls back to the full config
+	writer = &mockDeltaWriter{}
+	runner.cfgWriter = writer
+	runner.lastConfig = "[Interface]\nPrivateKey = other\n"
+	if err := runner.applyConfig(next, delta); err != nil {
+		t.Fatal(err)
+	}
+	if !writer.Applied() || writer.upserts != nil {
+		t.Error("expected the full config to be applied for a mismatched base")
+	}
+
+	// So does a delta the writer fails to apply
+	writer = &mockDeltaWriter{deltaErr: errors.New("wg set failed")}
+	runner.cfgWriter = writer
+	runner.lastConfig = previous
+	if err := runner.applyConfig(next, delta); err != nil {
+		t.Fatal(err)
+	}
+	if writer.Config() != next {
+		t.Error("expected the full config to be applied after the delta failed")
+	}
+}
+
+func TestSendConfigAck_KeepsLatest(t *testing.T) {
+	runner := NewRunner(&mockWebSocketClient{}, &mockConfigWriter{}, nil, nil, "ws://localhost:8080", "wg0", "", "")
+
+	runner.sendConfigAck("[Interface]\nPrivateKey = v1\n")
+	runner.sendConfigAck("[Interface]\nPrivateKey = v2\n")
+	if len(runner.configAcks) != 1 {
+		t.Fatalf("expected one queued ack, got %d", len(runner.configAcks))
+	}
+	var ack ConfigAck
+	if err := json.Unmarshal(<-runner.configAcks, &ack); err != nil {
+		t.Fatal(err)
+	}
+	if ack.Type != "config_ack" || ack.ConfigHash != configHash("[Interface]\nPrivateKey = v2\n") {
+		t.Errorf("unexpected ack %+v, want the hash of the latest config", ack)
+	}
+}
@@ -4,8 +4,10 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -108,6 +110,107 @@ func GetWireGuardHandshakes(iface string) map[string]time.Time {
 	return result
 }
 
+// PeerTransfer holds the WireGuard transfer counters of one peer.
+type PeerTransfer struct {
+	RxBytes int64 `json:"rx_bytes"`
+	TxBytes int64 `json:"tx_bytes"`
+}
+
+// GetWireGuardTransfer returns the bytes received from and sent to each peer,
+// keyed by public key, as reported by "wg show <iface> transfer".
+func GetWireGuardTransfer(iface string) map[string]PeerTransfer {
+	cmd := exec.Command("wg", "show", iface, "transfer") // #nosec G204
+	output, err := cmd.Output()
+	if err != nil {
+		return make(map[string]PeerTransfer)
+	}
+	return parseWireGuardTransfer(string(output))
+}
+
+// parseWireGuardTransfer parses "<pubkey>\t<rx bytes>\t<tx bytes>" lines.
+func parseWireGuardTransfer(output string) map[string]PeerTransfer {
+	result := make(map[string]PeerTransfer)
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		parts := strings.Fields(line)
+		if len(parts) != 3 {
+			continue
+		}
+		rx, errRx := strconv.ParseInt(parts[1], 10, 64)
+		tx, errTx := strconv.ParseInt(parts[2], 10, 64)
+		if errRx != nil || errTx != nil {
+			continue
+		}
+		result[parts[0]] = PeerTransfer{RxBytes: rx, TxBytes: tx}
+	}
+	return result
+}
+
+// maxLatencyProbes bounds the number of pings MeasurePeerLatency runs at once.
+const maxLatencyProbes = 16
+
+// MeasurePeerLatency pings the tunnel address of every peer of the interface
+// once and returns the round-trip time in milliseconds, keyed by public key.
+// Peers that do not answer within a second are reported as -1.
+func MeasurePeerLatency(iface string) map[string]float64 {
+	result := make(map[string]float64)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, maxLatencyProbes)
+	for pubKey, cidrs := range GetWireGuardAllowedIPs(iface) {
+		addr := tunnelAddress(cidrs)
+		if addr == "" {
+			continue
+		}
+		wg.Add(1)
+		go func(pubKey, addr string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			rtt := -1.0
+			output, err := exec.Command("ping", "-c", "1", "-W", "1", addr).Output() // #nosec G204
+			if err == nil {
+				if ms, ok := parsePingRTT(string(output)); ok {
+					rtt = ms
+				}
+			}
+			mu.Lock()
+			result[pubKey] = rtt
+			mu.Unlock()
+		}(pubKey, addr)
+	}
+	wg.Wait()
+	return result
+}
+
+// tunnelAddress returns the peer's own tunnel address: the first host route
+// (/32 or /128) among its allowed IPs, or "" if it has none.
+func tunnelAddress(cidrs []string) string {
+	for _, cidr := range cidrs {
+		if addr, ok := strings.CutSuffix(cidr, "/32"); ok {
+			return addr
+		}
+		if addr, ok := strings.CutSuffix(cidr, "/128"); ok {
+			return addr
+		}
+	}
+	return ""
+}
+
+var pingRTTPattern = regexp.MustCompile(`time[=<]([0-9.]+) ?ms`)
+
+// parsePingRTT extracts the round-trip time in milliseconds from ping output.
+func parsePingRTT(output string) (float64, bool) {
+	m := pingRTTPattern.FindStringSubmatch(output)
+	if m == nil {
+		return 0, false
+	}
+	ms, err := strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		return 0, false
+	}
+	return ms, true
+}
+
 // GetWireGuardAllowedIPs returns a map of peer public keys to their allowed-IP
 // CIDR lists, as reported by "wg show <iface> allowed-ips".
 // Example output line: "<pubkey>\t10.0.0.2/32 0.0.0.0/0"
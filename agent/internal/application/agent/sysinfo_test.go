@@ -256,3 +256,43 @@ func containsChar(s string, c byte) bool {
 	}
 	return false
 }
+
+func TestParseWireGuardTransfer(t *testing.T) {
+	output := "pubkeyA=\t1024\t2048\npubkeyB=\t0\t0\nmalformed line\npubkeyC=\tx\t1\n"
+
+	got := parseWireGuardTransfer(output)
+	if len(got) != 2 {
+		t.Fatalf("expected 2 peers, got %d: %v", len(got), got)
+	}
+	if got["pubkeyA="] != (PeerTransfer{RxBytes: 1024, TxBytes: 2048}) {
+		t.Errorf("unexpected counters for pubkeyA=: %+v", got["pubkeyA="])
+	}
+	if _, ok := got["pubkeyC="]; ok {
+		t.Error("expected unparsable counters to be skipped")
+	}
+}
+
+func TestParsePingRTT(t *testing.T) {
+	tests := []struct {
+		output string
+		want   float64
+		ok     bool
+	}{
+		{"64 bytes from 10.0.0.2: icmp_seq=1 ttl=64 time=12.4 ms\n", 12.4, true},
+		{"64 bytes from 10.0.0.2: icmp_seq=1 ttl=64 time<1 ms\n", 1, true},
+		{"1 packets transmitted, 0 received, 100% packet loss, time 0ms\n", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := parsePingRTT(tt.output)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("parsePingRTT(%q) = %v, %v; want %v, %v", tt.output, got, ok, tt.want, tt.ok)
+		}
+	}
+
+	if got := tunnelAddress([]string{"0.0.0.0/0", "10.0.0.2/32"}); got != "10.0.0.2" {
+		t.Errorf("tunnelAddress = %q, want 10.0.0.2", got)
+	}
+	if got := tunnelAddress([]string{"10.0.0.0/24"}); got != "" {
+		t.Errorf("tunnelAddress without a host route = %q, want empty", got)
+	}
+}
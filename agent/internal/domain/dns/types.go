@@ -1,10 +1,29 @@
 package dns
 
+import "time"
+
+// Record types a DNSPeer can carry.  An empty Type is an address record, so
+// configs from servers that predate record types keep working.
+const (
+	RecordTypeA     = "A" // IP and/or IPv6 (answers both A and AAAA queries)
+	RecordTypeCNAME = "CNAME"
+	RecordTypeTXT   = "TXT"
+)
+
 // DNSPeer represents minimal peer info for DNS publishing.
 type DNSPeer struct {
 	Name string `json:"name"`
 	IP   string `json:"ip"`
 	IPv6 string `json:"ipv6,omitempty"` // IPv6 WireGuard address (optional, set for dual-stack networks)
+	// Type is the record type (RecordTypeA when empty).  CNAME and TXT
+	// records leave IP/IPv6 empty and carry their target or text in Value.
+	Type  string `json:"type,omitempty"`
+	Value string `json:"value,omitempty"`
+}
+
+// IsAddress reports whether the record resolves to the IP/IPv6 addresses.
+func (p DNSPeer) IsAddress() bool {
+	return p.Type == "" || p.Type == RecordTypeA
 }
 
 // DNSConfig represents domain + peers list delivered to jump agent.
@@ -13,4 +32,21 @@ type DNSConfig struct {
 	Domain          string    `json:"domain"`
 	Peers           []DNSPeer `json:"peers"`
 	UpstreamServers []string  `json:"upstream_servers"` // Upstream DNS servers for forwarding
+	// ConditionalForwarders maps domains to the servers answering them and
+	// their subdomains instead of UpstreamServers (split-horizon DNS).
+	ConditionalForwarders map[string][]string `json:"conditional_forwarders,omitempty"`
+	// QueryLog asks the DNS server to record the queries it receives and
+	// report them with the heartbeat.  Off unless the network opted in.
+	QueryLog bool `json:"query_log,omitempty"`
+	// SearchDomains qualifies single-label queries, tried in order.
+	SearchDomains []string `json:"search_domains,omitempty"`
+}
+
+// QueryLogEntry is a query received by a jump agent's DNS server, as
+// reported to the server when the network logs DNS queries.
+type QueryLogEntry struct {
+	Name      string    `json:"name"`
+	Type      string    `json:"type"`
+	ClientIP  string    `json:"client_ip"`
+	Timestamp time.Time `json:"timestamp"`
 }
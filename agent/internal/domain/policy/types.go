@@ -4,4 +4,43 @@ package policy
 type JumpPolicy struct {
 	IP            string   `json:"ip"`
 	IPTablesRules []string `json:"iptables_rules"` // Generated iptables rules from policies
+	// FirewallBackend echoes the backend this agent reported in its heartbeat.
+	// When it is nftables, NftRules carries IPTablesRules translated to nft syntax.
+	FirewallBackend string   `json:"firewall_backend,omitempty"`
+	NftRules        []string `json:"nft_rules,omitempty"`
+	// Rules is the backend-neutral form of IPTablesRules.  When present it is
+	// rendered by the configured firewall backend instead of IPTablesRules.
+	Rules []Rule `json:"rules,omitempty"`
+	// Peers lists the network's peers.  UseAgent marks agent-managed peers,
+	// which are expected to roam between networks.
+	Peers []Peer `json:"peers,omitempty"`
+}
+
+// Peer is a network peer as seen by the jump peer.
+type Peer struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	IP       string `json:"ip"`
+	UseAgent bool   `json:"use_agent"`
+}
+
+// Address families of a Rule.
+const (
+	FamilyIPv4 = "ipv4"
+	FamilyIPv6 = "ipv6"
+)
+
+// Rule is a backend-neutral policy rule (mirrors the server's firewall.Rule).
+// A Rule without Action only carries a Comment and matches nothing.
+type Rule struct {
+	Family      string `json:"family,omitempty"`      // FamilyIPv4 or FamilyIPv6
+	Chain       string `json:"chain,omitempty"`       // lowercase built-in chain: forward, input, output
+	Source      string `json:"source,omitempty"`      // IP or CIDR
+	Destination string `json:"destination,omitempty"` // IP or CIDR
+	Protocol    string `json:"protocol,omitempty"`    // tcp, udp, ...
+	SourcePort  string `json:"source_port,omitempty"`
+	DestPort    string `json:"dest_port,omitempty"`
+	State       string `json:"state,omitempty"`  // lowercase conntrack states, e.g. "related,established"
+	Action      string `json:"action,omitempty"` // accept or drop
+	Comment     string `json:"comment,omitempty"`
 }
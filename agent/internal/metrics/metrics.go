@@ -0,0 +1,46 @@
+// Package metrics exposes the agent's Prometheus metrics.
+//
+// Values are published by the runner as it connects to the server and applies
+// configurations; cmd/agent serves them on /metrics when --metrics-port is set.
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const namespace = "wirety_agent"
+
+var (
+	// LastApplyTimestamp is the Unix time of the last successful config apply.
+	LastApplyTimestamp = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "last_config_apply_timestamp_seconds",
+		Help:      "Unix timestamp of the last successful WireGuard config apply.",
+	})
+
+	// ApplyFailures counts WireGuard config applies that failed.
+	ApplyFailures = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "config_apply_failures_total",
+		Help:      "Total number of failed WireGuard config applies.",
+	})
+
+	// Peers is the number of [Peer] sections in the last applied config.
+	Peers = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "peers",
+		Help:      "Number of WireGuard peers in the last applied config.",
+	})
+
+	// WebSocketReconnects counts reconnections to the server after the
+	// WebSocket connection was lost.
+	WebSocketReconnects = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "websocket_reconnects_total",
+		Help:      "Total number of WebSocket reconnections to the server.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(LastApplyTimestamp, ApplyFailures, Peers, WebSocketReconnects)
+}
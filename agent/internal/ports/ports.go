@@ -14,6 +14,13 @@ type ConfigWriterPort interface {
 	GetInterface() string
 }
 
+// PeerDeltaPort is implemented by config writers that can update individual
+// peers on a live interface.  cfg is the full resulting config, persisted so
+// a restart comes up in the same state.
+type PeerDeltaPort interface {
+	ApplyPeerDelta(cfg string, upserts []string, removed []string) error
+}
+
 // DNSStarterPort defines capability to start DNS server with given domain and peers.
 type DNSStarterPort interface {
 	Start(addr string) error
@@ -0,0 +1,8 @@
+-- 028: firewall backend reported by agents
+--
+-- Agents now report which firewall backend they use (iptables, iptables-nft or
+-- nftables) in their heartbeat.  The server keeps it on the session so it can
+-- deliver jump-peer rules in nft syntax to agents without the iptables CLI,
+-- and so admins can see it when troubleshooting.
+
+ALTER TABLE agent_sessions ADD COLUMN firewall_backend TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,7 @@
+-- 029: per-network peer naming convention
+--
+-- Networks can define a regular expression (e.g. `[a-z]+-(web|db)-[0-9]+`)
+-- that every peer name must fully match, on top of the DNS label rules.
+-- Empty means no convention.
+
+ALTER TABLE networks ADD COLUMN peer_name_pattern TEXT NOT NULL DEFAULT '';
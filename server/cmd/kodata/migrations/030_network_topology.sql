@@ -0,0 +1,9 @@
+-- 030: network topology
+--
+-- 'mesh' (the historical behaviour) creates a preshared-key connection
+-- between every pair of peers, which is O(n²) rows.  'hub' only creates
+-- connections that involve a jump peer: regular peers only ever talk to jump
+-- peers, so the other connections are never used in a generated config.
+
+ALTER TABLE networks ADD COLUMN topology TEXT NOT NULL DEFAULT 'mesh'
+    CHECK (topology IN ('mesh', 'hub'));
@@ -0,0 +1,27 @@
+-- 031: temporary (break-glass) routes
+--
+-- A temporary route grants a single peer access to a destination CIDR through
+-- a jump peer until expires_at.  route_id is set when an existing route was
+-- granted; its CIDRs are copied so later edits to the route do not widen an
+-- already-issued grant.  Expired rows are deleted by the server's background
+-- sweep, which also pushes fresh configs to the network.
+
+CREATE TABLE IF NOT EXISTS peer_temp_routes (
+    id                  TEXT PRIMARY KEY,
+    network_id          TEXT NOT NULL REFERENCES networks(id) ON DELETE CASCADE,
+    peer_id             TEXT NOT NULL REFERENCES peers(id)    ON DELETE CASCADE,
+    route_id            TEXT REFERENCES routes(id)            ON DELETE SET NULL,
+    destination_cidr    TEXT NOT NULL DEFAULT '',
+    destination_cidr_v6 TEXT NOT NULL DEFAULT '',
+    jump_peer_id        TEXT NOT NULL REFERENCES peers(id)    ON DELETE CASCADE,
+    reason              TEXT NOT NULL DEFAULT '',
+    created_by          TEXT NOT NULL DEFAULT '',
+    created_at          TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at          TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS peer_temp_routes_peer_idx
+    ON peer_temp_routes(network_id, peer_id);
+
+CREATE INDEX IF NOT EXISTS peer_temp_routes_expires_idx
+    ON peer_temp_routes(expires_at);
@@ -0,0 +1,9 @@
+-- 032: agent session revocation
+--
+-- Sessions are no longer only "the current one": admins can list a peer's
+-- full session history for forensics and revoke individual sessions.  A
+-- revoked session is kept (with the time it was revoked) but is never
+-- returned as the peer's current session, so the agent's next heartbeat
+-- starts a fresh session.
+
+ALTER TABLE agent_sessions ADD COLUMN revoked_at TIMESTAMPTZ;
@@ -0,0 +1,10 @@
+-- 033: peer role
+--
+-- A peer is either a client (routes through the jump peers, including any
+-- gateway routes) or a resource (a non-routing server whose AllowedIPs never
+-- include gateway routes).  Jump peers are stored with role 'jump'.
+
+ALTER TABLE peers ADD COLUMN role TEXT NOT NULL DEFAULT 'client'
+    CHECK (role IN ('client', 'resource', 'jump'));
+
+UPDATE peers SET role = 'jump' WHERE is_jump;
@@ -0,0 +1,8 @@
+-- 034: layer-4 matching on policy rules
+--
+-- Optional protocol (tcp, udp, icmp or any) and destination port/range.
+-- Empty values keep the previous behaviour of matching all traffic.
+
+ALTER TABLE policy_rules ADD COLUMN protocol TEXT NOT NULL DEFAULT ''
+    CHECK (protocol IN ('', 'any', 'tcp', 'udp', 'icmp'));
+ALTER TABLE policy_rules ADD COLUMN ports TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,18 @@
+-- 035: audit log of mutating operations
+--
+-- One row per create/update/delete performed through the services, with the
+-- acting user.  There are deliberately no foreign keys: entries must outlive
+-- the networks, peers and resources they describe.
+
+CREATE TABLE IF NOT EXISTS audit_log (
+    id          TEXT PRIMARY KEY,
+    actor_id    TEXT NOT NULL DEFAULT '',
+    actor_email TEXT NOT NULL DEFAULT '',
+    action      TEXT NOT NULL,
+    network_id  TEXT NOT NULL DEFAULT '',
+    peer_id     TEXT NOT NULL DEFAULT '',
+    resource_id TEXT NOT NULL DEFAULT '',
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_network_created ON audit_log(network_id, created_at DESC);
@@ -0,0 +1,6 @@
+-- 036: per-peer split-tunnel exclusions
+--
+-- CIDRs removed from the peer's AllowedIPs toward its jump peers, so local
+-- subnets bypass a full tunnel (0.0.0.0/0) route.
+
+ALTER TABLE peers ADD COLUMN split_tunnel_exclusions TEXT[] NOT NULL DEFAULT '{}';
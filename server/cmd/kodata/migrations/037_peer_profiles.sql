@@ -0,0 +1,31 @@
+-- 037: peer profiles
+--
+-- Named per-network bundles of client settings (MTU, keepalive, DNS,
+-- split-tunnel exclusions) assigned to peers.  The same settings on a peer
+-- override its profile.  An MTU of 0, an empty DNS list and a NULL keepalive
+-- or exclusion list mean "not set"; a keepalive of 0 disables keepalive and
+-- an empty exclusion list excludes nothing.
+
+CREATE TABLE IF NOT EXISTS peer_profiles (
+    id TEXT PRIMARY KEY,
+    network_id TEXT NOT NULL REFERENCES networks(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    mtu INTEGER NOT NULL DEFAULT 0,
+    persistent_keepalive INTEGER,
+    dns TEXT[] NOT NULL DEFAULT '{}',
+    split_tunnel_exclusions TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (network_id, name)
+);
+
+ALTER TABLE peers ADD COLUMN profile_id TEXT REFERENCES peer_profiles(id) ON DELETE SET NULL;
+ALTER TABLE peers ADD COLUMN mtu INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE peers ADD COLUMN persistent_keepalive INTEGER;
+ALTER TABLE peers ADD COLUMN dns TEXT[] NOT NULL DEFAULT '{}';
+
+-- Peers without exclusions inherit their profile's from now on.
+ALTER TABLE peers ALTER COLUMN split_tunnel_exclusions DROP NOT NULL;
+ALTER TABLE peers ALTER COLUMN split_tunnel_exclusions DROP DEFAULT;
+UPDATE peers SET split_tunnel_exclusions = NULL WHERE split_tunnel_exclusions = '{}';
@@ -0,0 +1,6 @@
+-- 038: per-peer transfer statistics on agent sessions
+--
+-- Bytes received/sent with each WireGuard peer (keyed by public key), as
+-- accumulated from the `wg show transfer` counters of the agent's heartbeats.
+
+ALTER TABLE agent_sessions ADD COLUMN peer_transfer JSONB NOT NULL DEFAULT '{}';
@@ -0,0 +1,6 @@
+-- 039: latest WireGuard handshakes on agent sessions
+--
+-- Time of the latest handshake with each peer (keyed by public key), used to
+-- report peers as online, stale or offline.
+
+ALTER TABLE agent_sessions ADD COLUMN peer_handshakes JSONB NOT NULL DEFAULT '{}';
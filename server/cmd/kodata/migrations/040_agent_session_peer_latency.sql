@@ -0,0 +1,6 @@
+-- 040: peer round-trip times on agent sessions
+--
+-- Latency last measured by a jump agent to each of its peers (keyed by public
+-- key); a null rtt_ms means the peer did not answer.
+
+ALTER TABLE agent_sessions ADD COLUMN peer_latency JSONB NOT NULL DEFAULT '{}';
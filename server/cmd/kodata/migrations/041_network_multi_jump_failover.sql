@@ -0,0 +1,7 @@
+-- 041: multi-jump failover
+--
+-- When set, a regular peer's config lists the routed CIDRs on every jump peer
+-- section instead of only on each route's gateway, so that another jump can
+-- take over when a gateway is down.
+
+ALTER TABLE networks ADD COLUMN multi_jump_failover BOOLEAN NOT NULL DEFAULT false;
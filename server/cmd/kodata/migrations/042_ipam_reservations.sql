@@ -0,0 +1,13 @@
+-- 042: IPAM reservations
+--
+-- Addresses taken out of a prefix's pool by an administrator (e.g. a routed
+-- subnet's hardware gateway) so that they are never handed to a peer.  They
+-- are kept apart from ipam_allocated_ips so they can be listed and released
+-- on their own.
+
+CREATE TABLE IF NOT EXISTS ipam_reservations (
+    prefix_cidr TEXT NOT NULL REFERENCES ipam_prefixes(cidr) ON DELETE CASCADE,
+    ip TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (prefix_cidr, ip)
+);
@@ -0,0 +1,20 @@
+-- 043: network-scoped DNS records
+--
+-- DNS records that belong to a network directly rather than to a route, for
+-- names pointing at addresses no route covers.  They share the
+-- <name>.<network>.<suffix> namespace with route DNS mappings.
+
+CREATE TABLE IF NOT EXISTS network_dns_records (
+    id TEXT PRIMARY KEY,
+    network_id TEXT NOT NULL REFERENCES networks(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    ip_address TEXT,
+    ip_address_v6 TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE(network_id, name),
+    CONSTRAINT network_dns_records_address_at_least_one_family
+        CHECK (ip_address IS NOT NULL OR ip_address_v6 IS NOT NULL)
+);
+
+CREATE INDEX IF NOT EXISTS idx_network_dns_records_network_id ON network_dns_records(network_id);
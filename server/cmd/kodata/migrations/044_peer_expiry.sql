@@ -0,0 +1,8 @@
+-- 044: peer expiry
+--
+-- Optional deadline after which a peer is cut off.  Expired peers are kept
+-- and blocked on the jump peers until an admin extends or clears the expiry.
+
+ALTER TABLE peers ADD COLUMN IF NOT EXISTS expires_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_peers_expires_at ON peers(expires_at) WHERE expires_at IS NOT NULL;
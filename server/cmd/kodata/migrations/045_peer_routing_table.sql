@@ -0,0 +1,6 @@
+-- 045: peer routing table
+--
+-- wg-quick Table setting of a peer's config: 'off', 'auto' or a table
+-- number.  Empty leaves the line out.
+
+ALTER TABLE peers ADD COLUMN IF NOT EXISTS routing_table TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,6 @@
+-- 046: peer fwmark
+--
+-- FwMark of a peer's [Interface] section as entered (hex or decimal).
+-- Empty leaves the line out.
+
+ALTER TABLE peers ADD COLUMN IF NOT EXISTS fwmark TEXT NOT NULL DEFAULT '';
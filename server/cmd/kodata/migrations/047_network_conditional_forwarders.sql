@@ -0,0 +1,6 @@
+-- 047: network conditional forwarders
+--
+-- Split-horizon DNS: domain -> resolvers the jump peers' DNS servers send
+-- queries for that domain (and its subdomains) to.
+
+ALTER TABLE networks ADD COLUMN IF NOT EXISTS conditional_forwarders JSONB NOT NULL DEFAULT '{}';
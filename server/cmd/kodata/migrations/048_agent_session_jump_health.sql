@@ -0,0 +1,7 @@
+-- 048: agent session jump health
+--
+-- Result of the latest health check a jump agent ran on the server's request:
+-- whether WireGuard holds its listen port and the internet is reachable
+-- through the NAT interface.  NULL until the first check.
+
+ALTER TABLE agent_sessions ADD COLUMN IF NOT EXISTS jump_health JSONB;
@@ -0,0 +1,9 @@
+-- 049: single-use and expiring enrollment tokens
+--
+-- token_single_use: the token enrolls one agent, then only authenticates it.
+-- token_expires_at: deadline to enroll with the token (NULL = never).
+-- token_used_at: first successful /agent/resolve with the token.
+
+ALTER TABLE peers ADD COLUMN IF NOT EXISTS token_single_use BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE peers ADD COLUMN IF NOT EXISTS token_expires_at TIMESTAMPTZ;
+ALTER TABLE peers ADD COLUMN IF NOT EXISTS token_used_at TIMESTAMPTZ;
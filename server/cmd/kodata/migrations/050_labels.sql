@@ -0,0 +1,10 @@
+-- 050: network and peer labels
+--
+-- Free-form key/value labels for organizing networks and peers.  The GIN
+-- indexes serve containment queries such as labels @> '{"env":"prod"}'.
+
+ALTER TABLE networks ADD COLUMN IF NOT EXISTS labels JSONB NOT NULL DEFAULT '{}';
+ALTER TABLE peers ADD COLUMN IF NOT EXISTS labels JSONB NOT NULL DEFAULT '{}';
+
+CREATE INDEX IF NOT EXISTS idx_networks_labels ON networks USING GIN (labels jsonb_path_ops);
+CREATE INDEX IF NOT EXISTS idx_peers_labels ON peers USING GIN (labels jsonb_path_ops);
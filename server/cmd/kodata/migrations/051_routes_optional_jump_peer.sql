@@ -0,0 +1,6 @@
+-- 051: routes without a jump peer
+--
+-- Routes copied by a network clone have no jump peer until an admin assigns
+-- one; they are left out of every config until then.
+
+ALTER TABLE routes ALTER COLUMN jump_peer_id DROP NOT NULL;
@@ -0,0 +1,6 @@
+-- 052: keep private keys out of agent configs
+--
+-- Networks with omit_private_keys set send agents configs without a
+-- PrivateKey line; agents supply the key themselves.
+
+ALTER TABLE networks ADD COLUMN IF NOT EXISTS omit_private_keys BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,6 @@
+-- 053: route metric
+--
+-- When routes share a destination through different jump peers, the one
+-- with the lowest metric wins, ahead of group priority.
+
+ALTER TABLE routes ADD COLUMN IF NOT EXISTS metric INTEGER NOT NULL DEFAULT 0;
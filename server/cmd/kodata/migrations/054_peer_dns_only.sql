@@ -0,0 +1,6 @@
+-- 054: dns-only peers
+--
+-- DNS-only peers (e.g. resolver appliances) keep an address and a DNS record
+-- served by the jump peers, but no config lists them as a [Peer].
+
+ALTER TABLE peers ADD COLUMN IF NOT EXISTS dns_only BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,7 @@
+-- 055: connection AllowedIPs overrides
+--
+-- A peer connection can replace the AllowedIPs derived for one of its peers'
+-- [Peer] section in the other peer's config, for asymmetric topologies.
+
+ALTER TABLE peer_connections ADD COLUMN IF NOT EXISTS allowed_ips TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE peer_connections ADD COLUMN IF NOT EXISTS allowed_ips_peer_id TEXT NOT NULL DEFAULT '';
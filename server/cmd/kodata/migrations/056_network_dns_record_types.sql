@@ -0,0 +1,18 @@
+-- 056: CNAME and TXT network DNS records
+--
+-- Network-scoped DNS records gain a type, so zone file imports can carry
+-- CNAME and TXT records next to address ones.  A name holds one record of
+-- each type, except TXT records, which only need distinct texts.
+
+ALTER TABLE network_dns_records ADD COLUMN IF NOT EXISTS record_type TEXT NOT NULL DEFAULT 'A';
+ALTER TABLE network_dns_records ADD COLUMN IF NOT EXISTS value TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE network_dns_records DROP CONSTRAINT IF EXISTS network_dns_records_network_id_name_key;
+ALTER TABLE network_dns_records DROP CONSTRAINT IF EXISTS network_dns_records_address_at_least_one_family;
+ALTER TABLE network_dns_records ADD CONSTRAINT network_dns_records_address_at_least_one_family
+    CHECK (record_type <> 'A' OR ip_address IS NOT NULL OR ip_address_v6 IS NOT NULL);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_network_dns_records_name_type
+    ON network_dns_records(network_id, name, record_type) WHERE record_type <> 'TXT';
+CREATE UNIQUE INDEX IF NOT EXISTS idx_network_dns_records_txt
+    ON network_dns_records(network_id, name, value) WHERE record_type = 'TXT';
@@ -0,0 +1,7 @@
+-- 057: DNS query logs
+--
+-- Networks with dns_query_log set have their jump agents report the DNS
+-- queries they receive; the latest window is kept on the agent session.
+
+ALTER TABLE networks ADD COLUMN IF NOT EXISTS dns_query_log BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE agent_sessions ADD COLUMN IF NOT EXISTS dns_queries JSONB NOT NULL DEFAULT '[]';
@@ -0,0 +1,6 @@
+-- 058: network maintenance windows
+--
+-- Until maintenance_until, security detections on the network raise
+-- incidents without denylisting or quarantining peers.
+
+ALTER TABLE networks ADD COLUMN IF NOT EXISTS maintenance_until TIMESTAMPTZ;
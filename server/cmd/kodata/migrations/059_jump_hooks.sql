@@ -0,0 +1,8 @@
+-- 059: jump PostUp/PostDown templates
+--
+-- Optional templates rendered into the PostUp and PostDown lines of the
+-- network's jump peer configs, and the NAT interface they can reference.
+
+ALTER TABLE networks ADD COLUMN IF NOT EXISTS jump_post_up TEXT NOT NULL DEFAULT '';
+ALTER TABLE networks ADD COLUMN IF NOT EXISTS jump_post_down TEXT NOT NULL DEFAULT '';
+ALTER TABLE networks ADD COLUMN IF NOT EXISTS jump_nat_interface TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,6 @@
+-- 060: severed peer connections
+--
+-- An admin can sever a pair of peers the topology connects: each is then
+-- left out of the other's config.  The row stays so it is not recreated.
+
+ALTER TABLE peer_connections ADD COLUMN IF NOT EXISTS severed BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,6 @@
+-- 061: network soft deletion
+--
+-- A deleted network keeps its rows, and its CIDR in IPAM, until the
+-- retention window has passed, so that it can be restored until then.
+
+ALTER TABLE networks ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
@@ -0,0 +1,6 @@
+-- 062: peer metadata comments in configs
+--
+-- Networks can have their generated configs carry "# ID:", "# Groups:" and
+-- "# Owner:" comments for each peer section.
+
+ALTER TABLE networks ADD COLUMN IF NOT EXISTS config_metadata BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,7 @@
+-- 063: agent session config acknowledgement
+--
+-- Hash of the latest config the agent acknowledged applying, and when.  Empty
+-- until the first acknowledgement; agents that predate acks never send one.
+
+ALTER TABLE agent_sessions ADD COLUMN IF NOT EXISTS last_applied_config_hash TEXT NOT NULL DEFAULT '';
+ALTER TABLE agent_sessions ADD COLUMN IF NOT EXISTS last_applied_at TIMESTAMPTZ;
@@ -0,0 +1,6 @@
+-- 064: global routes
+--
+-- A global route reaches every regular peer of the network, not only the
+-- peers of the groups it is attached to.
+
+ALTER TABLE routes ADD COLUMN IF NOT EXISTS global_route BOOLEAN NOT NULL DEFAULT false;
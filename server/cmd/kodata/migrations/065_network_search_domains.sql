@@ -0,0 +1,6 @@
+-- 065: network DNS search domains
+--
+-- Extra search domains, tried after the network's own domain, for peer
+-- configs and the jump peers' DNS servers.
+
+ALTER TABLE networks ADD COLUMN IF NOT EXISTS search_domains TEXT[] NOT NULL DEFAULT '{}';
@@ -5,7 +5,12 @@ import (
 	"crypto/rand"
 	"database/sql"
 	"encoding/hex"
+	"flag"
+	"net"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/gin-contrib/cors"
@@ -16,7 +21,6 @@ import (
 
 	"wirety/internal/adapters/api"
 	"wirety/internal/adapters/api/middleware"
-	"wirety/internal/audit"
 	"wirety/internal/adapters/db/memory"
 	pgrepo "wirety/internal/adapters/db/postgres"
 	appauth "wirety/internal/application/auth"
@@ -26,10 +30,13 @@ import (
 	appnetwork "wirety/internal/application/network"
 	apppolicy "wirety/internal/application/policy"
 	approute "wirety/internal/application/route"
+	"wirety/internal/audit"
 	"wirety/internal/config"
 	domainauth "wirety/internal/domain/auth"
 	domainipam "wirety/internal/domain/ipam"
 	domainnetwork "wirety/internal/domain/network"
+	"wirety/internal/infrastructure/webhook"
+	"wirety/internal/tracing"
 )
 
 //	@title			Wirety Server API
@@ -55,6 +62,8 @@ import (
 func main() {
 	// Load configuration first so log settings are available immediately.
 	cfg := config.LoadConfig()
+	flag.BoolVar(&cfg.CORSAllowAll, "cors-allow-all", cfg.CORSAllowAll, "accept cross-origin requests from any origin, without credentials (local development only; also CORS_ALLOW_ALL)")
+	flag.Parse()
 
 	// Configure zerolog level and format.
 	configureLogger(cfg.LogLevel, cfg.LogFormat)
@@ -63,22 +72,37 @@ func main() {
 	if err := cfg.Auth.Validate(); err != nil {
 		log.Fatal().Err(err).Msg("invalid auth configuration")
 	}
+	if err := config.ValidateCORSOrigins(cfg.CORSOrigins); err != nil {
+		log.Fatal().Err(err).Msg("invalid CORS configuration")
+	}
+	if err := config.ValidateBindAddress(cfg.BindAddress); err != nil {
+		log.Fatal().Err(err).Msg("invalid listen configuration")
+	}
+	if len(cfg.CORSOrigins) == 1 && cfg.CORSOrigins[0] == "*" {
+		log.Warn().Msg("CORS_ORIGIN='*' is deprecated - use --cors-allow-all (CORS_ALLOW_ALL=true) for local development, or list your frontend URL(s)")
+		cfg.CORSAllowAll = true
+		cfg.CORSOrigins = nil
+	}
 
 	// Initialize audit logger
 	audit.Init(cfg.AuditLog)
 
+	// Export trace spans when an OTLP collector is configured
+	if err := tracing.Init(context.Background(), cfg.Tracing.Endpoint, cfg.Tracing.ServiceName); err != nil {
+		log.Fatal().Err(err).Msg("init tracing")
+	}
+
 	log.Info().
 		Str("http_port", cfg.HTTPPort).
+		Str("bind_address", cfg.BindAddress).
 		Bool("auth_enabled", cfg.Auth.Enabled).
 		Str("issuer_url", cfg.Auth.IssuerURL).
 		Strs("cors_origins", cfg.CORSOrigins).
+		Bool("cors_allow_all", cfg.CORSAllowAll).
 		Msg("Starting Wirety server")
 
-	for _, origin := range cfg.CORSOrigins {
-		if origin == "*" && cfg.Auth.Enabled {
-			log.Warn().Msg("CORS_ORIGIN contains '*' while OIDC auth is enabled - set CORS_ORIGIN to your frontend URL(s) in production")
-			break
-		}
+	if cfg.CORSAllowAll {
+		log.Warn().Msg("CORS allows every origin (--cors-allow-all) - for local development only, set CORS_ORIGIN to your frontend URL(s) in production")
 	}
 
 	// Initialize repositories (choose Postgres or in-memory)
@@ -89,6 +113,7 @@ func main() {
 	var policyRepo domainnetwork.PolicyRepository
 	var routeRepo domainnetwork.RouteRepository
 	var dnsRepo domainnetwork.DNSRepository
+	var auditLogger audit.AuditLogger
 	var db *sql.DB
 
 	if cfg.Database.Enabled {
@@ -121,21 +146,48 @@ func main() {
 		policyRepo = pgrepo.NewPolicyRepository(db)
 		routeRepo = pgrepo.NewRouteRepository(db)
 		dnsRepo = pgrepo.NewDNSRepository(db)
+		auditLogger = pgrepo.NewAuditRepository(db)
 	} else {
 		log.Warn().Msg("DB disabled - using in-memory repositories")
-		networkRepo = memory.NewRepository()
+		memRepo := memory.NewRepository()
+		networkRepo = memRepo
 		ipamRepo = memory.NewIPAMRepository(context.Background())
 		userRepo = memory.NewUserRepository()
-		// TODO: Implement in-memory group repository
-		groupRepo = nil
-		policyRepo = nil
-		routeRepo = nil
-		dnsRepo = nil
+		groupRepo = memory.NewGroupRepository(memRepo)
+		policyRepo = memory.NewPolicyRepository(memRepo)
+		routeRepo = memory.NewRouteRepository(memRepo)
+		dnsRepo = memory.NewDNSRepository(memRepo)
+		auditLogger = memory.NewAuditRepository()
 	}
 
 	// Initialize services
 	networkService := appnetwork.NewService(networkRepo, ipamRepo, userRepo, groupRepo, routeRepo, dnsRepo, policyRepo)
+	networkService.SetStrictRouteConflicts(cfg.StrictRouteConflicts)
+	peerDefaults := domainnetwork.PeerDefaults{
+		MTU:                 cfg.PeerDefaults.MTU,
+		PersistentKeepalive: cfg.PeerDefaults.PersistentKeepalive,
+		DNS:                 cfg.PeerDefaults.DNS,
+	}
+	if err := peerDefaults.Validate(); err != nil {
+		log.Fatal().Err(err).Msg("invalid peer defaults")
+	}
+	networkService.SetPeerDefaults(peerDefaults)
+	networkService.SetPeerStaleThreshold(time.Duration(cfg.PeerStaleThreshold) * time.Second)
+	networkService.SetNetworkRetention(time.Duration(cfg.NetworkRetentionHours) * time.Hour)
+	networkService.SetConfigCacheSize(cfg.ConfigCacheSize)
+	networkService.SetAuditLogger(auditLogger)
+	if cfg.Webhook.URL != "" {
+		networkService.SetIncidentNotifier(webhook.NewNotifier(cfg.Webhook.URL, cfg.Webhook.Secret))
+		log.Info().Msg("Security incident webhook enabled")
+	}
 	ipamService := ipam.NewService(ipamRepo)
+	if err := ipamService.SetCIDRSizing(ipam.CIDRSizing{
+		MinPrefix: cfg.CIDRSizing.MinPrefix,
+		MaxPrefix: cfg.CIDRSizing.MaxPrefix,
+		Headroom:  cfg.CIDRSizing.Headroom,
+	}); err != nil {
+		log.Fatal().Err(err).Msg("invalid IPAM sizing configuration")
+	}
 
 	var authService *appauth.Service
 	if cfg.Auth.Enabled {
@@ -170,14 +222,19 @@ func main() {
 
 	// Initialize group service
 	var groupService api.GroupService
+	var groupServiceImpl *appgroup.Service
 	if groupRepo != nil && routeRepo != nil {
-		groupService = appgroup.NewService(groupRepo, networkRepo, routeRepo)
+		groupServiceImpl = appgroup.NewService(groupRepo, networkRepo, routeRepo)
+		groupServiceImpl.SetAuditLogger(auditLogger)
+		groupService = groupServiceImpl
 	}
 
 	// Initialize policy service
 	var policyService api.PolicyService
+	var policyServiceImpl *apppolicy.Service
 	if policyRepo != nil && routeRepo != nil {
-		policyServiceImpl := apppolicy.NewService(policyRepo, groupRepo, networkRepo, routeRepo)
+		policyServiceImpl = apppolicy.NewService(policyRepo, groupRepo, networkRepo, routeRepo)
+		policyServiceImpl.SetAuditLogger(auditLogger)
 		policyService = api.NewPolicyServiceAdapter(policyServiceImpl)
 		// Set policy service on network service for iptables rule generation
 		networkService.SetPolicyService(policyServiceImpl)
@@ -185,42 +242,70 @@ func main() {
 
 	// Initialize route service
 	var routeService api.RouteService
+	var routeServiceImpl *approute.Service
 	if routeRepo != nil {
-		routeService = approute.NewService(routeRepo, groupRepo, networkRepo)
+		routeServiceImpl = approute.NewService(routeRepo, groupRepo, networkRepo)
+		routeServiceImpl.SetAuditLogger(auditLogger)
+		routeService = routeServiceImpl
 	}
 
 	// Initialize DNS service
 	var dnsService api.DNSService
+	var dnsServiceImpl *appdns.Service
 	if dnsRepo != nil {
-		dnsServiceImpl := appdns.NewService(dnsRepo, routeRepo, networkRepo)
+		dnsServiceImpl = appdns.NewService(dnsRepo, routeRepo, networkRepo)
+		dnsServiceImpl.SetAuditLogger(auditLogger)
 		dnsService = api.NewDNSServiceAdapter(dnsServiceImpl)
 	}
 
 	// Initialize API handler
 	handler := api.NewHandler(networkService, ipamService, authService, groupService, policyService, routeService, dnsService, groupRepo, userRepo, &cfg.Auth)
+	handler.SetAuditLogger(auditLogger)
+	handler.SetRateLimit(cfg.RateLimit)
+	handler.SetReadinessChecks(db, ipamRepo)
+	handler.WebSocketManager().SetNotifyDebounce(time.Duration(cfg.NotifyDebounceMs) * time.Millisecond)
+	// Group, route and policy changes alter the configs of every peer they
+	// apply to: push them and invalidate the cached configs.
+	if groupServiceImpl != nil {
+		groupServiceImpl.SetWebSocketNotifier(handler.WebSocketManager())
+	}
+	if routeServiceImpl != nil {
+		routeServiceImpl.SetWebSocketNotifier(handler.WebSocketManager())
+	}
+	if policyServiceImpl != nil {
+		policyServiceImpl.SetWebSocketNotifier(handler.WebSocketManager())
+	}
+	if dnsServiceImpl != nil {
+		// DNS record changes only need to reach the jump agents
+		dnsServiceImpl.SetWebSocketNotifier(handler.WebSocketManager())
+	}
 
 	// Setup Gin router
 	gin.SetMode(gin.ReleaseMode)
 	r := gin.New()
 	r.Use(gin.Recovery())
+	r.Use(middleware.Tracing())
 	r.Use(middleware.RequestLogger())
 
-	// Configure CORS — enable credentials only when no wildcard origin is present
-	allowCredentials := true
-	for _, origin := range cfg.CORSOrigins {
-		if origin == "*" {
-			allowCredentials = false
-			break
-		}
-	}
+	// Configure CORS — credentials are only allowed for listed origins.
+	// Without any, only same-origin requests work, e.g. a dashboard served
+	// from the same host as the API.
 	corsConfig := cors.Config{
-		AllowOrigins:     cfg.CORSOrigins,
-		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
-		AllowCredentials: allowCredentials,
+		AllowMethods:  []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowHeaders:  []string{"Origin", "Content-Type", "Accept", "Authorization", middleware.ImpersonationHeader},
+		ExposeHeaders: []string{"Content-Length"},
+	}
+	switch {
+	case cfg.CORSAllowAll:
+		corsConfig.AllowAllOrigins = true
+		r.Use(cors.New(corsConfig))
+	case len(cfg.CORSOrigins) > 0:
+		corsConfig.AllowOrigins = cfg.CORSOrigins
+		corsConfig.AllowCredentials = true
+		r.Use(cors.New(corsConfig))
+	default:
+		log.Info().Msg("CORS_ORIGIN not set: cross-origin requests are refused")
 	}
-	r.Use(cors.New(corsConfig))
 
 	// Setup authentication middleware
 	authMiddleware := middleware.AuthMiddleware(authService, userRepo, &cfg.Auth)
@@ -232,10 +317,14 @@ func main() {
 
 	// Background cleanup.
 	// Two cadences:
-	//   • Hourly: long-lived state (user sessions, whitelist TTL).
-	//   • Every 2 minutes: captive portal tokens (10 min TTL) and endpoint
-	//     denylist (24 h TTL).  The token cleanup also walks unconsumed-and-
-	//     expired tokens to record strikes against peers that abandoned auth.
+	//   • Hourly: long-lived state (user sessions, whitelist TTL), the
+	//     reclaiming of orphaned IPAM allocations and the purge of networks
+	//     deleted longer than the retention window ago.
+	//   • Every 2 minutes: captive portal tokens (10 min TTL), endpoint
+	//     denylist (24 h TTL), expired temporary routes, expired peers and
+	//     expired enrollment tokens.  The captive portal token cleanup also
+	//     walks unconsumed-and-expired tokens to record strikes against peers
+	//     that abandoned auth.
 	go func() {
 		hourly := time.NewTicker(time.Hour)
 		defer hourly.Stop()
@@ -250,6 +339,12 @@ func main() {
 				if err := networkRepo.CleanupExpiredCaptivePortalWhitelist(context.Background()); err != nil {
 					log.Warn().Err(err).Msg("Captive portal whitelist cleanup failed")
 				}
+				if err := networkService.ReconcileIPAM(context.Background()); err != nil {
+					log.Warn().Err(err).Msg("IPAM reconciliation failed")
+				}
+				if err := networkService.PurgeDeletedNetworks(context.Background()); err != nil {
+					log.Warn().Err(err).Msg("Deleted network purge failed")
+				}
 			case <-fast.C:
 				if err := networkService.CleanupExpiredCaptivePortalTokens(context.Background()); err != nil {
 					log.Warn().Err(err).Msg("Captive portal token cleanup failed")
@@ -257,15 +352,67 @@ func main() {
 				if err := networkService.CleanupExpiredEndpointDenylist(context.Background()); err != nil {
 					log.Warn().Err(err).Msg("Endpoint denylist cleanup failed")
 				}
+				if err := networkService.CleanupExpiredTempRoutes(context.Background()); err != nil {
+					log.Warn().Err(err).Msg("Temporary route cleanup failed")
+				}
+				if err := networkService.CleanupExpiredPeers(context.Background()); err != nil {
+					log.Warn().Err(err).Msg("Peer expiry sweep failed")
+				}
+				if err := networkService.CleanupExpiredEnrollmentTokens(context.Background()); err != nil {
+					log.Warn().Err(err).Msg("Enrollment token sweep failed")
+				}
 			}
 		}
 	}()
 
+	// Ask the connected jump agents to check their listen port and internet
+	// access; GetPeerConnectivityStatus reports the results.
+	go func() {
+		ticker := time.NewTicker(appnetwork.JumpHealthCheckInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			handler.WebSocketManager().RequestJumpHealthChecks()
+		}
+	}()
+
 	// Start server
-	log.Info().Msgf("Starting Wirety server on port %s", cfg.HTTPPort)
-	if err := r.Run(":" + cfg.HTTPPort); err != nil {
+	srv := &http.Server{
+		Addr:              cfg.ListenAddress(),
+		Handler:           r,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	srv.RegisterOnShutdown(handler.WebSocketManager().CloseAll)
+	ln, err := net.Listen("tcp", srv.Addr)
+	if err != nil {
 		log.Fatal().Err(err).Msg("Failed to start server")
 	}
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	ctx, stop := context.WithCancel(context.Background())
+
+	// Handle shutdown gracefully
+	go func() {
+		<-sigCh
+		log.Info().Msg("shutdown signal received, stopping server...")
+
+		stop()
+	}()
+
+	log.Info().Msgf("Starting Wirety server on %s", srv.Addr)
+	serveErr := api.Serve(ctx, srv, ln, time.Duration(cfg.ShutdownTimeout)*time.Second)
+
+	// Flush the spans still queued, including those of the last requests
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	if err := tracing.Shutdown(shutdownCtx); err != nil {
+		log.Warn().Err(err).Msg("flush trace spans")
+	}
+	cancel()
+
+	if serveErr != nil {
+		log.Fatal().Err(serveErr).Msg("Server stopped with an error")
+	}
+	log.Info().Msg("server stopped")
 }
 
 func generateAdminPassword() string {
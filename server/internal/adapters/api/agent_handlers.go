@@ -28,7 +28,7 @@ func (h *Handler) ResolveAgent(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 		return
 	}
-	cfg, err := h.service.GeneratePeerConfig(c.Request.Context(), networkID, peer.ID)
+	cfg, err := h.service.GenerateAgentConfig(c.Request.Context(), networkID, peer.ID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
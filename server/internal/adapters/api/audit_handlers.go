@@ -0,0 +1,69 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"wirety/internal/audit"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PaginatedAuditEntries represents a paginated list of audit log entries
+type PaginatedAuditEntries struct {
+	Data     []*audit.Entry `json:"data"`
+	Total    int            `json:"total"`
+	Page     int            `json:"page"`
+	PageSize int            `json:"page_size"`
+}
+
+// SetAuditLogger sets the audit log served by ListAuditEntries
+func (h *Handler) SetAuditLogger(l audit.AuditLogger) {
+	h.auditLogger = l
+}
+
+// ListAuditEntries godoc
+//
+// @Summary      List audit log entries (paginated)
+// @Description  Get a paginated list of the mutating operations performed on a network, newest first (admin only)
+// @Tags         networks
+// @Produce      json
+// @Param        networkId path string true "Network ID"
+// @Param        page      query int    false "Page number" default(1)
+// @Param        page_size query int    false "Page size" default(20)
+// @Success      200 {object} PaginatedAuditEntries
+// @Failure      403 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Failure      501 {object} map[string]string
+// @Router       /networks/{networkId}/audit [get]
+// @Security     BearerAuth
+func (h *Handler) ListAuditEntries(c *gin.Context) {
+	if h.auditLogger == nil {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "audit log is not configured"})
+		return
+	}
+
+	networkID := c.Param("networkId")
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 500 {
+		pageSize = 20
+	}
+
+	entries, total, err := h.auditLogger.List(c.Request.Context(), networkID, (page-1)*pageSize, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, PaginatedAuditEntries{
+		Data:     entries,
+		Total:    total,
+		Page:     page,
+		PageSize: pageSize,
+	})
+}
@@ -0,0 +1,104 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"wirety/internal/adapters/api/middleware"
+	"wirety/internal/adapters/db/memory"
+	"wirety/internal/application/group"
+	"wirety/internal/domain/auth"
+	domain "wirety/internal/domain/network"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestListAuditEntries_RecordsActorAndPaginates(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+
+	repo := memory.NewRepository()
+	if err := repo.CreateNetwork(ctx, &domain.Network{ID: "net1", Name: "net1", CIDR: "10.0.0.0/24"}); err != nil {
+		t.Fatal(err)
+	}
+	auditLog := memory.NewAuditRepository()
+	groupService := group.NewService(memory.NewGroupRepository(repo), repo, memory.NewRouteRepository(repo))
+	groupService.SetAuditLogger(auditLog)
+
+	h := &Handler{groupService: groupService}
+	h.SetAuditLogger(auditLog)
+
+	admin := &auth.User{ID: "admin", Email: "admin@example.com", Role: auth.RoleAdministrator}
+	asAdmin := func(c *gin.Context) {
+		c.Set(middleware.UserContextKey, admin)
+		c.Next()
+	}
+	r := gin.New()
+	api := r.Group("", asAdmin, middleware.AuditActor())
+	api.POST("/networks/:networkId/groups", h.CreateGroup)
+	api.GET("/networks/:networkId/audit", h.ListAuditEntries)
+
+	for i := 0; i < 3; i++ {
+		req := httptest.NewRequest(http.MethodPost, "/networks/net1/groups", strings.NewReader(fmt.Sprintf(`{"name":"group-%d"}`, i)))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("create group: status %d: %s", w.Code, w.Body)
+		}
+	}
+
+	list := func(query string) PaginatedAuditEntries {
+		t.Helper()
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/networks/net1/audit"+query, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("list audit: status %d: %s", w.Code, w.Body)
+		}
+		var page PaginatedAuditEntries
+		if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil {
+			t.Fatal(err)
+		}
+		return page
+	}
+
+	page := list("?page=1&page_size=2")
+	if page.Total != 3 || len(page.Data) != 2 || page.PageSize != 2 {
+		t.Fatalf("page 1: total=%d len=%d page_size=%d", page.Total, len(page.Data), page.PageSize)
+	}
+	for _, e := range page.Data {
+		if e.Action != "group.create" || e.ActorID != "admin" || e.ActorEmail != "admin@example.com" || e.NetworkID != "net1" {
+			t.Errorf("unexpected entry %+v", e)
+		}
+	}
+	newest, err := groupService.ListGroups(ctx, "net1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lastID string
+	for _, g := range newest {
+		if g.Name == "group-2" {
+			lastID = g.ID
+		}
+	}
+	if page.Data[0].ResourceID != lastID {
+		t.Errorf("entries are not newest first: got resource %s, want %s", page.Data[0].ResourceID, lastID)
+	}
+
+	page = list("?page=2&page_size=2")
+	if page.Total != 3 || len(page.Data) != 1 || page.Page != 2 {
+		t.Fatalf("page 2: total=%d len=%d page=%d", page.Total, len(page.Data), page.Page)
+	}
+
+	// Other networks have their own log.
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/networks/net2/audit", nil))
+	if !strings.Contains(w.Body.String(), `"total":0`) {
+		t.Errorf("net2 audit log should be empty: %s", w.Body)
+	}
+}
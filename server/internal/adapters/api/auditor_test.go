@@ -0,0 +1,121 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"wirety/internal/adapters/api/middleware"
+	"wirety/internal/adapters/db/memory"
+	"wirety/internal/application/network"
+	"wirety/internal/domain/auth"
+	domain "wirety/internal/domain/network"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAuditor_ReadsEverythingMutatesNothing(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+
+	repo := memory.NewRepository()
+	userRepo := memory.NewUserRepository()
+	auditor := &auth.User{ID: "auditor", Email: "auditor@example.com", Role: auth.RoleAuditor}
+	alice := &auth.User{ID: "alice", Email: "alice@example.com", Role: auth.RoleUser, AuthorizedNetworks: []string{"net1"}}
+	for _, u := range []*auth.User{auditor, alice} {
+		if err := userRepo.CreateUser(u); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := repo.CreateNetwork(ctx, &domain.Network{ID: "net1", Name: "net1", CIDR: "10.0.0.0/24"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []*domain.Peer{
+		{ID: "jump", Name: "jump", Address: "10.0.0.1", IsJump: true, Token: "jump-secret"},
+		{ID: "alice-laptop", Name: "alice-laptop", Address: "10.0.0.2", OwnerID: "alice"},
+		{ID: "bob-laptop", Name: "bob-laptop", Address: "10.0.0.3", OwnerID: "bob"},
+	} {
+		if err := repo.CreatePeer(ctx, "net1", p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	h := &Handler{
+		service:  network.NewService(repo, nil, userRepo, nil, nil, nil, nil),
+		userRepo: userRepo,
+	}
+	asAuditor := func(c *gin.Context) {
+		c.Set(middleware.UserContextKey, auditor)
+		c.Next()
+	}
+	requireAdmin := middleware.RequireAdmin()
+	r := gin.New()
+	api := r.Group("", asAuditor, middleware.AuditorReadOnly())
+	api.GET("/users", requireAdmin, h.ListUsers)
+	api.DELETE("/users/:userId", requireAdmin, h.DeleteUser)
+	api.GET("/networks", h.ListNetworks)
+	api.DELETE("/networks/:networkId", requireAdmin, h.DeleteNetwork)
+	net1 := api.Group("/networks/:networkId", middleware.RequireNetworkAccess())
+	net1.GET("", h.GetNetwork)
+	net1.GET("/configs.zip", requireAdmin, h.ExportPeerConfigs)
+	net1.GET("/peers", h.ListPeers)
+	net1.POST("/peers", h.CreatePeer)
+	net1.GET("/peers/:peerId", h.GetPeer)
+	net1.PUT("/peers/:peerId", h.UpdatePeer)
+	net1.GET("/peers/:peerId/config", h.GetPeerConfig)
+
+	do := func(method, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(`{"name":"evil"}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	for _, path := range []string{"/users", "/networks", "/networks/net1", "/networks/net1/peers/bob-laptop"} {
+		if w := do(http.MethodGet, path); w.Code != http.StatusOK {
+			t.Errorf("GET %s: status %d: %s", path, w.Code, w.Body)
+		}
+	}
+
+	// Every peer is visible, not only the auditor's own, but secrets are not
+	w := do(http.MethodGet, "/networks/net1/peers")
+	if w.Code != http.StatusOK {
+		t.Fatalf("list peers: status %d: %s", w.Code, w.Body)
+	}
+	var page PaginatedPeers
+	if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 3 {
+		t.Errorf("auditor sees %d peers, want 3", page.Total)
+	}
+	if strings.Contains(w.Body.String(), "jump-secret") {
+		t.Errorf("enrollment token leaked to auditor: %s", w.Body)
+	}
+	for _, path := range []string{"/networks/net1/configs.zip", "/networks/net1/peers/bob-laptop/config"} {
+		if w := do(http.MethodGet, path); w.Code != http.StatusForbidden {
+			t.Errorf("GET %s: status %d, want 403", path, w.Code)
+		}
+	}
+
+	for _, tc := range []struct{ method, path string }{
+		{http.MethodPost, "/networks/net1/peers"},
+		{http.MethodPut, "/networks/net1/peers/bob-laptop"},
+		{http.MethodDelete, "/networks/net1"},
+		{http.MethodDelete, "/users/alice"},
+	} {
+		if w := do(tc.method, tc.path); w.Code != http.StatusForbidden {
+			t.Errorf("%s %s: status %d, want 403", tc.method, tc.path, w.Code)
+		}
+	}
+	if peers, _ := repo.ListPeers(ctx, "net1"); len(peers) != 3 {
+		t.Errorf("auditor changed the peers: %d peers", len(peers))
+	}
+	if _, err := userRepo.GetUser("alice"); err != nil {
+		t.Errorf("auditor deleted a user: %v", err)
+	}
+}
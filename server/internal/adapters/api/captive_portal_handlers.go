@@ -29,7 +29,7 @@ func (h *Handler) CreateCaptivePortalToken(c *gin.Context) {
 		return
 	}
 
-	networkID, peer, err := h.service.ResolveAgentToken(c.Request.Context(), token)
+	networkID, peer, err := h.service.AuthenticateAgentToken(c.Request.Context(), token)
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
 		return
@@ -1,6 +1,8 @@
 package api
 
 import (
+	"errors"
+	"io"
 	"net/http"
 
 	"wirety/internal/domain/network"
@@ -155,3 +157,234 @@ func (h *Handler) GetNetworkDNSRecords(c *gin.Context) {
 
 	c.JSON(http.StatusOK, records)
 }
+
+// GetNetworkZoneFile godoc
+//
+//	@Summary		Get network DNS zone file
+//	@Description	Render the network's peer and route DNS records as a BIND-compatible zone file (admin only). The forward zone holds A/AAAA records; the reverse zones hold PTR records for peer addresses.
+//	@Tags			dns
+//	@Produce		plain
+//	@Param			networkId	path		string	true	"Network ID"
+//	@Param			type		query		string	false	"Zone to render: forward (default), reverse-ipv4 or reverse-ipv6"
+//	@Success		200			{string}	string
+//	@Failure		400			{object}	map[string]string
+//	@Failure		403			{object}	map[string]string
+//	@Router			/networks/{networkId}/dns/zonefile [get]
+//	@Security		BearerAuth
+func (h *Handler) GetNetworkZoneFile(c *gin.Context) {
+	networkID := c.Param("networkId")
+
+	zone, err := h.dnsService.GetNetworkZoneFile(c.Request.Context(), networkID, c.Query("type"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Data(http.StatusOK, "text/dns; charset=utf-8", []byte(zone))
+}
+
+// maxZoneFileSize bounds the body of a zone file import
+const maxZoneFileSize = 1 << 20
+
+// ExportNetworkZoneFile godoc
+//
+//	@Summary		Export network DNS records as a zone file
+//	@Description	Render the network-scoped DNS records (A/AAAA, CNAME, TXT) as an RFC 1035 zone file that the import endpoint reads back without loss (admin only). Peer and route records are not included.
+//	@Tags			dns
+//	@Produce		plain
+//	@Param			networkId	path		string	true	"Network ID"
+//	@Success		200			{string}	string
+//	@Failure		403			{object}	map[string]string
+//	@Failure		404			{object}	map[string]string
+//	@Router			/networks/{networkId}/dns/export [get]
+//	@Security		BearerAuth
+func (h *Handler) ExportNetworkZoneFile(c *gin.Context) {
+	zone, err := h.dnsService.ExportNetworkZoneFile(c.Request.Context(), c.Param("networkId"))
+	if err != nil {
+		writeNetworkDNSRecordError(c, err)
+		return
+	}
+
+	c.Data(http.StatusOK, "text/dns; charset=utf-8", []byte(zone))
+}
+
+// ImportNetworkZoneFile godoc
+//
+//	@Summary		Import network DNS records from a zone file
+//	@Description	Create or update network-scoped DNS records from an RFC 1035 zone file sent as the request body (admin only). A and AAAA records of a name are merged; CNAME and TXT records are kept. Records that cannot be imported are reported and skipped; a syntax error rejects the whole file.
+//	@Tags			dns
+//	@Accept			plain
+//	@Produce		json
+//	@Param			networkId	path		string	true	"Network ID"
+//	@Param			zone		body		string	true	"Zone file"
+//	@Success		200			{object}	ZoneImportResult
+//	@Failure		400			{object}	map[string]string
+//	@Failure		403			{object}	map[string]string
+//	@Failure		404			{object}	map[string]string
+//	@Router			/networks/{networkId}/dns/import [post]
+//	@Security		BearerAuth
+func (h *Handler) ImportNetworkZoneFile(c *gin.Context) {
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxZoneFileSize+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(body) > maxZoneFileSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "zone file exceeds 1 MiB"})
+		return
+	}
+
+	result, err := h.dnsService.ImportNetworkZoneFile(c.Request.Context(), c.Param("networkId"), string(body))
+	if err != nil {
+		writeNetworkDNSRecordError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// CreateNetworkDNSRecord godoc
+//
+//	@Summary		Create a network DNS record
+//	@Description	Create a DNS record scoped to the network rather than a route, resolving as <name>.<network>.<suffix> (admin only). The addresses may lie outside every route.
+//	@Tags			dns
+//	@Accept			json
+//	@Produce		json
+//	@Param			networkId	path		string							true	"Network ID"
+//	@Param			record		body		network.DNSMappingCreateRequest	true	"DNS record creation request"
+//	@Success		201			{object}	network.NetworkDNSRecord
+//	@Failure		400			{object}	map[string]string
+//	@Failure		403			{object}	map[string]string
+//	@Failure		404			{object}	map[string]string
+//	@Failure		409			{object}	map[string]string
+//	@Router			/networks/{networkId}/dns/records [post]
+//	@Security		BearerAuth
+func (h *Handler) CreateNetworkDNSRecord(c *gin.Context) {
+	networkID := c.Param("networkId")
+
+	var req network.DNSMappingCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	record, err := h.dnsService.CreateNetworkDNSRecord(c.Request.Context(), networkID, &req)
+	if err != nil {
+		writeNetworkDNSRecordError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, record)
+}
+
+// ListNetworkDNSRecords godoc
+//
+//	@Summary		List network DNS records
+//	@Description	Get the DNS records scoped directly to the network (admin only). Route mappings are listed per route.
+//	@Tags			dns
+//	@Produce		json
+//	@Param			networkId	path		string	true	"Network ID"
+//	@Success		200			{array}		network.NetworkDNSRecord
+//	@Failure		403			{object}	map[string]string
+//	@Failure		404			{object}	map[string]string
+//	@Router			/networks/{networkId}/dns/records [get]
+//	@Security		BearerAuth
+func (h *Handler) ListNetworkDNSRecords(c *gin.Context) {
+	networkID := c.Param("networkId")
+
+	records, err := h.dnsService.ListNetworkDNSRecords(c.Request.Context(), networkID)
+	if err != nil {
+		writeNetworkDNSRecordError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, records)
+}
+
+// GetNetworkDNSRecord godoc
+//
+//	@Summary		Get a network DNS record
+//	@Description	Get a network-scoped DNS record by ID (admin only)
+//	@Tags			dns
+//	@Produce		json
+//	@Param			networkId	path		string	true	"Network ID"
+//	@Param			recordId	path		string	true	"DNS record ID"
+//	@Success		200			{object}	network.NetworkDNSRecord
+//	@Failure		403			{object}	map[string]string
+//	@Failure		404			{object}	map[string]string
+//	@Router			/networks/{networkId}/dns/records/{recordId} [get]
+//	@Security		BearerAuth
+func (h *Handler) GetNetworkDNSRecord(c *gin.Context) {
+	record, err := h.dnsService.GetNetworkDNSRecord(c.Request.Context(), c.Param("networkId"), c.Param("recordId"))
+	if err != nil {
+		writeNetworkDNSRecordError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, record)
+}
+
+// UpdateNetworkDNSRecord godoc
+//
+//	@Summary		Update a network DNS record
+//	@Description	Update a network-scoped DNS record (admin only)
+//	@Tags			dns
+//	@Accept			json
+//	@Produce		json
+//	@Param			networkId	path		string							true	"Network ID"
+//	@Param			recordId	path		string							true	"DNS record ID"
+//	@Param			record		body		network.DNSMappingUpdateRequest	true	"DNS record update request"
+//	@Success		200			{object}	network.NetworkDNSRecord
+//	@Failure		400			{object}	map[string]string
+//	@Failure		403			{object}	map[string]string
+//	@Failure		404			{object}	map[string]string
+//	@Failure		409			{object}	map[string]string
+//	@Router			/networks/{networkId}/dns/records/{recordId} [put]
+//	@Security		BearerAuth
+func (h *Handler) UpdateNetworkDNSRecord(c *gin.Context) {
+	var req network.DNSMappingUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	record, err := h.dnsService.UpdateNetworkDNSRecord(c.Request.Context(), c.Param("networkId"), c.Param("recordId"), &req)
+	if err != nil {
+		writeNetworkDNSRecordError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, record)
+}
+
+// DeleteNetworkDNSRecord godoc
+//
+//	@Summary		Delete a network DNS record
+//	@Description	Delete a network-scoped DNS record by ID (admin only)
+//	@Tags			dns
+//	@Param			networkId	path	string	true	"Network ID"
+//	@Param			recordId	path	string	true	"DNS record ID"
+//	@Success		204
+//	@Failure		403	{object}	map[string]string
+//	@Failure		404	{object}	map[string]string
+//	@Router			/networks/{networkId}/dns/records/{recordId} [delete]
+//	@Security		BearerAuth
+func (h *Handler) DeleteNetworkDNSRecord(c *gin.Context) {
+	if err := h.dnsService.DeleteNetworkDNSRecord(c.Request.Context(), c.Param("networkId"), c.Param("recordId")); err != nil {
+		writeNetworkDNSRecordError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func writeNetworkDNSRecordError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, network.ErrNetworkDNSRecordNotFound), errors.Is(err, network.ErrNetworkNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, network.ErrDuplicateNetworkDNSName):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
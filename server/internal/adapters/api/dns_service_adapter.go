@@ -58,8 +58,63 @@ func (a *DNSServiceAdapter) GetNetworkDNSRecords(ctx context.Context, networkID
 			IPv6Address: record.IPv6Address,
 			FQDN:        record.FQDN,
 			Type:        record.Type,
+			RecordType:  record.RecordType,
+			Value:       record.Value,
 		}
 	}
 
 	return apiRecords, nil
 }
+
+// GetNetworkZoneFile renders the network's DNS records as a zone file
+func (a *DNSServiceAdapter) GetNetworkZoneFile(ctx context.Context, networkID, kind string) (string, error) {
+	return a.service.GetNetworkZoneFile(ctx, networkID, kind)
+}
+
+// ExportNetworkZoneFile renders the network-scoped DNS records as a zone file
+func (a *DNSServiceAdapter) ExportNetworkZoneFile(ctx context.Context, networkID string) (string, error) {
+	return a.service.ExportNetworkZoneFile(ctx, networkID)
+}
+
+// ImportNetworkZoneFile imports network-scoped DNS records from a zone file
+func (a *DNSServiceAdapter) ImportNetworkZoneFile(ctx context.Context, networkID, zone string) (*ZoneImportResult, error) {
+	result, err := a.service.ImportNetworkZoneFile(ctx, networkID, zone)
+	if err != nil {
+		return nil, err
+	}
+
+	apiResult := &ZoneImportResult{
+		Created:   result.Created,
+		Updated:   result.Updated,
+		Unchanged: result.Unchanged,
+	}
+	for _, e := range result.Errors {
+		apiResult.Errors = append(apiResult.Errors, ZoneImportError{Name: e.Name, Type: e.Type, Error: e.Error})
+	}
+	return apiResult, nil
+}
+
+// CreateNetworkDNSRecord creates a network-scoped DNS record
+func (a *DNSServiceAdapter) CreateNetworkDNSRecord(ctx context.Context, networkID string, req *network.DNSMappingCreateRequest) (*network.NetworkDNSRecord, error) {
+	return a.service.CreateNetworkDNSRecord(ctx, networkID, req)
+}
+
+// GetNetworkDNSRecord retrieves a network-scoped DNS record by ID
+func (a *DNSServiceAdapter) GetNetworkDNSRecord(ctx context.Context, networkID, recordID string) (*network.NetworkDNSRecord, error) {
+	return a.service.GetNetworkDNSRecord(ctx, networkID, recordID)
+}
+
+// UpdateNetworkDNSRecord updates a network-scoped DNS record
+func (a *DNSServiceAdapter) UpdateNetworkDNSRecord(ctx context.Context, networkID, recordID string, req *network.DNSMappingUpdateRequest) (*network.NetworkDNSRecord, error) {
+	return a.service.UpdateNetworkDNSRecord(ctx, networkID, recordID, req)
+}
+
+// DeleteNetworkDNSRecord deletes a network-scoped DNS record
+func (a *DNSServiceAdapter) DeleteNetworkDNSRecord(ctx context.Context, networkID, recordID string) error {
+	return a.service.DeleteNetworkDNSRecord(ctx, networkID, recordID)
+}
+
+// ListNetworkDNSRecords lists the network-scoped DNS records of a network
+func (a *DNSServiceAdapter) ListNetworkDNSRecords(ctx context.Context, networkID string) ([]*network.NetworkDNSRecord, error) {
+	return a.service.ListNetworkDNSRecords(ctx, networkID)
+}
@@ -2,18 +2,25 @@ package api
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"net/http"
+	"time"
 
 	appauth "wirety/internal/application/auth"
 	"wirety/internal/application/ipam"
 	"wirety/internal/application/network"
 	"wirety/internal/adapters/api/middleware"
+	"wirety/internal/audit"
 	"wirety/internal/config"
 	"wirety/internal/domain/auth"
+	domainipam "wirety/internal/domain/ipam"
 	domain "wirety/internal/domain/network"
 	"wirety/internal/infrastructure/validation"
+	"wirety/internal/metrics"
 
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	swaggerFiles "github.com/swaggo/files"     // swagger embed files
 	ginSwagger "github.com/swaggo/gin-swagger" // gin-swagger middleware
 
@@ -39,10 +46,21 @@ type Handler struct {
 	policyService PolicyService
 	routeService  RouteService
 	dnsService    DNSService
+	importer      *network.Importer
 	wsManager     *WebSocketManager
 	userRepo      auth.Repository
 	groupRepo     domain.GroupRepository
 	authConfig    *config.AuthConfig
+	impersonator  *middleware.Impersonator
+	auditLogger   audit.AuditLogger
+	rateLimit     config.RateLimitConfig
+	db            pinger
+	ipamRepo      domainipam.Repository
+}
+
+// pinger is the part of *sql.DB the readiness probe uses.
+type pinger interface {
+	PingContext(ctx context.Context) error
 }
 
 // GroupService defines the interface for group operations
@@ -71,6 +89,7 @@ type PolicyService interface {
 	ListPolicies(ctx context.Context, networkID string) ([]*domain.Policy, error)
 	AddRuleToPolicy(ctx context.Context, networkID, policyID string, rule *domain.PolicyRule) error
 	RemoveRuleFromPolicy(ctx context.Context, networkID, policyID, ruleID string) error
+	EvaluatePolicy(ctx context.Context, networkID string, req *domain.PolicyEvaluateRequest) (*domain.PolicyEvaluation, error)
 }
 
 // RouteService defines the interface for route operations
@@ -85,13 +104,32 @@ type RouteService interface {
 
 // DNSRecord represents a combined DNS record (peer or route-based).
 // Dual-stack: IPAddress (IPv4) and IPv6Address are independent — at least one
-// is always populated.  See application/dns/service.go::DNSRecord for details.
+// is populated, except for CNAME and TXT network records, which carry Value.
+// See application/dns/service.go::DNSRecord for details.
 type DNSRecord struct {
 	Name        string `json:"name"`
 	IPAddress   string `json:"ip_address,omitempty"`
 	IPv6Address string `json:"ip_address_v6,omitempty"`
 	FQDN        string `json:"fqdn"`
-	Type        string `json:"type"` // "peer" or "route"
+	Type        string `json:"type"`                  // "peer", "route" or "network"
+	RecordType  string `json:"record_type,omitempty"` // "CNAME" or "TXT"; empty for address records
+	Value       string `json:"value,omitempty"`
+}
+
+// ZoneImportResult reports the outcome of a zone file import.  See
+// application/dns/zone_import.go::ZoneImportResult for details.
+type ZoneImportResult struct {
+	Created   int               `json:"created"`
+	Updated   int               `json:"updated"`
+	Unchanged int               `json:"unchanged"`
+	Errors    []ZoneImportError `json:"errors,omitempty"`
+}
+
+// ZoneImportError is a zone file record that could not be imported
+type ZoneImportError struct {
+	Name  string `json:"name"`
+	Type  string `json:"type"`
+	Error string `json:"error"`
 }
 
 // DNSService defines the interface for DNS mapping operations
@@ -102,6 +140,14 @@ type DNSService interface {
 	DeleteDNSMapping(ctx context.Context, networkID, routeID, mappingID string) error
 	ListDNSMappings(ctx context.Context, networkID, routeID string) ([]*domain.DNSMapping, error)
 	GetNetworkDNSRecords(ctx context.Context, networkID string) ([]DNSRecord, error)
+	GetNetworkZoneFile(ctx context.Context, networkID, kind string) (string, error)
+	ExportNetworkZoneFile(ctx context.Context, networkID string) (string, error)
+	ImportNetworkZoneFile(ctx context.Context, networkID, zone string) (*ZoneImportResult, error)
+	CreateNetworkDNSRecord(ctx context.Context, networkID string, req *domain.DNSMappingCreateRequest) (*domain.NetworkDNSRecord, error)
+	GetNetworkDNSRecord(ctx context.Context, networkID, recordID string) (*domain.NetworkDNSRecord, error)
+	UpdateNetworkDNSRecord(ctx context.Context, networkID, recordID string, req *domain.DNSMappingUpdateRequest) (*domain.NetworkDNSRecord, error)
+	DeleteNetworkDNSRecord(ctx context.Context, networkID, recordID string) error
+	ListNetworkDNSRecords(ctx context.Context, networkID string) ([]*domain.NetworkDNSRecord, error)
 }
 
 // NewHandler creates a new API handler
@@ -119,26 +165,58 @@ func NewHandler(service *network.Service, ipamService *ipam.Service, authService
 		policyService: policyService,
 		routeService:  routeService,
 		dnsService:    dnsService,
+		importer:      network.NewImporter(service, groupService, policyService, routeService),
 		wsManager:     wsManager,
 		userRepo:      userRepo,
 		groupRepo:     groupRepo,
 		authConfig:    authConfig,
+		impersonator:  middleware.NewImpersonator(userRepo),
 	}
 }
 
+// SetRateLimit sets the per-IP limit of the public token endpoints.  It
+// must be called before RegisterRoutes.
+func (h *Handler) SetRateLimit(cfg config.RateLimitConfig) {
+	h.rateLimit = cfg
+}
+
+// SetReadinessChecks sets the connections /health/ready checks.  db is nil
+// when the server runs on the in-memory repositories.
+func (h *Handler) SetReadinessChecks(db *sql.DB, ipamRepo domainipam.Repository) {
+	if db != nil {
+		h.db = db
+	}
+	h.ipamRepo = ipamRepo
+}
+
+// WebSocketManager returns the manager pushing updates to connected agents.
+func (h *Handler) WebSocketManager() *WebSocketManager {
+	return h.wsManager
+}
+
 // RegisterRoutes registers all API routes
 func (h *Handler) RegisterRoutes(r *gin.Engine, authMiddleware gin.HandlerFunc, requireAdmin gin.HandlerFunc, requireNetworkAccess gin.HandlerFunc) {
 	api := r.Group("/api/v1")
 
-	// Public routes (no auth required)
+	// Prometheus metrics are served at the root, outside the API and its auth
+	metrics.RegisterGauges(h.inventory, h.wsManager.ConnectionCount)
+	r.GET("/metrics", gin.WrapH(promhttp.Handler()))
+
+	// Public routes (no auth required).  The ones taking an agent token or
+	// a captive-portal token are rate limited per IP against brute force,
+	// with a bucket per group so that agent traffic from an IP does not use
+	// up its captive-portal budget.
+	agentRateLimit := middleware.RateLimit(h.rateLimit.RPS, h.rateLimit.Burst)
+	captivePortalRateLimit := middleware.RateLimit(h.rateLimit.RPS, h.rateLimit.Burst)
 	{
 		api.GET("/health", h.Health)
+		api.GET("/health/ready", h.Ready)
 		api.GET("/auth/config", h.GetAuthConfig)
 		api.POST("/auth/token", h.ExchangeToken)
 		api.POST("/auth/login", h.SimpleLogin)
 		api.POST("/auth/logout", h.Logout)
-		api.GET("/agent/resolve", h.ResolveAgent)
-		api.GET("/ws", h.HandleWebSocketToken) // token-based WebSocket
+		api.GET("/agent/resolve", agentRateLimit, h.ResolveAgent)
+		api.GET("/ws", agentRateLimit, h.HandleWebSocketToken) // token-based WebSocket
 		// NOTE: the legacy /ws/:networkId/:peerId route was removed — it was
 		// unauthenticated and streamed the peer's full WireGuard config (incl.
 		// its private key) to anyone who knew the network/peer UUIDs. All agents
@@ -146,8 +224,8 @@ func (h *Handler) RegisterRoutes(r *gin.Engine, authMiddleware gin.HandlerFunc,
 
 		// Captive portal: token creation is agent-authenticated (enrollment token),
 		// authenticate is unauthenticated (uses captive_token + session_hash).
-		api.POST("/captive-portal/token", h.CreateCaptivePortalToken)
-		api.POST("/captive-portal/authenticate", h.AuthenticateCaptivePortal)
+		api.POST("/captive-portal/token", captivePortalRateLimit, h.CreateCaptivePortalToken)
+		api.POST("/captive-portal/authenticate", captivePortalRateLimit, h.AuthenticateCaptivePortal)
 		// /start is the browser-binding bouncer that the agent's redirect
 		// targets — sets the cp_state cookie and 302s to /captive-portal.
 		// Public: it must be reachable WITHOUT a session cookie, since the
@@ -161,7 +239,7 @@ func (h *Handler) RegisterRoutes(r *gin.Engine, authMiddleware gin.HandlerFunc,
 
 	// Protected routes (auth required)
 	protected := api.Group("")
-	protected.Use(authMiddleware)
+	protected.Use(authMiddleware, h.impersonator.Middleware(), middleware.AuditorReadOnly(), middleware.AuditActor())
 	{
 		// User management routes
 		users := protected.Group("/users")
@@ -180,6 +258,11 @@ func (h *Handler) RegisterRoutes(r *gin.Engine, authMiddleware gin.HandlerFunc,
 				adminUsers.GET("/:userId", h.GetUser)
 				adminUsers.PUT("/:userId", h.UpdateUser)
 				adminUsers.DELETE("/:userId", h.DeleteUser)
+				adminUsers.POST("/:userId/impersonate", h.StartImpersonation)
+				adminUsers.GET("/:userId/api-keys", h.ListUserAPIKeys)
+				adminUsers.POST("/:userId/api-keys", h.CreateUserAPIKey)
+				adminUsers.DELETE("/:userId/api-keys/:tokenId", h.RevokeUserAPIKey)
+				adminUsers.POST("/impersonations/end", h.EndImpersonation)
 			}
 		}
 
@@ -188,6 +271,8 @@ func (h *Handler) RegisterRoutes(r *gin.Engine, authMiddleware gin.HandlerFunc,
 		{
 			networks.GET("", h.ListNetworks)
 			networks.POST("", requireAdmin, h.CreateNetwork)
+			networks.POST("/validate", requireAdmin, h.ValidateNetworkDocument)
+			networks.POST("/import", requireAdmin, h.ImportNetwork)
 
 			networkOps := networks.Group("/:networkId")
 			networkOps.Use(requireNetworkAccess)
@@ -195,22 +280,57 @@ func (h *Handler) RegisterRoutes(r *gin.Engine, authMiddleware gin.HandlerFunc,
 				networkOps.GET("", h.GetNetwork)
 				networkOps.PUT("", requireAdmin, h.UpdateNetwork)
 				networkOps.DELETE("", requireAdmin, h.DeleteNetwork)
+				networkOps.POST("/restore", requireAdmin, h.RestoreNetwork)
+				networkOps.DELETE("/ipam/:ip", requireAdmin, h.ReleaseNetworkIP)
+				networkOps.POST("/rotate-psk", requireAdmin, h.RotatePresharedKeys)
+				networkOps.POST("/clone", requireAdmin, h.CloneNetwork)
+				networkOps.GET("/audit", requireAdmin, h.ListAuditEntries)
+				networkOps.GET("/configs.zip", requireAdmin, h.ExportPeerConfigs)
+				networkOps.GET("/topology", requireAdmin, h.GetTopology)
+				networkOps.GET("/export", requireAdmin, h.ExportNetwork)
+
+				// Peer profile routes
+				profiles := networkOps.Group("/profiles")
+				{
+					profiles.GET("", h.ListPeerProfiles)
+					profiles.POST("", requireAdmin, h.CreatePeerProfile)
+					profiles.GET("/:profileId", h.GetPeerProfile)
+					profiles.PUT("/:profileId", requireAdmin, h.UpdatePeerProfile)
+					profiles.DELETE("/:profileId", requireAdmin, h.DeletePeerProfile)
+				}
 
 				// Peer routes
 				peers := networkOps.Group("/peers")
 				{
 					peers.POST("", h.CreatePeer)
+					peers.POST("/bulk", h.CreatePeersBulk)
 					peers.GET("", h.ListPeers)
 					peers.GET("/:peerId", h.GetPeer)
 					peers.PUT("/:peerId", h.UpdatePeer)
 					peers.DELETE("/:peerId", h.DeletePeer)
 					peers.GET("/:peerId/config", h.GetPeerConfig)
 					peers.GET("/:peerId/session", h.GetPeerConnectivityStatus)
+					peers.GET("/:peerId/stats", h.GetPeerStats)
+					peers.GET("/:peerId/sessions/history", requireAdmin, h.ListPeerSessionHistory)
+					peers.POST("/:peerId/sessions/:sessionId/revoke", requireAdmin, h.RevokePeerSession)
 					peers.GET("/:peerId/reachability", h.GetPeerReachability)
 					peers.POST("/:peerId/revoke-auth", h.RevokePeerAuthentication)
+					peers.POST("/:peerId/rotate-token", h.RotatePeerToken)
+					peers.POST("/:peerId/sync", h.SyncPeer)
+					peers.POST("/:peerId/quarantine", requireAdmin, h.QuarantinePeer)
+					peers.POST("/:peerId/unquarantine", requireAdmin, h.UnquarantinePeer)
+					peers.POST("/:peerId/temp-route", requireAdmin, h.GrantTempRoute)
+					peers.GET("/:peerId/temp-route", requireAdmin, h.ListTempRoutes)
+					peers.GET("/:peerId/iptables", requireAdmin, h.GetJumpPeerIPTables)
+					peers.PUT("/:peerId/connections/:otherPeerId", requireAdmin, h.SetConnectionAllowedIPs)
+					peers.GET("/:peerId/dns/queries", requireAdmin, h.GetPeerDNSQueries)
 				}
 
 				networkOps.GET("/sessions", h.ListNetworkSessions)
+				networkOps.GET("/connections", requireAdmin, h.ListAgentConnections)
+				networkOps.DELETE("/connections/:peerId", requireAdmin, h.DisconnectAgent)
+				networkOps.GET("/connections/mesh", requireAdmin, h.ListMeshConnections)
+				networkOps.DELETE("/connections/mesh/:peerId/:otherPeerId", requireAdmin, h.SeverConnection)
 
 				// ACL routes (admin only)
 				acl := networkOps.Group("/acl")
@@ -251,6 +371,7 @@ func (h *Handler) RegisterRoutes(r *gin.Engine, authMiddleware gin.HandlerFunc,
 					{
 						policies.POST("", h.CreatePolicy)
 						policies.GET("", h.ListPolicies)
+						policies.POST("/evaluate", h.EvaluatePolicy)
 						policies.GET("/:policyId", h.GetPolicy)
 						policies.PUT("/:policyId", h.UpdatePolicy)
 						policies.DELETE("/:policyId", h.DeletePolicy)
@@ -277,19 +398,42 @@ func (h *Handler) RegisterRoutes(r *gin.Engine, authMiddleware gin.HandlerFunc,
 						routes.DELETE("/:routeId/dns/:dnsId", h.DeleteDNSMapping)
 					}
 					networkOps.GET("/dns", requireAdmin, h.GetNetworkDNSRecords)
+					networkOps.GET("/dns/zonefile", requireAdmin, h.GetNetworkZoneFile)
+					networkOps.GET("/dns/export", requireAdmin, h.ExportNetworkZoneFile)
+					networkOps.POST("/dns/import", requireAdmin, h.ImportNetworkZoneFile)
+					dnsRecords := networkOps.Group("/dns/records")
+					dnsRecords.Use(requireAdmin)
+					{
+						dnsRecords.POST("", h.CreateNetworkDNSRecord)
+						dnsRecords.GET("", h.ListNetworkDNSRecords)
+						dnsRecords.GET("/:recordId", h.GetNetworkDNSRecord)
+						dnsRecords.PUT("/:recordId", h.UpdateNetworkDNSRecord)
+						dnsRecords.DELETE("/:recordId", h.DeleteNetworkDNSRecord)
+					}
 				} else {
 					networkOps.Any("/routes/*path", requireAdmin, dbOnlyHandler("routes"))
 					networkOps.GET("/dns", requireAdmin, dbOnlyHandler("DNS records"))
+					networkOps.GET("/dns/zonefile", requireAdmin, dbOnlyHandler("DNS records"))
+					networkOps.GET("/dns/export", requireAdmin, dbOnlyHandler("DNS records"))
+					networkOps.POST("/dns/import", requireAdmin, dbOnlyHandler("DNS records"))
+					networkOps.Any("/dns/records/*path", requireAdmin, dbOnlyHandler("DNS records"))
 				}
 			}
 		}
 
+		// Cross-network peer search
+		protected.GET("/peers/search", requireAdmin, h.SearchPeers)
+
 		// IPAM routes
 		ipam := protected.Group("/ipam")
 		{
 			ipam.GET("/available-cidrs", h.GetAvailableCIDRs)
 			ipam.GET("", h.ListIPAMAllocations)
 			ipam.GET("/networks/:networkId", requireNetworkAccess, h.GetNetworkIPAM)
+			ipam.GET("/networks/:networkId/usage", requireNetworkAccess, h.GetNetworkIPAMUsage)
+			ipam.POST("/networks/:networkId/reservations", requireAdmin, h.ReserveNetworkIP)
+			ipam.DELETE("/networks/:networkId/reservations/:ip", requireAdmin, h.ReleaseNetworkIPReservation)
+			ipam.POST("/networks/:networkId/reconcile", requireAdmin, h.ReconcileNetworkIPAM)
 		}
 
 	}
@@ -325,13 +469,91 @@ func (h *Handler) Health(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"status": "ok"})
 }
 
+// readinessTimeout bounds each readiness check so a hung database fails the
+// probe instead of blocking it.
+const readinessTimeout = 2 * time.Second
+
+// readinessProbeCIDR is the prefix the IPAM check reads.  It never holds
+// reservations: the read only has to reach the IPAM store.
+const readinessProbeCIDR = "0.0.0.0/32"
+
+// Ready godoc
+//
+//	@Summary		Readiness check
+//	@Description	Check that the API can reach the database and the IPAM store
+//	@Tags			health
+//	@Produce		json
+//	@Success		200	{object}	map[string]interface{}
+//	@Failure		503	{object}	map[string]interface{}
+//	@Router			/health/ready [get]
+func (h *Handler) Ready(c *gin.Context) {
+	components := gin.H{}
+	ready := true
+	check := func(name string, fn func(ctx context.Context) error) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), readinessTimeout)
+		defer cancel()
+		if err := fn(ctx); err != nil {
+			ready = false
+			components[name] = gin.H{"status": "error", "error": err.Error()}
+			return
+		}
+		components[name] = gin.H{"status": "ok"}
+	}
+
+	if h.db != nil {
+		check("database", h.db.PingContext)
+	}
+	if h.ipamRepo != nil {
+		check("ipam", func(ctx context.Context) error {
+			_, err := h.ipamRepo.ListReservations(ctx, readinessProbeCIDR)
+			return err
+		})
+	}
+
+	if !ready {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "unavailable", "components": components})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "components": components})
+}
+
+// inventory counts networks and peers for the Prometheus gauges
+func (h *Handler) inventory() (networks, peers int) {
+	nets, err := h.service.ListNetworks(context.Background())
+	if err != nil {
+		return 0, 0
+	}
+	for _, n := range nets {
+		peers += n.PeerCount
+	}
+	return len(nets), peers
+}
+
 // isValidationError checks if an error is a validation error
 func isValidationError(err error) bool {
-	return err == validation.ErrInvalidDNSName ||
-		err == validation.ErrNameTooLong ||
-		err == validation.ErrNameEmpty ||
-		err == validation.ErrNameStartsWithHyphen ||
-		err == validation.ErrNameEndsWithHyphen
+	return errors.Is(err, validation.ErrInvalidDNSName) ||
+		errors.Is(err, validation.ErrNameTooLong) ||
+		errors.Is(err, validation.ErrNameEmpty) ||
+		errors.Is(err, validation.ErrNameStartsWithHyphen) ||
+		errors.Is(err, validation.ErrNameEndsWithHyphen) ||
+		errors.Is(err, validation.ErrInvalidAllowedIP) ||
+		errors.Is(err, domain.ErrPeerNamePattern) ||
+		errors.Is(err, domain.ErrPeerExpiryInPast) ||
+		errors.Is(err, domain.ErrTokenExpiryInPast) ||
+		errors.Is(err, domain.ErrInvalidRoutingTable) ||
+		errors.Is(err, domain.ErrInvalidFwMark) ||
+		errors.Is(err, domain.ErrDNSOnlyJumpPeer) ||
+		errors.Is(err, domain.ErrPeersNotConnected) ||
+		errors.Is(err, domain.ErrInvalidPublicKey) ||
+		errors.Is(err, domain.ErrInvalidConditionalForwarder) ||
+		errors.Is(err, domain.ErrMaintenanceInPast) ||
+		errors.Is(err, domain.ErrInvalidJumpHook) ||
+		errors.Is(err, domain.ErrInvalidLabel) ||
+		errors.Is(err, domain.ErrPeerProfileNotFound) ||
+		errors.Is(err, domain.ErrInvalidCIDR) ||
+		errors.Is(err, domain.ErrInvalidIP) ||
+		errors.Is(err, domain.ErrIPNotInNetwork) ||
+		errors.Is(err, domain.ErrEmptySearchQuery)
 }
 
 // contains checks if s contains substr (case-insensitive)
@@ -0,0 +1,71 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"wirety/internal/adapters/db/memory"
+
+	"github.com/gin-gonic/gin"
+)
+
+type stubPinger struct{ err error }
+
+func (p stubPinger) PingContext(context.Context) error { return p.err }
+
+func TestReady_ReportsComponents(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ipamRepo := memory.NewIPAMRepository(context.Background())
+
+	tests := []struct {
+		name     string
+		db       pinger
+		wantCode int
+		wantDB   string
+	}{
+		{name: "without database", wantCode: http.StatusOK},
+		{name: "database up", db: stubPinger{}, wantCode: http.StatusOK, wantDB: "ok"},
+		{name: "database down", db: stubPinger{err: errors.New("connection refused")}, wantCode: http.StatusServiceUnavailable, wantDB: "error"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &Handler{db: tt.db, ipamRepo: ipamRepo}
+			r := gin.New()
+			r.GET("/health/ready", h.Ready)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health/ready", nil))
+			if w.Code != tt.wantCode {
+				t.Fatalf("status %d, want %d: %s", w.Code, tt.wantCode, w.Body)
+			}
+			var body struct {
+				Components map[string]struct {
+					Status string `json:"status"`
+					Error  string `json:"error"`
+				} `json:"components"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			if got := body.Components["ipam"].Status; got != "ok" {
+				t.Errorf("ipam status = %q, want ok", got)
+			}
+			db, ok := body.Components["database"]
+			if tt.wantDB == "" {
+				if ok {
+					t.Errorf("database component reported without a database: %+v", db)
+				}
+				return
+			}
+			if db.Status != tt.wantDB {
+				t.Errorf("database status = %q, want %q", db.Status, tt.wantDB)
+			}
+			if tt.wantDB == "error" && db.Error == "" {
+				t.Error("database error not reported")
+			}
+		})
+	}
+}
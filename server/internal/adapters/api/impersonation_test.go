@@ -0,0 +1,140 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"wirety/internal/adapters/api/middleware"
+	"wirety/internal/adapters/db/memory"
+	"wirety/internal/application/network"
+	"wirety/internal/audit"
+	"wirety/internal/domain/auth"
+	domain "wirety/internal/domain/network"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestImpersonation_ReadOnlyAsTargetUser(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+
+	var auditLog bytes.Buffer
+	audit.SetOutput(&auditLog)
+
+	repo := memory.NewRepository()
+	userRepo := memory.NewUserRepository()
+	admin := &auth.User{ID: "admin", Email: "admin@example.com", Role: auth.RoleAdministrator}
+	alice := &auth.User{ID: "alice", Email: "alice@example.com", Role: auth.RoleUser, AuthorizedNetworks: []string{"net1"}}
+	for _, u := range []*auth.User{admin, alice} {
+		if err := userRepo.CreateUser(u); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := repo.CreateNetwork(ctx, &domain.Network{ID: "net1", Name: "net1", CIDR: "10.0.0.0/24"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []*domain.Peer{
+		{ID: "jump", Name: "jump", Address: "10.0.0.1", IsJump: true},
+		{ID: "alice-laptop", Name: "alice-laptop", Address: "10.0.0.2", OwnerID: "alice"},
+		{ID: "bob-laptop", Name: "bob-laptop", Address: "10.0.0.3", OwnerID: "bob"},
+	} {
+		if err := repo.CreatePeer(ctx, "net1", p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	h := &Handler{
+		service:      network.NewService(repo, nil, userRepo, nil, nil, nil, nil),
+		userRepo:     userRepo,
+		impersonator: middleware.NewImpersonator(userRepo),
+	}
+	asAdmin := func(c *gin.Context) {
+		c.Set(middleware.UserContextKey, admin)
+		c.Next()
+	}
+	r := gin.New()
+	api := r.Group("", asAdmin, h.impersonator.Middleware())
+	api.POST("/users/:userId/impersonate", middleware.RequireAdmin(), h.StartImpersonation)
+	api.POST("/users/impersonations/end", middleware.RequireAdmin(), h.EndImpersonation)
+	api.GET("/networks/:networkId/peers", middleware.RequireNetworkAccess(), h.ListPeers)
+	api.POST("/networks/:networkId/peers", middleware.RequireNetworkAccess(), h.CreatePeer)
+
+	do := func(method, path, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(`{"name":"evil"}`))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set(middleware.ImpersonationHeader, token)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do(http.MethodPost, "/users/alice/impersonate", "")
+	if w.Code != http.StatusCreated {
+		t.Fatalf("start impersonation: status %d: %s", w.Code, w.Body)
+	}
+	var grant middleware.Impersonation
+	if err := json.Unmarshal(w.Body.Bytes(), &grant); err != nil {
+		t.Fatal(err)
+	}
+
+	// The admin sees exactly what alice sees: the jump peer and her own peer.
+	w = do(http.MethodGet, "/networks/net1/peers", grant.Token)
+	if w.Code != http.StatusOK {
+		t.Fatalf("list peers: status %d: %s", w.Code, w.Body)
+	}
+	var page PaginatedPeers
+	if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil {
+		t.Fatal(err)
+	}
+	seen := map[string]bool{}
+	for _, p := range page.Data {
+		seen[p.ID] = true
+	}
+	if len(seen) != 2 || !seen["jump"] || !seen["alice-laptop"] {
+		t.Errorf("impersonated peers = %v, want jump and alice-laptop", seen)
+	}
+
+	// Mutations are rejected and audited.
+	auditLog.Reset()
+	w = do(http.MethodPost, "/networks/net1/peers", grant.Token)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("mutation: status %d, want 403", w.Code)
+	}
+	peers, _ := repo.ListPeers(ctx, "net1")
+	if len(peers) != 3 {
+		t.Errorf("mutation created a peer: %d peers", len(peers))
+	}
+	if !strings.Contains(auditLog.String(), `"action":"impersonation.mutation_rejected"`) ||
+		!strings.Contains(auditLog.String(), `"impersonated_user_id":"alice"`) {
+		t.Errorf("mutation not audited: %s", auditLog.String())
+	}
+
+	// Impersonations cannot be chained.
+	if w = do(http.MethodPost, "/users/alice/impersonate", grant.Token); w.Code != http.StatusForbidden {
+		t.Errorf("admin endpoint under impersonation: status %d, want 403", w.Code)
+	}
+
+	// An unknown token never falls back to the admin's own identity.
+	if w = do(http.MethodGet, "/networks/net1/peers", "bogus"); w.Code != http.StatusForbidden {
+		t.Errorf("bogus token: status %d, want 403", w.Code)
+	}
+
+	// Ending the impersonation takes the token from the body and revokes it.
+	req := httptest.NewRequest(http.MethodPost, "/users/impersonations/end", strings.NewReader(`{"token":"`+grant.Token+`"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("end impersonation: status %d: %s", w.Code, w.Body)
+	}
+	if w = do(http.MethodGet, "/networks/net1/peers", grant.Token); w.Code != http.StatusForbidden {
+		t.Errorf("ended token: status %d, want 403", w.Code)
+	}
+}
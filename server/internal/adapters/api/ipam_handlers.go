@@ -1,10 +1,15 @@
 package api
 
 import (
+	"errors"
 	"net/http"
+	"net/netip"
 	"strconv"
 
 	"wirety/internal/adapters/api/middleware"
+	"wirety/internal/application/ipam"
+	"wirety/internal/audit"
+	"wirety/internal/domain/network"
 
 	"github.com/gin-gonic/gin"
 )
@@ -22,12 +27,18 @@ type IPAMAllocation struct {
 	PeerID      string `json:"peer_id,omitempty"`
 	PeerName    string `json:"peer_name,omitempty"`
 	Allocated   bool   `json:"allocated"`
+	Reserved    bool   `json:"reserved,omitempty"` // held back from peers, see ReserveNetworkIP
+}
+
+// IPReservationRequest is the body of ReserveNetworkIP.
+type IPReservationRequest struct {
+	IP string `json:"ip" binding:"required"`
 }
 
 // GetAvailableCIDRs godoc
 //
 // @Summary      Suggest available CIDRs
-// @Description  Returns a list of CIDRs sized to hold at least max_peers peers carved from base_cidr
+// @Description  Returns a list of CIDRs sized to hold at least max_peers peers, plus the configured headroom, carved from base_cidr
 // @Tags         ipam
 // @Produce      json
 // @Param        max_peers  query int true  "Maximum number of peers to fit in each CIDR"
@@ -64,7 +75,11 @@ func (h *Handler) GetAvailableCIDRs(c *gin.Context) {
 
 	prefixLen, cidrs, err := h.ipamService.SuggestCIDRs(c.Request.Context(), baseCIDR, maxPeers, count)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		if errors.Is(err, ipam.ErrTooManyPeers) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
 		return
 	}
 
@@ -122,7 +137,7 @@ func (h *Handler) ListIPAMAllocations(c *gin.Context) {
 		}
 
 		for _, peer := range peers {
-			if user != nil && !user.IsAdministrator() && peer.OwnerID != user.ID {
+			if user != nil && !user.CanViewAll() && peer.OwnerID != user.ID {
 				continue
 			}
 
@@ -183,7 +198,7 @@ func (h *Handler) ListIPAMAllocations(c *gin.Context) {
 
 // GetNetworkIPAM godoc
 // @Summary      Get network IPAM allocations
-// @Description  Get all IP allocations for a specific network
+// @Description  Get all IP allocations for a specific network, including reserved addresses
 // @Tags         ipam
 // @Produce      json
 // @Param        networkId path string true "Network ID"
@@ -235,5 +250,252 @@ func (h *Handler) GetNetworkIPAM(c *gin.Context) {
 		}
 	}
 
+	for _, family := range []struct{ name, cidr string }{{"ipv4", net.CIDR}, {"ipv6", net.CIDRv6}} {
+		if family.cidr == "" {
+			continue
+		}
+		reserved, err := h.ipamService.ListReservations(c.Request.Context(), family.cidr)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		for _, ip := range reserved {
+			allocations = append(allocations, IPAMAllocation{
+				NetworkID:   net.ID,
+				NetworkName: net.Name,
+				NetworkCIDR: family.cidr,
+				Family:      family.name,
+				IP:          ip,
+				Allocated:   true,
+				Reserved:    true,
+			})
+		}
+	}
+
 	c.JSON(http.StatusOK, allocations)
 }
+
+// GetNetworkIPAMUsage godoc
+// @Summary      Get network IPAM usage
+// @Description  Reports the total, allocated, reserved and free addresses of the network's IPv4 and IPv6 CIDRs. Total and free are omitted for prefixes with more than 2^32 addresses, such as an IPv6 /64.
+// @Tags         ipam
+// @Produce      json
+// @Param        networkId path string true "Network ID"
+// @Success      200 {object} network.IPAMUsage
+// @Failure      404 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Router       /ipam/networks/{networkId}/usage [get]
+// @Security     BearerAuth
+func (h *Handler) GetNetworkIPAMUsage(c *gin.Context) {
+	usage, err := h.service.GetIPAMUsage(c.Request.Context(), c.Param("networkId"))
+	if err != nil {
+		if errors.Is(err, network.ErrNetworkNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "network not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, usage)
+}
+
+// ReconcileNetworkIPAM godoc
+// @Summary      Reclaim orphaned IP allocations
+// @Description  Returns to IPAM the addresses allocated in the network that no peer holds. Reservations and allocations younger than 5 minutes are kept. Safe to repeat.
+// @Tags         ipam
+// @Produce      json
+// @Param        networkId path string true "Network ID"
+// @Success      200 {object} network.IPAMReconcileResult
+// @Failure      404 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Router       /ipam/networks/{networkId}/reconcile [post]
+// @Security     BearerAuth
+func (h *Handler) ReconcileNetworkIPAM(c *gin.Context) {
+	networkID := c.Param("networkId")
+	result, err := h.service.ReconcileNetworkIPAM(c.Request.Context(), networkID)
+	if err != nil {
+		if errors.Is(err, network.ErrNetworkNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "network not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	id, email := actor(c)
+	audit.Server(id, email, c.ClientIP()).
+		Str("action", "ipam.reconcile").
+		Str("network_id", networkID).
+		Int("released", len(result.Released)).
+		Msg("audit")
+
+	c.JSON(http.StatusOK, result)
+}
+
+// ReserveNetworkIP godoc
+// @Summary      Reserve an IP
+// @Description  Keeps an address of the network's IPv4 or IPv6 CIDR from ever being assigned to a peer, e.g. a hardware gateway. Refused with 409 if the address is already allocated.
+// @Tags         ipam
+// @Accept       json
+// @Produce      json
+// @Param        networkId   path string               true "Network ID"
+// @Param        reservation body IPReservationRequest true "Address to reserve"
+// @Success      201 {object} IPAMAllocation
+// @Failure      400 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Failure      409 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Router       /ipam/networks/{networkId}/reservations [post]
+// @Security     BearerAuth
+func (h *Handler) ReserveNetworkIP(c *gin.Context) {
+	networkID := c.Param("networkId")
+
+	var req IPReservationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	net, err := h.service.GetNetwork(c.Request.Context(), networkID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "network not found"})
+		return
+	}
+	family, cidr := reservationCIDR(net, req.IP)
+	if cidr == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": network.ErrIPNotInNetwork.Error()})
+		return
+	}
+
+	if err := h.ipamService.ReserveIP(c.Request.Context(), cidr, req.IP); err != nil {
+		writeReservationError(c, err)
+		return
+	}
+
+	id, email := actor(c)
+	audit.Server(id, email, c.ClientIP()).
+		Str("action", "ipam.reserve").
+		Str("network_id", networkID).
+		Str("ip", req.IP).
+		Msg("audit")
+
+	c.JSON(http.StatusCreated, IPAMAllocation{
+		NetworkID:   net.ID,
+		NetworkName: net.Name,
+		NetworkCIDR: cidr,
+		Family:      family,
+		IP:          req.IP,
+		Allocated:   true,
+		Reserved:    true,
+	})
+}
+
+// ReleaseNetworkIPReservation godoc
+// @Summary      Release an IP reservation
+// @Description  Returns a reserved address of the network to the pool
+// @Tags         ipam
+// @Param        networkId path string true "Network ID"
+// @Param        ip        path string true "Reserved IP address"
+// @Success      204
+// @Failure      400 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Router       /ipam/networks/{networkId}/reservations/{ip} [delete]
+// @Security     BearerAuth
+func (h *Handler) ReleaseNetworkIPReservation(c *gin.Context) {
+	networkID := c.Param("networkId")
+	ip := c.Param("ip")
+
+	net, err := h.service.GetNetwork(c.Request.Context(), networkID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "network not found"})
+		return
+	}
+	_, cidr := reservationCIDR(net, ip)
+	if cidr == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": network.ErrIPNotInNetwork.Error()})
+		return
+	}
+
+	if err := h.ipamService.ReleaseReservation(c.Request.Context(), cidr, ip); err != nil {
+		writeReservationError(c, err)
+		return
+	}
+
+	id, email := actor(c)
+	audit.Server(id, email, c.ClientIP()).
+		Str("action", "ipam.release_reservation").
+		Str("network_id", networkID).
+		Str("ip", ip).
+		Msg("audit")
+
+	c.Status(http.StatusNoContent)
+}
+
+// reservationCIDR returns the family and network CIDR an address would be
+// reserved in; the CIDR is empty if the network lacks that family.
+// Unparsable addresses fall back to IPv4 and are rejected by the IPAM service.
+func reservationCIDR(net *network.Network, ip string) (family, cidr string) {
+	if addr, err := netip.ParseAddr(ip); err == nil && addr.Is6() {
+		return "ipv6", net.CIDRv6
+	}
+	return "ipv4", net.CIDR
+}
+
+func writeReservationError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, network.ErrReservationNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, network.ErrIPAllocated):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, network.ErrInvalidIP), errors.Is(err, network.ErrIPNotInNetwork), errors.Is(err, network.ErrInvalidCIDR):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// ReleaseNetworkIP godoc
+// @Summary      Release an IP from IPAM
+// @Description  Forcibly returns a specific address of the network to IPAM, e.g. to reclaim an allocation that leaked. Refused with 409 if a live peer still holds the address unless force=true.
+// @Tags         ipam
+// @Produce      json
+// @Param        networkId path  string true  "Network ID"
+// @Param        ip        path  string true  "IP address to release"
+// @Param        force     query bool   false "Release even if a live peer holds the address"
+// @Success      204
+// @Failure      400 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Failure      409 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Router       /networks/{networkId}/ipam/{ip} [delete]
+// @Security     BearerAuth
+func (h *Handler) ReleaseNetworkIP(c *gin.Context) {
+	networkID := c.Param("networkId")
+	ip := c.Param("ip")
+	force := c.Query("force") == "true"
+
+	if err := h.service.ReleaseIP(c.Request.Context(), networkID, ip, force); err != nil {
+		switch {
+		case errors.Is(err, network.ErrNetworkNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, network.ErrIPInUse):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.Is(err, network.ErrInvalidIP), errors.Is(err, network.ErrIPNotInNetwork):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	id, email := actor(c)
+	audit.Server(id, email, c.ClientIP()).
+		Str("action", "ipam.release").
+		Str("network_id", networkID).
+		Str("ip", ip).
+		Bool("force", force).
+		Msg("audit")
+
+	c.Status(http.StatusNoContent)
+}
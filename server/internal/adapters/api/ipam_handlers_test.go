@@ -0,0 +1,76 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"wirety/internal/adapters/db/memory"
+	"wirety/internal/application/network"
+	domain "wirety/internal/domain/network"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestGetNetworkIPAMUsage_CountsBothFamilies(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+
+	repo := memory.NewRepository()
+	if err := repo.CreateNetwork(ctx, &domain.Network{ID: "net1", Name: "office", CIDR: "10.0.0.0/29", CIDRv6: "fd00:10::/64", Peers: map[string]*domain.Peer{}}); err != nil {
+		t.Fatal(err)
+	}
+	ipamRepo := memory.NewIPAMRepository(ctx)
+	for _, cidr := range []string{"10.0.0.0/29", "fd00:10::/64"} {
+		if _, err := ipamRepo.EnsureRootPrefix(ctx, cidr); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for range 3 {
+		if _, err := ipamRepo.AcquireIP(ctx, "10.0.0.0/29"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := ipamRepo.AcquireIP(ctx, "fd00:10::/64"); err != nil {
+		t.Fatal(err)
+	}
+	if err := ipamRepo.ReserveIP(ctx, "10.0.0.0/29", "10.0.0.6"); err != nil {
+		t.Fatal(err)
+	}
+	h := &Handler{service: network.NewService(repo, ipamRepo, nil, nil, nil, nil, nil)}
+
+	r := gin.New()
+	r.GET("/ipam/networks/:networkId/usage", h.GetNetworkIPAMUsage)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ipam/networks/net1/usage", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	var usage domain.IPAMUsage
+	if err := json.Unmarshal(w.Body.Bytes(), &usage); err != nil {
+		t.Fatal(err)
+	}
+
+	// A /29 has six host addresses: three allocated, one reserved
+	v4 := usage.IPv4
+	if v4 == nil || v4.Total == nil || v4.Free == nil {
+		t.Fatalf("ipv4 usage = %+v, want total and free", v4)
+	}
+	if *v4.Total != 6 || v4.Allocated != 3 || v4.Reserved != 1 || *v4.Free != 2 {
+		t.Errorf("ipv4 usage = total %d allocated %d reserved %d free %d, want 6/3/1/2", *v4.Total, v4.Allocated, v4.Reserved, *v4.Free)
+	}
+
+	// A /64 is too large to count: only allocations are reported
+	v6 := usage.IPv6
+	if v6 == nil || v6.Total != nil || v6.Free != nil || v6.Allocated != 1 {
+		t.Errorf("ipv6 usage = %+v, want 1 allocation and no total", v6)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ipam/networks/missing/usage", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("unknown network: status %d", w.Code)
+	}
+}
@@ -238,6 +238,9 @@ func (h *Handler) buildMCPServer() *mcp.Server {
 		&mcp.Tool{Name: "create_peer", Description: "Create a new peer in a network."},
 		func(ctx context.Context, _ *mcp.CallToolRequest, p CreatePeerParams) (*mcp.CallToolResult, any, error) {
 			user := mcpUserFrom(ctx)
+			if user != nil && user.IsAuditor() {
+				return mcpErr("auditor role is read-only"), nil, nil
+			}
 			ownerID := ""
 			if user != nil {
 				ownerID = user.ID
@@ -262,6 +265,9 @@ func (h *Handler) buildMCPServer() *mcp.Server {
 	mcp.AddTool(s,
 		&mcp.Tool{Name: "update_peer", Description: "Update a peer's name."},
 		func(ctx context.Context, _ *mcp.CallToolRequest, p UpdatePeerParams) (*mcp.CallToolResult, any, error) {
+			if user := mcpUserFrom(ctx); user != nil && user.IsAuditor() {
+				return mcpErr("auditor role is read-only"), nil, nil
+			}
 			peer, err := h.service.UpdatePeer(ctx, p.NetworkID, p.PeerID, &domain.PeerUpdateRequest{
 				Name: p.Name,
 			})
@@ -275,6 +281,9 @@ func (h *Handler) buildMCPServer() *mcp.Server {
 	mcp.AddTool(s,
 		&mcp.Tool{Name: "delete_peer", Description: "Delete a peer from a network."},
 		func(ctx context.Context, _ *mcp.CallToolRequest, p PeerParams) (*mcp.CallToolResult, any, error) {
+			if user := mcpUserFrom(ctx); user != nil && user.IsAuditor() {
+				return mcpErr("auditor role is read-only"), nil, nil
+			}
 			if err := h.service.DeletePeer(ctx, p.NetworkID, p.PeerID); err != nil {
 				return mcpErr(err.Error()), nil, nil
 			}
@@ -380,7 +389,7 @@ func (h *Handler) buildMCPServer() *mcp.Server {
 			Rules       []domain.PolicyRule `json:"rules,omitempty"`
 		}
 		mcp.AddTool(s,
-			&mcp.Tool{Name: "create_policy", Description: "Create a new policy in a network (admin only). Rules have fields: direction, action, target, target_type, description."},
+			&mcp.Tool{Name: "create_policy", Description: "Create a new policy in a network (admin only). Rules have fields: direction, action, target, target_type, protocol, ports, description."},
 			func(ctx context.Context, _ *mcp.CallToolRequest, p CreatePolicyParams) (*mcp.CallToolResult, any, error) {
 				user := mcpUserFrom(ctx)
 				if user == nil || user.Role != "administrator" {
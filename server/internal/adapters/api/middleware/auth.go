@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"wirety/internal/application/auth"
+	"wirety/internal/audit"
 	"wirety/internal/config"
 	domainAuth "wirety/internal/domain/auth"
 
@@ -42,20 +43,18 @@ const (
 // AuthMiddleware creates a middleware for authentication
 func AuthMiddleware(authService *auth.Service, userRepo domainAuth.Repository, cfg *config.AuthConfig) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// API tokens (wirety_*) are accepted in both auth modes via Authorization: Bearer
-		if authHeader := c.GetHeader("Authorization"); strings.HasPrefix(authHeader, "Bearer ") {
-			raw := strings.TrimPrefix(authHeader, "Bearer ")
-			if strings.HasPrefix(raw, apiTokenPrefix) {
-				user, err := handleAPITokenAuth(userRepo, raw)
-				if err != nil {
-					c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
-					c.Abort()
-					return
-				}
-				c.Set(UserContextKey, user)
-				c.Next()
+		// API tokens (wirety_*) are accepted in both auth modes, either as
+		// Authorization: Bearer or as Authorization: ApiKey for automation
+		if raw, ok := apiTokenFromHeader(c.GetHeader("Authorization")); ok {
+			user, err := handleAPITokenAuth(userRepo, raw)
+			if err != nil {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+				c.Abort()
 				return
 			}
+			c.Set(UserContextKey, user)
+			c.Next()
+			return
 		}
 
 		// If OIDC auth is disabled, use simple session-based auth
@@ -419,6 +418,20 @@ func handleSessionAuth(c *gin.Context, authService *auth.Service, userRepo domai
 	return user, nil
 }
 
+// apiTokenFromHeader extracts an API token from an Authorization header
+// using the Bearer or ApiKey scheme.
+func apiTokenFromHeader(header string) (string, bool) {
+	scheme, raw, ok := strings.Cut(header, " ")
+	if !ok || !strings.HasPrefix(raw, apiTokenPrefix) {
+		return "", false
+	}
+	switch strings.ToLower(scheme) {
+	case "bearer", "apikey":
+		return raw, true
+	}
+	return "", false
+}
+
 // handleAPITokenAuth handles API token authentication (wirety_* tokens)
 func handleAPITokenAuth(userRepo domainAuth.Repository, rawToken string) (*domainAuth.User, error) {
 	h := sha256.Sum256([]byte(rawToken))
@@ -455,7 +468,8 @@ func handleTokenAuth(c *gin.Context, authService *auth.Service, tokenString stri
 	return user, nil
 }
 
-// RequireAdmin is a middleware that requires administrator role
+// RequireAdmin is a middleware that requires administrator role.  Auditors
+// are let through for read methods.
 func RequireAdmin() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		user := GetUserFromContext(c)
@@ -465,7 +479,8 @@ func RequireAdmin() gin.HandlerFunc {
 			return
 		}
 
-		if !user.IsAdministrator() {
+		// Auditors may read admin endpoints; AuditorReadOnly rejects the rest
+		if !user.IsAdministrator() && !(user.IsAuditor() && isReadMethod(c.Request.Method)) {
 			c.JSON(http.StatusForbidden, gin.H{"error": "administrator role required"})
 			c.Abort()
 			return
@@ -475,6 +490,28 @@ func RequireAdmin() gin.HandlerFunc {
 	}
 }
 
+// AuditorReadOnly rejects every request that is not a read (GET, HEAD,
+// OPTIONS) from auditors. It must run after AuthMiddleware.
+func AuditorReadOnly() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if user := GetUserFromContext(c); user != nil && user.IsAuditor() && !isReadMethod(c.Request.Method) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "auditor role is read-only"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// isReadMethod reports whether an HTTP method cannot change state.
+func isReadMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
 // RequireNetworkAccess is a middleware that requires access to a specific network
 func RequireNetworkAccess() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -512,6 +549,18 @@ func GetUserFromContext(c *gin.Context) *domainAuth.User {
 	return nil
 }
 
+// AuditActor is a middleware that attaches the authenticated user to the
+// request context, so services can attribute the audit entries they record.
+// It must run after AuthMiddleware.
+func AuditActor() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if user := GetUserFromContext(c); user != nil {
+			c.Request = c.Request.WithContext(audit.WithActor(c.Request.Context(), user.ID, user.Email))
+		}
+		c.Next()
+	}
+}
+
 // isJWT returns true when s is a standard three-segment JSON Web Token
 // (header.payload.signature).  Opaque provider tokens (GitHub ghs_* / ghp_*,
 // Slack xoxp-*, etc.) contain no dots and return false, signalling that JWT
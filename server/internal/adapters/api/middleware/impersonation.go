@@ -0,0 +1,217 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"wirety/internal/audit"
+	domainAuth "wirety/internal/domain/auth"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// ImpersonationHeader carries the impersonation token on requests made by
+	// the admin who started the impersonation.
+	ImpersonationHeader = "X-Wirety-Impersonate"
+
+	// ImpersonatorContextKey is the key under which the real (admin) user is
+	// stored while UserContextKey holds the impersonated user.
+	ImpersonatorContextKey = "impersonator"
+
+	// ImpersonationTTL is how long an impersonation stays valid.
+	ImpersonationTTL = 15 * time.Minute
+
+	// impersonationsPerHour caps how many impersonations an admin can start
+	// within an hour.
+	impersonationsPerHour = 10
+)
+
+// Impersonation errors
+var (
+	ErrImpersonationRateLimited = errors.New("too many impersonations started, try again later")
+	ErrImpersonateAdministrator = errors.New("administrators cannot be impersonated")
+	ErrImpersonateSelf          = errors.New("cannot impersonate yourself")
+)
+
+// Impersonation is a time-limited, read-only grant letting an admin act as
+// another user.
+type Impersonation struct {
+	Token        string    `json:"token"`
+	AdminID      string    `json:"admin_id"`
+	TargetUserID string    `json:"target_user_id"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+// Impersonator tracks active impersonations. Grants live in memory only: they
+// are short-lived and a restart simply ends them.
+type Impersonator struct {
+	userRepo domainAuth.Repository
+	now      func() time.Time
+
+	mu     sync.Mutex
+	grants map[string]*Impersonation
+	starts map[string][]time.Time // admin ID -> recent start times
+}
+
+// NewImpersonator creates an Impersonator resolving target users from userRepo.
+func NewImpersonator(userRepo domainAuth.Repository) *Impersonator {
+	return &Impersonator{
+		userRepo: userRepo,
+		now:      time.Now,
+		grants:   make(map[string]*Impersonation),
+		starts:   make(map[string][]time.Time),
+	}
+}
+
+// Start grants admin a read-only impersonation of the target user.
+func (i *Impersonator) Start(admin *domainAuth.User, targetUserID string) (*Impersonation, error) {
+	if admin.ID == targetUserID {
+		return nil, ErrImpersonateSelf
+	}
+	target, err := i.userRepo.GetUser(targetUserID)
+	if err != nil {
+		return nil, err
+	}
+	if target.IsAdministrator() {
+		return nil, ErrImpersonateAdministrator
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, err
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	now := i.now()
+	recent := i.starts[admin.ID][:0]
+	for _, t := range i.starts[admin.ID] {
+		if now.Sub(t) < time.Hour {
+			recent = append(recent, t)
+		}
+	}
+	if len(recent) >= impersonationsPerHour {
+		i.starts[admin.ID] = recent
+		return nil, ErrImpersonationRateLimited
+	}
+	i.starts[admin.ID] = append(recent, now)
+
+	for token, g := range i.grants {
+		if !now.Before(g.ExpiresAt) {
+			delete(i.grants, token)
+		}
+	}
+
+	grant := &Impersonation{
+		Token:        hex.EncodeToString(raw),
+		AdminID:      admin.ID,
+		TargetUserID: target.ID,
+		ExpiresAt:    now.Add(ImpersonationTTL),
+	}
+	i.grants[grant.Token] = grant
+	return grant, nil
+}
+
+// End revokes an impersonation started by adminID. It reports whether a grant
+// was removed.
+func (i *Impersonator) End(adminID, token string) bool {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	g, ok := i.grants[token]
+	if !ok || g.AdminID != adminID {
+		return false
+	}
+	delete(i.grants, token)
+	return true
+}
+
+// lookup returns the unexpired grant for token, if any.
+func (i *Impersonator) lookup(token string) *Impersonation {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	g, ok := i.grants[token]
+	if !ok {
+		return nil
+	}
+	if !i.now().Before(g.ExpiresAt) {
+		delete(i.grants, token)
+		return nil
+	}
+	return g
+}
+
+// Middleware swaps the authenticated admin for the impersonated user when the
+// request carries ImpersonationHeader. It must run after AuthMiddleware.
+// Impersonated requests are read-only: any other method is rejected. Every
+// impersonated request, allowed or not, is audited.
+func (i *Impersonator) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := c.GetHeader(ImpersonationHeader)
+		if token == "" {
+			c.Next()
+			return
+		}
+
+		admin := GetUserFromContext(c)
+		if admin == nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found in context"})
+			c.Abort()
+			return
+		}
+
+		grant := i.lookup(token)
+		if grant == nil || grant.AdminID != admin.ID || !admin.IsAdministrator() {
+			audit.Server(admin.ID, admin.Email, c.ClientIP()).
+				Str("action", "impersonation.denied").
+				Str("method", c.Request.Method).
+				Str("path", c.Request.URL.Path).
+				Msg("audit")
+			c.JSON(http.StatusForbidden, gin.H{"error": "invalid or expired impersonation"})
+			c.Abort()
+			return
+		}
+
+		event := audit.Server(admin.ID, admin.Email, c.ClientIP()).
+			Str("impersonated_user_id", grant.TargetUserID).
+			Str("method", c.Request.Method).
+			Str("path", c.Request.URL.Path)
+
+		if !isReadMethod(c.Request.Method) {
+			event.Str("action", "impersonation.mutation_rejected").Msg("audit")
+			c.JSON(http.StatusForbidden, gin.H{"error": "impersonation is read-only"})
+			c.Abort()
+			return
+		}
+
+		target, err := i.userRepo.GetUser(grant.TargetUserID)
+		if err != nil {
+			i.End(admin.ID, token)
+			event.Str("action", "impersonation.denied").Msg("audit")
+			c.JSON(http.StatusForbidden, gin.H{"error": "impersonated user no longer exists"})
+			c.Abort()
+			return
+		}
+
+		event.Str("action", "impersonation.request").Msg("audit")
+		c.Set(ImpersonatorContextKey, admin)
+		c.Set(UserContextKey, target)
+		c.Next()
+	}
+}
+
+// GetImpersonatorFromContext returns the admin behind an impersonated request,
+// or nil when the request is not impersonated.
+func GetImpersonatorFromContext(c *gin.Context) *domainAuth.User {
+	if user, exists := c.Get(ImpersonatorContextKey); exists {
+		if u, ok := user.(*domainAuth.User); ok {
+			return u
+		}
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRateLimiter_PerIPTokenBucket(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := NewRateLimiter(0.5, 2) // one request every 2s, bursts of 2
+	l.now = func() time.Time { return now }
+
+	r := gin.New()
+	r.GET("/agent/resolve", l.Middleware(), func(c *gin.Context) { c.Status(http.StatusOK) })
+	get := func(ip string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/agent/resolve", nil)
+		req.RemoteAddr = ip + ":40000"
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	for i := 0; i < 2; i++ {
+		if w := get("198.51.100.1"); w.Code != http.StatusOK {
+			t.Fatalf("request %d within the burst: status %d", i+1, w.Code)
+		}
+	}
+	w := get("198.51.100.1")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("request over the burst: status %d, want 429", w.Code)
+	}
+	if ra := w.Header().Get("Retry-After"); ra != "2" {
+		t.Errorf("Retry-After = %q, want 2", ra)
+	}
+
+	// Other clients have their own bucket
+	if w := get("198.51.100.2"); w.Code != http.StatusOK {
+		t.Errorf("other IP: status %d", w.Code)
+	}
+
+	now = now.Add(2 * time.Second)
+	if w := get("198.51.100.1"); w.Code != http.StatusOK {
+		t.Errorf("after refill: status %d", w.Code)
+	}
+	if w := get("198.51.100.1"); w.Code != http.StatusTooManyRequests {
+		t.Errorf("refill is one token: status %d, want 429", w.Code)
+	}
+}
+
+func TestRateLimit_DisabledWithoutRate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/", RateLimit(0, 1), func(c *gin.Context) { c.Status(http.StatusOK) })
+	for i := 0; i < 20; i++ {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("request %d: status %d", i+1, w.Code)
+		}
+	}
+}
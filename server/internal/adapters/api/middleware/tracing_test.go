@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTracing_RootSpanPerRoute(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	recorder := tracetest.NewSpanRecorder()
+	prevProvider, prevPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer func() {
+		otel.SetTracerProvider(prevProvider)
+		otel.SetTextMapPropagator(prevPropagator)
+	}()
+
+	r := gin.New()
+	r.Use(Tracing())
+	r.GET("/networks/:networkId", func(c *gin.Context) {
+		if !trace.SpanFromContext(c.Request.Context()).SpanContext().IsValid() {
+			t.Error("handler context carries no span")
+		}
+		c.Status(http.StatusInternalServerError)
+	})
+
+	const remoteTrace = "4bf92f3577b34da6a3ce929d0e0e4736"
+	req := httptest.NewRequest(http.MethodGet, "/networks/net1", nil)
+	req.Header.Set("traceparent", "00-"+remoteTrace+"-00f067aa0ba902b7-01")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/nowhere", nil))
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(spans))
+	}
+	got := spans[0]
+	if got.Name() != "GET /networks/:networkId" || got.SpanKind() != trace.SpanKindServer {
+		t.Errorf("span = %q (%v), want server span GET /networks/:networkId", got.Name(), got.SpanKind())
+	}
+	if got.SpanContext().TraceID().String() != remoteTrace || got.Parent().SpanID().String() != "00f067aa0ba902b7" {
+		t.Errorf("span is not a child of the remote span: trace %s, parent %s", got.SpanContext().TraceID(), got.Parent().SpanID())
+	}
+	attrs := map[attribute.Key]attribute.Value{}
+	for _, kv := range got.Attributes() {
+		attrs[kv.Key] = kv.Value
+	}
+	if attrs["http.route"].AsString() != "/networks/:networkId" || attrs["http.response.status_code"].AsInt64() != http.StatusInternalServerError {
+		t.Errorf("attributes = %v", got.Attributes())
+	}
+	if got.Status().Code != codes.Error {
+		t.Errorf("status = %v, want error for a 500", got.Status())
+	}
+	if name := spans[1].Name(); name != "unmatched" {
+		t.Errorf("unrouted request span = %q, want unmatched", name)
+	}
+}
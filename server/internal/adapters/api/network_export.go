@@ -0,0 +1,363 @@
+package api
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	domain "wirety/internal/domain/network"
+)
+
+// HCL export
+//
+// renderNetworkHCL writes a network as Terraform resource blocks:
+//
+//	wirety_network         the network settings
+//	wirety_peer            one per peer, without keys or enrollment token
+//	wirety_policy          one per policy, its rules as nested rule blocks
+//	wirety_route           one per route
+//	wirety_group           one per group, with its member peers and routes
+//	wirety_group_policies  the ordered policies attached to a group
+//
+// Entities refer to each other through resource references
+// (wirety_peer.laptop.id) rather than IDs.  Group policies get their own
+// resource because policy rules may target groups.  Resources are sorted by
+// name and only set attributes are written, so exporting an unchanged
+// network gives the same file.
+
+// exportEntities are the parts of a network written by the export.
+type exportEntities struct {
+	network  *domain.Network
+	peers    []*domain.Peer
+	groups   []*domain.Group
+	policies []*domain.Policy
+	routes   []*domain.Route
+}
+
+// hclBlock is a block being rendered: attributes are written in the order
+// they are set, with their "=" aligned as terraform fmt does.
+type hclBlock struct {
+	keys   []string
+	values []string
+	nested []string
+}
+
+func (b *hclBlock) set(key, value string) {
+	b.keys = append(b.keys, key)
+	b.values = append(b.values, value)
+}
+
+func (b *hclBlock) str(key, value string) {
+	if value != "" {
+		b.set(key, hclString(value))
+	}
+}
+
+func (b *hclBlock) int(key string, value int) {
+	if value != 0 {
+		b.set(key, strconv.Itoa(value))
+	}
+}
+
+// optInt writes value when it is set, zero included.
+func (b *hclBlock) optInt(key string, value *int) {
+	if value != nil {
+		b.set(key, strconv.Itoa(*value))
+	}
+}
+
+func (b *hclBlock) bool(key string, value bool) {
+	if value {
+		b.set(key, "true")
+	}
+}
+
+func (b *hclBlock) strs(key string, values []string) {
+	if len(values) == 0 {
+		return
+	}
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = hclString(v)
+	}
+	b.set(key, "["+strings.Join(quoted, ", ")+"]")
+}
+
+func (b *hclBlock) refs(key string, refs []string) {
+	if len(refs) > 0 {
+		b.set(key, "["+strings.Join(refs, ", ")+"]")
+	}
+}
+
+func (b *hclBlock) labels(key string, m map[string]string) {
+	if len(m) == 0 {
+		return
+	}
+	pairs := make([]string, 0, len(m))
+	for _, k := range sortedKeys(m) {
+		pairs = append(pairs, hclString(k)+" = "+hclString(m[k]))
+	}
+	b.set(key, "{ "+strings.Join(pairs, ", ")+" }")
+}
+
+func (b *hclBlock) write(sb *strings.Builder, header, indent string) {
+	width := 0
+	for _, k := range b.keys {
+		width = max(width, len(k))
+	}
+	sb.WriteString(indent + header + " {\n")
+	for i, k := range b.keys {
+		fmt.Fprintf(sb, "%s  %-*s = %s\n", indent, width, k, b.values[i])
+	}
+	for _, n := range b.nested {
+		sb.WriteString(n)
+	}
+	sb.WriteString(indent + "}\n")
+}
+
+// hclString quotes s as an HCL string; template sequences are escaped so
+// the value is taken literally.
+func hclString(s string) string {
+	q := strconv.Quote(s)
+	q = strings.ReplaceAll(q, "${", "$${")
+	return strings.ReplaceAll(q, "%{", "%%{")
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// hclLabeler gives every entity a resource name derived from its own name,
+// unique within its resource type.
+type hclLabeler struct {
+	used map[string]bool
+	refs map[string]string // entity ID -> reference expression
+}
+
+func (l *hclLabeler) add(resourceType, id, name string) string {
+	base := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '_' {
+			return r
+		}
+		return '_'
+	}, strings.ToLower(name))
+	if base == "" || base[0] >= '0' && base[0] <= '9' {
+		base = "_" + base
+	}
+	label := base
+	for i := 2; l.used[resourceType+"."+label]; i++ {
+		label = fmt.Sprintf("%s_%d", base, i)
+	}
+	l.used[resourceType+"."+label] = true
+	l.refs[id] = resourceType + "." + label + ".id"
+	return label
+}
+
+// ref is the reference to the entity with id, or the ID itself as a
+// string when it is not part of the export.
+func (l *hclLabeler) ref(id string) string {
+	if ref, ok := l.refs[id]; ok {
+		return ref
+	}
+	return hclString(id)
+}
+
+// sortedRefs returns the references to ids, sorted.
+func (l *hclLabeler) sortedRefs(ids []string) []string {
+	refs := make([]string, len(ids))
+	for i, id := range ids {
+		refs[i] = l.ref(id)
+	}
+	sort.Strings(refs)
+	return refs
+}
+
+func byNameThenID[T any](items []T, key func(T) (string, string)) {
+	sort.SliceStable(items, func(i, j int) bool {
+		ni, ii := key(items[i])
+		nj, ij := key(items[j])
+		if ni != nj {
+			return ni < nj
+		}
+		return ii < ij
+	})
+}
+
+// renderNetworkHCL renders e as HCL; see the top of the file for the schema.
+func renderNetworkHCL(e exportEntities) string {
+	byNameThenID(e.peers, func(p *domain.Peer) (string, string) { return p.Name, p.ID })
+	byNameThenID(e.groups, func(g *domain.Group) (string, string) { return g.Name, g.ID })
+	byNameThenID(e.policies, func(p *domain.Policy) (string, string) { return p.Name, p.ID })
+	byNameThenID(e.routes, func(r *domain.Route) (string, string) { return r.Name, r.ID })
+
+	l := &hclLabeler{used: map[string]bool{}, refs: map[string]string{}}
+	net := e.network
+	netLabel := l.add("wirety_network", net.ID, net.Name)
+	peerLabels := make([]string, len(e.peers))
+	for i, p := range e.peers {
+		peerLabels[i] = l.add("wirety_peer", p.ID, p.Name)
+	}
+	groupLabels := make([]string, len(e.groups))
+	for i, g := range e.groups {
+		groupLabels[i] = l.add("wirety_group", g.ID, g.Name)
+	}
+	policyLabels := make([]string, len(e.policies))
+	for i, p := range e.policies {
+		policyLabels[i] = l.add("wirety_policy", p.ID, p.Name)
+	}
+	routeLabels := make([]string, len(e.routes))
+	for i, r := range e.routes {
+		routeLabels[i] = l.add("wirety_route", r.ID, r.Name)
+	}
+	netRef := l.ref(net.ID)
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# Network %s (%s) exported by Wirety.\n", strconv.Quote(net.Name), net.ID)
+	resource := func(resourceType, label string, b *hclBlock) {
+		sb.WriteString("\n")
+		b.write(&sb, fmt.Sprintf("resource %q %q", resourceType, label), "")
+	}
+
+	b := &hclBlock{}
+	b.str("name", net.Name)
+	b.str("cidr", net.CIDR)
+	b.str("cidr_v6", net.CIDRv6)
+	b.strs("dns", net.DNS)
+	b.str("domain_suffix", net.DomainSuffix)
+	b.strs("search_domains", net.SearchDomains)
+	if net.Topology != "" && net.Topology != domain.TopologyMesh {
+		b.str("topology", net.Topology)
+	}
+	b.str("peer_name_pattern", net.PeerNamePattern)
+	b.bool("multi_jump_failover", net.MultiJumpFailover)
+	b.bool("omit_private_keys", net.OmitPrivateKeys)
+	b.bool("dns_query_log", net.DNSQueryLog)
+	b.bool("config_metadata", net.ConfigMetadata)
+	b.str("jump_post_up", net.JumpPostUp)
+	b.str("jump_post_down", net.JumpPostDown)
+	b.str("jump_nat_interface", net.JumpNATInterface)
+	b.labels("labels", net.Labels)
+	if len(net.ConditionalForwarders) > 0 {
+		var fb strings.Builder
+		fb.WriteString("{\n")
+		for _, domainName := range sortedKeys(net.ConditionalForwarders) {
+			quoted := make([]string, len(net.ConditionalForwarders[domainName]))
+			for i, v := range net.ConditionalForwarders[domainName] {
+				quoted[i] = hclString(v)
+			}
+			fmt.Fprintf(&fb, "    %s = [%s]\n", hclString(domainName), strings.Join(quoted, ", "))
+		}
+		fb.WriteString("  }")
+		b.set("conditional_forwarders", fb.String())
+	}
+	resource("wirety_network", netLabel, b)
+
+	for i, p := range e.peers {
+		b := &hclBlock{}
+		b.set("network_id", netRef)
+		b.str("name", p.Name)
+		b.bool("is_jump", p.IsJump)
+		b.bool("use_agent", p.UseAgent)
+		b.bool("dns_only", p.DNSOnly)
+		if !p.IsJump && p.Role != "" && p.Role != domain.PeerRoleClient {
+			b.str("role", p.Role)
+		}
+		b.str("address", p.Address)
+		b.str("endpoint", p.Endpoint)
+		b.int("listen_port", p.ListenPort)
+		b.str("owner_id", p.OwnerID)
+		b.strs("additional_allowed_ips", p.AdditionalAllowedIPs)
+		if p.SplitTunnelExclusions != nil && len(p.SplitTunnelExclusions) == 0 {
+			b.set("split_tunnel_exclusions", "[]") // overrides the profile's exclusions
+		} else {
+			b.strs("split_tunnel_exclusions", p.SplitTunnelExclusions)
+		}
+		b.int("mtu", p.MTU)
+		b.optInt("persistent_keepalive", p.PersistentKeepalive)
+		b.strs("dns", p.DNS)
+		b.str("routing_table", p.RoutingTable)
+		b.str("fwmark", p.FwMark)
+		if p.ExpiresAt != nil {
+			b.str("expires_at", p.ExpiresAt.UTC().Format(time.RFC3339))
+		}
+		b.labels("labels", p.Labels)
+		resource("wirety_peer", peerLabels[i], b)
+	}
+
+	for i, p := range e.policies {
+		b := &hclBlock{}
+		b.set("network_id", netRef)
+		b.str("name", p.Name)
+		b.str("description", p.Description)
+		for _, rule := range p.Rules {
+			rb := &hclBlock{}
+			rb.str("direction", rule.Direction)
+			rb.str("action", rule.Action)
+			if rule.TargetType == "peer" || rule.TargetType == "group" {
+				rb.set("target", l.ref(rule.Target))
+			} else {
+				rb.str("target", rule.Target)
+			}
+			rb.str("target_type", rule.TargetType)
+			rb.str("protocol", rule.Protocol)
+			rb.str("ports", rule.Ports)
+			rb.str("description", rule.Description)
+			var nb strings.Builder
+			nb.WriteString("\n")
+			rb.write(&nb, "rule", "  ")
+			b.nested = append(b.nested, nb.String())
+		}
+		resource("wirety_policy", policyLabels[i], b)
+	}
+
+	for i, r := range e.routes {
+		b := &hclBlock{}
+		b.set("network_id", netRef)
+		b.str("name", r.Name)
+		b.str("description", r.Description)
+		b.str("destination_cidr", r.DestinationCIDR)
+		b.str("destination_cidr_v6", r.DestinationCIDRv6)
+		b.set("jump_peer_id", l.ref(r.JumpPeerID))
+		b.str("domain_suffix", r.DomainSuffix)
+		b.int("metric", r.Metric)
+		b.bool("global_route", r.GlobalRoute)
+		resource("wirety_route", routeLabels[i], b)
+	}
+
+	defaults := make(map[string]bool, len(net.DefaultGroupIDs))
+	for _, id := range net.DefaultGroupIDs {
+		defaults[id] = true
+	}
+	for i, g := range e.groups {
+		b := &hclBlock{}
+		b.set("network_id", netRef)
+		b.str("name", g.Name)
+		b.str("description", g.Description)
+		b.int("priority", g.Priority)
+		b.bool("default", defaults[g.ID])
+		b.refs("peer_ids", l.sortedRefs(g.PeerIDs))
+		b.refs("route_ids", l.sortedRefs(g.RouteIDs))
+		resource("wirety_group", groupLabels[i], b)
+	}
+	for i, g := range e.groups {
+		if len(g.PolicyIDs) == 0 {
+			continue
+		}
+		refs := make([]string, len(g.PolicyIDs))
+		for j, id := range g.PolicyIDs {
+			refs[j] = l.ref(id) // in application order
+		}
+		b := &hclBlock{}
+		b.set("group_id", l.ref(g.ID))
+		b.refs("policy_ids", refs)
+		resource("wirety_group_policies", groupLabels[i], b)
+	}
+	return sb.String()
+}
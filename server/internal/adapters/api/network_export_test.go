@@ -0,0 +1,158 @@
+package api
+
+import (
+	"testing"
+
+	domain "wirety/internal/domain/network"
+)
+
+func exportFixture(reversed bool) exportEntities {
+	noKeepalive := 0
+	e := exportEntities{
+		network: &domain.Network{
+			ID: "net1", Name: "office", CIDR: "10.0.0.0/24", DNS: []string{"1.1.1.1"},
+			DomainSuffix: "corp", DefaultGroupIDs: []string{"g-staff"},
+			Labels: map[string]string{"team": "infra", "env": "prod"},
+		},
+		peers: []*domain.Peer{
+			{ID: "p-jump", Name: "jump", IsJump: true, UseAgent: true, Address: "10.0.0.1", Endpoint: "vpn.example.com", ListenPort: 51820, PrivateKey: "secret", Token: "tok"},
+			{ID: "p-laptop", Name: "Laptop 1", Address: "10.0.0.2", OwnerID: "alice", PrivateKey: "secret", Role: domain.PeerRoleClient},
+			{ID: "p-db", Name: "db", Address: "10.0.0.3", Role: domain.PeerRoleResource, SplitTunnelExclusions: []string{}, PersistentKeepalive: &noKeepalive},
+		},
+		groups: []*domain.Group{
+			{ID: "g-staff", Name: "staff", Priority: 100, PeerIDs: []string{"p-laptop"}, PolicyIDs: []string{"pol-web", "pol-deny"}, RouteIDs: []string{"r-lan"}},
+		},
+		policies: []*domain.Policy{
+			{ID: "pol-web", Name: "web", Rules: []domain.PolicyRule{
+				{Direction: "input", Action: "allow", Target: "g-staff", TargetType: "group", Protocol: "tcp", Ports: "443"},
+			}},
+			{ID: "pol-deny", Name: "deny ${all}", Rules: []domain.PolicyRule{
+				{Direction: "input", Action: "deny", Target: "0.0.0.0/0", TargetType: "cidr"},
+			}},
+		},
+		routes: []*domain.Route{
+			{ID: "r-lan", Name: "lan", DestinationCIDR: "192.168.1.0/24", JumpPeerID: "p-jump", DomainSuffix: "lan", Metric: 10},
+		},
+	}
+	if reversed {
+		for i, j := 0, len(e.peers)-1; i < j; i, j = i+1, j-1 {
+			e.peers[i], e.peers[j] = e.peers[j], e.peers[i]
+		}
+		e.policies[0], e.policies[1] = e.policies[1], e.policies[0]
+	}
+	return e
+}
+
+func TestRenderNetworkHCL(t *testing.T) {
+	want := `# Network "office" (net1) exported by Wirety.
+
+resource "wirety_network" "office" {
+  name          = "office"
+  cidr          = "10.0.0.0/24"
+  dns           = ["1.1.1.1"]
+  domain_suffix = "corp"
+  labels        = { "env" = "prod", "team" = "infra" }
+}
+
+resource "wirety_peer" "laptop_1" {
+  network_id = wirety_network.office.id
+  name       = "Laptop 1"
+  address    = "10.0.0.2"
+  owner_id   = "alice"
+}
+
+resource "wirety_peer" "db" {
+  network_id              = wirety_network.office.id
+  name                    = "db"
+  role                    = "resource"
+  address                 = "10.0.0.3"
+  split_tunnel_exclusions = []
+  persistent_keepalive    = 0
+}
+
+resource "wirety_peer" "jump" {
+  network_id  = wirety_network.office.id
+  name        = "jump"
+  is_jump     = true
+  use_agent   = true
+  address     = "10.0.0.1"
+  endpoint    = "vpn.example.com"
+  listen_port = 51820
+}
+
+resource "wirety_policy" "deny___all_" {
+  network_id = wirety_network.office.id
+  name       = "deny $${all}"
+
+  rule {
+    direction   = "input"
+    action      = "deny"
+    target      = "0.0.0.0/0"
+    target_type = "cidr"
+  }
+}
+
+resource "wirety_policy" "web" {
+  network_id = wirety_network.office.id
+  name       = "web"
+
+  rule {
+    direction   = "input"
+    action      = "allow"
+    target      = wirety_group.staff.id
+    target_type = "group"
+    protocol    = "tcp"
+    ports       = "443"
+  }
+}
+
+resource "wirety_route" "lan" {
+  network_id       = wirety_network.office.id
+  name             = "lan"
+  destination_cidr = "192.168.1.0/24"
+  jump_peer_id     = wirety_peer.jump.id
+  domain_suffix    = "lan"
+  metric           = 10
+}
+
+resource "wirety_group" "staff" {
+  network_id = wirety_network.office.id
+  name       = "staff"
+  priority   = 100
+  default    = true
+  peer_ids   = [wirety_peer.laptop_1.id]
+  route_ids  = [wirety_route.lan.id]
+}
+
+resource "wirety_group_policies" "staff" {
+  group_id   = wirety_group.staff.id
+  policy_ids = [wirety_policy.web.id, wirety_policy.deny___all_.id]
+}
+`
+	got := renderNetworkHCL(exportFixture(false))
+	if got != want {
+		t.Errorf("unexpected HCL:\n%s", got)
+	}
+	if again := renderNetworkHCL(exportFixture(true)); again != got {
+		t.Errorf("export depends on the input order:\n%s", again)
+	}
+}
+
+func TestHCLLabelerDeduplicates(t *testing.T) {
+	l := &hclLabeler{used: map[string]bool{}, refs: map[string]string{}}
+	if got := l.add("wirety_peer", "a", "web-1"); got != "web_1" {
+		t.Errorf("label = %q, want web_1", got)
+	}
+	if got := l.add("wirety_peer", "b", "web_1"); got != "web_1_2" {
+		t.Errorf("duplicate label = %q, want web_1_2", got)
+	}
+	if got := l.add("wirety_route", "c", "web-1"); got != "web_1" {
+		t.Errorf("label of another type = %q, want web_1", got)
+	}
+	if got := l.add("wirety_peer", "d", "1st"); got != "_1st" {
+		t.Errorf("label starting with a digit = %q, want _1st", got)
+	}
+	if got := l.ref("unknown"); got != `"unknown"` {
+		t.Errorf("ref to an unexported ID = %s, want the quoted ID", got)
+	}
+}
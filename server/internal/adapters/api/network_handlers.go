@@ -1,14 +1,22 @@
 package api
 
 import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"sort"
 	"strconv"
+	"strings"
 
 	"wirety/internal/adapters/api/middleware"
 	"wirety/internal/audit"
 	domain "wirety/internal/domain/network"
+	"wirety/internal/infrastructure/validation"
 
 	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
 )
 
 // PaginatedNetworks represents a paginated list of networks
@@ -60,6 +68,76 @@ func (h *Handler) CreateNetwork(c *gin.Context) {
 	c.JSON(http.StatusCreated, net)
 }
 
+// ValidateNetworkDocument godoc
+//
+//	@Summary		Validate a proposed network
+//	@Description	Validate a complete network definition (network, peers, routes, policies) without creating anything. Routes reference their jump peer by name. Returns every error and warning found.
+//	@Tags			networks
+//	@Accept			json
+//	@Produce		json
+//	@Param			document	body		domain.NetworkDocument	true	"Proposed network definition"
+//	@Success		200			{object}	domain.ValidationReport
+//	@Failure		400			{object}	map[string]string
+//	@Failure		500			{object}	map[string]string
+//	@Router			/networks/validate [post]
+//	@Security		BearerAuth
+func (h *Handler) ValidateNetworkDocument(c *gin.Context) {
+	var doc domain.NetworkDocument
+	if err := c.ShouldBindJSON(&doc); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	report, err := h.service.ValidateNetworkDocument(c.Request.Context(), &doc)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// ImportNetwork godoc
+//
+//	@Summary		Import a network
+//	@Description	Create a network with its peers, groups, policies and routes from a network definition, as returned by GET /networks/{networkId}/export?format=json (admin only). Entities reference each other by name and get fresh IDs; peers get fresh keys and enrollment tokens. Entities that cannot be created are reported in errors without aborting the import.
+//	@Tags			networks
+//	@Accept			json
+//	@Produce		json
+//	@Param			document	body		domain.NetworkDocument	true	"Network definition"
+//	@Success		201			{object}	domain.NetworkImportResult
+//	@Failure		400			{object}	map[string]string
+//	@Failure		500			{object}	map[string]string
+//	@Router			/networks/import [post]
+//	@Security		BearerAuth
+func (h *Handler) ImportNetwork(c *gin.Context) {
+	var doc domain.NetworkDocument
+	if err := c.ShouldBindJSON(&doc); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	res, err := h.importer.ImportNetwork(c.Request.Context(), &doc)
+	if err != nil {
+		if isValidationError(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	id, email := actor(c)
+	audit.Server(id, email, c.ClientIP()).
+		Str("action", "network.import").
+		Str("network_id", res.Network.ID).
+		Str("network_name", res.Network.Name).
+		Int("errors", len(res.Errors)).
+		Msg("audit")
+
+	c.JSON(http.StatusCreated, res)
+}
+
 // GetNetwork godoc
 //
 //	@Summary		Get a network
@@ -93,6 +171,7 @@ func (h *Handler) GetNetwork(c *gin.Context) {
 // @Param        page      query int    false "Page number" default(1)
 // @Param        page_size query int    false "Page size" default(20)
 // @Param        filter    query string false "Filter by network name or CIDR"
+// @Param        label     query []string false "Only networks with this label, as key=value or key (repeatable)" collectionFormat(multi)
 // @Success      200 {object} PaginatedNetworks
 // @Failure      500 {object} map[string]string
 // @Router       /networks [get]
@@ -101,6 +180,7 @@ func (h *Handler) ListNetworks(c *gin.Context) {
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
 	filter := c.Query("filter")
+	labels := c.QueryArray("label")
 
 	if page < 1 {
 		page = 1
@@ -124,7 +204,7 @@ func (h *Handler) ListNetworks(c *gin.Context) {
 
 	var hasAccess []*domain.Network
 	for _, n := range networks {
-		if user.HasNetworkAccess(n.ID) {
+		if user.HasNetworkAccess(n.ID) && domain.MatchLabels(n.Labels, labels) {
 			hasAccess = append(hasAccess, n)
 		}
 	}
@@ -162,13 +242,15 @@ func (h *Handler) ListNetworks(c *gin.Context) {
 // UpdateNetwork godoc
 //
 //	@Summary		Update a network
-//	@Description	Update a network's configuration
+//	@Description	Update a network's configuration. With dry_run=true and a cidr, nothing is changed and the peer re-addressing plan is returned instead.
 //	@Tags			networks
 //	@Accept			json
 //	@Produce		json
 //	@Param			networkId	path		string						true	"Network ID"
+//	@Param			dry_run		query		bool						false	"Return the CIDR change plan without applying it"
 //	@Param			network		body		domain.NetworkUpdateRequest	true	"Network update request"
 //	@Success		200			{object}	domain.Network
+//	@Success		200			{object}	domain.CIDRChangePlan	"dry_run=true"
 //	@Failure		400			{object}	map[string]string
 //	@Failure		404			{object}	map[string]string
 //	@Router			/networks/{networkId} [put]
@@ -183,6 +265,24 @@ func (h *Handler) UpdateNetwork(c *gin.Context) {
 		return
 	}
 
+	if c.Query("dry_run") == "true" {
+		if req.CIDR == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "dry_run requires a cidr"})
+			return
+		}
+		plan, err := h.service.PlanCIDRChange(c.Request.Context(), networkID, req.CIDR)
+		if err != nil {
+			if isValidationError(err) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			} else {
+				c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			}
+			return
+		}
+		c.JSON(http.StatusOK, plan)
+		return
+	}
+
 	net, err := h.service.UpdateNetwork(c.Request.Context(), networkID, &req)
 	if err != nil {
 		if isValidationError(err) {
@@ -206,7 +306,7 @@ func (h *Handler) UpdateNetwork(c *gin.Context) {
 // DeleteNetwork godoc
 //
 //	@Summary		Delete a network
-//	@Description	Delete a network by ID
+//	@Description	Delete a network by ID. The network is kept, with its peers and CIDR, and can be restored until the retention window (NETWORK_RETENTION_HOURS) has passed; it is purged afterwards.
 //	@Tags			networks
 //	@Param			networkId	path	string	true	"Network ID"
 //	@Success		204
@@ -230,3 +330,303 @@ func (h *Handler) DeleteNetwork(c *gin.Context) {
 
 	c.Status(http.StatusNoContent)
 }
+
+// RestoreNetwork godoc
+//
+//	@Summary		Restore a deleted network
+//	@Description	Undo the deletion of a network while it is within the retention window. The network comes back with its peers, settings and CIDR.
+//	@Tags			networks
+//	@Produce		json
+//	@Param			networkId	path		string	true	"Network ID"
+//	@Success		200			{object}	domain.Network
+//	@Failure		404			{object}	map[string]string
+//	@Failure		409			{object}	map[string]string
+//	@Failure		410			{object}	map[string]string
+//	@Failure		500			{object}	map[string]string
+//	@Router			/networks/{networkId}/restore [post]
+//	@Security		BearerAuth
+func (h *Handler) RestoreNetwork(c *gin.Context) {
+	networkID := c.Param("networkId")
+
+	net, err := h.service.RestoreNetwork(c.Request.Context(), networkID)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrNetworkNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, domain.ErrNetworkNotDeleted):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.Is(err, domain.ErrRestoreWindowExpired):
+			c.JSON(http.StatusGone, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	id, email := actor(c)
+	audit.Server(id, email, c.ClientIP()).
+		Str("action", "network.restore").
+		Str("network_id", networkID).
+		Msg("audit")
+
+	c.JSON(http.StatusOK, net)
+}
+
+// CloneNetwork godoc
+//
+//	@Summary		Clone a network
+//	@Description	Create a new network with the settings, peer profiles, groups, policies, routes and DNS records of an existing one. Peers and IP allocations are not copied; cloned routes have no jump peer until one is assigned.
+//	@Tags			networks
+//	@Accept			json
+//	@Produce		json
+//	@Param			networkId	path		string						true	"Source network ID"
+//	@Param			clone		body		domain.NetworkCloneRequest	true	"Name and CIDR of the new network"
+//	@Success		201			{object}	domain.Network
+//	@Failure		400			{object}	map[string]string
+//	@Failure		404			{object}	map[string]string
+//	@Failure		500			{object}	map[string]string
+//	@Router			/networks/{networkId}/clone [post]
+//	@Security		BearerAuth
+func (h *Handler) CloneNetwork(c *gin.Context) {
+	networkID := c.Param("networkId")
+
+	var req domain.NetworkCloneRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	net, err := h.service.CloneNetwork(c.Request.Context(), networkID, req.Name, req.CIDR)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrNetworkNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case isValidationError(err):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	id, email := actor(c)
+	audit.Server(id, email, c.ClientIP()).
+		Str("action", "network.clone").
+		Str("network_id", net.ID).
+		Str("network_name", net.Name).
+		Str("source_network_id", networkID).
+		Msg("audit")
+
+	c.JSON(http.StatusCreated, net)
+}
+
+// RotatePresharedKeys godoc
+//
+//	@Summary		Rotate preshared keys
+//	@Description	Regenerate the preshared key of every peer connection in the network and push new configs to the peers. Safe to call repeatedly.
+//	@Tags			networks
+//	@Produce		json
+//	@Param			networkId	path		string	true	"Network ID"
+//	@Success		200			{object}	map[string]int
+//	@Failure		404			{object}	map[string]string
+//	@Failure		500			{object}	map[string]string
+//	@Router			/networks/{networkId}/rotate-psk [post]
+//	@Security		BearerAuth
+func (h *Handler) RotatePresharedKeys(c *gin.Context) {
+	networkID := c.Param("networkId")
+
+	rotated, err := h.service.RotateNetworkPresharedKeys(c.Request.Context(), networkID)
+	if err != nil {
+		if errors.Is(err, domain.ErrNetworkNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "rotated": rotated})
+		}
+		return
+	}
+
+	id, email := actor(c)
+	audit.Server(id, email, c.ClientIP()).
+		Str("action", "network.rotate_psk").
+		Str("network_id", networkID).
+		Int("connections", rotated).
+		Msg("audit")
+
+	c.JSON(http.StatusOK, gin.H{"rotated": rotated})
+}
+
+// GetTopology godoc
+//
+//	@Summary		Get the network topology
+//	@Description	Returns the network as a graph for diagrams: its peers as nodes, and the tunnels and routes between them as edges (admin only). Quarantined and expired peers have no edges.
+//	@Tags			networks
+//	@Produce		json
+//	@Param			networkId	path		string	true	"Network ID"
+//	@Success		200			{object}	network.Topology
+//	@Failure		404			{object}	map[string]string
+//	@Failure		500			{object}	map[string]string
+//	@Router			/networks/{networkId}/topology [get]
+//	@Security		BearerAuth
+func (h *Handler) GetTopology(c *gin.Context) {
+	topo, err := h.service.GetTopology(c.Request.Context(), c.Param("networkId"))
+	if err != nil {
+		if errors.Is(err, domain.ErrNetworkNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, topo)
+}
+
+// ExportNetwork godoc
+//
+//	@Summary		Export a network
+//	@Description	Download the network, its peers, groups, policies and routes (admin only), as Terraform (HCL) resource blocks referring to each other by resource name, or as the JSON network definition POST /networks/import takes. Keys and enrollment tokens are left out. The output only changes when the network does.
+//	@Tags			networks
+//	@Produce		plain
+//	@Produce		json
+//	@Param			networkId	path		string	true	"Network ID"
+//	@Param			format		query		string	false	"Export format"	Enums(hcl, json)	default(hcl)
+//	@Success		200			{string}	string
+//	@Failure		400			{object}	map[string]string
+//	@Failure		404			{object}	map[string]string
+//	@Failure		500			{object}	map[string]string
+//	@Router			/networks/{networkId}/export [get]
+//	@Security		BearerAuth
+func (h *Handler) ExportNetwork(c *gin.Context) {
+	format := c.DefaultQuery("format", "hcl")
+	if format != "hcl" && format != "json" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be hcl or json"})
+		return
+	}
+	networkID := c.Param("networkId")
+	ctx := c.Request.Context()
+
+	net, err := h.service.GetNetwork(ctx, networkID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	e := exportEntities{network: net}
+	if e.peers, err = h.service.ListPeers(ctx, networkID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if e.groups, err = h.groupService.ListGroups(ctx, networkID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if e.policies, err = h.policyService.ListPolicies(ctx, networkID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if e.routes, err = h.routeService.ListRoutes(ctx, networkID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if format == "json" {
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.json"`, validation.SanitizeDNSName(net.Name)))
+		c.IndentedJSON(http.StatusOK, networkDocument(e))
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.tf"`, validation.SanitizeDNSName(net.Name)))
+	c.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(renderNetworkHCL(e)))
+}
+
+// ExportPeerConfigs godoc
+//
+//	@Summary		Export all peer configurations
+//	@Description	Stream a zip archive holding the WireGuard configuration of every peer in the network, one <peer-name>.conf per peer (admin only). Peers whose configuration cannot be generated are listed in errors.txt.
+//	@Tags			networks
+//	@Produce		application/zip
+//	@Param			networkId	path		string	true	"Network ID"
+//	@Success		200			{file}		file
+//	@Failure		404			{object}	map[string]string
+//	@Failure		500			{object}	map[string]string
+//	@Router			/networks/{networkId}/configs.zip [get]
+//	@Security		BearerAuth
+func (h *Handler) ExportPeerConfigs(c *gin.Context) {
+	// Unlike other admin reads, the archive holds private keys, so auditors
+	// are not allowed
+	if user := middleware.GetUserFromContext(c); user != nil && !user.IsAdministrator() {
+		c.JSON(http.StatusForbidden, gin.H{"error": "administrator role required"})
+		return
+	}
+	networkID := c.Param("networkId")
+	ctx := c.Request.Context()
+
+	net, err := h.service.GetNetwork(ctx, networkID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	peers, err := h.service.ListPeers(ctx, networkID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	sort.Slice(peers, func(i, j int) bool { return peers[i].Name < peers[j].Name })
+
+	// Entries are written straight to the response, so the status is
+	// committed before the first config is generated.  Failures past this
+	// point can only be reported inside the archive.
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s-configs.zip"`, validation.SanitizeDNSName(net.Name)))
+	c.Status(http.StatusOK)
+
+	zw := zip.NewWriter(c.Writer)
+	used := make(map[string]bool, len(peers))
+	var failures []string
+	for _, peer := range peers {
+		config, err := h.service.GeneratePeerConfig(ctx, networkID, peer.ID)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s (%s): %v", peer.Name, peer.ID, err))
+			continue
+		}
+		w, err := zw.Create(configFileName(peer, used))
+		if err == nil {
+			_, err = io.WriteString(w, config)
+		}
+		if err != nil {
+			// The client went away or the archive is broken; either way
+			// there is nothing useful left to send.
+			log.Warn().Err(err).Str("network_id", networkID).Msg("config export aborted")
+			return
+		}
+	}
+	if len(failures) > 0 {
+		if w, err := zw.Create("errors.txt"); err == nil {
+			_, _ = io.WriteString(w, strings.Join(failures, "\n")+"\n")
+		}
+	}
+	if err := zw.Close(); err != nil {
+		log.Warn().Err(err).Str("network_id", networkID).Msg("config export aborted")
+		return
+	}
+
+	id, email := actor(c)
+	audit.Server(id, email, c.ClientIP()).
+		Str("action", "network.export_configs").
+		Str("network_id", networkID).
+		Int("peers", len(peers)-len(failures)).
+		Msg("audit")
+}
+
+// configFileName returns a unique archive entry name for peer, derived from
+// its sanitized name.  Peers whose names sanitize to the same value get a
+// numeric suffix in the order they are written.
+func configFileName(peer *domain.Peer, used map[string]bool) string {
+	base := validation.SanitizeDNSName(peer.Name)
+	if base == "" {
+		base = peer.ID
+	}
+	name := base
+	for i := 2; used[name]; i++ {
+		name = fmt.Sprintf("%s-%d", base, i)
+	}
+	used[name] = true
+	return name + ".conf"
+}
@@ -0,0 +1,244 @@
+package api
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"wirety/internal/adapters/api/middleware"
+	"wirety/internal/adapters/db/memory"
+	"wirety/internal/application/network"
+	"wirety/internal/domain/auth"
+	domain "wirety/internal/domain/network"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestExportPeerConfigs_ZipsOneConfigPerPeer(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+
+	repo := memory.NewRepository()
+	if err := repo.CreateNetwork(ctx, &domain.Network{ID: "net1", Name: "office", CIDR: "10.0.0.0/24", Peers: map[string]*domain.Peer{}}); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []*domain.Peer{
+		{ID: "p1", Name: "jump", Address: "10.0.0.1", IsJump: true, Endpoint: "vpn.example.com", ListenPort: 51820},
+		{ID: "p2", Name: "Laptop", Address: "10.0.0.2"},
+		{ID: "p3", Name: "laptop", Address: "10.0.0.3"},
+	} {
+		if err := repo.CreatePeer(ctx, "net1", p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	h := &Handler{service: network.NewService(repo, memory.NewIPAMRepository(ctx), nil, nil, nil, nil, nil)}
+
+	r := gin.New()
+	r.GET("/networks/:networkId/configs.zip", h.ExportPeerConfigs)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/networks/net1/configs.zip", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/zip" {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+	if err != nil {
+		t.Fatalf("response is not a zip archive: %v", err)
+	}
+	var names []string
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(rc)
+		_ = rc.Close()
+		if !strings.Contains(string(body), "[Interface]") {
+			t.Errorf("%s does not hold a WireGuard config:\n%s", f.Name, body)
+		}
+	}
+	sort.Strings(names)
+	if want := "jump.conf laptop-2.conf laptop.conf"; strings.Join(names, " ") != want {
+		t.Fatalf("entries = %v, want %s", names, want)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/networks/missing/configs.zip", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("unknown network: status %d", w.Code)
+	}
+}
+
+func TestListNetworks_FiltersByLabel(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+
+	repo := memory.NewRepository()
+	for _, n := range []*domain.Network{
+		{ID: "net1", Name: "prod-eu", CIDR: "10.0.0.0/24", Labels: map[string]string{"env": "prod", "region": "eu"}},
+		{ID: "net2", Name: "prod-us", CIDR: "10.0.1.0/24", Labels: map[string]string{"env": "prod"}},
+		{ID: "net3", Name: "staging", CIDR: "10.0.2.0/24", Labels: map[string]string{"env": "staging"}},
+		{ID: "net4", Name: "lab", CIDR: "10.0.3.0/24"},
+	} {
+		n.Peers = map[string]*domain.Peer{}
+		if err := repo.CreateNetwork(ctx, n); err != nil {
+			t.Fatal(err)
+		}
+	}
+	h := &Handler{service: network.NewService(repo, nil, nil, nil, nil, nil, nil)}
+
+	admin := &auth.User{ID: "admin", Role: auth.RoleAdministrator}
+	r := gin.New()
+	r.GET("/networks", func(c *gin.Context) {
+		c.Set(middleware.UserContextKey, admin)
+		c.Next()
+	}, h.ListNetworks)
+
+	for query, want := range map[string]string{
+		"":                             "net1 net2 net3 net4",
+		"?label=env=prod":              "net1 net2",
+		"?label=env=prod&label=region": "net1",
+		"?label=env":                   "net1 net2 net3",
+		"?label=env=dev":               "",
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/networks"+query, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%q: status %d: %s", query, w.Code, w.Body)
+		}
+		var page PaginatedNetworks
+		if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil {
+			t.Fatal(err)
+		}
+		var ids []string
+		for _, n := range page.Data {
+			ids = append(ids, n.ID)
+		}
+		sort.Strings(ids)
+		if got := strings.Join(ids, " "); got != want {
+			t.Errorf("%q: networks = %q, want %q", query, got, want)
+		}
+	}
+}
+
+func TestCloneNetwork_CopiesResourcesWithFreshIDs(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+
+	repo := memory.NewRepository()
+	groupRepo := memory.NewGroupRepository(repo)
+	routeRepo := memory.NewRouteRepository(repo)
+	dnsRepo := memory.NewDNSRepository(repo)
+	policyRepo := memory.NewPolicyRepository(repo)
+	svc := network.NewService(repo, memory.NewIPAMRepository(ctx), nil, groupRepo, routeRepo, dnsRepo, policyRepo)
+
+	src, err := svc.CreateNetwork(ctx, &domain.NetworkCreateRequest{Name: "acme", CIDR: "10.0.0.0/24", DomainSuffix: "acme.internal"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	jump := &domain.Peer{ID: "jump", Name: "jump", Address: "10.0.0.1", IsJump: true}
+	if err := repo.CreatePeer(ctx, src.ID, jump); err != nil {
+		t.Fatal(err)
+	}
+	group := &domain.Group{ID: "g1", Name: "staff", Priority: 10}
+	if err := groupRepo.CreateGroup(ctx, src.ID, group); err != nil {
+		t.Fatal(err)
+	}
+	src.DefaultGroupIDs = []string{"g1"}
+	if err := repo.UpdateNetwork(ctx, src); err != nil {
+		t.Fatal(err)
+	}
+	policy := &domain.Policy{ID: "pol1", Name: "web", Rules: []domain.PolicyRule{
+		{ID: "r1", Direction: "input", Action: "allow", Target: "g1", TargetType: "group"},
+		{ID: "r2", Direction: "input", Action: "allow", Target: "jump", TargetType: "peer"},
+		{ID: "r3", Direction: "output", Action: "allow", Target: "192.168.0.0/24", TargetType: "cidr"},
+	}}
+	if err := policyRepo.CreatePolicy(ctx, src.ID, policy); err != nil {
+		t.Fatal(err)
+	}
+	route := &domain.Route{ID: "rt1", Name: "lan", DestinationCIDR: "192.168.0.0/24", JumpPeerID: "jump"}
+	if err := routeRepo.CreateRoute(ctx, src.ID, route); err != nil {
+		t.Fatal(err)
+	}
+	if err := dnsRepo.CreateDNSMapping(ctx, "rt1", &domain.DNSMapping{ID: "m1", Name: "nas", IPAddress: "192.168.0.10"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := dnsRepo.CreateNetworkDNSRecord(ctx, &domain.NetworkDNSRecord{ID: "rec1", NetworkID: src.ID, Name: "wiki", IPAddress: "10.0.0.50"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := groupRepo.AttachPolicyToGroup(ctx, src.ID, "g1", "pol1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := groupRepo.AttachRouteToGroup(ctx, src.ID, "g1", "rt1"); err != nil {
+		t.Fatal(err)
+	}
+
+	h := &Handler{service: svc}
+	r := gin.New()
+	r.POST("/networks/:networkId/clone", h.CloneNetwork)
+	req := httptest.NewRequest(http.MethodPost, "/networks/"+src.ID+"/clone", strings.NewReader(`{"name":"globex","cidr":"10.1.0.0/24"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	var clone domain.Network
+	if err := json.Unmarshal(w.Body.Bytes(), &clone); err != nil {
+		t.Fatal(err)
+	}
+	if clone.ID == src.ID || clone.Name != "globex" || clone.CIDR != "10.1.0.0/24" || clone.DomainSuffix != "acme.internal" {
+		t.Fatalf("clone = %+v", clone)
+	}
+
+	if peers, _ := repo.ListPeers(ctx, clone.ID); len(peers) != 0 {
+		t.Errorf("peers were cloned: %v", peers)
+	}
+	groups, _ := groupRepo.ListGroups(ctx, clone.ID)
+	policies, _ := policyRepo.ListPolicies(ctx, clone.ID)
+	routes, _ := routeRepo.ListRoutes(ctx, clone.ID)
+	if len(groups) != 1 || len(policies) != 1 || len(routes) != 1 {
+		t.Fatalf("cloned %d groups, %d policies, %d routes; want 1 of each", len(groups), len(policies), len(routes))
+	}
+	g, p, rt := groups[0], policies[0], routes[0]
+	if g.ID == "g1" || p.ID == "pol1" || rt.ID == "rt1" {
+		t.Errorf("cloned entities reuse source IDs: group %s, policy %s, route %s", g.ID, p.ID, rt.ID)
+	}
+	if rt.JumpPeerID != "" {
+		t.Errorf("cloned route jump peer = %q, want none", rt.JumpPeerID)
+	}
+	if len(p.Rules) != 2 || p.Rules[0].Target != g.ID || p.Rules[0].ID == "r1" || p.Rules[1].Target != "192.168.0.0/24" {
+		t.Errorf("cloned rules = %+v, want the group rule re-pointed to %s and the peer rule dropped", p.Rules, g.ID)
+	}
+	if strings.Join(g.PolicyIDs, ",") != p.ID || strings.Join(g.RouteIDs, ",") != rt.ID {
+		t.Errorf("cloned group attachments: policies %v, routes %v", g.PolicyIDs, g.RouteIDs)
+	}
+	if stored, _ := repo.GetNetwork(ctx, clone.ID); strings.Join(clone.DefaultGroupIDs, ",") != g.ID || strings.Join(stored.DefaultGroupIDs, ",") != g.ID {
+		t.Errorf("clone default groups = %v, stored %v; want [%s]", clone.DefaultGroupIDs, stored.DefaultGroupIDs, g.ID)
+	}
+	if mappings, _ := dnsRepo.ListDNSMappings(ctx, rt.ID); len(mappings) != 1 || mappings[0].Name != "nas" || mappings[0].ID == "m1" {
+		t.Errorf("cloned DNS mappings = %+v", mappings)
+	}
+	if records, _ := dnsRepo.ListNetworkDNSRecords(ctx, clone.ID); len(records) != 1 || records[0].Name != "wiki" || records[0].ID == "rec1" {
+		t.Errorf("cloned DNS records = %+v", records)
+	}
+
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodPost, "/networks/missing/clone", strings.NewReader(`{"name":"x","cidr":"10.2.0.0/24"}`))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("unknown network: status %d", w.Code)
+	}
+}
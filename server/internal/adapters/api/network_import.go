@@ -0,0 +1,139 @@
+package api
+
+import (
+	"sort"
+
+	domain "wirety/internal/domain/network"
+)
+
+// JSON export
+//
+// A network travels as a domain.NetworkDocument, where entities refer to each
+// other by name.  network.Importer creates a network from one.
+
+// networkDocument converts e to the document network.Importer takes, replacing
+// references by names.  Entities are sorted as in the HCL export.
+func networkDocument(e exportEntities) *domain.NetworkDocument {
+	byNameThenID(e.peers, func(p *domain.Peer) (string, string) { return p.Name, p.ID })
+	byNameThenID(e.groups, func(g *domain.Group) (string, string) { return g.Name, g.ID })
+	byNameThenID(e.policies, func(p *domain.Policy) (string, string) { return p.Name, p.ID })
+	byNameThenID(e.routes, func(r *domain.Route) (string, string) { return r.Name, r.ID })
+
+	names := make(map[string]string)
+	for _, p := range e.peers {
+		names[p.ID] = p.Name
+	}
+	for _, g := range e.groups {
+		names[g.ID] = g.Name
+	}
+	for _, p := range e.policies {
+		names[p.ID] = p.Name
+	}
+	for _, r := range e.routes {
+		names[r.ID] = r.Name
+	}
+	nameOf := func(id string) string {
+		if name, ok := names[id]; ok {
+			return name
+		}
+		return id
+	}
+	namesOf := func(ids []string) []string {
+		if len(ids) == 0 {
+			return nil
+		}
+		out := make([]string, len(ids))
+		for i, id := range ids {
+			out[i] = nameOf(id)
+		}
+		return out
+	}
+
+	net := e.network
+	doc := &domain.NetworkDocument{
+		Network: domain.NetworkCreateRequest{
+			Name:                  net.Name,
+			CIDR:                  net.CIDR,
+			CIDRv6:                net.CIDRv6,
+			DNS:                   net.DNS,
+			DomainSuffix:          net.DomainSuffix,
+			PeerNamePattern:       net.PeerNamePattern,
+			Topology:              net.Topology,
+			MultiJumpFailover:     net.MultiJumpFailover,
+			OmitPrivateKeys:       net.OmitPrivateKeys,
+			ConditionalForwarders: net.ConditionalForwarders,
+			Labels:                net.Labels,
+			DNSQueryLog:           net.DNSQueryLog,
+			JumpPostUp:            net.JumpPostUp,
+			JumpPostDown:          net.JumpPostDown,
+			JumpNATInterface:      net.JumpNATInterface,
+			ConfigMetadata:        net.ConfigMetadata,
+			SearchDomains:         net.SearchDomains,
+		},
+		Peers:    make([]domain.PeerCreateRequest, 0, len(e.peers)),
+		Groups:   make([]domain.GroupDocument, 0, len(e.groups)),
+		Routes:   make([]domain.RouteCreateRequest, 0, len(e.routes)),
+		Policies: make([]domain.PolicyCreateRequest, 0, len(e.policies)),
+	}
+	for _, p := range e.peers {
+		req := domain.PeerCreateRequest{
+			Name:                  p.Name,
+			Endpoint:              p.Endpoint,
+			ListenPort:            p.ListenPort,
+			IsJump:                p.IsJump,
+			UseAgent:              p.UseAgent,
+			OwnerID:               p.OwnerID,
+			AdditionalAllowedIPs:  p.AdditionalAllowedIPs,
+			SplitTunnelExclusions: p.SplitTunnelExclusions,
+			MTU:                   p.MTU,
+			PersistentKeepalive:   p.PersistentKeepalive,
+			DNS:                   p.DNS,
+			RoutingTable:          p.RoutingTable,
+			FwMark:                p.FwMark,
+			Address:               p.Address,
+			ExpiresAt:             p.ExpiresAt,
+			Labels:                p.Labels,
+			DNSOnly:               p.DNSOnly,
+		}
+		if !p.IsJump && p.Role != domain.PeerRoleClient {
+			req.Role = p.Role
+		}
+		doc.Peers = append(doc.Peers, req)
+	}
+	for _, g := range e.groups {
+		priority := g.Priority
+		gd := domain.GroupDocument{
+			GroupCreateRequest: domain.GroupCreateRequest{Name: g.Name, Description: g.Description, Priority: &priority},
+			Peers:              namesOf(g.PeerIDs),
+			Policies:           namesOf(g.PolicyIDs), // in application order
+			Routes:             namesOf(g.RouteIDs),
+		}
+		sort.Strings(gd.Peers)
+		sort.Strings(gd.Routes)
+		doc.Groups = append(doc.Groups, gd)
+	}
+	for _, p := range e.policies {
+		rules := make([]domain.PolicyRule, len(p.Rules))
+		for i, rule := range p.Rules {
+			rule.ID = ""
+			if rule.TargetType == "peer" || rule.TargetType == "group" {
+				rule.Target = nameOf(rule.Target)
+			}
+			rules[i] = rule
+		}
+		doc.Policies = append(doc.Policies, domain.PolicyCreateRequest{Name: p.Name, Description: p.Description, Rules: rules})
+	}
+	for _, r := range e.routes {
+		doc.Routes = append(doc.Routes, domain.RouteCreateRequest{
+			Name:              r.Name,
+			Description:       r.Description,
+			DestinationCIDR:   r.DestinationCIDR,
+			DestinationCIDRv6: r.DestinationCIDRv6,
+			JumpPeerID:        nameOf(r.JumpPeerID),
+			DomainSuffix:      r.DomainSuffix,
+			Metric:            r.Metric,
+			GlobalRoute:       r.GlobalRoute,
+		})
+	}
+	return doc
+}
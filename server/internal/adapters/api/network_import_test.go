@@ -0,0 +1,143 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"wirety/internal/adapters/db/memory"
+	"wirety/internal/application/group"
+	"wirety/internal/application/network"
+	"wirety/internal/application/policy"
+	"wirety/internal/application/route"
+	domain "wirety/internal/domain/network"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newImportHandler(ctx context.Context) *Handler {
+	repo := memory.NewRepository()
+	groupRepo := memory.NewGroupRepository(repo)
+	routeRepo := memory.NewRouteRepository(repo)
+	policyRepo := memory.NewPolicyRepository(repo)
+	h := &Handler{
+		service:       network.NewService(repo, memory.NewIPAMRepository(ctx), nil, groupRepo, routeRepo, memory.NewDNSRepository(repo), policyRepo),
+		groupService:  group.NewService(groupRepo, repo, routeRepo),
+		policyService: policy.NewService(policyRepo, groupRepo, repo, routeRepo),
+		routeService:  route.NewService(routeRepo, groupRepo, repo),
+	}
+	h.importer = network.NewImporter(h.service, h.groupService, h.policyService, h.routeService)
+	return h
+}
+
+func TestImportNetwork_RoundTripsTheJSONExport(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+	h := newImportHandler(ctx)
+	r := gin.New()
+	r.POST("/networks/import", h.ImportNetwork)
+	r.GET("/networks/:networkId/export", h.ExportNetwork)
+
+	doc := `{
+		"network": {"name": "office", "cidr": "10.10.0.0/24"},
+		"peers": [
+			{"name": "gw", "is_jump": true, "endpoint": "vpn.example.com", "listen_port": 51820},
+			{"name": "laptop", "public_key": "not-carried-over"}
+		],
+		"groups": [
+			{"name": "staff", "priority": 50, "peers": ["laptop", "ghost"], "policies": ["web"], "routes": ["lan"]}
+		],
+		"routes": [
+			{"name": "lan", "destination_cidr": "192.168.1.0/24", "jump_peer_id": "gw"},
+			{"name": "dmz", "destination_cidr": "192.168.2.0/24", "jump_peer_id": "missing"}
+		],
+		"policies": [
+			{"name": "web", "rules": [
+				{"direction": "input", "action": "allow", "target": "staff", "target_type": "group", "protocol": "tcp", "ports": "443"}
+			]}
+		]
+	}`
+	import1 := func(body string) domain.NetworkImportResult {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/networks/import", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("import: status %d: %s", w.Code, w.Body)
+		}
+		var res domain.NetworkImportResult
+		if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+	export := func(networkID string) string {
+		t.Helper()
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/networks/"+networkID+"/export?format=json", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("export: status %d: %s", w.Code, w.Body)
+		}
+		return w.Body.String()
+	}
+
+	res := import1(doc)
+	if res.Peers != 2 || res.Groups != 1 || res.Policies != 1 || res.Routes != 1 {
+		t.Fatalf("created %d peers, %d groups, %d policies, %d routes; want 2, 1, 1, 1", res.Peers, res.Groups, res.Policies, res.Routes)
+	}
+	var paths []string
+	for _, e := range res.Errors {
+		paths = append(paths, e.Path)
+	}
+	if got, want := strings.Join(paths, " "), "routes[1] groups[0].peers[1]"; got != want {
+		t.Fatalf("errors at %q, want %q: %+v", got, want, res.Errors)
+	}
+
+	peers, _ := h.service.ListPeers(ctx, res.Network.ID)
+	for _, p := range peers {
+		if p.PublicKey == "" || p.PublicKey == "not-carried-over" || p.PrivateKey == "" {
+			t.Errorf("peer %s was not given a fresh key pair", p.Name)
+		}
+	}
+	policies, _ := h.policyService.ListPolicies(ctx, res.Network.ID)
+	groups, _ := h.groupService.ListGroups(ctx, res.Network.ID)
+	if len(policies) != 1 || len(groups) != 1 || policies[0].Rules[0].Target != groups[0].ID || policies[0].Rules[0].Ports != "443" {
+		t.Fatalf("policy rules = %+v, want the staff group targeted on port 443", policies)
+	}
+
+	// Restoring the export on another server gives a network that exports
+	// the same
+	first := export(res.Network.ID)
+	h, r = newImportHandler(ctx), gin.New()
+	r.POST("/networks/import", h.ImportNetwork)
+	r.GET("/networks/:networkId/export", h.ExportNetwork)
+	again := import1(first)
+	if len(again.Errors) != 0 {
+		t.Fatalf("re-import errors: %+v", again.Errors)
+	}
+	if again.Network.ID == res.Network.ID {
+		t.Fatal("re-import reused the network ID")
+	}
+	if second := export(again.Network.ID); second != first {
+		t.Errorf("export of the re-imported network differs:\n%s\nwant:\n%s", second, first)
+	}
+}
+
+func TestImportNetwork_RejectsAnInvalidNetwork(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := newImportHandler(context.Background())
+	r := gin.New()
+	r.POST("/networks/import", h.ImportNetwork)
+
+	req := httptest.NewRequest(http.MethodPost, "/networks/import", strings.NewReader(`{"network": {"name": "-office", "cidr": "10.10.0.0/24"}, "peers": [{"name": "laptop"}]}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+}
@@ -1,13 +1,18 @@
 package api
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
 	"wirety/internal/adapters/api/middleware"
 	"wirety/internal/audit"
 	"wirety/internal/domain/auth"
 	domain "wirety/internal/domain/network"
+	"wirety/pkg/firewall"
 
 	"github.com/gin-gonic/gin"
 )
@@ -36,6 +41,31 @@ func redactPeerForUser(p *domain.Peer, user *auth.User) *domain.Peer {
 	return &cp
 }
 
+// peerResponse returns the body of a peer create or update response.  With
+// ?validate_endpoint=true and an endpoint set, the peer's fields are joined
+// by endpoint_check, the outcome of resolving the endpoint host; a host that
+// does not resolve is reported there as a warning and never fails the call.
+func (h *Handler) peerResponse(c *gin.Context, peer *domain.Peer) any {
+	if c.Query("validate_endpoint") != "true" || peer.Endpoint == "" {
+		return peer
+	}
+
+	raw, err := json.Marshal(peer)
+	if err != nil {
+		return peer
+	}
+	var body map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &body); err != nil {
+		return peer
+	}
+	check, err := json.Marshal(h.service.CheckEndpoint(c.Request.Context(), peer.Endpoint))
+	if err != nil {
+		return peer
+	}
+	body["endpoint_check"] = check
+	return body
+}
+
 // PaginatedPeers represents a paginated list of peers
 type PaginatedPeers struct {
 	Data     []*domain.Peer `json:"data"`
@@ -44,6 +74,14 @@ type PaginatedPeers struct {
 	PageSize int            `json:"page_size"`
 }
 
+// PaginatedPeerSearchResults is a page of cross-network peer search results.
+type PaginatedPeerSearchResults struct {
+	Data     []*domain.PeerSearchResult `json:"data"`
+	Total    int                        `json:"total"`
+	Page     int                        `json:"page"`
+	PageSize int                        `json:"page_size"`
+}
+
 // CreatePeer godoc
 //
 //	@Summary		Create a new peer
@@ -53,8 +91,10 @@ type PaginatedPeers struct {
 //	@Produce		json
 //	@Param			networkId	path		string						true	"Network ID"
 //	@Param			peer		body		domain.PeerCreateRequest	true	"Peer creation request"
+//	@Param			validate_endpoint	query		bool						false	"Resolve the endpoint host and report the result in endpoint_check"
 //	@Success		201			{object}	domain.Peer
 //	@Failure		400			{object}	map[string]string
+//	@Failure		409			{object}	map[string]string	"Requested address is already allocated"
 //	@Failure		500			{object}	map[string]string
 //	@Router			/networks/{networkId}/peers [post]
 //	@Security		BearerAuth
@@ -70,6 +110,10 @@ func (h *Handler) CreatePeer(c *gin.Context) {
 
 	var ownerID string
 	if user != nil && !user.IsAdministrator() {
+		if len(req.GroupIDs) > 0 {
+			c.JSON(http.StatusForbidden, gin.H{"error": "only administrators can assign groups"})
+			return
+		}
 		// Non-admins always own their own peers; they cannot set arbitrary owners.
 		ownerID = user.ID
 	} else {
@@ -79,8 +123,10 @@ func (h *Handler) CreatePeer(c *gin.Context) {
 
 	peer, err := h.service.AddPeer(c.Request.Context(), networkID, &req, ownerID)
 	if err != nil {
-		if isValidationError(err) {
+		if isValidationError(err) || errors.Is(err, domain.ErrGroupNotFound) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else if errors.Is(err, domain.ErrIPAllocated) || errors.Is(err, domain.ErrPublicKeyInUse) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
 		} else {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		}
@@ -97,7 +143,85 @@ func (h *Handler) CreatePeer(c *gin.Context) {
 		Str("peer_name", peer.Name).
 		Msg("audit")
 
-	c.JSON(http.StatusCreated, peer)
+	c.JSON(http.StatusCreated, h.peerResponse(c, peer))
+}
+
+// CreatePeersBulk godoc
+//
+//	@Summary		Create several peers at once
+//	@Description	Add a batch of peers to the network. Entries are created independently: failed entries are reported with their index in errors and leave no IP allocation behind, while the others are created. Returns 201 when every peer was created, 207 when only some were, 400 when none were.
+//	@Tags			peers
+//	@Accept			json
+//	@Produce		json
+//	@Param			networkId	path		string							true	"Network ID"
+//	@Param			peers		body		domain.PeerBulkCreateRequest	true	"Bulk peer creation request"
+//	@Success		201			{object}	network.AddPeersResult
+//	@Success		207			{object}	network.AddPeersResult
+//	@Failure		400			{object}	network.AddPeersResult
+//	@Failure		404			{object}	map[string]string
+//	@Failure		500			{object}	map[string]string
+//	@Router			/networks/{networkId}/peers/bulk [post]
+//	@Security		BearerAuth
+func (h *Handler) CreatePeersBulk(c *gin.Context) {
+	networkID := c.Param("networkId")
+	user := middleware.GetUserFromContext(c)
+
+	var req domain.PeerBulkCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	reqs := make([]*domain.PeerCreateRequest, len(req.Peers))
+	for i := range req.Peers {
+		// Same rules as CreatePeer: non-admins always own their peers and
+		// cannot assign groups.
+		if user != nil && !user.IsAdministrator() {
+			if len(req.Peers[i].GroupIDs) > 0 {
+				c.JSON(http.StatusForbidden, gin.H{"error": "only administrators can assign groups"})
+				return
+			}
+			req.Peers[i].OwnerID = user.ID
+		}
+		reqs[i] = &req.Peers[i]
+	}
+
+	result, err := h.service.AddPeers(c.Request.Context(), networkID, reqs)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrNetworkNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case isValidationError(err):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	if len(result.Peers) > 0 {
+		go h.wsManager.NotifyNetworkPeers(networkID)
+	}
+
+	id, email := actor(c)
+	for _, peer := range result.Peers {
+		audit.Server(id, email, c.ClientIP()).
+			Str("action", "peer.create").
+			Str("network_id", networkID).
+			Str("peer_id", peer.ID).
+			Str("peer_name", peer.Name).
+			Bool("bulk", true).
+			Msg("audit")
+	}
+
+	status := http.StatusCreated
+	switch {
+	case len(result.Peers) == 0:
+		status = http.StatusBadRequest
+	case len(result.Errors) > 0:
+		status = http.StatusMultiStatus
+	}
+	c.JSON(status, result)
 }
 
 // GetPeer godoc
@@ -125,7 +249,7 @@ func (h *Handler) GetPeer(c *gin.Context) {
 	}
 
 	// Jump peers are shared network infrastructure visible to all users on the network.
-	if user != nil && !user.IsAdministrator() && !peer.IsJump && peer.OwnerID != user.ID {
+	if user != nil && !user.CanViewAll() && !peer.IsJump && peer.OwnerID != user.ID {
 		c.JSON(http.StatusForbidden, gin.H{"error": "you can only view your own peers"})
 		return
 	}
@@ -143,6 +267,7 @@ func (h *Handler) GetPeer(c *gin.Context) {
 // @Param        page      query int    false "Page number" default(1)
 // @Param        page_size query int    false "Page size" default(20)
 // @Param        filter    query string false "Filter by peer name, IP address or ID"
+// @Param        label     query []string false "Only peers with this label, as key=value or key (repeatable)" collectionFormat(multi)
 // @Success      200 {object} PaginatedPeers
 // @Failure      500 {object} map[string]string
 // @Router       /networks/{networkId}/peers [get]
@@ -152,6 +277,7 @@ func (h *Handler) ListPeers(c *gin.Context) {
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
 	filter := c.Query("filter")
+	labels := c.QueryArray("label")
 	user := middleware.GetUserFromContext(c)
 
 	if page < 1 {
@@ -172,7 +298,10 @@ func (h *Handler) ListPeers(c *gin.Context) {
 		// Jump peers are shared network infrastructure — every user on the network
 		// needs to see them so the frontend can build the captive-portal URL.
 		// All other peers are restricted to their owner (or admins).
-		if user != nil && !user.IsAdministrator() && !p.IsJump && p.OwnerID != user.ID {
+		if user != nil && !user.CanViewAll() && !p.IsJump && p.OwnerID != user.ID {
+			continue
+		}
+		if !domain.MatchLabels(p.Labels, labels) {
 			continue
 		}
 		accessiblePeers = append(accessiblePeers, p)
@@ -209,6 +338,13 @@ func (h *Handler) ListPeers(c *gin.Context) {
 		redacted[i] = redactPeerForUser(p, user)
 	}
 
+	// Status is best-effort: a failure must not hide the peers themselves.
+	if statuses, err := h.service.PeerStatuses(c.Request.Context(), networkID); err == nil {
+		for _, p := range redacted {
+			p.Status = statuses[p.ID]
+		}
+	}
+
 	c.JSON(http.StatusOK, PaginatedPeers{
 		Data:     redacted,
 		Total:    total,
@@ -217,6 +353,54 @@ func (h *Handler) ListPeers(c *gin.Context) {
 	})
 }
 
+// SearchPeers godoc
+//
+// @Summary      Search peers across networks (paginated)
+// @Description  Find the peers of every network whose name, IPv4 or IPv6 address, public key or ID contains q, ignoring case. Results are ordered by network name, then peer name, and carry their network's ID and name (admin only).
+// @Tags         peers
+// @Produce      json
+// @Param        q         query string true  "Search text"
+// @Param        page      query int    false "Page number" default(1)
+// @Param        page_size query int    false "Page size" default(20)
+// @Success      200 {object} PaginatedPeerSearchResults
+// @Failure      400 {object} map[string]string
+// @Failure      403 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Router       /peers/search [get]
+// @Security     BearerAuth
+func (h *Handler) SearchPeers(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 500 {
+		pageSize = 20
+	}
+
+	results, total, err := h.service.SearchPeers(c.Request.Context(), c.Query("q"), (page-1)*pageSize, pageSize)
+	if err != nil {
+		if isValidationError(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	// Auditors pass requireAdmin but must not see enrollment tokens
+	user := middleware.GetUserFromContext(c)
+	for _, res := range results {
+		res.Peer = redactPeerForUser(res.Peer, user)
+	}
+
+	c.JSON(http.StatusOK, PaginatedPeerSearchResults{
+		Data:     results,
+		Total:    total,
+		Page:     page,
+		PageSize: pageSize,
+	})
+}
+
 // UpdatePeer godoc
 //
 //	@Summary		Update a peer
@@ -227,6 +411,7 @@ func (h *Handler) ListPeers(c *gin.Context) {
 //	@Param			networkId	path		string						true	"Network ID"
 //	@Param			peerId		path		string						true	"Peer ID"
 //	@Param			peer		body		domain.PeerUpdateRequest	true	"Peer update request"
+//	@Param			validate_endpoint	query		bool						false	"Resolve the endpoint host and report the result in endpoint_check"
 //	@Success		200			{object}	domain.Peer
 //	@Failure		400			{object}	map[string]string
 //	@Failure		404			{object}	map[string]string
@@ -259,6 +444,11 @@ func (h *Handler) UpdatePeer(c *gin.Context) {
 		return
 	}
 
+	if (req.ExpiresAt != nil || req.ClearExpiry) && user != nil && !user.IsAdministrator() {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only administrators can change peer expiry"})
+		return
+	}
+
 	peer, err = h.service.UpdatePeer(c.Request.Context(), networkID, peerID, &req)
 	if err != nil {
 		if isValidationError(err) {
@@ -269,7 +459,19 @@ func (h *Handler) UpdatePeer(c *gin.Context) {
 		return
 	}
 
-	go h.wsManager.NotifyNetworkPeers(networkID)
+	switch {
+	case req.LabelsOnly():
+		// Labels appear in no config; nothing to push.
+	case req.RenameOnly():
+		// The renamed peer picks up its new interface name; elsewhere only
+		// the jumps' DNS records change.
+		go func() {
+			h.wsManager.NotifyPeerUpdate(networkID, peerID)
+			h.wsManager.NotifyNetworkDNS(networkID)
+		}()
+	default:
+		go h.wsManager.NotifyNetworkPeers(networkID)
+	}
 
 	id, email := actor(c)
 	audit.Server(id, email, c.ClientIP()).
@@ -279,7 +481,7 @@ func (h *Handler) UpdatePeer(c *gin.Context) {
 		Str("peer_name", peer.Name).
 		Msg("audit")
 
-	c.JSON(http.StatusOK, peer)
+	c.JSON(http.StatusOK, h.peerResponse(c, peer))
 }
 
 // DeletePeer godoc
@@ -370,15 +572,207 @@ func (h *Handler) RevokePeerAuthentication(c *gin.Context) {
 	c.Status(http.StatusNoContent)
 }
 
+// RotatePeerToken godoc
+//
+//	@Summary		Regenerate a peer's enrollment token
+//	@Description	Replaces the peer's agent enrollment token with a fresh one and returns it. The old token stops working at once: /agent/resolve answers 404 for it and the agent connected with it is disconnected.
+//	@Tags			peers
+//	@Produce		json
+//	@Param			networkId	path		string	true	"Network ID"
+//	@Param			peerId		path		string	true	"Peer ID"
+//	@Success		200			{object}	map[string]string
+//	@Failure		403			{object}	map[string]string
+//	@Failure		404			{object}	map[string]string
+//	@Router			/networks/{networkId}/peers/{peerId}/rotate-token [post]
+//	@Security		BearerAuth
+func (h *Handler) RotatePeerToken(c *gin.Context) {
+	networkID := c.Param("networkId")
+	peerID := c.Param("peerId")
+	user := middleware.GetUserFromContext(c)
+
+	peer, err := h.service.GetPeer(c.Request.Context(), networkID, peerID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "peer not found"})
+		return
+	}
+	if user != nil && !user.CanManagePeer(networkID, peer.OwnerID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "you can only manage your own peers"})
+		return
+	}
+
+	token, err := h.service.RegeneratePeerToken(c.Request.Context(), networkID, peerID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	// The connected agent authenticated with the old token.
+	h.wsManager.Disconnect(networkID, peerID)
+
+	id, email := actor(c)
+	audit.Server(id, email, c.ClientIP()).
+		Str("action", "peer.rotate_token").
+		Str("network_id", networkID).
+		Str("peer_id", peerID).
+		Msg("audit")
+
+	c.JSON(http.StatusOK, gin.H{"token": token})
+}
+
+// SyncPeer godoc
+//
+//	@Summary		Push a fresh config to a peer's agent
+//	@Description	Renders the peer's config again and sends it to its agent over WebSocket, without notifying the other peers of the network. Useful after manual changes or to recover a stuck agent. The response tells whether the peer had a live agent connection; nothing is sent otherwise.
+//	@Tags			peers
+//	@Produce		json
+//	@Param			networkId	path		string	true	"Network ID"
+//	@Param			peerId		path		string	true	"Peer ID"
+//	@Success		200			{object}	map[string]bool
+//	@Failure		403			{object}	map[string]string
+//	@Failure		404			{object}	map[string]string
+//	@Router			/networks/{networkId}/peers/{peerId}/sync [post]
+//	@Security		BearerAuth
+func (h *Handler) SyncPeer(c *gin.Context) {
+	networkID := c.Param("networkId")
+	peerID := c.Param("peerId")
+	user := middleware.GetUserFromContext(c)
+
+	peer, err := h.service.GetPeer(c.Request.Context(), networkID, peerID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "peer not found"})
+		return
+	}
+	if user != nil && !user.CanManagePeer(networkID, peer.OwnerID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "you can only manage your own peers"})
+		return
+	}
+
+	connected := h.wsManager.IsConnected(networkID, peerID)
+	if connected {
+		h.wsManager.NotifyPeerUpdate(networkID, peerID)
+	}
+
+	id, email := actor(c)
+	audit.Server(id, email, c.ClientIP()).
+		Str("action", "peer.sync").
+		Str("network_id", networkID).
+		Str("peer_id", peerID).
+		Bool("connected", connected).
+		Msg("audit")
+
+	c.JSON(http.StatusOK, gin.H{"connected": connected})
+}
+
+// QuarantinePeerRequest is the optional body of QuarantinePeer.
+type QuarantinePeerRequest struct {
+	// Duration is a Go duration such as "30m" or "72h" (default: 1h, the
+	// automatic quarantine duration).
+	Duration string `json:"duration,omitempty"`
+}
+
+// QuarantinePeer godoc
+//
+//	@Summary		Quarantine a peer
+//	@Description	Quarantines a peer on demand (admin only). The jump peers drop its traffic until the quarantine ends or is lifted, exactly as after repeated captive-portal failures.
+//	@Tags			peers
+//	@Accept			json
+//	@Produce		json
+//	@Param			networkId	path	string					true	"Network ID"
+//	@Param			peerId		path	string					true	"Peer ID"
+//	@Param			request		body	QuarantinePeerRequest	false	"Quarantine duration"
+//	@Success		200	{object}	domain.CaptivePortalQuarantine
+//	@Failure		400	{object}	map[string]string
+//	@Failure		403	{object}	map[string]string
+//	@Failure		404	{object}	map[string]string
+//	@Router			/networks/{networkId}/peers/{peerId}/quarantine [post]
+//	@Security		BearerAuth
+func (h *Handler) QuarantinePeer(c *gin.Context) {
+	networkID := c.Param("networkId")
+	peerID := c.Param("peerId")
+
+	var req QuarantinePeerRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	d := domain.QuarantineDuration
+	if req.Duration != "" {
+		var err error
+		if d, err = time.ParseDuration(req.Duration); err != nil || d <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "duration must be a positive duration such as 30m or 72h"})
+			return
+		}
+	}
+
+	q, err := h.service.QuarantinePeer(c.Request.Context(), networkID, peerID, d)
+	if err != nil {
+		if errors.Is(err, domain.ErrPeerNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	id, email := actor(c)
+	audit.Server(id, email, c.ClientIP()).
+		Str("action", "peer.quarantine").
+		Str("network_id", networkID).
+		Str("peer_id", peerID).
+		Time("until", *q.QuarantinedUntil).
+		Msg("audit")
+
+	c.JSON(http.StatusOK, q)
+}
+
+// UnquarantinePeer godoc
+//
+//	@Summary		Lift a peer's quarantine
+//	@Description	Lifts a manual or automatic quarantine and clears the peer's captive-portal strikes (admin only).
+//	@Tags			peers
+//	@Param			networkId	path	string	true	"Network ID"
+//	@Param			peerId		path	string	true	"Peer ID"
+//	@Success		204
+//	@Failure		403	{object}	map[string]string
+//	@Failure		404	{object}	map[string]string
+//	@Router			/networks/{networkId}/peers/{peerId}/unquarantine [post]
+//	@Security		BearerAuth
+func (h *Handler) UnquarantinePeer(c *gin.Context) {
+	networkID := c.Param("networkId")
+	peerID := c.Param("peerId")
+
+	if err := h.service.UnquarantinePeer(c.Request.Context(), networkID, peerID); err != nil {
+		if errors.Is(err, domain.ErrPeerNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	id, email := actor(c)
+	audit.Server(id, email, c.ClientIP()).
+		Str("action", "peer.unquarantine").
+		Str("network_id", networkID).
+		Str("peer_id", peerID).
+		Msg("audit")
+
+	c.Status(http.StatusNoContent)
+}
+
 // GetPeerConfig godoc
 //
 // @Summary      Get peer configuration
-// @Description  Get WireGuard configuration for a specific peer returned as JSON object
+// @Description  Get WireGuard configuration for a specific peer returned as JSON object, or as a downloadable .conf file with format=conf
 // @Tags         peers
 // @Produce      json
-// @Param        networkId path string true "Network ID"
-// @Param        peerId    path string true "Peer ID"
+// @Produce      plain
+// @Param        networkId path  string true  "Network ID"
+// @Param        peerId    path  string true  "Peer ID"
+// @Param        format    query string false "Response format" Enums(json, conf) default(json)
 // @Success      200 {object} map[string]string "JSON object containing config key"
+// @Failure      400 {object} map[string]string
 // @Failure      404 {object} map[string]string
 // @Router       /networks/{networkId}/peers/{peerId}/config [get]
 // @Security     BearerAuth
@@ -387,6 +781,12 @@ func (h *Handler) GetPeerConfig(c *gin.Context) {
 	peerID := c.Param("peerId")
 	user := middleware.GetUserFromContext(c)
 
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "conf" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be json or conf"})
+		return
+	}
+
 	peer, err := h.service.GetPeer(c.Request.Context(), networkID, peerID)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "peer not found"})
@@ -404,5 +804,282 @@ func (h *Handler) GetPeerConfig(c *gin.Context) {
 		return
 	}
 
+	if format == "conf" {
+		// Same naming as the configs.zip export: the peer name reduced to a
+		// DNS label, so it cannot break out of the header or the directory
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, configFileName(peer, map[string]bool{})))
+		c.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(config))
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{"config": config})
 }
+
+// GrantTempRoute godoc
+//
+//	@Summary		Grant a temporary route to a peer
+//	@Description	Give a peer break-glass access to an existing route or to a CIDR through a jump peer for a limited time (admin only). The route is removed automatically once it expires.
+//	@Tags			peers
+//	@Accept			json
+//	@Produce		json
+//	@Param			networkId	path		string							true	"Network ID"
+//	@Param			peerId		path		string							true	"Peer ID"
+//	@Param			request		body		network.TempRouteCreateRequest	true	"Temporary route"
+//	@Success		201			{object}	network.TempRoute
+//	@Failure		400			{object}	map[string]string
+//	@Failure		403			{object}	map[string]string
+//	@Failure		404			{object}	map[string]string
+//	@Failure		500			{object}	map[string]string
+//	@Router			/networks/{networkId}/peers/{peerId}/temp-route [post]
+//	@Security		BearerAuth
+func (h *Handler) GrantTempRoute(c *gin.Context) {
+	networkID := c.Param("networkId")
+	peerID := c.Param("peerId")
+
+	var req domain.TempRouteCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := req.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	id, email := actor(c)
+	temp, err := h.service.GrantTempRoute(c.Request.Context(), networkID, peerID, &req, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrPeerNotFound), errors.Is(err, domain.ErrRouteNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, domain.ErrJumpPeerNotFound), errors.Is(err, domain.ErrNotJumpPeer):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	audit.Server(id, email, c.ClientIP()).
+		Str("action", "peer.temp_route.grant").
+		Str("network_id", networkID).
+		Str("peer_id", peerID).
+		Str("temp_route_id", temp.ID).
+		Str("route_id", temp.RouteID).
+		Str("destination_cidr", temp.DestinationCIDR).
+		Str("destination_cidr_v6", temp.DestinationCIDRv6).
+		Str("jump_peer_id", temp.JumpPeerID).
+		Time("expires_at", temp.ExpiresAt).
+		Str("reason", temp.Reason).
+		Msg("audit")
+
+	c.JSON(http.StatusCreated, temp)
+}
+
+// ListTempRoutes godoc
+//
+//	@Summary		List a peer's temporary routes
+//	@Description	List the unexpired temporary routes granted to a peer (admin only)
+//	@Tags			peers
+//	@Produce		json
+//	@Param			networkId	path		string	true	"Network ID"
+//	@Param			peerId		path		string	true	"Peer ID"
+//	@Success		200			{array}		network.TempRoute
+//	@Failure		403			{object}	map[string]string
+//	@Failure		404			{object}	map[string]string
+//	@Router			/networks/{networkId}/peers/{peerId}/temp-route [get]
+//	@Security		BearerAuth
+func (h *Handler) ListTempRoutes(c *gin.Context) {
+	networkID := c.Param("networkId")
+	peerID := c.Param("peerId")
+
+	temps, err := h.service.ListTempRoutes(c.Request.Context(), networkID, peerID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, temps)
+}
+
+// JumpFirewallPreview is the firewall ruleset a jump agent receives
+type JumpFirewallPreview struct {
+	PeerID          string          `json:"peer_id"`
+	FirewallBackend string          `json:"firewall_backend,omitempty"`
+	IPTablesRules   []string        `json:"iptables_rules"`
+	NftRules        []string        `json:"nft_rules,omitempty"`
+	Rules           []firewall.Rule `json:"rules,omitempty"`
+}
+
+// GetJumpPeerIPTables godoc
+//
+//	@Summary		Preview a jump peer's firewall rules
+//	@Description	Returns the iptables rules generated from the policies attached to groups, default deny rules included, exactly as the jump agent receives them. nft_rules is set when the agent last reported the nftables backend (admin only)
+//	@Tags			peers
+//	@Produce		json
+//	@Param			networkId	path		string	true	"Network ID"
+//	@Param			peerId		path		string	true	"Peer ID"
+//	@Success		200			{object}	JumpFirewallPreview
+//	@Failure		400			{object}	map[string]string
+//	@Failure		403			{object}	map[string]string
+//	@Failure		404			{object}	map[string]string
+//	@Failure		500			{object}	map[string]string
+//	@Router			/networks/{networkId}/peers/{peerId}/iptables [get]
+//	@Security		BearerAuth
+func (h *Handler) GetJumpPeerIPTables(c *gin.Context) {
+	peerID := c.Param("peerId")
+
+	policy, err := h.service.PreviewJumpFirewall(c.Request.Context(), c.Param("networkId"), peerID)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrPeerNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, domain.ErrNotJumpPeer):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, JumpFirewallPreview{
+		PeerID:          peerID,
+		FirewallBackend: policy.FirewallBackend,
+		IPTablesRules:   policy.IPTablesRules,
+		NftRules:        policy.NftRules,
+		Rules:           policy.Rules,
+	})
+}
+
+// ConnectionAllowedIPs is the AllowedIPs override of peer_id's [Peer]
+// section in the config of the peer named in the path
+type ConnectionAllowedIPs struct {
+	PeerID     string   `json:"peer_id"`
+	AllowedIPs []string `json:"allowed_ips"`
+}
+
+// SetConnectionAllowedIPs godoc
+//
+//	@Summary		Override the AllowedIPs of a connection
+//	@Description	Replaces the AllowedIPs of the other peer's [Peer] section in the peer's config; the reverse section keeps its defaults. An empty allowed_ips removes the override (admin only)
+//	@Tags			peers
+//	@Accept			json
+//	@Produce		json
+//	@Param			networkId	path		string					true	"Network ID"
+//	@Param			peerId		path		string					true	"Peer ID"
+//	@Param			otherPeerId	path		string					true	"Peer ID of the [Peer] section"
+//	@Param			request		body		ConnectionAllowedIPs	true	"AllowedIPs (peer_id is ignored)"
+//	@Success		200			{object}	ConnectionAllowedIPs
+//	@Failure		400			{object}	map[string]string
+//	@Failure		403			{object}	map[string]string
+//	@Failure		404			{object}	map[string]string
+//	@Failure		500			{object}	map[string]string
+//	@Router			/networks/{networkId}/peers/{peerId}/connections/{otherPeerId} [put]
+//	@Security		BearerAuth
+func (h *Handler) SetConnectionAllowedIPs(c *gin.Context) {
+	networkID := c.Param("networkId")
+	peerID := c.Param("peerId")
+	otherPeerID := c.Param("otherPeerId")
+
+	var req ConnectionAllowedIPs
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	allowedIPs, err := h.service.SetConnectionAllowedIPs(c.Request.Context(), networkID, peerID, otherPeerID, req.AllowedIPs)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrNetworkNotFound), errors.Is(err, domain.ErrPeerNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case isValidationError(err):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, ConnectionAllowedIPs{PeerID: otherPeerID, AllowedIPs: allowedIPs})
+}
+
+// ListMeshConnections godoc
+//
+//	@Summary		List the peer connections of a network
+//	@Description	Lists the preshared-key connections between the peers of the network, without the keys (admin only)
+//	@Tags			peers
+//	@Produce		json
+//	@Param			networkId	path		string	true	"Network ID"
+//	@Success		200			{array}		network.MeshConnection
+//	@Failure		403			{object}	map[string]string
+//	@Failure		404			{object}	map[string]string
+//	@Failure		500			{object}	map[string]string
+//	@Router			/networks/{networkId}/connections/mesh [get]
+//	@Security		BearerAuth
+func (h *Handler) ListMeshConnections(c *gin.Context) {
+	conns, err := h.service.ListMeshConnections(c.Request.Context(), c.Param("networkId"))
+	if err != nil {
+		if errors.Is(err, domain.ErrNetworkNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, conns)
+}
+
+// SeverConnection godoc
+//
+//	@Summary		Sever a peer connection
+//	@Description	Leaves the two peers out of each other's configs, whatever the topology says (admin only)
+//	@Tags			peers
+//	@Param			networkId	path		string	true	"Network ID"
+//	@Param			peerId		path		string	true	"Peer ID"
+//	@Param			otherPeerId	path		string	true	"Peer ID of the other end"
+//	@Success		204
+//	@Failure		403	{object}	map[string]string
+//	@Failure		404	{object}	map[string]string
+//	@Failure		500	{object}	map[string]string
+//	@Router			/networks/{networkId}/connections/mesh/{peerId}/{otherPeerId} [delete]
+//	@Security		BearerAuth
+func (h *Handler) SeverConnection(c *gin.Context) {
+	err := h.service.SeverConnection(c.Request.Context(), c.Param("networkId"), c.Param("peerId"), c.Param("otherPeerId"))
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrNetworkNotFound), errors.Is(err, domain.ErrConnectionNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// GetPeerDNSQueries godoc
+//
+//	@Summary		List a jump peer's DNS queries
+//	@Description	Returns the latest queries received by the jump peer's DNS server, oldest first. Queries are only recorded while the network has dns_query_log set (admin only)
+//	@Tags			peers
+//	@Produce		json
+//	@Param			networkId	path		string	true	"Network ID"
+//	@Param			peerId		path		string	true	"Peer ID"
+//	@Success		200			{object}	network.DNSQueryLog
+//	@Failure		400			{object}	map[string]string
+//	@Failure		403			{object}	map[string]string
+//	@Failure		404			{object}	map[string]string
+//	@Router			/networks/{networkId}/peers/{peerId}/dns/queries [get]
+//	@Security		BearerAuth
+func (h *Handler) GetPeerDNSQueries(c *gin.Context) {
+	queries, err := h.service.GetPeerDNSQueries(c.Request.Context(), c.Param("networkId"), c.Param("peerId"))
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrNetworkNotFound), errors.Is(err, domain.ErrPeerNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, domain.ErrNotJumpPeer):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, queries)
+}
@@ -0,0 +1,313 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"wirety/internal/adapters/api/middleware"
+	"wirety/internal/adapters/db/memory"
+	"wirety/internal/application/network"
+	"wirety/internal/domain/auth"
+	domain "wirety/internal/domain/network"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+func TestGetPeerConfig_Formats(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+
+	repo := memory.NewRepository()
+	if err := repo.CreateNetwork(ctx, &domain.Network{ID: "net1", Name: "office", CIDR: "10.0.0.0/24", Peers: map[string]*domain.Peer{}}); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []*domain.Peer{
+		{ID: "p1", Name: "jump", Address: "10.0.0.1", IsJump: true, Endpoint: "vpn.example.com", ListenPort: 51820},
+		{ID: "p2", Name: `Alice's "Laptop"`, Address: "10.0.0.2", OwnerID: "alice"},
+	} {
+		if err := repo.CreatePeer(ctx, "net1", p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	h := &Handler{service: network.NewService(repo, memory.NewIPAMRepository(ctx), nil, nil, nil, nil, nil)}
+
+	var current *auth.User
+	r := gin.New()
+	r.GET("/networks/:networkId/peers/:peerId/config", func(c *gin.Context) {
+		c.Set(middleware.UserContextKey, current)
+		c.Next()
+	}, h.GetPeerConfig)
+	get := func(user *auth.User, query string) *httptest.ResponseRecorder {
+		current = user
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/networks/net1/peers/p2/config"+query, nil))
+		return w
+	}
+	alice := &auth.User{ID: "alice", Role: auth.RoleUser, AuthorizedNetworks: []string{"net1"}}
+
+	// JSON stays the default
+	w := get(alice, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("json: status %d: %s", w.Code, w.Body)
+	}
+	var body map[string]string
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || !strings.Contains(body["config"], "[Interface]") {
+		t.Fatalf("json: unexpected body %s (%v)", w.Body, err)
+	}
+
+	w = get(alice, "?format=conf")
+	if w.Code != http.StatusOK {
+		t.Fatalf("conf: status %d: %s", w.Code, w.Body)
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Errorf("Content-Type = %q, want text/plain", ct)
+	}
+	if cd := w.Header().Get("Content-Disposition"); cd != `attachment; filename="alice-s-laptop.conf"` {
+		t.Errorf("Content-Disposition = %q", cd)
+	}
+	if !strings.HasPrefix(w.Body.String(), "[Interface]") {
+		t.Errorf("conf: body is not a raw config:\n%s", w.Body)
+	}
+
+	if w := get(alice, "?format=yaml"); w.Code != http.StatusBadRequest {
+		t.Errorf("unknown format: status %d, want 400", w.Code)
+	}
+	bob := &auth.User{ID: "bob", Role: auth.RoleUser, AuthorizedNetworks: []string{"net1"}}
+	if w := get(bob, "?format=conf"); w.Code != http.StatusForbidden {
+		t.Errorf("other user's peer: status %d, want 403", w.Code)
+	}
+}
+
+func TestQuarantinePeer_ManualQuarantineAndLift(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+
+	repo := memory.NewRepository()
+	if err := repo.CreateNetwork(ctx, &domain.Network{ID: "net1", Name: "office", CIDR: "10.0.0.0/24", Peers: map[string]*domain.Peer{}}); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []*domain.Peer{
+		{ID: "jump", Name: "jump", Address: "10.0.0.1", IsJump: true},
+		{ID: "laptop", Name: "laptop", Address: "10.0.0.2"},
+	} {
+		if err := repo.CreatePeer(ctx, "net1", p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	svc := network.NewService(repo, nil, nil, nil, nil, nil, nil)
+	h := &Handler{service: svc}
+
+	r := gin.New()
+	r.POST("/networks/:networkId/peers/:peerId/quarantine", h.QuarantinePeer)
+	r.POST("/networks/:networkId/peers/:peerId/unquarantine", h.UnquarantinePeer)
+	post := func(path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	quarantined := func() []string {
+		t.Helper()
+		state, err := svc.GetCaptivePortalSecurityState(ctx, "net1", "jump")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return state.Quarantined
+	}
+
+	if w := post("/networks/net1/peers/laptop/quarantine", `{"duration":"soon"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid duration: status %d, want 400", w.Code)
+	}
+	if w := post("/networks/net1/peers/ghost/quarantine", ""); w.Code != http.StatusNotFound {
+		t.Errorf("unknown peer: status %d, want 404", w.Code)
+	}
+
+	w := post("/networks/net1/peers/laptop/quarantine", `{"duration":"72h"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("quarantine: status %d: %s", w.Code, w.Body)
+	}
+	var q domain.CaptivePortalQuarantine
+	if err := json.Unmarshal(w.Body.Bytes(), &q); err != nil {
+		t.Fatal(err)
+	}
+	if q.QuarantinedUntil == nil || time.Until(*q.QuarantinedUntil) < 71*time.Hour {
+		t.Errorf("quarantined_until = %v, want about 72h from now", q.QuarantinedUntil)
+	}
+	if got := quarantined(); len(got) != 1 || got[0] != "10.0.0.2" {
+		t.Fatalf("quarantined = %v, want the laptop", got)
+	}
+
+	if w := post("/networks/net1/peers/laptop/unquarantine", ""); w.Code != http.StatusNoContent {
+		t.Fatalf("unquarantine: status %d: %s", w.Code, w.Body)
+	}
+	if got := quarantined(); len(got) != 0 {
+		t.Errorf("quarantined after lift = %v", got)
+	}
+	if _, err := svc.GetPeer(ctx, "net1", "laptop"); err != nil {
+		t.Errorf("peer gone after lift: %v", err)
+	}
+}
+
+func TestRotatePeerToken_OldTokenStopsResolving(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+
+	repo := memory.NewRepository()
+	if err := repo.CreateNetwork(ctx, &domain.Network{ID: "net1", Name: "office", CIDR: "10.0.0.0/24", Peers: map[string]*domain.Peer{}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.CreatePeer(ctx, "net1", &domain.Peer{ID: "laptop", Name: "laptop", Address: "10.0.0.2", OwnerID: "alice", Token: "old-token"}); err != nil {
+		t.Fatal(err)
+	}
+	svc := network.NewService(repo, memory.NewIPAMRepository(ctx), nil, nil, nil, nil, nil)
+	h := &Handler{service: svc, wsManager: NewWebSocketManager(svc, nil)}
+
+	var current *auth.User
+	r := gin.New()
+	r.POST("/networks/:networkId/peers/:peerId/rotate-token", func(c *gin.Context) {
+		c.Set(middleware.UserContextKey, current)
+		c.Next()
+	}, h.RotatePeerToken)
+	r.GET("/agent/resolve", h.ResolveAgent)
+	rotate := func(user *auth.User, peerID string) *httptest.ResponseRecorder {
+		current = user
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/networks/net1/peers/"+peerID+"/rotate-token", nil))
+		return w
+	}
+	resolve := func(token string) int {
+		req := httptest.NewRequest(http.MethodGet, "/agent/resolve", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	bob := &auth.User{ID: "bob", Role: auth.RoleUser, AuthorizedNetworks: []string{"net1"}}
+	if w := rotate(bob, "laptop"); w.Code != http.StatusForbidden {
+		t.Errorf("other user: status %d, want 403", w.Code)
+	}
+	if w := rotate(nil, "ghost"); w.Code != http.StatusNotFound {
+		t.Errorf("unknown peer: status %d, want 404", w.Code)
+	}
+
+	alice := &auth.User{ID: "alice", Role: auth.RoleUser, AuthorizedNetworks: []string{"net1"}}
+	w := rotate(alice, "laptop")
+	if w.Code != http.StatusOK {
+		t.Fatalf("rotate: status %d: %s", w.Code, w.Body)
+	}
+	var body map[string]string
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || body["token"] == "" || body["token"] == "old-token" {
+		t.Fatalf("rotate: unexpected body %s (%v)", w.Body, err)
+	}
+
+	if code := resolve("old-token"); code != http.StatusNotFound {
+		t.Errorf("old token: status %d, want 404", code)
+	}
+	if code := resolve(body["token"]); code != http.StatusOK {
+		t.Errorf("new token: status %d, want 200", code)
+	}
+}
+
+func TestSyncPeer_PushesToOnePeer(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+
+	repo := memory.NewRepository()
+	if err := repo.CreateNetwork(ctx, &domain.Network{ID: "net1", Name: "office", CIDR: "10.0.0.0/24", Peers: map[string]*domain.Peer{}}); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []*domain.Peer{
+		{ID: "jump", Name: "jump", Address: "10.0.0.1", IsJump: true, Endpoint: "vpn.example.com", ListenPort: 51820},
+		{ID: "laptop", Name: "laptop", Address: "10.0.0.2", OwnerID: "alice"},
+		{ID: "desktop", Name: "desktop", Address: "10.0.0.3", OwnerID: "alice"},
+	} {
+		if err := repo.CreatePeer(ctx, "net1", p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	svc := network.NewService(repo, memory.NewIPAMRepository(ctx), nil, nil, nil, nil, nil)
+	m := NewWebSocketManager(svc, nil)
+	h := &Handler{service: svc, wsManager: m}
+
+	agentServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		m.Register("net1", "laptop", conn, "203.0.113.7")
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				break
+			}
+		}
+		m.Unregister("net1", "laptop", conn)
+	}))
+	defer agentServer.Close()
+	agent, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(agentServer.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = agent.Close() }()
+	deadline := time.Now().Add(time.Second)
+	for !m.IsConnected("net1", "laptop") && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	var current *auth.User
+	r := gin.New()
+	r.POST("/networks/:networkId/peers/:peerId/sync", func(c *gin.Context) {
+		c.Set(middleware.UserContextKey, current)
+		c.Next()
+	}, h.SyncPeer)
+	sync := func(user *auth.User, peerID string) *httptest.ResponseRecorder {
+		current = user
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/networks/net1/peers/"+peerID+"/sync", nil))
+		return w
+	}
+	connected := func(w *httptest.ResponseRecorder) bool {
+		var body map[string]bool
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatalf("unexpected body %s (%v)", w.Body, err)
+		}
+		return body["connected"]
+	}
+
+	bob := &auth.User{ID: "bob", Role: auth.RoleUser, AuthorizedNetworks: []string{"net1"}}
+	if w := sync(bob, "laptop"); w.Code != http.StatusForbidden {
+		t.Errorf("other user: status %d, want 403", w.Code)
+	}
+	if w := sync(nil, "ghost"); w.Code != http.StatusNotFound {
+		t.Errorf("unknown peer: status %d, want 404", w.Code)
+	}
+
+	alice := &auth.User{ID: "alice", Role: auth.RoleUser, AuthorizedNetworks: []string{"net1"}}
+	if w := sync(alice, "desktop"); w.Code != http.StatusOK || connected(w) {
+		t.Errorf("offline peer: status %d, body %s, want 200 not connected", w.Code, w.Body)
+	}
+	w := sync(alice, "laptop")
+	if w.Code != http.StatusOK || !connected(w) {
+		t.Fatalf("connected peer: status %d, body %s, want 200 connected", w.Code, w.Body)
+	}
+
+	_ = agent.SetReadDeadline(time.Now().Add(time.Second))
+	_, msg, err := agent.ReadMessage()
+	if err != nil {
+		t.Fatalf("agent received no config: %v", err)
+	}
+	var push struct {
+		Config string `json:"config"`
+		PeerID string `json:"peer_id"`
+	}
+	if err := json.Unmarshal(msg, &push); err != nil || push.PeerID != "laptop" || !strings.Contains(push.Config, "[Interface]") {
+		t.Errorf("unexpected push %s (%v)", msg, err)
+	}
+}
@@ -0,0 +1,110 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"wirety/internal/adapters/api/middleware"
+	"wirety/internal/adapters/db/memory"
+	"wirety/internal/application/network"
+	"wirety/internal/domain/auth"
+	domain "wirety/internal/domain/network"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestSearchPeers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+
+	repo := memory.NewRepository()
+	for _, n := range []*domain.Network{
+		{ID: "net-b", Name: "office", CIDR: "10.1.0.0/24"},
+		{ID: "net-a", Name: "lab", CIDR: "10.0.0.0/24"},
+	} {
+		if err := repo.CreateNetwork(ctx, n); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for netID, peers := range map[string][]*domain.Peer{
+		"net-a": {
+			{ID: "p1", Name: "Laptop-Alice", Address: "10.0.0.2", Token: "secret"},
+			{ID: "p2", Name: "printer", Address: "10.0.0.3", PublicKey: "LAPTOPkey"},
+		},
+		"net-b": {
+			{ID: "p3", Name: "alice-laptop", Address: "10.1.0.2"},
+			{ID: "p4", Name: "server", Address: "10.1.0.4"},
+		},
+	} {
+		for _, p := range peers {
+			if err := repo.CreatePeer(ctx, netID, p); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	h := &Handler{service: network.NewService(repo, nil, nil, nil, nil, nil, nil)}
+	search := func(user *auth.User, query string) *httptest.ResponseRecorder {
+		r := gin.New()
+		r.GET("/peers/search", func(c *gin.Context) {
+			c.Set(middleware.UserContextKey, user)
+			c.Next()
+		}, middleware.RequireAdmin(), h.SearchPeers)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/peers/search?"+query, nil))
+		return w
+	}
+	admin := &auth.User{ID: "admin", Role: auth.RoleAdministrator}
+
+	w := search(admin, "q=laptop")
+	if w.Code != http.StatusOK {
+		t.Fatalf("search: status %d: %s", w.Code, w.Body)
+	}
+	var page PaginatedPeerSearchResults
+	if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 3 || len(page.Data) != 3 {
+		t.Fatalf("got %d of %d results, want 3", len(page.Data), page.Total)
+	}
+	// Ordered by network name, then peer name; the public key matches too
+	want := []struct{ network, peer string }{{"lab", "p1"}, {"lab", "p2"}, {"office", "p3"}}
+	for i, res := range page.Data {
+		if res.NetworkName != want[i].network || res.Peer.ID != want[i].peer {
+			t.Errorf("result %d = %s/%s, want %s/%s", i, res.NetworkName, res.Peer.ID, want[i].network, want[i].peer)
+		}
+	}
+	if page.Data[0].NetworkID != "net-a" || page.Data[0].Peer.Token != "secret" {
+		t.Errorf("first result = %+v, want net-a with its token", page.Data[0])
+	}
+
+	w = search(admin, "q=10.1.0.&page=2&page_size=1")
+	page = PaginatedPeerSearchResults{}
+	if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 2 || len(page.Data) != 1 || page.Data[0].Peer.ID != "p4" {
+		t.Errorf("second page = %+v, want p4 of 2", page)
+	}
+
+	w = search(&auth.User{ID: "auditor", Role: auth.RoleAuditor}, "q=alice")
+	page = PaginatedPeerSearchResults{}
+	if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil {
+		t.Fatal(err)
+	}
+	for _, res := range page.Data {
+		if res.Peer.Token != "" {
+			t.Errorf("auditor sees the token of %s", res.Peer.ID)
+		}
+	}
+
+	if w := search(admin, "q=%20"); w.Code != http.StatusBadRequest {
+		t.Errorf("blank query: status %d, want 400", w.Code)
+	}
+	if w := search(&auth.User{ID: "alice", Role: auth.RoleUser}, "q=laptop"); w.Code != http.StatusForbidden {
+		t.Errorf("non-admin: status %d, want 403", w.Code)
+	}
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"errors"
 	"net/http"
 
 	"wirety/internal/audit"
@@ -250,6 +251,46 @@ func (h *Handler) RemoveRuleFromPolicy(c *gin.Context) {
 	c.Status(http.StatusNoContent)
 }
 
+// EvaluatePolicy godoc
+//
+//	@Summary		Evaluate policies against a packet
+//	@Description	Tell whether the jump peers would forward a hypothetical packet sent by a peer, and which rule decides, without deploying anything (admin only)
+//	@Tags			policies
+//	@Accept			json
+//	@Produce		json
+//	@Param			networkId	path		string							true	"Network ID"
+//	@Param			packet		body		network.PolicyEvaluateRequest	true	"Packet to evaluate"
+//	@Success		200			{object}	network.PolicyEvaluation
+//	@Failure		400			{object}	map[string]string
+//	@Failure		403			{object}	map[string]string
+//	@Failure		404			{object}	map[string]string
+//	@Router			/networks/{networkId}/policies/evaluate [post]
+//	@Security		BearerAuth
+func (h *Handler) EvaluatePolicy(c *gin.Context) {
+	networkID := c.Param("networkId")
+
+	var req network.PolicyEvaluateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	evaluation, err := h.policyService.EvaluatePolicy(c.Request.Context(), networkID, &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, network.ErrPeerNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, network.ErrInvalidPacket):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, evaluation)
+}
+
 // AttachPolicyToGroup godoc
 //
 //	@Summary		Attach policy to group
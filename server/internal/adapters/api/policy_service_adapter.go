@@ -45,3 +45,7 @@ func (a *policyServiceAdapter) RemoveRuleFromPolicy(ctx context.Context, network
 	return a.service.RemoveRuleFromPolicy(ctx, networkID, policyID, ruleID)
 }
 
+func (a *policyServiceAdapter) EvaluatePolicy(ctx context.Context, networkID string, req *network.PolicyEvaluateRequest) (*network.PolicyEvaluation, error) {
+	return a.service.EvaluatePolicy(ctx, networkID, req)
+}
+
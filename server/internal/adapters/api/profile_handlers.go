@@ -0,0 +1,155 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	domain "wirety/internal/domain/network"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CreatePeerProfile godoc
+//
+//	@Summary		Create a peer profile
+//	@Description	Create a named bundle of MTU, keepalive, DNS and split-tunnel settings that peers of the network can be assigned (admin only)
+//	@Tags			profiles
+//	@Accept			json
+//	@Produce		json
+//	@Param			networkId	path		string						true	"Network ID"
+//	@Param			profile		body		network.PeerProfileRequest	true	"Profile settings"
+//	@Success		201			{object}	network.PeerProfile
+//	@Failure		400			{object}	map[string]string
+//	@Failure		403			{object}	map[string]string
+//	@Failure		409			{object}	map[string]string
+//	@Router			/networks/{networkId}/profiles [post]
+//	@Security		BearerAuth
+func (h *Handler) CreatePeerProfile(c *gin.Context) {
+	networkID := c.Param("networkId")
+
+	var req domain.PeerProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := req.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	profile, err := h.service.CreatePeerProfile(c.Request.Context(), networkID, &req)
+	if err != nil {
+		writePeerProfileError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, profile)
+}
+
+// ListPeerProfiles godoc
+//
+//	@Summary		List peer profiles
+//	@Description	List the peer profiles of a network
+//	@Tags			profiles
+//	@Produce		json
+//	@Param			networkId	path		string	true	"Network ID"
+//	@Success		200			{array}		network.PeerProfile
+//	@Failure		500			{object}	map[string]string
+//	@Router			/networks/{networkId}/profiles [get]
+//	@Security		BearerAuth
+func (h *Handler) ListPeerProfiles(c *gin.Context) {
+	profiles, err := h.service.ListPeerProfiles(c.Request.Context(), c.Param("networkId"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, profiles)
+}
+
+// GetPeerProfile godoc
+//
+//	@Summary		Get a peer profile
+//	@Description	Get a peer profile by ID
+//	@Tags			profiles
+//	@Produce		json
+//	@Param			networkId	path		string	true	"Network ID"
+//	@Param			profileId	path		string	true	"Profile ID"
+//	@Success		200			{object}	network.PeerProfile
+//	@Failure		404			{object}	map[string]string
+//	@Router			/networks/{networkId}/profiles/{profileId} [get]
+//	@Security		BearerAuth
+func (h *Handler) GetPeerProfile(c *gin.Context) {
+	profile, err := h.service.GetPeerProfile(c.Request.Context(), c.Param("networkId"), c.Param("profileId"))
+	if err != nil {
+		writePeerProfileError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, profile)
+}
+
+// UpdatePeerProfile godoc
+//
+//	@Summary		Update a peer profile
+//	@Description	Replace a peer profile's settings; configs of the network's peers are pushed again (admin only)
+//	@Tags			profiles
+//	@Accept			json
+//	@Produce		json
+//	@Param			networkId	path		string						true	"Network ID"
+//	@Param			profileId	path		string						true	"Profile ID"
+//	@Param			profile		body		network.PeerProfileRequest	true	"Profile settings"
+//	@Success		200			{object}	network.PeerProfile
+//	@Failure		400			{object}	map[string]string
+//	@Failure		403			{object}	map[string]string
+//	@Failure		404			{object}	map[string]string
+//	@Failure		409			{object}	map[string]string
+//	@Router			/networks/{networkId}/profiles/{profileId} [put]
+//	@Security		BearerAuth
+func (h *Handler) UpdatePeerProfile(c *gin.Context) {
+	var req domain.PeerProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := req.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	profile, err := h.service.UpdatePeerProfile(c.Request.Context(), c.Param("networkId"), c.Param("profileId"), &req)
+	if err != nil {
+		writePeerProfileError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, profile)
+}
+
+// DeletePeerProfile godoc
+//
+//	@Summary		Delete a peer profile
+//	@Description	Delete a peer profile. Profiles still assigned to peers cannot be deleted (admin only)
+//	@Tags			profiles
+//	@Param			networkId	path	string	true	"Network ID"
+//	@Param			profileId	path	string	true	"Profile ID"
+//	@Success		204
+//	@Failure		403	{object}	map[string]string
+//	@Failure		404	{object}	map[string]string
+//	@Failure		409	{object}	map[string]string
+//	@Router			/networks/{networkId}/profiles/{profileId} [delete]
+//	@Security		BearerAuth
+func (h *Handler) DeletePeerProfile(c *gin.Context) {
+	if err := h.service.DeletePeerProfile(c.Request.Context(), c.Param("networkId"), c.Param("profileId")); err != nil {
+		writePeerProfileError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func writePeerProfileError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, domain.ErrPeerProfileNotFound), errors.Is(err, domain.ErrNetworkNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, domain.ErrDuplicatePeerProfileName), errors.Is(err, domain.ErrPeerProfileInUse):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
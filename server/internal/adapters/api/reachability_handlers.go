@@ -85,7 +85,7 @@ func (h *Handler) GetPeerReachability(c *gin.Context) {
 	// peer's owner or an admin. Unlike connectivity status there is no
 	// jump-peer exception — querying a jump peer would still leak the whole
 	// network map to any member.
-	if user != nil && !user.IsAdministrator() && peer.OwnerID != user.ID {
+	if user != nil && !user.CanViewAll() && peer.OwnerID != user.ID {
 		c.JSON(http.StatusForbidden, gin.H{"error": "you can only view your own peers"})
 		return
 	}
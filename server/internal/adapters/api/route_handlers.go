@@ -37,7 +37,11 @@ func (h *Handler) CreateRoute(c *gin.Context) {
 
 	route, err := h.routeService.CreateRoute(c.Request.Context(), networkID, &req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		status := http.StatusInternalServerError
+		if isJumpPeerError(err) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
 		return
 	}
 
@@ -130,7 +134,11 @@ func (h *Handler) UpdateRoute(c *gin.Context) {
 
 	route, err := h.routeService.UpdateRoute(c.Request.Context(), networkID, routeID, &req)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		status := http.StatusNotFound
+		if isJumpPeerError(err) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
 		return
 	}
 
@@ -282,3 +290,9 @@ func (h *Handler) GetGroupRoutes(c *gin.Context) {
 
 	c.JSON(http.StatusOK, routes)
 }
+
+// isJumpPeerError reports whether a route's jump_peer_id does not name a jump
+// peer of the network.
+func isJumpPeerError(err error) bool {
+	return errors.Is(err, network.ErrJumpPeerNotFound) || errors.Is(err, network.ErrNotJumpPeer)
+}
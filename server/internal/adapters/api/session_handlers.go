@@ -1,9 +1,12 @@
 package api
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
 
 	"wirety/internal/adapters/api/middleware"
+	"wirety/internal/audit"
 	domain "wirety/internal/domain/network"
 
 	"github.com/gin-gonic/gin"
@@ -34,7 +37,7 @@ func (h *Handler) GetPeerConnectivityStatus(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "peer not found"})
 		return
 	}
-	if user != nil && !user.IsAdministrator() && !peer.IsJump && peer.OwnerID != user.ID {
+	if user != nil && !user.CanViewAll() && !peer.IsJump && peer.OwnerID != user.ID {
 		c.JSON(http.StatusForbidden, gin.H{"error": "you can only view your own peers"})
 		return
 	}
@@ -48,6 +51,43 @@ func (h *Handler) GetPeerConnectivityStatus(c *gin.Context) {
 	c.JSON(http.StatusOK, status)
 }
 
+// GetPeerStats godoc
+// @Summary      Get peer transfer statistics
+// @Description  Get the bytes a peer received and sent over WireGuard during its current session, and its last handshake. Reported by the peer's own agent, or by the jump peers' agents for peers without one.
+// @Tags         peers
+// @Produce      json
+// @Param        networkId path string true "Network ID"
+// @Param        peerId    path string true "Peer ID"
+// @Success      200 {object} domain.PeerStats
+// @Failure      403 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Router       /networks/{networkId}/peers/{peerId}/stats [get]
+// @Security     BearerAuth
+func (h *Handler) GetPeerStats(c *gin.Context) {
+	networkID := c.Param("networkId")
+	peerID := c.Param("peerId")
+	user := middleware.GetUserFromContext(c)
+
+	// Same object-level authz as GetPeerConnectivityStatus.
+	peer, err := h.service.GetPeer(c.Request.Context(), networkID, peerID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "peer not found"})
+		return
+	}
+	if user != nil && !user.CanViewAll() && !peer.IsJump && peer.OwnerID != user.ID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "you can only view your own peers"})
+		return
+	}
+
+	stats, err := h.service.GetPeerStats(c.Request.Context(), networkID, peerID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
 // ListNetworkSessions godoc
 // @Summary      List network sessions
 // @Description  Get all active agent sessions in a network (admin only)
@@ -71,3 +111,152 @@ func (h *Handler) ListNetworkSessions(c *gin.Context) {
 
 	c.JSON(http.StatusOK, sessions)
 }
+
+// PaginatedSessions represents a paginated list of agent sessions
+type PaginatedSessions struct {
+	Data     []*domain.AgentSession `json:"data"`
+	Total    int                    `json:"total"`
+	Page     int                    `json:"page"`
+	PageSize int                    `json:"page_size"`
+}
+
+// ListPeerSessionHistory godoc
+// @Summary      List a peer's session history
+// @Description  Get every agent session recorded for a peer, including revoked and expired ones, most recent first (admin only)
+// @Tags         peers
+// @Produce      json
+// @Param        networkId path  string true  "Network ID"
+// @Param        peerId    path  string true  "Peer ID"
+// @Param        page      query int    false "Page number" default(1)
+// @Param        page_size query int    false "Page size" default(20)
+// @Success      200 {object} PaginatedSessions
+// @Failure      403 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Router       /networks/{networkId}/peers/{peerId}/sessions/history [get]
+// @Security     BearerAuth
+func (h *Handler) ListPeerSessionHistory(c *gin.Context) {
+	networkID := c.Param("networkId")
+	peerID := c.Param("peerId")
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 500 {
+		pageSize = 20
+	}
+
+	sessions, err := h.service.ListSessionHistory(c.Request.Context(), networkID, peerID)
+	if err != nil {
+		if errors.Is(err, domain.ErrPeerNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	total := len(sessions)
+	start := (page - 1) * pageSize
+	if start > total {
+		start = total
+	}
+	end := start + pageSize
+	if end > total {
+		end = total
+	}
+
+	c.JSON(http.StatusOK, PaginatedSessions{
+		Data:     sessions[start:end],
+		Total:    total,
+		Page:     page,
+		PageSize: pageSize,
+	})
+}
+
+// RevokePeerSession godoc
+// @Summary      Revoke a peer session
+// @Description  Mark one of a peer's agent sessions as revoked (admin only). The session stays in the history; the agent's next heartbeat starts a new session.
+// @Tags         peers
+// @Produce      json
+// @Param        networkId path string true "Network ID"
+// @Param        peerId    path string true "Peer ID"
+// @Param        sessionId path string true "Session ID"
+// @Success      200 {object} domain.AgentSession
+// @Failure      403 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Failure      500 {object} map[string]string
+// @Router       /networks/{networkId}/peers/{peerId}/sessions/{sessionId}/revoke [post]
+// @Security     BearerAuth
+func (h *Handler) RevokePeerSession(c *gin.Context) {
+	networkID := c.Param("networkId")
+	peerID := c.Param("peerId")
+	sessionID := c.Param("sessionId")
+
+	session, err := h.service.RevokeSession(c.Request.Context(), networkID, peerID, sessionID)
+	if err != nil {
+		if errors.Is(err, domain.ErrPeerNotFound) || errors.Is(err, domain.ErrSessionNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	id, email := actor(c)
+	audit.Server(id, email, c.ClientIP()).
+		Str("action", "peer.session.revoke").
+		Str("network_id", networkID).
+		Str("peer_id", peerID).
+		Str("session_id", sessionID).
+		Str("hostname", session.Hostname).
+		Msg("audit")
+
+	c.JSON(http.StatusOK, session)
+}
+
+// ListAgentConnections godoc
+// @Summary      List live agent connections
+// @Description  List the agents currently connected to the server over WebSocket in a network (admin only)
+// @Tags         networks
+// @Produce      json
+// @Param        networkId path string true "Network ID"
+// @Success      200 {array} AgentConnection
+// @Failure      403 {object} map[string]string
+// @Router       /networks/{networkId}/connections [get]
+// @Security     BearerAuth
+func (h *Handler) ListAgentConnections(c *gin.Context) {
+	c.JSON(http.StatusOK, h.wsManager.Connections(c.Param("networkId")))
+}
+
+// DisconnectAgent godoc
+// @Summary      Disconnect an agent
+// @Description  Close a peer's live agent WebSocket connection (admin only). The peer is not quarantined: the agent reconnects and receives its config again.
+// @Tags         networks
+// @Param        networkId path string true "Network ID"
+// @Param        peerId    path string true "Peer ID"
+// @Success      204
+// @Failure      403 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Router       /networks/{networkId}/connections/{peerId} [delete]
+// @Security     BearerAuth
+func (h *Handler) DisconnectAgent(c *gin.Context) {
+	networkID := c.Param("networkId")
+	peerID := c.Param("peerId")
+
+	if !h.wsManager.Disconnect(networkID, peerID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "peer has no live agent connection"})
+		return
+	}
+
+	id, email := actor(c)
+	audit.Server(id, email, c.ClientIP()).
+		Str("action", "peer.disconnect").
+		Str("network_id", networkID).
+		Str("peer_id", peerID).
+		Msg("audit")
+
+	c.Status(http.StatusNoContent)
+}
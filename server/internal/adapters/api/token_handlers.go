@@ -8,6 +8,7 @@ import (
 	"net/http"
 
 	"wirety/internal/adapters/api/middleware"
+	"wirety/internal/audit"
 	"wirety/internal/domain/auth"
 
 	"github.com/gin-gonic/gin"
@@ -43,11 +44,22 @@ func (h *Handler) CreateAPIToken(c *gin.Context) {
 		return
 	}
 
+	resp, err := h.mintAPIToken(user.ID, &req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, resp)
+}
+
+// mintAPIToken creates a token for userID.  The response carries the raw
+// token, which is shown exactly once; only its hash is stored.
+func (h *Handler) mintAPIToken(userID string, req *auth.APITokenCreateRequest) (*auth.APITokenResponse, error) {
 	// Generate 32 random bytes → 64 hex chars
 	raw, err := generateRawToken()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate token"})
-		return
+		return nil, fmt.Errorf("failed to generate token")
 	}
 
 	h256 := sha256.Sum256([]byte(raw))
@@ -55,25 +67,24 @@ func (h *Handler) CreateAPIToken(c *gin.Context) {
 
 	token := &auth.APIToken{
 		ID:        uuid.New().String(),
-		UserID:    user.ID,
+		UserID:    userID,
 		Name:      req.Name,
 		TokenHash: hash,
 		ExpiresAt: req.ExpiresAt,
 	}
 
 	if err := h.userRepo.CreateAPIToken(token); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create token"})
-		return
+		return nil, fmt.Errorf("failed to create token")
 	}
 
-	c.JSON(http.StatusCreated, auth.APITokenResponse{
+	return &auth.APITokenResponse{
 		ID:         token.ID,
 		Name:       token.Name,
 		RawToken:   raw, // shown exactly once
 		CreatedAt:  token.CreatedAt,
 		ExpiresAt:  token.ExpiresAt,
 		LastUsedAt: token.LastUsedAt,
-	})
+	}, nil
 }
 
 // ListAPITokens godoc
@@ -100,6 +111,11 @@ func (h *Handler) ListAPITokens(c *gin.Context) {
 		return
 	}
 
+	c.JSON(http.StatusOK, apiTokenResponses(tokens))
+}
+
+// apiTokenResponses converts stored tokens for listing, without secrets.
+func apiTokenResponses(tokens []*auth.APIToken) []auth.APITokenResponse {
 	resp := make([]auth.APITokenResponse, 0, len(tokens))
 	for _, t := range tokens {
 		resp = append(resp, auth.APITokenResponse{
@@ -110,8 +126,7 @@ func (h *Handler) ListAPITokens(c *gin.Context) {
 			LastUsedAt: t.LastUsedAt,
 		})
 	}
-
-	c.JSON(http.StatusOK, resp)
+	return resp
 }
 
 // DeleteAPIToken godoc
@@ -134,13 +149,16 @@ func (h *Handler) DeleteAPIToken(c *gin.Context) {
 		return
 	}
 
-	tokenID := c.Param("tokenId")
+	h.revokeAPIToken(c, user.ID, c.Param("tokenId"))
+}
 
+// revokeAPIToken deletes tokenID if it belongs to userID and writes the response.
+func (h *Handler) revokeAPIToken(c *gin.Context, userID, tokenID string) bool {
 	// Ensure token belongs to the user by listing their tokens
-	tokens, err := h.userRepo.ListAPITokens(user.ID)
+	tokens, err := h.userRepo.ListAPITokens(userID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list tokens"})
-		return
+		return false
 	}
 
 	owned := false
@@ -152,15 +170,111 @@ func (h *Handler) DeleteAPIToken(c *gin.Context) {
 	}
 	if !owned {
 		c.JSON(http.StatusForbidden, gin.H{"error": "token not found or not owned by user"})
-		return
+		return false
 	}
 
 	if err := h.userRepo.DeleteAPIToken(tokenID); err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "token not found"})
-		return
+		return false
 	}
 
 	c.Status(http.StatusNoContent)
+	return true
+}
+
+// CreateUserAPIKey godoc
+//
+//	@Summary		Create an API key for a user
+//	@Description	Mint a long-lived API key owned by the given user (admin only), e.g. for CI pipelines. The key carries the user's role and network access and is returned only in this response.
+//	@Tags			tokens
+//	@Accept			json
+//	@Produce		json
+//	@Param			userId	path		string						true	"User ID"
+//	@Param			request	body		auth.APITokenCreateRequest	true	"API key creation request"
+//	@Success		201		{object}	auth.APITokenResponse
+//	@Failure		400		{object}	map[string]string
+//	@Failure		403		{object}	map[string]string
+//	@Failure		404		{object}	map[string]string
+//	@Failure		500		{object}	map[string]string
+//	@Router			/users/{userId}/api-keys [post]
+//	@Security		BearerAuth
+func (h *Handler) CreateUserAPIKey(c *gin.Context) {
+	userID := c.Param("userId")
+	if _, err := h.userRepo.GetUser(userID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+
+	var req auth.APITokenCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	resp, err := h.mintAPIToken(userID, &req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	id, email := actor(c)
+	audit.Server(id, email, c.ClientIP()).
+		Str("action", "user.api_key.create").
+		Str("user_id", userID).
+		Str("token_id", resp.ID).
+		Str("token_name", resp.Name).
+		Msg("audit")
+
+	c.JSON(http.StatusCreated, resp)
+}
+
+// ListUserAPIKeys godoc
+//
+//	@Summary		List a user's API keys
+//	@Description	List the API keys owned by the given user (admin only); secrets are never returned
+//	@Tags			tokens
+//	@Produce		json
+//	@Param			userId	path		string	true	"User ID"
+//	@Success		200		{array}		auth.APITokenResponse
+//	@Failure		403		{object}	map[string]string
+//	@Failure		500		{object}	map[string]string
+//	@Router			/users/{userId}/api-keys [get]
+//	@Security		BearerAuth
+func (h *Handler) ListUserAPIKeys(c *gin.Context) {
+	tokens, err := h.userRepo.ListAPITokens(c.Param("userId"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list tokens"})
+		return
+	}
+
+	c.JSON(http.StatusOK, apiTokenResponses(tokens))
+}
+
+// RevokeUserAPIKey godoc
+//
+//	@Summary		Revoke a user's API key
+//	@Description	Revoke an API key owned by the given user (admin only)
+//	@Tags			tokens
+//	@Param			userId	path	string	true	"User ID"
+//	@Param			tokenId	path	string	true	"API key ID"
+//	@Success		204
+//	@Failure		403	{object}	map[string]string
+//	@Failure		404	{object}	map[string]string
+//	@Router			/users/{userId}/api-keys/{tokenId} [delete]
+//	@Security		BearerAuth
+func (h *Handler) RevokeUserAPIKey(c *gin.Context) {
+	userID := c.Param("userId")
+	tokenID := c.Param("tokenId")
+	if !h.revokeAPIToken(c, userID, tokenID) {
+		return
+	}
+
+	id, email := actor(c)
+	audit.Server(id, email, c.ClientIP()).
+		Str("action", "user.api_key.revoke").
+		Str("user_id", userID).
+		Str("token_id", tokenID).
+		Msg("audit")
 }
 
 func generateRawToken() (string, error) {
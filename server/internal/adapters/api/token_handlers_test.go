@@ -0,0 +1,100 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"wirety/internal/adapters/api/middleware"
+	"wirety/internal/adapters/db/memory"
+	"wirety/internal/config"
+	"wirety/internal/domain/auth"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestUserAPIKey_MintAuthenticateRevoke(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	userRepo := memory.NewUserRepository()
+	admin := &auth.User{ID: "admin", Email: "admin@example.com", Role: auth.RoleAdministrator}
+	ci := &auth.User{ID: "ci", Email: "ci@example.com", Role: auth.RoleUser, AuthorizedNetworks: []string{"net1"}}
+	for _, u := range []*auth.User{admin, ci} {
+		if err := userRepo.CreateUser(u); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	h := &Handler{userRepo: userRepo}
+	asAdmin := func(c *gin.Context) {
+		c.Set(middleware.UserContextKey, admin)
+		c.Next()
+	}
+	r := gin.New()
+	r.POST("/users/:userId/api-keys", asAdmin, middleware.RequireAdmin(), h.CreateUserAPIKey)
+	r.GET("/users/:userId/api-keys", asAdmin, middleware.RequireAdmin(), h.ListUserAPIKeys)
+	r.DELETE("/users/:userId/api-keys/:tokenId", asAdmin, middleware.RequireAdmin(), h.RevokeUserAPIKey)
+	r.GET("/whoami", middleware.AuthMiddleware(nil, userRepo, &config.AuthConfig{}), func(c *gin.Context) {
+		c.JSON(http.StatusOK, middleware.GetUserFromContext(c))
+	})
+
+	do := func(method, path, authorization, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := do(http.MethodPost, "/users/nobody/api-keys", "", `{"name":"ci"}`); w.Code != http.StatusNotFound {
+		t.Fatalf("mint for unknown user: status %d, want 404", w.Code)
+	}
+
+	w := do(http.MethodPost, "/users/ci/api-keys", "", `{"name":"pipeline"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("mint: status %d: %s", w.Code, w.Body)
+	}
+	var created auth.APITokenResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(created.RawToken, apiTokenPrefix) {
+		t.Fatalf("expected a %s key, got %q", apiTokenPrefix, created.RawToken)
+	}
+
+	// The key resolves to its owner, with the owner's role
+	for _, scheme := range []string{"ApiKey", "Bearer"} {
+		w = do(http.MethodGet, "/whoami", scheme+" "+created.RawToken, "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s auth: status %d: %s", scheme, w.Code, w.Body)
+		}
+		var who auth.User
+		if err := json.Unmarshal(w.Body.Bytes(), &who); err != nil {
+			t.Fatal(err)
+		}
+		if who.ID != "ci" || who.IsAdministrator() {
+			t.Fatalf("%s auth resolved to %+v, want the non-admin ci user", scheme, who)
+		}
+	}
+
+	// The secret is never returned again
+	w = do(http.MethodGet, "/users/ci/api-keys", "", "")
+	if w.Code != http.StatusOK || strings.Contains(w.Body.String(), created.RawToken) || !strings.Contains(w.Body.String(), created.ID) {
+		t.Fatalf("list: status %d: %s", w.Code, w.Body)
+	}
+
+	if w := do(http.MethodDelete, "/users/admin/api-keys/"+created.ID, "", ""); w.Code != http.StatusForbidden {
+		t.Fatalf("revoke under the wrong user: status %d, want 403", w.Code)
+	}
+	if w := do(http.MethodDelete, "/users/ci/api-keys/"+created.ID, "", ""); w.Code != http.StatusNoContent {
+		t.Fatalf("revoke: status %d: %s", w.Code, w.Body)
+	}
+	if w := do(http.MethodGet, "/whoami", "ApiKey "+created.RawToken, ""); w.Code != http.StatusUnauthorized {
+		t.Fatalf("revoked key: status %d, want 401", w.Code)
+	}
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"errors"
 	"net/http"
 
 	"wirety/internal/adapters/api/middleware"
@@ -57,8 +58,8 @@ func (h *Handler) CreateUser(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "password must be at least 8 characters"})
 		return
 	}
-	if req.Role != auth.RoleAdministrator && req.Role != auth.RoleUser {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "role must be 'administrator' or 'user'"})
+	if !req.Role.Valid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "role must be 'administrator', 'user' or 'auditor'"})
 		return
 	}
 
@@ -163,6 +164,11 @@ func (h *Handler) UpdateUser(c *gin.Context) {
 		return
 	}
 
+	if req.Role != "" && !req.Role.Valid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "role must be 'administrator', 'user' or 'auditor'"})
+		return
+	}
+
 	user, err := h.userRepo.GetUser(userID)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
@@ -312,3 +318,83 @@ func (h *Handler) UpdateDefaultPermissions(c *gin.Context) {
 
 	c.JSON(http.StatusOK, perms)
 }
+
+// StartImpersonation godoc
+// @Summary      Start impersonating a user
+// @Description  Start a read-only, time-limited impersonation of a user (admin only). Send the returned token in the X-Wirety-Impersonate header to see what the user sees; mutations are rejected.
+// @Tags         users
+// @Produce      json
+// @Param        userId path string true "User ID"
+// @Success      201 {object} middleware.Impersonation
+// @Failure      400 {object} map[string]string
+// @Failure      403 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Failure      429 {object} map[string]string
+// @Router       /users/{userId}/impersonate [post]
+// @Security     BearerAuth
+func (h *Handler) StartImpersonation(c *gin.Context) {
+	admin := middleware.GetUserFromContext(c)
+	if admin == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+		return
+	}
+	userID := c.Param("userId")
+
+	grant, err := h.impersonator.Start(admin, userID)
+	switch {
+	case errors.Is(err, middleware.ErrImpersonationRateLimited):
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, middleware.ErrImpersonateAdministrator), errors.Is(err, middleware.ErrImpersonateSelf):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+
+	audit.Server(admin.ID, admin.Email, c.ClientIP()).
+		Str("action", "impersonation.start").
+		Str("target_user_id", userID).
+		Time("expires_at", grant.ExpiresAt).
+		Msg("audit")
+
+	c.JSON(http.StatusCreated, grant)
+}
+
+// EndImpersonationRequest names the impersonation token to revoke. The token
+// travels in the body so it does not end up in URLs and access logs.
+type EndImpersonationRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// EndImpersonation godoc
+// @Summary      End an impersonation
+// @Description  Revoke an impersonation token before it expires (admin only)
+// @Tags         users
+// @Accept       json
+// @Param        request body EndImpersonationRequest true "Impersonation token"
+// @Success      204
+// @Failure      400 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Router       /users/impersonations/end [post]
+// @Security     BearerAuth
+func (h *Handler) EndImpersonation(c *gin.Context) {
+	var req EndImpersonationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	id, email := actor(c)
+	if !h.impersonator.End(id, req.Token) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "impersonation not found"})
+		return
+	}
+
+	audit.Server(id, email, c.ClientIP()).
+		Str("action", "impersonation.end").
+		Msg("audit")
+
+	c.Status(http.StatusNoContent)
+}
@@ -4,12 +4,15 @@ import (
 	"context"
 	"encoding/json"
 	"net/http"
+	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"wirety/internal/application/network"
 	"wirety/internal/config"
 	domain "wirety/internal/domain/network"
+	"wirety/pkg/wireguard"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
@@ -35,20 +38,147 @@ type WebSocketManager struct {
 	service     *network.Service
 	authConfig  *config.AuthConfig
 	connections map[string]map[string]*websocket.Conn // networkID -> peerID -> conn
+	connInfo    map[*websocket.Conn]AgentConnection
 	mu          sync.RWMutex
+	// sentConfigs holds the last WireGuard config sent on each connection,
+	// the base of the peer delta sent with the next update.
+	sentConfigs map[*websocket.Conn]string
+	sentMu      sync.Mutex
+
+	// NotifyNetworkPeers calls for a network within debounce of each other
+	// collapse into one push, sent once the network has been quiet for that
+	// long.  pushNetwork does the push; tests replace it.
+	debounce    time.Duration
+	pending     map[string]*time.Timer // networkID -> trailing push
+	pendingMu   sync.Mutex
+	pushNetwork func(networkID string)
+
+	// pendingAcks holds the config last sent on each connection until the
+	// agent acknowledges applying it; a warning is logged when it does not
+	// within ackTimeout.  Agents that predate config acks never send one,
+	// so only connections in ackers, which acknowledged a config before,
+	// are warned about.  Both are guarded by sentMu.
+	ackTimeout  time.Duration
+	pendingAcks map[*websocket.Conn]*pendingAck
+	ackers      map[*websocket.Conn]bool
 }
 
+// pendingAck is a config sent to an agent and not acknowledged yet.
+type pendingAck struct {
+	hash  string
+	timer *time.Timer
+}
+
+// defaultNotifyDebounce is the window NotifyNetworkPeers coalesces changes in.
+const defaultNotifyDebounce = 500 * time.Millisecond
+
+// defaultConfigAckTimeout is how long an agent has to acknowledge a config.
+const defaultConfigAckTimeout = 30 * time.Second
+
 // NewWebSocketManager creates a new WebSocket manager
 func NewWebSocketManager(service *network.Service, authConfig *config.AuthConfig) *WebSocketManager {
-	return &WebSocketManager{
+	m := &WebSocketManager{
 		service:     service,
 		authConfig:  authConfig,
 		connections: make(map[string]map[string]*websocket.Conn),
+		connInfo:    make(map[*websocket.Conn]AgentConnection),
+		sentConfigs: make(map[*websocket.Conn]string),
+		debounce:    defaultNotifyDebounce,
+		pending:     make(map[string]*time.Timer),
+		ackTimeout:  defaultConfigAckTimeout,
+		pendingAcks: make(map[*websocket.Conn]*pendingAck),
+		ackers:      make(map[*websocket.Conn]bool),
 	}
+	m.pushNetwork = m.pushNetworkPeers
+	return m
+}
+
+// SetNotifyDebounce sets the window in which NotifyNetworkPeers calls for
+// the same network are coalesced.  Zero pushes every call immediately.
+func (m *WebSocketManager) SetNotifyDebounce(d time.Duration) {
+	m.pendingMu.Lock()
+	defer m.pendingMu.Unlock()
+	m.debounce = d
+}
+
+// peerDelta returns the [Peer] changes between the last config sent on conn
+// and cfg, or nil when the agent needs the full config.
+func (m *WebSocketManager) peerDelta(conn *websocket.Conn, cfg string) *wireguard.PeerDelta {
+	m.sentMu.Lock()
+	defer m.sentMu.Unlock()
+	delta, ok := wireguard.DiffConfigs(m.sentConfigs[conn], cfg)
+	if !ok {
+		return nil
+	}
+	return delta
+}
+
+// configSent records cfg as the last config sent on conn.
+func (m *WebSocketManager) configSent(conn *websocket.Conn, cfg string) {
+	m.sentMu.Lock()
+	defer m.sentMu.Unlock()
+	m.sentConfigs[conn] = cfg
+}
+
+// expectAck waits for the agent on conn to acknowledge cfg, replacing the
+// config it was still expected to acknowledge.
+func (m *WebSocketManager) expectAck(networkID, peerID string, conn *websocket.Conn, cfg string) {
+	m.sentMu.Lock()
+	defer m.sentMu.Unlock()
+	if prev, ok := m.pendingAcks[conn]; ok {
+		prev.timer.Stop()
+	}
+	ack := &pendingAck{hash: wireguard.ConfigHash(cfg)}
+	ack.timer = time.AfterFunc(m.ackTimeout, func() {
+		m.sentMu.Lock()
+		defer m.sentMu.Unlock()
+		if m.pendingAcks[conn] != ack {
+			return
+		}
+		delete(m.pendingAcks, conn)
+		if m.ackers[conn] {
+			log.Warn().Str("network_id", networkID).Str("peer_id", peerID).Str("config_hash", ack.hash).Dur("timeout", m.ackTimeout).Msg("Agent did not acknowledge config update")
+		}
+	})
+	m.pendingAcks[conn] = ack
+}
+
+// configAcked records that the agent on conn applied the config with hash.
+// It reports whether that is the config last sent on conn.
+func (m *WebSocketManager) configAcked(conn *websocket.Conn, hash string) bool {
+	m.sentMu.Lock()
+	defer m.sentMu.Unlock()
+	m.ackers[conn] = true
+	ack, ok := m.pendingAcks[conn]
+	if !ok || ack.hash != hash {
+		return false
+	}
+	ack.timer.Stop()
+	delete(m.pendingAcks, conn)
+	return true
+}
+
+// forgetConn drops what was sent on a closed connection.
+func (m *WebSocketManager) forgetConn(conn *websocket.Conn) {
+	m.sentMu.Lock()
+	defer m.sentMu.Unlock()
+	delete(m.sentConfigs, conn)
+	if ack, ok := m.pendingAcks[conn]; ok {
+		ack.timer.Stop()
+		delete(m.pendingAcks, conn)
+	}
+	delete(m.ackers, conn)
+}
+
+// AgentConnection describes a live agent WebSocket connection.
+type AgentConnection struct {
+	PeerID      string    `json:"peer_id"`
+	ConnectedAt time.Time `json:"connected_at"`
+	RemoteAddr  string    `json:"remote_addr"`
 }
 
 // Register adds a connection to the manager
-func (m *WebSocketManager) Register(networkID, peerID string, conn *websocket.Conn) {
+func (m *WebSocketManager) Register(networkID, peerID string, conn *websocket.Conn, remoteAddr string) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -56,15 +186,18 @@ func (m *WebSocketManager) Register(networkID, peerID string, conn *websocket.Co
 		m.connections[networkID] = make(map[string]*websocket.Conn)
 	}
 	m.connections[networkID][peerID] = conn
+	m.connInfo[conn] = AgentConnection{PeerID: peerID, ConnectedAt: time.Now(), RemoteAddr: remoteAddr}
 	log.Info().Str("network_id", networkID).Str("peer_id", peerID).Msg("WebSocket connection registered")
 }
 
-// Unregister removes a connection from the manager
-func (m *WebSocketManager) Unregister(networkID, peerID string) {
+// Unregister removes a connection from the manager.  A peer that already
+// reconnected keeps its newer connection.
+func (m *WebSocketManager) Unregister(networkID, peerID string, conn *websocket.Conn) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	if peers, exists := m.connections[networkID]; exists {
+	delete(m.connInfo, conn)
+	if peers, exists := m.connections[networkID]; exists && peers[peerID] == conn {
 		delete(peers, peerID)
 		if len(peers) == 0 {
 			delete(m.connections, networkID)
@@ -73,6 +206,70 @@ func (m *WebSocketManager) Unregister(networkID, peerID string) {
 	log.Info().Str("network_id", networkID).Str("peer_id", peerID).Msg("WebSocket connection unregistered")
 }
 
+// Connections lists the live agent connections of a network, by peer ID.
+func (m *WebSocketManager) Connections(networkID string) []AgentConnection {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	conns := make([]AgentConnection, 0, len(m.connections[networkID]))
+	for _, conn := range m.connections[networkID] {
+		conns = append(conns, m.connInfo[conn])
+	}
+	sort.Slice(conns, func(i, j int) bool { return conns[i].PeerID < conns[j].PeerID })
+	return conns
+}
+
+// Disconnect closes a peer's agent connection and reports whether it had
+// one.  The agent reconnects on its own; nothing else about the peer changes.
+func (m *WebSocketManager) Disconnect(networkID, peerID string) bool {
+	m.mu.RLock()
+	conn, exists := m.connections[networkID][peerID]
+	m.mu.RUnlock()
+	if !exists {
+		return false
+	}
+
+	closeMsg := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "disconnected by an administrator")
+	_ = conn.WriteControl(websocket.CloseMessage, closeMsg, time.Now().Add(time.Second))
+	// The handler's read loop fails on the closed socket and unregisters it.
+	_ = conn.Close()
+	log.Info().Str("network_id", networkID).Str("peer_id", peerID).Msg("WebSocket connection closed by an administrator")
+	return true
+}
+
+// CloseAll sends the debounced network pushes still pending, then closes
+// every agent connection with a going-away close frame.  It runs on server
+// shutdown; the agents reconnect once the server is back.
+func (m *WebSocketManager) CloseAll() {
+	m.pendingMu.Lock()
+	flush := make([]string, 0, len(m.pending))
+	for networkID, timer := range m.pending {
+		// A timer that could not be stopped is already pushing
+		if timer.Stop() {
+			flush = append(flush, networkID)
+		}
+		delete(m.pending, networkID)
+	}
+	m.pendingMu.Unlock()
+	for _, networkID := range flush {
+		m.pushNetwork(networkID)
+	}
+
+	m.mu.RLock()
+	conns := make([]*websocket.Conn, 0, len(m.connInfo))
+	for conn := range m.connInfo {
+		conns = append(conns, conn)
+	}
+	m.mu.RUnlock()
+
+	closeMsg := websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down")
+	for _, conn := range conns {
+		_ = conn.WriteControl(websocket.CloseMessage, closeMsg, time.Now().Add(time.Second))
+		_ = conn.Close()
+	}
+	log.Info().Int("connections", len(conns)).Msg("WebSocket connections closed for shutdown")
+}
+
 // IsConnected checks if a peer has an active WebSocket connection
 func (m *WebSocketManager) IsConnected(networkID, peerID string) bool {
 	m.mu.RLock()
@@ -85,6 +282,18 @@ func (m *WebSocketManager) IsConnected(networkID, peerID string) bool {
 	return false
 }
 
+// ConnectionCount returns the number of currently connected agents
+func (m *WebSocketManager) ConnectionCount() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	count := 0
+	for _, peers := range m.connections {
+		count += len(peers)
+	}
+	return count
+}
+
 // HandleWebSocketToken handles WebSocket connections authenticated by enrollment token (Authorization: Bearer <token>)
 func (h *Handler) HandleWebSocketToken(c *gin.Context) {
 	token := extractBearerToken(c)
@@ -92,7 +301,7 @@ func (h *Handler) HandleWebSocketToken(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Authorization: Bearer <token> header required"})
 		return
 	}
-	networkID, peer, err := h.service.ResolveAgentToken(c.Request.Context(), token)
+	networkID, peer, err := h.service.AuthenticateAgentToken(c.Request.Context(), token)
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
 		return
@@ -103,14 +312,15 @@ func (h *Handler) HandleWebSocketToken(c *gin.Context) {
 		return
 	}
 	defer func() {
-		h.wsManager.Unregister(networkID, peer.ID)
+		h.wsManager.Unregister(networkID, peer.ID, conn)
+		h.wsManager.forgetConn(conn)
 		_ = conn.Close()
 	}()
 
 	log.Info().Str("network_id", networkID).Str("peer_id", peer.ID).Msg("WebSocket token connection established")
 
 	// Register connection
-	h.wsManager.Register(networkID, peer.ID, conn)
+	h.wsManager.Register(networkID, peer.ID, conn, c.ClientIP())
 
 	cfg, dnsCfg, policy, err := h.service.GeneratePeerConfigWithDNS(c.Request.Context(), networkID, peer.ID)
 	if err != nil {
@@ -169,6 +379,8 @@ func (h *Handler) HandleWebSocketToken(c *gin.Context) {
 		log.Error().Err(err).Msg("Failed to send initial config (token)")
 		return
 	}
+	h.wsManager.configSent(conn, cfg)
+	h.wsManager.expectAck(networkID, peer.ID, conn, cfg)
 	for {
 		msgType, message, err := conn.ReadMessage()
 		if err != nil {
@@ -178,6 +390,38 @@ func (h *Handler) HandleWebSocketToken(c *gin.Context) {
 
 		// Process heartbeat messages from agent
 		if msgType == websocket.TextMessage {
+			var envelope struct {
+				Type string `json:"type"`
+			}
+			_ = json.Unmarshal(message, &envelope)
+			if envelope.Type == domain.AgentMessageTypeConfigAck {
+				var ack domain.ConfigAck
+				if err := json.Unmarshal(message, &ack); err != nil {
+					log.Warn().Err(err).Msg("Failed to parse config ack")
+					continue
+				}
+				if !h.wsManager.configAcked(conn, ack.ConfigHash) {
+					log.Debug().Str("network_id", networkID).Str("peer_id", peer.ID).Str("config_hash", ack.ConfigHash).Msg("Agent acknowledged a config other than the last one sent")
+				}
+				if err := h.service.RecordConfigAck(c.Request.Context(), networkID, peer.ID, ack.ConfigHash); err != nil {
+					log.Error().Err(err).Msg("Failed to record config ack")
+				}
+				continue
+			}
+			if envelope.Type == domain.AgentMessageTypeJumpHealth {
+				var report domain.JumpHealthReport
+				if err := json.Unmarshal(message, &report); err != nil {
+					log.Warn().Err(err).Msg("Failed to parse jump health report")
+					continue
+				}
+				if err := h.service.RecordJumpHealth(c.Request.Context(), networkID, peer.ID, &report); err != nil {
+					log.Error().Err(err).Msg("Failed to record jump health")
+				} else if !report.ListenPortOK || !report.InternetOK {
+					log.Warn().Str("network_id", networkID).Str("peer_id", peer.ID).Str("reason", report.Reason).Msg("Jump health check failed")
+				}
+				continue
+			}
+
 			var heartbeat domain.AgentHeartbeat
 			if err := json.Unmarshal(message, &heartbeat); err != nil {
 				log.Warn().Err(err).Msg("Failed to parse heartbeat message")
@@ -201,6 +445,13 @@ func (h *Handler) HandleWebSocketToken(c *gin.Context) {
 
 // NotifyPeerUpdate sends updated configuration to a specific peer via WebSocket
 func (m *WebSocketManager) NotifyPeerUpdate(networkID, peerID string) {
+	m.service.InvalidateConfigs(networkID)
+	m.pushPeerUpdate(networkID, peerID)
+}
+
+// pushPeerUpdate sends the peer's current configuration, served from the
+// config cache when the network did not change since it was rendered.
+func (m *WebSocketManager) pushPeerUpdate(networkID, peerID string) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
@@ -245,8 +496,14 @@ func (m *WebSocketManager) NotifyPeerUpdate(networkID, peerID string) {
 				oauthIssuer = m.authConfig.IssuerURL
 			}
 
+			// Agents that understand the delta apply just the changed
+			// [Peer] sections with `wg set`; the full config still goes
+			// along so they can persist it and older agents keep working.
+			delta := m.peerDelta(conn, cfg)
+
 			msg := struct {
 				Config      string                               `json:"config"`
+				PeerDelta   *wireguard.PeerDelta                 `json:"peer_delta,omitempty"`
 				DNS         interface{}                          `json:"dns,omitempty"`
 				Policy      interface{}                          `json:"policy,omitempty"`
 				PeerID      string                               `json:"peer_id"`
@@ -259,6 +516,7 @@ func (m *WebSocketManager) NotifyPeerUpdate(networkID, peerID string) {
 				OAuthIssuer string                               `json:"oauth_issuer,omitempty"`
 			}{
 				Config:      cfg,
+				PeerDelta:   delta,
 				DNS:         dnsCfg,
 				Policy:      policy,
 				PeerID:      peer.ID,
@@ -274,14 +532,140 @@ func (m *WebSocketManager) NotifyPeerUpdate(networkID, peerID string) {
 			if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
 				log.Error().Err(err).Str("network_id", networkID).Str("peer_id", peerID).Msg("Failed to send config update")
 			} else {
-				log.Info().Str("network_id", networkID).Str("peer_id", peerID).Str("peer_name", peer.Name).Msg("Config update sent")
+				m.configSent(conn, cfg)
+				m.expectAck(networkID, peerID, conn, cfg)
+				log.Info().Str("network_id", networkID).Str("peer_id", peerID).Str("peer_name", peer.Name).Bool("delta", delta != nil).Msg("Config update sent")
 			}
 		}
 	}
 }
 
-// NotifyNetworkPeers sends updated configuration to all connected peers in a network
+// NotifyPeerDNS sends only the DNS config to a connected jump peer.  The
+// message carries no WireGuard config, so the agent updates its DNS server
+// and leaves the interface untouched.  Non-jump peers are skipped.
+func (m *WebSocketManager) NotifyPeerDNS(networkID, peerID string) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	peers, exists := m.connections[networkID]
+	if !exists {
+		return
+	}
+	conn, exists := peers[peerID]
+	if !exists {
+		return
+	}
+
+	ctx := context.Background()
+	peer, err := m.service.GetPeer(ctx, networkID, peerID)
+	if err != nil {
+		log.Error().Err(err).Str("network_id", networkID).Str("peer_id", peerID).Msg("Failed to get peer info for DNS update")
+		return
+	}
+	if !peer.IsJump {
+		return
+	}
+	dnsCfg, err := m.service.GeneratePeerDNSConfig(ctx, networkID, peerID)
+	if err != nil {
+		log.Error().Err(err).Str("network_id", networkID).Str("peer_id", peerID).Msg("Failed to generate DNS config for update")
+		return
+	}
+
+	msg := struct {
+		DNS    interface{} `json:"dns"`
+		PeerID string      `json:"peer_id"`
+	}{
+		DNS:    dnsCfg,
+		PeerID: peer.ID,
+	}
+	data, _ := json.Marshal(msg)
+	if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
+		log.Error().Err(err).Str("network_id", networkID).Str("peer_id", peerID).Msg("Failed to send DNS update")
+	} else {
+		log.Info().Str("network_id", networkID).Str("peer_id", peerID).Str("peer_name", peer.Name).Msg("DNS update sent")
+	}
+}
+
+// RequestJumpHealthChecks asks every connected jump agent to check its
+// listen port and internet access.  The agents reply with a jump_health
+// message, recorded on their session.
+func (m *WebSocketManager) RequestJumpHealthChecks() {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	ctx := context.Background()
+	data, _ := json.Marshal(struct {
+		HealthCheck bool `json:"health_check"`
+	}{HealthCheck: true})
+	for networkID, peers := range m.connections {
+		for peerID, conn := range peers {
+			peer, err := m.service.GetPeer(ctx, networkID, peerID)
+			if err != nil || !peer.IsJump {
+				continue
+			}
+			if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
+				log.Error().Err(err).Str("network_id", networkID).Str("peer_id", peerID).Msg("Failed to request jump health check")
+			}
+		}
+	}
+}
+
+// NotifyNetworkDNS sends the DNS config to every connected jump peer in a
+// network.  Use it instead of NotifyNetworkPeers when only DNS records changed.
+func (m *WebSocketManager) NotifyNetworkDNS(networkID string) {
+	m.service.InvalidateConfigs(networkID)
+
+	m.mu.RLock()
+	peerIDs := make([]string, 0)
+	if peers, exists := m.connections[networkID]; exists {
+		for peerID := range peers {
+			peerIDs = append(peerIDs, peerID)
+		}
+	}
+	m.mu.RUnlock()
+
+	for _, peerID := range peerIDs {
+		m.NotifyPeerDNS(networkID, peerID)
+	}
+}
+
+// NotifyNetworkPeers sends updated configuration to all connected peers in a
+// network.  Bulk operations call it once per change, so calls are debounced
+// per network: the push happens once no further call arrived for the
+// debounce window, and always reflects the state after the last change.
 func (m *WebSocketManager) NotifyNetworkPeers(networkID string) {
+	// Invalidate right away so configs generated during the debounce
+	// window, e.g. for a reconnecting agent, already reflect the change.
+	m.service.InvalidateConfigs(networkID)
+
+	m.pendingMu.Lock()
+	if m.debounce <= 0 {
+		m.pendingMu.Unlock()
+		m.pushNetwork(networkID)
+		return
+	}
+	defer m.pendingMu.Unlock()
+
+	// A timer that could not be stopped is already pushing, possibly with
+	// the state from before this change: schedule another push after it.
+	if timer, ok := m.pending[networkID]; ok && timer.Stop() {
+		timer.Reset(m.debounce)
+		return
+	}
+	var timer *time.Timer
+	timer = time.AfterFunc(m.debounce, func() {
+		m.pendingMu.Lock()
+		if m.pending[networkID] == timer {
+			delete(m.pending, networkID)
+		}
+		m.pendingMu.Unlock()
+		m.pushNetwork(networkID)
+	})
+	m.pending[networkID] = timer
+}
+
+// pushNetworkPeers sends updated configuration to all connected peers in a network
+func (m *WebSocketManager) pushNetworkPeers(networkID string) {
 	m.mu.RLock()
 	peerIDs := make([]string, 0)
 	if peers, exists := m.connections[networkID]; exists {
@@ -293,6 +677,6 @@ func (m *WebSocketManager) NotifyNetworkPeers(networkID string) {
 
 	// Generate and send config for each connected peer
 	for _, peerID := range peerIDs {
-		m.NotifyPeerUpdate(networkID, peerID)
+		m.pushPeerUpdate(networkID, peerID)
 	}
 }
@@ -0,0 +1,208 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"wirety/internal/application/network"
+	"wirety/pkg/wireguard"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+func TestNotifyNetworkPeers_Debounces(t *testing.T) {
+	m := NewWebSocketManager(&network.Service{}, nil)
+	m.SetNotifyDebounce(20 * time.Millisecond)
+
+	var mu sync.Mutex
+	pushes := make(map[string]int)
+	m.pushNetwork = func(networkID string) {
+		mu.Lock()
+		defer mu.Unlock()
+		pushes[networkID]++
+	}
+	count := func(networkID string) int {
+		mu.Lock()
+		defer mu.Unlock()
+		return pushes[networkID]
+	}
+
+	for i := 0; i < 50; i++ {
+		m.NotifyNetworkPeers("net1")
+	}
+	m.NotifyNetworkPeers("net2")
+	time.Sleep(100 * time.Millisecond)
+	if n := count("net1"); n != 1 {
+		t.Errorf("50 rapid changes produced %d pushes, want 1", n)
+	}
+	if n := count("net2"); n != 1 {
+		t.Errorf("other network: %d pushes, want 1", n)
+	}
+
+	// A change after the window is pushed on its own
+	m.NotifyNetworkPeers("net1")
+	time.Sleep(100 * time.Millisecond)
+	if n := count("net1"); n != 2 {
+		t.Errorf("later change: %d pushes in total, want 2", n)
+	}
+
+	// Without a window every change is pushed immediately
+	m.SetNotifyDebounce(0)
+	m.NotifyNetworkPeers("net2")
+	m.NotifyNetworkPeers("net2")
+	if n := count("net2"); n != 3 {
+		t.Errorf("undebounced: %d pushes in total, want 3", n)
+	}
+}
+
+func TestAgentConnections_ListAndDisconnect(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	m := NewWebSocketManager(nil, nil)
+	unregistered := make(chan struct{})
+	agentServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		m.Register("net1", "laptop", conn, "203.0.113.7")
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				break
+			}
+		}
+		m.Unregister("net1", "laptop", conn)
+		close(unregistered)
+	}))
+	defer agentServer.Close()
+
+	agent, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(agentServer.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = agent.Close() }()
+	deadline := time.Now().Add(time.Second)
+	for !m.IsConnected("net1", "laptop") && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	h := &Handler{wsManager: m}
+	r := gin.New()
+	r.GET("/networks/:networkId/connections", h.ListAgentConnections)
+	r.DELETE("/networks/:networkId/connections/:peerId", h.DisconnectAgent)
+	do := func(method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w
+	}
+
+	w := do(http.MethodGet, "/networks/net1/connections")
+	var conns []AgentConnection
+	if err := json.Unmarshal(w.Body.Bytes(), &conns); err != nil {
+		t.Fatal(err)
+	}
+	if len(conns) != 1 || conns[0].PeerID != "laptop" || conns[0].RemoteAddr != "203.0.113.7" || conns[0].ConnectedAt.IsZero() {
+		t.Fatalf("connections = %+v", conns)
+	}
+
+	if w := do(http.MethodDelete, "/networks/net1/connections/desktop"); w.Code != http.StatusNotFound {
+		t.Errorf("disconnect unknown peer: status %d, want 404", w.Code)
+	}
+	if w := do(http.MethodDelete, "/networks/net1/connections/laptop"); w.Code != http.StatusNoContent {
+		t.Fatalf("disconnect: status %d: %s", w.Code, w.Body)
+	}
+	if _, _, err := agent.ReadMessage(); !websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+		t.Errorf("agent read error = %v, want a normal close", err)
+	}
+	select {
+	case <-unregistered:
+	case <-time.After(time.Second):
+		t.Fatal("connection was not unregistered")
+	}
+	if conns := m.Connections("net1"); len(conns) != 0 {
+		t.Errorf("connections after disconnect = %+v", conns)
+	}
+}
+
+func TestCloseAll_FlushesPushesAndClosesAgents(t *testing.T) {
+	m := NewWebSocketManager(&network.Service{}, nil)
+	m.SetNotifyDebounce(time.Hour)
+	pushed := make(chan string, 1)
+	m.pushNetwork = func(networkID string) { pushed <- networkID }
+
+	agentServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		m.Register("net1", "laptop", conn, "203.0.113.7")
+	}))
+	defer agentServer.Close()
+
+	agent, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(agentServer.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = agent.Close() }()
+	deadline := time.Now().Add(time.Second)
+	for !m.IsConnected("net1", "laptop") && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	m.NotifyNetworkPeers("net1")
+	m.CloseAll()
+
+	select {
+	case networkID := <-pushed:
+		if networkID != "net1" {
+			t.Errorf("pushed %q, want net1", networkID)
+		}
+	default:
+		t.Error("the pending push was not sent before closing")
+	}
+	if _, _, err := agent.ReadMessage(); !websocket.IsCloseError(err, websocket.CloseGoingAway) {
+		t.Errorf("agent read error = %v, want a going-away close", err)
+	}
+}
+
+func TestConfigAcks(t *testing.T) {
+	m := NewWebSocketManager(nil, nil)
+	m.ackTimeout = 20 * time.Millisecond
+	conn := &websocket.Conn{}
+
+	m.expectAck("net1", "laptop", conn, "config v1")
+	m.expectAck("net1", "laptop", conn, "config v2")
+	if m.configAcked(conn, wireguard.ConfigHash("config v1")) {
+		t.Error("the ack of a superseded config matched the last one sent")
+	}
+	if !m.configAcked(conn, wireguard.ConfigHash("config v2")) {
+		t.Error("the ack of the last config sent did not match")
+	}
+
+	m.expectAck("net1", "laptop", conn, "config v3")
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		m.sentMu.Lock()
+		_, waiting := m.pendingAcks[conn]
+		m.sentMu.Unlock()
+		if !waiting {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if m.configAcked(conn, wireguard.ConfigHash("config v3")) {
+		t.Error("the config was still expected after the ack timeout")
+	}
+
+	m.forgetConn(conn)
+	m.sentMu.Lock()
+	defer m.sentMu.Unlock()
+	if len(m.pendingAcks) != 0 || len(m.ackers) != 0 {
+		t.Error("a closed connection is still tracked")
+	}
+}
@@ -0,0 +1,49 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"wirety/internal/audit"
+)
+
+// AuditRepository is an in-memory implementation of audit.AuditLogger
+type AuditRepository struct {
+	mu      sync.RWMutex
+	entries []*audit.Entry // in insertion order
+}
+
+// NewAuditRepository creates an empty in-memory audit log
+func NewAuditRepository() *AuditRepository {
+	return &AuditRepository{}
+}
+
+// Record appends an entry
+func (r *AuditRepository) Record(_ context.Context, entry *audit.Entry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e := *entry
+	r.entries = append(r.entries, &e)
+	return nil
+}
+
+// List returns a page of a network's entries, newest first
+func (r *AuditRepository) List(_ context.Context, networkID string, offset, limit int) ([]*audit.Entry, int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var matched []*audit.Entry
+	for i := len(r.entries) - 1; i >= 0; i-- {
+		if r.entries[i].NetworkID == networkID {
+			matched = append(matched, r.entries[i])
+		}
+	}
+	total := len(matched)
+	out := make([]*audit.Entry, 0)
+	for i := offset; i < total && len(out) < limit; i++ {
+		e := *matched[i]
+		out = append(out, &e)
+	}
+	return out, total, nil
+}
+
+var _ audit.AuditLogger = (*AuditRepository)(nil)
@@ -0,0 +1,266 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"wirety/internal/domain/network"
+)
+
+// DNSRepository is an in-memory implementation of network.DNSRepository
+type DNSRepository struct {
+	store *resourceStore
+}
+
+// NewDNSRepository creates an in-memory DNS repository sharing state with the
+// network repository's routes
+func NewDNSRepository(repo *Repository) *DNSRepository {
+	return &DNSRepository{store: repo.resources}
+}
+
+// validateAgainstRoute checks that the mapping's addresses sit inside the
+// matching family of the route's destination CIDRs. r.store.mu must be held.
+func (r *DNSRepository) validateAgainstRoute(routeID string, mapping *network.DNSMapping) error {
+	rt, ok := r.store.routes[routeID]
+	if !ok {
+		return fmt.Errorf("route not found")
+	}
+	if mapping.IPAddress != "" {
+		if rt.DestinationCIDR == "" {
+			return fmt.Errorf("ip_address: route has no IPv4 destination CIDR")
+		}
+		if err := network.ValidateIPInCIDR(mapping.IPAddress, rt.DestinationCIDR); err != nil {
+			return fmt.Errorf("ip_address validation failed: %w", err)
+		}
+	}
+	if mapping.IPv6Address != "" {
+		if rt.DestinationCIDRv6 == "" {
+			return fmt.Errorf("ip_address_v6: route has no IPv6 destination CIDR")
+		}
+		if err := network.ValidateIPInCIDR(mapping.IPv6Address, rt.DestinationCIDRv6); err != nil {
+			return fmt.Errorf("ip_address_v6 validation failed: %w", err)
+		}
+	}
+	return nil
+}
+
+// nameTaken reports whether another mapping of the route uses name. r.store.mu
+// must be held.
+func (r *DNSRepository) nameTaken(routeID, mappingID, name string) bool {
+	for _, m := range r.store.dns {
+		if m.ID != mappingID && m.RouteID == routeID && m.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateDNSMapping creates a new DNS mapping
+func (r *DNSRepository) CreateDNSMapping(ctx context.Context, routeID string, mapping *network.DNSMapping) error {
+	now := time.Now()
+	mapping.CreatedAt = now
+	mapping.UpdatedAt = now
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if err := r.validateAgainstRoute(routeID, mapping); err != nil {
+		return err
+	}
+	if _, exists := r.store.dns[mapping.ID]; exists {
+		return fmt.Errorf("DNS mapping already exists")
+	}
+	if r.nameTaken(routeID, mapping.ID, mapping.Name) {
+		return fmt.Errorf("DNS name already exists for route")
+	}
+
+	mapping.RouteID = routeID
+	r.store.dns[mapping.ID] = copyDNSMapping(mapping)
+	return nil
+}
+
+// GetDNSMapping retrieves a DNS mapping by ID
+func (r *DNSRepository) GetDNSMapping(ctx context.Context, routeID, mappingID string) (*network.DNSMapping, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	m, ok := r.store.dns[mappingID]
+	if !ok || m.RouteID != routeID {
+		return nil, fmt.Errorf("DNS mapping not found")
+	}
+	return copyDNSMapping(m), nil
+}
+
+// UpdateDNSMapping updates an existing DNS mapping
+func (r *DNSRepository) UpdateDNSMapping(ctx context.Context, routeID string, mapping *network.DNSMapping) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if err := r.validateAgainstRoute(routeID, mapping); err != nil {
+		return err
+	}
+	m, ok := r.store.dns[mapping.ID]
+	if !ok || m.RouteID != routeID {
+		return fmt.Errorf("DNS mapping not found")
+	}
+	if r.nameTaken(routeID, mapping.ID, mapping.Name) {
+		return fmt.Errorf("DNS name already exists for route")
+	}
+
+	mapping.UpdatedAt = time.Now()
+	m.Name = mapping.Name
+	m.IPAddress = mapping.IPAddress
+	m.IPv6Address = mapping.IPv6Address
+	m.UpdatedAt = mapping.UpdatedAt
+	return nil
+}
+
+// DeleteDNSMapping deletes a DNS mapping
+func (r *DNSRepository) DeleteDNSMapping(ctx context.Context, routeID, mappingID string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	m, ok := r.store.dns[mappingID]
+	if !ok || m.RouteID != routeID {
+		return fmt.Errorf("DNS mapping not found")
+	}
+	delete(r.store.dns, mappingID)
+	return nil
+}
+
+// ListDNSMappings lists all DNS mappings for a route, oldest first
+func (r *DNSRepository) ListDNSMappings(ctx context.Context, routeID string) ([]*network.DNSMapping, error) {
+	return r.filter(func(m *network.DNSMapping) bool { return m.RouteID == routeID }), nil
+}
+
+// GetNetworkDNSMappings retrieves all DNS mappings for a network, oldest first
+func (r *DNSRepository) GetNetworkDNSMappings(ctx context.Context, networkID string) ([]*network.DNSMapping, error) {
+	return r.filter(func(m *network.DNSMapping) bool {
+		rt, ok := r.store.routes[m.RouteID]
+		return ok && rt.NetworkID == networkID
+	}), nil
+}
+
+func (r *DNSRepository) filter(keep func(*network.DNSMapping) bool) []*network.DNSMapping {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	mappings := make([]*network.DNSMapping, 0)
+	for _, m := range r.store.dns {
+		if keep(m) {
+			mappings = append(mappings, copyDNSMapping(m))
+		}
+	}
+	sort.Slice(mappings, func(i, j int) bool {
+		if !mappings[i].CreatedAt.Equal(mappings[j].CreatedAt) {
+			return mappings[i].CreatedAt.Before(mappings[j].CreatedAt)
+		}
+		return mappings[i].ID < mappings[j].ID
+	})
+	return mappings
+}
+
+// networkNameTaken reports whether another record of the network conflicts
+// with record (see NetworkDNSRecord.ConflictsWith).  r.store.mu must be held.
+func (r *DNSRepository) networkNameTaken(record *network.NetworkDNSRecord) bool {
+	for _, rec := range r.store.networkDNS {
+		if record.ConflictsWith(rec) {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateNetworkDNSRecord creates a new network-scoped DNS record
+func (r *DNSRepository) CreateNetworkDNSRecord(ctx context.Context, record *network.NetworkDNSRecord) error {
+	now := time.Now()
+	record.CreatedAt = now
+	record.UpdatedAt = now
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, exists := r.store.networkDNS[record.ID]; exists {
+		return fmt.Errorf("network DNS record already exists")
+	}
+	if r.networkNameTaken(record) {
+		return network.ErrDuplicateNetworkDNSName
+	}
+	c := *record
+	r.store.networkDNS[record.ID] = &c
+	return nil
+}
+
+// GetNetworkDNSRecord retrieves a network-scoped DNS record by ID
+func (r *DNSRepository) GetNetworkDNSRecord(ctx context.Context, networkID, recordID string) (*network.NetworkDNSRecord, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	rec, ok := r.store.networkDNS[recordID]
+	if !ok || rec.NetworkID != networkID {
+		return nil, network.ErrNetworkDNSRecordNotFound
+	}
+	c := *rec
+	return &c, nil
+}
+
+// UpdateNetworkDNSRecord updates an existing network-scoped DNS record
+func (r *DNSRepository) UpdateNetworkDNSRecord(ctx context.Context, record *network.NetworkDNSRecord) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	rec, ok := r.store.networkDNS[record.ID]
+	if !ok || rec.NetworkID != record.NetworkID {
+		return network.ErrNetworkDNSRecordNotFound
+	}
+	if r.networkNameTaken(record) {
+		return network.ErrDuplicateNetworkDNSName
+	}
+
+	record.UpdatedAt = time.Now()
+	rec.Name = record.Name
+	rec.IPAddress = record.IPAddress
+	rec.IPv6Address = record.IPv6Address
+	rec.Type = record.Type
+	rec.Value = record.Value
+	rec.UpdatedAt = record.UpdatedAt
+	return nil
+}
+
+// DeleteNetworkDNSRecord deletes a network-scoped DNS record
+func (r *DNSRepository) DeleteNetworkDNSRecord(ctx context.Context, networkID, recordID string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	rec, ok := r.store.networkDNS[recordID]
+	if !ok || rec.NetworkID != networkID {
+		return network.ErrNetworkDNSRecordNotFound
+	}
+	delete(r.store.networkDNS, recordID)
+	return nil
+}
+
+// ListNetworkDNSRecords lists the network-scoped DNS records of a network, oldest first
+func (r *DNSRepository) ListNetworkDNSRecords(ctx context.Context, networkID string) ([]*network.NetworkDNSRecord, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	records := make([]*network.NetworkDNSRecord, 0)
+	for _, rec := range r.store.networkDNS {
+		if rec.NetworkID == networkID {
+			c := *rec
+			records = append(records, &c)
+		}
+	}
+	sort.Slice(records, func(i, j int) bool {
+		if !records[i].CreatedAt.Equal(records[j].CreatedAt) {
+			return records[i].CreatedAt.Before(records[j].CreatedAt)
+		}
+		return records[i].ID < records[j].ID
+	})
+	return records, nil
+}
+
+var _ network.DNSRepository = (*DNSRepository)(nil)
@@ -0,0 +1,286 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"wirety/internal/domain/network"
+)
+
+// GroupRepository is an in-memory implementation of network.GroupRepository
+type GroupRepository struct {
+	repo  *Repository
+	store *resourceStore
+}
+
+// NewGroupRepository creates an in-memory group repository sharing state
+// with the network repository's peers, policies and routes
+func NewGroupRepository(repo *Repository) *GroupRepository {
+	return &GroupRepository{repo: repo, store: repo.resources}
+}
+
+// CreateGroup creates a new group
+func (r *GroupRepository) CreateGroup(ctx context.Context, networkID string, group *network.Group) error {
+	if _, err := r.repo.GetNetwork(ctx, networkID); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	group.NetworkID = networkID
+	group.CreatedAt = now
+	group.UpdatedAt = now
+	if group.PeerIDs == nil {
+		group.PeerIDs = []string{}
+	}
+	if group.PolicyIDs == nil {
+		group.PolicyIDs = []string{}
+	}
+	if group.RouteIDs == nil {
+		group.RouteIDs = []string{}
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, exists := r.store.groups[group.ID]; exists {
+		return fmt.Errorf("group already exists")
+	}
+	for _, g := range r.store.groups {
+		if g.NetworkID == networkID && g.Name == group.Name {
+			return fmt.Errorf("group name already exists in network")
+		}
+	}
+
+	r.store.groups[group.ID] = copyGroup(group)
+	return nil
+}
+
+// GetGroup retrieves a group by ID
+func (r *GroupRepository) GetGroup(ctx context.Context, networkID, groupID string) (*network.Group, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	g, ok := r.store.group(networkID, groupID)
+	if !ok {
+		return nil, fmt.Errorf("group not found")
+	}
+	return copyGroup(g), nil
+}
+
+// UpdateGroup updates a group's name, description and priority
+func (r *GroupRepository) UpdateGroup(ctx context.Context, networkID string, group *network.Group) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	g, ok := r.store.group(networkID, group.ID)
+	if !ok {
+		return fmt.Errorf("group not found")
+	}
+	for _, other := range r.store.groups {
+		if other.ID != group.ID && other.NetworkID == networkID && other.Name == group.Name {
+			return fmt.Errorf("group name already exists in network")
+		}
+	}
+
+	group.UpdatedAt = time.Now()
+	g.Name = group.Name
+	g.Description = group.Description
+	g.Priority = group.Priority
+	g.UpdatedAt = group.UpdatedAt
+	return nil
+}
+
+// DeleteGroup deletes a group along with its memberships and attachments
+func (r *GroupRepository) DeleteGroup(ctx context.Context, networkID, groupID string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.group(networkID, groupID); !ok {
+		return fmt.Errorf("group not found")
+	}
+	delete(r.store.groups, groupID)
+	return nil
+}
+
+// ListGroups lists all groups in a network, by priority
+func (r *GroupRepository) ListGroups(ctx context.Context, networkID string) ([]*network.Group, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	groups := make([]*network.Group, 0)
+	for _, g := range r.store.groups {
+		if g.NetworkID == networkID {
+			groups = append(groups, copyGroup(g))
+		}
+	}
+	sortGroups(groups)
+	return groups, nil
+}
+
+// AddPeerToGroup adds a peer to a group; adding a member again is a no-op
+func (r *GroupRepository) AddPeerToGroup(ctx context.Context, networkID, groupID, peerID string) error {
+	r.store.mu.RLock()
+	_, ok := r.store.group(networkID, groupID)
+	r.store.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("group not found")
+	}
+	if _, err := r.repo.GetPeer(ctx, networkID, peerID); err != nil {
+		return fmt.Errorf("peer not found")
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	g, ok := r.store.group(networkID, groupID)
+	if !ok {
+		return fmt.Errorf("group not found")
+	}
+	if !containsID(g.PeerIDs, peerID) {
+		g.PeerIDs = append(g.PeerIDs, peerID)
+	}
+	return nil
+}
+
+// RemovePeerFromGroup removes a peer from a group
+func (r *GroupRepository) RemovePeerFromGroup(ctx context.Context, networkID, groupID, peerID string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	g, ok := r.store.group(networkID, groupID)
+	if !ok {
+		return fmt.Errorf("group not found")
+	}
+	if !containsID(g.PeerIDs, peerID) {
+		return fmt.Errorf("peer not in group")
+	}
+	g.PeerIDs = removeID(g.PeerIDs, peerID)
+	return nil
+}
+
+// GetPeerGroups retrieves all groups a peer belongs to, by priority
+func (r *GroupRepository) GetPeerGroups(ctx context.Context, networkID, peerID string) ([]*network.Group, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	groups := make([]*network.Group, 0)
+	for _, g := range r.store.groups {
+		if g.NetworkID == networkID && containsID(g.PeerIDs, peerID) {
+			groups = append(groups, copyGroup(g))
+		}
+	}
+	sortGroups(groups)
+	return groups, nil
+}
+
+// AttachPolicyToGroup attaches a policy to a group after its current policies
+func (r *GroupRepository) AttachPolicyToGroup(ctx context.Context, networkID, groupID, policyID string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	g, ok := r.store.group(networkID, groupID)
+	if !ok {
+		return fmt.Errorf("group not found")
+	}
+	if _, ok := r.store.policy(networkID, policyID); !ok {
+		return fmt.Errorf("policy not found")
+	}
+	if !containsID(g.PolicyIDs, policyID) {
+		g.PolicyIDs = append(g.PolicyIDs, policyID)
+	}
+	return nil
+}
+
+// DetachPolicyFromGroup detaches a policy from a group
+func (r *GroupRepository) DetachPolicyFromGroup(ctx context.Context, networkID, groupID, policyID string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	g, ok := r.store.group(networkID, groupID)
+	if !ok {
+		return fmt.Errorf("group not found")
+	}
+	if !containsID(g.PolicyIDs, policyID) {
+		return fmt.Errorf("policy not attached to group")
+	}
+	g.PolicyIDs = removeID(g.PolicyIDs, policyID)
+	return nil
+}
+
+// ReorderGroupPolicies puts the given policies first, in the given order;
+// attached policies that are not listed keep their relative order after them
+func (r *GroupRepository) ReorderGroupPolicies(ctx context.Context, networkID, groupID string, policyIDs []string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	g, ok := r.store.group(networkID, groupID)
+	if !ok {
+		return fmt.Errorf("group not found")
+	}
+	for _, policyID := range policyIDs {
+		if !containsID(g.PolicyIDs, policyID) {
+			return fmt.Errorf("policy %s not attached to group", policyID)
+		}
+	}
+
+	ordered := make([]string, 0, len(g.PolicyIDs))
+	for _, policyID := range policyIDs {
+		if !containsID(ordered, policyID) {
+			ordered = append(ordered, policyID)
+		}
+	}
+	for _, policyID := range g.PolicyIDs {
+		if !containsID(ordered, policyID) {
+			ordered = append(ordered, policyID)
+		}
+	}
+	g.PolicyIDs = ordered
+	return nil
+}
+
+// GetGroupPolicies retrieves the policies attached to a group, in order
+func (r *GroupRepository) GetGroupPolicies(ctx context.Context, networkID, groupID string) ([]*network.Policy, error) {
+	return r.store.groupPolicies(networkID, groupID), nil
+}
+
+// AttachRouteToGroup attaches a route to a group
+func (r *GroupRepository) AttachRouteToGroup(ctx context.Context, networkID, groupID, routeID string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	g, ok := r.store.group(networkID, groupID)
+	if !ok {
+		return fmt.Errorf("group not found")
+	}
+	if _, ok := r.store.route(networkID, routeID); !ok {
+		return fmt.Errorf("route not found")
+	}
+	if !containsID(g.RouteIDs, routeID) {
+		g.RouteIDs = append(g.RouteIDs, routeID)
+	}
+	return nil
+}
+
+// DetachRouteFromGroup detaches a route from a group
+func (r *GroupRepository) DetachRouteFromGroup(ctx context.Context, networkID, groupID, routeID string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	g, ok := r.store.group(networkID, groupID)
+	if !ok {
+		return fmt.Errorf("group not found")
+	}
+	if !containsID(g.RouteIDs, routeID) {
+		return fmt.Errorf("route not attached to group")
+	}
+	g.RouteIDs = removeID(g.RouteIDs, routeID)
+	return nil
+}
+
+// GetGroupRoutes retrieves the routes attached to a group, oldest first
+func (r *GroupRepository) GetGroupRoutes(ctx context.Context, networkID, groupID string) ([]*network.Route, error) {
+	return r.store.groupRoutes(networkID, groupID), nil
+}
+
+var _ network.GroupRepository = (*GroupRepository)(nil)
@@ -4,6 +4,9 @@ import (
 	"context"
 	"fmt"
 	"net"
+	"sort"
+	"sync"
+	"time"
 
 	"wirety/internal/domain/ipam"
 	"wirety/internal/domain/network"
@@ -14,11 +17,19 @@ import (
 // IPAMRepository is an in-memory implementation of ipam.Repository backed by go-ipam.
 type IPAMRepository struct {
 	engine goipam.Ipamer
+
+	mu           sync.Mutex
+	reservations map[string]map[string]bool      // cidr -> reserved IPs
+	allocations  map[string]map[string]time.Time // cidr -> allocated IP -> allocation time
 }
 
 // NewIPAMRepository creates a new in-memory IPAM repository.
 func NewIPAMRepository(ctx context.Context) *IPAMRepository {
-	return &IPAMRepository{engine: goipam.New(ctx)}
+	return &IPAMRepository{
+		engine:       goipam.New(ctx),
+		reservations: make(map[string]map[string]bool),
+		allocations:  make(map[string]map[string]time.Time),
+	}
 }
 
 // EnsureRootPrefix ensures a root prefix exists (creates if missing).
@@ -100,16 +111,99 @@ func (r *IPAMRepository) ListChildPrefixes(ctx context.Context, parentCIDR strin
 }
 
 func (r *IPAMRepository) AcquireIP(ctx context.Context, cidr string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 	ipObj, err := r.engine.AcquireIP(ctx, cidr)
 	if err != nil {
 		return "", err
 	}
+	r.recordAllocation(cidr, ipObj.IP.String())
 	return ipObj.IP.String(), nil
 }
 
 func (r *IPAMRepository) ReleaseIP(ctx context.Context, cidr string, ip string) error {
-	return r.engine.ReleaseIPFromPrefix(ctx, cidr, ip)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.engine.ReleaseIPFromPrefix(ctx, cidr, ip); err != nil {
+		return err
+	}
+	delete(r.allocations[cidr], ip)
+	return nil
+}
+
+func (r *IPAMRepository) AcquireSpecificIP(ctx context.Context, cidr string, ip string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, err := r.engine.AcquireSpecificIP(ctx, cidr, ip); err != nil {
+		return err
+	}
+	r.recordAllocation(cidr, ip)
+	return nil
+}
+
+// recordAllocation notes an allocation for ListAllocatedIPs; r.mu is held.
+func (r *IPAMRepository) recordAllocation(cidr, ip string) {
+	if r.allocations[cidr] == nil {
+		r.allocations[cidr] = make(map[string]time.Time)
+	}
+	r.allocations[cidr][ip] = time.Now()
+}
+
+func (r *IPAMRepository) ListAllocatedIPs(ctx context.Context, cidr string) ([]network.IPAllocation, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]network.IPAllocation, 0, len(r.allocations[cidr]))
+	for ip, at := range r.allocations[cidr] {
+		out = append(out, network.IPAllocation{IP: ip, AllocatedAt: at})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].IP < out[j].IP })
+	return out, nil
+}
+
+func (r *IPAMRepository) ReserveIP(ctx context.Context, cidr string, ip string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, err := r.engine.AcquireSpecificIP(ctx, cidr, ip); err != nil {
+		return err
+	}
+	if r.reservations[cidr] == nil {
+		r.reservations[cidr] = make(map[string]bool)
+	}
+	r.reservations[cidr][ip] = true
+	return nil
+}
+
+func (r *IPAMRepository) ReleaseReservation(ctx context.Context, cidr string, ip string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.reservations[cidr][ip] {
+		return network.ErrReservationNotFound
+	}
+	if err := r.engine.ReleaseIPFromPrefix(ctx, cidr, ip); err != nil {
+		return err
+	}
+	delete(r.reservations[cidr], ip)
+	return nil
+}
+
+func (r *IPAMRepository) ListReservations(ctx context.Context, cidr string) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]string, 0, len(r.reservations[cidr]))
+	for ip := range r.reservations[cidr] {
+		out = append(out, ip)
+	}
+	sort.Strings(out)
+	return out, nil
 }
 
 // Interface compliance assertion
 var _ ipam.Repository = (*IPAMRepository)(nil)
+
+func (r *IPAMRepository) AcquiredIPs(ctx context.Context, cidr string) (uint64, error) {
+	p, err := r.engine.PrefixFrom(ctx, cidr)
+	if err != nil {
+		return 0, fmt.Errorf("prefix not found: %w", err)
+	}
+	return p.Usage().AcquiredIPs, nil
+}
@@ -0,0 +1,80 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"wirety/internal/domain/network"
+)
+
+func TestIPAMRepository_ReservedIPIsNeverAcquired(t *testing.T) {
+	ctx := context.Background()
+	repo := NewIPAMRepository(ctx)
+	if _, err := repo.EnsureRootPrefix(ctx, "10.0.0.0/29"); err != nil {
+		t.Fatalf("EnsureRootPrefix: %v", err)
+	}
+	if err := repo.ReserveIP(ctx, "10.0.0.0/29", "10.0.0.1"); err != nil {
+		t.Fatalf("ReserveIP: %v", err)
+	}
+	if err := repo.ReserveIP(ctx, "10.0.0.0/29", "10.0.0.1"); err == nil {
+		t.Fatal("reserving the same IP twice should fail")
+	}
+
+	// A /29 has six host addresses; one is reserved.
+	for range 5 {
+		ip, err := repo.AcquireIP(ctx, "10.0.0.0/29")
+		if err != nil {
+			t.Fatalf("AcquireIP: %v", err)
+		}
+		if ip == "10.0.0.1" {
+			t.Fatal("AcquireIP handed out a reserved address")
+		}
+	}
+	if _, err := repo.AcquireIP(ctx, "10.0.0.0/29"); err == nil {
+		t.Fatal("expected the pool to be exhausted")
+	}
+
+	reserved, _ := repo.ListReservations(ctx, "10.0.0.0/29")
+	if len(reserved) != 1 || reserved[0] != "10.0.0.1" {
+		t.Fatalf("ListReservations = %v", reserved)
+	}
+	if err := repo.ReleaseReservation(ctx, "10.0.0.0/29", "10.0.0.2"); !errors.Is(err, network.ErrReservationNotFound) {
+		t.Fatalf("releasing an allocated, unreserved IP: expected ErrReservationNotFound, got %v", err)
+	}
+	if err := repo.ReleaseReservation(ctx, "10.0.0.0/29", "10.0.0.1"); err != nil {
+		t.Fatalf("ReleaseReservation: %v", err)
+	}
+	if ip, err := repo.AcquireIP(ctx, "10.0.0.0/29"); err != nil || ip != "10.0.0.1" {
+		t.Fatalf("released reservation should be acquirable, got %q, %v", ip, err)
+	}
+}
+
+func TestIPAMRepository_ListAllocatedIPs(t *testing.T) {
+	ctx := context.Background()
+	repo := NewIPAMRepository(ctx)
+	if _, err := repo.EnsureRootPrefix(ctx, "10.0.0.0/29"); err != nil {
+		t.Fatalf("EnsureRootPrefix: %v", err)
+	}
+	if err := repo.ReserveIP(ctx, "10.0.0.0/29", "10.0.0.1"); err != nil {
+		t.Fatalf("ReserveIP: %v", err)
+	}
+	ip, err := repo.AcquireIP(ctx, "10.0.0.0/29")
+	if err != nil {
+		t.Fatalf("AcquireIP: %v", err)
+	}
+	if err := repo.AcquireSpecificIP(ctx, "10.0.0.0/29", "10.0.0.5"); err != nil {
+		t.Fatalf("AcquireSpecificIP: %v", err)
+	}
+
+	allocs, _ := repo.ListAllocatedIPs(ctx, "10.0.0.0/29")
+	if len(allocs) != 2 || allocs[0].IP != ip || allocs[1].IP != "10.0.0.5" || allocs[0].AllocatedAt.IsZero() {
+		t.Fatalf("ListAllocatedIPs = %+v, want %s and 10.0.0.5 without the reservation", allocs, ip)
+	}
+	if err := repo.ReleaseIP(ctx, "10.0.0.0/29", "10.0.0.5"); err != nil {
+		t.Fatalf("ReleaseIP: %v", err)
+	}
+	if allocs, _ := repo.ListAllocatedIPs(ctx, "10.0.0.0/29"); len(allocs) != 1 {
+		t.Fatalf("released address still listed: %+v", allocs)
+	}
+}
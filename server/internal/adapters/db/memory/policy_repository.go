@@ -0,0 +1,180 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"wirety/internal/domain/network"
+)
+
+// PolicyRepository is an in-memory implementation of network.PolicyRepository
+type PolicyRepository struct {
+	repo  *Repository
+	store *resourceStore
+}
+
+// NewPolicyRepository creates an in-memory policy repository sharing state
+// with the network repository's groups
+func NewPolicyRepository(repo *Repository) *PolicyRepository {
+	return &PolicyRepository{repo: repo, store: repo.resources}
+}
+
+// CreatePolicy creates a new policy with its rules
+func (r *PolicyRepository) CreatePolicy(ctx context.Context, networkID string, policy *network.Policy) error {
+	if _, err := r.repo.GetNetwork(ctx, networkID); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	policy.NetworkID = networkID
+	policy.CreatedAt = now
+	policy.UpdatedAt = now
+	if policy.Rules == nil {
+		policy.Rules = []network.PolicyRule{}
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, exists := r.store.policies[policy.ID]; exists {
+		return fmt.Errorf("policy already exists")
+	}
+	for _, p := range r.store.policies {
+		if p.NetworkID == networkID && p.Name == policy.Name {
+			return fmt.Errorf("policy name already exists in network")
+		}
+	}
+
+	r.store.policies[policy.ID] = copyPolicy(policy)
+	return nil
+}
+
+// GetPolicy retrieves a policy by ID
+func (r *PolicyRepository) GetPolicy(ctx context.Context, networkID, policyID string) (*network.Policy, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	p, ok := r.store.policy(networkID, policyID)
+	if !ok {
+		return nil, fmt.Errorf("policy not found")
+	}
+	return copyPolicy(p), nil
+}
+
+// UpdatePolicy updates a policy's name and description
+func (r *PolicyRepository) UpdatePolicy(ctx context.Context, networkID string, policy *network.Policy) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	p, ok := r.store.policy(networkID, policy.ID)
+	if !ok {
+		return fmt.Errorf("policy not found")
+	}
+	for _, other := range r.store.policies {
+		if other.ID != policy.ID && other.NetworkID == networkID && other.Name == policy.Name {
+			return fmt.Errorf("policy name already exists in network")
+		}
+	}
+
+	policy.UpdatedAt = time.Now()
+	p.Name = policy.Name
+	p.Description = policy.Description
+	p.UpdatedAt = policy.UpdatedAt
+	return nil
+}
+
+// DeletePolicy deletes a policy and detaches it from every group
+func (r *PolicyRepository) DeletePolicy(ctx context.Context, networkID, policyID string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.policy(networkID, policyID); !ok {
+		return fmt.Errorf("policy not found")
+	}
+	delete(r.store.policies, policyID)
+	for _, g := range r.store.groups {
+		g.PolicyIDs = removeID(g.PolicyIDs, policyID)
+	}
+	return nil
+}
+
+// ListPolicies lists all policies in a network, oldest first
+func (r *PolicyRepository) ListPolicies(ctx context.Context, networkID string) ([]*network.Policy, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	policies := make([]*network.Policy, 0)
+	for _, p := range r.store.policies {
+		if p.NetworkID == networkID {
+			policies = append(policies, copyPolicy(p))
+		}
+	}
+	sort.Slice(policies, func(i, j int) bool {
+		if !policies[i].CreatedAt.Equal(policies[j].CreatedAt) {
+			return policies[i].CreatedAt.Before(policies[j].CreatedAt)
+		}
+		return policies[i].ID < policies[j].ID
+	})
+	return policies, nil
+}
+
+// AddRuleToPolicy appends a rule to a policy
+func (r *PolicyRepository) AddRuleToPolicy(ctx context.Context, networkID, policyID string, rule *network.PolicyRule) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	p, ok := r.store.policy(networkID, policyID)
+	if !ok {
+		return fmt.Errorf("policy not found")
+	}
+	p.Rules = append(p.Rules, *rule)
+	p.UpdatedAt = time.Now()
+	return nil
+}
+
+// RemoveRuleFromPolicy removes a rule from a policy
+func (r *PolicyRepository) RemoveRuleFromPolicy(ctx context.Context, networkID, policyID, ruleID string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	p, ok := r.store.policy(networkID, policyID)
+	if !ok {
+		return fmt.Errorf("policy not found")
+	}
+	for i, rule := range p.Rules {
+		if rule.ID == ruleID {
+			p.Rules = append(p.Rules[:i:i], p.Rules[i+1:]...)
+			p.UpdatedAt = time.Now()
+			return nil
+		}
+	}
+	return fmt.Errorf("rule not found")
+}
+
+// UpdateRule replaces a rule of a policy, keeping its position
+func (r *PolicyRepository) UpdateRule(ctx context.Context, networkID, policyID string, rule *network.PolicyRule) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	p, ok := r.store.policy(networkID, policyID)
+	if !ok {
+		return fmt.Errorf("policy not found")
+	}
+	for i := range p.Rules {
+		if p.Rules[i].ID == rule.ID {
+			p.Rules[i] = *rule
+			p.UpdatedAt = time.Now()
+			return nil
+		}
+	}
+	return fmt.Errorf("rule not found")
+}
+
+// GetPoliciesForGroup retrieves the policies attached to a group, in order
+func (r *PolicyRepository) GetPoliciesForGroup(ctx context.Context, networkID, groupID string) ([]*network.Policy, error) {
+	return r.store.groupPolicies(networkID, groupID), nil
+}
+
+var _ network.PolicyRepository = (*PolicyRepository)(nil)
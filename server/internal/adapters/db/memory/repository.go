@@ -3,13 +3,17 @@ package memory
 import (
 	"context"
 	"fmt"
+	"sort"
 	"sync"
 	"time"
 
 	"wirety/internal/domain/network"
 )
 
-// Repository is an in-memory implementation of the network repository
+// Repository is an in-memory implementation of the network repository.  It
+// is safe for concurrent use: every method holds mu, and networks, peers,
+// connections, sessions and tokens are copied in and out so that callers
+// never share state with the store.
 type Repository struct {
 	mu               sync.RWMutex
 	networks         map[string]*network.Network
@@ -21,6 +25,9 @@ type Repository struct {
 	endpointDenylist map[string][]*network.EndpointDenylistEntry   // "networkID:jumpPeerID" -> entries
 	quarantine       map[string]*network.CaptivePortalQuarantine   // "networkID:peerID" -> quarantine state
 	peerRoutes       map[string]map[string][]string                // networkID -> peerID -> AllowedIPs
+	tempRoutes       map[string]*network.TempRoute                 // id -> TempRoute
+	profiles         map[string]*network.PeerProfile               // id -> PeerProfile
+	resources        *resourceStore                                // groups, policies, routes, DNS mappings
 }
 
 // NewRepository creates a new in-memory repository
@@ -29,12 +36,45 @@ func NewRepository() *Repository {
 		networks:    make(map[string]*network.Network),
 		connections: make(map[string]map[string]*network.PeerConnection),
 		sessions:    make(map[string]map[string]*network.AgentSession),
+		resources:   newResourceStore(),
 	}
 	return repo
 }
 
 // (IPAM operations removed - now handled by dedicated IPAM repository)
 
+// copyNetwork returns a copy of net with its own peer map and peers, and the
+// peer count filled in.
+func copyNetwork(net *network.Network) *network.Network {
+	c := *net
+	c.Peers = make(map[string]*network.Peer, len(net.Peers))
+	for id, peer := range net.Peers {
+		c.Peers[id] = copyPeer(peer)
+	}
+	c.PeerCount = len(c.Peers)
+	return &c
+}
+
+func copyPeer(peer *network.Peer) *network.Peer {
+	c := *peer
+	return &c
+}
+
+func copyConnection(conn *network.PeerConnection) *network.PeerConnection {
+	c := *conn
+	return &c
+}
+
+func copySession(session *network.AgentSession) *network.AgentSession {
+	c := *session
+	return &c
+}
+
+func copyCaptivePortalToken(token *network.CaptivePortalToken) *network.CaptivePortalToken {
+	c := *token
+	return &c
+}
+
 // CreateNetwork creates a new network
 func (r *Repository) CreateNetwork(ctx context.Context, net *network.Network) error {
 	r.mu.Lock()
@@ -44,7 +84,7 @@ func (r *Repository) CreateNetwork(ctx context.Context, net *network.Network) er
 		return fmt.Errorf("network already exists")
 	}
 
-	r.networks[net.ID] = net
+	r.networks[net.ID] = copyNetwork(net)
 	return nil
 }
 
@@ -54,24 +94,27 @@ func (r *Repository) GetNetwork(ctx context.Context, networkID string) (*network
 	defer r.mu.RUnlock()
 
 	net, exists := r.networks[networkID]
-	if !exists {
-		return nil, fmt.Errorf("network not found")
+	if !exists || net.DeletedAt != nil {
+		return nil, network.ErrNetworkNotFound
 	}
-	net.PeerCount = len(net.Peers)
 
-	return net, nil
+	return copyNetwork(net), nil
 }
 
-// UpdateNetwork updates a network
+// UpdateNetwork updates a network's settings.  Its peers are left alone, as
+// they are changed through the peer methods.
 func (r *Repository) UpdateNetwork(ctx context.Context, net *network.Network) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	if _, exists := r.networks[net.ID]; !exists {
-		return fmt.Errorf("network not found")
+	existing, exists := r.networks[net.ID]
+	if !exists {
+		return network.ErrNetworkNotFound
 	}
 
-	r.networks[net.ID] = net
+	updated := *net
+	updated.Peers = existing.Peers
+	r.networks[net.ID] = &updated
 	return nil
 }
 
@@ -81,10 +124,16 @@ func (r *Repository) DeleteNetwork(ctx context.Context, networkID string) error
 	defer r.mu.Unlock()
 
 	if _, exists := r.networks[networkID]; !exists {
-		return fmt.Errorf("network not found")
+		return network.ErrNetworkNotFound
 	}
 
 	delete(r.networks, networkID)
+	for id, p := range r.profiles {
+		if p.NetworkID == networkID {
+			delete(r.profiles, id)
+		}
+	}
+	r.resources.networkDeleted(networkID)
 	return nil
 }
 
@@ -95,8 +144,55 @@ func (r *Repository) ListNetworks(ctx context.Context) ([]*network.Network, erro
 
 	networks := make([]*network.Network, 0, len(r.networks))
 	for _, net := range r.networks {
-		net.PeerCount = len(net.Peers)
-		networks = append(networks, net)
+		if net.DeletedAt != nil {
+			continue
+		}
+		networks = append(networks, copyNetwork(net))
+	}
+
+	return networks, nil
+}
+
+// SoftDeleteNetwork marks a network deleted at the given time
+func (r *Repository) SoftDeleteNetwork(ctx context.Context, networkID string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	net, exists := r.networks[networkID]
+	if !exists || net.DeletedAt != nil {
+		return network.ErrNetworkNotFound
+	}
+	net.DeletedAt = &at
+	return nil
+}
+
+// RestoreNetwork clears the deletion mark of a soft-deleted network
+func (r *Repository) RestoreNetwork(ctx context.Context, networkID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	net, exists := r.networks[networkID]
+	if !exists {
+		return network.ErrNetworkNotFound
+	}
+	if net.DeletedAt == nil {
+		return network.ErrNetworkNotDeleted
+	}
+	net.DeletedAt = nil
+	return nil
+}
+
+// ListDeletedNetworks retrieves the soft-deleted networks
+func (r *Repository) ListDeletedNetworks(ctx context.Context) ([]*network.Network, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	networks := make([]*network.Network, 0)
+	for _, net := range r.networks {
+		if net.DeletedAt == nil {
+			continue
+		}
+		networks = append(networks, copyNetwork(net))
 	}
 
 	return networks, nil
@@ -109,14 +205,14 @@ func (r *Repository) CreatePeer(ctx context.Context, networkID string, peer *net
 
 	net, exists := r.networks[networkID]
 	if !exists {
-		return fmt.Errorf("network not found")
+		return network.ErrNetworkNotFound
 	}
 
 	if _, exists := net.Peers[peer.ID]; exists {
 		return fmt.Errorf("peer already exists")
 	}
 
-	net.AddPeer(peer)
+	net.AddPeer(copyPeer(peer))
 	return nil
 }
 
@@ -127,15 +223,15 @@ func (r *Repository) GetPeer(ctx context.Context, networkID, peerID string) (*ne
 
 	net, exists := r.networks[networkID]
 	if !exists {
-		return nil, fmt.Errorf("network not found")
+		return nil, network.ErrNetworkNotFound
 	}
 
 	peer, exists := net.GetPeer(peerID)
 	if !exists {
-		return nil, fmt.Errorf("peer not found")
+		return nil, network.ErrPeerNotFound
 	}
 
-	return peer, nil
+	return copyPeer(peer), nil
 }
 
 // GetPeerByToken finds a peer by its enrollment token
@@ -144,9 +240,12 @@ func (r *Repository) GetPeerByToken(ctx context.Context, token string) (string,
 	defer r.mu.RUnlock()
 
 	for networkID, net := range r.networks {
+		if net.DeletedAt != nil {
+			continue
+		}
 		for _, peer := range net.Peers {
 			if peer.Token == token {
-				return networkID, peer, nil
+				return networkID, copyPeer(peer), nil
 			}
 		}
 	}
@@ -161,14 +260,14 @@ func (r *Repository) UpdatePeer(ctx context.Context, networkID string, peer *net
 
 	net, exists := r.networks[networkID]
 	if !exists {
-		return fmt.Errorf("network not found")
+		return network.ErrNetworkNotFound
 	}
 
 	if _, exists := net.Peers[peer.ID]; !exists {
-		return fmt.Errorf("peer not found")
+		return network.ErrPeerNotFound
 	}
 
-	net.AddPeer(peer)
+	net.AddPeer(copyPeer(peer))
 	return nil
 }
 
@@ -179,14 +278,15 @@ func (r *Repository) DeletePeer(ctx context.Context, networkID, peerID string) e
 
 	net, exists := r.networks[networkID]
 	if !exists {
-		return fmt.Errorf("network not found")
+		return network.ErrNetworkNotFound
 	}
 
 	if _, exists := net.Peers[peerID]; !exists {
-		return fmt.Errorf("peer not found")
+		return network.ErrPeerNotFound
 	}
 
 	net.RemovePeer(peerID)
+	r.resources.peerDeleted(networkID, peerID)
 	return nil
 }
 
@@ -197,10 +297,52 @@ func (r *Repository) ListPeers(ctx context.Context, networkID string) ([]*networ
 
 	net, exists := r.networks[networkID]
 	if !exists {
-		return nil, fmt.Errorf("network not found")
+		return nil, network.ErrNetworkNotFound
 	}
 
-	return net.GetAllPeers(), nil
+	peers := net.GetAllPeers()
+	for i, peer := range peers {
+		peers[i] = copyPeer(peer)
+	}
+	return peers, nil
+}
+
+// SearchPeers returns the peers of the live networks matching query
+func (r *Repository) SearchPeers(ctx context.Context, query string, offset, limit int) ([]*network.PeerSearchResult, int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var results []*network.PeerSearchResult
+	for _, net := range r.networks {
+		if net.DeletedAt != nil {
+			continue
+		}
+		for _, peer := range net.Peers {
+			if peer.MatchesSearch(query) {
+				results = append(results, &network.PeerSearchResult{NetworkID: net.ID, NetworkName: net.Name, Peer: copyPeer(peer)})
+			}
+		}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if a.NetworkName != b.NetworkName {
+			return a.NetworkName < b.NetworkName
+		}
+		if a.Peer.Name != b.Peer.Name {
+			return a.Peer.Name < b.Peer.Name
+		}
+		return a.Peer.ID < b.Peer.ID
+	})
+
+	total := len(results)
+	if offset > total {
+		offset = total
+	}
+	end := offset + limit
+	if end > total {
+		end = total
+	}
+	return results[offset:end], total, nil
 }
 
 // CreateACL creates an ACL for a network
@@ -242,7 +384,7 @@ func (r *Repository) CreateConnection(ctx context.Context, networkID string, con
 	}
 
 	key := connectionKey(conn.Peer1ID, conn.Peer2ID)
-	r.connections[networkID][key] = conn
+	r.connections[networkID][key] = copyConnection(conn)
 	return nil
 }
 
@@ -261,7 +403,7 @@ func (r *Repository) GetConnection(ctx context.Context, networkID, peer1ID, peer
 		return nil, fmt.Errorf("connection not found")
 	}
 
-	return conn, nil
+	return copyConnection(conn), nil
 }
 
 // ListConnections retrieves all connections in a network
@@ -272,13 +414,31 @@ func (r *Repository) ListConnections(ctx context.Context, networkID string) ([]*
 	conns := make([]*network.PeerConnection, 0)
 	if r.connections[networkID] != nil {
 		for _, conn := range r.connections[networkID] {
-			conns = append(conns, conn)
+			conns = append(conns, copyConnection(conn))
 		}
 	}
 
 	return conns, nil
 }
 
+// UpdateConnection replaces the preshared key, AllowedIPs override and
+// severed flag of an existing connection
+func (r *Repository) UpdateConnection(ctx context.Context, networkID string, conn *network.PeerConnection) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := connectionKey(conn.Peer1ID, conn.Peer2ID)
+	existing, exists := r.connections[networkID][key]
+	if !exists {
+		return fmt.Errorf("connection not found")
+	}
+	existing.PresharedKey = conn.PresharedKey
+	existing.AllowedIPs = conn.AllowedIPs
+	existing.AllowedIPsPeerID = conn.AllowedIPsPeerID
+	existing.Severed = conn.Severed
+	return nil
+}
+
 // DeleteConnection removes a connection between two peers
 func (r *Repository) DeleteConnection(ctx context.Context, networkID, peer1ID, peer2ID string) error {
 	r.mu.Lock()
@@ -304,25 +464,27 @@ func (r *Repository) CreateOrUpdateSession(ctx context.Context, networkID string
 		r.sessions[networkID] = make(map[string]*network.AgentSession)
 	}
 
-	r.sessions[networkID][session.SessionID] = session
+	r.sessions[networkID][session.SessionID] = copySession(session)
 	return nil
 }
 
-// GetSession retrieves a specific session by session ID
-func (r *Repository) GetSession(ctx context.Context, networkID, sessionID string) (*network.AgentSession, error) {
+// GetSession retrieves the most recent non-revoked session of a peer (same
+// semantics as the Postgres repository)
+func (r *Repository) GetSession(ctx context.Context, networkID, peerID string) (*network.AgentSession, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	if r.sessions[networkID] == nil {
-		return nil, fmt.Errorf("session not found")
+	var latest *network.AgentSession
+	for _, session := range r.sessions[networkID] {
+		if session.PeerID == peerID && !session.IsRevoked() && (latest == nil || session.LastSeen.After(latest.LastSeen)) {
+			latest = session
+		}
 	}
-
-	session, exists := r.sessions[networkID][sessionID]
-	if !exists {
+	if latest == nil {
 		return nil, fmt.Errorf("session not found")
 	}
 
-	return session, nil
+	return copySession(latest), nil
 }
 
 // GetActiveSessionsForPeer retrieves all active sessions for a specific peer
@@ -336,8 +498,8 @@ func (r *Repository) GetActiveSessionsForPeer(ctx context.Context, networkID, pe
 	}
 
 	for _, session := range r.sessions[networkID] {
-		if session.PeerID == peerID {
-			sessions = append(sessions, session)
+		if session.PeerID == peerID && !session.IsRevoked() {
+			sessions = append(sessions, copySession(session))
 		}
 	}
 
@@ -368,12 +530,46 @@ func (r *Repository) ListSessions(ctx context.Context, networkID string) ([]*net
 	}
 
 	for _, session := range r.sessions[networkID] {
-		sessions = append(sessions, session)
+		if !session.IsRevoked() {
+			sessions = append(sessions, copySession(session))
+		}
 	}
 
 	return sessions, nil
 }
 
+// ListSessionHistory lists every session ever recorded for a peer, including
+// revoked ones, most recent first
+func (r *Repository) ListSessionHistory(ctx context.Context, networkID, peerID string) ([]*network.AgentSession, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var sessions []*network.AgentSession
+	for _, session := range r.sessions[networkID] {
+		if session.PeerID == peerID {
+			sessions = append(sessions, copySession(session))
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].FirstSeen.After(sessions[j].FirstSeen)
+	})
+
+	return sessions, nil
+}
+
+// RevokeSession marks a session as revoked
+func (r *Repository) RevokeSession(ctx context.Context, networkID, sessionID string, revokedAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	session, exists := r.sessions[networkID][sessionID]
+	if !exists {
+		return network.ErrSessionNotFound
+	}
+	session.RevokedAt = &revokedAt
+	return nil
+}
+
 
 // Captive portal whitelist operations
 
@@ -462,7 +658,7 @@ func (r *Repository) CreateCaptivePortalToken(ctx context.Context, token *networ
 		r.captiveTokens = make(map[string]*network.CaptivePortalToken)
 	}
 
-	r.captiveTokens[token.Token] = token
+	r.captiveTokens[token.Token] = copyCaptivePortalToken(token)
 	return nil
 }
 
@@ -489,7 +685,7 @@ func (r *Repository) GetCaptivePortalToken(ctx context.Context, tokenStr string)
 		return nil, fmt.Errorf("token not found")
 	}
 
-	return token, nil
+	return copyCaptivePortalToken(token), nil
 }
 
 func (r *Repository) DeleteCaptivePortalToken(ctx context.Context, tokenStr string) error {
@@ -601,6 +797,8 @@ func (r *Repository) AddEndpointDenylist(ctx context.Context, e *network.Endpoin
 		e.ExpiresAt = e.CreatedAt.Add(network.EndpointDenylistDefaultTTL)
 	}
 	key := e.NetworkID + ":" + e.JumpPeerID
+	cp := *e
+	e = &cp
 	// Replace any existing matching entry.
 	entries := r.endpointDenylist[key]
 	for i, existing := range entries {
@@ -749,3 +947,121 @@ func (r *Repository) ListPeerLocalRoutes(ctx context.Context, networkID string)
 	return out, nil
 }
 
+// Temporary routes (in-memory)
+func (r *Repository) CreateTempRoute(ctx context.Context, route *network.TempRoute) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.tempRoutes == nil {
+		r.tempRoutes = make(map[string]*network.TempRoute)
+	}
+	cp := *route
+	r.tempRoutes[route.ID] = &cp
+	return nil
+}
+
+func (r *Repository) ListTempRoutes(ctx context.Context, networkID, peerID string) ([]*network.TempRoute, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]*network.TempRoute, 0)
+	for _, t := range r.tempRoutes {
+		if t.NetworkID == networkID && (peerID == "" || t.PeerID == peerID) {
+			cp := *t
+			out = append(out, &cp)
+		}
+	}
+	return out, nil
+}
+
+func (r *Repository) DeleteTempRoute(ctx context.Context, networkID, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t, ok := r.tempRoutes[id]
+	if !ok || t.NetworkID != networkID {
+		return network.ErrTempRouteNotFound
+	}
+	delete(r.tempRoutes, id)
+	return nil
+}
+
+func (r *Repository) ListExpiredTempRoutes(ctx context.Context, before time.Time) ([]*network.TempRoute, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]*network.TempRoute, 0)
+	for _, t := range r.tempRoutes {
+		if !t.ExpiresAt.After(before) {
+			cp := *t
+			out = append(out, &cp)
+		}
+	}
+	return out, nil
+}
+
+// Peer profiles (in-memory)
+func (r *Repository) CreatePeerProfile(ctx context.Context, profile *network.PeerProfile) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.profiles == nil {
+		r.profiles = make(map[string]*network.PeerProfile)
+	}
+	for _, p := range r.profiles {
+		if p.NetworkID == profile.NetworkID && p.Name == profile.Name {
+			return network.ErrDuplicatePeerProfileName
+		}
+	}
+	cp := *profile
+	r.profiles[profile.ID] = &cp
+	return nil
+}
+
+func (r *Repository) GetPeerProfile(ctx context.Context, networkID, profileID string) (*network.PeerProfile, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	p, ok := r.profiles[profileID]
+	if !ok || p.NetworkID != networkID {
+		return nil, network.ErrPeerProfileNotFound
+	}
+	cp := *p
+	return &cp, nil
+}
+
+func (r *Repository) UpdatePeerProfile(ctx context.Context, profile *network.PeerProfile) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	existing, ok := r.profiles[profile.ID]
+	if !ok || existing.NetworkID != profile.NetworkID {
+		return network.ErrPeerProfileNotFound
+	}
+	for _, p := range r.profiles {
+		if p.ID != profile.ID && p.NetworkID == profile.NetworkID && p.Name == profile.Name {
+			return network.ErrDuplicatePeerProfileName
+		}
+	}
+	cp := *profile
+	r.profiles[profile.ID] = &cp
+	return nil
+}
+
+func (r *Repository) DeletePeerProfile(ctx context.Context, networkID, profileID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	p, ok := r.profiles[profileID]
+	if !ok || p.NetworkID != networkID {
+		return network.ErrPeerProfileNotFound
+	}
+	delete(r.profiles, profileID)
+	return nil
+}
+
+func (r *Repository) ListPeerProfiles(ctx context.Context, networkID string) ([]*network.PeerProfile, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]*network.PeerProfile, 0)
+	for _, p := range r.profiles {
+		if p.NetworkID == networkID {
+			cp := *p
+			out = append(out, &cp)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out, nil
+}
@@ -0,0 +1,129 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"wirety/internal/domain/network"
+)
+
+// TestRepository_ConcurrentAccess hammers the repository the way concurrent
+// API requests do.  Run with -race: callers read and modify what they get
+// back while other goroutines write.
+func TestRepository_ConcurrentAccess(t *testing.T) {
+	ctx := context.Background()
+	repo := NewRepository()
+	const workers, peers = 8, 20
+	if err := repo.CreateNetwork(ctx, &network.Network{ID: "shared", Name: "shared", Peers: map[string]*network.Peer{}}); err != nil {
+		t.Fatalf("CreateNetwork: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, workers*peers*4)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			netID := fmt.Sprintf("net-%d", w)
+			if err := repo.CreateNetwork(ctx, &network.Network{ID: netID, Name: netID, Peers: map[string]*network.Peer{}}); err != nil {
+				errs <- err
+				return
+			}
+			for p := 0; p < peers; p++ {
+				// Every worker also adds peers to the shared network
+				for _, target := range []string{netID, "shared"} {
+					peer := &network.Peer{ID: fmt.Sprintf("peer-%d-%d", w, p), Name: "peer", Address: fmt.Sprintf("10.0.%d.%d", w, p)}
+					if err := repo.CreatePeer(ctx, target, peer); err != nil {
+						errs <- err
+					}
+				}
+
+				nets, err := repo.ListNetworks(ctx)
+				if err != nil {
+					errs <- err
+					continue
+				}
+				for _, n := range nets {
+					for _, peer := range n.Peers {
+						peer.Name = "renamed"
+					}
+					n.Peers["scratch"] = &network.Peer{ID: "scratch"}
+				}
+
+				n, err := repo.GetNetwork(ctx, netID)
+				if err != nil {
+					errs <- err
+					continue
+				}
+				n.Name = fmt.Sprintf("%s-%d", netID, p)
+				if err := repo.UpdateNetwork(ctx, n); err != nil {
+					errs <- err
+				}
+				if _, err := repo.ListPeers(ctx, "shared"); err != nil {
+					errs <- err
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("concurrent access: %v", err)
+	}
+
+	nets, err := repo.ListNetworks(ctx)
+	if err != nil {
+		t.Fatalf("ListNetworks: %v", err)
+	}
+	if len(nets) != workers+1 {
+		t.Fatalf("got %d networks, want %d", len(nets), workers+1)
+	}
+	for _, n := range nets {
+		want := peers
+		if n.ID == "shared" {
+			want = workers * peers
+		}
+		if n.PeerCount != want {
+			t.Errorf("%s has %d peers, want %d", n.ID, n.PeerCount, want)
+		}
+		for _, peer := range n.Peers {
+			if peer.Name != "peer" {
+				t.Errorf("%s: peer %s was changed through a returned copy", n.ID, peer.ID)
+			}
+		}
+	}
+}
+
+func TestRepository_SearchPeersSkipsDeletedNetworks(t *testing.T) {
+	ctx := context.Background()
+	repo := NewRepository()
+	for _, id := range []string{"live", "deleted"} {
+		if err := repo.CreateNetwork(ctx, &network.Network{ID: id, Name: id}); err != nil {
+			t.Fatal(err)
+		}
+		if err := repo.CreatePeer(ctx, id, &network.Peer{ID: id + "-peer", Name: "laptop"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := repo.SoftDeleteNetwork(ctx, "deleted", time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	results, total, err := repo.SearchPeers(ctx, "LAP", 0, 10)
+	if err != nil {
+		t.Fatalf("SearchPeers: %v", err)
+	}
+	if total != 1 || len(results) != 1 || results[0].Peer.ID != "live-peer" {
+		t.Fatalf("SearchPeers = %d results of %d, want live-peer only", len(results), total)
+	}
+	results[0].Peer.Name = "changed"
+	if p, _ := repo.GetPeer(ctx, "live", "live-peer"); p.Name != "laptop" {
+		t.Error("search result shares the stored peer")
+	}
+	if results, total, _ := repo.SearchPeers(ctx, "laptop", 5, 10); total != 1 || len(results) != 0 {
+		t.Errorf("offset past the end = %d results of %d, want 0 of 1", len(results), total)
+	}
+}
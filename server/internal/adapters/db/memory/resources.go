@@ -0,0 +1,229 @@
+package memory
+
+import (
+	"sort"
+	"sync"
+
+	"wirety/internal/domain/network"
+)
+
+// resourceStore holds the groups, policies, routes and DNS mappings shared by
+// the in-memory Group/Policy/Route/DNS repositories. They reference each other
+// (and peers) the way the Postgres tables do through foreign keys, so they
+// live in one store with one lock. Memberships are kept on the group itself
+// (PeerIDs, PolicyIDs, RouteIDs) in the order Postgres returns them.
+//
+// Lock order: Repository.mu before resourceStore.mu. Repository methods are
+// never called while holding resourceStore.mu.
+type resourceStore struct {
+	mu       sync.RWMutex
+	groups   map[string]*network.Group      // groupID -> group
+	policies map[string]*network.Policy     // policyID -> policy
+	routes   map[string]*network.Route      // routeID -> route
+	dns      map[string]*network.DNSMapping // mappingID -> mapping
+
+	networkDNS map[string]*network.NetworkDNSRecord // recordID -> record
+}
+
+func newResourceStore() *resourceStore {
+	return &resourceStore{
+		groups:   make(map[string]*network.Group),
+		policies: make(map[string]*network.Policy),
+		routes:   make(map[string]*network.Route),
+		dns:      make(map[string]*network.DNSMapping),
+
+		networkDNS: make(map[string]*network.NetworkDNSRecord),
+	}
+}
+
+// peerDeleted drops the peer's group memberships and the routes it is the
+// jump peer of (ON DELETE CASCADE in Postgres).
+func (s *resourceStore) peerDeleted(networkID, peerID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, g := range s.groups {
+		if g.NetworkID == networkID {
+			g.PeerIDs = removeID(g.PeerIDs, peerID)
+		}
+	}
+	for id, rt := range s.routes {
+		if rt.NetworkID == networkID && rt.JumpPeerID == peerID {
+			s.deleteRouteLocked(id)
+		}
+	}
+}
+
+// networkDeleted drops everything belonging to the network.
+func (s *resourceStore) networkDeleted(networkID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, g := range s.groups {
+		if g.NetworkID == networkID {
+			delete(s.groups, id)
+		}
+	}
+	for id, p := range s.policies {
+		if p.NetworkID == networkID {
+			delete(s.policies, id)
+		}
+	}
+	for id, rt := range s.routes {
+		if rt.NetworkID == networkID {
+			s.deleteRouteLocked(id)
+		}
+	}
+	for id, rec := range s.networkDNS {
+		if rec.NetworkID == networkID {
+			delete(s.networkDNS, id)
+		}
+	}
+}
+
+// deleteRouteLocked removes a route, its DNS mappings and its group
+// attachments. s.mu must be held.
+func (s *resourceStore) deleteRouteLocked(routeID string) {
+	delete(s.routes, routeID)
+	for id, m := range s.dns {
+		if m.RouteID == routeID {
+			delete(s.dns, id)
+		}
+	}
+	for _, g := range s.groups {
+		g.RouteIDs = removeID(g.RouteIDs, routeID)
+	}
+}
+
+// group returns the group if it exists in the network. s.mu must be held.
+func (s *resourceStore) group(networkID, groupID string) (*network.Group, bool) {
+	g, ok := s.groups[groupID]
+	if !ok || g.NetworkID != networkID {
+		return nil, false
+	}
+	return g, true
+}
+
+// policy returns the policy if it exists in the network. s.mu must be held.
+func (s *resourceStore) policy(networkID, policyID string) (*network.Policy, bool) {
+	p, ok := s.policies[policyID]
+	if !ok || p.NetworkID != networkID {
+		return nil, false
+	}
+	return p, true
+}
+
+// route returns the route if it exists in the network. s.mu must be held.
+func (s *resourceStore) route(networkID, routeID string) (*network.Route, bool) {
+	rt, ok := s.routes[routeID]
+	if !ok || rt.NetworkID != networkID {
+		return nil, false
+	}
+	return rt, true
+}
+
+// groupPolicies returns copies of the policies attached to a group, in order.
+func (s *resourceStore) groupPolicies(networkID, groupID string) []*network.Policy {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	policies := make([]*network.Policy, 0)
+	g, ok := s.group(networkID, groupID)
+	if !ok {
+		return policies
+	}
+	for _, policyID := range g.PolicyIDs {
+		if p, ok := s.policy(networkID, policyID); ok {
+			policies = append(policies, copyPolicy(p))
+		}
+	}
+	return policies
+}
+
+// groupRoutes returns copies of the routes attached to a group, oldest first.
+func (s *resourceStore) groupRoutes(networkID, groupID string) []*network.Route {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	routes := make([]*network.Route, 0)
+	g, ok := s.group(networkID, groupID)
+	if !ok {
+		return routes
+	}
+	for _, routeID := range g.RouteIDs {
+		if rt, ok := s.route(networkID, routeID); ok {
+			routes = append(routes, copyRoute(rt))
+		}
+	}
+	sortRoutes(routes)
+	return routes
+}
+
+// Callers get copies so that mutating a returned value has no effect until it
+// is written back, as with the Postgres repositories.
+
+func copyGroup(g *network.Group) *network.Group {
+	c := *g
+	c.PeerIDs = append([]string{}, g.PeerIDs...)
+	c.PolicyIDs = append([]string{}, g.PolicyIDs...)
+	c.RouteIDs = append([]string{}, g.RouteIDs...)
+	return &c
+}
+
+func copyPolicy(p *network.Policy) *network.Policy {
+	c := *p
+	c.Rules = append([]network.PolicyRule{}, p.Rules...)
+	return &c
+}
+
+func copyRoute(rt *network.Route) *network.Route {
+	c := *rt
+	return &c
+}
+
+func copyDNSMapping(m *network.DNSMapping) *network.DNSMapping {
+	c := *m
+	return &c
+}
+
+// sortGroups orders groups by priority, then creation time.
+func sortGroups(groups []*network.Group) {
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Priority != groups[j].Priority {
+			return groups[i].Priority < groups[j].Priority
+		}
+		if !groups[i].CreatedAt.Equal(groups[j].CreatedAt) {
+			return groups[i].CreatedAt.Before(groups[j].CreatedAt)
+		}
+		return groups[i].ID < groups[j].ID
+	})
+}
+
+// sortRoutes orders routes by creation time.
+func sortRoutes(routes []*network.Route) {
+	sort.Slice(routes, func(i, j int) bool {
+		if !routes[i].CreatedAt.Equal(routes[j].CreatedAt) {
+			return routes[i].CreatedAt.Before(routes[j].CreatedAt)
+		}
+		return routes[i].ID < routes[j].ID
+	})
+}
+
+func containsID(ids []string, id string) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+
+func removeID(ids []string, id string) []string {
+	out := ids[:0]
+	for _, v := range ids {
+		if v != id {
+			out = append(out, v)
+		}
+	}
+	return out
+}
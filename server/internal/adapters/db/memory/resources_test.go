@@ -0,0 +1,151 @@
+package memory
+
+import (
+	"context"
+	"testing"
+
+	"wirety/internal/domain/network"
+)
+
+func setupResources(t *testing.T) (*Repository, *GroupRepository, *PolicyRepository, *RouteRepository, *DNSRepository) {
+	t.Helper()
+	ctx := context.Background()
+	repo := NewRepository()
+	if err := repo.CreateNetwork(ctx, &network.Network{ID: "net", Name: "net", CIDR: "10.0.0.0/24", Peers: map[string]*network.Peer{}}); err != nil {
+		t.Fatalf("create network: %v", err)
+	}
+	for _, p := range []*network.Peer{
+		{ID: "jump", Name: "jump", Address: "10.0.0.1", IsJump: true},
+		{ID: "client", Name: "client", Address: "10.0.0.2"},
+	} {
+		if err := repo.CreatePeer(ctx, "net", p); err != nil {
+			t.Fatalf("create peer: %v", err)
+		}
+	}
+	return repo, NewGroupRepository(repo), NewPolicyRepository(repo), NewRouteRepository(repo), NewDNSRepository(repo)
+}
+
+func TestResources_GroupMembershipAndPolicies(t *testing.T) {
+	ctx := context.Background()
+	_, groups, policies, _, _ := setupResources(t)
+
+	if err := groups.CreateGroup(ctx, "net", &network.Group{ID: "g1", Name: "ops"}); err != nil {
+		t.Fatalf("create group: %v", err)
+	}
+	if err := groups.CreateGroup(ctx, "net", &network.Group{ID: "g2", Name: "ops"}); err == nil {
+		t.Fatal("expected duplicate group name to be rejected")
+	}
+	if err := groups.AddPeerToGroup(ctx, "net", "g1", "missing"); err == nil {
+		t.Fatal("expected unknown peer to be rejected")
+	}
+	if err := groups.AddPeerToGroup(ctx, "net", "g1", "client"); err != nil {
+		t.Fatalf("add peer: %v", err)
+	}
+
+	for _, id := range []string{"p1", "p2"} {
+		if err := policies.CreatePolicy(ctx, "net", &network.Policy{ID: id, Name: id}); err != nil {
+			t.Fatalf("create policy: %v", err)
+		}
+		if err := groups.AttachPolicyToGroup(ctx, "net", "g1", id); err != nil {
+			t.Fatalf("attach policy: %v", err)
+		}
+	}
+	if err := groups.ReorderGroupPolicies(ctx, "net", "g1", []string{"p2"}); err != nil {
+		t.Fatalf("reorder: %v", err)
+	}
+	got, _ := policies.GetPoliciesForGroup(ctx, "net", "g1")
+	if len(got) != 2 || got[0].ID != "p2" || got[1].ID != "p1" {
+		t.Fatalf("unexpected policy order: %+v", got)
+	}
+
+	if err := policies.DeletePolicy(ctx, "net", "p2"); err != nil {
+		t.Fatalf("delete policy: %v", err)
+	}
+	g, _ := groups.GetGroup(ctx, "net", "g1")
+	if len(g.PolicyIDs) != 1 || g.PolicyIDs[0] != "p1" {
+		t.Fatalf("deleted policy still attached: %v", g.PolicyIDs)
+	}
+
+	// Returned values are copies.
+	g.PeerIDs = nil
+	g, _ = groups.GetGroup(ctx, "net", "g1")
+	if len(g.PeerIDs) != 1 {
+		t.Fatalf("mutating a returned group changed the store: %v", g.PeerIDs)
+	}
+}
+
+func TestResources_RoutesAndDNS(t *testing.T) {
+	ctx := context.Background()
+	_, groups, _, routes, dns := setupResources(t)
+
+	if err := routes.CreateRoute(ctx, "net", &network.Route{ID: "r", Name: "lan", DestinationCIDR: "192.168.1.0/24", JumpPeerID: "client"}); err == nil {
+		t.Fatal("expected non-jump peer to be rejected")
+	}
+	rt := &network.Route{ID: "r", Name: "lan", DestinationCIDR: "192.168.1.0/24", JumpPeerID: "jump"}
+	if err := routes.CreateRoute(ctx, "net", rt); err != nil {
+		t.Fatalf("create route: %v", err)
+	}
+	if rt.DomainSuffix != "internal" {
+		t.Fatalf("expected default domain suffix, got %q", rt.DomainSuffix)
+	}
+
+	if err := dns.CreateDNSMapping(ctx, "r", &network.DNSMapping{ID: "d", Name: "nas", IPAddress: "10.1.1.1"}); err == nil {
+		t.Fatal("expected address outside the route CIDR to be rejected")
+	}
+	if err := dns.CreateDNSMapping(ctx, "r", &network.DNSMapping{ID: "d", Name: "nas", IPAddress: "192.168.1.10"}); err != nil {
+		t.Fatalf("create mapping: %v", err)
+	}
+	if err := dns.CreateDNSMapping(ctx, "r", &network.DNSMapping{ID: "d2", Name: "nas", IPAddress: "192.168.1.11"}); err == nil {
+		t.Fatal("expected duplicate DNS name to be rejected")
+	}
+
+	if err := groups.CreateGroup(ctx, "net", &network.Group{ID: "g", Name: "ops"}); err != nil {
+		t.Fatalf("create group: %v", err)
+	}
+	if err := groups.AttachRouteToGroup(ctx, "net", "g", "r"); err != nil {
+		t.Fatalf("attach route: %v", err)
+	}
+	if got, _ := routes.GetRoutesForGroup(ctx, "net", "g"); len(got) != 1 {
+		t.Fatalf("expected one group route, got %d", len(got))
+	}
+	if got, _ := dns.GetNetworkDNSMappings(ctx, "net"); len(got) != 1 {
+		t.Fatalf("expected one network mapping, got %d", len(got))
+	}
+}
+
+func TestResources_CascadeOnPeerAndNetworkDelete(t *testing.T) {
+	ctx := context.Background()
+	repo, groups, policies, routes, dns := setupResources(t)
+
+	_ = groups.CreateGroup(ctx, "net", &network.Group{ID: "g", Name: "ops"})
+	_ = groups.AddPeerToGroup(ctx, "net", "g", "client")
+	_ = groups.AddPeerToGroup(ctx, "net", "g", "jump")
+	_ = routes.CreateRoute(ctx, "net", &network.Route{ID: "r", Name: "lan", DestinationCIDR: "192.168.1.0/24", JumpPeerID: "jump"})
+	_ = groups.AttachRouteToGroup(ctx, "net", "g", "r")
+	_ = dns.CreateDNSMapping(ctx, "r", &network.DNSMapping{ID: "d", Name: "nas", IPAddress: "192.168.1.10"})
+	_ = policies.CreatePolicy(ctx, "net", &network.Policy{ID: "p", Name: "p"})
+
+	if err := repo.DeletePeer(ctx, "net", "jump"); err != nil {
+		t.Fatalf("delete peer: %v", err)
+	}
+	g, _ := groups.GetGroup(ctx, "net", "g")
+	if len(g.PeerIDs) != 1 || len(g.RouteIDs) != 0 {
+		t.Fatalf("peer deletion did not cascade to group: %+v", g)
+	}
+	if _, err := routes.GetRoute(ctx, "net", "r"); err == nil {
+		t.Fatal("expected the jump peer's route to be deleted")
+	}
+	if _, err := dns.GetDNSMapping(ctx, "r", "d"); err == nil {
+		t.Fatal("expected the route's DNS mapping to be deleted")
+	}
+
+	if err := repo.DeleteNetwork(ctx, "net"); err != nil {
+		t.Fatalf("delete network: %v", err)
+	}
+	if _, err := groups.GetGroup(ctx, "net", "g"); err == nil {
+		t.Fatal("expected group to be deleted with its network")
+	}
+	if _, err := policies.GetPolicy(ctx, "net", "p"); err == nil {
+		t.Fatal("expected policy to be deleted with its network")
+	}
+}
@@ -0,0 +1,152 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"wirety/internal/domain/network"
+)
+
+// RouteRepository is an in-memory implementation of network.RouteRepository
+type RouteRepository struct {
+	repo  *Repository
+	store *resourceStore
+}
+
+// NewRouteRepository creates an in-memory route repository sharing state
+// with the network repository's peers and groups
+func NewRouteRepository(repo *Repository) *RouteRepository {
+	return &RouteRepository{repo: repo, store: repo.resources}
+}
+
+// checkJumpPeer verifies the peer exists in the network and is a jump peer
+func (r *RouteRepository) checkJumpPeer(ctx context.Context, networkID, peerID string) error {
+	peer, err := r.repo.GetPeer(ctx, networkID, peerID)
+	if err != nil {
+		return fmt.Errorf("jump peer not found")
+	}
+	if !peer.IsJump {
+		return fmt.Errorf("peer is not a jump peer")
+	}
+	return nil
+}
+
+// CreateRoute creates a new route.  A route without a jump peer (a cloned
+// one) is accepted.
+func (r *RouteRepository) CreateRoute(ctx context.Context, networkID string, route *network.Route) error {
+	if route.JumpPeerID != "" {
+		if err := r.checkJumpPeer(ctx, networkID, route.JumpPeerID); err != nil {
+			return err
+		}
+	}
+
+	now := time.Now()
+	route.NetworkID = networkID
+	route.CreatedAt = now
+	route.UpdatedAt = now
+	if route.DomainSuffix == "" {
+		route.DomainSuffix = "internal"
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, exists := r.store.routes[route.ID]; exists {
+		return fmt.Errorf("route already exists")
+	}
+	for _, rt := range r.store.routes {
+		if rt.NetworkID == networkID && rt.Name == route.Name {
+			return fmt.Errorf("route name already exists in network")
+		}
+	}
+
+	r.store.routes[route.ID] = copyRoute(route)
+	return nil
+}
+
+// GetRoute retrieves a route by ID
+func (r *RouteRepository) GetRoute(ctx context.Context, networkID, routeID string) (*network.Route, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	rt, ok := r.store.route(networkID, routeID)
+	if !ok {
+		return nil, fmt.Errorf("route not found")
+	}
+	return copyRoute(rt), nil
+}
+
+// UpdateRoute updates an existing route
+func (r *RouteRepository) UpdateRoute(ctx context.Context, networkID string, route *network.Route) error {
+	if route.JumpPeerID != "" {
+		if err := r.checkJumpPeer(ctx, networkID, route.JumpPeerID); err != nil {
+			return err
+		}
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	rt, ok := r.store.route(networkID, route.ID)
+	if !ok {
+		return fmt.Errorf("route not found")
+	}
+	for _, other := range r.store.routes {
+		if other.ID != route.ID && other.NetworkID == networkID && other.Name == route.Name {
+			return fmt.Errorf("route name already exists in network")
+		}
+	}
+
+	route.UpdatedAt = time.Now()
+	createdAt := rt.CreatedAt
+	*rt = *route
+	rt.NetworkID = networkID
+	rt.CreatedAt = createdAt
+	return nil
+}
+
+// DeleteRoute deletes a route along with its DNS mappings and group attachments
+func (r *RouteRepository) DeleteRoute(ctx context.Context, networkID, routeID string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.route(networkID, routeID); !ok {
+		return fmt.Errorf("route not found")
+	}
+	r.store.deleteRouteLocked(routeID)
+	return nil
+}
+
+// ListRoutes lists all routes in a network, oldest first
+func (r *RouteRepository) ListRoutes(ctx context.Context, networkID string) ([]*network.Route, error) {
+	return r.filter(func(rt *network.Route) bool { return rt.NetworkID == networkID }), nil
+}
+
+// GetRoutesForGroup retrieves the routes attached to a group, oldest first
+func (r *RouteRepository) GetRoutesForGroup(ctx context.Context, networkID, groupID string) ([]*network.Route, error) {
+	return r.store.groupRoutes(networkID, groupID), nil
+}
+
+// GetRoutesByJumpPeer retrieves the routes using a jump peer, oldest first
+func (r *RouteRepository) GetRoutesByJumpPeer(ctx context.Context, networkID, jumpPeerID string) ([]*network.Route, error) {
+	return r.filter(func(rt *network.Route) bool {
+		return rt.NetworkID == networkID && rt.JumpPeerID == jumpPeerID
+	}), nil
+}
+
+func (r *RouteRepository) filter(keep func(*network.Route) bool) []*network.Route {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	routes := make([]*network.Route, 0)
+	for _, rt := range r.store.routes {
+		if keep(rt) {
+			routes = append(routes, copyRoute(rt))
+		}
+	}
+	sortRoutes(routes)
+	return routes
+}
+
+var _ network.RouteRepository = (*RouteRepository)(nil)
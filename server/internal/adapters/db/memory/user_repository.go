@@ -8,7 +8,9 @@ import (
 	"wirety/internal/domain/auth"
 )
 
-// UserRepository is an in-memory implementation of the auth repository
+// UserRepository is an in-memory implementation of the auth repository.
+// Users, sessions and API tokens are copied in and out, so that callers never
+// share state with the store.
 type UserRepository struct {
 	mu           sync.RWMutex
 	users        map[string]*auth.User            // userID -> User
@@ -33,6 +35,22 @@ func NewUserRepository() *UserRepository {
 	}
 }
 
+func copyUser(user *auth.User) *auth.User {
+	c := *user
+	c.AuthorizedNetworks = append([]string{}, user.AuthorizedNetworks...)
+	return &c
+}
+
+func copyAuthSession(session *auth.Session) *auth.Session {
+	c := *session
+	return &c
+}
+
+func copyAPIToken(token *auth.APIToken) *auth.APIToken {
+	c := *token
+	return &c
+}
+
 // GetUser retrieves a user by their OIDC subject ID
 func (r *UserRepository) GetUser(userID string) (*auth.User, error) {
 	r.mu.RLock()
@@ -42,7 +60,7 @@ func (r *UserRepository) GetUser(userID string) (*auth.User, error) {
 	if !exists {
 		return nil, fmt.Errorf("user not found")
 	}
-	return user, nil
+	return copyUser(user), nil
 }
 
 // GetUserByEmail retrieves a user by their email
@@ -54,7 +72,7 @@ func (r *UserRepository) GetUserByEmail(email string) (*auth.User, error) {
 	if !exists {
 		return nil, fmt.Errorf("user not found")
 	}
-	return user, nil
+	return copyUser(user), nil
 }
 
 // CreateUser creates a new user
@@ -66,8 +84,9 @@ func (r *UserRepository) CreateUser(user *auth.User) error {
 		return fmt.Errorf("user already exists")
 	}
 
-	r.users[user.ID] = user
-	r.usersByEmail[user.Email] = user
+	c := copyUser(user)
+	r.users[user.ID] = c
+	r.usersByEmail[user.Email] = c
 	return nil
 }
 
@@ -83,10 +102,11 @@ func (r *UserRepository) UpdateUser(user *auth.User) error {
 	// Update email index if email changed
 	if oldUser := r.users[user.ID]; oldUser.Email != user.Email {
 		delete(r.usersByEmail, oldUser.Email)
-		r.usersByEmail[user.Email] = user
 	}
 
-	r.users[user.ID] = user
+	c := copyUser(user)
+	r.users[user.ID] = c
+	r.usersByEmail[user.Email] = c
 	return nil
 }
 
@@ -112,7 +132,7 @@ func (r *UserRepository) ListUsers() ([]*auth.User, error) {
 
 	users := make([]*auth.User, 0, len(r.users))
 	for _, user := range r.users {
-		users = append(users, user)
+		users = append(users, copyUser(user))
 	}
 	return users, nil
 }
@@ -128,7 +148,7 @@ func (r *UserRepository) GetFirstUser() (*auth.User, error) {
 
 	// Return any user (map iteration is random but that's okay)
 	for _, user := range r.users {
-		return user, nil
+		return copyUser(user), nil
 	}
 	return nil, fmt.Errorf("no users found")
 }
@@ -141,7 +161,9 @@ func (r *UserRepository) GetDefaultPermissions() (*auth.DefaultNetworkPermission
 	if r.defaultPerms == nil {
 		return nil, fmt.Errorf("default permissions not set")
 	}
-	return r.defaultPerms, nil
+	c := *r.defaultPerms
+	c.DefaultAuthorizedNetworks = append([]string{}, r.defaultPerms.DefaultAuthorizedNetworks...)
+	return &c, nil
 }
 
 // SetDefaultPermissions sets default permissions for new users
@@ -149,7 +171,9 @@ func (r *UserRepository) SetDefaultPermissions(perms *auth.DefaultNetworkPermiss
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	r.defaultPerms = perms
+	c := *perms
+	c.DefaultAuthorizedNetworks = append([]string{}, perms.DefaultAuthorizedNetworks...)
+	r.defaultPerms = &c
 	return nil
 }
 
@@ -168,7 +192,7 @@ func (r *UserRepository) CreateSession(session *auth.Session) error {
 		return fmt.Errorf("session already exists")
 	}
 
-	r.sessions[session.SessionHash] = session
+	r.sessions[session.SessionHash] = copyAuthSession(session)
 	return nil
 }
 
@@ -185,7 +209,7 @@ func (r *UserRepository) GetSession(sessionHash string) (*auth.Session, error) {
 	if !exists {
 		return nil, fmt.Errorf("session not found")
 	}
-	return session, nil
+	return copyAuthSession(session), nil
 }
 
 // UpdateSession updates an existing session
@@ -201,7 +225,7 @@ func (r *UserRepository) UpdateSession(session *auth.Session) error {
 		return fmt.Errorf("session not found")
 	}
 
-	r.sessions[session.SessionHash] = session
+	r.sessions[session.SessionHash] = copyAuthSession(session)
 	return nil
 }
 
@@ -245,8 +269,9 @@ func (r *UserRepository) CreateAPIToken(token *auth.APIToken) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	token.CreatedAt = time.Now()
-	r.apiTokens[token.ID] = token
-	r.tokensByHash[token.TokenHash] = token
+	c := copyAPIToken(token)
+	r.apiTokens[token.ID] = c
+	r.tokensByHash[token.TokenHash] = c
 	return nil
 }
 
@@ -257,7 +282,7 @@ func (r *UserRepository) GetAPITokenByHash(hash string) (*auth.APIToken, error)
 	if !ok {
 		return nil, fmt.Errorf("api token not found")
 	}
-	return t, nil
+	return copyAPIToken(t), nil
 }
 
 func (r *UserRepository) ListAPITokens(userID string) ([]*auth.APIToken, error) {
@@ -266,7 +291,7 @@ func (r *UserRepository) ListAPITokens(userID string) ([]*auth.APIToken, error)
 	var out []*auth.APIToken
 	for _, t := range r.apiTokens {
 		if t.UserID == userID {
-			out = append(out, t)
+			out = append(out, copyAPIToken(t))
 		}
 	}
 	return out, nil
@@ -0,0 +1,59 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"wirety/internal/audit"
+)
+
+// AuditRepository is a PostgreSQL implementation of audit.AuditLogger
+type AuditRepository struct {
+	db tracedDB
+}
+
+// NewAuditRepository constructs a new AuditRepository
+func NewAuditRepository(db *sql.DB) *AuditRepository {
+	return &AuditRepository{db: traced(db)}
+}
+
+func (r *AuditRepository) Record(ctx context.Context, e *audit.Entry) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO audit_log (id, actor_id, actor_email, action, network_id, peer_id, resource_id, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`, e.ID, e.ActorID, e.ActorEmail, e.Action, e.NetworkID, e.PeerID, e.ResourceID, e.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("record audit entry: %w", err)
+	}
+	return nil
+}
+
+func (r *AuditRepository) List(ctx context.Context, networkID string, offset, limit int) ([]*audit.Entry, int, error) {
+	var total int
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM audit_log WHERE network_id=$1`, networkID).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("count audit entries: %w", err)
+	}
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, actor_id, actor_email, action, network_id, peer_id, resource_id, created_at
+		FROM audit_log WHERE network_id=$1
+		ORDER BY created_at DESC, id
+		LIMIT $2 OFFSET $3
+	`, networkID, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("list audit entries: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	out := make([]*audit.Entry, 0)
+	for rows.Next() {
+		e := &audit.Entry{}
+		if err := rows.Scan(&e.ID, &e.ActorID, &e.ActorEmail, &e.Action, &e.NetworkID, &e.PeerID, &e.ResourceID, &e.CreatedAt); err != nil {
+			return nil, 0, err
+		}
+		out = append(out, e)
+	}
+	return out, total, rows.Err()
+}
+
+var _ audit.AuditLogger = (*AuditRepository)(nil)
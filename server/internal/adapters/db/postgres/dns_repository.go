@@ -14,12 +14,12 @@ import (
 
 // DNSRepository is a PostgreSQL implementation of network.DNSRepository
 type DNSRepository struct {
-	db *sql.DB
+	db tracedDB
 }
 
 // NewDNSRepository constructs a new DNSRepository
 func NewDNSRepository(db *sql.DB) *DNSRepository {
-	return &DNSRepository{db: db}
+	return &DNSRepository{db: traced(db)}
 }
 
 // dnsMappingColumns is the column list every SELECT for dns_mappings must use,
@@ -236,3 +236,121 @@ func (r *DNSRepository) GetNetworkDNSMappings(ctx context.Context, networkID str
 
 	return mappings, rows.Err()
 }
+
+// networkDNSRecordColumns is the column list every SELECT for
+// network_dns_records uses, in the order scanNetworkDNSRecord expects.
+const networkDNSRecordColumns = "id, network_id, name, ip_address, ip_address_v6, record_type, value, created_at, updated_at"
+
+func scanNetworkDNSRecord(s interface{ Scan(...interface{}) error }, rec *network.NetworkDNSRecord) error {
+	var ip4, ip6 sql.NullString
+	if err := s.Scan(&rec.ID, &rec.NetworkID, &rec.Name, &ip4, &ip6, &rec.Type, &rec.Value, &rec.CreatedAt, &rec.UpdatedAt); err != nil {
+		return err
+	}
+	rec.IPAddress = strFromNull(ip4)
+	rec.IPv6Address = strFromNull(ip6)
+	return nil
+}
+
+// CreateNetworkDNSRecord creates a new network-scoped DNS record
+func (r *DNSRepository) CreateNetworkDNSRecord(ctx context.Context, record *network.NetworkDNSRecord) error {
+	now := time.Now()
+	record.CreatedAt = now
+	record.UpdatedAt = now
+
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO network_dns_records (id, network_id, name, ip_address, ip_address_v6, record_type, value, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`,
+		record.ID, record.NetworkID, record.Name,
+		nullStr(record.IPAddress), nullStr(record.IPv6Address),
+		record.RecordType(), record.Value,
+		record.CreatedAt, record.UpdatedAt)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return network.ErrDuplicateNetworkDNSName
+		}
+		return fmt.Errorf("create network DNS record: %w", err)
+	}
+	return nil
+}
+
+// GetNetworkDNSRecord retrieves a network-scoped DNS record by ID
+func (r *DNSRepository) GetNetworkDNSRecord(ctx context.Context, networkID, recordID string) (*network.NetworkDNSRecord, error) {
+	var rec network.NetworkDNSRecord
+	row := r.db.QueryRowContext(ctx, `
+		SELECT `+networkDNSRecordColumns+`
+		FROM network_dns_records
+		WHERE id = $1 AND network_id = $2
+	`, recordID, networkID)
+	if err := scanNetworkDNSRecord(row, &rec); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, network.ErrNetworkDNSRecordNotFound
+		}
+		return nil, fmt.Errorf("get network DNS record: %w", err)
+	}
+	return &rec, nil
+}
+
+// UpdateNetworkDNSRecord updates an existing network-scoped DNS record
+func (r *DNSRepository) UpdateNetworkDNSRecord(ctx context.Context, record *network.NetworkDNSRecord) error {
+	record.UpdatedAt = time.Now()
+
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE network_dns_records
+		SET name = $3, ip_address = $4, ip_address_v6 = $5, record_type = $6, value = $7, updated_at = $8
+		WHERE id = $1 AND network_id = $2
+	`,
+		record.ID, record.NetworkID, record.Name,
+		nullStr(record.IPAddress), nullStr(record.IPv6Address),
+		record.RecordType(), record.Value,
+		record.UpdatedAt)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return network.ErrDuplicateNetworkDNSName
+		}
+		return fmt.Errorf("update network DNS record: %w", err)
+	}
+	if rows, _ := res.RowsAffected(); rows == 0 {
+		return network.ErrNetworkDNSRecordNotFound
+	}
+	return nil
+}
+
+// DeleteNetworkDNSRecord deletes a network-scoped DNS record
+func (r *DNSRepository) DeleteNetworkDNSRecord(ctx context.Context, networkID, recordID string) error {
+	res, err := r.db.ExecContext(ctx, `
+		DELETE FROM network_dns_records
+		WHERE id = $1 AND network_id = $2
+	`, recordID, networkID)
+	if err != nil {
+		return fmt.Errorf("delete network DNS record: %w", err)
+	}
+	if rows, _ := res.RowsAffected(); rows == 0 {
+		return network.ErrNetworkDNSRecordNotFound
+	}
+	return nil
+}
+
+// ListNetworkDNSRecords lists the network-scoped DNS records of a network
+func (r *DNSRepository) ListNetworkDNSRecords(ctx context.Context, networkID string) ([]*network.NetworkDNSRecord, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+networkDNSRecordColumns+`
+		FROM network_dns_records
+		WHERE network_id = $1
+		ORDER BY created_at ASC
+	`, networkID)
+	if err != nil {
+		return nil, fmt.Errorf("list network DNS records: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	records := make([]*network.NetworkDNSRecord, 0)
+	for rows.Next() {
+		var rec network.NetworkDNSRecord
+		if err := scanNetworkDNSRecord(rows, &rec); err != nil {
+			return nil, fmt.Errorf("scan network DNS record: %w", err)
+		}
+		records = append(records, &rec)
+	}
+	return records, rows.Err()
+}
@@ -14,12 +14,12 @@ import (
 
 // GroupRepository is a PostgreSQL implementation of network.GroupRepository
 type GroupRepository struct {
-	db *sql.DB
+	db tracedDB
 }
 
 // NewGroupRepository constructs a new GroupRepository
 func NewGroupRepository(db *sql.DB) *GroupRepository {
-	return &GroupRepository{db: db}
+	return &GroupRepository{db: traced(db)}
 }
 
 // CreateGroup creates a new group in the database
@@ -669,7 +669,7 @@ func (r *GroupRepository) DetachRouteFromGroup(ctx context.Context, networkID, g
 // GetGroupRoutes retrieves all routes attached to a group
 func (r *GroupRepository) GetGroupRoutes(ctx context.Context, networkID, groupID string) ([]*network.Route, error) {
 	rows, err := r.db.QueryContext(ctx, `
-		SELECT r.id, r.network_id, r.name, r.description, r.destination_cidr, r.destination_cidr_v6, r.jump_peer_id, r.domain_suffix, r.created_at, r.updated_at
+		SELECT r.id, r.network_id, r.name, r.description, r.destination_cidr, r.destination_cidr_v6, r.jump_peer_id, r.domain_suffix, r.created_at, r.updated_at, r.metric, r.global_route
 		FROM routes r
 		INNER JOIN group_routes gr ON r.id = gr.route_id
 		WHERE gr.group_id = $1 AND r.network_id = $2
@@ -683,13 +683,9 @@ func (r *GroupRepository) GetGroupRoutes(ctx context.Context, networkID, groupID
 	routes := make([]*network.Route, 0)
 	for rows.Next() {
 		var r network.Route
-		var cidr, cidrV6 sql.NullString
-		err = rows.Scan(&r.ID, &r.NetworkID, &r.Name, &r.Description, &cidr, &cidrV6, &r.JumpPeerID, &r.DomainSuffix, &r.CreatedAt, &r.UpdatedAt)
-		if err != nil {
+		if err := scanRoute(rows, &r); err != nil {
 			return nil, fmt.Errorf("scan route: %w", err)
 		}
-		r.DestinationCIDR = strFromNull(cidr)
-		r.DestinationCIDRv6 = strFromNull(cidrV6)
 		routes = append(routes, &r)
 	}
 
@@ -763,7 +759,7 @@ func (r *GroupRepository) loadGroupRouteIDs(ctx context.Context, groupID string)
 
 func (r *GroupRepository) loadPolicyRules(ctx context.Context, policyID string) ([]network.PolicyRule, error) {
 	rows, err := r.db.QueryContext(ctx, `
-		SELECT id, direction, action, target, target_type, description
+		SELECT id, direction, action, target, target_type, protocol, ports, description
 		FROM policy_rules
 		WHERE policy_id = $1
 		ORDER BY rule_order ASC
@@ -776,7 +772,7 @@ func (r *GroupRepository) loadPolicyRules(ctx context.Context, policyID string)
 	rules := make([]network.PolicyRule, 0)
 	for rows.Next() {
 		var rule network.PolicyRule
-		err = rows.Scan(&rule.ID, &rule.Direction, &rule.Action, &rule.Target, &rule.TargetType, &rule.Description)
+		err = rows.Scan(&rule.ID, &rule.Direction, &rule.Action, &rule.Target, &rule.TargetType, &rule.Protocol, &rule.Ports, &rule.Description)
 		if err != nil {
 			return nil, fmt.Errorf("scan policy rule: %w", err)
 		}
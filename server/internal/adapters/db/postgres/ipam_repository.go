@@ -15,13 +15,13 @@ import (
 // It keeps an in-memory go-ipam engine for allocation logic and persists state
 // (prefixes and allocated IPs) to SQL tables.
 type IPAMRepository struct {
-	db     *sql.DB
+	db     tracedDB
 	engine goipam.Ipamer
 }
 
 // NewIPAMRepository creates a repository and loads existing state.
 func NewIPAMRepository(ctx context.Context, db *sql.DB) (*IPAMRepository, error) {
-	r := &IPAMRepository{db: db, engine: goipam.New(ctx)}
+	r := &IPAMRepository{db: traced(db), engine: goipam.New(ctx)}
 
 	// Load prefixes first
 	rows, err := db.QueryContext(ctx, `SELECT cidr FROM ipam_prefixes ORDER BY cidr`)
@@ -60,6 +60,24 @@ func NewIPAMRepository(ctx context.Context, db *sql.DB) (*IPAMRepository, error)
 			_, _ = r.engine.AcquireSpecificIP(ctx, prefix, ip)
 		}
 	}
+
+	// Reserved IPs are taken out of the pool the same way
+	resRows, err := db.QueryContext(ctx, `SELECT prefix_cidr, ip FROM ipam_reservations`)
+	if err == nil {
+		defer func() {
+			_ = resRows.Close()
+		}()
+		for resRows.Next() {
+			var prefix, ip string
+			if err = resRows.Scan(&prefix, &ip); err != nil {
+				return nil, err
+			}
+			if _, err2 := r.engine.PrefixFrom(ctx, prefix); err2 != nil {
+				_, _ = r.engine.NewPrefix(ctx, prefix)
+			}
+			_, _ = r.engine.AcquireSpecificIP(ctx, prefix, ip)
+		}
+	}
 	return r, nil
 }
 
@@ -218,5 +236,80 @@ func (r *IPAMRepository) ReleaseIP(ctx context.Context, cidr string, ip string)
 	return nil
 }
 
+// ReserveIP takes a specific IP out of the pool and records it as reserved
+func (r *IPAMRepository) ReserveIP(ctx context.Context, cidr string, ip string) error {
+	if _, err := r.engine.PrefixFrom(ctx, cidr); err != nil {
+		return fmt.Errorf("prefix missing: %w", err)
+	}
+	if _, err := r.engine.AcquireSpecificIP(ctx, cidr, ip); err != nil {
+		return err
+	}
+	if _, err := r.db.ExecContext(ctx, `INSERT INTO ipam_reservations (prefix_cidr, ip, created_at) VALUES ($1,$2,NOW())`, cidr, ip); err != nil {
+		_ = r.engine.ReleaseIPFromPrefix(ctx, cidr, ip)
+		return fmt.Errorf("persist reservation: %w", err)
+	}
+	return nil
+}
+
+func (r *IPAMRepository) ReleaseReservation(ctx context.Context, cidr string, ip string) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM ipam_reservations WHERE prefix_cidr=$1 AND ip=$2`, cidr, ip)
+	if err != nil {
+		return fmt.Errorf("delete reservation: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return network.ErrReservationNotFound
+	}
+	return r.engine.ReleaseIPFromPrefix(ctx, cidr, ip)
+}
+
+func (r *IPAMRepository) ListReservations(ctx context.Context, cidr string) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT ip FROM ipam_reservations WHERE prefix_cidr=$1 ORDER BY ip`, cidr)
+	if err != nil {
+		return nil, fmt.Errorf("list reservations: %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+	out := make([]string, 0)
+	for rows.Next() {
+		var ip string
+		if err := rows.Scan(&ip); err != nil {
+			return nil, err
+		}
+		out = append(out, ip)
+	}
+	return out, rows.Err()
+}
+
+// ListAllocatedIPs returns the allocations persisted for cidr.
+func (r *IPAMRepository) ListAllocatedIPs(ctx context.Context, cidr string) ([]network.IPAllocation, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT ip, allocated_at FROM ipam_allocated_ips WHERE prefix_cidr=$1 ORDER BY ip`, cidr)
+	if err != nil {
+		return nil, fmt.Errorf("list allocated ips: %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+	out := make([]network.IPAllocation, 0)
+	for rows.Next() {
+		var a network.IPAllocation
+		if err := rows.Scan(&a.IP, &a.AllocatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, a)
+	}
+	return out, rows.Err()
+}
+
+// AcquiredIPs counts the addresses taken from cidr's pool.  The engine holds
+// every persisted allocation and reservation since NewIPAMRepository.
+func (r *IPAMRepository) AcquiredIPs(ctx context.Context, cidr string) (uint64, error) {
+	p, err := r.engine.PrefixFrom(ctx, cidr)
+	if err != nil {
+		return 0, fmt.Errorf("prefix missing: %w", err)
+	}
+	return p.Usage().AcquiredIPs, nil
+}
+
 // Ensure interface compliance
 var _ ipam.Repository = (*IPAMRepository)(nil)
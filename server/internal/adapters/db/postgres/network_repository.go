@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"wirety/internal/domain/network"
@@ -16,13 +17,13 @@ import (
 // NetworkRepository is a Postgres implementation of network.Repository
 // ACL and IPAM are kept in-memory for now (non-persistent) to avoid schema changes.
 type NetworkRepository struct {
-	db   *sql.DB
+	db   tracedDB
 	acls map[string]*network.ACL
 }
 
 // NewNetworkRepository constructs a new repository
 func NewNetworkRepository(db *sql.DB) *NetworkRepository {
-	return &NetworkRepository{db: db, acls: make(map[string]*network.ACL)}
+	return &NetworkRepository{db: traced(db), acls: make(map[string]*network.ACL)}
 }
 
 // Network operations
@@ -34,8 +35,16 @@ func (r *NetworkRepository) CreateNetwork(ctx context.Context, n *network.Networ
 	if n.DNS == nil {
 		n.DNS = []string{}
 	}
-	_, err := r.db.ExecContext(ctx, `INSERT INTO networks (id,name,cidr,cidr_v6,dns,created_at,updated_at) VALUES ($1,$2,$3,$4,$5,$6,$7)`,
-		n.ID, n.Name, n.CIDR, nullableString(n.CIDRv6), pq.Array(n.DNS), n.CreatedAt, n.UpdatedAt)
+	forwarders, err := marshalForwarders(n.ConditionalForwarders)
+	if err != nil {
+		return err
+	}
+	labels, err := marshalLabels(n.Labels)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.ExecContext(ctx, `INSERT INTO networks (id,name,cidr,cidr_v6,dns,created_at,updated_at,peer_name_pattern,topology,multi_jump_failover,conditional_forwarders,labels,omit_private_keys,dns_query_log,maintenance_until,jump_post_up,jump_post_down,jump_nat_interface,config_metadata,search_domains) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16,$17,$18,$19,$20)`,
+		n.ID, n.Name, n.CIDR, nullableString(n.CIDRv6), pq.Array(n.DNS), n.CreatedAt, n.UpdatedAt, n.PeerNamePattern, n.Topology, n.MultiJumpFailover, forwarders, labels, n.OmitPrivateKeys, n.DNSQueryLog, n.MaintenanceUntil, n.JumpPostUp, n.JumpPostDown, n.JumpNATInterface, n.ConfigMetadata, pq.Array(n.SearchDomains))
 	if err != nil {
 		return fmt.Errorf("create network: %w", err)
 	}
@@ -48,18 +57,30 @@ func (r *NetworkRepository) CreateNetwork(ctx context.Context, n *network.Networ
 func (r *NetworkRepository) GetNetwork(ctx context.Context, networkID string) (*network.Network, error) {
 	var n network.Network
 	var cidrV6 sql.NullString
-	err := r.db.QueryRowContext(ctx, `SELECT id,name,cidr,cidr_v6,dns,created_at,updated_at,domain_suffix FROM networks WHERE id=$1`, networkID).
-		Scan(&n.ID, &n.Name, &n.CIDR, &cidrV6, pq.Array(&n.DNS), &n.CreatedAt, &n.UpdatedAt, &n.DomainSuffix)
+	var forwarders, labels []byte
+	var maintenanceUntil sql.NullTime
+	err := r.db.QueryRowContext(ctx, `SELECT id,name,cidr,cidr_v6,dns,created_at,updated_at,domain_suffix,peer_name_pattern,topology,multi_jump_failover,conditional_forwarders,labels,omit_private_keys,dns_query_log,maintenance_until,jump_post_up,jump_post_down,jump_nat_interface,config_metadata,search_domains FROM networks WHERE id=$1 AND deleted_at IS NULL`, networkID).
+		Scan(&n.ID, &n.Name, &n.CIDR, &cidrV6, pq.Array(&n.DNS), &n.CreatedAt, &n.UpdatedAt, &n.DomainSuffix, &n.PeerNamePattern, &n.Topology, &n.MultiJumpFailover, &forwarders, &labels, &n.OmitPrivateKeys, &n.DNSQueryLog, &maintenanceUntil, &n.JumpPostUp, &n.JumpPostDown, &n.JumpNATInterface, &n.ConfigMetadata, pq.Array(&n.SearchDomains))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return nil, fmt.Errorf("network not found")
+			return nil, network.ErrNetworkNotFound
 		}
 		return nil, fmt.Errorf("get network: %w", err)
 	}
 	n.CIDRv6 = cidrV6.String
+	if maintenanceUntil.Valid {
+		t := maintenanceUntil.Time
+		n.MaintenanceUntil = &t
+	}
+	if n.ConditionalForwarders, err = unmarshalForwarders(forwarders); err != nil {
+		return nil, err
+	}
+	if n.Labels, err = unmarshalLabels(labels); err != nil {
+		return nil, err
+	}
 	// Load peers
 	n.Peers = make(map[string]*network.Peer)
-	rows, err := r.db.QueryContext(ctx, `SELECT id,name,public_key,private_key,address,address_v6,endpoint,listen_port,additional_allowed_ips,token,is_jump,use_agent,owner_id,created_at,updated_at FROM peers WHERE network_id=$1`, networkID)
+	rows, err := r.db.QueryContext(ctx, `SELECT `+peerColumns+` FROM peers WHERE network_id=$1`, networkID)
 	if err != nil {
 		return nil, fmt.Errorf("load peers: %w", err)
 	}
@@ -69,14 +90,9 @@ func (r *NetworkRepository) GetNetwork(ctx context.Context, networkID string) (*
 	count := 0
 	for rows.Next() {
 		var p network.Peer
-		var addrs []string
-		var addrV6 sql.NullString
-		err = rows.Scan(&p.ID, &p.Name, &p.PublicKey, &p.PrivateKey, &p.Address, &addrV6, &p.Endpoint, &p.ListenPort, pq.Array(&addrs), &p.Token, &p.IsJump, &p.UseAgent, &p.OwnerID, &p.CreatedAt, &p.UpdatedAt)
-		if err != nil {
+		if err = scanPeer(rows, &p); err != nil {
 			return nil, fmt.Errorf("scan peer: %w", err)
 		}
-		p.AdditionalAllowedIPs = addrs
-		p.AddressV6 = addrV6.String
 		n.AddPeer(&p)
 		count++
 	}
@@ -91,14 +107,70 @@ func (r *NetworkRepository) UpdateNetwork(ctx context.Context, n *network.Networ
 	if n.DNS == nil {
 		n.DNS = []string{}
 	}
-	_, err := r.db.ExecContext(ctx, `UPDATE networks SET name=$2,cidr=$3,cidr_v6=$4,dns=$5,updated_at=$6,domain_suffix=$7 WHERE id=$1`,
-		n.ID, n.Name, n.CIDR, nullableString(n.CIDRv6), pq.Array(n.DNS), n.UpdatedAt, n.DomainSuffix)
+	forwarders, err := marshalForwarders(n.ConditionalForwarders)
+	if err != nil {
+		return err
+	}
+	labels, err := marshalLabels(n.Labels)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.ExecContext(ctx, `UPDATE networks SET name=$2,cidr=$3,cidr_v6=$4,dns=$5,updated_at=$6,domain_suffix=$7,peer_name_pattern=$8,topology=$9,multi_jump_failover=$10,conditional_forwarders=$11,labels=$12,omit_private_keys=$13,dns_query_log=$14,maintenance_until=$15,jump_post_up=$16,jump_post_down=$17,jump_nat_interface=$18,config_metadata=$19,search_domains=$20 WHERE id=$1`,
+		n.ID, n.Name, n.CIDR, nullableString(n.CIDRv6), pq.Array(n.DNS), n.UpdatedAt, n.DomainSuffix, n.PeerNamePattern, n.Topology, n.MultiJumpFailover, forwarders, labels, n.OmitPrivateKeys, n.DNSQueryLog, n.MaintenanceUntil, n.JumpPostUp, n.JumpPostDown, n.JumpNATInterface, n.ConfigMetadata, pq.Array(n.SearchDomains))
 	if err != nil {
 		return fmt.Errorf("update network: %w", err)
 	}
 	return nil
 }
 
+// marshalForwarders encodes a network's conditional forwarders for the
+// conditional_forwarders JSONB column.
+func marshalForwarders(forwarders map[string][]string) (string, error) {
+	if len(forwarders) == 0 {
+		return "{}", nil
+	}
+	data, err := json.Marshal(forwarders)
+	if err != nil {
+		return "", fmt.Errorf("marshal conditional_forwarders: %w", err)
+	}
+	return string(data), nil
+}
+
+func unmarshalForwarders(data []byte) (map[string][]string, error) {
+	var forwarders map[string][]string
+	if err := json.Unmarshal(data, &forwarders); err != nil {
+		return nil, fmt.Errorf("unmarshal conditional_forwarders: %w", err)
+	}
+	if len(forwarders) == 0 {
+		return nil, nil
+	}
+	return forwarders, nil
+}
+
+// marshalLabels encodes the labels of a network or peer for their labels
+// JSONB column.
+func marshalLabels(labels map[string]string) (string, error) {
+	if len(labels) == 0 {
+		return "{}", nil
+	}
+	data, err := json.Marshal(labels)
+	if err != nil {
+		return "", fmt.Errorf("marshal labels: %w", err)
+	}
+	return string(data), nil
+}
+
+func unmarshalLabels(data []byte) (map[string]string, error) {
+	var labels map[string]string
+	if err := json.Unmarshal(data, &labels); err != nil {
+		return nil, fmt.Errorf("unmarshal labels: %w", err)
+	}
+	if len(labels) == 0 {
+		return nil, nil
+	}
+	return labels, nil
+}
+
 func (r *NetworkRepository) DeleteNetwork(ctx context.Context, networkID string) error {
 	res, err := r.db.ExecContext(ctx, `DELETE FROM networks WHERE id=$1`, networkID)
 	if err != nil {
@@ -106,14 +178,14 @@ func (r *NetworkRepository) DeleteNetwork(ctx context.Context, networkID string)
 	}
 	rows, _ := res.RowsAffected()
 	if rows == 0 {
-		return fmt.Errorf("network not found")
+		return network.ErrNetworkNotFound
 	}
 	delete(r.acls, networkID)
 	return nil
 }
 
 func (r *NetworkRepository) ListNetworks(ctx context.Context) ([]*network.Network, error) {
-	rows, err := r.db.QueryContext(ctx, `SELECT n.id,n.name,n.cidr,n.cidr_v6,n.dns,n.created_at,n.updated_at,n.domain_suffix, COALESCE(p.peer_count,0) AS peer_count FROM networks n LEFT JOIN (SELECT network_id, COUNT(*) AS peer_count FROM peers GROUP BY network_id) p ON p.network_id = n.id ORDER BY n.created_at ASC`)
+	rows, err := r.db.QueryContext(ctx, `SELECT n.id,n.name,n.cidr,n.cidr_v6,n.dns,n.created_at,n.updated_at,n.domain_suffix,n.peer_name_pattern,n.topology,n.multi_jump_failover,n.conditional_forwarders,n.labels,n.omit_private_keys,n.dns_query_log,n.maintenance_until,n.jump_post_up,n.jump_post_down,n.jump_nat_interface,n.config_metadata,n.search_domains, COALESCE(p.peer_count,0) AS peer_count FROM networks n LEFT JOIN (SELECT network_id, COUNT(*) AS peer_count FROM peers GROUP BY network_id) p ON p.network_id = n.id WHERE n.deleted_at IS NULL ORDER BY n.created_at ASC`)
 	if err != nil {
 		return nil, fmt.Errorf("list networks: %w", err)
 	}
@@ -124,11 +196,23 @@ func (r *NetworkRepository) ListNetworks(ctx context.Context) ([]*network.Networ
 	for rows.Next() {
 		var n network.Network
 		var cidrV6 sql.NullString
-		err = rows.Scan(&n.ID, &n.Name, &n.CIDR, &cidrV6, pq.Array(&n.DNS), &n.CreatedAt, &n.UpdatedAt, &n.DomainSuffix, &n.PeerCount)
+		var forwarders, labels []byte
+		var maintenanceUntil sql.NullTime
+		err = rows.Scan(&n.ID, &n.Name, &n.CIDR, &cidrV6, pq.Array(&n.DNS), &n.CreatedAt, &n.UpdatedAt, &n.DomainSuffix, &n.PeerNamePattern, &n.Topology, &n.MultiJumpFailover, &forwarders, &labels, &n.OmitPrivateKeys, &n.DNSQueryLog, &maintenanceUntil, &n.JumpPostUp, &n.JumpPostDown, &n.JumpNATInterface, &n.ConfigMetadata, pq.Array(&n.SearchDomains), &n.PeerCount)
 		if err != nil {
 			return nil, err
 		}
 		n.CIDRv6 = cidrV6.String
+		if maintenanceUntil.Valid {
+			t := maintenanceUntil.Time
+			n.MaintenanceUntil = &t
+		}
+		if n.ConditionalForwarders, err = unmarshalForwarders(forwarders); err != nil {
+			return nil, err
+		}
+		if n.Labels, err = unmarshalLabels(labels); err != nil {
+			return nil, err
+		}
 		n.Peers = make(map[string]*network.Peer) // not loaded to keep call light
 		// ACL system removed
 		out = append(out, &n)
@@ -136,7 +220,99 @@ func (r *NetworkRepository) ListNetworks(ctx context.Context) ([]*network.Networ
 	return out, rows.Err()
 }
 
+func (r *NetworkRepository) SoftDeleteNetwork(ctx context.Context, networkID string, at time.Time) error {
+	res, err := r.db.ExecContext(ctx, `UPDATE networks SET deleted_at=$2 WHERE id=$1 AND deleted_at IS NULL`, networkID, at)
+	if err != nil {
+		return fmt.Errorf("soft delete network: %w", err)
+	}
+	rows, _ := res.RowsAffected()
+	if rows == 0 {
+		return network.ErrNetworkNotFound
+	}
+	return nil
+}
+
+func (r *NetworkRepository) RestoreNetwork(ctx context.Context, networkID string) error {
+	var deletedAt sql.NullTime
+	err := r.db.QueryRowContext(ctx, `SELECT deleted_at FROM networks WHERE id=$1`, networkID).Scan(&deletedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return network.ErrNetworkNotFound
+		}
+		return fmt.Errorf("restore network: %w", err)
+	}
+	if !deletedAt.Valid {
+		return network.ErrNetworkNotDeleted
+	}
+	if _, err := r.db.ExecContext(ctx, `UPDATE networks SET deleted_at=NULL WHERE id=$1`, networkID); err != nil {
+		return fmt.Errorf("restore network: %w", err)
+	}
+	return nil
+}
+
+// ListDeletedNetworks returns the soft-deleted networks, without their
+// peers: the purge only needs their CIDRs.
+func (r *NetworkRepository) ListDeletedNetworks(ctx context.Context) ([]*network.Network, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT id,name,cidr,cidr_v6,created_at,updated_at,deleted_at FROM networks WHERE deleted_at IS NOT NULL ORDER BY deleted_at ASC`)
+	if err != nil {
+		return nil, fmt.Errorf("list deleted networks: %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+	out := make([]*network.Network, 0)
+	for rows.Next() {
+		var n network.Network
+		var cidrV6 sql.NullString
+		var deletedAt time.Time
+		if err := rows.Scan(&n.ID, &n.Name, &n.CIDR, &cidrV6, &n.CreatedAt, &n.UpdatedAt, &deletedAt); err != nil {
+			return nil, err
+		}
+		n.CIDRv6 = cidrV6.String
+		n.DeletedAt = &deletedAt
+		n.Peers = make(map[string]*network.Peer)
+		out = append(out, &n)
+	}
+	return out, rows.Err()
+}
+
 // Peer operations
+
+const peerColumns = "id,name,public_key,private_key,address,address_v6,endpoint,listen_port,additional_allowed_ips,token,is_jump,use_agent,owner_id,role,created_at,updated_at,split_tunnel_exclusions,profile_id,mtu,persistent_keepalive,dns,expires_at,routing_table,fwmark,token_single_use,token_expires_at,token_used_at,labels,dns_only"
+
+func scanPeer(row interface{ Scan(...interface{}) error }, p *network.Peer, extra ...interface{}) error {
+	var addrs, exclusions, dns []string
+	var addrV6, profileID sql.NullString
+	var expiresAt, tokenExpiresAt, tokenUsedAt sql.NullTime
+	var labels []byte
+	dest := append(extra, &p.ID, &p.Name, &p.PublicKey, &p.PrivateKey, &p.Address, &addrV6, &p.Endpoint, &p.ListenPort, pq.Array(&addrs), &p.Token, &p.IsJump, &p.UseAgent, &p.OwnerID, &p.Role, &p.CreatedAt, &p.UpdatedAt, pq.Array(&exclusions), &profileID, &p.MTU, &p.PersistentKeepalive, pq.Array(&dns), &expiresAt, &p.RoutingTable, &p.FwMark, &p.TokenSingleUse, &tokenExpiresAt, &tokenUsedAt, &labels, &p.DNSOnly)
+	if err := row.Scan(dest...); err != nil {
+		return err
+	}
+	var err error
+	if p.Labels, err = unmarshalLabels(labels); err != nil {
+		return err
+	}
+	p.AdditionalAllowedIPs = addrs
+	p.SplitTunnelExclusions = exclusions
+	p.DNS = dns
+	p.AddressV6 = addrV6.String
+	p.ProfileID = profileID.String
+	if expiresAt.Valid {
+		t := expiresAt.Time
+		p.ExpiresAt = &t
+	}
+	if tokenExpiresAt.Valid {
+		t := tokenExpiresAt.Time
+		p.TokenExpiresAt = &t
+	}
+	if tokenUsedAt.Valid {
+		t := tokenUsedAt.Time
+		p.TokenUsedAt = &t
+	}
+	return nil
+}
+
 func (r *NetworkRepository) CreatePeer(ctx context.Context, networkID string, p *network.Peer) error {
 	now := time.Now()
 	p.CreatedAt = now
@@ -145,8 +321,13 @@ func (r *NetworkRepository) CreatePeer(ctx context.Context, networkID string, p
 	if p.AdditionalAllowedIPs == nil {
 		p.AdditionalAllowedIPs = []string{}
 	}
-	_, err := r.db.ExecContext(ctx, `INSERT INTO peers (id,network_id,name,public_key,private_key,address,address_v6,endpoint,listen_port,additional_allowed_ips,token,is_jump,use_agent,owner_id,created_at,updated_at) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16)`,
-		p.ID, networkID, p.Name, p.PublicKey, p.PrivateKey, p.Address, nullableString(p.AddressV6), p.Endpoint, p.ListenPort, pq.Array(p.AdditionalAllowedIPs), p.Token, p.IsJump, p.UseAgent, p.OwnerID, p.CreatedAt, p.UpdatedAt)
+	labels, err := marshalLabels(p.Labels)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.ExecContext(ctx, `INSERT INTO peers (id,network_id,name,public_key,private_key,address,address_v6,endpoint,listen_port,additional_allowed_ips,token,is_jump,use_agent,owner_id,role,created_at,updated_at,split_tunnel_exclusions,profile_id,mtu,persistent_keepalive,dns,expires_at,routing_table,fwmark,token_single_use,token_expires_at,token_used_at,labels,dns_only) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16,$17,$18,$19,$20,$21,$22,$23,$24,$25,$26,$27,$28,$29,$30)`,
+		p.ID, networkID, p.Name, p.PublicKey, p.PrivateKey, p.Address, nullableString(p.AddressV6), p.Endpoint, p.ListenPort, pq.Array(p.AdditionalAllowedIPs), p.Token, p.IsJump, p.UseAgent, p.OwnerID, p.EffectiveRole(), p.CreatedAt, p.UpdatedAt, pq.Array(p.SplitTunnelExclusions),
+		nullableString(p.ProfileID), p.MTU, p.PersistentKeepalive, pq.Array(nonNilStrings(p.DNS)), p.ExpiresAt, p.RoutingTable, p.FwMark, p.TokenSingleUse, p.TokenExpiresAt, p.TokenUsedAt, labels, p.DNSOnly)
 	if err != nil {
 		return fmt.Errorf("create peer: %w", err)
 	}
@@ -155,18 +336,13 @@ func (r *NetworkRepository) CreatePeer(ctx context.Context, networkID string, p
 
 func (r *NetworkRepository) GetPeer(ctx context.Context, networkID, peerID string) (*network.Peer, error) {
 	var p network.Peer
-	var addrs []string
-	var addrV6 sql.NullString
-	err := r.db.QueryRowContext(ctx, `SELECT id,name,public_key,private_key,address,address_v6,endpoint,listen_port,additional_allowed_ips,token,is_jump,use_agent,owner_id,created_at,updated_at FROM peers WHERE id=$1 AND network_id=$2`, peerID, networkID).
-		Scan(&p.ID, &p.Name, &p.PublicKey, &p.PrivateKey, &p.Address, &addrV6, &p.Endpoint, &p.ListenPort, pq.Array(&addrs), &p.Token, &p.IsJump, &p.UseAgent, &p.OwnerID, &p.CreatedAt, &p.UpdatedAt)
+	err := scanPeer(r.db.QueryRowContext(ctx, `SELECT `+peerColumns+` FROM peers WHERE id=$1 AND network_id=$2`, peerID, networkID), &p)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return nil, fmt.Errorf("peer not found")
+			return nil, network.ErrPeerNotFound
 		}
 		return nil, fmt.Errorf("get peer: %w", err)
 	}
-	p.AdditionalAllowedIPs = addrs
-	p.AddressV6 = addrV6.String
 
 	// Load group IDs for this peer
 	groupIDs, err := r.loadPeerGroupIDs(ctx, peerID)
@@ -181,18 +357,13 @@ func (r *NetworkRepository) GetPeer(ctx context.Context, networkID, peerID strin
 func (r *NetworkRepository) GetPeerByToken(ctx context.Context, token string) (string, *network.Peer, error) {
 	var p network.Peer
 	var networkID string
-	var addrs []string
-	var addrV6 sql.NullString
-	err := r.db.QueryRowContext(ctx, `SELECT network_id,id,name,public_key,private_key,address,address_v6,endpoint,listen_port,additional_allowed_ips,token,is_jump,use_agent,owner_id,created_at,updated_at FROM peers WHERE token=$1`, token).
-		Scan(&networkID, &p.ID, &p.Name, &p.PublicKey, &p.PrivateKey, &p.Address, &addrV6, &p.Endpoint, &p.ListenPort, pq.Array(&addrs), &p.Token, &p.IsJump, &p.UseAgent, &p.OwnerID, &p.CreatedAt, &p.UpdatedAt)
+	err := scanPeer(r.db.QueryRowContext(ctx, `SELECT network_id,`+peerColumns+` FROM peers WHERE token=$1 AND network_id IN (SELECT id FROM networks WHERE deleted_at IS NULL)`, token), &p, &networkID)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return "", nil, fmt.Errorf("token not found")
 		}
 		return "", nil, fmt.Errorf("get peer by token: %w", err)
 	}
-	p.AdditionalAllowedIPs = addrs
-	p.AddressV6 = addrV6.String
 	return networkID, &p, nil
 }
 
@@ -202,14 +373,19 @@ func (r *NetworkRepository) UpdatePeer(ctx context.Context, networkID string, p
 	if p.AdditionalAllowedIPs == nil {
 		p.AdditionalAllowedIPs = []string{}
 	}
-	res, err := r.db.ExecContext(ctx, `UPDATE peers SET name=$3,public_key=$4,private_key=$5,address=$6,address_v6=$7,endpoint=$8,listen_port=$9,additional_allowed_ips=$10,token=$11,is_jump=$12,use_agent=$13,owner_id=$14,updated_at=$15 WHERE id=$1 AND network_id=$2`,
-		p.ID, networkID, p.Name, p.PublicKey, p.PrivateKey, p.Address, nullableString(p.AddressV6), p.Endpoint, p.ListenPort, pq.Array(p.AdditionalAllowedIPs), p.Token, p.IsJump, p.UseAgent, p.OwnerID, p.UpdatedAt)
+	labels, err := marshalLabels(p.Labels)
+	if err != nil {
+		return err
+	}
+	res, err := r.db.ExecContext(ctx, `UPDATE peers SET name=$3,public_key=$4,private_key=$5,address=$6,address_v6=$7,endpoint=$8,listen_port=$9,additional_allowed_ips=$10,token=$11,is_jump=$12,use_agent=$13,owner_id=$14,role=$15,updated_at=$16,split_tunnel_exclusions=$17,profile_id=$18,mtu=$19,persistent_keepalive=$20,dns=$21,expires_at=$22,routing_table=$23,fwmark=$24,token_single_use=$25,token_expires_at=$26,token_used_at=$27,labels=$28,dns_only=$29 WHERE id=$1 AND network_id=$2`,
+		p.ID, networkID, p.Name, p.PublicKey, p.PrivateKey, p.Address, nullableString(p.AddressV6), p.Endpoint, p.ListenPort, pq.Array(p.AdditionalAllowedIPs), p.Token, p.IsJump, p.UseAgent, p.OwnerID, p.EffectiveRole(), p.UpdatedAt, pq.Array(p.SplitTunnelExclusions),
+		nullableString(p.ProfileID), p.MTU, p.PersistentKeepalive, pq.Array(nonNilStrings(p.DNS)), p.ExpiresAt, p.RoutingTable, p.FwMark, p.TokenSingleUse, p.TokenExpiresAt, p.TokenUsedAt, labels, p.DNSOnly)
 	if err != nil {
 		return fmt.Errorf("update peer: %w", err)
 	}
 	rows, _ := res.RowsAffected()
 	if rows == 0 {
-		return fmt.Errorf("peer not found")
+		return network.ErrPeerNotFound
 	}
 	return nil
 }
@@ -221,13 +397,13 @@ func (r *NetworkRepository) DeletePeer(ctx context.Context, networkID, peerID st
 	}
 	rows, _ := res.RowsAffected()
 	if rows == 0 {
-		return fmt.Errorf("peer not found")
+		return network.ErrPeerNotFound
 	}
 	return nil
 }
 
 func (r *NetworkRepository) ListPeers(ctx context.Context, networkID string) ([]*network.Peer, error) {
-	rows, err := r.db.QueryContext(ctx, `SELECT id,name,public_key,private_key,address,address_v6,endpoint,listen_port,additional_allowed_ips,token,is_jump,use_agent,owner_id,created_at,updated_at FROM peers WHERE network_id=$1 ORDER BY created_at ASC`, networkID)
+	rows, err := r.db.QueryContext(ctx, `SELECT `+peerColumns+` FROM peers WHERE network_id=$1 ORDER BY created_at ASC`, networkID)
 	if err != nil {
 		return nil, fmt.Errorf("list peers: %w", err)
 	}
@@ -237,14 +413,9 @@ func (r *NetworkRepository) ListPeers(ctx context.Context, networkID string) ([]
 	out := make([]*network.Peer, 0)
 	for rows.Next() {
 		var p network.Peer
-		var addrs []string
-		var addrV6 sql.NullString
-		err = rows.Scan(&p.ID, &p.Name, &p.PublicKey, &p.PrivateKey, &p.Address, &addrV6, &p.Endpoint, &p.ListenPort, pq.Array(&addrs), &p.Token, &p.IsJump, &p.UseAgent, &p.OwnerID, &p.CreatedAt, &p.UpdatedAt)
-		if err != nil {
+		if err = scanPeer(rows, &p); err != nil {
 			return nil, err
 		}
-		p.AdditionalAllowedIPs = addrs
-		p.AddressV6 = addrV6.String
 
 		// Load group IDs for this peer
 		groupIDs, err := r.loadPeerGroupIDs(ctx, p.ID)
@@ -258,6 +429,52 @@ func (r *NetworkRepository) ListPeers(ctx context.Context, networkID string) ([]
 	return out, rows.Err()
 }
 
+// likeEscaper escapes the LIKE wildcards of a user-supplied search query.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// peerSearchFrom selects the peers of live networks, with their network name,
+// whose name, addresses, public key or ID contain $1.
+const peerSearchFrom = `
+	FROM (
+		SELECT p.*, n.name AS network_name
+		FROM peers p JOIN networks n ON n.id = p.network_id
+		WHERE n.deleted_at IS NULL
+	) peers
+	WHERE name ILIKE $1 OR address ILIKE $1 OR address_v6 ILIKE $1 OR public_key ILIKE $1 OR id ILIKE $1`
+
+func (r *NetworkRepository) SearchPeers(ctx context.Context, query string, offset, limit int) ([]*network.PeerSearchResult, int, error) {
+	pattern := "%" + likeEscaper.Replace(query) + "%"
+	var total int
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*)`+peerSearchFrom, pattern).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("count peer search results: %w", err)
+	}
+	rows, err := r.db.QueryContext(ctx, `SELECT network_id,network_name,`+peerColumns+peerSearchFrom+`
+		ORDER BY network_name, name, id
+		LIMIT $2 OFFSET $3`, pattern, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("search peers: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	out := make([]*network.PeerSearchResult, 0)
+	for rows.Next() {
+		res := &network.PeerSearchResult{Peer: &network.Peer{}}
+		if err := scanPeer(rows, res.Peer, &res.NetworkID, &res.NetworkName); err != nil {
+			return nil, 0, err
+		}
+		out = append(out, res)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	for _, res := range out {
+		if res.Peer.GroupIDs, err = r.loadPeerGroupIDs(ctx, res.Peer.ID); err != nil {
+			return nil, 0, fmt.Errorf("load peer group IDs: %w", err)
+		}
+	}
+	return out, total, nil
+}
+
 // loadPeerGroupIDs loads all group IDs that a peer belongs to
 func (r *NetworkRepository) loadPeerGroupIDs(ctx context.Context, peerID string) ([]string, error) {
 	rows, err := r.db.QueryContext(ctx, `
@@ -292,6 +509,14 @@ func nullableString(s string) sql.NullString {
 	return sql.NullString{String: s, Valid: true}
 }
 
+// nonNilStrings maps a nil slice to an empty one, for NOT NULL array columns.
+func nonNilStrings(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
+
 // ACL operations (ephemeral)
 func (r *NetworkRepository) CreateACL(ctx context.Context, networkID string, acl *network.ACL) error {
 	r.acls[networkID] = acl
@@ -316,7 +541,8 @@ func connectionKey(peer1ID, peer2ID string) (string, string) {
 func (r *NetworkRepository) CreateConnection(ctx context.Context, networkID string, conn *network.PeerConnection) error {
 	// Ensure peer order deterministic (peer1<peer2)
 	p1, p2 := connectionKey(conn.Peer1ID, conn.Peer2ID)
-	_, err := r.db.ExecContext(ctx, `INSERT INTO peer_connections (peer1_id,peer2_id,preshared_key,created_at) VALUES ($1,$2,$3,$4)`, p1, p2, conn.PresharedKey, time.Now())
+	_, err := r.db.ExecContext(ctx, `INSERT INTO peer_connections (peer1_id,peer2_id,preshared_key,created_at,allowed_ips,allowed_ips_peer_id,severed) VALUES ($1,$2,$3,$4,$5,$6,$7)`,
+		p1, p2, conn.PresharedKey, time.Now(), pq.Array(nonNilStrings(conn.AllowedIPs)), conn.AllowedIPsPeerID, conn.Severed)
 	if err != nil {
 		return fmt.Errorf("create connection: %w", err)
 	}
@@ -326,8 +552,8 @@ func (r *NetworkRepository) CreateConnection(ctx context.Context, networkID stri
 func (r *NetworkRepository) GetConnection(ctx context.Context, networkID, peer1ID, peer2ID string) (*network.PeerConnection, error) {
 	p1, p2 := connectionKey(peer1ID, peer2ID)
 	var c network.PeerConnection
-	err := r.db.QueryRowContext(ctx, `SELECT peer1_id,peer2_id,preshared_key,created_at FROM peer_connections WHERE peer1_id=$1 AND peer2_id=$2`, p1, p2).
-		Scan(&c.Peer1ID, &c.Peer2ID, &c.PresharedKey, &c.CreatedAt)
+	err := r.db.QueryRowContext(ctx, `SELECT peer1_id,peer2_id,preshared_key,created_at,allowed_ips,allowed_ips_peer_id,severed FROM peer_connections WHERE peer1_id=$1 AND peer2_id=$2`, p1, p2).
+		Scan(&c.Peer1ID, &c.Peer2ID, &c.PresharedKey, &c.CreatedAt, pq.Array(&c.AllowedIPs), &c.AllowedIPsPeerID, &c.Severed)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, fmt.Errorf("connection not found")
@@ -339,7 +565,7 @@ func (r *NetworkRepository) GetConnection(ctx context.Context, networkID, peer1I
 
 func (r *NetworkRepository) ListConnections(ctx context.Context, networkID string) ([]*network.PeerConnection, error) {
 	// Filter by peers belonging to network using join
-	rows, err := r.db.QueryContext(ctx, `SELECT c.peer1_id,c.peer2_id,c.preshared_key,c.created_at FROM peer_connections c
+	rows, err := r.db.QueryContext(ctx, `SELECT c.peer1_id,c.peer2_id,c.preshared_key,c.created_at,c.allowed_ips,c.allowed_ips_peer_id,c.severed FROM peer_connections c
         JOIN peers p1 ON c.peer1_id=p1.id JOIN peers p2 ON c.peer2_id=p2.id WHERE p1.network_id=$1 AND p2.network_id=$1`, networkID)
 	if err != nil {
 		return nil, fmt.Errorf("list connections: %w", err)
@@ -350,7 +576,7 @@ func (r *NetworkRepository) ListConnections(ctx context.Context, networkID strin
 	out := make([]*network.PeerConnection, 0)
 	for rows.Next() {
 		var c network.PeerConnection
-		if err = rows.Scan(&c.Peer1ID, &c.Peer2ID, &c.PresharedKey, &c.CreatedAt); err != nil {
+		if err = rows.Scan(&c.Peer1ID, &c.Peer2ID, &c.PresharedKey, &c.CreatedAt, pq.Array(&c.AllowedIPs), &c.AllowedIPsPeerID, &c.Severed); err != nil {
 			return nil, err
 		}
 		out = append(out, &c)
@@ -358,6 +584,19 @@ func (r *NetworkRepository) ListConnections(ctx context.Context, networkID strin
 	return out, rows.Err()
 }
 
+func (r *NetworkRepository) UpdateConnection(ctx context.Context, networkID string, conn *network.PeerConnection) error {
+	p1, p2 := connectionKey(conn.Peer1ID, conn.Peer2ID)
+	res, err := r.db.ExecContext(ctx, `UPDATE peer_connections SET preshared_key=$3,allowed_ips=$4,allowed_ips_peer_id=$5,severed=$6 WHERE peer1_id=$1 AND peer2_id=$2`,
+		p1, p2, conn.PresharedKey, pq.Array(nonNilStrings(conn.AllowedIPs)), conn.AllowedIPsPeerID, conn.Severed)
+	if err != nil {
+		return fmt.Errorf("update connection: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("connection not found")
+	}
+	return nil
+}
+
 func (r *NetworkRepository) DeleteConnection(ctx context.Context, networkID, peer1ID, peer2ID string) error {
 	p1, p2 := connectionKey(peer1ID, peer2ID)
 	_, err := r.db.ExecContext(ctx, `DELETE FROM peer_connections WHERE peer1_id=$1 AND peer2_id=$2`, p1, p2)
@@ -382,20 +621,107 @@ func (r *NetworkRepository) CreateOrUpdateSession(ctx context.Context, networkID
 		s.FirstSeen = now
 	}
 	s.LastSeen = now
-	_, err = r.db.ExecContext(ctx, `INSERT INTO agent_sessions (session_id,peer_id,hostname,system_uptime,wireguard_uptime,reported_endpoint,last_seen,first_seen) VALUES ($1,$2,$3,$4,$5,$6,$7,$8)
-        ON CONFLICT (session_id) DO UPDATE SET hostname=EXCLUDED.hostname,system_uptime=EXCLUDED.system_uptime,wireguard_uptime=EXCLUDED.wireguard_uptime,reported_endpoint=EXCLUDED.reported_endpoint,last_seen=EXCLUDED.last_seen`,
-		s.SessionID, s.PeerID, s.Hostname, s.SystemUptime, s.WireGuardUptime, s.ReportedEndpoint, s.LastSeen, s.FirstSeen)
+	transfer, handshakes, latency, queries := []byte("{}"), []byte("{}"), []byte("{}"), []byte("[]")
+	if len(s.PeerTransfer) > 0 {
+		if transfer, err = json.Marshal(s.PeerTransfer); err != nil {
+			return fmt.Errorf("marshal peer_transfer: %w", err)
+		}
+	}
+	if len(s.PeerHandshakes) > 0 {
+		if handshakes, err = json.Marshal(s.PeerHandshakes); err != nil {
+			return fmt.Errorf("marshal peer_handshakes: %w", err)
+		}
+	}
+	if len(s.PeerLatency) > 0 {
+		if latency, err = json.Marshal(s.PeerLatency); err != nil {
+			return fmt.Errorf("marshal peer_latency: %w", err)
+		}
+	}
+	if len(s.DNSQueries) > 0 {
+		if queries, err = json.Marshal(s.DNSQueries); err != nil {
+			return fmt.Errorf("marshal dns_queries: %w", err)
+		}
+	}
+	var jumpHealth sql.NullString
+	if s.JumpHealth != nil {
+		data, err := json.Marshal(s.JumpHealth)
+		if err != nil {
+			return fmt.Errorf("marshal jump_health: %w", err)
+		}
+		jumpHealth = sql.NullString{String: string(data), Valid: true}
+	}
+	_, err = r.db.ExecContext(ctx, `INSERT INTO agent_sessions (session_id,peer_id,hostname,system_uptime,wireguard_uptime,reported_endpoint,last_seen,first_seen,firewall_backend,peer_transfer,peer_handshakes,peer_latency,jump_health,dns_queries,last_applied_config_hash,last_applied_at) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16)
+        ON CONFLICT (session_id) DO UPDATE SET hostname=EXCLUDED.hostname,system_uptime=EXCLUDED.system_uptime,wireguard_uptime=EXCLUDED.wireguard_uptime,reported_endpoint=EXCLUDED.reported_endpoint,last_seen=EXCLUDED.last_seen,firewall_backend=EXCLUDED.firewall_backend,peer_transfer=EXCLUDED.peer_transfer,peer_handshakes=EXCLUDED.peer_handshakes,peer_latency=EXCLUDED.peer_latency,jump_health=EXCLUDED.jump_health,dns_queries=EXCLUDED.dns_queries,last_applied_config_hash=EXCLUDED.last_applied_config_hash,last_applied_at=EXCLUDED.last_applied_at`,
+		s.SessionID, s.PeerID, s.Hostname, s.SystemUptime, s.WireGuardUptime, s.ReportedEndpoint, s.LastSeen, s.FirstSeen, s.FirewallBackend, string(transfer), string(handshakes), string(latency), jumpHealth, string(queries), s.LastAppliedConfigHash, s.LastAppliedAt)
 	if err != nil {
 		return fmt.Errorf("upsert session: %w", err)
 	}
 	return nil
 }
 
+const sessionColumns = "s.session_id,s.peer_id,s.hostname,s.system_uptime,s.wireguard_uptime,s.reported_endpoint,s.last_seen,s.first_seen,s.firewall_backend,s.revoked_at,s.peer_transfer,s.peer_handshakes,s.peer_latency,s.jump_health,s.dns_queries,s.last_applied_config_hash,s.last_applied_at"
+
+func scanSession(row interface{ Scan(...interface{}) error }, s *network.AgentSession) error {
+	var revokedAt, lastAppliedAt sql.NullTime
+	var transfer, handshakes, latency, jumpHealth, queries []byte
+	if err := row.Scan(&s.SessionID, &s.PeerID, &s.Hostname, &s.SystemUptime, &s.WireGuardUptime, &s.ReportedEndpoint, &s.LastSeen, &s.FirstSeen, &s.FirewallBackend, &revokedAt, &transfer, &handshakes, &latency, &jumpHealth, &queries, &s.LastAppliedConfigHash, &lastAppliedAt); err != nil {
+		return err
+	}
+	if len(transfer) > 0 {
+		if err := json.Unmarshal(transfer, &s.PeerTransfer); err != nil {
+			return fmt.Errorf("unmarshal peer_transfer: %w", err)
+		}
+	}
+	if len(handshakes) > 0 {
+		if err := json.Unmarshal(handshakes, &s.PeerHandshakes); err != nil {
+			return fmt.Errorf("unmarshal peer_handshakes: %w", err)
+		}
+	}
+	if len(latency) > 0 {
+		if err := json.Unmarshal(latency, &s.PeerLatency); err != nil {
+			return fmt.Errorf("unmarshal peer_latency: %w", err)
+		}
+	}
+	if len(jumpHealth) > 0 {
+		if err := json.Unmarshal(jumpHealth, &s.JumpHealth); err != nil {
+			return fmt.Errorf("unmarshal jump_health: %w", err)
+		}
+	}
+	if len(queries) > 0 {
+		if err := json.Unmarshal(queries, &s.DNSQueries); err != nil {
+			return fmt.Errorf("unmarshal dns_queries: %w", err)
+		}
+	}
+	if revokedAt.Valid {
+		t := revokedAt.Time
+		s.RevokedAt = &t
+	}
+	if lastAppliedAt.Valid {
+		t := lastAppliedAt.Time
+		s.LastAppliedAt = &t
+	}
+	return nil
+}
+
+func scanSessions(rows *sql.Rows) ([]*network.AgentSession, error) {
+	defer func() {
+		_ = rows.Close()
+	}()
+	out := make([]*network.AgentSession, 0)
+	for rows.Next() {
+		var s network.AgentSession
+		if err := scanSession(rows, &s); err != nil {
+			return nil, err
+		}
+		out = append(out, &s)
+	}
+	return out, rows.Err()
+}
+
 func (r *NetworkRepository) GetSession(ctx context.Context, networkID, peerID string) (*network.AgentSession, error) {
-	// Return most recent session for peer
+	// Return most recent non-revoked session for peer
 	var s network.AgentSession
-	err := r.db.QueryRowContext(ctx, `SELECT session_id,peer_id,hostname,system_uptime,wireguard_uptime,reported_endpoint,last_seen,first_seen FROM agent_sessions WHERE peer_id=$1 ORDER BY last_seen DESC LIMIT 1`, peerID).
-		Scan(&s.SessionID, &s.PeerID, &s.Hostname, &s.SystemUptime, &s.WireGuardUptime, &s.ReportedEndpoint, &s.LastSeen, &s.FirstSeen)
+	err := scanSession(r.db.QueryRowContext(ctx, `SELECT `+sessionColumns+` FROM agent_sessions s WHERE s.peer_id=$1 AND s.revoked_at IS NULL ORDER BY s.last_seen DESC LIMIT 1`, peerID), &s)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, fmt.Errorf("session not found")
@@ -412,27 +738,16 @@ func (r *NetworkRepository) GetSession(ctx context.Context, networkID, peerID st
 }
 
 func (r *NetworkRepository) GetActiveSessionsForPeer(ctx context.Context, networkID, peerID string) ([]*network.AgentSession, error) {
-	rows, err := r.db.QueryContext(ctx, `SELECT session_id,peer_id,hostname,system_uptime,wireguard_uptime,reported_endpoint,last_seen,first_seen FROM agent_sessions WHERE peer_id=$1`, peerID)
-	if err != nil {
-		return nil, fmt.Errorf("list peer sessions: %w", err)
-	}
-	defer func() {
-		_ = rows.Close()
-	}()
-	out := make([]*network.AgentSession, 0)
 	var belongs bool
 	_ = r.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM peers WHERE id=$1 AND network_id=$2)`, peerID, networkID).Scan(&belongs)
 	if !belongs {
 		return nil, fmt.Errorf("peer not in network")
 	}
-	for rows.Next() {
-		var s network.AgentSession
-		if err = rows.Scan(&s.SessionID, &s.PeerID, &s.Hostname, &s.SystemUptime, &s.WireGuardUptime, &s.ReportedEndpoint, &s.LastSeen, &s.FirstSeen); err != nil {
-			return nil, err
-		}
-		out = append(out, &s)
+	rows, err := r.db.QueryContext(ctx, `SELECT `+sessionColumns+` FROM agent_sessions s WHERE s.peer_id=$1 AND s.revoked_at IS NULL`, peerID)
+	if err != nil {
+		return nil, fmt.Errorf("list peer sessions: %w", err)
 	}
-	return out, rows.Err()
+	return scanSessions(rows)
 }
 
 func (r *NetworkRepository) DeleteSession(ctx context.Context, networkID, sessionID string) error {
@@ -444,24 +759,34 @@ func (r *NetworkRepository) DeleteSession(ctx context.Context, networkID, sessio
 }
 
 func (r *NetworkRepository) ListSessions(ctx context.Context, networkID string) ([]*network.AgentSession, error) {
-	// Only sessions for peers in this network
-	rows, err := r.db.QueryContext(ctx, `SELECT s.session_id,s.peer_id,s.hostname,s.system_uptime,s.wireguard_uptime,s.reported_endpoint,s.last_seen,s.first_seen FROM agent_sessions s
-        JOIN peers p ON s.peer_id=p.id WHERE p.network_id=$1`, networkID)
+	// Only non-revoked sessions for peers in this network
+	rows, err := r.db.QueryContext(ctx, `SELECT `+sessionColumns+` FROM agent_sessions s
+        JOIN peers p ON s.peer_id=p.id WHERE p.network_id=$1 AND s.revoked_at IS NULL`, networkID)
 	if err != nil {
 		return nil, fmt.Errorf("list sessions: %w", err)
 	}
-	defer func() {
-		_ = rows.Close()
-	}()
-	out := make([]*network.AgentSession, 0)
-	for rows.Next() {
-		var s network.AgentSession
-		if err = rows.Scan(&s.SessionID, &s.PeerID, &s.Hostname, &s.SystemUptime, &s.WireGuardUptime, &s.ReportedEndpoint, &s.LastSeen, &s.FirstSeen); err != nil {
-			return nil, err
-		}
-		out = append(out, &s)
+	return scanSessions(rows)
+}
+
+func (r *NetworkRepository) ListSessionHistory(ctx context.Context, networkID, peerID string) ([]*network.AgentSession, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+sessionColumns+` FROM agent_sessions s
+        JOIN peers p ON s.peer_id=p.id WHERE p.network_id=$1 AND s.peer_id=$2 ORDER BY s.first_seen DESC`, networkID, peerID)
+	if err != nil {
+		return nil, fmt.Errorf("list session history: %w", err)
 	}
-	return out, rows.Err()
+	return scanSessions(rows)
+}
+
+func (r *NetworkRepository) RevokeSession(ctx context.Context, networkID, sessionID string, revokedAt time.Time) error {
+	res, err := r.db.ExecContext(ctx, `UPDATE agent_sessions SET revoked_at=$3 WHERE session_id=$2
+        AND peer_id IN (SELECT id FROM peers WHERE network_id=$1)`, networkID, sessionID, revokedAt)
+	if err != nil {
+		return fmt.Errorf("revoke session: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return network.ErrSessionNotFound
+	}
+	return nil
 }
 
 // CaptivePortalWhitelistTTL is how long a whitelist entry remains valid after authentication.
@@ -890,3 +1215,145 @@ func (r *NetworkRepository) ListPeerLocalRoutes(ctx context.Context, networkID s
 	return out, rows.Err()
 }
 
+// Temporary route operations
+
+const tempRouteColumns = "id, network_id, peer_id, COALESCE(route_id, ''), destination_cidr, destination_cidr_v6, jump_peer_id, reason, created_by, created_at, expires_at"
+
+func (r *NetworkRepository) CreateTempRoute(ctx context.Context, t *network.TempRoute) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO peer_temp_routes
+			(id, network_id, peer_id, route_id, destination_cidr, destination_cidr_v6, jump_peer_id, reason, created_by, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	`, t.ID, t.NetworkID, t.PeerID, nullableString(t.RouteID), t.DestinationCIDR, t.DestinationCIDRv6, t.JumpPeerID, t.Reason, t.CreatedBy, t.CreatedAt, t.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("create temp route: %w", err)
+	}
+	return nil
+}
+
+func (r *NetworkRepository) ListTempRoutes(ctx context.Context, networkID, peerID string) ([]*network.TempRoute, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+tempRouteColumns+` FROM peer_temp_routes
+		WHERE network_id=$1 AND ($2 = '' OR peer_id=$2) ORDER BY created_at ASC`, networkID, peerID)
+	if err != nil {
+		return nil, fmt.Errorf("list temp routes: %w", err)
+	}
+	return scanTempRoutes(rows)
+}
+
+func (r *NetworkRepository) DeleteTempRoute(ctx context.Context, networkID, id string) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM peer_temp_routes WHERE network_id=$1 AND id=$2`, networkID, id)
+	if err != nil {
+		return fmt.Errorf("delete temp route: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return network.ErrTempRouteNotFound
+	}
+	return nil
+}
+
+func (r *NetworkRepository) ListExpiredTempRoutes(ctx context.Context, before time.Time) ([]*network.TempRoute, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+tempRouteColumns+` FROM peer_temp_routes WHERE expires_at <= $1`, before)
+	if err != nil {
+		return nil, fmt.Errorf("list expired temp routes: %w", err)
+	}
+	return scanTempRoutes(rows)
+}
+
+func scanTempRoutes(rows *sql.Rows) ([]*network.TempRoute, error) {
+	defer func() { _ = rows.Close() }()
+	out := make([]*network.TempRoute, 0)
+	for rows.Next() {
+		t := &network.TempRoute{}
+		if err := rows.Scan(&t.ID, &t.NetworkID, &t.PeerID, &t.RouteID, &t.DestinationCIDR, &t.DestinationCIDRv6, &t.JumpPeerID, &t.Reason, &t.CreatedBy, &t.CreatedAt, &t.ExpiresAt); err != nil {
+			return nil, err
+		}
+		out = append(out, t)
+	}
+	return out, rows.Err()
+}
+
+// Peer profile operations
+
+const peerProfileColumns = "id, network_id, name, description, mtu, persistent_keepalive, dns, split_tunnel_exclusions, created_at, updated_at"
+
+func (r *NetworkRepository) CreatePeerProfile(ctx context.Context, p *network.PeerProfile) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO peer_profiles
+			(id, network_id, name, description, mtu, persistent_keepalive, dns, split_tunnel_exclusions, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`, p.ID, p.NetworkID, p.Name, p.Description, p.MTU, p.PersistentKeepalive, pq.Array(nonNilStrings(p.DNS)), pq.Array(p.SplitTunnelExclusions), p.CreatedAt, p.UpdatedAt)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return network.ErrDuplicatePeerProfileName
+		}
+		return fmt.Errorf("create peer profile: %w", err)
+	}
+	return nil
+}
+
+func (r *NetworkRepository) GetPeerProfile(ctx context.Context, networkID, profileID string) (*network.PeerProfile, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+peerProfileColumns+` FROM peer_profiles WHERE network_id=$1 AND id=$2`, networkID, profileID)
+	if err != nil {
+		return nil, fmt.Errorf("get peer profile: %w", err)
+	}
+	profiles, err := scanPeerProfiles(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(profiles) == 0 {
+		return nil, network.ErrPeerProfileNotFound
+	}
+	return profiles[0], nil
+}
+
+func (r *NetworkRepository) UpdatePeerProfile(ctx context.Context, p *network.PeerProfile) error {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE peer_profiles SET name=$3, description=$4, mtu=$5, persistent_keepalive=$6, dns=$7, split_tunnel_exclusions=$8, updated_at=$9
+		WHERE network_id=$1 AND id=$2
+	`, p.NetworkID, p.ID, p.Name, p.Description, p.MTU, p.PersistentKeepalive, pq.Array(nonNilStrings(p.DNS)), pq.Array(p.SplitTunnelExclusions), p.UpdatedAt)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return network.ErrDuplicatePeerProfileName
+		}
+		return fmt.Errorf("update peer profile: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return network.ErrPeerProfileNotFound
+	}
+	return nil
+}
+
+func (r *NetworkRepository) DeletePeerProfile(ctx context.Context, networkID, profileID string) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM peer_profiles WHERE network_id=$1 AND id=$2`, networkID, profileID)
+	if err != nil {
+		return fmt.Errorf("delete peer profile: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return network.ErrPeerProfileNotFound
+	}
+	return nil
+}
+
+func (r *NetworkRepository) ListPeerProfiles(ctx context.Context, networkID string) ([]*network.PeerProfile, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+peerProfileColumns+` FROM peer_profiles WHERE network_id=$1 ORDER BY name ASC`, networkID)
+	if err != nil {
+		return nil, fmt.Errorf("list peer profiles: %w", err)
+	}
+	return scanPeerProfiles(rows)
+}
+
+func scanPeerProfiles(rows *sql.Rows) ([]*network.PeerProfile, error) {
+	defer func() { _ = rows.Close() }()
+	out := make([]*network.PeerProfile, 0)
+	for rows.Next() {
+		p := &network.PeerProfile{}
+		var dns, exclusions []string
+		if err := rows.Scan(&p.ID, &p.NetworkID, &p.Name, &p.Description, &p.MTU, &p.PersistentKeepalive, pq.Array(&dns), pq.Array(&exclusions), &p.CreatedAt, &p.UpdatedAt); err != nil {
+			return nil, err
+		}
+		p.DNS = dns
+		p.SplitTunnelExclusions = exclusions
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
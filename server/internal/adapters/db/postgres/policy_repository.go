@@ -14,12 +14,12 @@ import (
 
 // PolicyRepository is a PostgreSQL implementation of network.PolicyRepository
 type PolicyRepository struct {
-	db *sql.DB
+	db tracedDB
 }
 
 // NewPolicyRepository constructs a new PolicyRepository
 func NewPolicyRepository(db *sql.DB) *PolicyRepository {
-	return &PolicyRepository{db: db}
+	return &PolicyRepository{db: traced(db)}
 }
 
 // CreatePolicy creates a new policy in the database
@@ -56,9 +56,9 @@ func (r *PolicyRepository) CreatePolicy(ctx context.Context, networkID string, p
 	// Insert rules if any
 	for i, rule := range policy.Rules {
 		_, err = tx.ExecContext(ctx, `
-			INSERT INTO policy_rules (id, policy_id, direction, action, target, target_type, description, rule_order, created_at)
-			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
-		`, rule.ID, policy.ID, rule.Direction, rule.Action, rule.Target, rule.TargetType, rule.Description, i, now)
+			INSERT INTO policy_rules (id, policy_id, direction, action, target, target_type, protocol, ports, description, rule_order, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		`, rule.ID, policy.ID, rule.Direction, rule.Action, rule.Target, rule.TargetType, rule.Protocol, rule.Ports, rule.Description, i, now)
 		if err != nil {
 			return fmt.Errorf("create policy rule: %w", err)
 		}
@@ -210,9 +210,9 @@ func (r *PolicyRepository) AddRuleToPolicy(ctx context.Context, networkID, polic
 
 	// Insert rule
 	_, err = tx.ExecContext(ctx, `
-		INSERT INTO policy_rules (id, policy_id, direction, action, target, target_type, description, rule_order, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
-	`, rule.ID, policyID, rule.Direction, rule.Action, rule.Target, rule.TargetType, rule.Description, nextOrder, time.Now())
+		INSERT INTO policy_rules (id, policy_id, direction, action, target, target_type, protocol, ports, description, rule_order, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	`, rule.ID, policyID, rule.Direction, rule.Action, rule.Target, rule.TargetType, rule.Protocol, rule.Ports, rule.Description, nextOrder, time.Now())
 	if err != nil {
 		return fmt.Errorf("add rule to policy: %w", err)
 	}
@@ -306,9 +306,9 @@ func (r *PolicyRepository) UpdateRule(ctx context.Context, networkID, policyID s
 	// Update rule
 	res, err := tx.ExecContext(ctx, `
 		UPDATE policy_rules
-		SET direction = $3, action = $4, target = $5, target_type = $6, description = $7
+		SET direction = $3, action = $4, target = $5, target_type = $6, protocol = $7, ports = $8, description = $9
 		WHERE id = $1 AND policy_id = $2
-	`, rule.ID, policyID, rule.Direction, rule.Action, rule.Target, rule.TargetType, rule.Description)
+	`, rule.ID, policyID, rule.Direction, rule.Action, rule.Target, rule.TargetType, rule.Protocol, rule.Ports, rule.Description)
 	if err != nil {
 		return fmt.Errorf("update rule: %w", err)
 	}
@@ -372,7 +372,7 @@ func (r *PolicyRepository) GetPoliciesForGroup(ctx context.Context, networkID, g
 
 func (r *PolicyRepository) loadPolicyRules(ctx context.Context, policyID string) ([]network.PolicyRule, error) {
 	rows, err := r.db.QueryContext(ctx, `
-		SELECT id, direction, action, target, target_type, description
+		SELECT id, direction, action, target, target_type, protocol, ports, description
 		FROM policy_rules
 		WHERE policy_id = $1
 		ORDER BY rule_order ASC
@@ -385,7 +385,7 @@ func (r *PolicyRepository) loadPolicyRules(ctx context.Context, policyID string)
 	rules := make([]network.PolicyRule, 0)
 	for rows.Next() {
 		var rule network.PolicyRule
-		err = rows.Scan(&rule.ID, &rule.Direction, &rule.Action, &rule.Target, &rule.TargetType, &rule.Description)
+		err = rows.Scan(&rule.ID, &rule.Direction, &rule.Action, &rule.Target, &rule.TargetType, &rule.Protocol, &rule.Ports, &rule.Description)
 		if err != nil {
 			return nil, fmt.Errorf("scan policy rule: %w", err)
 		}
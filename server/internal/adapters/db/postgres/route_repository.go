@@ -14,12 +14,12 @@ import (
 
 // RouteRepository is a PostgreSQL implementation of network.RouteRepository
 type RouteRepository struct {
-	db *sql.DB
+	db tracedDB
 }
 
 // NewRouteRepository constructs a new RouteRepository
 func NewRouteRepository(db *sql.DB) *RouteRepository {
-	return &RouteRepository{db: db}
+	return &RouteRepository{db: traced(db)}
 }
 
 // nullStr converts a Go string to a sql.NullString.  Empty string maps to NULL
@@ -60,19 +60,22 @@ func (r *RouteRepository) CreateRoute(ctx context.Context, networkID string, rou
 	}
 	defer func() { _ = tx.Rollback() }()
 
-	// Verify jump peer exists and belongs to network
-	var isJump bool
-	err = tx.QueryRowContext(ctx, `
-		SELECT is_jump FROM peers WHERE id = $1 AND network_id = $2
-	`, route.JumpPeerID, networkID).Scan(&isJump)
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return fmt.Errorf("jump peer not found")
+	// Verify jump peer exists and belongs to network.  Cloned routes have
+	// none until one is assigned.
+	if route.JumpPeerID != "" {
+		var isJump bool
+		err = tx.QueryRowContext(ctx, `
+			SELECT is_jump FROM peers WHERE id = $1 AND network_id = $2
+		`, route.JumpPeerID, networkID).Scan(&isJump)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return fmt.Errorf("jump peer not found")
+			}
+			return fmt.Errorf("check jump peer: %w", err)
+		}
+		if !isJump {
+			return fmt.Errorf("peer is not a jump peer")
 		}
-		return fmt.Errorf("check jump peer: %w", err)
-	}
-	if !isJump {
-		return fmt.Errorf("peer is not a jump peer")
 	}
 
 	// Insert route — both destination_cidr columns are NULLABLE since the
@@ -80,12 +83,12 @@ func (r *RouteRepository) CreateRoute(ctx context.Context, networkID string, rou
 	// at least one is set, but we trust the service layer to have validated
 	// before reaching here.
 	_, err = tx.ExecContext(ctx, `
-		INSERT INTO routes (id, network_id, name, description, destination_cidr, destination_cidr_v6, jump_peer_id, domain_suffix, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		INSERT INTO routes (id, network_id, name, description, destination_cidr, destination_cidr_v6, jump_peer_id, domain_suffix, created_at, updated_at, metric, global_route)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
 	`,
 		route.ID, networkID, route.Name, route.Description,
 		nullStr(route.DestinationCIDR), nullStr(route.DestinationCIDRv6),
-		route.JumpPeerID, route.DomainSuffix, route.CreatedAt, route.UpdatedAt)
+		nullStr(route.JumpPeerID), route.DomainSuffix, route.CreatedAt, route.UpdatedAt, route.Metric, route.GlobalRoute)
 	if err != nil {
 		// Check for unique constraint violation
 		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
@@ -104,22 +107,24 @@ func (r *RouteRepository) CreateRoute(ctx context.Context, networkID string, rou
 // scanRoute pulls a route row out of a Scanner with the new dual-stack columns.
 // Centralised so all SELECTs read the same columns in the same order.
 func scanRoute(s interface{ Scan(...interface{}) error }, route *network.Route) error {
-	var cidr, cidrV6 sql.NullString
+	var cidr, cidrV6, jumpPeerID sql.NullString
 	if err := s.Scan(
 		&route.ID, &route.NetworkID, &route.Name, &route.Description,
 		&cidr, &cidrV6,
-		&route.JumpPeerID, &route.DomainSuffix, &route.CreatedAt, &route.UpdatedAt,
+		&jumpPeerID, &route.DomainSuffix, &route.CreatedAt, &route.UpdatedAt,
+		&route.Metric, &route.GlobalRoute,
 	); err != nil {
 		return err
 	}
 	route.DestinationCIDR = strFromNull(cidr)
 	route.DestinationCIDRv6 = strFromNull(cidrV6)
+	route.JumpPeerID = strFromNull(jumpPeerID)
 	return nil
 }
 
 // routeColumns is the column list every SELECT * for routes must use, in the
 // order scanRoute expects.
-const routeColumns = "id, network_id, name, description, destination_cidr, destination_cidr_v6, jump_peer_id, domain_suffix, created_at, updated_at"
+const routeColumns = "id, network_id, name, description, destination_cidr, destination_cidr_v6, jump_peer_id, domain_suffix, created_at, updated_at, metric, global_route"
 
 // GetRoute retrieves a route by ID
 func (r *RouteRepository) GetRoute(ctx context.Context, networkID, routeID string) (*network.Route, error) {
@@ -169,12 +174,12 @@ func (r *RouteRepository) UpdateRoute(ctx context.Context, networkID string, rou
 	// Update route
 	res, err := tx.ExecContext(ctx, `
 		UPDATE routes
-		SET name = $3, description = $4, destination_cidr = $5, destination_cidr_v6 = $6, jump_peer_id = $7, domain_suffix = $8, updated_at = $9
+		SET name = $3, description = $4, destination_cidr = $5, destination_cidr_v6 = $6, jump_peer_id = $7, domain_suffix = $8, updated_at = $9, metric = $10, global_route = $11
 		WHERE id = $1 AND network_id = $2
 	`,
 		route.ID, networkID, route.Name, route.Description,
 		nullStr(route.DestinationCIDR), nullStr(route.DestinationCIDRv6),
-		route.JumpPeerID, route.DomainSuffix, route.UpdatedAt)
+		nullStr(route.JumpPeerID), route.DomainSuffix, route.UpdatedAt, route.Metric, route.GlobalRoute)
 	if err != nil {
 		// Check for unique constraint violation
 		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
@@ -241,7 +246,7 @@ func (r *RouteRepository) ListRoutes(ctx context.Context, networkID string) ([]*
 // GetRoutesForGroup retrieves all routes attached to a group
 func (r *RouteRepository) GetRoutesForGroup(ctx context.Context, networkID, groupID string) ([]*network.Route, error) {
 	rows, err := r.db.QueryContext(ctx, `
-		SELECT r.id, r.network_id, r.name, r.description, r.destination_cidr, r.destination_cidr_v6, r.jump_peer_id, r.domain_suffix, r.created_at, r.updated_at
+		SELECT r.id, r.network_id, r.name, r.description, r.destination_cidr, r.destination_cidr_v6, r.jump_peer_id, r.domain_suffix, r.created_at, r.updated_at, r.metric, r.global_route
 		FROM routes r
 		INNER JOIN group_routes gr ON r.id = gr.route_id
 		WHERE gr.group_id = $1 AND r.network_id = $2
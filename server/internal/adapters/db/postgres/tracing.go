@@ -0,0 +1,70 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"wirety/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// maxStatementLen caps the db.statement attribute so large INSERTs do not
+// bloat exported spans.
+const maxStatementLen = 512
+
+// tracedDB wraps a *sql.DB so the context-aware statement methods record a
+// client span per query.  Statements run inside a transaction are not traced
+// individually.
+type tracedDB struct {
+	*sql.DB
+}
+
+func traced(db *sql.DB) tracedDB { return tracedDB{DB: db} }
+
+func (d tracedDB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	ctx, span := startQuerySpan(ctx, query)
+	defer span.End()
+	res, err := d.DB.ExecContext(ctx, query, args...)
+	tracing.RecordError(span, err)
+	return res, err
+}
+
+func (d tracedDB) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	ctx, span := startQuerySpan(ctx, query)
+	defer span.End()
+	rows, err := d.DB.QueryContext(ctx, query, args...)
+	tracing.RecordError(span, err)
+	return rows, err
+}
+
+func (d tracedDB) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	ctx, span := startQuerySpan(ctx, query)
+	defer span.End()
+	row := d.DB.QueryRowContext(ctx, query, args...)
+	if err := row.Err(); err != nil && err != sql.ErrNoRows {
+		tracing.RecordError(span, err)
+	}
+	return row
+}
+
+// startQuerySpan names the span after the statement's verb ("postgres
+// SELECT") and records the statement text with whitespace collapsed.
+func startQuerySpan(ctx context.Context, query string) (context.Context, trace.Span) {
+	if !tracing.Enabled() {
+		return ctx, trace.SpanFromContext(ctx)
+	}
+	statement := strings.Join(strings.Fields(query), " ")
+	verb, _, _ := strings.Cut(statement, " ")
+	if len(statement) > maxStatementLen {
+		statement = statement[:maxStatementLen]
+	}
+	return tracing.Start(ctx, "postgres "+strings.ToUpper(verb),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", "postgresql"),
+			attribute.String("db.statement", statement),
+		))
+}
@@ -13,11 +13,11 @@ import (
 
 // UserRepository is a Postgres implementation of auth.Repository
 type UserRepository struct {
-	db *sql.DB
+	db tracedDB
 }
 
 // NewUserRepository constructs a UserRepository
-func NewUserRepository(db *sql.DB) *UserRepository { return &UserRepository{db: db} }
+func NewUserRepository(db *sql.DB) *UserRepository { return &UserRepository{db: traced(db)} }
 
 // scanUser scans a user row into an auth.User
 func scanUser(rows scanner) (*auth.User, error) {
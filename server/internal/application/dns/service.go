@@ -5,36 +5,44 @@ import (
 	"fmt"
 	"time"
 
+	"wirety/internal/audit"
 	"wirety/internal/domain/network"
 
 	"github.com/google/uuid"
 )
 
-// WebSocketNotifier is an interface for notifying peers about config updates
+// WebSocketNotifier is an interface for notifying peers about DNS updates.
+// DNS records are only served by jump peers, so a change pushes the new DNS
+// config to them without regenerating any WireGuard config.
 type WebSocketNotifier interface {
-	NotifyNetworkPeers(networkID string)
+	NotifyNetworkDNS(networkID string)
 }
 
-// DNSRecord represents a combined DNS record (peer or route-based).
+// DNSRecord represents a combined DNS record (peer, route or network-based).
 //
 // Dual-stack: a single record can carry both an IPv4 (IPAddress) and an IPv6
-// (IPv6Address) value.  At least one is always non-empty.  For peer records
-// IPv4 = peer.Address, IPv6 = peer.AddressV6.  For route-based records both
-// fields come from the underlying DNSMapping (since migration 027).
+// (IPv6Address) value.  At least one is always non-empty, except for the
+// CNAME and TXT network records, which carry their target or text in Value.
+// For peer records IPv4 = peer.Address, IPv6 = peer.AddressV6.  For
+// route-based records both fields come from the underlying DNSMapping (since
+// migration 027).
 type DNSRecord struct {
 	Name        string `json:"name"`
 	IPAddress   string `json:"ip_address,omitempty"`
 	IPv6Address string `json:"ip_address_v6,omitempty"`
 	FQDN        string `json:"fqdn"`
-	Type        string `json:"type"` // "peer" or "route"
+	Type        string `json:"type"`                  // "peer", "route" or "network"
+	RecordType  string `json:"record_type,omitempty"` // "CNAME" or "TXT"; empty for address records
+	Value       string `json:"value,omitempty"`
 }
 
 // Service implements the business logic for DNS mapping management
 type Service struct {
-	dnsRepo    network.DNSRepository
-	routeRepo  network.RouteRepository
-	peerRepo   network.Repository
-	wsNotifier WebSocketNotifier
+	dnsRepo     network.DNSRepository
+	routeRepo   network.RouteRepository
+	peerRepo    network.Repository
+	wsNotifier  WebSocketNotifier
+	auditLogger audit.AuditLogger
 }
 
 // NewService creates a new DNS service
@@ -51,6 +59,11 @@ func (s *Service) SetWebSocketNotifier(notifier WebSocketNotifier) {
 	s.wsNotifier = notifier
 }
 
+// SetAuditLogger sets the audit log that records mutating operations
+func (s *Service) SetAuditLogger(l audit.AuditLogger) {
+	s.auditLogger = l
+}
+
 // CreateDNSMapping creates a new DNS mapping with IP validation within route CIDR.
 // Dual-stack: each address is validated against the SAME-FAMILY CIDR on the
 // route.  Submitting an IPv6 address on a route that has no IPv6 destination
@@ -101,9 +114,11 @@ func (s *Service) CreateDNSMapping(ctx context.Context, networkID, routeID strin
 
 	// Trigger DNS server updates via WebSocket
 	if s.wsNotifier != nil {
-		s.wsNotifier.NotifyNetworkPeers(route.NetworkID)
+		s.wsNotifier.NotifyNetworkDNS(route.NetworkID)
 	}
 
+	audit.Record(ctx, s.auditLogger, "dns.create", networkID, "", mapping.ID)
+
 	return mapping, nil
 }
 
@@ -169,9 +184,11 @@ func (s *Service) UpdateDNSMapping(ctx context.Context, networkID, routeID, mapp
 
 	// Trigger DNS server updates via WebSocket
 	if s.wsNotifier != nil {
-		s.wsNotifier.NotifyNetworkPeers(route.NetworkID)
+		s.wsNotifier.NotifyNetworkDNS(route.NetworkID)
 	}
 
+	audit.Record(ctx, s.auditLogger, "dns.update", networkID, "", mappingID)
+
 	return mapping, nil
 }
 
@@ -195,9 +212,11 @@ func (s *Service) DeleteDNSMapping(ctx context.Context, networkID, routeID, mapp
 
 	// Trigger DNS server updates via WebSocket
 	if s.wsNotifier != nil {
-		s.wsNotifier.NotifyNetworkPeers(route.NetworkID)
+		s.wsNotifier.NotifyNetworkDNS(route.NetworkID)
 	}
 
+	audit.Record(ctx, s.auditLogger, "dns.delete", networkID, "", mappingID)
+
 	return nil
 }
 
@@ -217,7 +236,137 @@ func (s *Service) ListDNSMappings(ctx context.Context, networkID, routeID string
 	return mappings, nil
 }
 
-// GetNetworkDNSRecords combines peer and route DNS records
+// CreateNetworkDNSRecord creates a DNS record scoped to the network rather
+// than a route.  Unlike route mappings, its addresses are not restricted to
+// any CIDR.
+func (s *Service) CreateNetworkDNSRecord(ctx context.Context, networkID string, req *network.DNSMappingCreateRequest) (*network.NetworkDNSRecord, error) {
+	if err := req.Validate(); err != nil {
+		return nil, fmt.Errorf("validation failed: %w", err)
+	}
+	if _, err := s.peerRepo.GetNetwork(ctx, networkID); err != nil {
+		return nil, fmt.Errorf("network not found: %w", err)
+	}
+
+	now := time.Now()
+	record := &network.NetworkDNSRecord{
+		ID:          uuid.New().String(),
+		NetworkID:   networkID,
+		Name:        req.Name,
+		IPAddress:   req.IPAddress,
+		IPv6Address: req.IPv6Address,
+		Type:        network.DNSRecordTypeA,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if err := s.checkNetworkDNSRecordConflict(ctx, record); err != nil {
+		return nil, err
+	}
+	if err := s.dnsRepo.CreateNetworkDNSRecord(ctx, record); err != nil {
+		return nil, fmt.Errorf("failed to create network DNS record: %w", err)
+	}
+
+	if s.wsNotifier != nil {
+		s.wsNotifier.NotifyNetworkDNS(networkID)
+	}
+
+	audit.Record(ctx, s.auditLogger, "dns.record_create", networkID, "", record.ID)
+
+	return record, nil
+}
+
+// GetNetworkDNSRecord retrieves a network-scoped DNS record by ID
+func (s *Service) GetNetworkDNSRecord(ctx context.Context, networkID, recordID string) (*network.NetworkDNSRecord, error) {
+	record, err := s.dnsRepo.GetNetworkDNSRecord(ctx, networkID, recordID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get network DNS record: %w", err)
+	}
+	return record, nil
+}
+
+// UpdateNetworkDNSRecord updates a network-scoped DNS record
+func (s *Service) UpdateNetworkDNSRecord(ctx context.Context, networkID, recordID string, req *network.DNSMappingUpdateRequest) (*network.NetworkDNSRecord, error) {
+	if err := req.Validate(); err != nil {
+		return nil, fmt.Errorf("validation failed: %w", err)
+	}
+
+	record, err := s.dnsRepo.GetNetworkDNSRecord(ctx, networkID, recordID)
+	if err != nil {
+		return nil, fmt.Errorf("network DNS record not found: %w", err)
+	}
+	if !record.IsAddress() && (req.IPAddress != "" || req.IPv6Address != "") {
+		return nil, fmt.Errorf("validation failed: a %s record has no IP address", record.RecordType())
+	}
+	if req.Name != "" {
+		record.Name = req.Name
+	}
+	if req.IPAddress != "" {
+		record.IPAddress = req.IPAddress
+	}
+	if req.IPv6Address != "" {
+		record.IPv6Address = req.IPv6Address
+	}
+	record.UpdatedAt = time.Now()
+	if err := s.checkNetworkDNSRecordConflict(ctx, record); err != nil {
+		return nil, err
+	}
+
+	if err := s.dnsRepo.UpdateNetworkDNSRecord(ctx, record); err != nil {
+		return nil, fmt.Errorf("failed to update network DNS record: %w", err)
+	}
+
+	if s.wsNotifier != nil {
+		s.wsNotifier.NotifyNetworkDNS(networkID)
+	}
+
+	audit.Record(ctx, s.auditLogger, "dns.record_update", networkID, "", recordID)
+
+	return record, nil
+}
+
+// DeleteNetworkDNSRecord deletes a network-scoped DNS record
+func (s *Service) DeleteNetworkDNSRecord(ctx context.Context, networkID, recordID string) error {
+	if err := s.dnsRepo.DeleteNetworkDNSRecord(ctx, networkID, recordID); err != nil {
+		return fmt.Errorf("failed to delete network DNS record: %w", err)
+	}
+
+	if s.wsNotifier != nil {
+		s.wsNotifier.NotifyNetworkDNS(networkID)
+	}
+
+	audit.Record(ctx, s.auditLogger, "dns.record_delete", networkID, "", recordID)
+
+	return nil
+}
+
+// checkNetworkDNSRecordConflict returns ErrDuplicateNetworkDNSName when
+// another record of the network cannot coexist with record.  The database
+// only enforces one record per name and type; a CNAME must also be alone.
+func (s *Service) checkNetworkDNSRecordConflict(ctx context.Context, record *network.NetworkDNSRecord) error {
+	existing, err := s.dnsRepo.ListNetworkDNSRecords(ctx, record.NetworkID)
+	if err != nil {
+		return fmt.Errorf("failed to list network DNS records: %w", err)
+	}
+	for _, other := range existing {
+		if record.ConflictsWith(other) {
+			return network.ErrDuplicateNetworkDNSName
+		}
+	}
+	return nil
+}
+
+// ListNetworkDNSRecords lists the network-scoped DNS records of a network
+func (s *Service) ListNetworkDNSRecords(ctx context.Context, networkID string) ([]*network.NetworkDNSRecord, error) {
+	if _, err := s.peerRepo.GetNetwork(ctx, networkID); err != nil {
+		return nil, fmt.Errorf("network not found: %w", err)
+	}
+	records, err := s.dnsRepo.ListNetworkDNSRecords(ctx, networkID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list network DNS records: %w", err)
+	}
+	return records, nil
+}
+
+// GetNetworkDNSRecords combines peer, route and network DNS records
 func (s *Service) GetNetworkDNSRecords(ctx context.Context, networkID string) ([]DNSRecord, error) {
 	// Verify network exists
 	net, err := s.peerRepo.GetNetwork(ctx, networkID)
@@ -275,5 +424,24 @@ func (s *Service) GetNetworkDNSRecords(ctx context.Context, networkID string) ([
 		})
 	}
 
+	networkRecords, err := s.dnsRepo.ListNetworkDNSRecords(ctx, networkID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list network DNS records: %w", err)
+	}
+	for _, record := range networkRecords {
+		r := DNSRecord{
+			Name:        record.Name,
+			IPAddress:   record.IPAddress,
+			IPv6Address: record.IPv6Address,
+			FQDN:        record.GetFQDN(net),
+			Type:        "network",
+		}
+		if !record.IsAddress() {
+			r.RecordType = record.RecordType()
+			r.Value = record.Value
+		}
+		records = append(records, r)
+	}
+
 	return records, nil
 }
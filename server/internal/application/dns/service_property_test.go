@@ -18,11 +18,13 @@ import (
 
 type mockDNSRepository struct {
 	mappings map[string]*network.DNSMapping // mappingID -> mapping
+	records  map[string]*network.NetworkDNSRecord
 }
 
 func newMockDNSRepository() *mockDNSRepository {
 	return &mockDNSRepository{
 		mappings: make(map[string]*network.DNSMapping),
+		records:  make(map[string]*network.NetworkDNSRecord),
 	}
 }
 
@@ -81,6 +83,50 @@ func (m *mockDNSRepository) GetNetworkDNSMappings(ctx context.Context, networkID
 	return mappings, nil
 }
 
+func (m *mockDNSRepository) CreateNetworkDNSRecord(ctx context.Context, record *network.NetworkDNSRecord) error {
+	for _, existing := range m.records {
+		if record.ConflictsWith(existing) {
+			return network.ErrDuplicateNetworkDNSName
+		}
+	}
+	m.records[record.ID] = record
+	return nil
+}
+
+func (m *mockDNSRepository) GetNetworkDNSRecord(ctx context.Context, networkID, recordID string) (*network.NetworkDNSRecord, error) {
+	record, ok := m.records[recordID]
+	if !ok || record.NetworkID != networkID {
+		return nil, network.ErrNetworkDNSRecordNotFound
+	}
+	return record, nil
+}
+
+func (m *mockDNSRepository) UpdateNetworkDNSRecord(ctx context.Context, record *network.NetworkDNSRecord) error {
+	if _, ok := m.records[record.ID]; !ok {
+		return network.ErrNetworkDNSRecordNotFound
+	}
+	m.records[record.ID] = record
+	return nil
+}
+
+func (m *mockDNSRepository) DeleteNetworkDNSRecord(ctx context.Context, networkID, recordID string) error {
+	if _, ok := m.records[recordID]; !ok {
+		return network.ErrNetworkDNSRecordNotFound
+	}
+	delete(m.records, recordID)
+	return nil
+}
+
+func (m *mockDNSRepository) ListNetworkDNSRecords(ctx context.Context, networkID string) ([]*network.NetworkDNSRecord, error) {
+	var records []*network.NetworkDNSRecord
+	for _, record := range m.records {
+		if record.NetworkID == networkID {
+			records = append(records, record)
+		}
+	}
+	return records, nil
+}
+
 type mockRouteRepository struct {
 	routes map[string]*network.Route // routeID -> route
 }
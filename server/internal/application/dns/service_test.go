@@ -115,6 +115,33 @@ func (m *mockPeerRepository) GetPeerLocalRoutes(ctx context.Context, networkID,
 func (m *mockPeerRepository) ListPeerLocalRoutes(ctx context.Context, networkID string) (map[string][]string, error) {
 	return nil, nil
 }
+func (m *mockPeerRepository) CreateTempRoute(ctx context.Context, route *network.TempRoute) error {
+	return nil
+}
+func (m *mockPeerRepository) ListTempRoutes(ctx context.Context, networkID, peerID string) ([]*network.TempRoute, error) {
+	return nil, nil
+}
+func (m *mockPeerRepository) DeleteTempRoute(ctx context.Context, networkID, id string) error {
+	return nil
+}
+func (m *mockPeerRepository) ListExpiredTempRoutes(ctx context.Context, before time.Time) ([]*network.TempRoute, error) {
+	return nil, nil
+}
+func (m *mockPeerRepository) CreatePeerProfile(ctx context.Context, profile *network.PeerProfile) error {
+	return nil
+}
+func (m *mockPeerRepository) GetPeerProfile(ctx context.Context, networkID, profileID string) (*network.PeerProfile, error) {
+	return nil, network.ErrPeerProfileNotFound
+}
+func (m *mockPeerRepository) UpdatePeerProfile(ctx context.Context, profile *network.PeerProfile) error {
+	return nil
+}
+func (m *mockPeerRepository) DeletePeerProfile(ctx context.Context, networkID, profileID string) error {
+	return nil
+}
+func (m *mockPeerRepository) ListPeerProfiles(ctx context.Context, networkID string) ([]*network.PeerProfile, error) {
+	return nil, nil
+}
 func (m *mockPeerRepository) CreateACL(ctx context.Context, networkID string, acl *network.ACL) error {
 	return nil
 }
@@ -136,6 +163,9 @@ func (m *mockPeerRepository) ListConnections(ctx context.Context, networkID stri
 func (m *mockPeerRepository) DeleteConnection(ctx context.Context, networkID, peer1ID, peer2ID string) error {
 	return nil
 }
+func (m *mockPeerRepository) UpdateConnection(ctx context.Context, networkID string, conn *network.PeerConnection) error {
+	return nil
+}
 func (m *mockPeerRepository) CreateOrUpdateSession(ctx context.Context, networkID string, session *network.AgentSession) error {
 	return nil
 }
@@ -152,6 +182,14 @@ func (m *mockPeerRepository) ListSessions(ctx context.Context, networkID string)
 	return nil, nil
 }
 
+func (m *mockPeerRepository) ListSessionHistory(ctx context.Context, networkID, peerID string) ([]*network.AgentSession, error) {
+	return nil, nil
+}
+
+func (m *mockPeerRepository) RevokeSession(ctx context.Context, networkID, sessionID string, revokedAt time.Time) error {
+	return nil
+}
+
 // Stub methods for interface compliance
 func (m *mockPeerRepository) CreateNetwork(ctx context.Context, net *network.Network) error {
 	return nil
@@ -165,6 +203,18 @@ func (m *mockPeerRepository) DeleteNetwork(ctx context.Context, networkID string
 func (m *mockPeerRepository) ListNetworks(ctx context.Context) ([]*network.Network, error) {
 	return nil, nil
 }
+func (m *mockPeerRepository) SoftDeleteNetwork(ctx context.Context, networkID string, at time.Time) error {
+	return nil
+}
+func (m *mockPeerRepository) RestoreNetwork(ctx context.Context, networkID string) error {
+	return nil
+}
+func (m *mockPeerRepository) ListDeletedNetworks(ctx context.Context) ([]*network.Network, error) {
+	return nil, nil
+}
+func (m *mockPeerRepository) SearchPeers(ctx context.Context, query string, offset, limit int) ([]*network.PeerSearchResult, int, error) {
+	return nil, 0, nil
+}
 func (m *mockPeerRepository) CreatePeer(ctx context.Context, networkID string, peer *network.Peer) error {
 	return nil
 }
@@ -185,7 +235,7 @@ type mockWebSocketNotifier struct {
 	notifiedNetworks []string
 }
 
-func (m *mockWebSocketNotifier) NotifyNetworkPeers(networkID string) {
+func (m *mockWebSocketNotifier) NotifyNetworkDNS(networkID string) {
 	m.notifiedNetworks = append(m.notifiedNetworks, networkID)
 }
 
@@ -316,7 +366,7 @@ func TestService_CreateDNSMapping(t *testing.T) {
 
 			// Verify WebSocket notification was sent
 			if len(wsNotifier.notifiedNetworks) != 1 || wsNotifier.notifiedNetworks[0] != tt.networkID {
-				t.Errorf("Expected WebSocket notification for network %s", tt.networkID)
+				t.Errorf("Expected DNS update for network %s", tt.networkID)
 			}
 		})
 	}
@@ -506,7 +556,7 @@ func TestService_UpdateDNSMapping(t *testing.T) {
 
 			// Verify WebSocket notification was sent
 			if len(wsNotifier.notifiedNetworks) != 1 || wsNotifier.notifiedNetworks[0] != tt.networkID {
-				t.Errorf("Expected WebSocket notification for network %s", tt.networkID)
+				t.Errorf("Expected DNS update for network %s", tt.networkID)
 			}
 		})
 	}
@@ -556,7 +606,7 @@ func TestService_DeleteDNSMapping(t *testing.T) {
 
 	// Verify WebSocket notification was sent
 	if len(wsNotifier.notifiedNetworks) != 1 || wsNotifier.notifiedNetworks[0] != "net1" {
-		t.Error("Expected WebSocket notification for network net1")
+		t.Error("Expected DNS update for network net1")
 	}
 
 	// Test deleting non-existent mapping
@@ -762,3 +812,66 @@ func TestService_GetNetworkDNSRecords(t *testing.T) {
 		t.Error("Expected error for non-existent network")
 	}
 }
+
+func TestService_NetworkDNSRecords(t *testing.T) {
+	dnsRepo := newMockDNSRepository()
+	routeRepo := newMockRouteRepository()
+	peerRepo := newMockPeerRepository()
+	peerRepo.networks["net1"] = &network.Network{ID: "net1", Name: "testnet", DomainSuffix: "example.com"}
+
+	service := NewService(dnsRepo, routeRepo, peerRepo)
+	ctx := context.Background()
+
+	// The address does not have to lie inside any route
+	record, err := service.CreateNetworkDNSRecord(ctx, "net1", &network.DNSMappingCreateRequest{
+		Name:      "grafana",
+		IPAddress: "203.0.113.7",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	_, err = service.CreateNetworkDNSRecord(ctx, "net1", &network.DNSMappingCreateRequest{
+		Name:      "grafana",
+		IPAddress: "203.0.113.8",
+	})
+	if !errors.Is(err, network.ErrDuplicateNetworkDNSName) {
+		t.Errorf("Expected ErrDuplicateNetworkDNSName, got %v", err)
+	}
+
+	_, err = service.CreateNetworkDNSRecord(ctx, "missing", &network.DNSMappingCreateRequest{
+		Name:      "grafana",
+		IPAddress: "203.0.113.7",
+	})
+	if err == nil {
+		t.Error("Expected error for non-existent network")
+	}
+
+	newIP := "198.51.100.1"
+	updated, err := service.UpdateNetworkDNSRecord(ctx, "net1", record.ID, &network.DNSMappingUpdateRequest{IPAddress: newIP})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if updated.IPAddress != newIP {
+		t.Errorf("Expected IP %s, got %s", newIP, updated.IPAddress)
+	}
+
+	records, err := service.GetNetworkDNSRecords(ctx, "net1")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(records) != 1 || records[0].Type != "network" || records[0].FQDN != "grafana.testnet.example.com" {
+		t.Errorf("Expected one network record for grafana.testnet.example.com, got %+v", records)
+	}
+
+	if err := service.DeleteNetworkDNSRecord(ctx, "net1", record.ID); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	list, err := service.ListNetworkDNSRecords(ctx, "net1")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(list) != 0 {
+		t.Errorf("Expected no records after delete, got %d", len(list))
+	}
+}
@@ -0,0 +1,242 @@
+package dns
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"wirety/internal/audit"
+	"wirety/internal/domain/network"
+
+	"github.com/google/uuid"
+	mdns "github.com/miekg/dns"
+)
+
+// ZoneImportResult reports what ImportNetworkZoneFile did with each record
+// of a zone file.  A record in Errors was skipped; the others were applied.
+type ZoneImportResult struct {
+	Created   int               `json:"created"`
+	Updated   int               `json:"updated"`
+	Unchanged int               `json:"unchanged"`
+	Errors    []ZoneImportError `json:"errors,omitempty"`
+}
+
+// ZoneImportError is a record of a zone file that could not be imported.
+type ZoneImportError struct {
+	Name  string `json:"name"`
+	Type  string `json:"type"`
+	Error string `json:"error"`
+}
+
+// ExportNetworkZoneFile renders the network-scoped DNS records as a zone file
+// that ImportNetworkZoneFile reads back without loss.  Peer and route
+// records are left out since they follow their peers and routes; see
+// GetNetworkZoneFile for the complete zone.
+func (s *Service) ExportNetworkZoneFile(ctx context.Context, networkID string) (string, error) {
+	nw, err := s.peerRepo.GetNetwork(ctx, networkID)
+	if err != nil {
+		return "", fmt.Errorf("network not found: %w", err)
+	}
+	peers, err := s.peerRepo.ListPeers(ctx, networkID)
+	if err != nil {
+		return "", fmt.Errorf("failed to list peers: %w", err)
+	}
+	sort.Slice(peers, func(i, j int) bool { return peers[i].Name < peers[j].Name })
+	records, err := s.dnsRepo.ListNetworkDNSRecords(ctx, networkID)
+	if err != nil {
+		return "", fmt.Errorf("failed to list network DNS records: %w", err)
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		a, b := records[i], records[j]
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		if a.RecordType() != b.RecordType() {
+			return a.RecordType() < b.RecordType()
+		}
+		return a.Value < b.Value
+	})
+
+	domain := zoneDomain(nw)
+	z := &zoneWriter{}
+	z.header(domain, domain, zoneNameServers(peers, domain))
+	for _, r := range records {
+		switch r.RecordType() {
+		case network.DNSRecordTypeCNAME:
+			z.record(r.Name, "CNAME", mdns.Fqdn(r.Value))
+		case network.DNSRecordTypeTXT:
+			// Values keep the zone file escapes they were imported with
+			z.record(r.Name, "TXT", `"`+r.Value+`"`)
+		default:
+			if r.IPAddress != "" {
+				z.record(r.Name, "A", r.IPAddress)
+			}
+			if r.IPv6Address != "" {
+				z.record(r.Name, "AAAA", r.IPv6Address)
+			}
+		}
+	}
+	return z.String(), nil
+}
+
+// ImportNetworkZoneFile creates or updates network-scoped DNS records from
+// an RFC 1035 zone file.  The origin defaults to the network's zone, and
+// owners must be single labels (or wildcards) under it.  A and AAAA records
+// of a name are merged into one address record; CNAME and TXT records are
+// kept as such.  The SOA and NS records of the apex are ignored.  Records
+// already present are updated in place, and records absent from the file
+// are left alone.
+//
+// A syntax error rejects the whole file with ErrInvalidZoneFile; otherwise
+// each record that cannot be imported is reported in the result and the
+// others are applied.
+func (s *Service) ImportNetworkZoneFile(ctx context.Context, networkID, zone string) (*ZoneImportResult, error) {
+	nw, err := s.peerRepo.GetNetwork(ctx, networkID)
+	if err != nil {
+		return nil, fmt.Errorf("network not found: %w", err)
+	}
+	domain := zoneDomain(nw)
+
+	var rrs []mdns.RR
+	zp := mdns.NewZoneParser(strings.NewReader(zone), domain, "")
+	for rr, ok := zp.Next(); ok; rr, ok = zp.Next() {
+		rrs = append(rrs, rr)
+	}
+	if err := zp.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", network.ErrInvalidZoneFile, err)
+	}
+
+	result := &ZoneImportResult{}
+	wanted := zoneRecords(rrs, networkID, domain, result)
+
+	existing, err := s.dnsRepo.ListNetworkDNSRecords(ctx, networkID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list network DNS records: %w", err)
+	}
+	for _, record := range wanted {
+		if err := s.importNetworkDNSRecord(ctx, record, &existing, result); err != nil {
+			result.Errors = append(result.Errors, ZoneImportError{Name: record.Name, Type: record.RecordType(), Error: err.Error()})
+		}
+	}
+
+	if result.Created+result.Updated > 0 {
+		if s.wsNotifier != nil {
+			s.wsNotifier.NotifyNetworkDNS(networkID)
+		}
+		audit.Record(ctx, s.auditLogger, "dns.zone_import", networkID, "", "")
+	}
+	return result, nil
+}
+
+// zoneRecords turns parsed resource records into network DNS records,
+// reporting those that have no network DNS record equivalent.
+func zoneRecords(rrs []mdns.RR, networkID, domain string, result *ZoneImportResult) []*network.NetworkDNSRecord {
+	var records []*network.NetworkDNSRecord
+	addresses := make(map[string]*network.NetworkDNSRecord)
+	reject := func(name, rrType, msg string) {
+		result.Errors = append(result.Errors, ZoneImportError{Name: name, Type: rrType, Error: msg})
+	}
+
+	for _, rr := range rrs {
+		hdr := rr.Header()
+		rrType := mdns.TypeToString[hdr.Rrtype]
+		name := relativeName(hdr.Name, domain)
+		if name == "@" {
+			if hdr.Rrtype != mdns.TypeSOA && hdr.Rrtype != mdns.TypeNS {
+				reject(hdr.Name, rrType, "records at the zone apex are not supported")
+			}
+			continue
+		}
+		if name == hdr.Name {
+			reject(hdr.Name, rrType, "owner is outside the network zone "+domain)
+			continue
+		}
+
+		switch v := rr.(type) {
+		case *mdns.A, *mdns.AAAA:
+			record, ok := addresses[name]
+			if !ok {
+				record = &network.NetworkDNSRecord{NetworkID: networkID, Name: name, Type: network.DNSRecordTypeA}
+				addresses[name] = record
+				records = append(records, record)
+			}
+			if a, isA := v.(*mdns.A); isA {
+				if record.IPAddress != "" {
+					reject(name, rrType, "only one A record per name is supported")
+					continue
+				}
+				record.IPAddress = a.A.String()
+			} else {
+				if record.IPv6Address != "" {
+					reject(name, rrType, "only one AAAA record per name is supported")
+					continue
+				}
+				record.IPv6Address = v.(*mdns.AAAA).AAAA.String()
+			}
+		case *mdns.CNAME:
+			records = append(records, &network.NetworkDNSRecord{
+				NetworkID: networkID,
+				Name:      name,
+				Type:      network.DNSRecordTypeCNAME,
+				Value:     strings.TrimSuffix(v.Target, "."),
+			})
+		case *mdns.TXT:
+			records = append(records, &network.NetworkDNSRecord{
+				NetworkID: networkID,
+				Name:      name,
+				Type:      network.DNSRecordTypeTXT,
+				Value:     strings.Join(v.Txt, ""),
+			})
+		default:
+			reject(name, rrType, "unsupported record type")
+		}
+	}
+	return records
+}
+
+// importNetworkDNSRecord creates record, or updates the existing record of
+// the same name and type.  existing is kept up to date with the changes.
+func (s *Service) importNetworkDNSRecord(ctx context.Context, record *network.NetworkDNSRecord, existing *[]*network.NetworkDNSRecord, result *ZoneImportResult) error {
+	if err := record.Validate(); err != nil {
+		return err
+	}
+
+	for _, current := range *existing {
+		if current.Name != record.Name || current.RecordType() != record.RecordType() {
+			continue
+		}
+		if record.RecordType() == network.DNSRecordTypeTXT && current.Value != record.Value {
+			continue // TXT records only match on their text
+		}
+		if current.IPAddress == record.IPAddress && current.IPv6Address == record.IPv6Address && current.Value == record.Value {
+			result.Unchanged++
+			return nil
+		}
+		updated := *current
+		updated.IPAddress = record.IPAddress
+		updated.IPv6Address = record.IPv6Address
+		updated.Value = record.Value
+		updated.UpdatedAt = time.Now()
+		if err := s.dnsRepo.UpdateNetworkDNSRecord(ctx, &updated); err != nil {
+			return err
+		}
+		*current = updated
+		result.Updated++
+		return nil
+	}
+
+	for _, other := range *existing {
+		if record.ConflictsWith(other) {
+			return network.ErrDuplicateNetworkDNSName
+		}
+	}
+	record.ID = uuid.New().String()
+	if err := s.dnsRepo.CreateNetworkDNSRecord(ctx, record); err != nil {
+		return err
+	}
+	*existing = append(*existing, record)
+	result.Created++
+	return nil
+}
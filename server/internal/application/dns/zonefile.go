@@ -0,0 +1,197 @@
+package dns
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"wirety/internal/domain/network"
+
+	mdns "github.com/miekg/dns"
+)
+
+// Zone kinds rendered by GetNetworkZoneFile.
+const (
+	ZoneForward     = "forward"      // <network>.<suffix> with A/AAAA records
+	ZoneReverseIPv4 = "reverse-ipv4" // in-addr.arpa zone of the network's IPv4 CIDR
+	ZoneReverseIPv6 = "reverse-ipv6" // ip6.arpa zone of the network's IPv6 CIDR
+)
+
+// zoneTTL is the default TTL ($TTL) of generated zone files.
+const zoneTTL = 300
+
+// GetNetworkZoneFile renders the network's DNS records as a BIND-compatible
+// zone file.  The forward zone holds A/AAAA records for peers and route DNS
+// mappings; the reverse zones hold PTR records for the peer addresses in the
+// network's CIDRs.  Route mappings point outside the network's CIDRs and so
+// have no PTR records here.
+//
+// Jump peers run the network's DNS server and are listed as name servers.
+func (s *Service) GetNetworkZoneFile(ctx context.Context, networkID, kind string) (string, error) {
+	nw, err := s.peerRepo.GetNetwork(ctx, networkID)
+	if err != nil {
+		return "", fmt.Errorf("network not found: %w", err)
+	}
+	peers, err := s.peerRepo.ListPeers(ctx, networkID)
+	if err != nil {
+		return "", fmt.Errorf("failed to list peers: %w", err)
+	}
+	sort.Slice(peers, func(i, j int) bool { return peers[i].Name < peers[j].Name })
+
+	domain := zoneDomain(nw)
+	nameServers := zoneNameServers(peers, domain)
+
+	z := &zoneWriter{}
+	switch kind {
+	case "", ZoneForward:
+		records, err := s.GetNetworkDNSRecords(ctx, networkID)
+		if err != nil {
+			return "", err
+		}
+		z.header(domain, domain, nameServers)
+		sort.SliceStable(records, func(i, j int) bool { return records[i].FQDN < records[j].FQDN })
+		for _, r := range records {
+			name := relativeName(mdns.Fqdn(r.FQDN), domain)
+			switch r.RecordType {
+			case network.DNSRecordTypeCNAME:
+				z.record(name, "CNAME", mdns.Fqdn(r.Value))
+				continue
+			case network.DNSRecordTypeTXT:
+				z.record(name, "TXT", `"`+r.Value+`"`)
+				continue
+			}
+			if r.IPAddress != "" {
+				z.record(name, "A", r.IPAddress)
+			}
+			if r.IPv6Address != "" {
+				z.record(name, "AAAA", r.IPv6Address)
+			}
+		}
+	case ZoneReverseIPv4, ZoneReverseIPv6:
+		cidr := nw.CIDR
+		if kind == ZoneReverseIPv6 {
+			cidr = nw.CIDRv6
+		}
+		if cidr == "" {
+			return "", fmt.Errorf("network has no %s CIDR", strings.TrimPrefix(kind, "reverse-"))
+		}
+		origin, ipNet, err := reverseZone(cidr)
+		if err != nil {
+			return "", err
+		}
+		z.header(origin, domain, nameServers)
+		for _, p := range peers {
+			addr := p.Address
+			if kind == ZoneReverseIPv6 {
+				addr = p.AddressV6
+			}
+			ip := hostIP(addr)
+			if ip == nil || !ipNet.Contains(ip) {
+				continue
+			}
+			ptr, err := mdns.ReverseAddr(ip.String())
+			if err != nil {
+				continue
+			}
+			z.record(relativeName(ptr, origin), "PTR", p.Name+"."+domain)
+		}
+	default:
+		return "", fmt.Errorf("unknown zone kind %q", kind)
+	}
+	return z.String(), nil
+}
+
+// zoneDomain returns the forward zone of a network, <network>.<suffix>.
+func zoneDomain(nw *network.Network) string {
+	suffix := nw.DomainSuffix
+	if suffix == "" {
+		suffix = "internal"
+	}
+	return mdns.Fqdn(fmt.Sprintf("%s.%s", nw.Name, suffix))
+}
+
+// zoneNameServers returns the NS names of a zone: its jump peers.
+func zoneNameServers(peers []*network.Peer, domain string) []string {
+	var nameServers []string
+	for _, p := range peers {
+		if p.IsJump {
+			nameServers = append(nameServers, p.Name+"."+domain)
+		}
+	}
+	if len(nameServers) == 0 {
+		// Nothing serves the zone yet; keep the file loadable.
+		nameServers = []string{"localhost."}
+	}
+	return nameServers
+}
+
+// reverseZone returns the arpa origin covering cidr.  Reverse zones are
+// delegated on octet (IPv4) or nibble (IPv6) boundaries, so the prefix is
+// rounded down to the enclosing one.
+func reverseZone(cidr string) (string, *net.IPNet, error) {
+	_, ipNet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid CIDR %q: %w", cidr, err)
+	}
+	ones, _ := ipNet.Mask.Size()
+	var labels []string
+	if ip4 := ipNet.IP.To4(); ip4 != nil {
+		for i := 0; i < ones/8; i++ {
+			labels = append([]string{fmt.Sprint(ip4[i])}, labels...)
+		}
+		return strings.Join(append(labels, "in-addr.arpa."), "."), ipNet, nil
+	}
+	hex := fmt.Sprintf("%x", []byte(ipNet.IP.To16()))
+	for i := 0; i < ones/4; i++ {
+		labels = append([]string{hex[i : i+1]}, labels...)
+	}
+	return strings.Join(append(labels, "ip6.arpa."), "."), ipNet, nil
+}
+
+// hostIP parses a peer address, which may carry a prefix length.
+func hostIP(addr string) net.IP {
+	if idx := strings.IndexByte(addr, '/'); idx != -1 {
+		addr = addr[:idx]
+	}
+	return net.ParseIP(addr)
+}
+
+// relativeName returns fqdn relative to origin ("@" for the apex), or fqdn
+// itself when it lies outside origin.
+func relativeName(fqdn, origin string) string {
+	if strings.EqualFold(fqdn, origin) {
+		return "@"
+	}
+	if strings.HasSuffix(strings.ToLower(fqdn), "."+strings.ToLower(origin)) {
+		return fqdn[:len(fqdn)-len(origin)-1]
+	}
+	return fqdn
+}
+
+type zoneWriter struct {
+	b strings.Builder
+}
+
+// header writes the $ORIGIN/$TTL directives and the SOA and NS records.
+// The serial is the generation time, so secondaries always see a newer zone.
+func (z *zoneWriter) header(origin, domain string, nameServers []string) {
+	fmt.Fprintf(&z.b, "; Generated by Wirety for %s\n", domain)
+	fmt.Fprintf(&z.b, "$ORIGIN %s\n", origin)
+	fmt.Fprintf(&z.b, "$TTL %d\n", zoneTTL)
+	fmt.Fprintf(&z.b, "@\tIN\tSOA\t%s hostmaster.%s %d 3600 600 604800 %d\n",
+		nameServers[0], domain, uint32(time.Now().Unix()), zoneTTL)
+	for _, ns := range nameServers {
+		z.record("@", "NS", ns)
+	}
+}
+
+func (z *zoneWriter) record(name, rrType, value string) {
+	fmt.Fprintf(&z.b, "%s\tIN\t%s\t%s\n", name, rrType, value)
+}
+
+func (z *zoneWriter) String() string {
+	return z.b.String()
+}
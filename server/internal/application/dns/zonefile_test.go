@@ -0,0 +1,248 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"wirety/internal/domain/network"
+
+	mdns "github.com/miekg/dns"
+)
+
+// parseZone parses a zone file with a BIND-compatible parser and returns its
+// records keyed by "<owner> <type>".
+func parseZone(t *testing.T, zone string) map[string]string {
+	t.Helper()
+	records := make(map[string]string)
+	zp := mdns.NewZoneParser(strings.NewReader(zone), "", "")
+	for rr, ok := zp.Next(); ok; rr, ok = zp.Next() {
+		h := rr.Header()
+		key := h.Name + " " + mdns.TypeToString[h.Rrtype]
+		records[key] = strings.TrimPrefix(rr.String(), h.String())
+	}
+	if err := zp.Err(); err != nil {
+		t.Fatalf("zone file does not parse: %v\n%s", err, zone)
+	}
+	return records
+}
+
+func setupZoneService() *Service {
+	dnsRepo := newMockDNSRepository()
+	routeRepo := newMockRouteRepository()
+	peerRepo := newMockPeerRepository()
+
+	peerRepo.networks["net1"] = &network.Network{
+		ID:     "net1",
+		Name:   "testnet",
+		CIDR:   "10.0.0.0/22",
+		CIDRv6: "fd00:1::/64",
+	}
+	peerRepo.peers["net1"] = []*network.Peer{
+		{ID: "jump", Name: "gateway", Address: "10.0.0.1", IsJump: true},
+		{ID: "peer1", Name: "laptop", Address: "10.0.1.10", AddressV6: "fd00:1::10"},
+	}
+	routeRepo.routes["route1"] = &network.Route{
+		ID:              "route1",
+		NetworkID:       "net1",
+		Name:            "backend",
+		DestinationCIDR: "192.168.1.0/24",
+	}
+	dnsRepo.mappings["mapping1"] = &network.DNSMapping{
+		ID:        "mapping1",
+		RouteID:   "route1",
+		Name:      "api",
+		IPAddress: "192.168.1.10",
+	}
+	return NewService(dnsRepo, routeRepo, peerRepo)
+}
+
+func TestService_GetNetworkZoneFile_Forward(t *testing.T) {
+	service := setupZoneService()
+
+	zone, err := service.GetNetworkZoneFile(context.Background(), "net1", ZoneForward)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	records := parseZone(t, zone)
+
+	want := map[string]string{
+		"testnet.internal. SOA":         "gateway.testnet.internal. hostmaster.testnet.internal.",
+		"testnet.internal. NS":          "gateway.testnet.internal.",
+		"gateway.testnet.internal. A":   "10.0.0.1",
+		"laptop.testnet.internal. A":    "10.0.1.10",
+		"laptop.testnet.internal. AAAA": "fd00:1::10",
+		"api.testnet.internal. A":       "192.168.1.10",
+	}
+	for key, value := range want {
+		got, ok := records[key]
+		if !ok {
+			t.Errorf("missing %s record in zone:\n%s", key, zone)
+			continue
+		}
+		if !strings.HasPrefix(got, value) {
+			t.Errorf("%s = %q, want %q", key, got, value)
+		}
+	}
+}
+
+func TestService_GetNetworkZoneFile_Reverse(t *testing.T) {
+	service := setupZoneService()
+
+	zone, err := service.GetNetworkZoneFile(context.Background(), "net1", ZoneReverseIPv4)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(zone, "$ORIGIN 0.10.in-addr.arpa.") {
+		t.Errorf("expected the /22 to be rounded to a /16 reverse zone:\n%s", zone)
+	}
+	records := parseZone(t, zone)
+	if got := records["10.1.0.10.in-addr.arpa. PTR"]; got != "laptop.testnet.internal." {
+		t.Errorf("laptop PTR = %q", got)
+	}
+	if got := records["1.0.0.10.in-addr.arpa. PTR"]; got != "gateway.testnet.internal." {
+		t.Errorf("gateway PTR = %q", got)
+	}
+
+	zone, err = service.GetNetworkZoneFile(context.Background(), "net1", ZoneReverseIPv6)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	records = parseZone(t, zone)
+	ptr, _ := mdns.ReverseAddr("fd00:1::10")
+	if got := records[ptr+" PTR"]; got != "laptop.testnet.internal." {
+		t.Errorf("laptop IPv6 PTR = %q\n%s", got, zone)
+	}
+}
+
+func TestService_GetNetworkZoneFile_Errors(t *testing.T) {
+	service := setupZoneService()
+
+	if _, err := service.GetNetworkZoneFile(context.Background(), "missing", ZoneForward); err == nil {
+		t.Error("expected error for unknown network")
+	}
+	if _, err := service.GetNetworkZoneFile(context.Background(), "net1", "bogus"); err == nil {
+		t.Error("expected error for unknown zone kind")
+	}
+}
+
+// networkRecordSet summarizes the network DNS records of net1, one line per
+// record, for comparisons that ignore IDs and timestamps.
+func networkRecordSet(t *testing.T, service *Service) map[string]bool {
+	t.Helper()
+	records, err := service.ListNetworkDNSRecords(context.Background(), "net1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	set := make(map[string]bool)
+	for _, r := range records {
+		set[strings.Join([]string{r.Name, r.RecordType(), r.IPAddress, r.IPv6Address, r.Value}, " ")] = true
+	}
+	return set
+}
+
+func TestService_ImportNetworkZoneFile_RoundTrip(t *testing.T) {
+	ctx := context.Background()
+	service := setupZoneService()
+	zone := `$ORIGIN testnet.internal.
+$TTL 300
+@        IN SOA  gateway hostmaster 1 3600 600 604800 300
+@        IN NS   gateway
+www      IN A    192.0.2.10
+www      IN AAAA 2001:db8::10
+docs     IN CNAME www.example.com.
+verify   IN TXT  "token-123"
+spf      IN TXT  "v=spf1 include:example.com" " -all"
+spf      IN TXT  "second \"quoted\" text"
+mail     IN MX   10 mx.example.com.
+other.example.com. IN A 192.0.2.20
+deep.sub IN A    192.0.2.30
+`
+	result, err := service.ImportNetworkZoneFile(ctx, "net1", zone)
+	if err != nil {
+		t.Fatalf("ImportNetworkZoneFile: %v", err)
+	}
+	if result.Created != 5 || result.Updated != 0 {
+		t.Errorf("created %d updated %d, want 5 and 0", result.Created, result.Updated)
+	}
+	rejected := make(map[string]bool)
+	for _, e := range result.Errors {
+		rejected[e.Name+" "+e.Type] = true
+	}
+	for _, key := range []string{"mail MX", "other.example.com. A", "deep.sub A"} {
+		if !rejected[key] {
+			t.Errorf("%s should be reported, got errors %+v", key, result.Errors)
+		}
+	}
+	if len(result.Errors) != 3 {
+		t.Errorf("errors = %+v, want 3", result.Errors)
+	}
+
+	imported := networkRecordSet(t, service)
+	for _, want := range []string{
+		"www A 192.0.2.10 2001:db8::10 ",
+		"docs CNAME   www.example.com",
+		"verify TXT   token-123",
+		"spf TXT   v=spf1 include:example.com -all",
+	} {
+		if !imported[want] {
+			t.Errorf("missing imported record %q in %v", want, imported)
+		}
+	}
+
+	exported, err := service.ExportNetworkZoneFile(ctx, "net1")
+	if err != nil {
+		t.Fatalf("ExportNetworkZoneFile: %v", err)
+	}
+	if strings.Contains(exported, "laptop") || strings.Contains(exported, "api") {
+		t.Errorf("export should only hold network records:\n%s", exported)
+	}
+
+	// Re-importing the export changes nothing
+	again, err := service.ImportNetworkZoneFile(ctx, "net1", exported)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Created != 0 || again.Updated != 0 || again.Unchanged != 5 || len(again.Errors) != 0 {
+		t.Errorf("re-import = %+v, want 5 unchanged records", again)
+	}
+
+	// Importing it elsewhere recreates the same records
+	fresh := setupZoneService()
+	if _, err := fresh.ImportNetworkZoneFile(ctx, "net1", exported); err != nil {
+		t.Fatal(err)
+	}
+	if got := networkRecordSet(t, fresh); len(got) != len(imported) {
+		t.Errorf("round trip = %v, want %v", got, imported)
+	} else {
+		for key := range imported {
+			if !got[key] {
+				t.Errorf("round trip lost %q:\n%s", key, exported)
+			}
+		}
+	}
+}
+
+func TestService_ImportNetworkZoneFile_UpdatesAndConflicts(t *testing.T) {
+	ctx := context.Background()
+	service := setupZoneService()
+	if _, err := service.ImportNetworkZoneFile(ctx, "net1", "www IN A 192.0.2.10\nalias IN CNAME www.example.com.\n"); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := service.ImportNetworkZoneFile(ctx, "net1", "www IN A 192.0.2.11\nalias IN A 192.0.2.12\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Updated != 1 || result.Created != 0 || len(result.Errors) != 1 || result.Errors[0].Name != "alias" {
+		t.Errorf("result = %+v, want www updated and alias rejected", result)
+	}
+	if !networkRecordSet(t, service)["www A 192.0.2.11  "] {
+		t.Errorf("www should point at the new address: %v", networkRecordSet(t, service))
+	}
+
+	if _, err := service.ImportNetworkZoneFile(ctx, "net1", "www IN A not-an-ip\n"); !errors.Is(err, network.ErrInvalidZoneFile) {
+		t.Errorf("syntax error: err = %v, want ErrInvalidZoneFile", err)
+	}
+}
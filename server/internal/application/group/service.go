@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"time"
 
+	"wirety/internal/audit"
 	"wirety/internal/domain/network"
 
 	"github.com/google/uuid"
@@ -17,10 +18,11 @@ type WebSocketNotifier interface {
 
 // Service implements the business logic for group management
 type Service struct {
-	groupRepo  network.GroupRepository
-	peerRepo   network.Repository
-	routeRepo  network.RouteRepository
-	wsNotifier WebSocketNotifier
+	groupRepo   network.GroupRepository
+	peerRepo    network.Repository
+	routeRepo   network.RouteRepository
+	wsNotifier  WebSocketNotifier
+	auditLogger audit.AuditLogger
 }
 
 // NewService creates a new group service
@@ -37,6 +39,11 @@ func (s *Service) SetWebSocketNotifier(notifier WebSocketNotifier) {
 	s.wsNotifier = notifier
 }
 
+// SetAuditLogger sets the audit log that records mutating operations
+func (s *Service) SetAuditLogger(l audit.AuditLogger) {
+	s.auditLogger = l
+}
+
 // CreateGroup creates a new group with name validation
 func (s *Service) CreateGroup(ctx context.Context, networkID string, req *network.GroupCreateRequest) (*network.Group, error) {
 	// Validate request
@@ -75,6 +82,8 @@ func (s *Service) CreateGroup(ctx context.Context, networkID string, req *networ
 		return nil, fmt.Errorf("failed to create group: %w", err)
 	}
 
+	audit.Record(ctx, s.auditLogger, "group.create", networkID, "", group.ID)
+
 	return group, nil
 }
 
@@ -116,6 +125,8 @@ func (s *Service) UpdateGroup(ctx context.Context, networkID, groupID string, re
 		return nil, fmt.Errorf("failed to update group: %w", err)
 	}
 
+	audit.Record(ctx, s.auditLogger, "group.update", networkID, "", groupID)
+
 	return group, nil
 }
 
@@ -131,6 +142,8 @@ func (s *Service) DeleteGroup(ctx context.Context, networkID, groupID string) er
 		return fmt.Errorf("failed to delete group: %w", err)
 	}
 
+	audit.Record(ctx, s.auditLogger, "group.delete", networkID, "", groupID)
+
 	return nil
 }
 
@@ -194,6 +207,8 @@ func (s *Service) AddPeerToGroup(ctx context.Context, networkID, groupID, peerID
 		s.wsNotifier.NotifyNetworkPeers(networkID)
 	}
 
+	audit.Record(ctx, s.auditLogger, "group.peer_add", networkID, peerID, groupID)
+
 	return nil
 }
 
@@ -221,6 +236,8 @@ func (s *Service) RemovePeerFromGroup(ctx context.Context, networkID, groupID, p
 		s.wsNotifier.NotifyNetworkPeers(networkID)
 	}
 
+	audit.Record(ctx, s.auditLogger, "group.peer_remove", networkID, peerID, groupID)
+
 	return nil
 }
 
@@ -242,6 +259,8 @@ func (s *Service) AttachPolicyToGroup(ctx context.Context, networkID, groupID, p
 		s.wsNotifier.NotifyNetworkPeers(networkID)
 	}
 
+	audit.Record(ctx, s.auditLogger, "group.policy_attach", networkID, "", groupID)
+
 	return nil
 }
 
@@ -263,6 +282,8 @@ func (s *Service) DetachPolicyFromGroup(ctx context.Context, networkID, groupID,
 		s.wsNotifier.NotifyNetworkPeers(networkID)
 	}
 
+	audit.Record(ctx, s.auditLogger, "group.policy_detach", networkID, "", groupID)
+
 	return nil
 }
 
@@ -300,6 +321,8 @@ func (s *Service) AttachRouteToGroup(ctx context.Context, networkID, groupID, ro
 		s.wsNotifier.NotifyNetworkPeers(networkID)
 	}
 
+	audit.Record(ctx, s.auditLogger, "group.route_attach", networkID, "", groupID)
+
 	return nil
 }
 
@@ -321,6 +344,8 @@ func (s *Service) DetachRouteFromGroup(ctx context.Context, networkID, groupID,
 		s.wsNotifier.NotifyNetworkPeers(networkID)
 	}
 
+	audit.Record(ctx, s.auditLogger, "group.route_detach", networkID, "", groupID)
+
 	return nil
 }
 
@@ -359,5 +384,7 @@ func (s *Service) ReorderGroupPolicies(ctx context.Context, networkID, groupID s
 		s.wsNotifier.NotifyNetworkPeers(networkID)
 	}
 
+	audit.Record(ctx, s.auditLogger, "group.policy_reorder", networkID, "", groupID)
+
 	return nil
 }
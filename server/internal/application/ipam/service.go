@@ -2,9 +2,13 @@ package ipam
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math"
+	"net/netip"
 
 	"wirety/internal/domain/ipam"
+	"wirety/internal/domain/network"
 
 	goipam "github.com/metal-stack/go-ipam"
 	"github.com/rs/zerolog/log"
@@ -13,38 +17,82 @@ import (
 // Service provides IPAM helper operations backed by an IPAM repository.
 // Hexagonal: application layer depends only on ipam.Repository abstraction.
 type Service struct {
-	repo ipam.Repository
+	repo   ipam.Repository
+	sizing CIDRSizing
 }
 
 // NewService constructs an IPAM service using the provided repository.
-func NewService(repo ipam.Repository) *Service { return &Service{repo: repo} }
+func NewService(repo ipam.Repository) *Service {
+	return &Service{repo: repo, sizing: DefaultCIDRSizing}
+}
 
-// SuggestCIDRs returns a list of CIDRs sized to hold at least maxPeers peers.
-// baseCIDR is the root network we carve from (e.g. 10.0.0.0/8). count is how many suggestions.
-func (s *Service) SuggestCIDRs(ctx context.Context, baseCIDR string, maxPeers, count int) (int, []string, error) {
-	if maxPeers <= 0 {
-		return 0, nil, fmt.Errorf("maxPeers must be > 0")
+// ErrTooManyPeers is returned by SuggestCIDRs when the peers do not fit in
+// the largest network it may suggest.
+var ErrTooManyPeers = errors.New("too many peers for the largest suggested network")
+
+// CIDRSizing bounds the networks SuggestCIDRs suggests.  MinPrefix is the
+// largest network (8 for a /8) and MaxPrefix the smallest (30 for a /30).
+// Headroom is the fraction of extra addresses kept on top of the requested
+// peers: 0.5 sizes a network for 150 addresses when 100 peers are asked for.
+type CIDRSizing struct {
+	MinPrefix int
+	MaxPrefix int
+	Headroom  float64
+}
+
+// DefaultCIDRSizing gives the smallest network holding the requested peers,
+// from /30 up to /8.
+var DefaultCIDRSizing = CIDRSizing{MinPrefix: 8, MaxPrefix: 30}
+
+// Validate checks the bounds are ordered IPv4 prefix lengths leaving room
+// for hosts, and the headroom is not negative.
+func (c CIDRSizing) Validate() error {
+	if c.MinPrefix < 0 || c.MaxPrefix > 30 || c.MinPrefix > c.MaxPrefix {
+		return fmt.Errorf("invalid prefix bounds /%d to /%d: need 0 <= minimum <= maximum <= 30", c.MinPrefix, c.MaxPrefix)
 	}
-	if count <= 0 {
-		count = 1
+	if c.Headroom < 0 {
+		return fmt.Errorf("invalid headroom %g: must not be negative", c.Headroom)
 	}
+	return nil
+}
 
-	// Determine required prefix length: smallest prefix with usable hosts >= maxPeers.
-	// Usable hosts = 2^(32-prefix) - 2
-	prefixLen := 32
-	for prefixLen >= 0 {
-		usable := (1 << (32 - prefixLen)) - 2
-		if usable >= maxPeers {
-			break
+// PrefixLength returns the longest prefix whose usable hosts (2^(32-prefix)
+// - 2) hold maxPeers plus the headroom, raised to MaxPrefix for small
+// counts.  It fails with ErrTooManyPeers when even a /MinPrefix is too small.
+func (c CIDRSizing) PrefixLength(maxPeers int) (int, error) {
+	if maxPeers <= 0 {
+		return 0, fmt.Errorf("maxPeers must be > 0")
+	}
+	needed := int(math.Ceil(float64(maxPeers) * (1 + c.Headroom)))
+	prefixLen := c.MaxPrefix
+	for (1<<(32-prefixLen))-2 < needed {
+		if prefixLen == c.MinPrefix {
+			return 0, fmt.Errorf("%w: %d addresses need more than a /%d", ErrTooManyPeers, needed, c.MinPrefix)
 		}
 		prefixLen--
 	}
-	if prefixLen < 0 {
-		return 0, nil, fmt.Errorf("cannot satisfy maxPeers=%d", maxPeers)
+	return prefixLen, nil
+}
+
+// SetCIDRSizing replaces DefaultCIDRSizing for SuggestCIDRs.
+func (s *Service) SetCIDRSizing(c CIDRSizing) error {
+	if err := c.Validate(); err != nil {
+		return err
 	}
-	// Bound prefix so we don't propose absurdly small networks
-	if prefixLen < 8 { // avoid generating giant /7 etc.
-		prefixLen = 8
+	s.sizing = c
+	return nil
+}
+
+// SuggestCIDRs returns a list of CIDRs sized to hold at least maxPeers peers,
+// plus the configured headroom (see CIDRSizing).
+// baseCIDR is the root network we carve from (e.g. 10.0.0.0/8). count is how many suggestions.
+func (s *Service) SuggestCIDRs(ctx context.Context, baseCIDR string, maxPeers, count int) (int, []string, error) {
+	prefixLen, err := s.sizing.PrefixLength(maxPeers)
+	if err != nil {
+		return 0, nil, err
+	}
+	if count <= 0 {
+		count = 1
 	}
 	log.Info().Str("base_cidr", baseCIDR).Int("max_peers", maxPeers).Int("count", count).Msg("suggesting CIDRs")
 
@@ -80,3 +128,58 @@ func (s *Service) SuggestCIDRs(ctx context.Context, baseCIDR string, maxPeers, c
 
 	return prefixLen, cidrs, nil
 }
+
+// ReserveIP keeps ip out of cidr's pool so it is never assigned to a peer,
+// e.g. for a hardware gateway on a routed subnet.  The reservation is
+// persisted by the repository and lasts until ReleaseReservation.
+func (s *Service) ReserveIP(ctx context.Context, cidr, ip string) error {
+	addr, err := addrInPrefix(cidr, ip)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.ReserveIP(ctx, cidr, addr.String()); err != nil {
+		if errors.Is(err, goipam.ErrAlreadyAllocated) {
+			return fmt.Errorf("%w: %s", network.ErrIPAllocated, addr)
+		}
+		return fmt.Errorf("failed to reserve IP: %w", err)
+	}
+	log.Info().Str("cidr", cidr).Str("ip", addr.String()).Msg("reserved IP")
+	return nil
+}
+
+// ReleaseReservation returns a reserved ip to cidr's pool.
+func (s *Service) ReleaseReservation(ctx context.Context, cidr, ip string) error {
+	addr, err := addrInPrefix(cidr, ip)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.ReleaseReservation(ctx, cidr, addr.String()); err != nil {
+		if errors.Is(err, network.ErrReservationNotFound) {
+			return err
+		}
+		return fmt.Errorf("failed to release reservation: %w", err)
+	}
+	log.Info().Str("cidr", cidr).Str("ip", addr.String()).Msg("released IP reservation")
+	return nil
+}
+
+// ListReservations returns the reserved addresses of cidr.
+func (s *Service) ListReservations(ctx context.Context, cidr string) ([]string, error) {
+	return s.repo.ListReservations(ctx, cidr)
+}
+
+// addrInPrefix parses ip and checks it lies within cidr.
+func addrInPrefix(cidr, ip string) (netip.Addr, error) {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("%w: %q", network.ErrInvalidIP, ip)
+	}
+	prefix, err := netip.ParsePrefix(cidr)
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("%w: %q", network.ErrInvalidCIDR, cidr)
+	}
+	if !prefix.Contains(addr) {
+		return netip.Addr{}, fmt.Errorf("%w: %s is outside %s", network.ErrIPNotInNetwork, addr, cidr)
+	}
+	return addr, nil
+}
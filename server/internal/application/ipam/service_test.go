@@ -3,21 +3,26 @@ package ipam
 import (
 	"context"
 	"errors"
+	"strings"
 	"testing"
 
 	"wirety/internal/domain/network"
+
+	goipam "github.com/metal-stack/go-ipam"
 )
 
 // mockIPAMRepository implements ipam.Repository for testing
 type mockIPAMRepository struct {
 	prefixes map[string]*network.IPAMPrefix
 	nextIP   map[string]int // CIDR -> next IP counter
+	reserved map[string]bool
 }
 
 func newMockIPAMRepository() *mockIPAMRepository {
 	return &mockIPAMRepository{
 		prefixes: make(map[string]*network.IPAMPrefix),
 		nextIP:   make(map[string]int),
+		reserved: make(map[string]bool),
 	}
 }
 
@@ -104,6 +109,42 @@ func (m *mockIPAMRepository) ReleaseIP(ctx context.Context, cidr string, ip stri
 	return nil
 }
 
+func (m *mockIPAMRepository) AcquireSpecificIP(ctx context.Context, cidr string, ip string) error {
+	return nil
+}
+
+func (m *mockIPAMRepository) ReserveIP(ctx context.Context, cidr string, ip string) error {
+	if m.reserved[ip] {
+		return goipam.ErrAlreadyAllocated
+	}
+	m.reserved[ip] = true
+	return nil
+}
+
+func (m *mockIPAMRepository) ReleaseReservation(ctx context.Context, cidr string, ip string) error {
+	if !m.reserved[ip] {
+		return network.ErrReservationNotFound
+	}
+	delete(m.reserved, ip)
+	return nil
+}
+
+func (m *mockIPAMRepository) ListReservations(ctx context.Context, cidr string) ([]string, error) {
+	var out []string
+	for ip := range m.reserved {
+		out = append(out, ip)
+	}
+	return out, nil
+}
+
+func (m *mockIPAMRepository) AcquiredIPs(ctx context.Context, cidr string) (uint64, error) {
+	return uint64(len(m.reserved)), nil
+}
+
+func (m *mockIPAMRepository) ListAllocatedIPs(ctx context.Context, cidr string) ([]network.IPAllocation, error) {
+	return nil, nil
+}
+
 // Helper function to calculate usable hosts from CIDR
 func calculateUsableHosts(cidr string) int {
 	// Simple calculation for /24 networks
@@ -376,3 +417,96 @@ func TestService_SuggestCIDRs_ExtremelyLargePeers(t *testing.T) {
 		t.Errorf("Expected 1 CIDR, got %d", len(cidrs))
 	}
 }
+
+func TestService_ReserveIP(t *testing.T) {
+	repo := newMockIPAMRepository()
+	service := NewService(repo)
+	ctx := context.Background()
+
+	if err := service.ReserveIP(ctx, "10.0.0.0/24", "10.0.0.1"); err != nil {
+		t.Fatalf("ReserveIP: %v", err)
+	}
+	if err := service.ReserveIP(ctx, "10.0.0.0/24", "10.0.0.1"); !errors.Is(err, network.ErrIPAllocated) {
+		t.Errorf("expected ErrIPAllocated, got %v", err)
+	}
+	if err := service.ReserveIP(ctx, "10.0.0.0/24", "10.0.1.1"); !errors.Is(err, network.ErrIPNotInNetwork) {
+		t.Errorf("expected ErrIPNotInNetwork, got %v", err)
+	}
+	if err := service.ReserveIP(ctx, "10.0.0.0/24", "gateway"); !errors.Is(err, network.ErrInvalidIP) {
+		t.Errorf("expected ErrInvalidIP, got %v", err)
+	}
+
+	if err := service.ReleaseReservation(ctx, "10.0.0.0/24", "10.0.0.1"); err != nil {
+		t.Fatalf("ReleaseReservation: %v", err)
+	}
+	if err := service.ReleaseReservation(ctx, "10.0.0.0/24", "10.0.0.1"); !errors.Is(err, network.ErrReservationNotFound) {
+		t.Errorf("expected ErrReservationNotFound, got %v", err)
+	}
+}
+
+func TestCIDRSizing_PrefixLength(t *testing.T) {
+	tests := []struct {
+		name     string
+		sizing   CIDRSizing
+		maxPeers int
+		want     int
+	}{
+		{"one peer gets the smallest network", DefaultCIDRSizing, 1, 30},
+		{"exact fit", DefaultCIDRSizing, 14, 28},
+		{"one over a boundary", DefaultCIDRSizing, 15, 27},
+		{"100 peers", DefaultCIDRSizing, 100, 25},
+		{"1000 peers", DefaultCIDRSizing, 1000, 22},
+		{"50% headroom on 10 peers needs 15 addresses", CIDRSizing{MinPrefix: 8, MaxPrefix: 30, Headroom: 0.5}, 10, 27},
+		{"50% headroom on 100 peers needs 150 addresses", CIDRSizing{MinPrefix: 8, MaxPrefix: 30, Headroom: 0.5}, 100, 24},
+		{"50% headroom on 1000 peers needs 1500 addresses", CIDRSizing{MinPrefix: 8, MaxPrefix: 30, Headroom: 0.5}, 1000, 21},
+		{"headroom rounds up", CIDRSizing{MinPrefix: 8, MaxPrefix: 30, Headroom: 0.5}, 43, 25},
+		{"clamped to the smallest network", CIDRSizing{MinPrefix: 20, MaxPrefix: 24}, 5, 24},
+		{"largest network that fits", CIDRSizing{MinPrefix: 20, MaxPrefix: 24}, 4094, 20},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.sizing.PrefixLength(tt.maxPeers)
+			if err != nil {
+				t.Fatalf("PrefixLength(%d): %v", tt.maxPeers, err)
+			}
+			if got != tt.want {
+				t.Errorf("PrefixLength(%d) = /%d, want /%d", tt.maxPeers, got, tt.want)
+			}
+		})
+	}
+
+	if _, err := (CIDRSizing{MinPrefix: 20, MaxPrefix: 24}).PrefixLength(4095); !errors.Is(err, ErrTooManyPeers) {
+		t.Errorf("peers beyond the largest network: got %v, want ErrTooManyPeers", err)
+	}
+	if _, err := (CIDRSizing{MinPrefix: 20, MaxPrefix: 24, Headroom: 0.5}).PrefixLength(3000); !errors.Is(err, ErrTooManyPeers) {
+		t.Errorf("headroom beyond the largest network: got %v, want ErrTooManyPeers", err)
+	}
+}
+
+func TestService_SetCIDRSizing(t *testing.T) {
+	service := NewService(newMockIPAMRepository())
+	for _, invalid := range []CIDRSizing{
+		{MinPrefix: 24, MaxPrefix: 20},
+		{MinPrefix: 8, MaxPrefix: 31},
+		{MinPrefix: -1, MaxPrefix: 30},
+		{MinPrefix: 8, MaxPrefix: 30, Headroom: -0.5},
+	} {
+		if err := service.SetCIDRSizing(invalid); err == nil {
+			t.Errorf("SetCIDRSizing(%+v) accepted invalid bounds", invalid)
+		}
+	}
+
+	if err := service.SetCIDRSizing(CIDRSizing{MinPrefix: 16, MaxPrefix: 30, Headroom: 0.5}); err != nil {
+		t.Fatalf("SetCIDRSizing: %v", err)
+	}
+	prefixLen, cidrs, err := service.SuggestCIDRs(context.Background(), "10.0.0.0/8", 100, 1)
+	if err != nil {
+		t.Fatalf("SuggestCIDRs: %v", err)
+	}
+	if prefixLen != 24 || len(cidrs) != 1 || !strings.HasSuffix(cidrs[0], "/24") {
+		t.Errorf("SuggestCIDRs = /%d %v, want one /24 for 100 peers with 50%% headroom", prefixLen, cidrs)
+	}
+	if _, _, err := service.SuggestCIDRs(context.Background(), "10.0.0.0/8", 70000, 1); !errors.Is(err, ErrTooManyPeers) {
+		t.Errorf("SuggestCIDRs beyond a /16: got %v, want ErrTooManyPeers", err)
+	}
+}
@@ -0,0 +1,145 @@
+package network
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"sort"
+	"time"
+
+	"wirety/internal/domain/network"
+
+	"github.com/rs/zerolog/log"
+)
+
+// PlanCIDRChange works out what moving a network to newCIDR would do without
+// changing anything.  Peers keep their relative order: sorted by current
+// address, they are given the new CIDR's host addresses from the bottom up,
+// skipping reserved ones, which is also the order IPAM hands them out in a
+// fresh prefix.  Regular peers without an agent are reported as blockers
+// since nothing would push them their new address.
+func (s *Service) PlanCIDRChange(ctx context.Context, networkID, newCIDR string) (*network.CIDRChangePlan, error) {
+	net, err := s.repo.GetNetwork(ctx, networkID)
+	if err != nil {
+		return nil, fmt.Errorf("network not found: %w", err)
+	}
+	return s.planCIDRChange(ctx, networkID, net.CIDR, newCIDR)
+}
+
+func (s *Service) planCIDRChange(ctx context.Context, networkID, oldCIDR, newCIDR string) (*network.CIDRChangePlan, error) {
+	prefix, err := netip.ParsePrefix(newCIDR)
+	if err != nil || !prefix.Addr().Is4() {
+		return nil, fmt.Errorf("%w: %q is not an IPv4 CIDR", network.ErrInvalidCIDR, newCIDR)
+	}
+	prefix = prefix.Masked()
+
+	peers, err := s.repo.ListPeers(ctx, networkID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list peers: %w", err)
+	}
+	sort.Slice(peers, func(i, j int) bool {
+		a, errA := netip.ParseAddr(peers[i].Address)
+		b, errB := netip.ParseAddr(peers[j].Address)
+		if errA != nil || errB != nil {
+			return peers[i].Address < peers[j].Address
+		}
+		return a.Less(b)
+	})
+
+	reservations, err := s.repo.ListReservations(ctx, prefix.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list IP reservations: %w", err)
+	}
+	reserved := make(map[netip.Addr]bool, len(reservations))
+	for _, ip := range reservations {
+		if addr, err := netip.ParseAddr(ip); err == nil {
+			reserved[addr] = true
+		}
+	}
+
+	plan := &network.CIDRChangePlan{
+		NetworkID:     networkID,
+		OldCIDR:       oldCIDR,
+		NewCIDR:       prefix.String(),
+		Reassignments: make([]network.PeerReassignment, 0, len(peers)),
+		Blockers:      make([]network.CIDRChangeBlocker, 0),
+	}
+
+	// Skip the network address; the broadcast address is never reached
+	// below because it fails the Contains check on the following address.
+	addr := prefix.Addr().Next()
+	for _, peer := range peers {
+		for reserved[addr] {
+			addr = addr.Next()
+		}
+		if !peer.IsJump && !peer.UseAgent {
+			plan.Blockers = append(plan.Blockers, network.CIDRChangeBlocker{
+				PeerID:   peer.ID,
+				PeerName: peer.Name,
+				Reason:   "static regular peer would require manual reconfiguration",
+			})
+		}
+		if !prefix.Contains(addr) || !prefix.Contains(addr.Next()) {
+			return nil, fmt.Errorf("%w: %s is too small for the network's %d peers", network.ErrInvalidCIDR, prefix, len(peers))
+		}
+		plan.Reassignments = append(plan.Reassignments, network.PeerReassignment{
+			PeerID:     peer.ID,
+			PeerName:   peer.Name,
+			OldAddress: peer.Address,
+			NewAddress: addr.String(),
+		})
+		addr = addr.Next()
+	}
+	return plan, nil
+}
+
+// applyCIDRChangePlan moves every peer to the address the plan gives it.  The
+// plan's addresses are acquired explicitly, so a peer added since the plan
+// was made, or an address taken in the meantime, fails the change instead of
+// silently diverging from what was previewed.
+func (s *Service) applyCIDRChangePlan(ctx context.Context, plan *network.CIDRChangePlan) error {
+	if len(plan.Blockers) > 0 {
+		return fmt.Errorf("cannot change CIDR: network contains static regular peer '%s' which would require manual reconfiguration", plan.Blockers[0].PeerName)
+	}
+
+	if _, err := s.repo.EnsureRootPrefix(ctx, plan.NewCIDR); err != nil {
+		return fmt.Errorf("failed to ensure new root prefix: %w", err)
+	}
+
+	peers, err := s.repo.ListPeers(ctx, plan.NetworkID)
+	if err != nil {
+		return fmt.Errorf("failed to list peers: %w", err)
+	}
+	if len(peers) != len(plan.Reassignments) {
+		return fmt.Errorf("CIDR change plan is stale: network has %d peers, plan covers %d", len(peers), len(plan.Reassignments))
+	}
+	byID := make(map[string]*network.Peer, len(peers))
+	for _, peer := range peers {
+		byID[peer.ID] = peer
+	}
+
+	for _, r := range plan.Reassignments {
+		peer, ok := byID[r.PeerID]
+		if !ok || peer.Address != r.OldAddress {
+			return fmt.Errorf("CIDR change plan is stale: peer %s has changed", r.PeerID)
+		}
+
+		// Release old IP from old CIDR
+		if err := s.repo.ReleaseIP(ctx, plan.OldCIDR, peer.Address); err != nil {
+			// Log but don't fail - old CIDR may not exist in IPAM
+			log.Warn().Err(err).Str("ip", peer.Address).Str("cidr", plan.OldCIDR).Msg("failed to release old IP during CIDR migration")
+		}
+
+		if err := s.repo.AcquireSpecificIP(ctx, plan.NewCIDR, r.NewAddress); err != nil {
+			return fmt.Errorf("failed to allocate planned IP %s for peer %s: %w", r.NewAddress, peer.ID, err)
+		}
+
+		peer.Address = r.NewAddress
+		peer.UpdatedAt = time.Now()
+
+		if err := s.repo.UpdatePeer(ctx, plan.NetworkID, peer); err != nil {
+			return fmt.Errorf("failed to update peer %s with new IP: %w", peer.ID, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,222 @@
+package network
+
+import (
+	"context"
+	"fmt"
+
+	"wirety/internal/audit"
+	"wirety/internal/domain/network"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// CloneNetwork creates a network named newName on newCIDR with the settings,
+// peer profiles, groups, default groups, policies, routes and DNS records of
+// srcNetworkID.  Peers and IP allocations are not copied, and neither is the
+// IPv6 CIDR.  Every cloned entity gets a fresh ID.  Cloned routes have no
+// jump peer until one is assigned, and policy rules targeting a peer are
+// dropped.  If a copy fails, the new network is deleted again.
+func (s *Service) CloneNetwork(ctx context.Context, srcNetworkID, newName, newCIDR string) (*network.Network, error) {
+	src, err := s.repo.GetNetwork(ctx, srcNetworkID)
+	if err != nil {
+		return nil, err
+	}
+
+	net, err := s.CreateNetwork(ctx, &network.NetworkCreateRequest{
+		Name:                  newName,
+		CIDR:                  newCIDR,
+		DNS:                   src.DNS,
+		DomainSuffix:          src.DomainSuffix,
+		PeerNamePattern:       src.PeerNamePattern,
+		Topology:              src.Topology,
+		MultiJumpFailover:     src.MultiJumpFailover,
+		OmitPrivateKeys:       src.OmitPrivateKeys,
+		ConditionalForwarders: src.ConditionalForwarders,
+		Labels:                src.Labels,
+		SearchDomains:         src.SearchDomains,
+		DNSQueryLog:           src.DNSQueryLog,
+		JumpPostUp:            src.JumpPostUp,
+		JumpPostDown:          src.JumpPostDown,
+		JumpNATInterface:      src.JumpNATInterface,
+		ConfigMetadata:        src.ConfigMetadata,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.cloneNetworkResources(ctx, src, net); err != nil {
+		if delErr := s.purgeNetwork(ctx, net); delErr != nil {
+			log.Warn().Err(delErr).Str("network_id", net.ID).Msg("failed to delete partially cloned network")
+		}
+		return nil, fmt.Errorf("failed to clone network: %w", err)
+	}
+
+	audit.Record(ctx, s.auditLogger, "network.clone", net.ID, "", src.ID)
+	return net, nil
+}
+
+// cloneNetworkResources copies everything but the network itself from src to
+// dst, mapping the IDs the copies refer to.
+func (s *Service) cloneNetworkResources(ctx context.Context, src, dst *network.Network) error {
+	profiles, err := s.repo.ListPeerProfiles(ctx, src.ID)
+	if err != nil {
+		return fmt.Errorf("list peer profiles: %w", err)
+	}
+	for _, p := range profiles {
+		cp := *p
+		cp.ID = uuid.New().String()
+		cp.NetworkID = dst.ID
+		if err := s.repo.CreatePeerProfile(ctx, &cp); err != nil {
+			return fmt.Errorf("clone peer profile %q: %w", p.Name, err)
+		}
+	}
+
+	// Old ID -> new ID, for the references between the copies
+	groupIDs := make(map[string]string)
+	policyIDs := make(map[string]string)
+	routeIDs := make(map[string]string)
+
+	var groups []*network.Group
+	if s.groupRepo != nil {
+		if groups, err = s.groupRepo.ListGroups(ctx, src.ID); err != nil {
+			return fmt.Errorf("list groups: %w", err)
+		}
+		for _, g := range groups {
+			cp := &network.Group{
+				ID:          uuid.New().String(),
+				NetworkID:   dst.ID,
+				Name:        g.Name,
+				Description: g.Description,
+				Priority:    g.Priority,
+			}
+			if err := s.groupRepo.CreateGroup(ctx, dst.ID, cp); err != nil {
+				return fmt.Errorf("clone group %q: %w", g.Name, err)
+			}
+			groupIDs[g.ID] = cp.ID
+		}
+	}
+
+	// Non-admin peers of the clone join the copies of the source's default
+	// groups
+	if len(src.DefaultGroupIDs) > 0 {
+		defaults := make([]string, 0, len(src.DefaultGroupIDs))
+		for _, id := range src.DefaultGroupIDs {
+			if newID, ok := groupIDs[id]; ok {
+				defaults = append(defaults, newID)
+			}
+		}
+		dst.DefaultGroupIDs = defaults
+		if err := s.repo.UpdateNetwork(ctx, dst); err != nil {
+			return fmt.Errorf("set default groups: %w", err)
+		}
+	}
+
+	if s.policyRepo != nil {
+		policies, err := s.policyRepo.ListPolicies(ctx, src.ID)
+		if err != nil {
+			return fmt.Errorf("list policies: %w", err)
+		}
+		for _, p := range policies {
+			cp := &network.Policy{
+				ID:          uuid.New().String(),
+				NetworkID:   dst.ID,
+				Name:        p.Name,
+				Description: p.Description,
+				Rules:       make([]network.PolicyRule, 0, len(p.Rules)),
+			}
+			for _, rule := range p.Rules {
+				switch rule.TargetType {
+				case "peer":
+					log.Info().Str("network_id", dst.ID).Str("policy", p.Name).Str("rule_id", rule.ID).
+						Msg("not cloning a policy rule that targets a peer")
+					continue
+				case "group":
+					newID, ok := groupIDs[rule.Target]
+					if !ok {
+						continue
+					}
+					rule.Target = newID
+				}
+				rule.ID = uuid.New().String()
+				cp.Rules = append(cp.Rules, rule)
+			}
+			if err := s.policyRepo.CreatePolicy(ctx, dst.ID, cp); err != nil {
+				return fmt.Errorf("clone policy %q: %w", p.Name, err)
+			}
+			policyIDs[p.ID] = cp.ID
+		}
+	}
+
+	if s.routeRepo != nil {
+		routes, err := s.routeRepo.ListRoutes(ctx, src.ID)
+		if err != nil {
+			return fmt.Errorf("list routes: %w", err)
+		}
+		for _, r := range routes {
+			cp := &network.Route{
+				ID:                uuid.New().String(),
+				NetworkID:         dst.ID,
+				Name:              r.Name,
+				Description:       r.Description,
+				DestinationCIDR:   r.DestinationCIDR,
+				DestinationCIDRv6: r.DestinationCIDRv6,
+				DomainSuffix:      r.DomainSuffix,
+				Metric:            r.Metric,
+				GlobalRoute:       r.GlobalRoute,
+			}
+			if err := s.routeRepo.CreateRoute(ctx, dst.ID, cp); err != nil {
+				return fmt.Errorf("clone route %q: %w", r.Name, err)
+			}
+			routeIDs[r.ID] = cp.ID
+		}
+	}
+
+	if s.dnsRepo != nil {
+		for oldID, newID := range routeIDs {
+			mappings, err := s.dnsRepo.ListDNSMappings(ctx, oldID)
+			if err != nil {
+				return fmt.Errorf("list DNS mappings: %w", err)
+			}
+			for _, m := range mappings {
+				cp := *m
+				cp.ID = uuid.New().String()
+				if err := s.dnsRepo.CreateDNSMapping(ctx, newID, &cp); err != nil {
+					return fmt.Errorf("clone DNS mapping %q: %w", m.Name, err)
+				}
+			}
+		}
+		records, err := s.dnsRepo.ListNetworkDNSRecords(ctx, src.ID)
+		if err != nil {
+			return fmt.Errorf("list network DNS records: %w", err)
+		}
+		for _, rec := range records {
+			cp := *rec
+			cp.ID = uuid.New().String()
+			cp.NetworkID = dst.ID
+			if err := s.dnsRepo.CreateNetworkDNSRecord(ctx, &cp); err != nil {
+				return fmt.Errorf("clone DNS record %q: %w", rec.Name, err)
+			}
+		}
+	}
+
+	// Attachments keep the source's policy order
+	for _, g := range groups {
+		for _, policyID := range g.PolicyIDs {
+			if newID, ok := policyIDs[policyID]; ok {
+				if err := s.groupRepo.AttachPolicyToGroup(ctx, dst.ID, groupIDs[g.ID], newID); err != nil {
+					return fmt.Errorf("attach policy to group %q: %w", g.Name, err)
+				}
+			}
+		}
+		for _, routeID := range g.RouteIDs {
+			if newID, ok := routeIDs[routeID]; ok {
+				if err := s.groupRepo.AttachRouteToGroup(ctx, dst.ID, groupIDs[g.ID], newID); err != nil {
+					return fmt.Errorf("attach route to group %q: %w", g.Name, err)
+				}
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,117 @@
+package network
+
+import (
+	"container/list"
+	"sync"
+)
+
+// configCache holds the configs GeneratePeerConfigWithDNS rendered, keyed by
+// peer and network version.  Every network carries an in-memory version that
+// InvalidateConfigs bumps on a topology, policy, route or DNS change; entries
+// rendered under an older version are never served again.  The cache holds
+// at most size entries and evicts the least recently used one beyond that.
+type configCache struct {
+	mu       sync.Mutex
+	size     int
+	versions map[string]uint64 // networkID -> version
+	entries  map[configCacheKey]*list.Element
+	lru      *list.List // of *cachedConfig, most recently used first
+}
+
+type configCacheKey struct {
+	networkID string
+	peerID    string
+	version   uint64
+}
+
+// cachedConfig is one rendered config.  The DNS config and policy are shared
+// between callers and must not be modified.
+type cachedConfig struct {
+	key    configCacheKey
+	config string
+	dns    *PeerDNSConfig
+	policy *JumpPolicy
+}
+
+func newConfigCache(size int) *configCache {
+	return &configCache{
+		size:     size,
+		versions: make(map[string]uint64),
+		entries:  make(map[configCacheKey]*list.Element),
+		lru:      list.New(),
+	}
+}
+
+// version returns the current version of a network
+func (c *configCache) version(networkID string) uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.versions[networkID]
+}
+
+// get returns the config of a peer rendered under the given network version
+func (c *configCache) get(networkID, peerID string, version uint64) (*cachedConfig, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[configCacheKey{networkID, peerID, version}]
+	if !ok {
+		return nil, false
+	}
+	c.lru.MoveToFront(elem)
+	return elem.Value.(*cachedConfig), true
+}
+
+// put stores a config rendered under the given version.  A config rendered
+// while the network changed is dropped: it may predate the change.
+func (c *configCache) put(entry *cachedConfig) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if entry.key.version != c.versions[entry.key.networkID] {
+		return
+	}
+	if elem, ok := c.entries[entry.key]; ok {
+		elem.Value = entry
+		c.lru.MoveToFront(elem)
+		return
+	}
+	c.entries[entry.key] = c.lru.PushFront(entry)
+	for c.lru.Len() > c.size {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cachedConfig).key)
+	}
+}
+
+// invalidate bumps the version of a network and drops its entries
+func (c *configCache) invalidate(networkID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.versions[networkID]++
+	for elem := c.lru.Front(); elem != nil; {
+		next := elem.Next()
+		if key := elem.Value.(*cachedConfig).key; key.networkID == networkID {
+			c.lru.Remove(elem)
+			delete(c.entries, key)
+		}
+		elem = next
+	}
+}
+
+// SetConfigCacheSize enables caching of generated agent configs, holding at
+// most size of them.  Zero or less disables the cache.
+func (s *Service) SetConfigCacheSize(size int) {
+	if size <= 0 {
+		s.configCache = nil
+		return
+	}
+	s.configCache = newConfigCache(size)
+}
+
+// InvalidateConfigs bumps the config version of a network so the next
+// config generation for each of its peers renders a fresh config.  Call it
+// on every change that can alter a generated config.
+func (s *Service) InvalidateConfigs(networkID string) {
+	if s.configCache != nil {
+		s.configCache.invalidate(networkID)
+	}
+}
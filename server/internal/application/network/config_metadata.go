@@ -0,0 +1,64 @@
+package network
+
+import (
+	"context"
+
+	"wirety/internal/domain/network"
+	"wirety/pkg/wireguard"
+
+	"github.com/rs/zerolog/log"
+)
+
+// configOptions returns the config generation options of a network: the
+// private key is left out of agent configs when the network omits them, and
+// the peer metadata comments are filled in when it asks for them.
+func (s *Service) configOptions(ctx context.Context, net *network.Network, forAgent bool) wireguard.ConfigOptions {
+	opts := wireguard.ConfigOptions{OmitPrivateKey: forAgent && net.OmitPrivateKeys}
+	if net.ConfigMetadata {
+		opts.Metadata = s.peerMetadata(ctx, net)
+	}
+	return opts
+}
+
+// peerMetadata returns the group names and owner of every peer of the
+// network.  Owners are shown by email when the user is known.  A lookup
+// failure only leaves the comments it would have filled out.
+func (s *Service) peerMetadata(ctx context.Context, net *network.Network) map[string]wireguard.PeerMetadata {
+	metadata := make(map[string]wireguard.PeerMetadata, len(net.Peers))
+	owners := make(map[string]string)
+	for id, peer := range net.Peers {
+		meta := wireguard.PeerMetadata{Owner: peer.OwnerID}
+		if peer.OwnerID != "" && s.authRepo != nil {
+			email, seen := owners[peer.OwnerID]
+			if !seen {
+				if user, err := s.authRepo.GetUser(peer.OwnerID); err == nil {
+					email = user.Email
+				}
+				owners[peer.OwnerID] = email
+			}
+			if email != "" {
+				meta.Owner = email
+			}
+		}
+		metadata[id] = meta
+	}
+
+	if s.groupRepo == nil {
+		return metadata
+	}
+	groups, err := s.groupRepo.ListGroups(ctx, net.ID)
+	if err != nil {
+		log.Warn().Err(err).Str("network_id", net.ID).Msg("failed to list groups for config metadata")
+		return metadata
+	}
+	// Groups come ordered by priority, and so do each peer's group names
+	for _, g := range groups {
+		for _, peerID := range g.PeerIDs {
+			if meta, ok := metadata[peerID]; ok {
+				meta.Groups = append(meta.Groups, g.Name)
+				metadata[peerID] = meta
+			}
+		}
+	}
+	return metadata
+}
@@ -0,0 +1,56 @@
+package network
+
+import (
+	"context"
+	"fmt"
+
+	"wirety/internal/domain/network"
+)
+
+// DNSQueryLog is the latest window of queries a jump peer's DNS server
+// received.  Enabled reports whether the network currently logs queries;
+// when it does not, Queries is always empty.
+type DNSQueryLog struct {
+	PeerID  string             `json:"peer_id"`
+	Enabled bool               `json:"enabled"`
+	Queries []network.DNSQuery `json:"queries"`
+}
+
+// GetPeerDNSQueries returns the DNS queries most recently reported by a
+// jump peer's agent, oldest first.
+func (s *Service) GetPeerDNSQueries(ctx context.Context, networkID, peerID string) (*DNSQueryLog, error) {
+	nw, err := s.repo.GetNetwork(ctx, networkID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", network.ErrNetworkNotFound, networkID)
+	}
+	peer, err := s.repo.GetPeer(ctx, networkID, peerID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", network.ErrPeerNotFound, peerID)
+	}
+	if !peer.IsJump {
+		return nil, network.ErrNotJumpPeer
+	}
+
+	out := &DNSQueryLog{PeerID: peerID, Enabled: nw.DNSQueryLog, Queries: []network.DNSQuery{}}
+	if !nw.DNSQueryLog {
+		return out, nil
+	}
+	if session, err := s.repo.GetSession(ctx, networkID, peerID); err == nil && len(session.DNSQueries) > 0 {
+		out.Queries = session.DNSQueries
+	}
+	return out, nil
+}
+
+// recordDNSQueries returns the session's query log with the queries of
+// heartbeat appended, keeping the latest network.MaxDNSQueries.
+func recordDNSQueries(existing *network.AgentSession, heartbeat *network.AgentHeartbeat) []network.DNSQuery {
+	var queries []network.DNSQuery
+	if existing != nil {
+		queries = append(queries, existing.DNSQueries...)
+	}
+	queries = append(queries, heartbeat.DNSQueries...)
+	if len(queries) > network.MaxDNSQueries {
+		queries = queries[len(queries)-network.MaxDNSQueries:]
+	}
+	return queries
+}
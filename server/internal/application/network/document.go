@@ -0,0 +1,310 @@
+package network
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+
+	"wirety/internal/domain/network"
+	"wirety/internal/infrastructure/validation"
+)
+
+// ValidateNetworkDocument runs every check that creating the network, its
+// peers, groups, routes and policies would run, plus the cross-entity checks
+// that only make sense on the whole document (name collisions, references by
+// name, route overlaps, policy lockouts). Nothing is created. The returned error is
+// only set when the existing networks cannot be listed; problems in the
+// document are reported as issues.
+func (s *Service) ValidateNetworkDocument(ctx context.Context, doc *network.NetworkDocument) (*network.ValidationReport, error) {
+	v := &documentValidator{report: &network.ValidationReport{Issues: []network.ValidationIssue{}}}
+
+	existing, err := s.repo.ListNetworks(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list networks: %w", err)
+	}
+
+	v.validateNetwork(doc, existing)
+	jumps := v.validatePeers(doc)
+	v.validateRoutes(doc, jumps)
+	v.validatePolicies(doc)
+	v.validateGroups(doc)
+
+	v.report.Valid = true
+	for _, issue := range v.report.Issues {
+		if issue.Severity == network.SeverityError {
+			v.report.Valid = false
+			break
+		}
+	}
+	return v.report, nil
+}
+
+// documentValidator accumulates the issues found in a NetworkDocument.
+type documentValidator struct {
+	report *network.ValidationReport
+	// networkCIDRs holds the parsed network CIDRs (v4 and/or v6) when valid.
+	networkCIDRs []*net.IPNet
+}
+
+func (v *documentValidator) errorf(path, format string, args ...interface{}) {
+	v.report.Issues = append(v.report.Issues, network.ValidationIssue{Severity: network.SeverityError, Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *documentValidator) warnf(path, format string, args ...interface{}) {
+	v.report.Issues = append(v.report.Issues, network.ValidationIssue{Severity: network.SeverityWarning, Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *documentValidator) validateNetwork(doc *network.NetworkDocument, existing []*network.Network) {
+	req := &doc.Network
+	if err := validateNetworkCreateRequest(req); err != nil {
+		v.errorf("network", "%v", err)
+	}
+	for _, cidr := range []string{req.CIDR, req.CIDRv6} {
+		if cidr == "" || validateNetworkCIDR(cidr) != nil {
+			continue
+		}
+		_, ipnet, _ := net.ParseCIDR(cidr)
+		v.networkCIDRs = append(v.networkCIDRs, ipnet)
+	}
+	for _, n := range existing {
+		if strings.EqualFold(n.Name, req.Name) {
+			v.warnf("network.name", "a network named %q already exists", n.Name)
+			break
+		}
+	}
+}
+
+// validatePeers checks each peer and returns the jump peers by name, for
+// resolving route references.
+func (v *documentValidator) validatePeers(doc *network.NetworkDocument) map[string]bool {
+	isJump := make(map[string]bool, len(doc.Peers))
+	seen := make(map[string]int, len(doc.Peers))
+	patternNet := &network.Network{PeerNamePattern: doc.Network.PeerNamePattern}
+	if patternNet.PeerNamePattern != "" {
+		if _, err := validation.CompilePeerNamePattern(patternNet.PeerNamePattern); err != nil {
+			patternNet.PeerNamePattern = "" // already reported on the network
+		}
+	}
+	hasJump := false
+
+	for i, req := range doc.Peers {
+		path := fmt.Sprintf("peers[%d]", i)
+		if err := validation.ValidateDNSName(req.Name); err != nil {
+			v.errorf(path, "invalid peer name: %v", err)
+		} else if err := checkPeerNamePattern(patternNet, req.Name); err != nil {
+			v.errorf(path, "%v", err)
+		}
+		if req.Endpoint != "" {
+			if err := validation.ValidateEndpointHost(req.Endpoint); err != nil {
+				v.errorf(path, "invalid endpoint: %v", err)
+			}
+		}
+		if err := validatePeerRole(req.Role); err != nil {
+			v.errorf(path, "%v", err)
+		}
+		if _, err := validation.NormalizeAllowedIPs(req.AdditionalAllowedIPs); err != nil {
+			v.errorf(path, "additional_allowed_ips: %v", err)
+		}
+		if err := network.ValidatePeerSettings(req.MTU, req.PersistentKeepalive, req.DNS, req.SplitTunnelExclusions); err != nil {
+			v.errorf(path, "%v", err)
+		}
+
+		key := strings.ToLower(req.Name)
+		if first, dup := seen[key]; dup {
+			v.errorf(path, "peer name %q is already used by peers[%d]", req.Name, first)
+		} else {
+			seen[key] = i
+			isJump[req.Name] = req.IsJump
+		}
+
+		if req.IsJump {
+			hasJump = true
+			if req.Endpoint == "" {
+				v.warnf(path, "jump peer %q has no endpoint, regular peers will not be able to reach it", req.Name)
+			}
+		}
+	}
+
+	if len(doc.Peers) > 0 && !hasJump {
+		v.warnf("peers", "no jump peer: regular peers only connect through jump peers")
+	}
+	return isJump
+}
+
+// documentRoute is a route CIDR being checked for overlaps.
+type documentRoute struct {
+	index int
+	name  string
+	jump  string
+	ipnet *net.IPNet
+}
+
+func (v *documentValidator) validateRoutes(doc *network.NetworkDocument, jumps map[string]bool) {
+	seen := make(map[string]int, len(doc.Routes))
+	var cidrs []documentRoute
+
+	for i := range doc.Routes {
+		req := &doc.Routes[i]
+		path := fmt.Sprintf("routes[%d]", i)
+		if err := req.Validate(); err != nil {
+			v.errorf(path, "%v", err)
+		}
+
+		if first, dup := seen[req.Name]; dup {
+			v.errorf(path, "route name %q is already used by routes[%d]", req.Name, first)
+		} else {
+			seen[req.Name] = i
+		}
+
+		if req.JumpPeerID != "" {
+			jump, ok := jumps[req.JumpPeerID]
+			switch {
+			case !ok:
+				v.errorf(path, "jump peer %q is not defined in peers", req.JumpPeerID)
+			case !jump:
+				v.errorf(path, "peer %q is not a jump peer", req.JumpPeerID)
+			}
+		}
+
+		for _, cidr := range []string{req.DestinationCIDR, req.DestinationCIDRv6} {
+			if cidr == "" {
+				continue
+			}
+			_, ipnet, err := net.ParseCIDR(cidr)
+			if err != nil {
+				continue // already reported by Validate
+			}
+			cidrs = append(cidrs, documentRoute{index: i, name: req.Name, jump: req.JumpPeerID, ipnet: ipnet})
+			for _, netCIDR := range v.networkCIDRs {
+				if cidrsOverlap(ipnet, netCIDR) {
+					v.warnf(path, "%s overlaps the network CIDR %s", ipnet, netCIDR)
+				}
+			}
+		}
+	}
+
+	for a := 0; a < len(cidrs); a++ {
+		for b := a + 1; b < len(cidrs); b++ {
+			ra, rb := cidrs[a], cidrs[b]
+			if ra.index == rb.index || !cidrsOverlap(ra.ipnet, rb.ipnet) {
+				continue
+			}
+			path := fmt.Sprintf("routes[%d]", rb.index)
+			same := ra.ipnet.String() == rb.ipnet.String()
+			switch {
+			case same && ra.jump != rb.jump:
+				v.errorf(path, "%v: %s via route %q and route %q", network.ErrRouteConflict, rb.ipnet, ra.name, rb.name)
+			case same:
+				v.warnf(path, "%s duplicates route %q", rb.ipnet, ra.name)
+			default:
+				v.warnf(path, "%s overlaps %s of route %q", rb.ipnet, ra.ipnet, ra.name)
+			}
+		}
+	}
+}
+
+func (v *documentValidator) validatePolicies(doc *network.NetworkDocument) {
+	seen := make(map[string]int, len(doc.Policies))
+
+	for i := range doc.Policies {
+		req := &doc.Policies[i]
+		path := fmt.Sprintf("policies[%d]", i)
+		if err := req.Validate(); err != nil {
+			v.errorf(path, "%v", err)
+		}
+
+		if first, dup := seen[req.Name]; dup {
+			v.errorf(path, "policy name %q is already used by policies[%d]", req.Name, first)
+		} else {
+			seen[req.Name] = i
+		}
+
+		// A deny rule covering the whole network cuts the peers the policy is
+		// attached to off from every other peer, including the admins that
+		// would have to fix it.
+		for j, rule := range req.Rules {
+			if rule.Action != "deny" || rule.TargetType != "cidr" {
+				continue
+			}
+			_, target, err := net.ParseCIDR(rule.Target)
+			if err != nil {
+				continue
+			}
+			for _, netCIDR := range v.networkCIDRs {
+				if cidrCovers(target, netCIDR) {
+					v.errorf(fmt.Sprintf("%s.rules[%d]", path, j), "denies %s, which covers the whole network %s: attached peers would be locked out of the network", target, netCIDR)
+				}
+			}
+		}
+	}
+}
+
+// validateGroups checks each group, the members it lists and the peer and
+// group targets of policy rules, which all reference entities by name.
+func (v *documentValidator) validateGroups(doc *network.NetworkDocument) {
+	names := func(n int, name func(i int) string) map[string]bool {
+		m := make(map[string]bool, n)
+		for i := 0; i < n; i++ {
+			m[name(i)] = true
+		}
+		return m
+	}
+	peers := names(len(doc.Peers), func(i int) string { return doc.Peers[i].Name })
+	groups := names(len(doc.Groups), func(i int) string { return doc.Groups[i].Name })
+	policies := names(len(doc.Policies), func(i int) string { return doc.Policies[i].Name })
+	routes := names(len(doc.Routes), func(i int) string { return doc.Routes[i].Name })
+
+	seen := make(map[string]int, len(doc.Groups))
+	for i := range doc.Groups {
+		g := &doc.Groups[i]
+		path := fmt.Sprintf("groups[%d]", i)
+		if err := g.GroupCreateRequest.Validate(); err != nil {
+			v.errorf(path, "%v", err)
+		}
+		if first, dup := seen[g.Name]; dup {
+			v.errorf(path, "group name %q is already used by groups[%d]", g.Name, first)
+		} else {
+			seen[g.Name] = i
+		}
+		for j, name := range g.Peers {
+			if !peers[name] {
+				v.errorf(fmt.Sprintf("%s.peers[%d]", path, j), "unknown peer %q", name)
+			}
+		}
+		for j, name := range g.Policies {
+			if !policies[name] {
+				v.errorf(fmt.Sprintf("%s.policies[%d]", path, j), "unknown policy %q", name)
+			}
+		}
+		for j, name := range g.Routes {
+			if !routes[name] {
+				v.errorf(fmt.Sprintf("%s.routes[%d]", path, j), "unknown route %q", name)
+			}
+		}
+	}
+
+	for i, policy := range doc.Policies {
+		for j, rule := range policy.Rules {
+			path := fmt.Sprintf("policies[%d].rules[%d]", i, j)
+			switch {
+			case rule.TargetType == "peer" && !peers[rule.Target]:
+				v.errorf(path, "unknown target peer %q", rule.Target)
+			case rule.TargetType == "group" && !groups[rule.Target]:
+				v.errorf(path, "unknown target group %q", rule.Target)
+			}
+		}
+	}
+}
+
+// cidrsOverlap reports whether a and b share at least one address.
+func cidrsOverlap(a, b *net.IPNet) bool {
+	return a.Contains(b.IP) || b.Contains(a.IP)
+}
+
+// cidrCovers reports whether outer contains every address of inner.
+func cidrCovers(outer, inner *net.IPNet) bool {
+	outerOnes, outerBits := outer.Mask.Size()
+	innerOnes, innerBits := inner.Mask.Size()
+	return outerBits == innerBits && outerOnes <= innerOnes && outer.Contains(inner.IP)
+}
@@ -0,0 +1,53 @@
+package network
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"wirety/internal/infrastructure/validation"
+)
+
+// endpointResolveTimeout bounds the DNS lookup made by CheckEndpoint.
+const endpointResolveTimeout = 5 * time.Second
+
+// EndpointCheck is the outcome of resolving a peer's public endpoint.
+type EndpointCheck struct {
+	Host      string   `json:"host"`
+	Addresses []string `json:"resolved_addresses"`
+	// Warning is set when the host did not resolve.  It is advisory: the
+	// peer has been saved regardless, since the name may only resolve
+	// from where the other peers run.
+	Warning string `json:"warning,omitempty"`
+}
+
+// CheckEndpoint resolves the host part of endpoint, which may carry a port
+// and IPv6 brackets.  IP literals resolve to themselves without a lookup.
+func (s *Service) CheckEndpoint(ctx context.Context, endpoint string) *EndpointCheck {
+	host := validation.EndpointHost(endpoint)
+	check := &EndpointCheck{Host: host, Addresses: []string{}}
+
+	if ip := net.ParseIP(host); ip != nil {
+		check.Addresses = []string{ip.String()}
+		return check
+	}
+
+	lookup := s.lookupHost
+	if lookup == nil {
+		lookup = net.DefaultResolver.LookupHost
+	}
+	ctx, cancel := context.WithTimeout(ctx, endpointResolveTimeout)
+	defer cancel()
+
+	addrs, err := lookup(ctx, host)
+	switch {
+	case err != nil:
+		check.Warning = fmt.Sprintf("endpoint %q does not resolve: %v", host, err)
+	case len(addrs) == 0:
+		check.Warning = fmt.Sprintf("endpoint %q resolves to no address", host)
+	default:
+		check.Addresses = addrs
+	}
+	return check
+}
@@ -0,0 +1,135 @@
+package network
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"wirety/internal/audit"
+	"wirety/internal/domain/network"
+
+	"github.com/rs/zerolog/log"
+)
+
+// errTokenNotFound rejects an empty token, which would otherwise match the
+// peers whose token the expiry sweep revoked.
+var errTokenNotFound = errors.New("token not found")
+
+// newEnrollmentToken returns a random agent enrollment token.
+func newEnrollmentToken() (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// ResolveAgentToken enrolls an agent: it returns networkID, peer for a given
+// enrollment token.  Expired tokens are rejected, and a single-use token is
+// consumed by its first resolve.
+func (s *Service) ResolveAgentToken(ctx context.Context, token string) (string, *network.Peer, error) {
+	if token == "" {
+		return "", nil, errTokenNotFound
+	}
+	s.enrollMu.Lock()
+	defer s.enrollMu.Unlock()
+
+	networkID, peer, err := s.repo.GetPeerByToken(ctx, token)
+	if err != nil {
+		return "", nil, err
+	}
+	now := s.clock()
+	if peer.IsTokenExpired(now) {
+		return "", nil, network.ErrTokenExpired
+	}
+	if peer.TokenSingleUse && peer.TokenUsedAt != nil {
+		return "", nil, network.ErrTokenConsumed
+	}
+	if peer.TokenUsedAt == nil {
+		peer.TokenUsedAt = &now
+		if err := s.repo.UpdatePeer(ctx, networkID, peer); err != nil {
+			return "", nil, fmt.Errorf("failed to record token use: %w", err)
+		}
+	}
+	return networkID, peer, nil
+}
+
+// AuthenticateAgentToken returns networkID, peer for the token an agent
+// authenticates its WebSocket and API calls with.  Unlike ResolveAgentToken
+// it does not consume the token, and an agent that enrolled with it keeps
+// working after the token expired or was used up.
+func (s *Service) AuthenticateAgentToken(ctx context.Context, token string) (string, *network.Peer, error) {
+	if token == "" {
+		return "", nil, errTokenNotFound
+	}
+	networkID, peer, err := s.repo.GetPeerByToken(ctx, token)
+	if err != nil {
+		return "", nil, err
+	}
+	if peer.TokenUsedAt == nil && peer.IsTokenExpired(s.clock()) {
+		return "", nil, network.ErrTokenExpired
+	}
+	return networkID, peer, nil
+}
+
+// RegeneratePeerToken replaces a peer's enrollment token with a fresh one
+// and returns it.  The old token stops resolving at once; the new one keeps
+// the peer's single-use and expiry settings.
+func (s *Service) RegeneratePeerToken(ctx context.Context, networkID, peerID string) (string, error) {
+	s.enrollMu.Lock()
+	defer s.enrollMu.Unlock()
+
+	peer, err := s.repo.GetPeer(ctx, networkID, peerID)
+	if err != nil {
+		return "", fmt.Errorf("peer not found: %w", err)
+	}
+	token, err := newEnrollmentToken()
+	if err != nil {
+		return "", err
+	}
+	peer.Token = token
+	peer.TokenUsedAt = nil
+	peer.UpdatedAt = s.clock()
+	if err := s.repo.UpdatePeer(ctx, networkID, peer); err != nil {
+		return "", fmt.Errorf("failed to update peer: %w", err)
+	}
+	audit.Record(ctx, s.auditLogger, "peer.token_rotate", networkID, peerID, "")
+	return token, nil
+}
+
+// CleanupExpiredEnrollmentTokens revokes the enrollment tokens that expired
+// before any agent enrolled with them.  Tokens in use by an enrolled agent
+// are kept so the agent can reconnect.
+func (s *Service) CleanupExpiredEnrollmentTokens(ctx context.Context) error {
+	networks, err := s.repo.ListNetworks(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list networks: %w", err)
+	}
+
+	s.enrollMu.Lock()
+	defer s.enrollMu.Unlock()
+
+	now := s.clock()
+	for _, net := range networks {
+		peers, err := s.repo.ListPeers(ctx, net.ID)
+		if err != nil {
+			log.Warn().Err(err).Str("network_id", net.ID).Msg("failed to list peers for enrollment token sweep")
+			continue
+		}
+		for _, peer := range peers {
+			if peer.Token == "" || peer.TokenUsedAt != nil || !peer.IsTokenExpired(now) {
+				continue
+			}
+			peer.Token = ""
+			peer.UpdatedAt = now
+			if err := s.repo.UpdatePeer(ctx, net.ID, peer); err != nil {
+				log.Warn().Err(err).Str("network_id", net.ID).Str("peer_id", peer.ID).Msg("failed to revoke expired enrollment token")
+				continue
+			}
+			log.Info().Str("network_id", net.ID).Str("peer_id", peer.ID).Msg("expired enrollment token revoked")
+		}
+	}
+	return nil
+}
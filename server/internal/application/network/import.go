@@ -0,0 +1,174 @@
+package network
+
+import (
+	"context"
+	"fmt"
+
+	"wirety/internal/domain/network"
+)
+
+// GroupImportService is the part of the group service an import uses
+type GroupImportService interface {
+	CreateGroup(ctx context.Context, networkID string, req *network.GroupCreateRequest) (*network.Group, error)
+	AddPeerToGroup(ctx context.Context, networkID, groupID, peerID string) error
+	AttachPolicyToGroup(ctx context.Context, networkID, groupID, policyID string) error
+	AttachRouteToGroup(ctx context.Context, networkID, groupID, routeID string) error
+}
+
+// PolicyImportService is the part of the policy service an import uses
+type PolicyImportService interface {
+	CreatePolicy(ctx context.Context, networkID string, req *network.PolicyCreateRequest) (*network.Policy, error)
+}
+
+// RouteImportService is the part of the route service an import uses
+type RouteImportService interface {
+	CreateRoute(ctx context.Context, networkID string, req *network.RouteCreateRequest) (*network.Route, error)
+}
+
+// Importer creates a network from a network.NetworkDocument, where entities
+// refer to each other by name.  It goes through the same services as the
+// API, in dependency order: the network, peers, groups, policies, routes and
+// finally group memberships, so every entity is validated and announced as
+// if it had been created by hand.  Every entity gets a fresh ID; peers get
+// fresh keys and enrollment tokens.
+type Importer struct {
+	networks *Service
+	groups   GroupImportService
+	policies PolicyImportService
+	routes   RouteImportService
+}
+
+// NewImporter creates an importer on the given services
+func NewImporter(networks *Service, groups GroupImportService, policies PolicyImportService, routes RouteImportService) *Importer {
+	return &Importer{networks: networks, groups: groups, policies: policies, routes: routes}
+}
+
+// ImportNetwork creates doc and returns what was created.  Only a network
+// that cannot be created fails the import; any other entity that fails is
+// reported in the result, along with the entities that needed it.
+func (imp *Importer) ImportNetwork(ctx context.Context, doc *network.NetworkDocument) (*network.NetworkImportResult, error) {
+	net, err := imp.networks.CreateNetwork(ctx, &doc.Network)
+	if err != nil {
+		return nil, err
+	}
+	res := &network.NetworkImportResult{Network: net, Errors: []network.ImportError{}}
+	fail := func(path, format string, args ...interface{}) {
+		res.Errors = append(res.Errors, network.ImportError{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	peerIDs := make(map[string]string, len(doc.Peers))
+	for i := range doc.Peers {
+		req := doc.Peers[i]
+		// Keys are never carried over, and profiles and groups are not
+		// part of the document
+		req.PublicKey = ""
+		req.ProfileID = ""
+		req.GroupIDs = nil
+		peer, err := imp.networks.AddPeer(ctx, net.ID, &req, req.OwnerID)
+		if err != nil {
+			fail(fmt.Sprintf("peers[%d]", i), "%v", err)
+			continue
+		}
+		peerIDs[req.Name] = peer.ID
+		res.Peers++
+	}
+
+	groupIDs := make(map[string]string, len(doc.Groups))
+	for i := range doc.Groups {
+		req := doc.Groups[i].GroupCreateRequest
+		group, err := imp.groups.CreateGroup(ctx, net.ID, &req)
+		if err != nil {
+			fail(fmt.Sprintf("groups[%d]", i), "%v", err)
+			continue
+		}
+		groupIDs[req.Name] = group.ID
+		res.Groups++
+	}
+
+	policyIDs := make(map[string]string, len(doc.Policies))
+policies:
+	for i := range doc.Policies {
+		req := doc.Policies[i]
+		path := fmt.Sprintf("policies[%d]", i)
+		req.Rules = append([]network.PolicyRule(nil), req.Rules...)
+		for j := range req.Rules {
+			rule := &req.Rules[j]
+			var ids map[string]string
+			switch rule.TargetType {
+			case "peer":
+				ids = peerIDs
+			case "group":
+				ids = groupIDs
+			default:
+				continue
+			}
+			id, ok := ids[rule.Target]
+			if !ok {
+				fail(path, "rules[%d]: %s %q was not imported", j, rule.TargetType, rule.Target)
+				continue policies
+			}
+			rule.Target = id
+		}
+		policy, err := imp.policies.CreatePolicy(ctx, net.ID, &req)
+		if err != nil {
+			fail(path, "%v", err)
+			continue
+		}
+		policyIDs[req.Name] = policy.ID
+		res.Policies++
+	}
+
+	routeIDs := make(map[string]string, len(doc.Routes))
+	for i := range doc.Routes {
+		req := doc.Routes[i]
+		path := fmt.Sprintf("routes[%d]", i)
+		jumpID, ok := peerIDs[req.JumpPeerID]
+		if !ok {
+			fail(path, "jump peer %q was not imported", req.JumpPeerID)
+			continue
+		}
+		req.JumpPeerID = jumpID
+		route, err := imp.routes.CreateRoute(ctx, net.ID, &req)
+		if err != nil {
+			fail(path, "%v", err)
+			continue
+		}
+		routeIDs[req.Name] = route.ID
+		res.Routes++
+	}
+
+	// Members of a group that failed are covered by the group's own error
+	for i, g := range doc.Groups {
+		groupID, ok := groupIDs[g.Name]
+		if !ok {
+			continue
+		}
+		link := func(kind string, names []string, ids map[string]string, attach func(id string) error) {
+			for j, name := range names {
+				path := fmt.Sprintf("groups[%d].%ss[%d]", i, kind, j)
+				id, ok := ids[name]
+				if !ok {
+					fail(path, "%s %q was not imported", kind, name)
+					continue
+				}
+				if err := attach(id); err != nil {
+					fail(path, "%v", err)
+				}
+			}
+		}
+		link("peer", g.Peers, peerIDs, func(id string) error {
+			return imp.groups.AddPeerToGroup(ctx, net.ID, groupID, id)
+		})
+		link("policy", g.Policies, policyIDs, func(id string) error {
+			return imp.groups.AttachPolicyToGroup(ctx, net.ID, groupID, id)
+		})
+		link("route", g.Routes, routeIDs, func(id string) error {
+			return imp.groups.AttachRouteToGroup(ctx, net.ID, groupID, id)
+		})
+	}
+
+	if updated, err := imp.networks.GetNetwork(ctx, net.ID); err == nil {
+		res.Network = updated
+	}
+	return res, nil
+}
@@ -0,0 +1,88 @@
+package network
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"wirety/internal/audit"
+	"wirety/internal/domain/network"
+
+	"github.com/rs/zerolog/log"
+)
+
+// IPAMReconcileGracePeriod is how old an allocation must be before the
+// reconciliation may reclaim it.  AddPeer allocates the address before it
+// saves the peer, so a younger allocation may belong to a peer being added.
+const IPAMReconcileGracePeriod = 5 * time.Minute
+
+// ReconcileNetworkIPAM releases the addresses allocated in the network's
+// IPAM prefixes that no peer holds, e.g. after a peer deletion failed to
+// release its address.  Reservations are left alone, and so are allocations
+// younger than IPAMReconcileGracePeriod.  Running it again releases nothing
+// more.
+func (s *Service) ReconcileNetworkIPAM(ctx context.Context, networkID string) (*network.IPAMReconcileResult, error) {
+	net, err := s.repo.GetNetwork(ctx, networkID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", network.ErrNetworkNotFound, networkID)
+	}
+	cutoff := s.clock().Add(-IPAMReconcileGracePeriod)
+
+	// The allocations are listed before the peers: a peer added in between
+	// holds an address that is either missing from the list or too young.
+	allocations := make(map[string][]network.IPAllocation)
+	for _, cidr := range []string{net.CIDR, net.CIDRv6} {
+		if cidr == "" {
+			continue
+		}
+		allocs, err := s.repo.ListAllocatedIPs(ctx, cidr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list allocations of %s: %w", cidr, err)
+		}
+		allocations[cidr] = allocs
+	}
+	peers, err := s.repo.ListPeers(ctx, networkID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list peers: %w", err)
+	}
+	held := make(map[string]bool, 2*len(peers))
+	for _, p := range peers {
+		held[p.Address] = true
+		held[p.AddressV6] = true
+	}
+
+	result := &network.IPAMReconcileResult{NetworkID: networkID, Released: []string{}}
+	for cidr, allocs := range allocations {
+		for _, a := range allocs {
+			if held[a.IP] || a.AllocatedAt.After(cutoff) {
+				continue
+			}
+			if err := s.repo.ReleaseIP(ctx, cidr, a.IP); err != nil {
+				log.Warn().Err(err).Str("network_id", networkID).Str("ip", a.IP).Msg("failed to reclaim orphaned IPAM allocation")
+				continue
+			}
+			log.Info().Str("network_id", networkID).Str("cidr", cidr).Str("ip", a.IP).
+				Time("allocated_at", a.AllocatedAt).Msg("reclaimed orphaned IPAM allocation")
+			audit.Record(ctx, s.auditLogger, "ipam.reclaim", networkID, "", a.IP)
+			result.Released = append(result.Released, a.IP)
+		}
+	}
+	sort.Strings(result.Released)
+	return result, nil
+}
+
+// ReconcileIPAM runs ReconcileNetworkIPAM on every network.  A failing
+// network is logged and skipped.
+func (s *Service) ReconcileIPAM(ctx context.Context) error {
+	networks, err := s.repo.ListNetworks(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list networks: %w", err)
+	}
+	for _, net := range networks {
+		if _, err := s.ReconcileNetworkIPAM(ctx, net.ID); err != nil {
+			log.Warn().Err(err).Str("network_id", net.ID).Msg("IPAM reconciliation failed")
+		}
+	}
+	return nil
+}
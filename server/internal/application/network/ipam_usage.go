@@ -0,0 +1,79 @@
+package network
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+
+	"wirety/internal/domain/network"
+)
+
+// maxCountedHostBits is the largest host part, in bits, for which
+// GetIPAMUsage reports a total and free count.  Beyond it (any usual IPv6
+// prefix) the pool cannot realistically run out and only allocations are
+// reported.
+const maxCountedHostBits = 32
+
+// GetIPAMUsage reports the total, allocated, reserved and free addresses of
+// each CIDR of a network, computed from its IPAM prefix.
+func (s *Service) GetIPAMUsage(ctx context.Context, networkID string) (*network.IPAMUsage, error) {
+	net, err := s.repo.GetNetwork(ctx, networkID)
+	if err != nil {
+		return nil, fmt.Errorf("network not found: %w", err)
+	}
+
+	usage := &network.IPAMUsage{NetworkID: net.ID}
+	if net.CIDR != "" {
+		if usage.IPv4, err = s.prefixUsage(ctx, net.CIDR); err != nil {
+			return nil, err
+		}
+	}
+	if net.CIDRv6 != "" {
+		if usage.IPv6, err = s.prefixUsage(ctx, net.CIDRv6); err != nil {
+			return nil, err
+		}
+	}
+	return usage, nil
+}
+
+// prefixUsage counts the addresses of one IPAM prefix
+func (s *Service) prefixUsage(ctx context.Context, cidr string) (*network.IPAMFamilyUsage, error) {
+	prefix, err := netip.ParsePrefix(cidr)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %q", network.ErrInvalidCIDR, cidr)
+	}
+	acquired, err := s.repo.AcquiredIPs(ctx, cidr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read IPAM usage of %s: %w", cidr, err)
+	}
+	reservations, err := s.repo.ListReservations(ctx, cidr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list reservations of %s: %w", cidr, err)
+	}
+
+	// The IPAM engine blocks the first address of every prefix, and the
+	// broadcast address of IPv4 ones: neither is ever handed out.
+	hostBits := prefix.Addr().BitLen() - prefix.Bits()
+	blocked := uint64(1)
+	if prefix.Addr().Is4() && hostBits > 0 {
+		blocked = 2
+	}
+
+	usage := &network.IPAMFamilyUsage{
+		CIDR:     cidr,
+		Reserved: uint64(len(reservations)),
+	}
+	if taken := blocked + usage.Reserved; acquired > taken {
+		usage.Allocated = acquired - taken
+	}
+	if hostBits <= maxCountedHostBits {
+		total := uint64(1)<<hostBits - blocked
+		free := uint64(0)
+		if used := usage.Allocated + usage.Reserved; total > used {
+			free = total - used
+		}
+		usage.Total = &total
+		usage.Free = &free
+	}
+	return usage, nil
+}
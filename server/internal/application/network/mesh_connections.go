@@ -0,0 +1,100 @@
+package network
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"wirety/internal/audit"
+	"wirety/internal/domain/network"
+)
+
+// MeshConnection is a peer connection as listed to admins: the pair and its
+// state, without the preshared key.
+type MeshConnection struct {
+	Peer1ID   string    `json:"peer1_id"`
+	Peer1Name string    `json:"peer1_name"`
+	Peer2ID   string    `json:"peer2_id"`
+	Peer2Name string    `json:"peer2_name"`
+	CreatedAt time.Time `json:"created_at"`
+	Severed   bool      `json:"severed"`
+}
+
+// ListMeshConnections returns the preshared-key connections of the network,
+// ordered by peer pair.
+func (s *Service) ListMeshConnections(ctx context.Context, networkID string) ([]MeshConnection, error) {
+	net, err := s.repo.GetNetwork(ctx, networkID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", network.ErrNetworkNotFound, networkID)
+	}
+	conns, err := s.repo.ListConnections(ctx, networkID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list connections: %w", err)
+	}
+
+	out := make([]MeshConnection, 0, len(conns))
+	for _, c := range conns {
+		mc := MeshConnection{Peer1ID: c.Peer1ID, Peer2ID: c.Peer2ID, CreatedAt: c.CreatedAt, Severed: c.Severed}
+		if p, ok := net.GetPeer(c.Peer1ID); ok {
+			mc.Peer1Name = p.Name
+		}
+		if p, ok := net.GetPeer(c.Peer2ID); ok {
+			mc.Peer2Name = p.Name
+		}
+		out = append(out, mc)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Peer1ID != out[j].Peer1ID {
+			return out[i].Peer1ID < out[j].Peer1ID
+		}
+		return out[i].Peer2ID < out[j].Peer2ID
+	})
+	return out, nil
+}
+
+// SeverConnection cuts the connection between two peers: each is left out
+// of the other's config from then on, whatever the topology says.
+// Severing an already severed pair is a no-op.
+func (s *Service) SeverConnection(ctx context.Context, networkID, peer1ID, peer2ID string) error {
+	if _, err := s.repo.GetNetwork(ctx, networkID); err != nil {
+		return fmt.Errorf("%w: %s", network.ErrNetworkNotFound, networkID)
+	}
+	conn, err := s.repo.GetConnection(ctx, networkID, peer1ID, peer2ID)
+	if err != nil {
+		return fmt.Errorf("%w: %s/%s", network.ErrConnectionNotFound, peer1ID, peer2ID)
+	}
+	if conn.Severed {
+		return nil
+	}
+	updated := *conn
+	updated.Severed = true
+	if err := s.repo.UpdateConnection(ctx, networkID, &updated); err != nil {
+		return fmt.Errorf("failed to update connection: %w", err)
+	}
+
+	s.InvalidateConfigs(networkID)
+	if s.wsNotifier != nil {
+		s.wsNotifier.NotifyNetworkPeers(networkID)
+	}
+	audit.Record(ctx, s.auditLogger, "peer.connection_sever", networkID, peer1ID, peer2ID)
+	return nil
+}
+
+// peerConnections returns the allowed peers of peerID that are not severed
+// from it, and its connections with them.
+func (s *Service) peerConnections(ctx context.Context, networkID, peerID string, allowedPeers []*network.Peer) ([]*network.Peer, map[string]*network.PeerConnection) {
+	kept := make([]*network.Peer, 0, len(allowedPeers))
+	connections := make(map[string]*network.PeerConnection)
+	for _, allowedPeer := range allowedPeers {
+		conn, err := s.repo.GetConnection(ctx, networkID, peerID, allowedPeer.ID)
+		if err == nil && conn != nil {
+			if conn.Severed {
+				continue
+			}
+			connections[allowedPeer.ID] = conn
+		}
+		kept = append(kept, allowedPeer)
+	}
+	return kept, connections
+}
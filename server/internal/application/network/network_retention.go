@@ -0,0 +1,112 @@
+package network
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"wirety/internal/audit"
+	"wirety/internal/domain/network"
+
+	"github.com/rs/zerolog/log"
+)
+
+// DefaultNetworkRetention is how long a deleted network can be restored
+// before PurgeDeletedNetworks removes it for good.
+const DefaultNetworkRetention = 7 * 24 * time.Hour
+
+func (s *Service) retention() time.Duration {
+	if s.networkRetention > 0 {
+		return s.networkRetention
+	}
+	return DefaultNetworkRetention
+}
+
+// RestoreNetwork undoes DeleteNetwork while the network is within the
+// retention window.  The network comes back with its peers, settings and
+// CIDR.
+func (s *Service) RestoreNetwork(ctx context.Context, networkID string) (*network.Network, error) {
+	deleted, err := s.deletedNetwork(ctx, networkID)
+	if err != nil {
+		return nil, err
+	}
+	if !s.clock().Before(deleted.DeletedAt.Add(s.retention())) {
+		return nil, fmt.Errorf("%w: network %s was deleted at %s", network.ErrRestoreWindowExpired, networkID, deleted.DeletedAt.Format(time.RFC3339))
+	}
+	if err := s.repo.RestoreNetwork(ctx, networkID); err != nil {
+		return nil, fmt.Errorf("failed to restore network: %w", err)
+	}
+	s.InvalidateConfigs(networkID)
+
+	audit.Record(ctx, s.auditLogger, "network.restore", networkID, "", "")
+	return s.repo.GetNetwork(ctx, networkID)
+}
+
+// deletedNetwork returns the soft-deleted network networkID, or
+// ErrNetworkNotDeleted when it is still live.
+func (s *Service) deletedNetwork(ctx context.Context, networkID string) (*network.Network, error) {
+	deleted, err := s.repo.ListDeletedNetworks(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deleted networks: %w", err)
+	}
+	for _, net := range deleted {
+		if net.ID == networkID {
+			return net, nil
+		}
+	}
+	if _, err := s.repo.GetNetwork(ctx, networkID); err == nil {
+		return nil, fmt.Errorf("%w: %s", network.ErrNetworkNotDeleted, networkID)
+	}
+	return nil, fmt.Errorf("%w: %s", network.ErrNetworkNotFound, networkID)
+}
+
+// PurgeDeletedNetworks removes the networks deleted longer than the
+// retention window ago, and releases their CIDRs from IPAM.  A network that
+// fails to purge is logged and retried on the next run.
+func (s *Service) PurgeDeletedNetworks(ctx context.Context) error {
+	deleted, err := s.repo.ListDeletedNetworks(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list deleted networks: %w", err)
+	}
+	cutoff := s.clock().Add(-s.retention())
+	for _, net := range deleted {
+		if net.DeletedAt.After(cutoff) {
+			continue
+		}
+		if err := s.purgeNetwork(ctx, net); err != nil {
+			log.Warn().Err(err).Str("network_id", net.ID).Msg("failed to purge deleted network")
+			continue
+		}
+		log.Info().Str("network_id", net.ID).Time("deleted_at", *net.DeletedAt).Msg("purged deleted network")
+		audit.Record(ctx, s.auditLogger, "network.purge", net.ID, "", "")
+	}
+	return nil
+}
+
+// purgeNetwork deletes a network for good and releases its CIDRs from IPAM
+// for reuse.
+func (s *Service) purgeNetwork(ctx context.Context, net *network.Network) error {
+	if err := s.repo.DeleteNetwork(ctx, net.ID); err != nil {
+		return fmt.Errorf("failed to delete network: %w", err)
+	}
+
+	if net.CIDR != "" {
+		if err := s.repo.DeletePrefix(ctx, net.CIDR); err != nil {
+			log.Warn().Err(err).Str("network_id", net.ID).Str("cidr", net.CIDR).
+				Msg("Failed to release IPv4 CIDR from IPAM after network deletion")
+		} else {
+			log.Info().Str("network_id", net.ID).Str("cidr", net.CIDR).
+				Msg("Successfully released IPv4 CIDR from IPAM after network deletion")
+		}
+	}
+	if net.CIDRv6 != "" {
+		if err := s.repo.DeletePrefix(ctx, net.CIDRv6); err != nil {
+			log.Warn().Err(err).Str("network_id", net.ID).Str("cidr_v6", net.CIDRv6).
+				Msg("Failed to release IPv6 CIDR from IPAM after network deletion")
+		} else {
+			log.Info().Str("network_id", net.ID).Str("cidr_v6", net.CIDRv6).
+				Msg("Successfully released IPv6 CIDR from IPAM after network deletion")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package network
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"wirety/internal/audit"
+
+	"github.com/rs/zerolog/log"
+)
+
+// CleanupExpiredPeers cuts off peers whose expiry has passed.  Expired peers
+// are not deleted: GetCaptivePortalSecurityState reports them as quarantined,
+// so the jump peers drop their traffic until an admin extends or clears the
+// expiry.  This sweep only announces each newly expired peer and pushes the
+// change to its network's agents.
+func (s *Service) CleanupExpiredPeers(ctx context.Context) error {
+	networks, err := s.repo.ListNetworks(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list networks: %w", err)
+	}
+
+	now := s.clock()
+	expired := make(map[string]bool)
+	affected := make(map[string]bool)
+
+	s.expiredPeersMu.Lock()
+	defer s.expiredPeersMu.Unlock()
+	for _, net := range networks {
+		peers, err := s.repo.ListPeers(ctx, net.ID)
+		if err != nil {
+			log.Warn().Err(err).Str("network_id", net.ID).Msg("failed to list peers for expiry sweep")
+			// Keep what we knew about this network rather than announcing
+			// its expired peers again on the next sweep.
+			for key := range s.expiredPeers {
+				if strings.HasPrefix(key, net.ID+":") {
+					expired[key] = true
+				}
+			}
+			continue
+		}
+		for _, peer := range peers {
+			if !peer.IsExpired(now) {
+				continue
+			}
+			key := net.ID + ":" + peer.ID
+			expired[key] = true
+			if s.expiredPeers[key] {
+				continue
+			}
+			affected[net.ID] = true
+			log.Info().Str("network_id", net.ID).Str("peer_id", peer.ID).Time("expires_at", *peer.ExpiresAt).Msg("peer expired")
+			audit.Server("", "", "").
+				Str("action", "peer.expire").
+				Str("network_id", net.ID).
+				Str("peer_id", peer.ID).
+				Str("peer_name", peer.Name).
+				Msg("audit")
+		}
+	}
+	// Peers whose expiry was extended or cleared drop out of the set, so a
+	// later expiry is announced again.
+	s.expiredPeers = expired
+
+	if s.wsNotifier != nil {
+		for networkID := range affected {
+			s.wsNotifier.NotifyNetworkPeers(networkID)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package network
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"wirety/internal/audit"
+	"wirety/internal/domain/network"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// CreatePeerProfile creates a named profile of peer settings in a network
+func (s *Service) CreatePeerProfile(ctx context.Context, networkID string, req *network.PeerProfileRequest) (*network.PeerProfile, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	if _, err := s.repo.GetNetwork(ctx, networkID); err != nil {
+		return nil, fmt.Errorf("network not found: %w", err)
+	}
+
+	now := time.Now()
+	profile := &network.PeerProfile{
+		ID:        uuid.New().String(),
+		NetworkID: networkID,
+		CreatedAt: now,
+	}
+	applyPeerProfileRequest(profile, req, now)
+	if err := s.repo.CreatePeerProfile(ctx, profile); err != nil {
+		return nil, fmt.Errorf("failed to create peer profile: %w", err)
+	}
+	audit.Record(ctx, s.auditLogger, "peer_profile.create", networkID, "", profile.ID)
+
+	return profile, nil
+}
+
+// GetPeerProfile retrieves a peer profile
+func (s *Service) GetPeerProfile(ctx context.Context, networkID, profileID string) (*network.PeerProfile, error) {
+	return s.repo.GetPeerProfile(ctx, networkID, profileID)
+}
+
+// ListPeerProfiles lists the peer profiles of a network
+func (s *Service) ListPeerProfiles(ctx context.Context, networkID string) ([]*network.PeerProfile, error) {
+	return s.repo.ListPeerProfiles(ctx, networkID)
+}
+
+// UpdatePeerProfile replaces a profile's settings and pushes new configs to
+// the network, since every peer assigned the profile may be affected.
+func (s *Service) UpdatePeerProfile(ctx context.Context, networkID, profileID string, req *network.PeerProfileRequest) (*network.PeerProfile, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	profile, err := s.repo.GetPeerProfile(ctx, networkID, profileID)
+	if err != nil {
+		return nil, err
+	}
+	applyPeerProfileRequest(profile, req, time.Now())
+	if err := s.repo.UpdatePeerProfile(ctx, profile); err != nil {
+		return nil, fmt.Errorf("failed to update peer profile: %w", err)
+	}
+	audit.Record(ctx, s.auditLogger, "peer_profile.update", networkID, "", profileID)
+
+	if s.wsNotifier != nil {
+		s.wsNotifier.NotifyNetworkPeers(networkID)
+	}
+	return profile, nil
+}
+
+// DeletePeerProfile deletes a profile that no peer is assigned to
+func (s *Service) DeletePeerProfile(ctx context.Context, networkID, profileID string) error {
+	if _, err := s.repo.GetPeerProfile(ctx, networkID, profileID); err != nil {
+		return err
+	}
+	peers, err := s.repo.ListPeers(ctx, networkID)
+	if err != nil {
+		return fmt.Errorf("failed to list peers: %w", err)
+	}
+	for _, p := range peers {
+		if p.ProfileID == profileID {
+			return fmt.Errorf("%w: %s", network.ErrPeerProfileInUse, p.Name)
+		}
+	}
+	if err := s.repo.DeletePeerProfile(ctx, networkID, profileID); err != nil {
+		return err
+	}
+	audit.Record(ctx, s.auditLogger, "peer_profile.delete", networkID, "", profileID)
+
+	return nil
+}
+
+func applyPeerProfileRequest(profile *network.PeerProfile, req *network.PeerProfileRequest, now time.Time) {
+	profile.Name = req.Name
+	profile.Description = req.Description
+	profile.MTU = req.MTU
+	profile.PersistentKeepalive = req.PersistentKeepalive
+	profile.DNS = req.DNS
+	profile.SplitTunnelExclusions = req.SplitTunnelExclusions
+	profile.UpdatedAt = now
+}
+
+// resolvePeerProfile returns the peer with its profile's settings, then the
+// server's peer defaults, filled in.  A profile that cannot be loaded is
+// ignored so config generation keeps working.
+func (s *Service) resolvePeerProfile(ctx context.Context, networkID string, peer *network.Peer) *network.Peer {
+	if peer.ProfileID != "" {
+		profile, err := s.repo.GetPeerProfile(ctx, networkID, peer.ProfileID)
+		if err == nil {
+			peer = peer.WithProfile(profile)
+		} else if !errors.Is(err, network.ErrPeerProfileNotFound) {
+			log.Warn().Err(err).Str("network_id", networkID).Str("peer_id", peer.ID).Msg("failed to load peer profile")
+		}
+	}
+	if s.peerDefaults != nil {
+		peer = peer.WithDefaults(*s.peerDefaults)
+	}
+	return peer
+}
@@ -2,6 +2,7 @@ package network
 
 import (
 	"context"
+	"time"
 
 	"wirety/internal/domain/ipam"
 	"wirety/internal/domain/network"
@@ -40,6 +41,15 @@ func (c *CombinedRepository) DeleteNetwork(ctx context.Context, id string) error
 func (c *CombinedRepository) ListNetworks(ctx context.Context) ([]*network.Network, error) {
 	return c.netRepo.ListNetworks(ctx)
 }
+func (c *CombinedRepository) SoftDeleteNetwork(ctx context.Context, id string, at time.Time) error {
+	return c.netRepo.SoftDeleteNetwork(ctx, id, at)
+}
+func (c *CombinedRepository) RestoreNetwork(ctx context.Context, id string) error {
+	return c.netRepo.RestoreNetwork(ctx, id)
+}
+func (c *CombinedRepository) ListDeletedNetworks(ctx context.Context) ([]*network.Network, error) {
+	return c.netRepo.ListDeletedNetworks(ctx)
+}
 func (c *CombinedRepository) CreatePeer(ctx context.Context, networkID string, p *network.Peer) error {
 	return c.netRepo.CreatePeer(ctx, networkID, p)
 }
@@ -58,6 +68,9 @@ func (c *CombinedRepository) DeletePeer(ctx context.Context, networkID, peerID s
 func (c *CombinedRepository) ListPeers(ctx context.Context, networkID string) ([]*network.Peer, error) {
 	return c.netRepo.ListPeers(ctx, networkID)
 }
+func (c *CombinedRepository) SearchPeers(ctx context.Context, query string, offset, limit int) ([]*network.PeerSearchResult, int, error) {
+	return c.netRepo.SearchPeers(ctx, query, offset, limit)
+}
 func (c *CombinedRepository) CreateACL(ctx context.Context, networkID string, acl *network.ACL) error {
 	return c.netRepo.CreateACL(ctx, networkID, acl)
 }
@@ -76,6 +89,9 @@ func (c *CombinedRepository) GetConnection(ctx context.Context, networkID, p1, p
 func (c *CombinedRepository) ListConnections(ctx context.Context, networkID string) ([]*network.PeerConnection, error) {
 	return c.netRepo.ListConnections(ctx, networkID)
 }
+func (c *CombinedRepository) UpdateConnection(ctx context.Context, networkID string, conn *network.PeerConnection) error {
+	return c.netRepo.UpdateConnection(ctx, networkID, conn)
+}
 func (c *CombinedRepository) DeleteConnection(ctx context.Context, networkID, p1, p2 string) error {
 	return c.netRepo.DeleteConnection(ctx, networkID, p1, p2)
 }
@@ -94,6 +110,12 @@ func (c *CombinedRepository) DeleteSession(ctx context.Context, networkID, sessi
 func (c *CombinedRepository) ListSessions(ctx context.Context, networkID string) ([]*network.AgentSession, error) {
 	return c.netRepo.ListSessions(ctx, networkID)
 }
+func (c *CombinedRepository) ListSessionHistory(ctx context.Context, networkID, peerID string) ([]*network.AgentSession, error) {
+	return c.netRepo.ListSessionHistory(ctx, networkID, peerID)
+}
+func (c *CombinedRepository) RevokeSession(ctx context.Context, networkID, sessionID string, revokedAt time.Time) error {
+	return c.netRepo.RevokeSession(ctx, networkID, sessionID, revokedAt)
+}
 
 // Delegate ipam.Repository methods
 func (c *CombinedRepository) EnsureRootPrefix(ctx context.Context, cidr string) (*network.IPAMPrefix, error) {
@@ -120,6 +142,24 @@ func (c *CombinedRepository) AcquireIP(ctx context.Context, cidr string) (string
 func (c *CombinedRepository) ReleaseIP(ctx context.Context, cidr string, ip string) error {
 	return c.ipamRepo.ReleaseIP(ctx, cidr, ip)
 }
+func (c *CombinedRepository) AcquireSpecificIP(ctx context.Context, cidr string, ip string) error {
+	return c.ipamRepo.AcquireSpecificIP(ctx, cidr, ip)
+}
+func (c *CombinedRepository) ReserveIP(ctx context.Context, cidr string, ip string) error {
+	return c.ipamRepo.ReserveIP(ctx, cidr, ip)
+}
+func (c *CombinedRepository) ReleaseReservation(ctx context.Context, cidr string, ip string) error {
+	return c.ipamRepo.ReleaseReservation(ctx, cidr, ip)
+}
+func (c *CombinedRepository) ListReservations(ctx context.Context, cidr string) ([]string, error) {
+	return c.ipamRepo.ListReservations(ctx, cidr)
+}
+func (c *CombinedRepository) AcquiredIPs(ctx context.Context, cidr string) (uint64, error) {
+	return c.ipamRepo.AcquiredIPs(ctx, cidr)
+}
+func (c *CombinedRepository) ListAllocatedIPs(ctx context.Context, cidr string) ([]network.IPAllocation, error) {
+	return c.ipamRepo.ListAllocatedIPs(ctx, cidr)
+}
 
 var _ FullRepository = (*CombinedRepository)(nil)
 
@@ -220,3 +260,34 @@ func (c *CombinedRepository) ListPeerLocalRoutes(ctx context.Context, networkID
 	return c.netRepo.ListPeerLocalRoutes(ctx, networkID)
 }
 
+// Temporary route methods
+func (c *CombinedRepository) CreateTempRoute(ctx context.Context, route *network.TempRoute) error {
+	return c.netRepo.CreateTempRoute(ctx, route)
+}
+func (c *CombinedRepository) ListTempRoutes(ctx context.Context, networkID, peerID string) ([]*network.TempRoute, error) {
+	return c.netRepo.ListTempRoutes(ctx, networkID, peerID)
+}
+func (c *CombinedRepository) DeleteTempRoute(ctx context.Context, networkID, id string) error {
+	return c.netRepo.DeleteTempRoute(ctx, networkID, id)
+}
+func (c *CombinedRepository) ListExpiredTempRoutes(ctx context.Context, before time.Time) ([]*network.TempRoute, error) {
+	return c.netRepo.ListExpiredTempRoutes(ctx, before)
+}
+
+// Peer profile methods
+func (c *CombinedRepository) CreatePeerProfile(ctx context.Context, profile *network.PeerProfile) error {
+	return c.netRepo.CreatePeerProfile(ctx, profile)
+}
+func (c *CombinedRepository) GetPeerProfile(ctx context.Context, networkID, profileID string) (*network.PeerProfile, error) {
+	return c.netRepo.GetPeerProfile(ctx, networkID, profileID)
+}
+func (c *CombinedRepository) UpdatePeerProfile(ctx context.Context, profile *network.PeerProfile) error {
+	return c.netRepo.UpdatePeerProfile(ctx, profile)
+}
+func (c *CombinedRepository) DeletePeerProfile(ctx context.Context, networkID, profileID string) error {
+	return c.netRepo.DeletePeerProfile(ctx, networkID, profileID)
+}
+func (c *CombinedRepository) ListPeerProfiles(ctx context.Context, networkID string) ([]*network.PeerProfile, error) {
+	return c.netRepo.ListPeerProfiles(ctx, networkID)
+}
+
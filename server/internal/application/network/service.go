@@ -4,21 +4,33 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/base64"
+	"encoding/binary"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"net"
+	"net/netip"
+	"reflect"
+	"slices"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
+	"wirety/internal/audit"
 	"wirety/internal/domain/auth"
 	"wirety/internal/domain/ipam"
 	"wirety/internal/domain/network"
 	"wirety/internal/infrastructure/validation"
+	"wirety/internal/metrics"
+	"wirety/internal/tracing"
+	"wirety/pkg/firewall"
 	"wirety/pkg/wireguard"
 
 	"github.com/google/uuid"
+	goipam "github.com/metal-stack/go-ipam"
 	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // WebSocketNotifier is an interface for notifying peers about config updates
@@ -31,6 +43,12 @@ type WebSocketConnectionChecker interface {
 	IsConnected(networkID, peerID string) bool
 }
 
+// IncidentNotifier is an interface for forwarding security incidents to an
+// external system (e.g. a webhook).  Implementations must not block.
+type IncidentNotifier interface {
+	NotifyIncident(incident *network.SecurityIncident)
+}
+
 // PolicyService interface for generating iptables rules
 type PolicyService interface {
 	GenerateIPTablesRules(ctx context.Context, networkID, jumpPeerID string) ([]string, error)
@@ -47,6 +65,37 @@ type Service struct {
 	policyService       PolicyService
 	wsNotifier          WebSocketNotifier
 	wsConnectionChecker WebSocketConnectionChecker
+	auditLogger         audit.AuditLogger
+	incidentNotifier    IncidentNotifier
+
+	// now returns the current time; overridden in tests to drive expiry of
+	// temporary routes.  Use s.clock() rather than calling it directly.
+	now func() time.Time
+
+	// lookupHost resolves endpoint hostnames for CheckEndpoint; overridden
+	// in tests.  nil uses net.DefaultResolver.
+	lookupHost func(ctx context.Context, host string) ([]string, error)
+
+	// strictRouteConflicts turns route conflicts (same destination CIDR via
+	// different jump peers) into config generation errors instead of
+	// resolving them by priority.
+	strictRouteConflicts bool
+
+	// peerDefaults fills in the settings a peer gets neither from itself nor
+	// from its profile; nil leaves them to GenerateConfig's own defaults.
+	peerDefaults *network.PeerDefaults
+
+	// configCache serves GeneratePeerConfigWithDNS while a network is
+	// unchanged; nil disables it (see SetConfigCacheSize).
+	configCache *configCache
+
+	// peerStaleThreshold is the handshake age beyond which a peer is stale
+	// (DefaultPeerStaleThreshold when zero).
+	peerStaleThreshold time.Duration
+
+	// networkRetention is how long a deleted network can be restored
+	// before it is purged (DefaultNetworkRetention when zero).
+	networkRetention time.Duration
 
 	// wgLastSeen tracks the last time a jump peer reported seeing each peer
 	// via an active WireGuard handshake.  Key: "networkID:peerID".
@@ -56,6 +105,15 @@ type Service struct {
 	// after restart is acceptable; the next jump-peer heartbeat restores it.
 	wgLastSeen   map[string]time.Time
 	wgLastSeenMu sync.RWMutex
+
+	// expiredPeers holds the "networkID:peerID" keys of peers the expiry
+	// sweeper has already cut off, so each expiry is announced once.
+	expiredPeers   map[string]bool
+	expiredPeersMu sync.Mutex
+
+	// enrollMu makes checking and consuming a single-use enrollment token
+	// atomic.
+	enrollMu sync.Mutex
 }
 
 // SetWebSocketNotifier sets the WebSocket notifier for the service
@@ -63,16 +121,21 @@ func (s *Service) SetWebSocketNotifier(notifier WebSocketNotifier) {
 	s.wsNotifier = notifier
 }
 
+// SetAuditLogger sets the audit log that records mutating operations
+func (s *Service) SetAuditLogger(l audit.AuditLogger) {
+	s.auditLogger = l
+}
+
+// SetIncidentNotifier sets the notifier receiving security incidents
+func (s *Service) SetIncidentNotifier(notifier IncidentNotifier) {
+	s.incidentNotifier = notifier
+}
+
 // SetWebSocketConnectionChecker sets the WebSocket connection checker for the service
 func (s *Service) SetWebSocketConnectionChecker(checker WebSocketConnectionChecker) {
 	s.wsConnectionChecker = checker
 }
 
-// ResolveAgentToken returns networkID, peer for a given enrollment token.
-func (s *Service) ResolveAgentToken(ctx context.Context, token string) (string, *network.Peer, error) {
-	return s.repo.GetPeerByToken(ctx, token)
-}
-
 // NewService creates a new network service
 func NewService(networkRepo network.Repository, ipamRepo ipam.Repository, authRepo auth.Repository, groupRepo network.GroupRepository, routeRepo network.RouteRepository, dnsRepo network.DNSRepository, policyRepo network.PolicyRepository) *Service {
 	return &Service{
@@ -86,6 +149,38 @@ func NewService(networkRepo network.Repository, ipamRepo ipam.Repository, authRe
 	}
 }
 
+// clock returns the service's notion of the current time
+func (s *Service) clock() time.Time {
+	if s.now != nil {
+		return s.now()
+	}
+	return time.Now()
+}
+
+// SetStrictRouteConflicts makes config generation fail on conflicting routes
+// instead of resolving them by group priority
+func (s *Service) SetStrictRouteConflicts(strict bool) {
+	s.strictRouteConflicts = strict
+}
+
+// SetPeerDefaults sets the server-wide MTU, keepalive and DNS of peers that
+// set them neither themselves nor through a profile
+func (s *Service) SetPeerDefaults(defaults network.PeerDefaults) {
+	s.peerDefaults = &defaults
+}
+
+// SetPeerStaleThreshold sets the handshake age beyond which a peer is
+// reported stale instead of online
+func (s *Service) SetPeerStaleThreshold(d time.Duration) {
+	s.peerStaleThreshold = d
+}
+
+// SetNetworkRetention sets how long a deleted network can be restored
+// before it is purged
+func (s *Service) SetNetworkRetention(d time.Duration) {
+	s.networkRetention = d
+}
+
 // SetPolicyService sets the policy service for iptables rule generation
 func (s *Service) SetPolicyService(policyService PolicyService) {
 	s.policyService = policyService
@@ -93,9 +188,16 @@ func (s *Service) SetPolicyService(policyService PolicyService) {
 
 // CreateNetwork creates a new WireGuard network
 func (s *Service) CreateNetwork(ctx context.Context, req *network.NetworkCreateRequest) (*network.Network, error) {
-	// Validate network name follows DNS hostname convention (dots allowed for subdomains)
-	if err := validation.ValidateDNSHostname(req.Name); err != nil {
-		return nil, fmt.Errorf("invalid network name: %w", err)
+	if err := validateNetworkCreateRequest(req); err != nil {
+		return nil, err
+	}
+	forwarders, err := normalizeConditionalForwarders(req.ConditionalForwarders)
+	if err != nil {
+		return nil, err
+	}
+	searchDomains, err := normalizeSearchDomains(req.SearchDomains)
+	if err != nil {
+		return nil, err
 	}
 
 	// Set default domain suffix if not provided
@@ -104,27 +206,8 @@ func (s *Service) CreateNetwork(ctx context.Context, req *network.NetworkCreateR
 		domainSuffix = "internal"
 	}
 
-	// Validate domain suffix (dots allowed, e.g. "corp.example.com")
-	if err := validation.ValidateDNSHostname(domainSuffix); err != nil {
-		return nil, fmt.Errorf("invalid domain suffix: %w", err)
-	}
-
 	now := time.Now()
 
-	if req.CIDR == "" && req.CIDRv6 == "" {
-		return nil, fmt.Errorf("at least one of cidr (IPv4) or cidr_v6 (IPv6) must be provided")
-	}
-	if req.CIDR != "" {
-		if err := validateNetworkCIDR(req.CIDR); err != nil {
-			return nil, fmt.Errorf("invalid cidr: %w", err)
-		}
-	}
-	if req.CIDRv6 != "" {
-		if err := validateNetworkCIDR(req.CIDRv6); err != nil {
-			return nil, fmt.Errorf("invalid cidr_v6: %w", err)
-		}
-	}
-
 	net := &network.Network{
 		ID:              uuid.New().String(),
 		Name:            req.Name,
@@ -133,9 +216,25 @@ func (s *Service) CreateNetwork(ctx context.Context, req *network.NetworkCreateR
 		Peers:           make(map[string]*network.Peer),
 		DomainSuffix:    domainSuffix,
 		DefaultGroupIDs: []string{}, // Initialize empty default groups
+		PeerNamePattern: req.PeerNamePattern,
+		Topology:        network.TopologyMesh,
 		CreatedAt:       now,
 		UpdatedAt:       now,
 		DNS:             req.DNS,
+
+		MultiJumpFailover:     req.MultiJumpFailover,
+		OmitPrivateKeys:       req.OmitPrivateKeys,
+		ConditionalForwarders: forwarders,
+		Labels:                req.Labels,
+		DNSQueryLog:           req.DNSQueryLog,
+		JumpPostUp:            req.JumpPostUp,
+		JumpPostDown:          req.JumpPostDown,
+		JumpNATInterface:      req.JumpNATInterface,
+		ConfigMetadata:        req.ConfigMetadata,
+		SearchDomains:         searchDomains,
+	}
+	if req.Topology != "" {
+		net.Topology = req.Topology
 	}
 
 	if err := s.repo.CreateNetwork(ctx, net); err != nil {
@@ -154,6 +253,8 @@ func (s *Service) CreateNetwork(ctx context.Context, req *network.NetworkCreateR
 		}
 	}
 
+	audit.Record(ctx, s.auditLogger, "network.create", net.ID, "", "")
+
 	return net, nil
 }
 
@@ -183,11 +284,54 @@ func (s *Service) UpdateNetwork(ctx context.Context, networkID string, req *netw
 		}
 	}
 
+	if req.PeerNamePattern != nil && *req.PeerNamePattern != "" {
+		if _, err := validation.CompilePeerNamePattern(*req.PeerNamePattern); err != nil {
+			return nil, err
+		}
+	}
+	if req.Topology != "" && req.Topology != network.TopologyMesh && req.Topology != network.TopologyHub {
+		return nil, fmt.Errorf("invalid topology %q: must be %q or %q", req.Topology, network.TopologyMesh, network.TopologyHub)
+	}
+	if err := network.ValidateLabels(req.Labels); err != nil {
+		return nil, err
+	}
+	forwarders, err := normalizeConditionalForwarders(req.ConditionalForwarders)
+	if err != nil {
+		return nil, err
+	}
+	searchDomains, err := normalizeSearchDomains(req.SearchDomains)
+	if err != nil {
+		return nil, err
+	}
+	if req.MaintenanceUntil != nil && !req.MaintenanceUntil.After(s.clock()) {
+		return nil, network.ErrMaintenanceInPast
+	}
+
 	net, err := s.repo.GetNetwork(ctx, networkID)
 	if err != nil {
 		return nil, fmt.Errorf("network not found: %w", err)
 	}
 
+	// The jump hooks are validated together, since a request may change
+	// only one of them
+	postUp, postDown, natInterface := net.JumpPostUp, net.JumpPostDown, net.JumpNATInterface
+	if req.JumpPostUp != nil {
+		postUp = *req.JumpPostUp
+	}
+	if req.JumpPostDown != nil {
+		postDown = *req.JumpPostDown
+	}
+	if req.JumpNATInterface != nil {
+		natInterface = *req.JumpNATInterface
+	}
+	hooksChanged := postUp != net.JumpPostUp || postDown != net.JumpPostDown || natInterface != net.JumpNATInterface
+	if hooksChanged {
+		if err := wireguard.ValidateJumpHooks(postUp, postDown, natInterface); err != nil {
+			return nil, err
+		}
+		net.JumpPostUp, net.JumpPostDown, net.JumpNATInterface = postUp, postDown, natInterface
+	}
+
 	oldCIDR := net.CIDR
 	cidrChanged := false
 	dnsChanged := false
@@ -198,10 +342,59 @@ func (s *Service) UpdateNetwork(ctx context.Context, networkID string, req *netw
 	if req.DomainSuffix != "" {
 		net.DomainSuffix = req.DomainSuffix
 	}
+	if req.PeerNamePattern != nil {
+		net.PeerNamePattern = *req.PeerNamePattern
+	}
+	topologyChanged := false
+	if req.Topology != "" && req.Topology != net.Topology {
+		net.Topology = req.Topology
+		topologyChanged = true
+	}
+	failoverChanged := false
+	if req.MultiJumpFailover != nil && *req.MultiJumpFailover != net.MultiJumpFailover {
+		net.MultiJumpFailover = *req.MultiJumpFailover
+		failoverChanged = true
+	}
+	// Takes effect on the next config sent to each agent
+	if req.OmitPrivateKeys != nil {
+		net.OmitPrivateKeys = *req.OmitPrivateKeys
+	}
+	if req.ConfigMetadata != nil {
+		net.ConfigMetadata = *req.ConfigMetadata
+	}
 	if req.CIDR != "" && req.CIDR != oldCIDR {
 		net.CIDR = req.CIDR
 		cidrChanged = true
 	}
+	if req.ConditionalForwarders != nil {
+		if !reflect.DeepEqual(forwarders, net.ConditionalForwarders) {
+			dnsChanged = true
+		}
+		net.ConditionalForwarders = forwarders
+	}
+	if req.SearchDomains != nil {
+		if !slices.Equal(searchDomains, net.SearchDomains) {
+			dnsChanged = true
+		}
+		net.SearchDomains = searchDomains
+	}
+	if req.DNSQueryLog != nil && *req.DNSQueryLog != net.DNSQueryLog {
+		net.DNSQueryLog = *req.DNSQueryLog
+		dnsChanged = true
+	}
+	switch {
+	case req.MaintenanceUntil != nil:
+		until := *req.MaintenanceUntil
+		net.MaintenanceUntil = &until
+		log.Warn().Str("network_id", networkID).Time("until", until).
+			Msg("maintenance window: security auto-blocking suspended, incidents are still raised")
+	case req.ClearMaintenance && net.MaintenanceUntil != nil:
+		net.MaintenanceUntil = nil
+		log.Info().Str("network_id", networkID).Msg("maintenance window ended early: security auto-blocking resumed")
+	}
+	if req.Labels != nil {
+		net.Labels = req.Labels
+	}
 	if req.DNS != nil {
 		if len(req.DNS) != len(net.DNS) {
 			dnsChanged = true
@@ -225,68 +418,118 @@ func (s *Service) UpdateNetwork(ctx context.Context, networkID string, req *netw
 
 	net.UpdatedAt = time.Now()
 
-	// If CIDR changed, reallocate all peer IPs
+	// If CIDR changed, reallocate all peer IPs following the same plan a
+	// dry run would have shown.
 	if cidrChanged {
-		// Get all peers to check for static peers
-		peers, err := s.repo.ListPeers(ctx, networkID)
+		plan, err := s.planCIDRChange(ctx, networkID, oldCIDR, net.CIDR)
 		if err != nil {
-			return nil, fmt.Errorf("failed to list peers: %w", err)
+			return nil, err
 		}
-
-		// Check if any regular peers are using static config (not using agent)
-		for _, peer := range peers {
-			if !peer.IsJump && !peer.UseAgent {
-				return nil, fmt.Errorf("cannot change CIDR: network contains static regular peer '%s' which would require manual reconfiguration", peer.Name)
-			}
-		}
-
-		// Ensure new root prefix exists
-		if _, err := s.repo.EnsureRootPrefix(ctx, net.CIDR); err != nil {
-			return nil, fmt.Errorf("failed to ensure new root prefix: %w", err)
-		}
-
-		// Release old IPs and allocate new ones
-		for _, peer := range peers {
-			// Release old IP from old CIDR
-			if err := s.repo.ReleaseIP(ctx, oldCIDR, peer.Address); err != nil {
-				// Log but don't fail - old CIDR may not exist in IPAM
-				log.Warn().Err(err).Str("ip", peer.Address).Str("cidr", oldCIDR).Msg("failed to release old IP during CIDR migration")
-			}
-
-			// Allocate new IP from new CIDR
-			newAddress, err := s.repo.AcquireIP(ctx, net.CIDR)
-			if err != nil {
-				return nil, fmt.Errorf("failed to allocate new IP for peer %s: %w", peer.ID, err)
-			}
-
-			peer.Address = newAddress
-			peer.UpdatedAt = time.Now()
-
-			if err := s.repo.UpdatePeer(ctx, networkID, peer); err != nil {
-				return nil, fmt.Errorf("failed to update peer %s with new IP: %w", peer.ID, err)
-			}
+		if err := s.applyCIDRChangePlan(ctx, plan); err != nil {
+			return nil, err
 		}
+		net.CIDR = plan.NewCIDR
 	}
 
 	if err := s.repo.UpdateNetwork(ctx, net); err != nil {
 		return nil, fmt.Errorf("failed to update network: %w", err)
 	}
+	// Settings such as the domain suffix or private key omission change the
+	// next config without a push.
+	s.InvalidateConfigs(networkID)
 
-	if cidrChanged || dnsChanged {
+	// Switching to mesh needs the connections hub mode never created.  Going
+	// the other way leaves the extra connections in place; they are unused.
+	if topologyChanged {
+		if err := s.ensureConnections(ctx, net); err != nil {
+			return nil, err
+		}
+	}
+
+	if cidrChanged || dnsChanged || failoverChanged || hooksChanged {
 		if s.wsNotifier != nil {
 			s.wsNotifier.NotifyNetworkPeers(networkID)
 		}
 	}
 
+	audit.Record(ctx, s.auditLogger, "network.update", networkID, "", "")
+
 	return net, nil
 }
 
 // AddPeer adds a new peer to the network
-func (s *Service) AddPeer(ctx context.Context, networkID string, req *network.PeerCreateRequest, ownerID string) (*network.Peer, error) {
+func (s *Service) AddPeer(ctx context.Context, networkID string, req *network.PeerCreateRequest, ownerID string) (_ *network.Peer, err error) {
+	ctx, span := tracing.Start(ctx, "network.AddPeer")
+	span.SetAttributes(attribute.String("network.id", networkID))
+	defer func() { tracing.RecordError(span, err); span.End() }()
+
 	// Validate peer name follows DNS naming convention
 	if err := validation.ValidateDNSName(req.Name); err != nil {
 		return nil, fmt.Errorf("invalid peer name: %w", err)
 	}
+	if req.Endpoint != "" {
+		if err := validation.ValidateEndpointHost(req.Endpoint); err != nil {
+			return nil, fmt.Errorf("invalid endpoint: %w", err)
+		}
+	}
+	if err := validatePeerRole(req.Role); err != nil {
+		return nil, err
+	}
+	if err := network.ValidatePeerSettings(req.MTU, req.PersistentKeepalive, req.DNS, req.SplitTunnelExclusions); err != nil {
+		return nil, err
+	}
+	if err := network.ValidateRoutingTable(req.RoutingTable); err != nil {
+		return nil, err
+	}
+	if err := network.ValidateFwMark(req.FwMark); err != nil {
+		return nil, err
+	}
+	if req.DNSOnly && req.IsJump {
+		return nil, network.ErrDNSOnlyJumpPeer
+	}
+	if err := network.ValidateLabels(req.Labels); err != nil {
+		return nil, err
+	}
+	// A malformed AllowedIPs entry would break the whole interface on apply
+	additionalIPs, err := validation.NormalizeAllowedIPs(req.AdditionalAllowedIPs)
+	if err != nil {
+		return nil, fmt.Errorf("invalid additional_allowed_ips: %w", err)
+	}
+	if req.ProfileID != "" {
+		if _, err := s.repo.GetPeerProfile(ctx, networkID, req.ProfileID); err != nil {
+			return nil, err
+		}
+	}
+	if len(req.GroupIDs) > 0 {
+		if s.groupRepo == nil {
+			return nil, fmt.Errorf("%w: groups are not available", network.ErrGroupNotFound)
+		}
+		for _, groupID := range req.GroupIDs {
+			if _, err := s.groupRepo.GetGroup(ctx, networkID, groupID); err != nil {
+				return nil, fmt.Errorf("%w: %s", network.ErrGroupNotFound, groupID)
+			}
+		}
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(s.clock()) {
+		return nil, network.ErrPeerExpiryInPast
+	}
+	if req.TokenExpiresAt != nil && !req.TokenExpiresAt.After(s.clock()) {
+		return nil, network.ErrTokenExpiryInPast
+	}
+	if req.PublicKey != "" {
+		if err := network.ValidatePublicKey(req.PublicKey); err != nil {
+			return nil, err
+		}
+		peers, err := s.repo.ListPeers(ctx, networkID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list peers: %w", err)
+		}
+		for _, p := range peers {
+			if p.PublicKey == req.PublicKey {
+				return nil, network.ErrPublicKeyInUse
+			}
+		}
+	}
 
 	// Ownership: jump peers and agent-managed peers are typically ownerless
 	// infrastructure. Regular user-device peers may optionally have an owner.
@@ -299,11 +542,27 @@ func (s *Service) AddPeer(ctx context.Context, networkID string, req *network.Pe
 	if err != nil {
 		return nil, fmt.Errorf("network not found: %w", err)
 	}
+	if err := checkPeerNamePattern(net, req.Name); err != nil {
+		return nil, err
+	}
+	if req.Address != "" {
+		if err := checkStaticAddress(net, req.Address); err != nil {
+			return nil, err
+		}
+	}
 
 	// Allocate IP address(es) for the peer using IPAM repository (hexagonal compliant).
 	// At least one of CIDR / CIDRv6 is set (validated at network creation).
 	var address, addressV6 string
-	if net.CIDR != "" {
+	if req.Address != "" {
+		if err := s.repo.AcquireSpecificIP(ctx, net.CIDR, req.Address); err != nil {
+			if errors.Is(err, goipam.ErrAlreadyAllocated) {
+				return nil, fmt.Errorf("%w: %s", network.ErrIPAllocated, req.Address)
+			}
+			return nil, fmt.Errorf("failed to acquire IPv4 address %s from IPAM: %w", req.Address, err)
+		}
+		address = req.Address
+	} else if net.CIDR != "" {
 		var err error
 		address, err = s.repo.AcquireIP(ctx, net.CIDR)
 		if err != nil {
@@ -322,16 +581,36 @@ func (s *Service) AddPeer(ctx context.Context, networkID string, req *network.Pe
 		}
 	}
 
-	// Generate WireGuard keys for the peer
-	privateKey, publicKey, err := wireguard.GenerateKeyPair()
-	if err != nil {
-		return nil, fmt.Errorf("failed to generate key pair: %w", err)
-	}
+	// From here on, any failure must undo the partial creation and hand the
+	// acquired address(es) back to IPAM, otherwise a failed create slowly
+	// leaks the network's address space.
+	var persistedPeerID string
+	var joinedGroups []string
+	succeeded := false
+	defer func() {
+		if succeeded {
+			return
+		}
+		for _, groupID := range joinedGroups {
+			if err := s.groupRepo.RemovePeerFromGroup(ctx, networkID, groupID, persistedPeerID); err != nil {
+				log.Warn().Err(err).Str("peer_id", persistedPeerID).Str("group_id", groupID).Msg("failed to roll back group membership")
+			}
+		}
+		if persistedPeerID != "" {
+			if err := s.repo.DeletePeer(ctx, networkID, persistedPeerID); err != nil {
+				log.Warn().Err(err).Str("peer_id", persistedPeerID).Msg("failed to roll back partially created peer")
+			}
+		}
+		s.releasePeerAddresses(ctx, net, address, addressV6)
+	}()
 
-	// Ensure AdditionalAllowedIPs is never nil
-	additionalIPs := req.AdditionalAllowedIPs
-	if additionalIPs == nil {
-		additionalIPs = []string{}
+	// Generate WireGuard keys for the peer, unless its device keeps the
+	// private key
+	var privateKey, publicKey string
+	if req.PublicKey != "" {
+		publicKey = req.PublicKey
+	} else if privateKey, publicKey, err = wireguard.GenerateKeyPair(); err != nil {
+		return nil, fmt.Errorf("failed to generate key pair: %w", err)
 	}
 
 	now := time.Now()
@@ -349,16 +628,30 @@ func (s *Service) AddPeer(ctx context.Context, networkID string, req *network.Pe
 		AdditionalAllowedIPs: additionalIPs, // Ensure never nil to avoid DB constraint violation
 		OwnerID:              ownerID,       // Set the owner of the peer
 		GroupIDs:             []string{},    // Initialize empty group list
+		Role:                 req.Role,
 		CreatedAt:            now,
 		UpdatedAt:            now,
+
+		SplitTunnelExclusions: req.SplitTunnelExclusions,
+		ProfileID:             req.ProfileID,
+		MTU:                   req.MTU,
+		PersistentKeepalive:   req.PersistentKeepalive,
+		DNS:                   req.DNS,
+		RoutingTable:          req.RoutingTable,
+		FwMark:                req.FwMark,
+		ExpiresAt:             req.ExpiresAt,
+		TokenSingleUse:        req.TokenSingleUse,
+		TokenExpiresAt:        req.TokenExpiresAt,
+		Labels:                req.Labels,
+		DNSOnly:               req.DNSOnly,
 	}
 
 	// Generate enrollment token
-	raw := make([]byte, 32)
-	if _, err := rand.Read(raw); err != nil {
-		return nil, fmt.Errorf("failed to generate token: %w", err)
+	token, err := newEnrollmentToken()
+	if err != nil {
+		return nil, err
 	}
-	peer.Token = base64.RawURLEncoding.EncodeToString(raw)
+	peer.Token = token
 
 	// Default listen port for jump peers if not provided
 	if peer.IsJump && peer.ListenPort == 0 {
@@ -369,10 +662,23 @@ func (s *Service) AddPeer(ctx context.Context, networkID string, req *network.Pe
 	if peer.IsJump {
 		peer.UseAgent = true
 	}
+	peer.Role = peer.EffectiveRole()
 
 	if err := s.repo.CreatePeer(ctx, networkID, peer); err != nil {
 		return nil, fmt.Errorf("failed to create peer: %w", err)
 	}
+	persistedPeerID = peer.ID
+
+	// Requested groups must all be joined, or the peer is not created
+	for _, groupID := range req.GroupIDs {
+		if slices.Contains(joinedGroups, groupID) {
+			continue
+		}
+		if err := s.groupRepo.AddPeerToGroup(ctx, networkID, groupID, peer.ID); err != nil {
+			return nil, fmt.Errorf("failed to add peer to group %s: %w", groupID, err)
+		}
+		joinedGroups = append(joinedGroups, groupID)
+	}
 
 	// Check if user is admin or non-admin and handle default groups
 	if ownerID != "" && s.authRepo != nil && s.groupRepo != nil {
@@ -381,6 +687,9 @@ func (s *Service) AddPeer(ctx context.Context, networkID string, req *network.Pe
 			// For non-admin users, automatically add peer to network's default groups
 			if !user.IsAdministrator() && len(net.DefaultGroupIDs) > 0 {
 				for _, groupID := range net.DefaultGroupIDs {
+					if slices.Contains(joinedGroups, groupID) {
+						continue
+					}
 					// Add peer to each default group
 					if err := s.groupRepo.AddPeerToGroup(ctx, networkID, groupID, peer.ID); err != nil {
 						// Log error but don't fail peer creation
@@ -389,21 +698,25 @@ func (s *Service) AddPeer(ctx context.Context, networkID string, req *network.Pe
 							Str("peer_id", peer.ID).
 							Str("group_id", groupID).
 							Msg("failed to add peer to default group")
+						continue
 					}
+					joinedGroups = append(joinedGroups, groupID)
 				}
 			}
 		}
 	}
+	peer.GroupIDs = append([]string{}, joinedGroups...)
 
-	// Create preshared key connections with all existing peers
+	// Create preshared key connections with the existing peers the topology
+	// pairs this peer with (every peer in mesh, jump peers only in hub)
 	existingPeers, err := s.repo.ListPeers(ctx, networkID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list existing peers: %w", err)
 	}
 
 	for _, existingPeer := range existingPeers {
-		if existingPeer.ID == peer.ID {
-			continue // skip self
+		if !net.NeedsConnection(peer, existingPeer) {
+			continue
 		}
 
 		presharedKey, err := wireguard.GeneratePresharedKey()
@@ -423,9 +736,216 @@ func (s *Service) AddPeer(ctx context.Context, networkID string, req *network.Pe
 		}
 	}
 
+	succeeded = true
+	metrics.PeersCreated.Inc()
+	audit.Record(ctx, s.auditLogger, "peer.create", networkID, peer.ID, "")
+
 	return peer, nil
 }
 
+// ensureConnections creates the preshared-key connections the network's
+// topology requires but that do not exist yet.
+func (s *Service) ensureConnections(ctx context.Context, net *network.Network) error {
+	peers, err := s.repo.ListPeers(ctx, net.ID)
+	if err != nil {
+		return fmt.Errorf("failed to list peers: %w", err)
+	}
+	conns, err := s.repo.ListConnections(ctx, net.ID)
+	if err != nil {
+		return fmt.Errorf("failed to list connections: %w", err)
+	}
+	existing := make(map[[2]string]bool, len(conns))
+	for _, c := range conns {
+		existing[[2]string{c.Peer1ID, c.Peer2ID}] = true
+		existing[[2]string{c.Peer2ID, c.Peer1ID}] = true
+	}
+
+	created := 0
+	for i, a := range peers {
+		for _, b := range peers[i+1:] {
+			if !net.NeedsConnection(a, b) || existing[[2]string{a.ID, b.ID}] {
+				continue
+			}
+			psk, err := wireguard.GeneratePresharedKey()
+			if err != nil {
+				return fmt.Errorf("failed to generate preshared key: %w", err)
+			}
+			conn := &network.PeerConnection{Peer1ID: a.ID, Peer2ID: b.ID, PresharedKey: psk, CreatedAt: time.Now()}
+			if err := s.repo.CreateConnection(ctx, net.ID, conn); err != nil {
+				return fmt.Errorf("failed to create connection: %w", err)
+			}
+			created++
+		}
+	}
+	if created > 0 {
+		log.Info().Str("network_id", net.ID).Str("topology", net.Topology).Int("created", created).
+			Msg("created missing peer connections for topology")
+	}
+	return nil
+}
+
+// releasePeerAddresses returns the given address(es) to the network's IPAM
+// pools. Failures are logged rather than returned: callers use this on an
+// error path and already have a more relevant error to report.
+func (s *Service) releasePeerAddresses(ctx context.Context, net *network.Network, address, addressV6 string) {
+	if net.CIDR != "" && address != "" {
+		if err := s.repo.ReleaseIP(ctx, net.CIDR, address); err != nil {
+			log.Warn().Err(err).Str("ip", address).Str("cidr", net.CIDR).Msg("failed to release IPv4 address")
+		}
+	}
+	if net.CIDRv6 != "" && addressV6 != "" {
+		if err := s.repo.ReleaseIP(ctx, net.CIDRv6, addressV6); err != nil {
+			log.Warn().Err(err).Str("ip", addressV6).Str("cidr", net.CIDRv6).Msg("failed to release IPv6 address")
+		}
+	}
+}
+
+// BulkPeerError describes why a single entry of a bulk peer creation failed
+type BulkPeerError struct {
+	Index int    `json:"index"`
+	Name  string `json:"name"`
+	Error string `json:"error"`
+}
+
+// AddPeersResult is the outcome of a bulk peer creation: the peers that were
+// created and an error entry for every request that was not.
+type AddPeersResult struct {
+	Peers  []*network.Peer `json:"peers"`
+	Errors []BulkPeerError `json:"errors"`
+}
+
+// AddPeers creates a batch of peers in the network. Each request is processed
+// in order through AddPeer, so IP allocation, key generation, default-group
+// assignment and preshared-key connections (including between peers of the
+// same batch) behave exactly as for single creations. A failing entry does
+// not abort the batch; its addresses are released and it is reported in
+// Errors with its index so the caller knows exactly which peers exist.
+// The owner of each peer is taken from its request's OwnerID.
+func (s *Service) AddPeers(ctx context.Context, networkID string, reqs []*network.PeerCreateRequest) (*AddPeersResult, error) {
+	if _, err := s.repo.GetNetwork(ctx, networkID); err != nil {
+		return nil, fmt.Errorf("network not found: %w", err)
+	}
+
+	result := &AddPeersResult{
+		Peers:  []*network.Peer{},
+		Errors: []BulkPeerError{},
+	}
+	for i, req := range reqs {
+		peer, err := s.AddPeer(ctx, networkID, req, req.OwnerID)
+		if err != nil {
+			result.Errors = append(result.Errors, BulkPeerError{Index: i, Name: req.Name, Error: err.Error()})
+			continue
+		}
+		result.Peers = append(result.Peers, peer)
+	}
+
+	return result, nil
+}
+
+// RotateNetworkPresharedKeys regenerates the preshared key of every peer
+// connection in the network and notifies the peers to pull their new configs.
+// Connections are processed in a deterministic order (by peer pair) so that a
+// failure always stops at the same place; since every call simply issues
+// fresh keys, a failed or repeated rotation is fixed by calling it again.
+// It returns the number of connections rotated.
+func (s *Service) RotateNetworkPresharedKeys(ctx context.Context, networkID string) (int, error) {
+	if _, err := s.repo.GetNetwork(ctx, networkID); err != nil {
+		return 0, fmt.Errorf("network not found: %w", err)
+	}
+
+	conns, err := s.repo.ListConnections(ctx, networkID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list connections: %w", err)
+	}
+	sort.Slice(conns, func(i, j int) bool {
+		if conns[i].Peer1ID != conns[j].Peer1ID {
+			return conns[i].Peer1ID < conns[j].Peer1ID
+		}
+		return conns[i].Peer2ID < conns[j].Peer2ID
+	})
+
+	rotated := 0
+	var rotateErr error
+	for _, conn := range conns {
+		psk, err := wireguard.GeneratePresharedKey()
+		if err != nil {
+			rotateErr = fmt.Errorf("failed to generate preshared key: %w", err)
+			break
+		}
+		updated := *conn
+		updated.PresharedKey = psk
+		if err := s.repo.UpdateConnection(ctx, networkID, &updated); err != nil {
+			rotateErr = fmt.Errorf("failed to update connection %s/%s: %w", conn.Peer1ID, conn.Peer2ID, err)
+			break
+		}
+		rotated++
+	}
+
+	// Peers only pick up the new keys once they pull a fresh config; notify
+	// even on partial failure since some keys have already changed.
+	if rotated > 0 && s.wsNotifier != nil {
+		s.wsNotifier.NotifyNetworkPeers(networkID)
+	}
+	if rotateErr != nil {
+		return rotated, rotateErr
+	}
+
+	log.Info().Str("network_id", networkID).Int("connections", rotated).Msg("rotated network preshared keys")
+	audit.Record(ctx, s.auditLogger, "network.psk_rotate", networkID, "", "")
+
+	return rotated, nil
+}
+
+// SetConnectionAllowedIPs sets the AllowedIPs of otherPeerID's [Peer]
+// section in peerID's config, replacing the ones derived from the topology
+// and routes.  The reverse section keeps its defaults.  An empty list
+// removes the override.  It returns the normalized list.
+func (s *Service) SetConnectionAllowedIPs(ctx context.Context, networkID, peerID, otherPeerID string, allowedIPs []string) ([]string, error) {
+	net, err := s.repo.GetNetwork(ctx, networkID)
+	if err != nil {
+		return nil, fmt.Errorf("network not found: %w", err)
+	}
+	if _, ok := net.GetPeer(peerID); !ok {
+		return nil, network.ErrPeerNotFound
+	}
+	if _, ok := net.GetPeer(otherPeerID); !ok {
+		return nil, network.ErrPeerNotFound
+	}
+	connected := false
+	for _, p := range net.GetAllowedPeersFor(peerID) {
+		connected = connected || p.ID == otherPeerID
+	}
+	if !connected {
+		return nil, network.ErrPeersNotConnected
+	}
+	normalized, err := validation.NormalizeAllowedIPs(allowedIPs)
+	if err != nil {
+		return nil, fmt.Errorf("invalid allowed_ips: %w", err)
+	}
+
+	conn, err := s.repo.GetConnection(ctx, networkID, peerID, otherPeerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get connection: %w", err)
+	}
+	updated := *conn
+	updated.AllowedIPs = nil
+	updated.AllowedIPsPeerID = ""
+	if len(normalized) > 0 {
+		updated.AllowedIPs = normalized
+		updated.AllowedIPsPeerID = otherPeerID
+	}
+	if err := s.repo.UpdateConnection(ctx, networkID, &updated); err != nil {
+		return nil, fmt.Errorf("failed to update connection: %w", err)
+	}
+
+	// A previous override may have applied to either peer's config
+	if s.wsNotifier != nil {
+		s.wsNotifier.NotifyNetworkPeers(networkID)
+	}
+	audit.Record(ctx, s.auditLogger, "peer.connection_allowed_ips", networkID, peerID, otherPeerID)
+	return normalized, nil
+}
+
 // GetPeer retrieves a peer by ID
 func (s *Service) GetPeer(ctx context.Context, networkID, peerID string) (*network.Peer, error) {
 	return s.repo.GetPeer(ctx, networkID, peerID)
@@ -436,6 +956,53 @@ func (s *Service) ListPeers(ctx context.Context, networkID string) ([]*network.P
 	return s.repo.ListPeers(ctx, networkID)
 }
 
+// SearchPeers finds the peers of every network whose name, addresses, public
+// key or ID contain query, ignoring case, and returns the requested page with
+// the total number of matches.
+func (s *Service) SearchPeers(ctx context.Context, query string, offset, limit int) ([]*network.PeerSearchResult, int, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, 0, network.ErrEmptySearchQuery
+	}
+	return s.repo.SearchPeers(ctx, query, offset, limit)
+}
+
+// checkPeerNamePattern enforces the network's optional naming convention.
+// The error names the expected pattern so users can fix the name.
+func checkPeerNamePattern(net *network.Network, name string) error {
+	if net.PeerNamePattern == "" {
+		return nil
+	}
+	re, err := validation.CompilePeerNamePattern(net.PeerNamePattern)
+	if err != nil {
+		return err
+	}
+	if !re.MatchString(name) {
+		return fmt.Errorf("%w: %q must match %s", network.ErrPeerNamePattern, name, net.PeerNamePattern)
+	}
+	return nil
+}
+
+// checkStaticAddress checks that a requested peer address is an IPv4 host
+// address of the network's CIDR, before anything is taken from IPAM.
+func checkStaticAddress(nw *network.Network, address string) error {
+	addr, err := netip.ParseAddr(address)
+	if err != nil || !addr.Is4() {
+		return fmt.Errorf("%w: %q is not an IPv4 address", network.ErrInvalidIP, address)
+	}
+	prefix, err := netip.ParsePrefix(nw.CIDR)
+	if err != nil || !prefix.Contains(addr) {
+		return fmt.Errorf("%w: %s is outside %q", network.ErrIPNotInNetwork, addr, nw.CIDR)
+	}
+	if prefix.Bits() < 31 {
+		host := binary.BigEndian.Uint32(addr.AsSlice()) &^ (^uint32(0) << (32 - prefix.Bits()))
+		if host == 0 || host == ^uint32(0)>>prefix.Bits() {
+			return fmt.Errorf("%w: %s is the network or broadcast address of %s", network.ErrIPNotInNetwork, addr, nw.CIDR)
+		}
+	}
+	return nil
+}
+
 // UpdatePeer updates a peer's configuration
 func (s *Service) UpdatePeer(ctx context.Context, networkID, peerID string, req *network.PeerUpdateRequest) (*network.Peer, error) {
 	// Validate peer name if provided
@@ -444,12 +1011,66 @@ func (s *Service) UpdatePeer(ctx context.Context, networkID, peerID string, req
 			return nil, fmt.Errorf("invalid peer name: %w", err)
 		}
 	}
+	if req.Endpoint != "" {
+		if err := validation.ValidateEndpointHost(req.Endpoint); err != nil {
+			return nil, fmt.Errorf("invalid endpoint: %w", err)
+		}
+	}
+	if err := validatePeerRole(req.Role); err != nil {
+		return nil, err
+	}
+	var mtu int
+	if req.MTU != nil {
+		mtu = *req.MTU
+	}
+	if err := network.ValidatePeerSettings(mtu, req.PersistentKeepalive, req.DNS, req.SplitTunnelExclusions); err != nil {
+		return nil, err
+	}
+	if err := validateInherit(req); err != nil {
+		return nil, err
+	}
+	if req.RoutingTable != nil {
+		if err := network.ValidateRoutingTable(*req.RoutingTable); err != nil {
+			return nil, err
+		}
+	}
+	if req.FwMark != nil {
+		if err := network.ValidateFwMark(*req.FwMark); err != nil {
+			return nil, err
+		}
+	}
+	if err := network.ValidateLabels(req.Labels); err != nil {
+		return nil, err
+	}
+	var additionalIPs []string
+	if req.AdditionalAllowedIPs != nil {
+		var err error
+		additionalIPs, err = validation.NormalizeAllowedIPs(req.AdditionalAllowedIPs)
+		if err != nil {
+			return nil, fmt.Errorf("invalid additional_allowed_ips: %w", err)
+		}
+	}
+	if req.ProfileID != nil && *req.ProfileID != "" {
+		if _, err := s.repo.GetPeerProfile(ctx, networkID, *req.ProfileID); err != nil {
+			return nil, err
+		}
+	}
 
 	peer, err := s.repo.GetPeer(ctx, networkID, peerID)
 	if err != nil {
 		return nil, fmt.Errorf("peer not found: %w", err)
 	}
 
+	if req.Name != "" && req.Name != peer.Name {
+		net, err := s.repo.GetNetwork(ctx, networkID)
+		if err != nil {
+			return nil, fmt.Errorf("network not found: %w", err)
+		}
+		if err := checkPeerNamePattern(net, req.Name); err != nil {
+			return nil, err
+		}
+	}
+
 	if req.ListenPort != 0 {
 		peer.ListenPort = req.ListenPort
 	}
@@ -459,8 +1080,8 @@ func (s *Service) UpdatePeer(ctx context.Context, networkID, peerID string, req
 	if req.Endpoint != "" {
 		peer.Endpoint = req.Endpoint
 	}
-	if req.AdditionalAllowedIPs != nil {
-		peer.AdditionalAllowedIPs = req.AdditionalAllowedIPs
+	if additionalIPs != nil {
+		peer.AdditionalAllowedIPs = additionalIPs
 	}
 	// Ensure AdditionalAllowedIPs is never nil
 	if peer.AdditionalAllowedIPs == nil {
@@ -470,6 +1091,66 @@ func (s *Service) UpdatePeer(ctx context.Context, networkID, peerID string, req
 	if req.OwnerID != "" {
 		peer.OwnerID = req.OwnerID
 	}
+	if req.Role != "" {
+		peer.Role = req.Role
+	}
+	peer.Role = peer.EffectiveRole()
+	if req.SplitTunnelExclusions != nil {
+		peer.SplitTunnelExclusions = req.SplitTunnelExclusions
+	}
+	if req.ProfileID != nil {
+		peer.ProfileID = *req.ProfileID
+	}
+	if req.MTU != nil {
+		peer.MTU = mtu
+	}
+	if req.PersistentKeepalive != nil {
+		peer.PersistentKeepalive = req.PersistentKeepalive
+	}
+	if req.DNS != nil {
+		peer.DNS = req.DNS
+	}
+	for _, setting := range req.Inherit {
+		switch setting {
+		case network.PeerSettingMTU:
+			peer.MTU = 0
+		case network.PeerSettingPersistentKeepalive:
+			peer.PersistentKeepalive = nil
+		case network.PeerSettingDNS:
+			peer.DNS = nil
+		case network.PeerSettingSplitTunnelExclusions:
+			peer.SplitTunnelExclusions = nil
+		}
+	}
+	if req.RoutingTable != nil {
+		peer.RoutingTable = *req.RoutingTable
+	}
+	if req.FwMark != nil {
+		peer.FwMark = *req.FwMark
+	}
+	if req.DNSOnly != nil {
+		if *req.DNSOnly && peer.IsJump {
+			return nil, network.ErrDNSOnlyJumpPeer
+		}
+		peer.DNSOnly = *req.DNSOnly
+	}
+	// A past expiry is accepted here: it cuts the peer off right away
+	if req.ClearExpiry {
+		peer.ExpiresAt = nil
+	} else if req.ExpiresAt != nil {
+		peer.ExpiresAt = req.ExpiresAt
+	}
+	if req.TokenSingleUse != nil {
+		peer.TokenSingleUse = *req.TokenSingleUse
+	}
+	if req.ClearTokenExpiry {
+		peer.TokenExpiresAt = nil
+	} else if req.TokenExpiresAt != nil {
+		peer.TokenExpiresAt = req.TokenExpiresAt
+	}
+	if req.Labels != nil {
+		peer.Labels = req.Labels
+	}
 	peer.UpdatedAt = time.Now()
 	// Preserve token (do not allow overwrite via update)
 
@@ -479,9 +1160,46 @@ func (s *Service) UpdatePeer(ctx context.Context, networkID, peerID string, req
 		return nil, fmt.Errorf("failed to update peer: %w", err)
 	}
 
+	audit.Record(ctx, s.auditLogger, "peer.update", networkID, peerID, "")
+
 	return peer, nil
 }
 
+// validatePeerRole checks a role requested for a peer. Empty means "keep the
+// default"; jump is not accepted because it is derived from IsJump.
+func validatePeerRole(role string) error {
+	switch role {
+	case "", network.PeerRoleClient, network.PeerRoleResource:
+		return nil
+	default:
+		return fmt.Errorf("invalid peer role %q: must be %q or %q", role, network.PeerRoleClient, network.PeerRoleResource)
+	}
+}
+
+// validateInherit checks that the settings an update inherits are known and
+// not set by the same request.
+func validateInherit(req *network.PeerUpdateRequest) error {
+	for _, setting := range req.Inherit {
+		var set bool
+		switch setting {
+		case network.PeerSettingMTU:
+			set = req.MTU != nil
+		case network.PeerSettingPersistentKeepalive:
+			set = req.PersistentKeepalive != nil
+		case network.PeerSettingDNS:
+			set = req.DNS != nil
+		case network.PeerSettingSplitTunnelExclusions:
+			set = req.SplitTunnelExclusions != nil
+		default:
+			return fmt.Errorf("cannot inherit unknown setting %q", setting)
+		}
+		if set {
+			return fmt.Errorf("%s cannot be both set and inherited", setting)
+		}
+	}
+	return nil
+}
+
 // DeletePeer removes a peer from the network
 func (s *Service) DeletePeer(ctx context.Context, networkID, peerID string) error {
 	// Retrieve network and peer to release IP before deletion
@@ -537,11 +1255,78 @@ func (s *Service) DeletePeer(ctx context.Context, networkID, peerID string) erro
 		}
 	}
 
-	return s.repo.DeletePeer(ctx, networkID, peerID)
+	if err := s.repo.DeletePeer(ctx, networkID, peerID); err != nil {
+		return err
+	}
+
+	audit.Record(ctx, s.auditLogger, "peer.delete", networkID, peerID, "")
+
+	return nil
+}
+
+// ReleaseIP forcibly returns a single address of the network back to IPAM.
+// It is meant for reclaiming allocations that leaked (e.g. a peer row deleted
+// out-of-band). Unless force is set, the release is refused when a live peer
+// still holds the address, since handing it out again would create a duplicate.
+func (s *Service) ReleaseIP(ctx context.Context, networkID, ip string, force bool) error {
+	nw, err := s.repo.GetNetwork(ctx, networkID)
+	if err != nil {
+		return fmt.Errorf("network not found: %w", err)
+	}
+
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return fmt.Errorf("%w: %q", network.ErrInvalidIP, ip)
+	}
+
+	// Pick the network CIDR of the matching family.
+	cidr := ""
+	for _, candidate := range []string{nw.CIDR, nw.CIDRv6} {
+		if candidate == "" {
+			continue
+		}
+		if _, ipNet, err := net.ParseCIDR(candidate); err == nil && ipNet.Contains(addr) {
+			cidr = candidate
+			break
+		}
+	}
+	if cidr == "" {
+		return network.ErrIPNotInNetwork
+	}
+
+	peers, err := s.repo.ListPeers(ctx, networkID)
+	if err != nil {
+		return fmt.Errorf("failed to list peers: %w", err)
+	}
+	for _, p := range peers {
+		if p.Address == addr.String() || p.AddressV6 == addr.String() {
+			if !force {
+				return fmt.Errorf("%w: %s (%s)", network.ErrIPInUse, p.Name, p.ID)
+			}
+			log.Warn().Str("network_id", networkID).Str("peer_id", p.ID).Str("ip", addr.String()).Msg("force-releasing IP still held by a live peer")
+		}
+	}
+
+	if err := s.repo.ReleaseIP(ctx, cidr, addr.String()); err != nil {
+		return fmt.Errorf("failed to release IP: %w", err)
+	}
+	audit.Record(ctx, s.auditLogger, "ipam.release", networkID, "", ip)
+
+	return nil
 }
 
 // GeneratePeerConfig generates WireGuard configuration for a specific peer
 func (s *Service) GeneratePeerConfig(ctx context.Context, networkID, peerID string) (string, error) {
+	return s.generatePeerConfig(ctx, networkID, peerID, false)
+}
+
+// GenerateAgentConfig is GeneratePeerConfig for the peer's agent: the
+// private key is left out when the network has OmitPrivateKeys set.
+func (s *Service) GenerateAgentConfig(ctx context.Context, networkID, peerID string) (string, error) {
+	return s.generatePeerConfig(ctx, networkID, peerID, true)
+}
+
+func (s *Service) generatePeerConfig(ctx context.Context, networkID, peerID string, forAgent bool) (string, error) {
 	net, err := s.repo.GetNetwork(ctx, networkID)
 	if err != nil {
 		return "", fmt.Errorf("network not found: %w", err)
@@ -552,43 +1337,306 @@ func (s *Service) GeneratePeerConfig(ctx context.Context, networkID, peerID stri
 		return "", fmt.Errorf("peer not found")
 	}
 
-	allowedPeers := net.GetAllowedPeersFor(peerID)
+	// Connections carry the preshared keys and AllowedIPs overrides, and
+	// drop the severed peers
+	allowedPeers, connections := s.peerConnections(ctx, networkID, peerID, net.GetAllowedPeersFor(peerID))
 
-	// Build a map of preshared keys for allowed peers
-	presharedKeys := make(map[string]string)
-	for _, allowedPeer := range allowedPeers {
-		conn, err := s.repo.GetConnection(ctx, networkID, peerID, allowedPeer.ID)
-		if err == nil && conn != nil {
-			presharedKeys[allowedPeer.ID] = conn.PresharedKey
-		}
+	// Get routes for this peer based on group membership
+	peerRoutes, err := s.collectPeerRoutes(ctx, networkID, peer)
+	if err != nil {
+		return "", err
 	}
 
-	// Get routes for this peer based on group membership
+	opts := s.configOptions(ctx, net, forAgent)
+	config := wireguard.GenerateConfigWithOptions(s.resolvePeerProfile(ctx, networkID, peer), allowedPeers, net, connections, peerRoutes, opts)
+
+	metrics.ConfigGenerations.Inc()
+	return config, nil
+}
+
+// globalRoutePriority ranks global routes behind every group (group
+// priorities go up to 999), so a group granting the same destination wins.
+const globalRoutePriority = 1000
+
+// collectPeerRoutes returns the routes granted to a peer through its group
+// memberships, plus the network's global routes for regular peers,
+// deduplicated by route ID and with conflicts resolved (see
+// resolveRouteConflicts). Each route inherits the priority of the
+// highest-priority (lowest number) group granting it.
+func (s *Service) collectPeerRoutes(ctx context.Context, networkID string, peer *network.Peer) ([]*network.Route, error) {
+	peerID := peer.ID
+	// Collect all routes from all groups, deduplicated in first-seen order
 	var peerRoutes []*network.Route
+	priorities := make(map[string]int)
 	if s.routeRepo != nil && s.groupRepo != nil {
 		// Get all groups this peer belongs to
 		groups, err := s.groupRepo.GetPeerGroups(ctx, networkID, peerID)
 		if err == nil {
-			// Collect all routes from all groups
-			routeMap := make(map[string]*network.Route) // Use map to deduplicate routes
 			for _, group := range groups {
 				routes, err := s.groupRepo.GetGroupRoutes(ctx, networkID, group.ID)
-				if err == nil {
-					for _, route := range routes {
-						routeMap[route.ID] = route
+				if err != nil {
+					continue
+				}
+				for _, route := range routes {
+					if route.JumpPeerID == "" {
+						continue // cloned route still waiting for its jump peer
+					}
+					prio, seen := priorities[route.ID]
+					if !seen {
+						peerRoutes = append(peerRoutes, route)
+					}
+					if !seen || group.Priority < prio {
+						priorities[route.ID] = group.Priority
 					}
 				}
 			}
-			// Convert map to slice
-			for _, route := range routeMap {
+		}
+	}
+
+	// Jump peers are the gateways: a global route would send its
+	// destination back into the tunnel from every one of them
+	if s.routeRepo != nil && !peer.IsJump {
+		routes, err := s.routeRepo.ListRoutes(ctx, networkID)
+		if err != nil {
+			log.Warn().Err(err).Str("network_id", networkID).Msg("failed to list global routes")
+		}
+		for _, route := range routes {
+			if !route.GlobalRoute || route.JumpPeerID == "" {
+				continue
+			}
+			if _, seen := priorities[route.ID]; !seen {
 				peerRoutes = append(peerRoutes, route)
+				priorities[route.ID] = globalRoutePriority
 			}
 		}
 	}
 
-	config := wireguard.GenerateConfig(peer, allowedPeers, net, presharedKeys, peerRoutes)
+	// Break-glass grants outrank every group (group priorities start at 1)
+	for _, temp := range s.activeTempRoutes(ctx, networkID, peerID) {
+		route := temp.AsRoute()
+		peerRoutes = append(peerRoutes, route)
+		priorities[route.ID] = 0
+	}
 
-	return config, nil
+	return resolveRouteConflicts(peerID, peerRoutes, priorities, s.strictRouteConflicts)
+}
+
+// activeTempRoutes returns the peer's unexpired temporary routes.  Errors are
+// logged and treated as "no grants" so config generation keeps working.
+func (s *Service) activeTempRoutes(ctx context.Context, networkID, peerID string) []*network.TempRoute {
+	grants, err := s.repo.ListTempRoutes(ctx, networkID, peerID)
+	if err != nil {
+		log.Warn().Err(err).Str("network_id", networkID).Str("peer_id", peerID).Msg("failed to list temporary routes")
+		return nil
+	}
+	now := s.clock()
+	active := make([]*network.TempRoute, 0, len(grants))
+	for _, g := range grants {
+		if g.ExpiresAt.After(now) {
+			active = append(active, g)
+		}
+	}
+	return active
+}
+
+// GrantTempRoute gives a peer time-bounded access to a route or CIDR through
+// a jump peer.  The grant shows up in the peer's config immediately and is
+// removed by CleanupExpiredTempRoutes once it expires.
+func (s *Service) GrantTempRoute(ctx context.Context, networkID, peerID string, req *network.TempRouteCreateRequest, grantedBy string) (*network.TempRoute, error) {
+	if err := req.Validate(); err != nil {
+		return nil, fmt.Errorf("validation failed: %w", err)
+	}
+
+	peer, err := s.repo.GetPeer(ctx, networkID, peerID)
+	if err != nil {
+		return nil, fmt.Errorf("peer not found: %w", err)
+	}
+	if peer.IsJump {
+		return nil, fmt.Errorf("temporary routes can only be granted to regular peers")
+	}
+
+	now := s.clock()
+	temp := &network.TempRoute{
+		ID:                uuid.New().String(),
+		NetworkID:         networkID,
+		PeerID:            peerID,
+		DestinationCIDR:   req.DestinationCIDR,
+		DestinationCIDRv6: req.DestinationCIDRv6,
+		JumpPeerID:        req.JumpPeerID,
+		Reason:            req.Reason,
+		CreatedBy:         grantedBy,
+		CreatedAt:         now,
+		ExpiresAt:         now.Add(time.Duration(req.DurationMinutes) * time.Minute),
+	}
+	if req.RouteID != "" {
+		if s.routeRepo == nil {
+			return nil, fmt.Errorf("route repository not configured")
+		}
+		route, err := s.routeRepo.GetRoute(ctx, networkID, req.RouteID)
+		if err != nil {
+			return nil, fmt.Errorf("route not found: %w", err)
+		}
+		temp.RouteID = route.ID
+		temp.DestinationCIDR = route.DestinationCIDR
+		temp.DestinationCIDRv6 = route.DestinationCIDRv6
+		temp.JumpPeerID = route.JumpPeerID
+	}
+
+	jump, err := s.repo.GetPeer(ctx, networkID, temp.JumpPeerID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", network.ErrJumpPeerNotFound, temp.JumpPeerID)
+	}
+	if !jump.IsJump {
+		return nil, network.ErrNotJumpPeer
+	}
+
+	if err := s.repo.CreateTempRoute(ctx, temp); err != nil {
+		return nil, fmt.Errorf("failed to create temporary route: %w", err)
+	}
+
+	if s.wsNotifier != nil {
+		s.wsNotifier.NotifyNetworkPeers(networkID)
+	}
+	audit.Record(ctx, s.auditLogger, "peer.temp_route_grant", networkID, peerID, temp.ID)
+
+	return temp, nil
+}
+
+// ListTempRoutes returns the unexpired temporary routes of a peer
+func (s *Service) ListTempRoutes(ctx context.Context, networkID, peerID string) ([]*network.TempRoute, error) {
+	if _, err := s.repo.GetPeer(ctx, networkID, peerID); err != nil {
+		return nil, fmt.Errorf("peer not found: %w", err)
+	}
+	return s.activeTempRoutes(ctx, networkID, peerID), nil
+}
+
+// CleanupExpiredTempRoutes deletes expired temporary routes and pushes fresh
+// configs to the affected networks.  Each removal is written to the audit log.
+func (s *Service) CleanupExpiredTempRoutes(ctx context.Context) error {
+	expired, err := s.repo.ListExpiredTempRoutes(ctx, s.clock())
+	if err != nil {
+		return fmt.Errorf("failed to list expired temporary routes: %w", err)
+	}
+
+	affected := make(map[string]bool)
+	for _, temp := range expired {
+		if err := s.repo.DeleteTempRoute(ctx, temp.NetworkID, temp.ID); err != nil {
+			log.Warn().Err(err).Str("network_id", temp.NetworkID).Str("temp_route_id", temp.ID).
+				Msg("failed to delete expired temporary route")
+			continue
+		}
+		affected[temp.NetworkID] = true
+		audit.Server("", "", "").
+			Str("action", "peer.temp_route.expire").
+			Str("network_id", temp.NetworkID).
+			Str("peer_id", temp.PeerID).
+			Str("temp_route_id", temp.ID).
+			Str("destination_cidr", temp.DestinationCIDR).
+			Str("destination_cidr_v6", temp.DestinationCIDRv6).
+			Msg("audit")
+	}
+
+	if s.wsNotifier != nil {
+		for networkID := range affected {
+			s.wsNotifier.NotifyNetworkPeers(networkID)
+		}
+	}
+	return nil
+}
+
+// resolveRouteConflicts detects routes that send the same destination CIDR
+// through different jump peers. Left alone, both jumps' [Peer] sections would
+// claim the CIDR in AllowedIPs and the next hop would be ambiguous. The route
+// with the lowest metric wins, then the one with the best group priority
+// (lower number first), then the oldest, then the lowest ID so the outcome
+// is deterministic; the losing route only loses the conflicting address
+// family and is dropped once it has no CIDR left. In strict mode a conflict
+// is an error instead. The result is sorted in that order, with more
+// specific destinations ahead of broader ones between routes that tie on
+// metric and priority.
+func resolveRouteConflicts(peerID string, routes []*network.Route, priorities map[string]int, strict bool) ([]*network.Route, error) {
+	sort.Slice(routes, func(i, j int) bool {
+		a, b := routes[i], routes[j]
+		if a.Metric != b.Metric {
+			return a.Metric < b.Metric
+		}
+		if priorities[a.ID] != priorities[b.ID] {
+			return priorities[a.ID] < priorities[b.ID]
+		}
+		if pa, pb := routePrefixLen(a), routePrefixLen(b); pa != pb {
+			return pa > pb
+		}
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.Before(b.CreatedAt)
+		}
+		return a.ID < b.ID
+	})
+
+	// Normalized destination CIDR -> route that currently owns it.
+	owners := make(map[string]*network.Route)
+	resolved := make([]*network.Route, 0, len(routes))
+	for _, route := range routes {
+		kept := route
+		for _, v6 := range []bool{false, true} {
+			cidr := kept.DestinationCIDR
+			if v6 {
+				cidr = kept.DestinationCIDRv6
+			}
+			if cidr == "" {
+				continue
+			}
+			key := cidr
+			if _, ipNet, err := net.ParseCIDR(cidr); err == nil {
+				key = ipNet.String()
+			}
+			owner, taken := owners[key]
+			if !taken {
+				owners[key] = route
+				continue
+			}
+			if owner.JumpPeerID == route.JumpPeerID {
+				continue // same next hop, nothing ambiguous
+			}
+			if strict {
+				return nil, fmt.Errorf("%w: %s via route %q and route %q", network.ErrRouteConflict, key, owner.Name, route.Name)
+			}
+			log.Warn().
+				Str("peer_id", peerID).
+				Str("cidr", key).
+				Str("winning_route_id", owner.ID).
+				Str("winning_jump_peer_id", owner.JumpPeerID).
+				Str("dropped_route_id", route.ID).
+				Str("dropped_jump_peer_id", route.JumpPeerID).
+				Msg("conflicting routes for the same destination via different jump peers; keeping the higher-priority route")
+			if kept == route {
+				cp := *route
+				kept = &cp
+			}
+			if v6 {
+				kept.DestinationCIDRv6 = ""
+			} else {
+				kept.DestinationCIDR = ""
+			}
+		}
+		if kept.DestinationCIDR != "" || kept.DestinationCIDRv6 != "" {
+			resolved = append(resolved, kept)
+		}
+	}
+	return resolved, nil
+}
+
+// routePrefixLen returns the prefix length of a route's IPv4 destination, or
+// of its IPv6 destination for IPv6-only routes; -1 when neither parses.
+func routePrefixLen(route *network.Route) int {
+	cidr := route.DestinationCIDR
+	if cidr == "" {
+		cidr = route.DestinationCIDRv6
+	}
+	if _, ipNet, err := net.ParseCIDR(cidr); err == nil {
+		ones, _ := ipNet.Mask.Size()
+		return ones
+	}
+	return -1
 }
 
 // PeerDNSConfig is sent to jump agents for DNS server startup
@@ -599,6 +1647,10 @@ type DNSPeer struct {
 	Name string `json:"name"`
 	IP   string `json:"ip"`
 	IPv6 string `json:"ipv6,omitempty"` // IPv6 WireGuard address (optional)
+	// Type is "A" (the default when empty), "CNAME" or "TXT"; CNAME and TXT
+	// records carry their target or text in Value instead of an address.
+	Type  string `json:"type,omitempty"`
+	Value string `json:"value,omitempty"`
 }
 
 type PeerDNSConfig struct {
@@ -606,6 +1658,16 @@ type PeerDNSConfig struct {
 	Domain          string    `json:"domain"`
 	Peers           []DNSPeer `json:"peers"`
 	UpstreamServers []string  `json:"upstream_servers"` // Upstream DNS servers for forwarding
+	// ConditionalForwarders maps domains to the resolvers answering them
+	// instead of UpstreamServers (split-horizon DNS).
+	ConditionalForwarders map[string][]string `json:"conditional_forwarders,omitempty"`
+	// QueryLog has the DNS server record the queries it receives and report
+	// them with its heartbeat.
+	QueryLog bool `json:"query_log,omitempty"`
+	// SearchDomains qualifies single-label queries, tried in order; the
+	// first is the network's own domain.  Empty unless the network has
+	// search domains.
+	SearchDomains []string `json:"search_domains,omitempty"`
 }
 
 // sanitizeDNSLabel converts a peer name into a DNS-safe lowercase label.
@@ -634,7 +1696,15 @@ func sanitizeDNSLabel(s string) string {
 type JumpPolicy struct {
 	IP            string   `json:"ip"`
 	IPTablesRules []string `json:"iptables_rules"` // Generated iptables rules from policies
-	Peers         []struct {
+	// FirewallBackend is the backend the jump agent last reported.  When it is
+	// nftables, NftRules carries the same rules translated to nft syntax;
+	// IPTablesRules is still sent for agents that predate NftRules.
+	FirewallBackend string   `json:"firewall_backend,omitempty"`
+	NftRules        []string `json:"nft_rules,omitempty"`
+	// Rules is the backend-neutral form of IPTablesRules, rendered by agents
+	// that choose their firewall backend locally.
+	Rules []firewall.Rule `json:"rules,omitempty"`
+	Peers []struct {
 		ID       string `json:"id"`
 		Name     string `json:"name"`
 		IP       string `json:"ip"`
@@ -643,7 +1713,42 @@ type JumpPolicy struct {
 }
 
 // GeneratePeerConfigWithDNS returns WireGuard config, DNS config & jump policy (for jump peers)
-func (s *Service) GeneratePeerConfigWithDNS(ctx context.Context, networkID, peerID string) (string, *PeerDNSConfig, *JumpPolicy, error) {
+func (s *Service) GeneratePeerConfigWithDNS(ctx context.Context, networkID, peerID string) (_ string, _ *PeerDNSConfig, _ *JumpPolicy, err error) {
+	ctx, span := tracing.Start(ctx, "network.GeneratePeerConfigWithDNS")
+	span.SetAttributes(attribute.String("network.id", networkID), attribute.String("peer.id", peerID))
+	defer func() { tracing.RecordError(span, err); span.End() }()
+
+	if s.configCache == nil {
+		return s.generatePeerConfigWithDNS(ctx, networkID, peerID)
+	}
+
+	// Read the version first: a change landing while the config renders
+	// bumps it, and put then drops the possibly outdated result.
+	version := s.configCache.version(networkID)
+	if cached, ok := s.configCache.get(networkID, peerID, version); ok {
+		metrics.ConfigCacheLookups.WithLabelValues("hit").Inc()
+		span.SetAttributes(attribute.String("config.cache", "hit"))
+		return cached.config, cached.dns, cached.policy, nil
+	}
+	metrics.ConfigCacheLookups.WithLabelValues("miss").Inc()
+	span.SetAttributes(attribute.String("config.cache", "miss"))
+
+	config, dnsConfig, policy, err := s.generatePeerConfigWithDNS(ctx, networkID, peerID)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	s.configCache.put(&cachedConfig{
+		key:    configCacheKey{networkID: networkID, peerID: peerID, version: version},
+		config: config,
+		dns:    dnsConfig,
+		policy: policy,
+	})
+	return config, dnsConfig, policy, nil
+}
+
+// generatePeerConfigWithDNS renders a peer's config, DNS config and policy
+// from the repositories, bypassing the config cache.
+func (s *Service) generatePeerConfigWithDNS(ctx context.Context, networkID, peerID string) (string, *PeerDNSConfig, *JumpPolicy, error) {
 	net, err := s.repo.GetNetwork(ctx, networkID)
 	if err != nil {
 		return "", nil, nil, fmt.Errorf("network not found: %w", err)
@@ -652,66 +1757,34 @@ func (s *Service) GeneratePeerConfigWithDNS(ctx context.Context, networkID, peer
 	if !exists {
 		return "", nil, nil, fmt.Errorf("peer not found")
 	}
-	allowedPeers := net.GetAllowedPeersFor(peerID)
-
-	presharedKeys := make(map[string]string)
-	for _, allowedPeer := range allowedPeers {
-		conn, err := s.repo.GetConnection(ctx, networkID, peerID, allowedPeer.ID)
-		if err == nil && conn != nil {
-			presharedKeys[allowedPeer.ID] = conn.PresharedKey
-		}
-	}
+	allowedPeers, connections := s.peerConnections(ctx, networkID, peerID, net.GetAllowedPeersFor(peerID))
 
 	// Get routes for this peer based on group membership
-	var peerRoutes []*network.Route
-	if s.routeRepo != nil && s.groupRepo != nil {
-		// Get all groups this peer belongs to
-		groups, err := s.groupRepo.GetPeerGroups(ctx, networkID, peerID)
-		if err == nil {
-			// Collect all routes from all groups
-			routeMap := make(map[string]*network.Route) // Use map to deduplicate routes
-			for _, group := range groups {
-				routes, err := s.groupRepo.GetGroupRoutes(ctx, networkID, group.ID)
-				if err == nil {
-					for _, route := range routes {
-						routeMap[route.ID] = route
-					}
-				}
-			}
-			// Convert map to slice
-			for _, route := range routeMap {
-				peerRoutes = append(peerRoutes, route)
-			}
-		}
+	peerRoutes, err := s.collectPeerRoutes(ctx, networkID, peer)
+	if err != nil {
+		return "", nil, nil, err
 	}
 
-	config := wireguard.GenerateConfig(peer, allowedPeers, net, presharedKeys, peerRoutes)
+	// Only agents receive these configs
+	opts := s.configOptions(ctx, net, true)
+	config := wireguard.GenerateConfigWithOptions(s.resolvePeerProfile(ctx, networkID, peer), allowedPeers, net, connections, peerRoutes, opts)
 	var dnsConfig *PeerDNSConfig
 	var policy *JumpPolicy
 	if peer.IsJump {
-		peerList := make([]DNSPeer, 0, len(net.Peers))
 		policy = &JumpPolicy{
-			IP: peer.Address,
-		}
-
-		// Generate iptables rules from policies attached to groups
-		if s.policyService != nil {
-			iptablesRules, err := s.policyService.GenerateIPTablesRules(ctx, networkID, peerID)
-			if err != nil {
-				// Log error but don't fail - jump peer can still function without policy rules
-				log.Warn().
-					Err(err).
-					Str("network_id", networkID).
-					Str("peer_id", peerID).
-					Msg("failed to generate iptables rules for jump peer")
-			} else {
-				policy.IPTablesRules = iptablesRules
-			}
+			IP: peer.Address,
+		}
+
+		if err := s.fillJumpFirewall(ctx, networkID, peerID, policy); err != nil {
+			// Log error but don't fail - jump peer can still function without policy rules
+			log.Warn().
+				Err(err).
+				Str("network_id", networkID).
+				Str("peer_id", peerID).
+				Msg("failed to generate iptables rules for jump peer")
 		}
 
-		// Add peer DNS records (include IPv6 when available for dual-stack networks)
 		for _, p := range net.Peers {
-			peerList = append(peerList, DNSPeer{Name: sanitizeDNSLabel(p.Name), IP: p.Address, IPv6: p.AddressV6})
 			policy.Peers = append(policy.Peers, struct {
 				ID       string `json:"id"`
 				Name     string `json:"name"`
@@ -725,72 +1798,7 @@ func (s *Service) GeneratePeerConfigWithDNS(ctx context.Context, networkID, peer
 			})
 		}
 
-		// Add route DNS records
-		if s.dnsRepo != nil && s.routeRepo != nil {
-			routeMappings, err := s.dnsRepo.GetNetworkDNSMappings(ctx, networkID)
-			if err == nil {
-				// Build FQDN from the *network's* name + domain suffix.  The
-				// route the mapping belongs to is still looked up so we can
-				// skip orphans, but it no longer influences the resolved name.
-				networkDomainSuffix := net.DomainSuffix
-				if networkDomainSuffix == "" {
-					networkDomainSuffix = "internal"
-				}
-				for _, mapping := range routeMappings {
-					if _, err := s.routeRepo.GetRoute(ctx, networkID, mapping.RouteID); err != nil {
-						// Skip if route not found
-						continue
-					}
-
-					// Format: <record-name>.<network-name>.<network-domain-suffix>
-					//
-					// Wildcard names ("*" or "*.sub") must keep the "*." prefix
-					// intact — sanitizeDNSLabel would corrupt it.  Only the
-					// non-wildcard portion of the suffix goes through sanitization.
-					var fqdn string
-					switch {
-					case mapping.Name == "*":
-						// bare wildcard → "*.network.suffix"
-						fqdn = fmt.Sprintf("*.%s.%s", sanitizeDNSLabel(net.Name), networkDomainSuffix)
-					case strings.HasPrefix(mapping.Name, "*."):
-						// e.g. "*.api" → "*.api.network.suffix"
-						// The suffix labels after "*." are already validated
-						// (alphanumeric + hyphens only); just lowercase them.
-						subPath := strings.ToLower(mapping.Name[2:])
-						fqdn = fmt.Sprintf("*.%s.%s.%s", subPath, sanitizeDNSLabel(net.Name), networkDomainSuffix)
-					default:
-						fqdn = fmt.Sprintf("%s.%s.%s", sanitizeDNSLabel(mapping.Name), sanitizeDNSLabel(net.Name), networkDomainSuffix)
-					}
-
-					// Place each address in the correct family slot.  DNSPeer
-					// has separate IP (IPv4) and IPv6 fields and the agent's
-					// DNS server returns them via lookupPeerAddresses(name)
-					// (ipv4, ipv6).  Since migration 027 a single mapping
-					// can carry BOTH families — when both are set, the agent
-					// returns the A record for IPv4 queries and the AAAA
-					// record for IPv6 queries on the same hostname.
-					peer := DNSPeer{
-						Name: fqdn,
-						IP:   mapping.IPAddress,   // empty when v4 not set
-						IPv6: mapping.IPv6Address, // empty when v6 not set
-					}
-					peerList = append(peerList, peer)
-				}
-			}
-		}
-
-		// Use network's custom domain suffix
-		domainSuffix := net.DomainSuffix
-		if domainSuffix == "" {
-			domainSuffix = "internal"
-		}
-
-		dnsConfig = &PeerDNSConfig{
-			IP:              peer.Address,
-			Domain:          fmt.Sprintf("%s.%s", net.Name, domainSuffix),
-			Peers:           peerList,
-			UpstreamServers: net.DNS, // Use network's configured DNS servers for forwarding
-		}
+		dnsConfig = s.buildPeerDNSConfig(ctx, net, peer)
 	} else {
 		// For non-jump peers using agent, send an empty policy to trigger firewall initialization
 		// This ensures firewall rules are applied even for non-jump peers
@@ -806,9 +1814,183 @@ func (s *Service) GeneratePeerConfigWithDNS(ctx context.Context, networkID, peer
 			}
 		}
 	}
+	metrics.ConfigGenerations.Inc()
 	return config, dnsConfig, policy, nil
 }
 
+// fillJumpFirewall sets the firewall rules of a jump peer's policy: the
+// iptables rules generated from the policies attached to groups, in the
+// format the agent's firewall backend understands.  The backend is set even
+// when rule generation fails.
+func (s *Service) fillJumpFirewall(ctx context.Context, networkID, peerID string, policy *JumpPolicy) error {
+	var err error
+	if s.policyService != nil {
+		var iptablesRules []string
+		if iptablesRules, err = s.policyService.GenerateIPTablesRules(ctx, networkID, peerID); err == nil {
+			policy.IPTablesRules = iptablesRules
+			policy.Rules = firewall.ParseRules(iptablesRules)
+		}
+	}
+
+	if session, serr := s.repo.GetSession(ctx, networkID, peerID); serr == nil && session != nil {
+		policy.FirewallBackend = session.FirewallBackend
+		if session.FirewallBackend == network.FirewallBackendNftables {
+			policy.NftRules = firewall.TranslateToNft(policy.IPTablesRules)
+		}
+	}
+	return err
+}
+
+// PreviewJumpFirewall returns the firewall rules of a jump peer exactly as
+// its agent receives them with its config, including the default deny
+// rules.  Unlike config generation, a rule generation failure is returned.
+func (s *Service) PreviewJumpFirewall(ctx context.Context, networkID, peerID string) (*JumpPolicy, error) {
+	peer, err := s.repo.GetPeer(ctx, networkID, peerID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", network.ErrPeerNotFound, peerID)
+	}
+	if !peer.IsJump {
+		return nil, network.ErrNotJumpPeer
+	}
+	if s.policyService == nil {
+		return nil, fmt.Errorf("policy service not configured")
+	}
+
+	policy := &JumpPolicy{IP: peer.Address}
+	if err := s.fillJumpFirewall(ctx, networkID, peerID, policy); err != nil {
+		return nil, fmt.Errorf("failed to generate iptables rules: %w", err)
+	}
+	return policy, nil
+}
+
+// buildPeerDNSConfig assembles the DNS server config served by a jump peer:
+// a record for every peer in the network plus the route DNS mappings.
+func (s *Service) buildPeerDNSConfig(ctx context.Context, net *network.Network, peer *network.Peer) *PeerDNSConfig {
+	peerList := make([]DNSPeer, 0, len(net.Peers))
+
+	// Add peer DNS records (include IPv6 when available for dual-stack networks)
+	for _, p := range net.Peers {
+		peerList = append(peerList, DNSPeer{Name: sanitizeDNSLabel(p.Name), IP: p.Address, IPv6: p.AddressV6})
+	}
+
+	// Add route DNS records
+	if s.dnsRepo != nil && s.routeRepo != nil {
+		routeMappings, err := s.dnsRepo.GetNetworkDNSMappings(ctx, net.ID)
+		if err == nil {
+			// Build FQDN from the *network's* name + domain suffix.  The
+			// route the mapping belongs to is still looked up so we can
+			// skip orphans, but it no longer influences the resolved name.
+			networkDomainSuffix := net.DomainSuffix
+			if networkDomainSuffix == "" {
+				networkDomainSuffix = "internal"
+			}
+			for _, mapping := range routeMappings {
+				if _, err := s.routeRepo.GetRoute(ctx, net.ID, mapping.RouteID); err != nil {
+					// Skip if route not found
+					continue
+				}
+
+				fqdn := dnsRecordFQDN(mapping.Name, net.Name, networkDomainSuffix)
+
+				// Place each address in the correct family slot.  DNSPeer
+				// has separate IP (IPv4) and IPv6 fields and the agent's
+				// DNS server returns them via lookupPeerAddresses(name)
+				// (ipv4, ipv6).  Since migration 027 a single mapping
+				// can carry BOTH families — when both are set, the agent
+				// returns the A record for IPv4 queries and the AAAA
+				// record for IPv6 queries on the same hostname.
+				record := DNSPeer{
+					Name: fqdn,
+					IP:   mapping.IPAddress,   // empty when v4 not set
+					IPv6: mapping.IPv6Address, // empty when v6 not set
+				}
+				peerList = append(peerList, record)
+			}
+		}
+	}
+
+	// Use network's custom domain suffix
+	domainSuffix := net.DomainSuffix
+	if domainSuffix == "" {
+		domainSuffix = "internal"
+	}
+
+	// Add network-scoped DNS records, which live in the same namespace
+	if s.dnsRepo != nil {
+		networkRecords, err := s.dnsRepo.ListNetworkDNSRecords(ctx, net.ID)
+		if err == nil {
+			for _, record := range networkRecords {
+				dnsPeer := DNSPeer{
+					Name: dnsRecordFQDN(record.Name, net.Name, domainSuffix),
+					IP:   record.IPAddress,
+					IPv6: record.IPv6Address,
+				}
+				if !record.IsAddress() {
+					dnsPeer.Type = record.RecordType()
+					dnsPeer.Value = record.Value
+				}
+				peerList = append(peerList, dnsPeer)
+			}
+		}
+	}
+
+	var searchDomains []string
+	if len(net.SearchDomains) > 0 {
+		searchDomains = net.SearchList()
+	}
+
+	return &PeerDNSConfig{
+		IP:              peer.Address,
+		Domain:          fmt.Sprintf("%s.%s", net.Name, domainSuffix),
+		Peers:           peerList,
+		UpstreamServers: net.DNS, // Use network's configured DNS servers for forwarding
+		SearchDomains:   searchDomains,
+
+		ConditionalForwarders: net.ConditionalForwarders,
+		QueryLog:              net.DNSQueryLog,
+	}
+}
+
+// dnsRecordFQDN returns the name a route mapping or network DNS record
+// resolves as: <record-name>.<network-name>.<network-domain-suffix>.
+//
+// Wildcard names ("*" or "*.sub") must keep the "*." prefix intact —
+// sanitizeDNSLabel would corrupt it.  Only the non-wildcard portion of the
+// suffix goes through sanitization.
+func dnsRecordFQDN(name, networkName, domainSuffix string) string {
+	switch {
+	case name == "*":
+		// bare wildcard → "*.network.suffix"
+		return fmt.Sprintf("*.%s.%s", sanitizeDNSLabel(networkName), domainSuffix)
+	case strings.HasPrefix(name, "*."):
+		// e.g. "*.api" → "*.api.network.suffix"
+		// The suffix labels after "*." are already validated
+		// (alphanumeric + hyphens only); just lowercase them.
+		subPath := strings.ToLower(name[2:])
+		return fmt.Sprintf("*.%s.%s.%s", subPath, sanitizeDNSLabel(networkName), domainSuffix)
+	default:
+		return fmt.Sprintf("%s.%s.%s", sanitizeDNSLabel(name), sanitizeDNSLabel(networkName), domainSuffix)
+	}
+}
+
+// GeneratePeerDNSConfig returns only the DNS config of a jump peer, without
+// generating its WireGuard config or policy.  It is used to push DNS record
+// changes to jump agents without touching the rest of the network.
+func (s *Service) GeneratePeerDNSConfig(ctx context.Context, networkID, peerID string) (*PeerDNSConfig, error) {
+	net, err := s.repo.GetNetwork(ctx, networkID)
+	if err != nil {
+		return nil, fmt.Errorf("network not found: %w", err)
+	}
+	peer, exists := net.GetPeer(peerID)
+	if !exists {
+		return nil, fmt.Errorf("peer not found")
+	}
+	if !peer.IsJump {
+		return nil, fmt.Errorf("peer %s is not a jump peer", peerID)
+	}
+	return s.buildPeerDNSConfig(ctx, net, peer), nil
+}
+
 // UpdateACL is deprecated - use policy-based access control instead
 // Kept for backward compatibility during migration
 func (s *Service) UpdateACL(ctx context.Context, networkID string, acl interface{}) error {
@@ -821,38 +2003,16 @@ func (s *Service) GetACL(ctx context.Context, networkID string) (interface{}, er
 	return nil, fmt.Errorf("ACL system has been removed - use policy-based access control instead")
 }
 
-// DeleteNetwork deletes a network and releases its CIDR from IPAM
+// DeleteNetwork soft-deletes a network: it is no longer served but keeps
+// its peers and its CIDR in IPAM, so RestoreNetwork can bring it back until
+// PurgeDeletedNetworks removes it after the retention window.
 func (s *Service) DeleteNetwork(ctx context.Context, networkID string) error {
-	// Get the network to retrieve its CIDR before deletion
-	net, err := s.repo.GetNetwork(ctx, networkID)
-	if err != nil {
-		return fmt.Errorf("failed to get network for deletion: %w", err)
-	}
-
-	// Delete the network first
-	if err := s.repo.DeleteNetwork(ctx, networkID); err != nil {
+	if err := s.repo.SoftDeleteNetwork(ctx, networkID, s.clock()); err != nil {
 		return fmt.Errorf("failed to delete network: %w", err)
 	}
+	s.InvalidateConfigs(networkID)
 
-	// Release CIDR(s) from IPAM to allow reuse.
-	if net.CIDR != "" {
-		if err := s.repo.DeletePrefix(ctx, net.CIDR); err != nil {
-			log.Warn().Err(err).Str("network_id", networkID).Str("cidr", net.CIDR).
-				Msg("Failed to release IPv4 CIDR from IPAM after network deletion")
-		} else {
-			log.Info().Str("network_id", networkID).Str("cidr", net.CIDR).
-				Msg("Successfully released IPv4 CIDR from IPAM after network deletion")
-		}
-	}
-	if net.CIDRv6 != "" {
-		if err := s.repo.DeletePrefix(ctx, net.CIDRv6); err != nil {
-			log.Warn().Err(err).Str("network_id", networkID).Str("cidr_v6", net.CIDRv6).
-				Msg("Failed to release IPv6 CIDR from IPAM after network deletion")
-		} else {
-			log.Info().Str("network_id", networkID).Str("cidr_v6", net.CIDRv6).
-				Msg("Successfully released IPv6 CIDR from IPAM after network deletion")
-		}
-	}
+	audit.Record(ctx, s.auditLogger, "network.delete", networkID, "", "")
 
 	return nil
 }
@@ -865,7 +2025,11 @@ func (s *Service) DeleteNetwork(ctx context.Context, networkID string) error {
 // suspicious activity) was removed in v2 — the captive portal now performs an
 // endpoint check on every authenticated connection, which provides a stronger
 // guarantee than after-the-fact heartbeat analysis.
-func (s *Service) ProcessAgentHeartbeat(ctx context.Context, networkID, peerID string, heartbeat *network.AgentHeartbeat) error {
+func (s *Service) ProcessAgentHeartbeat(ctx context.Context, networkID, peerID string, heartbeat *network.AgentHeartbeat) (err error) {
+	ctx, span := tracing.Start(ctx, "network.ProcessAgentHeartbeat")
+	span.SetAttributes(attribute.String("network.id", networkID), attribute.String("peer.id", peerID))
+	defer func() { tracing.RecordError(span, err); span.End() }()
+
 	now := time.Now()
 
 	// Preserve FirstSeen / SessionID across heartbeats so the session is treated
@@ -878,19 +2042,37 @@ func (s *Service) ProcessAgentHeartbeat(ctx context.Context, networkID, peerID s
 		SystemUptime:    heartbeat.SystemUptime,
 		WireGuardUptime: heartbeat.WireGuardUptime,
 		LastSeen:        now,
+		FirewallBackend: heartbeat.FirewallBackend,
 	}
 	if existing != nil {
 		session.FirstSeen = existing.FirstSeen
 		session.SessionID = existing.SessionID
 		session.ReportedEndpoint = existing.ReportedEndpoint
+		session.JumpHealth = existing.JumpHealth
+		session.LastAppliedConfigHash = existing.LastAppliedConfigHash
+		session.LastAppliedAt = existing.LastAppliedAt
 	} else {
 		session.FirstSeen = now
 		session.SessionID = uuid.NewString()
 	}
+	session.PeerTransfer = recordPeerTransfer(existing, heartbeat, now)
+	session.PeerHandshakes = recordPeerHandshakes(existing, heartbeat)
+	session.PeerLatency = recordPeerLatency(existing, heartbeat, now)
+	if len(heartbeat.DNSQueries) > 0 || (existing != nil && len(existing.DNSQueries) > 0) {
+		// Queries are only kept while the network logs them, so turning
+		// logging off also drops those already stored.
+		if nw, err := s.repo.GetNetwork(ctx, networkID); err == nil && nw.DNSQueryLog {
+			session.DNSQueries = recordDNSQueries(existing, heartbeat)
+		}
+	}
 
 	if err := s.repo.CreateOrUpdateSession(ctx, networkID, session); err != nil {
 		return fmt.Errorf("failed to update session: %w", err)
 	}
+	// The jump policy is rendered for the reported firewall backend
+	if existing == nil || existing.FirewallBackend != session.FirewallBackend {
+		s.InvalidateConfigs(networkID)
+	}
 
 	// Persist this peer's locally-configured AllowedIPs so the jump peer's DNS
 	// server can decide route-aware whether to redirect external queries when
@@ -1093,9 +2275,102 @@ func (s *Service) GetPeerConnectivityStatus(ctx context.Context, networkID, peer
 	// 4. Captive portal auth state.
 	status.CaptivePortalState = s.getPeerCaptivePortalState(ctx, networkID, peerID)
 
+	// 5. Handshake status.
+	handshakes, err := s.peerLastHandshakes(ctx, networkID)
+	if err != nil {
+		return nil, err
+	}
+	last := handshakes[peerID]
+	if !last.IsZero() {
+		status.LastHandshake = &last
+	}
+	status.Status = network.PeerStatusAt(last, now, s.staleThreshold())
+
+	// 6. Round-trip time measured by the jump agents.
+	if peer, err := s.repo.GetPeer(ctx, networkID, peerID); err == nil {
+		status.LatencyMs = s.peerLatency(ctx, networkID, peer, now)
+
+		// 7. Jump health reported by the agent itself.
+		if peer.IsJump {
+			status.JumpHealthy, status.JumpHealthReason = jumpHealthStatus(status, now)
+		}
+	}
+
+	// 8. Convergence: whether the agent acknowledged the current config.
+	if session := status.CurrentSession; session != nil && session.LastAppliedConfigHash != "" {
+		if cfg, _, _, err := s.GeneratePeerConfigWithDNS(ctx, networkID, peerID); err == nil {
+			inSync := session.LastAppliedConfigHash == wireguard.ConfigHash(cfg)
+			status.ConfigInSync = &inSync
+		}
+	}
+
 	return status, nil
 }
 
+// JumpHealthCheckInterval is how often the server asks connected jump agents
+// to check their listen port and internet access.
+const JumpHealthCheckInterval = time.Minute
+
+// jumpHealthMaxAge is how old the latest jump health check may be before the
+// jump is no longer considered healthy: three missed checks.
+const jumpHealthMaxAge = 3 * JumpHealthCheckInterval
+
+// jumpHealthStatus derives JumpHealthy and its reason from the connectivity
+// status of a jump peer.
+func jumpHealthStatus(status *network.PeerConnectivityStatus, now time.Time) (bool, string) {
+	if !status.HasActiveAgent {
+		return false, "agent not connected"
+	}
+	if status.CurrentSession == nil || status.CurrentSession.JumpHealth == nil {
+		return false, "no health check result yet"
+	}
+	health := status.CurrentSession.JumpHealth
+	if now.Sub(health.CheckedAt) > jumpHealthMaxAge {
+		return false, fmt.Sprintf("last health check is stale (%s ago)", now.Sub(health.CheckedAt).Round(time.Second))
+	}
+	if !health.Healthy() {
+		return false, health.Reason
+	}
+	return true, ""
+}
+
+// RecordJumpHealth stores the result of a health check reported by a jump
+// agent on its current session.
+func (s *Service) RecordJumpHealth(ctx context.Context, networkID, peerID string, report *network.JumpHealthReport) error {
+	session, err := s.repo.GetSession(ctx, networkID, peerID)
+	if err != nil || session == nil {
+		return fmt.Errorf("no agent session for peer %s", peerID)
+	}
+	session.JumpHealth = &network.JumpHealth{
+		ListenPortOK: report.ListenPortOK,
+		InternetOK:   report.InternetOK,
+		Reason:       report.Reason,
+		CheckedAt:    time.Now(),
+	}
+	if err := s.repo.CreateOrUpdateSession(ctx, networkID, session); err != nil {
+		return fmt.Errorf("failed to update session: %w", err)
+	}
+	return nil
+}
+
+// RecordConfigAck stores on the agent's current session the hash of the
+// config the agent reported it applied.  The ack of the initial config can
+// arrive before the first heartbeat of a new agent, so the session is
+// started when there is none yet.
+func (s *Service) RecordConfigAck(ctx context.Context, networkID, peerID, configHash string) error {
+	now := time.Now()
+	session, err := s.repo.GetSession(ctx, networkID, peerID)
+	if err != nil || session == nil {
+		session = &network.AgentSession{PeerID: peerID, FirstSeen: now, SessionID: uuid.NewString()}
+	}
+	session.LastAppliedConfigHash = configHash
+	session.LastAppliedAt = &now
+	if err := s.repo.CreateOrUpdateSession(ctx, networkID, session); err != nil {
+		return fmt.Errorf("failed to update session: %w", err)
+	}
+	return nil
+}
+
 // getPeerCaptivePortalState returns the captive-portal authentication state for
 // a given peer.  Priority: quarantined > authenticated > pending_auth > "".
 func (s *Service) getPeerCaptivePortalState(ctx context.Context, networkID, peerID string) string {
@@ -1160,6 +2435,55 @@ func (s *Service) ListSessions(ctx context.Context, networkID string) ([]*networ
 	return s.repo.ListSessions(ctx, networkID)
 }
 
+// ListSessionHistory returns every session recorded for a peer, including
+// revoked ones, most recent first.
+func (s *Service) ListSessionHistory(ctx context.Context, networkID, peerID string) ([]*network.AgentSession, error) {
+	if _, err := s.repo.GetPeer(ctx, networkID, peerID); err != nil {
+		return nil, fmt.Errorf("peer not found: %w", err)
+	}
+	sessions, err := s.repo.ListSessionHistory(ctx, networkID, peerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list session history: %w", err)
+	}
+	sort.SliceStable(sessions, func(i, j int) bool {
+		return sessions[i].FirstSeen.After(sessions[j].FirstSeen)
+	})
+	return sessions, nil
+}
+
+// RevokeSession invalidates one of a peer's sessions.  The session is kept in
+// the history for forensics but is no longer treated as the peer's current
+// session: the agent's next heartbeat starts a new one.  Revoking an already
+// revoked session is a no-op.
+func (s *Service) RevokeSession(ctx context.Context, networkID, peerID, sessionID string) (*network.AgentSession, error) {
+	sessions, err := s.ListSessionHistory(ctx, networkID, peerID)
+	if err != nil {
+		return nil, err
+	}
+	var session *network.AgentSession
+	for _, candidate := range sessions {
+		if candidate.SessionID == sessionID {
+			session = candidate
+			break
+		}
+	}
+	if session == nil {
+		return nil, network.ErrSessionNotFound
+	}
+	if session.IsRevoked() {
+		return session, nil
+	}
+
+	now := s.clock()
+	if err := s.repo.RevokeSession(ctx, networkID, sessionID, now); err != nil {
+		return nil, fmt.Errorf("failed to revoke session: %w", err)
+	}
+	session.RevokedAt = &now
+	audit.Record(ctx, s.auditLogger, "peer.session_revoke", networkID, peerID, sessionID)
+
+	return session, nil
+}
+
 // CreateCaptivePortalToken creates a short-lived token for the captive portal flow.
 // Called by the jump peer agent when a new peer connects and needs authentication.
 // peerEndpoint is the peer's current full public endpoint ("ip:port", strict);
@@ -1511,11 +2835,29 @@ func (s *Service) GetCaptivePortalWhitelist(ctx context.Context, networkID, jump
 // physical interface — preventing the rogue source from completing further
 // WireGuard handshakes and stealing the peer slot back.
 func (s *Service) processEndpointTakeovers(ctx context.Context, networkID, jumpPeerID string, takeovers []network.EndpointTakeoverReport) error {
+	maintenanceUntil := s.maintenanceUntil(ctx, networkID)
 	for _, t := range takeovers {
 		blockedIP, blockedPort := splitEndpoint(t.ObservedAt)
 		if blockedIP == "" {
 			continue
 		}
+		if maintenanceUntil != nil {
+			s.raiseIncident(&network.SecurityIncident{
+				IncidentType: metrics.IncidentEndpointTakeover,
+				NetworkID:    networkID,
+				JumpPeerID:   jumpPeerID,
+				WgIP:         t.WgIP,
+				Endpoints:    []string{t.AuthenticatedAt, t.ObservedAt},
+				Details:      fmt.Sprintf("endpoint change not denylisted: network in maintenance until %s", maintenanceUntil.Format(time.RFC3339)),
+			})
+			log.Warn().
+				Str("network_id", networkID).
+				Str("wg_ip", t.WgIP).
+				Str("observed_at", t.ObservedAt).
+				Time("maintenance_until", *maintenanceUntil).
+				Msg("captive portal: endpoint takeover not blocked, network in maintenance")
+			continue
+		}
 		entry := &network.EndpointDenylistEntry{
 			NetworkID:   networkID,
 			JumpPeerID:  jumpPeerID,
@@ -1531,6 +2873,14 @@ func (s *Service) processEndpointTakeovers(ctx context.Context, networkID, jumpP
 				Msg("failed to add endpoint denylist entry")
 			continue
 		}
+		s.raiseIncident(&network.SecurityIncident{
+			IncidentType: metrics.IncidentEndpointTakeover,
+			NetworkID:    networkID,
+			JumpPeerID:   jumpPeerID,
+			WgIP:         t.WgIP,
+			Endpoints:    []string{t.AuthenticatedAt, t.ObservedAt},
+			Details:      "rogue WireGuard source denylisted: " + entry.Reason,
+		})
 		log.Warn().
 			Str("network_id", networkID).
 			Str("jump_peer_id", jumpPeerID).
@@ -1546,6 +2896,27 @@ func (s *Service) processEndpointTakeovers(ctx context.Context, networkID, jumpP
 	return nil
 }
 
+// maintenanceUntil returns the end of the network's maintenance window, or
+// nil when it is not in one.  Security detections block nobody until then.
+func (s *Service) maintenanceUntil(ctx context.Context, networkID string) *time.Time {
+	net, err := s.repo.GetNetwork(ctx, networkID)
+	if err != nil || !net.InMaintenance(s.clock()) {
+		return nil
+	}
+	return net.MaintenanceUntil
+}
+
+// raiseIncident counts a security incident and forwards it to the incident
+// notifier, if any.
+func (s *Service) raiseIncident(incident *network.SecurityIncident) {
+	incident.ID = uuid.New().String()
+	incident.DetectedAt = time.Now()
+	metrics.SecurityIncidents.WithLabelValues(incident.IncidentType).Inc()
+	if s.incidentNotifier != nil {
+		s.incidentNotifier.NotifyIncident(incident)
+	}
+}
+
 // splitEndpoint parses "ip:port" into (ip, port).  Returns ("", 0) on parse
 // failure.  Handles IPv6 brackets ("[::1]:51820") as well as bare IPv4.
 func splitEndpoint(ep string) (string, int) {
@@ -1578,9 +2949,31 @@ func (s *Service) RecordCaptivePortalAuthFailure(ctx context.Context, networkID,
 	}
 	q.Strikes++
 	q.LastStrikeAt = &now
-	if q.Strikes >= network.QuarantineStrikeThreshold {
+	// Strikes keep counting during maintenance, so the first failure after
+	// the window quarantines a peer that crossed the threshold in it.
+	maintenanceUntil := s.maintenanceUntil(ctx, networkID)
+	if q.Strikes >= network.QuarantineStrikeThreshold && maintenanceUntil != nil {
+		s.raiseIncident(&network.SecurityIncident{
+			IncidentType: metrics.IncidentQuarantine,
+			NetworkID:    networkID,
+			PeerID:       peerID,
+			Details:      fmt.Sprintf("quarantine suppressed after %d captive portal auth failures: network in maintenance until %s", q.Strikes, maintenanceUntil.Format(time.RFC3339)),
+		})
+		log.Warn().
+			Str("network_id", networkID).
+			Str("peer_id", peerID).
+			Int("strikes", q.Strikes).
+			Time("maintenance_until", *maintenanceUntil).
+			Msg("captive portal: quarantine suppressed, network in maintenance")
+	} else if q.Strikes >= network.QuarantineStrikeThreshold {
 		until := now.Add(network.QuarantineDuration)
 		q.QuarantinedUntil = &until
+		s.raiseIncident(&network.SecurityIncident{
+			IncidentType: metrics.IncidentQuarantine,
+			NetworkID:    networkID,
+			PeerID:       peerID,
+			Details:      fmt.Sprintf("peer quarantined until %s after %d captive portal auth failures", until.Format(time.RFC3339), q.Strikes),
+		})
 		log.Warn().
 			Str("network_id", networkID).
 			Str("peer_id", peerID).
@@ -1611,6 +3004,52 @@ func (s *Service) ResetCaptivePortalStrikes(ctx context.Context, networkID, peer
 	return s.repo.ClearQuarantine(ctx, networkID, peerID)
 }
 
+// QuarantinePeer quarantines a peer on an administrator's request, as if it
+// had hit the captive-portal strike threshold: the jump peers drop its
+// traffic until d has passed or UnquarantinePeer lifts it.
+func (s *Service) QuarantinePeer(ctx context.Context, networkID, peerID string, d time.Duration) (*network.CaptivePortalQuarantine, error) {
+	if _, err := s.repo.GetPeer(ctx, networkID, peerID); err != nil {
+		return nil, fmt.Errorf("get peer: %w", err)
+	}
+	q, err := s.repo.GetQuarantine(ctx, networkID, peerID)
+	if err != nil {
+		return nil, fmt.Errorf("get quarantine: %w", err)
+	}
+	if q == nil {
+		q = &network.CaptivePortalQuarantine{NetworkID: networkID, PeerID: peerID}
+	}
+	until := s.clock().Add(d)
+	q.QuarantinedUntil = &until
+	if err := s.repo.UpsertQuarantine(ctx, q); err != nil {
+		return nil, fmt.Errorf("quarantine peer: %w", err)
+	}
+
+	log.Warn().Str("network_id", networkID).Str("peer_id", peerID).Time("until", until).Msg("peer quarantined by admin")
+	if s.wsNotifier != nil {
+		s.wsNotifier.NotifyNetworkPeers(networkID)
+	}
+	audit.Record(ctx, s.auditLogger, "peer.quarantine", networkID, peerID, "")
+	return q, nil
+}
+
+// UnquarantinePeer lifts a peer's quarantine, manual or automatic, and
+// clears its captive-portal strikes.
+func (s *Service) UnquarantinePeer(ctx context.Context, networkID, peerID string) error {
+	if _, err := s.repo.GetPeer(ctx, networkID, peerID); err != nil {
+		return fmt.Errorf("get peer: %w", err)
+	}
+	if err := s.repo.ClearQuarantine(ctx, networkID, peerID); err != nil {
+		return fmt.Errorf("clear quarantine: %w", err)
+	}
+
+	log.Info().Str("network_id", networkID).Str("peer_id", peerID).Msg("peer quarantine lifted by admin")
+	if s.wsNotifier != nil {
+		s.wsNotifier.NotifyNetworkPeers(networkID)
+	}
+	audit.Record(ctx, s.auditLogger, "peer.unquarantine", networkID, peerID, "")
+	return nil
+}
+
 // CaptivePortalSecurityState aggregates everything the jump peer needs to know
 // to enforce the three-tier authentication gate: who is authenticated, who has
 // an in-flight token (pending auth), who is rogue (denylisted), who is
@@ -1702,24 +3141,28 @@ func (s *Service) GetCaptivePortalSecurityState(ctx context.Context, networkID,
 	}
 
 	// 4. Quarantined peers — block all access including captive portal redirect.
+	// Expired peers are quarantined the same way until their expiry is
+	// extended or cleared.
 	qList, err := s.repo.ListQuarantinedPeers(ctx, networkID)
 	if err != nil {
 		return nil, fmt.Errorf("list quarantined: %w", err)
 	}
-	if len(qList) > 0 {
-		// Translate peer IDs to wgIPs.
-		peers, err := s.repo.ListPeers(ctx, networkID)
-		if err == nil {
-			peerByID := make(map[string]*network.Peer, len(peers))
-			for _, p := range peers {
-				peerByID[p.ID] = p
+	// Translate peer IDs to wgIPs.
+	if peers, err := s.repo.ListPeers(ctx, networkID); err == nil {
+		quarantined := make(map[string]bool, len(qList))
+		for _, q := range qList {
+			quarantined[q.PeerID] = true
+		}
+		now := s.clock()
+		for _, p := range peers {
+			if !quarantined[p.ID] && !p.IsExpired(now) {
+				continue
 			}
-			for _, q := range qList {
-				p, ok := peerByID[q.PeerID]
-				if !ok {
+			// Both families, or a dual-stack peer keeps its IPv6 access
+			for _, addr := range []string{p.Address, p.AddressV6} {
+				if addr == "" {
 					continue
 				}
-				addr := p.Address
 				if idx := indexByte(addr, '/'); idx != -1 {
 					addr = addr[:idx]
 				}
@@ -1897,6 +3340,8 @@ func (s *Service) RevokePeerAuthentication(ctx context.Context, networkID, peerI
 	if s.wsNotifier != nil {
 		s.wsNotifier.NotifyNetworkPeers(networkID)
 	}
+	audit.Record(ctx, s.auditLogger, "peer.auth_revoke", networkID, peerID, "")
+
 	return nil
 }
 
@@ -1936,6 +3381,106 @@ func (s *Service) CleanupWhitelistForDisconnectedPeers(ctx context.Context, netw
 	return nil
 }
 
+// normalizeConditionalForwarders validates conditional forwarders and
+// returns them with lowercase domains stripped of their trailing dot.  Each
+// resolver is an IP address, optionally with a port.
+func normalizeConditionalForwarders(forwarders map[string][]string) (map[string][]string, error) {
+	if forwarders == nil {
+		return nil, nil
+	}
+	out := make(map[string][]string, len(forwarders))
+	for domain, servers := range forwarders {
+		name := strings.ToLower(strings.TrimSuffix(strings.TrimSpace(domain), "."))
+		if err := validation.ValidateDNSHostname(name); err != nil {
+			return nil, fmt.Errorf("%w: domain %q: %v", network.ErrInvalidConditionalForwarder, domain, err)
+		}
+		if _, dup := out[name]; dup {
+			return nil, fmt.Errorf("%w: domain %q is listed twice", network.ErrInvalidConditionalForwarder, name)
+		}
+		if len(servers) == 0 {
+			return nil, fmt.Errorf("%w: domain %q has no resolvers", network.ErrInvalidConditionalForwarder, name)
+		}
+		for _, server := range servers {
+			if _, err := netip.ParseAddr(server); err == nil {
+				continue
+			}
+			if _, err := netip.ParseAddrPort(server); err != nil {
+				return nil, fmt.Errorf("%w: resolver %q of %q", network.ErrInvalidConditionalForwarder, server, name)
+			}
+		}
+		out[name] = servers
+	}
+	return out, nil
+}
+
+// normalizeSearchDomains validates DNS search domains and returns them
+// lowercase, without their trailing dot, once each, in the given order.
+func normalizeSearchDomains(domains []string) ([]string, error) {
+	if domains == nil {
+		return nil, nil
+	}
+	out := make([]string, 0, len(domains))
+	for _, domain := range domains {
+		name := strings.ToLower(strings.TrimSuffix(strings.TrimSpace(domain), "."))
+		if err := validation.ValidateDNSHostname(name); err != nil {
+			return nil, fmt.Errorf("invalid search domain %q: %w", domain, err)
+		}
+		if !slices.Contains(out, name) {
+			out = append(out, name)
+		}
+	}
+	return out, nil
+}
+
+// validateNetworkCreateRequest checks the name, domain suffix, naming
+// pattern, topology, labels and CIDRs of a network creation request.
+func validateNetworkCreateRequest(req *network.NetworkCreateRequest) error {
+	// Validate network name follows DNS hostname convention (dots allowed for subdomains)
+	if err := validation.ValidateDNSHostname(req.Name); err != nil {
+		return fmt.Errorf("invalid network name: %w", err)
+	}
+
+	// Validate domain suffix (dots allowed, e.g. "corp.example.com")
+	if req.DomainSuffix != "" {
+		if err := validation.ValidateDNSHostname(req.DomainSuffix); err != nil {
+			return fmt.Errorf("invalid domain suffix: %w", err)
+		}
+	}
+
+	if req.PeerNamePattern != "" {
+		if _, err := validation.CompilePeerNamePattern(req.PeerNamePattern); err != nil {
+			return err
+		}
+	}
+
+	if req.Topology != "" && req.Topology != network.TopologyMesh && req.Topology != network.TopologyHub {
+		return fmt.Errorf("invalid topology %q: must be %q or %q", req.Topology, network.TopologyMesh, network.TopologyHub)
+	}
+
+	if err := network.ValidateLabels(req.Labels); err != nil {
+		return err
+	}
+
+	if err := wireguard.ValidateJumpHooks(req.JumpPostUp, req.JumpPostDown, req.JumpNATInterface); err != nil {
+		return err
+	}
+
+	if req.CIDR == "" && req.CIDRv6 == "" {
+		return fmt.Errorf("at least one of cidr (IPv4) or cidr_v6 (IPv6) must be provided")
+	}
+	if req.CIDR != "" {
+		if err := validateNetworkCIDR(req.CIDR); err != nil {
+			return fmt.Errorf("invalid cidr: %w", err)
+		}
+	}
+	if req.CIDRv6 != "" {
+		if err := validateNetworkCIDR(req.CIDRv6); err != nil {
+			return fmt.Errorf("invalid cidr_v6: %w", err)
+		}
+	}
+	return nil
+}
+
 // validateNetworkCIDR verifies that cidr is syntactically valid AND that the IP
 // address is the actual network address for the given prefix (host bits are zero).
 // For example, "10.255.238.0/22" is rejected because the network address is
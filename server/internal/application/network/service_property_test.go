@@ -13,6 +13,7 @@ import (
 	"github.com/leanovate/gopter"
 	"github.com/leanovate/gopter/gen"
 	"github.com/leanovate/gopter/prop"
+	goipam "github.com/metal-stack/go-ipam"
 )
 
 // Mock implementations for testing
@@ -172,11 +173,13 @@ func (m *mockRouteRepository) GetRoutesByJumpPeer(ctx context.Context, networkID
 
 type mockDNSRepository struct {
 	mappings map[string]*network.DNSMapping
+	records  map[string]*network.NetworkDNSRecord
 }
 
 func newMockDNSRepository() *mockDNSRepository {
 	return &mockDNSRepository{
 		mappings: make(map[string]*network.DNSMapping),
+		records:  make(map[string]*network.NetworkDNSRecord),
 	}
 }
 
@@ -221,6 +224,50 @@ func (m *mockDNSRepository) GetNetworkDNSMappings(ctx context.Context, networkID
 	return mappings, nil
 }
 
+func (m *mockDNSRepository) CreateNetworkDNSRecord(ctx context.Context, record *network.NetworkDNSRecord) error {
+	for _, existing := range m.records {
+		if existing.NetworkID == record.NetworkID && existing.Name == record.Name {
+			return network.ErrDuplicateNetworkDNSName
+		}
+	}
+	m.records[record.ID] = record
+	return nil
+}
+
+func (m *mockDNSRepository) GetNetworkDNSRecord(ctx context.Context, networkID, recordID string) (*network.NetworkDNSRecord, error) {
+	record, ok := m.records[recordID]
+	if !ok || record.NetworkID != networkID {
+		return nil, network.ErrNetworkDNSRecordNotFound
+	}
+	return record, nil
+}
+
+func (m *mockDNSRepository) UpdateNetworkDNSRecord(ctx context.Context, record *network.NetworkDNSRecord) error {
+	if _, ok := m.records[record.ID]; !ok {
+		return network.ErrNetworkDNSRecordNotFound
+	}
+	m.records[record.ID] = record
+	return nil
+}
+
+func (m *mockDNSRepository) DeleteNetworkDNSRecord(ctx context.Context, networkID, recordID string) error {
+	if _, ok := m.records[recordID]; !ok {
+		return network.ErrNetworkDNSRecordNotFound
+	}
+	delete(m.records, recordID)
+	return nil
+}
+
+func (m *mockDNSRepository) ListNetworkDNSRecords(ctx context.Context, networkID string) ([]*network.NetworkDNSRecord, error) {
+	var records []*network.NetworkDNSRecord
+	for _, record := range m.records {
+		if record.NetworkID == networkID {
+			records = append(records, record)
+		}
+	}
+	return records, nil
+}
+
 func (m *mockGroupRepository) CreateGroup(ctx context.Context, networkID string, group *network.Group) error {
 	m.groups[group.ID] = group
 	m.groupPeers[group.ID] = []string{}
@@ -319,22 +366,29 @@ func (m *mockGroupRepository) ReorderGroupPolicies(ctx context.Context, networkI
 
 // Minimal mock for FullRepository - only implementing methods needed for AddPeer
 type mockFullRepository struct {
-	networks map[string]*network.Network
-	peers    map[string]*network.Peer
-	ipam     *mockIPAMRepository
+	networks    map[string]*network.Network
+	peers       map[string]*network.Peer
+	connections []*network.PeerConnection
+	sessions    map[string]*network.AgentSession // keyed by session ID
+	tempRoutes  map[string]*network.TempRoute
+	profiles    map[string]*network.PeerProfile
+	ipam        *mockIPAMRepository
 }
 
 func newMockFullRepository() *mockFullRepository {
 	return &mockFullRepository{
-		networks: make(map[string]*network.Network),
-		peers:    make(map[string]*network.Peer),
-		ipam:     newMockIPAMRepository(),
+		networks:   make(map[string]*network.Network),
+		peers:      make(map[string]*network.Peer),
+		sessions:   make(map[string]*network.AgentSession),
+		tempRoutes: make(map[string]*network.TempRoute),
+		profiles:   make(map[string]*network.PeerProfile),
+		ipam:       newMockIPAMRepository(),
 	}
 }
 
 func (m *mockFullRepository) GetNetwork(ctx context.Context, networkID string) (*network.Network, error) {
 	net, exists := m.networks[networkID]
-	if !exists {
+	if !exists || net.DeletedAt != nil {
 		return nil, network.ErrNetworkNotFound
 	}
 	return net, nil
@@ -369,6 +423,30 @@ func (m *mockFullRepository) ReleaseIP(ctx context.Context, cidr, ip string) err
 	return m.ipam.ReleaseIP(ctx, cidr, ip)
 }
 
+func (m *mockFullRepository) AcquireSpecificIP(ctx context.Context, cidr, ip string) error {
+	return m.ipam.AcquireSpecificIP(ctx, cidr, ip)
+}
+
+func (m *mockFullRepository) ReserveIP(ctx context.Context, cidr, ip string) error {
+	return m.ipam.ReserveIP(ctx, cidr, ip)
+}
+
+func (m *mockFullRepository) ReleaseReservation(ctx context.Context, cidr, ip string) error {
+	return m.ipam.ReleaseReservation(ctx, cidr, ip)
+}
+
+func (m *mockFullRepository) ListReservations(ctx context.Context, cidr string) ([]string, error) {
+	return m.ipam.ListReservations(ctx, cidr)
+}
+
+func (m *mockFullRepository) AcquiredIPs(ctx context.Context, cidr string) (uint64, error) {
+	return m.ipam.AcquiredIPs(ctx, cidr)
+}
+
+func (m *mockFullRepository) ListAllocatedIPs(ctx context.Context, cidr string) ([]network.IPAllocation, error) {
+	return m.ipam.ListAllocatedIPs(ctx, cidr)
+}
+
 func (m *mockFullRepository) EnsureRootPrefix(ctx context.Context, cidr string) (*network.IPAMPrefix, error) {
 	return &network.IPAMPrefix{CIDR: cidr}, nil
 }
@@ -386,7 +464,7 @@ func (m *mockFullRepository) ReleaseChildPrefix(ctx context.Context, cidr string
 }
 
 func (m *mockFullRepository) DeletePrefix(ctx context.Context, cidr string) error {
-	return nil
+	return m.ipam.DeletePrefix(ctx, cidr)
 }
 
 func (m *mockFullRepository) ListChildPrefixes(ctx context.Context, parentCIDR string) ([]*network.IPAMPrefix, error) {
@@ -394,6 +472,7 @@ func (m *mockFullRepository) ListChildPrefixes(ctx context.Context, parentCIDR s
 }
 
 func (m *mockFullRepository) CreateConnection(ctx context.Context, networkID string, conn *network.PeerConnection) error {
+	m.connections = append(m.connections, conn)
 	return nil
 }
 
@@ -405,13 +484,62 @@ func (m *mockFullRepository) UpdateNetwork(ctx context.Context, net *network.Net
 	return nil
 }
 func (m *mockFullRepository) DeleteNetwork(ctx context.Context, networkID string) error {
+	delete(m.networks, networkID)
 	return nil
 }
 func (m *mockFullRepository) ListNetworks(ctx context.Context) ([]*network.Network, error) {
-	return nil, nil
+	var networks []*network.Network
+	for _, n := range m.networks {
+		if n.DeletedAt == nil {
+			networks = append(networks, n)
+		}
+	}
+	return networks, nil
+}
+func (m *mockFullRepository) SoftDeleteNetwork(ctx context.Context, networkID string, at time.Time) error {
+	net, exists := m.networks[networkID]
+	if !exists || net.DeletedAt != nil {
+		return network.ErrNetworkNotFound
+	}
+	net.DeletedAt = &at
+	return nil
+}
+func (m *mockFullRepository) RestoreNetwork(ctx context.Context, networkID string) error {
+	net, exists := m.networks[networkID]
+	if !exists {
+		return network.ErrNetworkNotFound
+	}
+	if net.DeletedAt == nil {
+		return network.ErrNetworkNotDeleted
+	}
+	net.DeletedAt = nil
+	return nil
+}
+func (m *mockFullRepository) ListDeletedNetworks(ctx context.Context) ([]*network.Network, error) {
+	var networks []*network.Network
+	for _, n := range m.networks {
+		if n.DeletedAt != nil {
+			networks = append(networks, n)
+		}
+	}
+	return networks, nil
+}
+func (m *mockFullRepository) SearchPeers(ctx context.Context, query string, offset, limit int) ([]*network.PeerSearchResult, int, error) {
+	var results []*network.PeerSearchResult
+	for _, peer := range m.peers {
+		if peer.MatchesSearch(query) {
+			results = append(results, &network.PeerSearchResult{NetworkID: "net-1", Peer: peer})
+		}
+	}
+	return results, len(results), nil
 }
 func (m *mockFullRepository) GetPeerByToken(ctx context.Context, token string) (string, *network.Peer, error) {
-	return "", nil, nil
+	for _, peer := range m.peers {
+		if peer.Token == token {
+			return "net-1", peer, nil
+		}
+	}
+	return "", nil, fmt.Errorf("token not found")
 }
 func (m *mockFullRepository) UpdatePeer(ctx context.Context, networkID string, peer *network.Peer) error {
 	return nil
@@ -429,19 +557,46 @@ func (m *mockFullRepository) UpdateACL(ctx context.Context, networkID string, ac
 	return nil
 }
 func (m *mockFullRepository) GetConnection(ctx context.Context, networkID, peer1ID, peer2ID string) (*network.PeerConnection, error) {
-	return nil, nil
+	for _, c := range m.connections {
+		if (c.Peer1ID == peer1ID && c.Peer2ID == peer2ID) || (c.Peer1ID == peer2ID && c.Peer2ID == peer1ID) {
+			return c, nil
+		}
+	}
+	return nil, fmt.Errorf("connection not found")
 }
 func (m *mockFullRepository) ListConnections(ctx context.Context, networkID string) ([]*network.PeerConnection, error) {
-	return nil, nil
+	return m.connections, nil
 }
 func (m *mockFullRepository) DeleteConnection(ctx context.Context, networkID, peer1ID, peer2ID string) error {
 	return nil
 }
+func (m *mockFullRepository) UpdateConnection(ctx context.Context, networkID string, conn *network.PeerConnection) error {
+	for _, c := range m.connections {
+		if c.Peer1ID == conn.Peer1ID && c.Peer2ID == conn.Peer2ID {
+			c.PresharedKey = conn.PresharedKey
+			c.AllowedIPs = conn.AllowedIPs
+			c.AllowedIPsPeerID = conn.AllowedIPsPeerID
+			c.Severed = conn.Severed
+			return nil
+		}
+	}
+	return fmt.Errorf("connection not found")
+}
 func (m *mockFullRepository) CreateOrUpdateSession(ctx context.Context, networkID string, session *network.AgentSession) error {
+	m.sessions[session.SessionID] = session
 	return nil
 }
 func (m *mockFullRepository) GetSession(ctx context.Context, networkID, peerID string) (*network.AgentSession, error) {
-	return nil, nil
+	var latest *network.AgentSession
+	for _, session := range m.sessions {
+		if session.PeerID == peerID && !session.IsRevoked() && (latest == nil || session.LastSeen.After(latest.LastSeen)) {
+			latest = session
+		}
+	}
+	if latest == nil {
+		return nil, fmt.Errorf("session not found")
+	}
+	return latest, nil
 }
 func (m *mockFullRepository) GetActiveSessionsForPeer(ctx context.Context, networkID, peerID string) ([]*network.AgentSession, error) {
 	return nil, nil
@@ -450,7 +605,30 @@ func (m *mockFullRepository) DeleteSession(ctx context.Context, networkID, sessi
 	return nil
 }
 func (m *mockFullRepository) ListSessions(ctx context.Context, networkID string) ([]*network.AgentSession, error) {
-	return nil, nil
+	var sessions []*network.AgentSession
+	for _, session := range m.sessions {
+		if !session.IsRevoked() {
+			sessions = append(sessions, session)
+		}
+	}
+	return sessions, nil
+}
+func (m *mockFullRepository) ListSessionHistory(ctx context.Context, networkID, peerID string) ([]*network.AgentSession, error) {
+	var history []*network.AgentSession
+	for _, session := range m.sessions {
+		if session.PeerID == peerID {
+			history = append(history, session)
+		}
+	}
+	return history, nil
+}
+func (m *mockFullRepository) RevokeSession(ctx context.Context, networkID, sessionID string, revokedAt time.Time) error {
+	session, exists := m.sessions[sessionID]
+	if !exists {
+		return network.ErrSessionNotFound
+	}
+	session.RevokedAt = &revokedAt
+	return nil
 }
 func (m *mockFullRepository) AddCaptivePortalWhitelist(ctx context.Context, networkID, jumpPeerID, peerIP, peerEndpoint string) error {
 	return nil
@@ -529,11 +707,15 @@ func (m *mockFullRepository) ListPeerLocalRoutes(ctx context.Context, networkID
 }
 
 type mockIPAMRepository struct {
-	nextIP int
+	nextIP          int
+	released        []string
+	specific        map[string]bool
+	allocations     []network.IPAllocation
+	deletedPrefixes []string
 }
 
 func newMockIPAMRepository() *mockIPAMRepository {
-	return &mockIPAMRepository{nextIP: 10}
+	return &mockIPAMRepository{nextIP: 10, specific: make(map[string]bool)}
 }
 
 func (m *mockIPAMRepository) AcquireIP(ctx context.Context, cidr string) (string, error) {
@@ -543,9 +725,38 @@ func (m *mockIPAMRepository) AcquireIP(ctx context.Context, cidr string) (string
 }
 
 func (m *mockIPAMRepository) ReleaseIP(ctx context.Context, cidr, ip string) error {
+	m.released = append(m.released, ip)
 	return nil
 }
 
+func (m *mockIPAMRepository) AcquireSpecificIP(ctx context.Context, cidr, ip string) error {
+	if m.specific[ip] {
+		return goipam.ErrAlreadyAllocated
+	}
+	m.specific[ip] = true
+	return nil
+}
+
+func (m *mockIPAMRepository) ReserveIP(ctx context.Context, cidr, ip string) error {
+	return nil
+}
+
+func (m *mockIPAMRepository) ReleaseReservation(ctx context.Context, cidr, ip string) error {
+	return nil
+}
+
+func (m *mockIPAMRepository) ListReservations(ctx context.Context, cidr string) ([]string, error) {
+	return nil, nil
+}
+
+func (m *mockIPAMRepository) AcquiredIPs(ctx context.Context, cidr string) (uint64, error) {
+	return 0, nil
+}
+
+func (m *mockIPAMRepository) ListAllocatedIPs(ctx context.Context, cidr string) ([]network.IPAllocation, error) {
+	return m.allocations, nil
+}
+
 func (m *mockIPAMRepository) EnsureRootPrefix(ctx context.Context, cidr string) (*network.IPAMPrefix, error) {
 	return &network.IPAMPrefix{CIDR: cidr}, nil
 }
@@ -563,6 +774,7 @@ func (m *mockIPAMRepository) ReleaseChildPrefix(ctx context.Context, cidr string
 }
 
 func (m *mockIPAMRepository) DeletePrefix(ctx context.Context, cidr string) error {
+	m.deletedPrefixes = append(m.deletedPrefixes, cidr)
 	return nil
 }
 
@@ -572,6 +784,40 @@ func (m *mockIPAMRepository) ListChildPrefixes(ctx context.Context, parentCIDR s
 
 // Generators for property-based testing
 
+// genRouteCIDRs generates two distinct route destinations, IPv4 or IPv6.
+func genRouteCIDRs() gopter.Gen {
+	return gen.SliceOfN(2, gen.OneConstOf("192.168.1.0/24", "192.168.2.0/24", "192.168.3.0/24", "10.1.0.0/16", "2001:db8:1::/48", "2001:db8:2::/48", "fd10::/64")).SuchThat(func(v interface{}) bool {
+		slice := v.([]string)
+		// Ensure unique CIDRs
+		seen := make(map[string]bool)
+		for _, cidr := range slice {
+			if seen[cidr] {
+				return false
+			}
+			seen[cidr] = true
+		}
+		return true
+	})
+}
+
+// newPropertyTestRoute builds route i through jumpPeerID, putting cidr in
+// the destination field of its address family.
+func newPropertyTestRoute(networkID string, i int, cidr, jumpPeerID string) *network.Route {
+	route := &network.Route{
+		ID:           fmt.Sprintf("route-%d", i),
+		NetworkID:    networkID,
+		Name:         fmt.Sprintf("route-%d", i),
+		JumpPeerID:   jumpPeerID,
+		DomainSuffix: "internal",
+	}
+	if strings.Contains(cidr, ":") {
+		route.DestinationCIDRv6 = cidr
+	} else {
+		route.DestinationCIDR = cidr
+	}
+	return route
+}
+
 func genUserID() gopter.Gen {
 	return gen.Identifier().Map(func(v string) string {
 		return "user-" + v
@@ -1037,22 +1283,25 @@ func TestProperty_RouteDNSQueryResolution(t *testing.T) {
 				// influence the FQDN.
 				routeID := "route-1"
 				route := &network.Route{
-					ID:              routeID,
-					NetworkID:       networkID,
-					Name:            routeName,
-					DestinationCIDR: "192.168.1.0/24",
-					JumpPeerID:      jumpPeerID,
-					DomainSuffix:    "irrelevant-route-suffix",
+					ID:                routeID,
+					NetworkID:         networkID,
+					Name:              routeName,
+					DestinationCIDR:   "192.168.1.0/24",
+					DestinationCIDRv6: "2001:db8:1::/48",
+					JumpPeerID:        jumpPeerID,
+					DomainSuffix:      "irrelevant-route-suffix",
 				}
 				routeRepo.routes[routeID] = route
 
-				// Create DNS mapping for the route
+				// Create DNS mapping for the route, with an address in each
+				// family
 				mappingID := "dns-1"
 				mapping := &network.DNSMapping{
-					ID:        mappingID,
-					RouteID:   routeID,
-					Name:      dnsName,
-					IPAddress: ipAddress,
+					ID:          mappingID,
+					RouteID:     routeID,
+					Name:        dnsName,
+					IPAddress:   ipAddress,
+					IPv6Address: "2001:db8:1::10",
 				}
 				dnsRepo.mappings[mappingID] = mapping
 
@@ -1082,7 +1331,7 @@ func TestProperty_RouteDNSQueryResolution(t *testing.T) {
 				// Find the DNS record for the route
 				found := false
 				for _, dnsPeer := range dnsConfig.Peers {
-					if dnsPeer.Name == expectedFQDN && dnsPeer.IP == ipAddress {
+					if dnsPeer.Name == expectedFQDN && dnsPeer.IP == ipAddress && dnsPeer.IPv6 == "2001:db8:1::10" {
 						found = true
 						break
 					}
@@ -1261,16 +1510,8 @@ func TestProperty_WireGuardConfigRouteInclusion(t *testing.T) {
 				// Create routes and attach to group
 				routes := []*network.Route{}
 				for i, cidr := range routeCIDRs {
-					routeID := fmt.Sprintf("route-%d", i)
-					route := &network.Route{
-						ID:              routeID,
-						NetworkID:       networkID,
-						Name:            fmt.Sprintf("route-%d", i),
-						DestinationCIDR: cidr,
-						JumpPeerID:      jumpPeerID,
-						DomainSuffix:    "internal",
-					}
-					routeRepo.routes[routeID] = route
+					route := newPropertyTestRoute(networkID, i, cidr, jumpPeerID)
+					routeRepo.routes[route.ID] = route
 					routes = append(routes, route)
 				}
 
@@ -1305,18 +1546,7 @@ func TestProperty_WireGuardConfigRouteInclusion(t *testing.T) {
 			},
 			genNetworkID(),
 			genPeerName(),
-			gen.SliceOfN(2, gen.OneConstOf("192.168.1.0/24", "192.168.2.0/24", "192.168.3.0/24", "10.1.0.0/16")).SuchThat(func(v interface{}) bool {
-				slice := v.([]string)
-				// Ensure unique CIDRs
-				seen := make(map[string]bool)
-				for _, cidr := range slice {
-					if seen[cidr] {
-						return false
-					}
-					seen[cidr] = true
-				}
-				return true
-			}),
+			genRouteCIDRs(),
 		))
 
 	properties.TestingRun(t, gopter.ConsoleReporter(false))
@@ -1712,16 +1942,8 @@ func TestProperty_JumpPeerConfigRouteCIDRs(t *testing.T) {
 				// Create routes
 				routes := []*network.Route{}
 				for i, cidr := range routeCIDRs {
-					routeID := fmt.Sprintf("route-%d", i)
-					route := &network.Route{
-						ID:              routeID,
-						NetworkID:       networkID,
-						Name:            fmt.Sprintf("route-%d", i),
-						DestinationCIDR: cidr,
-						JumpPeerID:      jumpPeerID,
-						DomainSuffix:    "internal",
-					}
-					routeRepo.routes[routeID] = route
+					route := newPropertyTestRoute(networkID, i, cidr, jumpPeerID)
+					routeRepo.routes[route.ID] = route
 					routes = append(routes, route)
 				}
 
@@ -1765,18 +1987,7 @@ func TestProperty_JumpPeerConfigRouteCIDRs(t *testing.T) {
 				return true
 			},
 			genNetworkID(),
-			gen.SliceOfN(2, gen.OneConstOf("192.168.1.0/24", "192.168.2.0/24", "192.168.3.0/24", "10.1.0.0/16")).SuchThat(func(v interface{}) bool {
-				slice := v.([]string)
-				// Ensure unique CIDRs
-				seen := make(map[string]bool)
-				for _, cidr := range slice {
-					if seen[cidr] {
-						return false
-					}
-					seen[cidr] = true
-				}
-				return true
-			}),
+			genRouteCIDRs(),
 		))
 
 	properties.TestingRun(t, gopter.ConsoleReporter(false))
@@ -2115,3 +2326,68 @@ type mockPolicyService struct {
 func (m *mockPolicyService) GenerateIPTablesRules(ctx context.Context, networkID, jumpPeerID string) ([]string, error) {
 	return m.rules, nil
 }
+
+func (m *mockFullRepository) CreateTempRoute(ctx context.Context, route *network.TempRoute) error {
+	m.tempRoutes[route.ID] = route
+	return nil
+}
+func (m *mockFullRepository) ListTempRoutes(ctx context.Context, networkID, peerID string) ([]*network.TempRoute, error) {
+	var out []*network.TempRoute
+	for _, t := range m.tempRoutes {
+		if t.NetworkID == networkID && (peerID == "" || t.PeerID == peerID) {
+			out = append(out, t)
+		}
+	}
+	return out, nil
+}
+func (m *mockFullRepository) DeleteTempRoute(ctx context.Context, networkID, id string) error {
+	if _, ok := m.tempRoutes[id]; !ok {
+		return network.ErrTempRouteNotFound
+	}
+	delete(m.tempRoutes, id)
+	return nil
+}
+func (m *mockFullRepository) ListExpiredTempRoutes(ctx context.Context, before time.Time) ([]*network.TempRoute, error) {
+	var out []*network.TempRoute
+	for _, t := range m.tempRoutes {
+		if !t.ExpiresAt.After(before) {
+			out = append(out, t)
+		}
+	}
+	return out, nil
+}
+
+func (m *mockFullRepository) CreatePeerProfile(ctx context.Context, profile *network.PeerProfile) error {
+	m.profiles[profile.ID] = profile
+	return nil
+}
+func (m *mockFullRepository) GetPeerProfile(ctx context.Context, networkID, profileID string) (*network.PeerProfile, error) {
+	p, ok := m.profiles[profileID]
+	if !ok || p.NetworkID != networkID {
+		return nil, network.ErrPeerProfileNotFound
+	}
+	return p, nil
+}
+func (m *mockFullRepository) UpdatePeerProfile(ctx context.Context, profile *network.PeerProfile) error {
+	if _, ok := m.profiles[profile.ID]; !ok {
+		return network.ErrPeerProfileNotFound
+	}
+	m.profiles[profile.ID] = profile
+	return nil
+}
+func (m *mockFullRepository) DeletePeerProfile(ctx context.Context, networkID, profileID string) error {
+	if _, ok := m.profiles[profileID]; !ok {
+		return network.ErrPeerProfileNotFound
+	}
+	delete(m.profiles, profileID)
+	return nil
+}
+func (m *mockFullRepository) ListPeerProfiles(ctx context.Context, networkID string) ([]*network.PeerProfile, error) {
+	var out []*network.PeerProfile
+	for _, p := range m.profiles {
+		if p.NetworkID == networkID {
+			out = append(out, p)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,2270 @@
+package network
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"wirety/internal/domain/auth"
+	"wirety/internal/domain/network"
+	"wirety/internal/infrastructure/validation"
+	"wirety/internal/metrics"
+	"wirety/pkg/wireguard"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+func newReleaseIPTestService() (*Service, *mockFullRepository) {
+	repo := newMockFullRepository()
+	repo.networks["net-1"] = &network.Network{
+		ID:     "net-1",
+		Name:   "test-network",
+		CIDR:   "10.0.0.0/24",
+		CIDRv6: "fd00::/64",
+	}
+	repo.peers["peer-1"] = &network.Peer{
+		ID:        "peer-1",
+		Name:      "laptop",
+		Address:   "10.0.0.10",
+		AddressV6: "fd00::10",
+	}
+	return &Service{repo: repo}, repo
+}
+
+func TestReleaseIP_LeakedAddress(t *testing.T) {
+	svc, repo := newReleaseIPTestService()
+
+	if err := svc.ReleaseIP(context.Background(), "net-1", "10.0.0.42", false); err != nil {
+		t.Fatalf("releasing a leaked IP should succeed, got %v", err)
+	}
+	if len(repo.ipam.released) != 1 || repo.ipam.released[0] != "10.0.0.42" {
+		t.Fatalf("expected 10.0.0.42 to be released, got %v", repo.ipam.released)
+	}
+}
+
+func TestReleaseIP_HeldByLivePeer(t *testing.T) {
+	svc, repo := newReleaseIPTestService()
+	ctx := context.Background()
+
+	for _, ip := range []string{"10.0.0.10", "fd00::10"} {
+		err := svc.ReleaseIP(ctx, "net-1", ip, false)
+		if !errors.Is(err, network.ErrIPInUse) {
+			t.Fatalf("expected ErrIPInUse for %s, got %v", ip, err)
+		}
+	}
+	if len(repo.ipam.released) != 0 {
+		t.Fatalf("nothing should have been released, got %v", repo.ipam.released)
+	}
+
+	if err := svc.ReleaseIP(ctx, "net-1", "10.0.0.10", true); err != nil {
+		t.Fatalf("forced release should succeed, got %v", err)
+	}
+	if len(repo.ipam.released) != 1 || repo.ipam.released[0] != "10.0.0.10" {
+		t.Fatalf("expected 10.0.0.10 to be released, got %v", repo.ipam.released)
+	}
+}
+
+func TestReleaseIP_InvalidInput(t *testing.T) {
+	svc, _ := newReleaseIPTestService()
+	ctx := context.Background()
+
+	if err := svc.ReleaseIP(ctx, "net-1", "not-an-ip", false); !errors.Is(err, network.ErrInvalidIP) {
+		t.Errorf("expected ErrInvalidIP, got %v", err)
+	}
+	if err := svc.ReleaseIP(ctx, "net-1", "192.168.1.1", false); !errors.Is(err, network.ErrIPNotInNetwork) {
+		t.Errorf("expected ErrIPNotInNetwork, got %v", err)
+	}
+	if err := svc.ReleaseIP(ctx, "missing", "10.0.0.42", false); !errors.Is(err, network.ErrNetworkNotFound) {
+		t.Errorf("expected ErrNetworkNotFound, got %v", err)
+	}
+}
+
+// newTestService returns a Service over a mock repository holding the
+// 10.0.0.0/24 network "net-1".
+func newTestService() (*Service, *mockFullRepository) {
+	repo := newMockFullRepository()
+	repo.networks["net-1"] = &network.Network{ID: "net-1", Name: "test-network", CIDR: "10.0.0.0/24"}
+	return &Service{repo: repo}, repo
+}
+
+func TestAddPeers_ReportsPerItemErrors(t *testing.T) {
+	svc, repo := newTestService()
+
+	result, err := svc.AddPeers(context.Background(), "net-1", []*network.PeerCreateRequest{
+		{Name: "laptop-1"},
+		{Name: "Not A Valid Name!"},
+		{Name: "laptop-2"},
+	})
+	if err != nil {
+		t.Fatalf("AddPeers returned error: %v", err)
+	}
+
+	if len(result.Peers) != 2 || result.Peers[0].Name != "laptop-1" || result.Peers[1].Name != "laptop-2" {
+		t.Fatalf("expected laptop-1 and laptop-2 to be created, got %+v", result.Peers)
+	}
+	if len(result.Errors) != 1 || result.Errors[0].Index != 1 {
+		t.Fatalf("expected a single error for index 1, got %+v", result.Errors)
+	}
+	if len(repo.peers) != 2 {
+		t.Fatalf("expected 2 stored peers, got %d", len(repo.peers))
+	}
+	if result.Peers[0].Address == result.Peers[1].Address {
+		t.Fatalf("bulk-created peers must get distinct addresses")
+	}
+}
+
+func TestAddPeers_UnknownNetwork(t *testing.T) {
+	svc := &Service{repo: newMockFullRepository()}
+
+	if _, err := svc.AddPeers(context.Background(), "missing", []*network.PeerCreateRequest{{Name: "laptop"}}); !errors.Is(err, network.ErrNetworkNotFound) {
+		t.Fatalf("expected ErrNetworkNotFound, got %v", err)
+	}
+}
+
+// newRouteConflictTestService builds a network where "laptop" belongs to two
+// groups that route 10.50.0.0/16 through different jump peers.
+func newRouteConflictTestService() *Service {
+	jump1 := &network.Peer{ID: "jump-1", Name: "jump-1", PublicKey: "pk-jump-1", Address: "10.0.0.1", IsJump: true, Endpoint: "203.0.113.1", ListenPort: 51820}
+	jump2 := &network.Peer{ID: "jump-2", Name: "jump-2", PublicKey: "pk-jump-2", Address: "10.0.0.2", IsJump: true, Endpoint: "203.0.113.2", ListenPort: 51820}
+	laptop := &network.Peer{ID: "laptop", Name: "laptop", PublicKey: "pk-laptop", Address: "10.0.0.10"}
+
+	repo := newMockFullRepository()
+	repo.networks["net-1"] = &network.Network{
+		ID:    "net-1",
+		Name:  "test-network",
+		CIDR:  "10.0.0.0/24",
+		Peers: map[string]*network.Peer{jump1.ID: jump1, jump2.ID: jump2, laptop.ID: laptop},
+	}
+
+	groupRepo := newMockGroupRepository()
+	groupRepo.groups["g-low"] = &network.Group{ID: "g-low", NetworkID: "net-1", Name: "low", Priority: 200}
+	groupRepo.groups["g-high"] = &network.Group{ID: "g-high", NetworkID: "net-1", Name: "high", Priority: 10}
+	groupRepo.groupPeers["g-low"] = []string{"laptop"}
+	groupRepo.groupPeers["g-high"] = []string{"laptop"}
+	groupRepo.getGroupRoutes = func(ctx context.Context, networkID, groupID string) ([]*network.Route, error) {
+		switch groupID {
+		case "g-high":
+			return []*network.Route{{ID: "route-b", Name: "via-jump-2", DestinationCIDR: "10.50.0.0/16", JumpPeerID: "jump-2"}}, nil
+		case "g-low":
+			return []*network.Route{{ID: "route-a", Name: "via-jump-1", DestinationCIDR: "10.50.0.0/16", JumpPeerID: "jump-1"}}, nil
+		}
+		return nil, nil
+	}
+
+	return &Service{repo: repo, groupRepo: groupRepo, routeRepo: newMockRouteRepository()}
+}
+
+// peerSection returns the [Peer] section of a generated config whose
+// "# Name:" comment matches name.
+func peerSection(config, name string) string {
+	for _, section := range strings.Split(config, "[Peer]") {
+		if strings.Contains(section, "# Name: "+name+"\n") {
+			return section
+		}
+	}
+	return ""
+}
+
+func TestGeneratePeerConfig_RouteConflictResolvedByPriority(t *testing.T) {
+	var logs bytes.Buffer
+	prev := log.Logger
+	log.Logger = zerolog.New(&logs)
+	defer func() { log.Logger = prev }()
+
+	svc := newRouteConflictTestService()
+
+	config, err := svc.GeneratePeerConfig(context.Background(), "net-1", "laptop")
+	if err != nil {
+		t.Fatalf("GeneratePeerConfig returned error: %v", err)
+	}
+
+	if !strings.Contains(peerSection(config, "jump-2"), "10.50.0.0/16") {
+		t.Errorf("higher-priority route via jump-2 should carry 10.50.0.0/16:\n%s", config)
+	}
+	if strings.Contains(peerSection(config, "jump-1"), "10.50.0.0/16") {
+		t.Errorf("lower-priority route via jump-1 must not carry 10.50.0.0/16:\n%s", config)
+	}
+	if strings.Count(config, "10.50.0.0/16") != 1 {
+		t.Errorf("conflicting CIDR should appear exactly once:\n%s", config)
+	}
+	if !strings.Contains(logs.String(), `"level":"warn"`) || !strings.Contains(logs.String(), "route-a") {
+		t.Errorf("expected a warning naming the dropped route, got logs: %s", logs.String())
+	}
+}
+
+func TestGeneratePeerConfig_RouteConflictStrictMode(t *testing.T) {
+	svc := newRouteConflictTestService()
+	svc.SetStrictRouteConflicts(true)
+
+	if _, err := svc.GeneratePeerConfig(context.Background(), "net-1", "laptop"); !errors.Is(err, network.ErrRouteConflict) {
+		t.Fatalf("expected ErrRouteConflict in strict mode, got %v", err)
+	}
+}
+
+func TestGeneratePeerConfig_GlobalRouteReachesEveryPeer(t *testing.T) {
+	svc := newRouteConflictTestService()
+	repo := svc.repo.(*mockFullRepository)
+	repo.networks["net-1"].Peers["printer"] = &network.Peer{ID: "printer", Name: "printer", PublicKey: "pk-printer", Address: "10.0.0.11"}
+	svc.routeRepo.(*mockRouteRepository).routes["route-site"] = &network.Route{ID: "route-site", NetworkID: "net-1", Name: "site", DestinationCIDR: "192.168.50.0/24", JumpPeerID: "jump-1", GlobalRoute: true}
+	ctx := context.Background()
+
+	for _, peerID := range []string{"laptop", "printer"} {
+		config, err := svc.GeneratePeerConfig(ctx, "net-1", peerID)
+		if err != nil {
+			t.Fatalf("GeneratePeerConfig(%s): %v", peerID, err)
+		}
+		if !strings.Contains(peerSection(config, "jump-1"), "192.168.50.0/24") {
+			t.Errorf("%s: global route missing from the jump-1 section:\n%s", peerID, config)
+		}
+	}
+
+	// Group-scoped routes still only reach their groups' members
+	config, err := svc.GeneratePeerConfig(ctx, "net-1", "printer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(config, "10.50.0.0/16") {
+		t.Errorf("printer is in no group but got the group route:\n%s", config)
+	}
+	config, err = svc.GeneratePeerConfig(ctx, "net-1", "laptop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(peerSection(config, "jump-2"), "10.50.0.0/16") {
+		t.Errorf("laptop lost its group route:\n%s", config)
+	}
+
+	config, err = svc.GeneratePeerConfig(ctx, "net-1", "jump-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(config, "192.168.50.0/24") {
+		t.Errorf("the gateway must not route the global destination back into the tunnel:\n%s", config)
+	}
+}
+
+func TestGetTopology(t *testing.T) {
+	svc := newRouteConflictTestService()
+	repo := svc.repo.(*mockFullRepository)
+	past := time.Now().Add(-time.Hour)
+	peers := repo.networks["net-1"].Peers
+	peers["kiosk"] = &network.Peer{ID: "kiosk", Name: "kiosk", Address: "10.0.0.12", ExpiresAt: &past}
+	peers["nas"] = &network.Peer{ID: "nas", Name: "nas", Address: "10.0.0.13", DNSOnly: true}
+	peers["printer"] = &network.Peer{ID: "printer", Name: "printer", Address: "10.0.0.11"}
+	repo.connections = append(repo.connections, &network.PeerConnection{Peer1ID: "jump-2", Peer2ID: "printer", Severed: true})
+
+	topo, err := svc.GetTopology(context.Background(), "net-1")
+	if err != nil {
+		t.Fatalf("GetTopology: %v", err)
+	}
+	types := make(map[string]string)
+	for _, n := range topo.Nodes {
+		types[n.ID] = n.Type
+		if n.Quarantined != (n.ID == "kiosk") {
+			t.Errorf("node %s quarantined = %v", n.ID, n.Quarantined)
+		}
+	}
+	want := map[string]string{"jump-1": "jump", "jump-2": "jump", "laptop": "regular", "printer": "regular", "kiosk": "regular", "nas": "dns-only"}
+	if !reflect.DeepEqual(types, want) {
+		t.Errorf("node types = %v, want %v", types, want)
+	}
+
+	var edges []string
+	for _, e := range topo.Edges {
+		edges = append(edges, e.Type+":"+e.Source+"-"+e.Target+":"+e.RouteID)
+	}
+	wantEdges := []string{
+		"connection:jump-1-jump-2:",
+		"connection:jump-1-laptop:",
+		"connection:jump-1-printer:",
+		"connection:jump-2-laptop:",
+		"route:laptop-jump-2:route-b",
+	}
+	if !reflect.DeepEqual(edges, wantEdges) {
+		t.Errorf("edges = %v, want %v", edges, wantEdges)
+	}
+
+	if _, err := svc.GetTopology(context.Background(), "missing"); !errors.Is(err, network.ErrNetworkNotFound) {
+		t.Errorf("unknown network: err = %v, want ErrNetworkNotFound", err)
+	}
+}
+
+func TestGeneratePeerConfig_OverlappingRoutesOrderedByMetric(t *testing.T) {
+	svc := newRouteConflictTestService()
+	svc.groupRepo.(*mockGroupRepository).getGroupRoutes = func(ctx context.Context, networkID, groupID string) ([]*network.Route, error) {
+		switch groupID {
+		case "g-high":
+			return []*network.Route{
+				{ID: "route-b", Name: "via-jump-2", DestinationCIDR: "10.50.0.0/16", JumpPeerID: "jump-2", Metric: 20},
+				{ID: "route-c", Name: "wide", DestinationCIDR: "10.0.0.0/8", JumpPeerID: "jump-1"},
+				{ID: "route-d", Name: "narrow", DestinationCIDR: "10.60.1.0/24", JumpPeerID: "jump-1"},
+			}, nil
+		case "g-low":
+			return []*network.Route{
+				{ID: "route-a", Name: "via-jump-1", DestinationCIDR: "10.50.0.0/16", JumpPeerID: "jump-1", Metric: 10},
+			}, nil
+		}
+		return nil, nil
+	}
+
+	first, err := svc.GeneratePeerConfig(context.Background(), "net-1", "laptop")
+	if err != nil {
+		t.Fatalf("GeneratePeerConfig returned error: %v", err)
+	}
+	for i := 0; i < 20; i++ {
+		config, err := svc.GeneratePeerConfig(context.Background(), "net-1", "laptop")
+		if err != nil {
+			t.Fatalf("GeneratePeerConfig returned error: %v", err)
+		}
+		if config != first {
+			t.Fatalf("config changed between generations:\n%s\nvs\n%s", first, config)
+		}
+	}
+
+	// The lower metric beats the higher-priority group
+	if strings.Contains(peerSection(first, "jump-2"), "10.50.0.0/16") {
+		t.Errorf("higher-metric route via jump-2 must not carry 10.50.0.0/16:\n%s", first)
+	}
+	// Among equal metrics and priorities, more specific destinations come first
+	if !strings.Contains(peerSection(first, "jump-1"), "AllowedIPs = 10.0.0.1/32, 10.60.1.0/24, 10.0.0.0/8, 10.50.0.0/16\n") {
+		t.Errorf("unexpected jump-1 AllowedIPs order:\n%s", first)
+	}
+}
+
+func TestGeneratePeerDNSConfig_NewMappingLeavesSpokeConfigUnchanged(t *testing.T) {
+	jump := &network.Peer{ID: "jump", Name: "jump", PublicKey: "pk-jump", Address: "10.0.0.1", IsJump: true, Endpoint: "203.0.113.1", ListenPort: 51820}
+	laptop := &network.Peer{ID: "laptop", Name: "laptop", PublicKey: "pk-laptop", Address: "10.0.0.10"}
+	repo := newMockFullRepository()
+	repo.networks["net-1"] = &network.Network{
+		ID:    "net-1",
+		Name:  "office",
+		CIDR:  "10.0.0.0/24",
+		Peers: map[string]*network.Peer{jump.ID: jump, laptop.ID: laptop},
+	}
+	routeRepo := newMockRouteRepository()
+	routeRepo.routes["route-1"] = &network.Route{ID: "route-1", NetworkID: "net-1", Name: "lan", DestinationCIDR: "192.168.1.0/24", JumpPeerID: "jump"}
+	dnsRepo := newMockDNSRepository()
+	svc := &Service{repo: repo, routeRepo: routeRepo, dnsRepo: dnsRepo}
+	ctx := context.Background()
+
+	before, err := svc.GeneratePeerConfig(ctx, "net-1", "laptop")
+	if err != nil {
+		t.Fatalf("GeneratePeerConfig returned error: %v", err)
+	}
+
+	dnsRepo.mappings["dns-1"] = &network.DNSMapping{ID: "dns-1", RouteID: "route-1", Name: "nas", IPAddress: "192.168.1.20"}
+
+	dnsCfg, err := svc.GeneratePeerDNSConfig(ctx, "net-1", "jump")
+	if err != nil {
+		t.Fatalf("GeneratePeerDNSConfig returned error: %v", err)
+	}
+	found := false
+	for _, p := range dnsCfg.Peers {
+		if p.Name == "nas.office.internal" && p.IP == "192.168.1.20" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected the new mapping in the jump's DNS config, got %+v", dnsCfg.Peers)
+	}
+
+	after, err := svc.GeneratePeerConfig(ctx, "net-1", "laptop")
+	if err != nil {
+		t.Fatalf("GeneratePeerConfig returned error: %v", err)
+	}
+	if after != before {
+		t.Errorf("a DNS mapping must not change a spoke's WireGuard config:\nbefore:\n%s\nafter:\n%s", before, after)
+	}
+
+	if _, err := svc.GeneratePeerDNSConfig(ctx, "net-1", "laptop"); err == nil {
+		t.Error("expected an error for a non-jump peer")
+	}
+}
+
+func TestGeneratePeerDNSConfig_IncludesNetworkDNSRecords(t *testing.T) {
+	jump := &network.Peer{ID: "jump", Name: "jump", PublicKey: "pk-jump", Address: "10.0.0.1", IsJump: true}
+	repo := newMockFullRepository()
+	repo.networks["net-1"] = &network.Network{
+		ID:           "net-1",
+		Name:         "office",
+		CIDR:         "10.0.0.0/24",
+		DomainSuffix: "corp",
+		Peers:        map[string]*network.Peer{jump.ID: jump},
+	}
+	dnsRepo := newMockDNSRepository()
+	dnsRepo.records["rec-1"] = &network.NetworkDNSRecord{ID: "rec-1", NetworkID: "net-1", Name: "grafana", IPAddress: "203.0.113.7"}
+	dnsRepo.records["rec-2"] = &network.NetworkDNSRecord{ID: "rec-2", NetworkID: "net-2", Name: "other", IPAddress: "203.0.113.8"}
+	svc := &Service{repo: repo, routeRepo: newMockRouteRepository(), dnsRepo: dnsRepo}
+
+	dnsCfg, err := svc.GeneratePeerDNSConfig(context.Background(), "net-1", "jump")
+	if err != nil {
+		t.Fatalf("GeneratePeerDNSConfig returned error: %v", err)
+	}
+	found := false
+	for _, p := range dnsCfg.Peers {
+		if p.Name == "grafana.office.corp" && p.IP == "203.0.113.7" {
+			found = true
+		}
+		if strings.HasPrefix(p.Name, "other.") {
+			t.Errorf("record from another network leaked into the config: %+v", p)
+		}
+	}
+	if !found {
+		t.Errorf("expected grafana.office.corp in the jump's DNS config, got %+v", dnsCfg.Peers)
+	}
+}
+
+func TestConditionalForwarders_NormalizedAndSentToJumpPeers(t *testing.T) {
+	jump := &network.Peer{ID: "jump", Name: "jump", PublicKey: "pk-jump", Address: "10.0.0.1", IsJump: true}
+	repo := newMockFullRepository()
+	repo.networks["net-1"] = &network.Network{
+		ID:    "net-1",
+		Name:  "office",
+		CIDR:  "10.0.0.0/24",
+		DNS:   []string{"1.1.1.1"},
+		Peers: map[string]*network.Peer{jump.ID: jump},
+	}
+	notifier := &recordingNotifier{}
+	svc := &Service{repo: repo, routeRepo: newMockRouteRepository(), wsNotifier: notifier}
+	ctx := context.Background()
+
+	for _, forwarders := range []map[string][]string{
+		{"corp.example.com": {}},
+		{"corp.example.com": {"not-an-ip"}},
+		{"bad_domain": {"10.1.0.53"}},
+	} {
+		_, err := svc.UpdateNetwork(ctx, "net-1", &network.NetworkUpdateRequest{ConditionalForwarders: forwarders})
+		if !errors.Is(err, network.ErrInvalidConditionalForwarder) {
+			t.Errorf("UpdateNetwork(%v) = %v, want ErrInvalidConditionalForwarder", forwarders, err)
+		}
+	}
+
+	_, err := svc.UpdateNetwork(ctx, "net-1", &network.NetworkUpdateRequest{ConditionalForwarders: map[string][]string{
+		"Corp.Example.com.": {"10.1.0.53", "10.1.0.54:5353"},
+	}})
+	if err != nil {
+		t.Fatalf("UpdateNetwork returned error: %v", err)
+	}
+	if len(notifier.notified) != 1 {
+		t.Errorf("expected the network's peers to be notified once, got %v", notifier.notified)
+	}
+
+	dnsCfg, err := svc.GeneratePeerDNSConfig(ctx, "net-1", "jump")
+	if err != nil {
+		t.Fatalf("GeneratePeerDNSConfig returned error: %v", err)
+	}
+	if got := dnsCfg.ConditionalForwarders["corp.example.com"]; len(got) != 2 || got[0] != "10.1.0.53" || got[1] != "10.1.0.54:5353" {
+		t.Errorf("expected the normalized forwarder in the DNS config, got %v", dnsCfg.ConditionalForwarders)
+	}
+}
+
+func TestSearchDomains_InPeerConfigAndJumpDNS(t *testing.T) {
+	jump := &network.Peer{ID: "jump", Name: "jump", PublicKey: "pk-jump", Address: "10.0.0.1", IsJump: true, Endpoint: "203.0.113.1", ListenPort: 51820}
+	laptop := &network.Peer{ID: "laptop", Name: "laptop", PublicKey: "pk-laptop", Address: "10.0.0.10"}
+	repo := newMockFullRepository()
+	repo.networks["net-1"] = &network.Network{
+		ID:           "net-1",
+		Name:         "office",
+		CIDR:         "10.0.0.0/24",
+		DomainSuffix: "internal",
+		Peers:        map[string]*network.Peer{jump.ID: jump, laptop.ID: laptop},
+	}
+	notifier := &recordingNotifier{}
+	svc := &Service{repo: repo, routeRepo: newMockRouteRepository(), wsNotifier: notifier}
+	ctx := context.Background()
+
+	config, err := svc.GeneratePeerConfig(ctx, "net-1", "laptop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(config, "DNS = 10.0.0.1\n") {
+		t.Fatalf("without search domains the DNS line must be unchanged:\n%s", config)
+	}
+
+	if _, err := svc.UpdateNetwork(ctx, "net-1", &network.NetworkUpdateRequest{SearchDomains: []string{"corp_internal"}}); !errors.Is(err, validation.ErrInvalidDNSName) {
+		t.Errorf("invalid search domain: err = %v, want ErrInvalidDNSName", err)
+	}
+	net, err := svc.UpdateNetwork(ctx, "net-1", &network.NetworkUpdateRequest{SearchDomains: []string{"Corp.Internal.", "eng.internal", "corp.internal"}})
+	if err != nil {
+		t.Fatalf("UpdateNetwork: %v", err)
+	}
+	if !slices.Equal(net.SearchDomains, []string{"corp.internal", "eng.internal"}) {
+		t.Errorf("SearchDomains = %v, want them normalized once each", net.SearchDomains)
+	}
+	if len(notifier.notified) != 1 {
+		t.Errorf("expected the network's peers to be notified once, got %v", notifier.notified)
+	}
+
+	config, err = svc.GeneratePeerConfig(ctx, "net-1", "laptop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(config, "DNS = 10.0.0.1, office.internal, corp.internal, eng.internal\n") {
+		t.Errorf("expected the search list after the jump DNS server, the network domain first:\n%s", config)
+	}
+	dnsCfg, err := svc.GeneratePeerDNSConfig(ctx, "net-1", "jump")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dnsCfg.Domain != "office.internal" || !slices.Equal(dnsCfg.SearchDomains, []string{"office.internal", "corp.internal", "eng.internal"}) {
+		t.Errorf("jump DNS config domain %q, search domains %v", dnsCfg.Domain, dnsCfg.SearchDomains)
+	}
+
+	if net, err = svc.UpdateNetwork(ctx, "net-1", &network.NetworkUpdateRequest{SearchDomains: []string{}}); err != nil || len(net.SearchDomains) != 0 {
+		t.Errorf("[] must remove the search domains, got %v, %v", net, err)
+	}
+}
+
+type recordingNotifier struct {
+	notified []string
+}
+
+func (n *recordingNotifier) NotifyNetworkPeers(networkID string) {
+	n.notified = append(n.notified, networkID)
+}
+
+func TestRotateNetworkPresharedKeys(t *testing.T) {
+	svc, repo := newTestService()
+	notifier := &recordingNotifier{}
+	svc.wsNotifier = notifier
+	ctx := context.Background()
+
+	for _, name := range []string{"jump", "laptop-1", "laptop-2"} {
+		if _, err := svc.AddPeer(ctx, "net-1", &network.PeerCreateRequest{Name: name}, ""); err != nil {
+			t.Fatalf("AddPeer(%s): %v", name, err)
+		}
+	}
+	if len(repo.connections) != 3 {
+		t.Fatalf("expected 3 connections, got %d", len(repo.connections))
+	}
+
+	seen := make(map[string]bool)
+	for _, c := range repo.connections {
+		seen[c.PresharedKey] = true
+	}
+
+	for round := 0; round < 2; round++ {
+		rotated, err := svc.RotateNetworkPresharedKeys(ctx, "net-1")
+		if err != nil {
+			t.Fatalf("round %d: RotateNetworkPresharedKeys: %v", round, err)
+		}
+		if rotated != 3 {
+			t.Fatalf("round %d: expected 3 rotated connections, got %d", round, rotated)
+		}
+		for _, c := range repo.connections {
+			if c.PresharedKey == "" || seen[c.PresharedKey] {
+				t.Fatalf("round %d: connection %s/%s kept an old or empty key", round, c.Peer1ID, c.Peer2ID)
+			}
+			seen[c.PresharedKey] = true
+		}
+	}
+
+	if len(notifier.notified) != 2 || notifier.notified[0] != "net-1" {
+		t.Fatalf("expected one notification per rotation, got %v", notifier.notified)
+	}
+}
+
+type staticPolicyService struct {
+	rules []string
+}
+
+func (p *staticPolicyService) GenerateIPTablesRules(ctx context.Context, networkID, jumpPeerID string) ([]string, error) {
+	return p.rules, nil
+}
+
+func TestGeneratePeerConfigWithDNS_NftablesAgentGetsNftRules(t *testing.T) {
+	svc := newRouteConflictTestService()
+	repo := svc.repo.(*mockFullRepository)
+	for id, p := range repo.networks["net-1"].Peers {
+		repo.peers[id] = p
+	}
+	svc.policyService = &staticPolicyService{rules: []string{
+		"iptables -A FORWARD -s 10.0.0.10 -d 10.50.0.0/16 -j ACCEPT",
+		"iptables -A FORWARD -j DROP",
+	}}
+	ctx := context.Background()
+
+	// Before the agent reports anything, only iptables rules are delivered
+	_, _, policy, err := svc.GeneratePeerConfigWithDNS(ctx, "net-1", "jump-1")
+	if err != nil {
+		t.Fatalf("GeneratePeerConfigWithDNS: %v", err)
+	}
+	if policy.FirewallBackend != "" || len(policy.NftRules) != 0 {
+		t.Fatalf("expected no nft rules before the agent reports its backend, got %+v", policy)
+	}
+
+	heartbeat := &network.AgentHeartbeat{Hostname: "jump-1", FirewallBackend: network.FirewallBackendNftables}
+	if err := svc.ProcessAgentHeartbeat(ctx, "net-1", "jump-1", heartbeat); err != nil {
+		t.Fatalf("ProcessAgentHeartbeat: %v", err)
+	}
+	session, err := repo.GetSession(ctx, "net-1", "jump-1")
+	if err != nil {
+		t.Fatalf("GetSession: %v", err)
+	}
+	if got := session.FirewallBackend; got != network.FirewallBackendNftables {
+		t.Fatalf("session firewall backend = %q, want nftables", got)
+	}
+
+	_, _, policy, err = svc.GeneratePeerConfigWithDNS(ctx, "net-1", "jump-1")
+	if err != nil {
+		t.Fatalf("GeneratePeerConfigWithDNS: %v", err)
+	}
+	if policy.FirewallBackend != network.FirewallBackendNftables {
+		t.Fatalf("policy firewall backend = %q, want nftables", policy.FirewallBackend)
+	}
+	want := []string{
+		"nft add rule inet wirety forward ip saddr 10.0.0.10 ip daddr 10.50.0.0/16 accept",
+		"nft add rule inet wirety forward meta nfproto ipv4 drop",
+	}
+	if len(policy.NftRules) != len(want) {
+		t.Fatalf("nft rules = %q, want %q", policy.NftRules, want)
+	}
+	for i := range want {
+		if policy.NftRules[i] != want[i] {
+			t.Fatalf("nft rule %d = %q, want %q", i, policy.NftRules[i], want[i])
+		}
+	}
+	if len(policy.IPTablesRules) != 2 {
+		t.Fatalf("iptables rules should still be delivered for older agents, got %q", policy.IPTablesRules)
+	}
+
+}
+
+func TestPreviewJumpFirewall_MatchesAgentPolicy(t *testing.T) {
+	svc := newRouteConflictTestService()
+	repo := svc.repo.(*mockFullRepository)
+	for id, p := range repo.networks["net-1"].Peers {
+		repo.peers[id] = p
+	}
+	svc.policyService = &staticPolicyService{rules: []string{
+		"iptables -A FORWARD -s 10.0.0.10 -d 10.50.0.0/16 -j ACCEPT",
+		"iptables -A FORWARD -j DROP",
+		"ip6tables -A FORWARD -j DROP",
+	}}
+	ctx := context.Background()
+
+	preview, err := svc.PreviewJumpFirewall(ctx, "net-1", "jump-1")
+	if err != nil {
+		t.Fatalf("PreviewJumpFirewall: %v", err)
+	}
+	_, _, policy, err := svc.GeneratePeerConfigWithDNS(ctx, "net-1", "jump-1")
+	if err != nil {
+		t.Fatalf("GeneratePeerConfigWithDNS: %v", err)
+	}
+	if strings.Join(preview.IPTablesRules, "\n") != strings.Join(policy.IPTablesRules, "\n") {
+		t.Errorf("preview rules = %q, agent receives %q", preview.IPTablesRules, policy.IPTablesRules)
+	}
+	if len(preview.Rules) != len(policy.Rules) {
+		t.Errorf("preview has %d parsed rules, agent receives %d", len(preview.Rules), len(policy.Rules))
+	}
+
+	if _, err := svc.PreviewJumpFirewall(ctx, "net-1", "laptop"); !errors.Is(err, network.ErrNotJumpPeer) {
+		t.Errorf("regular peer: err = %v, want ErrNotJumpPeer", err)
+	}
+	if _, err := svc.PreviewJumpFirewall(ctx, "net-1", "missing"); !errors.Is(err, network.ErrPeerNotFound) {
+		t.Errorf("unknown peer: err = %v, want ErrPeerNotFound", err)
+	}
+}
+
+func TestAddPeer_ValidatesEndpoint(t *testing.T) {
+	svc, _ := newTestService()
+	ctx := context.Background()
+
+	for _, endpoint := range []string{"vpn.example.com", "203.0.113.1", "2001:db8::1"} {
+		name := "jump-" + strings.NewReplacer(".", "-", ":", "-").Replace(endpoint)
+		req := &network.PeerCreateRequest{Name: name, IsJump: true, Endpoint: endpoint}
+		if _, err := svc.AddPeer(ctx, "net-1", req, ""); err != nil {
+			t.Fatalf("AddPeer with endpoint %q: %v", endpoint, err)
+		}
+	}
+
+	req := &network.PeerCreateRequest{Name: "jump-bad", IsJump: true, Endpoint: "203.0.113.1:51820"}
+	if _, err := svc.AddPeer(ctx, "net-1", req, ""); err == nil || !strings.Contains(err.Error(), "invalid endpoint") {
+		t.Fatalf("expected an invalid endpoint error for host:port, got %v", err)
+	}
+}
+
+func TestAddPeer_ImportedPublicKey(t *testing.T) {
+	svc, _ := newTestService()
+	ctx := context.Background()
+	const key = "xTIBA5rboUvnH4htodjb6e697QjLERt1NAB4mZqp8Dg="
+
+	peer, err := svc.AddPeer(ctx, "net-1", &network.PeerCreateRequest{Name: "yubikey", PublicKey: key}, "")
+	if err != nil {
+		t.Fatalf("AddPeer: %v", err)
+	}
+	if peer.PublicKey != key || peer.PrivateKey != "" {
+		t.Errorf("expected only the imported public key to be stored, got public %q private %q", peer.PublicKey, peer.PrivateKey)
+	}
+
+	if _, err := svc.AddPeer(ctx, "net-1", &network.PeerCreateRequest{Name: "clone", PublicKey: key}, ""); !errors.Is(err, network.ErrPublicKeyInUse) {
+		t.Errorf("reusing the key: got %v, want ErrPublicKeyInUse", err)
+	}
+	if _, err := svc.AddPeer(ctx, "net-1", &network.PeerCreateRequest{Name: "bad", PublicKey: "not-a-key"}, ""); !errors.Is(err, network.ErrInvalidPublicKey) {
+		t.Errorf("invalid key: got %v, want ErrInvalidPublicKey", err)
+	}
+}
+
+func TestAddPeer_GroupsAtCreation(t *testing.T) {
+	repo := newMockFullRepository()
+	repo.networks["net-1"] = &network.Network{ID: "net-1", Name: "test-network", CIDR: "10.0.0.0/24", DefaultGroupIDs: []string{"everyone", "staff"}}
+	groups := newMockGroupRepository()
+	for _, id := range []string{"everyone", "staff", "servers"} {
+		groups.groups[id] = &network.Group{ID: id, Name: id, NetworkID: "net-1"}
+	}
+	users := newMockAuthRepository()
+	users.users["user-1"] = &auth.User{ID: "user-1", Role: auth.RoleUser}
+	svc := &Service{repo: repo, authRepo: users, groupRepo: groups}
+	ctx := context.Background()
+
+	peer, err := svc.AddPeer(ctx, "net-1", &network.PeerCreateRequest{Name: "laptop", GroupIDs: []string{"servers", "staff", "servers"}}, "user-1")
+	if err != nil {
+		t.Fatalf("AddPeer: %v", err)
+	}
+	if want := []string{"servers", "staff", "everyone"}; !slices.Equal(peer.GroupIDs, want) {
+		t.Errorf("peer.GroupIDs = %v, want %v", peer.GroupIDs, want)
+	}
+	for _, id := range []string{"everyone", "staff", "servers"} {
+		if members := groups.groupPeers[id]; len(members) != 1 || members[0] != peer.ID {
+			t.Errorf("group %s members = %v, want only %s", id, members, peer.ID)
+		}
+	}
+
+	peers := len(repo.peers)
+	_, err = svc.AddPeer(ctx, "net-1", &network.PeerCreateRequest{Name: "server", GroupIDs: []string{"servers", "missing"}}, "")
+	if !errors.Is(err, network.ErrGroupNotFound) {
+		t.Fatalf("unknown group: err = %v, want ErrGroupNotFound", err)
+	}
+	if len(repo.peers) != peers || len(groups.groupPeers["servers"]) != 1 {
+		t.Error("a rejected create left a peer or group membership behind")
+	}
+}
+
+func TestPeerAllowedIPs_ValidatedAndNormalized(t *testing.T) {
+	svc, repo := newTestService()
+	ctx := context.Background()
+
+	req := &network.PeerCreateRequest{Name: "gateway", AdditionalAllowedIPs: []string{"192.168.1.0/24", "192.168.1.7", "fd10::1"}}
+	peer, err := svc.AddPeer(ctx, "net-1", req, "")
+	if err != nil {
+		t.Fatalf("AddPeer: %v", err)
+	}
+	want := []string{"192.168.1.0/24", "192.168.1.7/32", "fd10::1/128"}
+	if strings.Join(peer.AdditionalAllowedIPs, ",") != strings.Join(want, ",") {
+		t.Errorf("expected normalized allowed IPs %v, got %v", want, peer.AdditionalAllowedIPs)
+	}
+
+	bad := &network.PeerCreateRequest{Name: "broken", AdditionalAllowedIPs: []string{"10.0.0.0/33"}}
+	if _, err := svc.AddPeer(ctx, "net-1", bad, ""); !errors.Is(err, validation.ErrInvalidAllowedIP) {
+		t.Fatalf("expected ErrInvalidAllowedIP from AddPeer, got %v", err)
+	}
+
+	update := &network.PeerUpdateRequest{AdditionalAllowedIPs: []string{""}}
+	if _, err := svc.UpdatePeer(ctx, "net-1", peer.ID, update); !errors.Is(err, validation.ErrInvalidAllowedIP) {
+		t.Fatalf("expected ErrInvalidAllowedIP from UpdatePeer, got %v", err)
+	}
+	if got, _ := repo.GetPeer(ctx, "net-1", peer.ID); len(got.AdditionalAllowedIPs) != len(want) {
+		t.Errorf("a rejected update must not change the peer, got %v", got.AdditionalAllowedIPs)
+	}
+}
+
+func TestPeerNamePattern(t *testing.T) {
+	repo := newMockFullRepository()
+	repo.networks["net-1"] = &network.Network{
+		ID:              "net-1",
+		Name:            "test-network",
+		CIDR:            "10.0.0.0/24",
+		PeerNamePattern: `[a-z]{3}-(web|db)-[0-9]+`,
+	}
+	svc := &Service{repo: repo}
+	ctx := context.Background()
+
+	peer, err := svc.AddPeer(ctx, "net-1", &network.PeerCreateRequest{Name: "par-web-1"}, "")
+	if err != nil {
+		t.Fatalf("name matching the pattern should be accepted, got %v", err)
+	}
+
+	for _, name := range []string{"laptop", "par-web-1-old"} {
+		_, err := svc.AddPeer(ctx, "net-1", &network.PeerCreateRequest{Name: name}, "")
+		if !errors.Is(err, network.ErrPeerNamePattern) {
+			t.Fatalf("AddPeer(%q): expected ErrPeerNamePattern, got %v", name, err)
+		}
+		if !strings.Contains(err.Error(), `[a-z]{3}-(web|db)-[0-9]+`) {
+			t.Fatalf("error should name the expected pattern, got %v", err)
+		}
+	}
+
+	if _, err := svc.UpdatePeer(ctx, "net-1", peer.ID, &network.PeerUpdateRequest{Name: "laptop"}); !errors.Is(err, network.ErrPeerNamePattern) {
+		t.Fatalf("UpdatePeer: expected ErrPeerNamePattern, got %v", err)
+	}
+	if _, err := svc.UpdatePeer(ctx, "net-1", peer.ID, &network.PeerUpdateRequest{Name: "par-db-2"}); err != nil {
+		t.Fatalf("UpdatePeer to a matching name: %v", err)
+	}
+}
+
+func TestUpdatePeer_OverridesAndInheritsProfileSettings(t *testing.T) {
+	svc, _ := newTestService()
+	svc.SetPeerDefaults(network.PeerDefaults{PersistentKeepalive: 30})
+	ctx := context.Background()
+
+	keepalive := 15
+	profile, err := svc.CreatePeerProfile(ctx, "net-1", &network.PeerProfileRequest{
+		Name:                  "mobile",
+		PersistentKeepalive:   &keepalive,
+		SplitTunnelExclusions: []string{"192.168.0.0/16"},
+	})
+	if err != nil {
+		t.Fatalf("CreatePeerProfile: %v", err)
+	}
+	peer, err := svc.AddPeer(ctx, "net-1", &network.PeerCreateRequest{Name: "phone", ProfileID: profile.ID}, "")
+	if err != nil {
+		t.Fatalf("AddPeer: %v", err)
+	}
+	resolved := svc.resolvePeerProfile(ctx, "net-1", peer)
+	if *resolved.PersistentKeepalive != 15 || len(resolved.SplitTunnelExclusions) != 1 {
+		t.Fatalf("profile not applied: keepalive %d, exclusions %v", *resolved.PersistentKeepalive, resolved.SplitTunnelExclusions)
+	}
+
+	// 0 and [] are overrides, not "unset".
+	off := 0
+	peer, err = svc.UpdatePeer(ctx, "net-1", peer.ID, &network.PeerUpdateRequest{PersistentKeepalive: &off, SplitTunnelExclusions: []string{}})
+	if err != nil {
+		t.Fatalf("UpdatePeer: %v", err)
+	}
+	resolved = svc.resolvePeerProfile(ctx, "net-1", peer)
+	if *resolved.PersistentKeepalive != 0 || len(resolved.SplitTunnelExclusions) != 0 {
+		t.Errorf("overrides lost: keepalive %d, exclusions %v", *resolved.PersistentKeepalive, resolved.SplitTunnelExclusions)
+	}
+
+	// Inheriting brings back the profile, and without a profile the defaults.
+	inherit := []string{network.PeerSettingPersistentKeepalive, network.PeerSettingSplitTunnelExclusions}
+	peer, err = svc.UpdatePeer(ctx, "net-1", peer.ID, &network.PeerUpdateRequest{Inherit: inherit})
+	if err != nil {
+		t.Fatalf("UpdatePeer: %v", err)
+	}
+	if resolved = svc.resolvePeerProfile(ctx, "net-1", peer); *resolved.PersistentKeepalive != 15 || len(resolved.SplitTunnelExclusions) != 1 {
+		t.Errorf("profile not inherited: keepalive %d, exclusions %v", *resolved.PersistentKeepalive, resolved.SplitTunnelExclusions)
+	}
+	noProfile := ""
+	peer, err = svc.UpdatePeer(ctx, "net-1", peer.ID, &network.PeerUpdateRequest{ProfileID: &noProfile})
+	if err != nil {
+		t.Fatalf("UpdatePeer: %v", err)
+	}
+	if resolved = svc.resolvePeerProfile(ctx, "net-1", peer); *resolved.PersistentKeepalive != 30 {
+		t.Errorf("keepalive without a profile = %d, want the server default 30", *resolved.PersistentKeepalive)
+	}
+
+	for _, req := range []*network.PeerUpdateRequest{
+		{Inherit: []string{"listen_port"}},
+		{PersistentKeepalive: &off, Inherit: []string{network.PeerSettingPersistentKeepalive}},
+	} {
+		if _, err := svc.UpdatePeer(ctx, "net-1", peer.ID, req); err == nil {
+			t.Errorf("UpdatePeer(inherit %v) should fail", req.Inherit)
+		}
+	}
+}
+
+func TestAddPeer_HubTopologyOnlyConnectsJumpPeers(t *testing.T) {
+	repo := newMockFullRepository()
+	repo.networks["net-1"] = &network.Network{
+		ID:       "net-1",
+		Name:     "test-network",
+		CIDR:     "10.0.0.0/24",
+		Topology: network.TopologyHub,
+		Peers:    map[string]*network.Peer{},
+	}
+	svc := &Service{repo: repo}
+	ctx := context.Background()
+
+	reqs := []*network.PeerCreateRequest{
+		{Name: "jump-1", IsJump: true, Endpoint: "203.0.113.1", ListenPort: 51820},
+		{Name: "jump-2", IsJump: true, Endpoint: "203.0.113.2", ListenPort: 51820},
+		{Name: "laptop-1"},
+		{Name: "laptop-2"},
+		{Name: "laptop-3"},
+	}
+	peers := make(map[string]*network.Peer)
+	for _, req := range reqs {
+		p, err := svc.AddPeer(ctx, "net-1", req, "")
+		if err != nil {
+			t.Fatalf("AddPeer(%s): %v", req.Name, err)
+		}
+		peers[p.Name] = p
+		repo.networks["net-1"].AddPeer(p)
+	}
+
+	// 1 jump<->jump + 2 jumps * 3 laptops; a mesh would need 10
+	if len(repo.connections) != 7 {
+		t.Fatalf("expected 7 connections in hub topology, got %d", len(repo.connections))
+	}
+	for _, c := range repo.connections {
+		if !repo.peers[c.Peer1ID].IsJump && !repo.peers[c.Peer2ID].IsJump {
+			t.Fatalf("hub topology created a connection between regular peers %s and %s", c.Peer1ID, c.Peer2ID)
+		}
+	}
+
+	// Every peer listed in a regular peer's config still gets its PSK
+	config, err := svc.GeneratePeerConfig(ctx, "net-1", peers["laptop-1"].ID)
+	if err != nil {
+		t.Fatalf("GeneratePeerConfig: %v", err)
+	}
+	if got := strings.Count(config, "PresharedKey = "); got != 2 {
+		t.Fatalf("expected a preshared key for both jump peers, got %d:\n%s", got, config)
+	}
+
+	// Switching to mesh backfills the regular peer pairs
+	if _, err := svc.UpdateNetwork(ctx, "net-1", &network.NetworkUpdateRequest{Topology: network.TopologyMesh}); err != nil {
+		t.Fatalf("UpdateNetwork: %v", err)
+	}
+	if len(repo.connections) != 10 {
+		t.Fatalf("expected 10 connections after switching to mesh, got %d", len(repo.connections))
+	}
+}
+
+func TestTempRoute_AppearsInConfigAndExpires(t *testing.T) {
+	svc := newRouteConflictTestService()
+	repo := svc.repo.(*mockFullRepository)
+	for id, p := range repo.networks["net-1"].Peers {
+		repo.peers[id] = p
+	}
+	notifier := &recordingNotifier{}
+	svc.wsNotifier = notifier
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	svc.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	temp, err := svc.GrantTempRoute(ctx, "net-1", "laptop", &network.TempRouteCreateRequest{
+		DestinationCIDR: "192.168.77.0/24",
+		JumpPeerID:      "jump-1",
+		DurationMinutes: 30,
+		Reason:          "incident 42",
+	}, "admin-1")
+	if err != nil {
+		t.Fatalf("GrantTempRoute: %v", err)
+	}
+	if !temp.ExpiresAt.Equal(now.Add(30 * time.Minute)) {
+		t.Fatalf("expires_at = %v, want %v", temp.ExpiresAt, now.Add(30*time.Minute))
+	}
+
+	config, err := svc.GeneratePeerConfig(ctx, "net-1", "laptop")
+	if err != nil {
+		t.Fatalf("GeneratePeerConfig: %v", err)
+	}
+	if !strings.Contains(peerSection(config, "jump-1"), "192.168.77.0/24") {
+		t.Fatalf("temporary route missing from jump-1 section:\n%s", config)
+	}
+
+	// Sweeping before expiry keeps the grant
+	now = now.Add(29 * time.Minute)
+	if err := svc.CleanupExpiredTempRoutes(ctx); err != nil {
+		t.Fatalf("CleanupExpiredTempRoutes: %v", err)
+	}
+	if len(repo.tempRoutes) != 1 {
+		t.Fatalf("grant removed before expiry")
+	}
+
+	// The route stops being served as soon as it expires, and the sweep
+	// deletes it and pushes new configs
+	now = now.Add(2 * time.Minute)
+	config, err = svc.GeneratePeerConfig(ctx, "net-1", "laptop")
+	if err != nil {
+		t.Fatalf("GeneratePeerConfig: %v", err)
+	}
+	if strings.Contains(config, "192.168.77.0/24") {
+		t.Fatalf("expired temporary route still in config:\n%s", config)
+	}
+	notified := len(notifier.notified)
+	if err := svc.CleanupExpiredTempRoutes(ctx); err != nil {
+		t.Fatalf("CleanupExpiredTempRoutes: %v", err)
+	}
+	if len(repo.tempRoutes) != 0 {
+		t.Fatalf("expired grant not removed by the sweep")
+	}
+	if len(notifier.notified) != notified+1 {
+		t.Fatalf("sweep should notify the network once, got %v", notifier.notified[notified:])
+	}
+}
+
+func TestGrantTempRoute_RejectsInvalidRequests(t *testing.T) {
+	svc := newRouteConflictTestService()
+	repo := svc.repo.(*mockFullRepository)
+	for id, p := range repo.networks["net-1"].Peers {
+		repo.peers[id] = p
+	}
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		peerID string
+		req    network.TempRouteCreateRequest
+		want   error
+	}{
+		{"via a regular peer", "laptop", network.TempRouteCreateRequest{DestinationCIDR: "192.168.77.0/24", JumpPeerID: "laptop", DurationMinutes: 10}, network.ErrNotJumpPeer},
+		{"unknown peer", "ghost", network.TempRouteCreateRequest{DestinationCIDR: "192.168.77.0/24", JumpPeerID: "jump-1", DurationMinutes: 10}, network.ErrPeerNotFound},
+		{"too long", "laptop", network.TempRouteCreateRequest{DestinationCIDR: "192.168.77.0/24", JumpPeerID: "jump-1", DurationMinutes: 25 * 60}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := svc.GrantTempRoute(ctx, "net-1", tt.peerID, &tt.req, "admin-1")
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestMetrics_PeerCreationAndConfigGeneration(t *testing.T) {
+	svc, _ := newTestService()
+	ctx := context.Background()
+
+	created := testutil.ToFloat64(metrics.PeersCreated)
+	generated := testutil.ToFloat64(metrics.ConfigGenerations)
+
+	peer, err := svc.AddPeer(ctx, "net-1", &network.PeerCreateRequest{Name: "laptop"}, "")
+	if err != nil {
+		t.Fatalf("AddPeer: %v", err)
+	}
+	if _, err := svc.AddPeer(ctx, "net-1", &network.PeerCreateRequest{Name: "-" + peer.Name}, ""); err == nil {
+		t.Fatal("expected invalid peer name to be rejected")
+	}
+	if _, err := newRouteConflictTestService().GeneratePeerConfig(ctx, "net-1", "laptop"); err != nil {
+		t.Fatalf("GeneratePeerConfig: %v", err)
+	}
+
+	if got := testutil.ToFloat64(metrics.PeersCreated) - created; got != 1 {
+		t.Errorf("peers created delta = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(metrics.ConfigGenerations) - generated; got != 1 {
+		t.Errorf("config generations delta = %v, want 1", got)
+	}
+}
+
+func TestSessionHistory_IncludesPastSessionsAndRevocation(t *testing.T) {
+	svc, repo := newTestService()
+	repo.peers["laptop"] = &network.Peer{ID: "laptop", Name: "laptop", Address: "10.0.0.2", UseAgent: true}
+	ctx := context.Background()
+
+	if err := svc.ProcessAgentHeartbeat(ctx, "net-1", "laptop", &network.AgentHeartbeat{Hostname: "old-host"}); err != nil {
+		t.Fatalf("ProcessAgentHeartbeat: %v", err)
+	}
+	first, err := repo.GetSession(ctx, "net-1", "laptop")
+	if err != nil {
+		t.Fatalf("GetSession: %v", err)
+	}
+
+	revoked, err := svc.RevokeSession(ctx, "net-1", "laptop", first.SessionID)
+	if err != nil {
+		t.Fatalf("RevokeSession: %v", err)
+	}
+	if !revoked.IsRevoked() {
+		t.Fatal("revoked session should be marked invalid")
+	}
+
+	// The next heartbeat must not resume the revoked session.
+	if err := svc.ProcessAgentHeartbeat(ctx, "net-1", "laptop", &network.AgentHeartbeat{Hostname: "new-host"}); err != nil {
+		t.Fatalf("ProcessAgentHeartbeat: %v", err)
+	}
+	current, err := repo.GetSession(ctx, "net-1", "laptop")
+	if err != nil {
+		t.Fatalf("GetSession: %v", err)
+	}
+	if current.SessionID == first.SessionID || current.IsRevoked() {
+		t.Fatalf("heartbeat after revocation should start a new session, got %+v", current)
+	}
+
+	history, err := svc.ListSessionHistory(ctx, "net-1", "laptop")
+	if err != nil {
+		t.Fatalf("ListSessionHistory: %v", err)
+	}
+	if len(history) != 2 {
+		t.Fatalf("history should include both sessions, got %d", len(history))
+	}
+	if history[0].SessionID != current.SessionID || history[1].SessionID != first.SessionID {
+		t.Fatalf("history should be most recent first, got %s then %s", history[0].Hostname, history[1].Hostname)
+	}
+	if !history[1].IsRevoked() || history[0].IsRevoked() {
+		t.Fatal("only the first session should be revoked")
+	}
+
+	if _, err := svc.RevokeSession(ctx, "net-1", "laptop", "unknown"); !errors.Is(err, network.ErrSessionNotFound) {
+		t.Fatalf("revoking an unknown session: got %v, want ErrSessionNotFound", err)
+	}
+}
+
+func TestValidateNetworkDocument_ReportsRouteOverlapAndPolicyLockout(t *testing.T) {
+	svc := &Service{repo: newMockFullRepository()}
+	ctx := context.Background()
+
+	doc := &network.NetworkDocument{
+		Network: network.NetworkCreateRequest{Name: "corp", CIDR: "10.0.0.0/24"},
+		Peers: []network.PeerCreateRequest{
+			{Name: "jump-1", IsJump: true, Endpoint: "203.0.113.1"},
+			{Name: "jump-2", IsJump: true, Endpoint: "203.0.113.2"},
+			{Name: "laptop"},
+		},
+		Routes: []network.RouteCreateRequest{
+			{Name: "office", DestinationCIDR: "192.168.0.0/24", JumpPeerID: "jump-1"},
+		},
+		Policies: []network.PolicyCreateRequest{
+			{Name: "web", Rules: []network.PolicyRule{{Direction: "output", Action: "allow", Target: "192.168.0.0/24", TargetType: "cidr"}}},
+		},
+	}
+
+	report, err := svc.ValidateNetworkDocument(ctx, doc)
+	if err != nil {
+		t.Fatalf("ValidateNetworkDocument: %v", err)
+	}
+	if !report.Valid || len(report.Issues) != 0 {
+		t.Fatalf("expected a clean document to be valid, got %+v", report)
+	}
+
+	doc.Routes = append(doc.Routes, network.RouteCreateRequest{Name: "office-backup", DestinationCIDR: "192.168.0.0/24", JumpPeerID: "jump-2"})
+	doc.Policies = append(doc.Policies, network.PolicyCreateRequest{
+		Name:  "lockdown",
+		Rules: []network.PolicyRule{{Direction: "output", Action: "deny", Target: "10.0.0.0/16", TargetType: "cidr"}},
+	})
+
+	report, err = svc.ValidateNetworkDocument(ctx, doc)
+	if err != nil {
+		t.Fatalf("ValidateNetworkDocument: %v", err)
+	}
+	if report.Valid {
+		t.Fatal("expected the document to be invalid")
+	}
+
+	var overlap, lockout bool
+	for _, issue := range report.Issues {
+		if issue.Severity != network.SeverityError {
+			continue
+		}
+		switch {
+		case issue.Path == "routes[1]" && strings.Contains(issue.Message, network.ErrRouteConflict.Error()):
+			overlap = true
+		case issue.Path == "policies[1].rules[0]" && strings.Contains(issue.Message, "locked out"):
+			lockout = true
+		}
+	}
+	if !overlap || !lockout {
+		t.Fatalf("expected both the overlapping route and the policy lockout to be reported, got %+v", report.Issues)
+	}
+}
+
+func TestValidateNetworkDocument_ReportsUnknownNames(t *testing.T) {
+	svc := &Service{repo: newMockFullRepository()}
+
+	doc := &network.NetworkDocument{
+		Network: network.NetworkCreateRequest{Name: "corp", CIDR: "10.0.0.0/24"},
+		Peers:   []network.PeerCreateRequest{{Name: "laptop"}},
+		Groups: []network.GroupDocument{
+			{GroupCreateRequest: network.GroupCreateRequest{Name: "staff"}, Peers: []string{"laptop", "desktop"}, Policies: []string{"web"}},
+		},
+		Policies: []network.PolicyCreateRequest{
+			{Name: "web", Rules: []network.PolicyRule{
+				{Direction: "input", Action: "allow", Target: "staff", TargetType: "group"},
+				{Direction: "input", Action: "allow", Target: "printer", TargetType: "peer"},
+			}},
+		},
+	}
+
+	report, err := svc.ValidateNetworkDocument(context.Background(), doc)
+	if err != nil {
+		t.Fatalf("ValidateNetworkDocument: %v", err)
+	}
+	var paths []string
+	for _, issue := range report.Issues {
+		if issue.Severity == network.SeverityError {
+			paths = append(paths, issue.Path)
+		}
+	}
+	if got, want := strings.Join(paths, " "), "groups[0].peers[1] policies[0].rules[1]"; got != want {
+		t.Fatalf("issues at %q, want %q: %+v", got, want, report.Issues)
+	}
+}
+
+type recordingIncidentNotifier struct {
+	incidents []*network.SecurityIncident
+}
+
+func (r *recordingIncidentNotifier) NotifyIncident(incident *network.SecurityIncident) {
+	r.incidents = append(r.incidents, incident)
+}
+
+func TestEndpointTakeover_RaisesSecurityIncident(t *testing.T) {
+	repo := newMockFullRepository()
+	notifier := &recordingIncidentNotifier{}
+	svc := &Service{repo: repo}
+	svc.SetIncidentNotifier(notifier)
+
+	before := testutil.ToFloat64(metrics.SecurityIncidents.WithLabelValues(metrics.IncidentEndpointTakeover))
+	err := svc.processEndpointTakeovers(context.Background(), "net-1", "jump-1", []network.EndpointTakeoverReport{
+		{WgIP: "10.0.0.5", AuthenticatedAt: "203.0.113.5:51820", ObservedAt: "198.51.100.7:40000"},
+		{WgIP: "10.0.0.6", ObservedAt: "not-an-endpoint"}, // ignored
+	})
+	if err != nil {
+		t.Fatalf("processEndpointTakeovers: %v", err)
+	}
+
+	if len(notifier.incidents) != 1 {
+		t.Fatalf("expected 1 incident, got %d", len(notifier.incidents))
+	}
+	inc := notifier.incidents[0]
+	if inc.ID == "" || inc.DetectedAt.IsZero() {
+		t.Errorf("incident ID and detection time must be set: %+v", inc)
+	}
+	if inc.IncidentType != metrics.IncidentEndpointTakeover || inc.NetworkID != "net-1" || inc.JumpPeerID != "jump-1" || inc.WgIP != "10.0.0.5" {
+		t.Errorf("unexpected incident %+v", inc)
+	}
+	if got := testutil.ToFloat64(metrics.SecurityIncidents.WithLabelValues(metrics.IncidentEndpointTakeover)) - before; got != 1 {
+		t.Errorf("security incidents delta = %v, want 1", got)
+	}
+}
+
+func TestPeerStats_AccumulatesTransferAcrossCounterResets(t *testing.T) {
+	svc, repo := newTestService()
+	repo.peers["jump-1"] = &network.Peer{ID: "jump-1", Name: "jump", PublicKey: "jump-pub", Address: "10.0.0.1", IsJump: true, UseAgent: true}
+	repo.peers["phone"] = &network.Peer{ID: "phone", Name: "phone", PublicKey: "phone-pub", Address: "10.0.0.2"}
+	svc.wgLastSeen = make(map[string]time.Time)
+	ctx := context.Background()
+
+	handshake := time.Now().Add(-time.Minute).Unix()
+	report := func(rx, tx int64) {
+		t.Helper()
+		heartbeat := &network.AgentHeartbeat{
+			Hostname:       "jump",
+			PeerHandshakes: map[string]int64{"phone-pub": handshake},
+			PeerTransfer:   map[string]network.PeerTransfer{"phone-pub": {RxBytes: rx, TxBytes: tx}},
+		}
+		if err := svc.ProcessAgentHeartbeat(ctx, "net-1", "jump-1", heartbeat); err != nil {
+			t.Fatalf("ProcessAgentHeartbeat: %v", err)
+		}
+	}
+
+	report(1000, 5000)
+	report(1500, 9000)
+	// The jump agent restarted: its counters start over from zero.
+	report(200, 100)
+
+	stats, err := svc.GetPeerStats(ctx, "net-1", "phone")
+	if err != nil {
+		t.Fatalf("GetPeerStats: %v", err)
+	}
+	// The jump peer's view is mirrored: what it sent, the phone received.
+	if stats.RxBytes != 9100 || stats.TxBytes != 1700 {
+		t.Errorf("stats rx/tx = %d/%d, want 9100/1700", stats.RxBytes, stats.TxBytes)
+	}
+	if stats.LastHandshake == nil || stats.LastHandshake.Unix() != handshake {
+		t.Errorf("last handshake = %v, want %v", stats.LastHandshake, time.Unix(handshake, 0))
+	}
+
+	session, err := repo.GetSession(ctx, "net-1", "jump-1")
+	if err != nil {
+		t.Fatalf("GetSession: %v", err)
+	}
+	if got := session.PeerTransfer["phone-pub"]; got == nil || got.Counters.RxBytes != 200 {
+		t.Errorf("session should keep the latest raw counters, got %+v", got)
+	}
+}
+
+func TestPeerStatus_FromHandshakeAge(t *testing.T) {
+	svc, repo := newTestService()
+	repo.peers["jump-1"] = &network.Peer{ID: "jump-1", Name: "jump", PublicKey: "jump-pub", Address: "10.0.0.1", IsJump: true, UseAgent: true}
+	repo.peers["phone"] = &network.Peer{ID: "phone", Name: "phone", PublicKey: "phone-pub", Address: "10.0.0.2"}
+	repo.peers["laptop"] = &network.Peer{ID: "laptop", Name: "laptop", PublicKey: "laptop-pub", Address: "10.0.0.3"}
+	repo.peers["tablet"] = &network.Peer{ID: "tablet", Name: "tablet", PublicKey: "tablet-pub", Address: "10.0.0.4"}
+	svc.wgLastSeen = make(map[string]time.Time)
+	svc.SetPeerStaleThreshold(5 * time.Minute)
+	ctx := context.Background()
+
+	now := time.Now()
+	heartbeat := &network.AgentHeartbeat{
+		Hostname: "jump",
+		PeerHandshakes: map[string]int64{
+			"phone-pub":  now.Add(-time.Minute).Unix(),
+			"laptop-pub": now.Add(-time.Hour).Unix(),
+		},
+	}
+	if err := svc.ProcessAgentHeartbeat(ctx, "net-1", "jump-1", heartbeat); err != nil {
+		t.Fatalf("ProcessAgentHeartbeat: %v", err)
+	}
+	// A later heartbeat without the laptop must not forget its handshake.
+	heartbeat.PeerHandshakes = map[string]int64{"phone-pub": now.Unix()}
+	if err := svc.ProcessAgentHeartbeat(ctx, "net-1", "jump-1", heartbeat); err != nil {
+		t.Fatalf("ProcessAgentHeartbeat: %v", err)
+	}
+
+	statuses, err := svc.PeerStatuses(ctx, "net-1")
+	if err != nil {
+		t.Fatalf("PeerStatuses: %v", err)
+	}
+	want := map[string]string{
+		"jump-1": network.PeerStatusOnline, // the reporting end of the tunnels
+		"phone":  network.PeerStatusOnline,
+		"laptop": network.PeerStatusStale,
+		"tablet": network.PeerStatusOffline, // never handshook
+	}
+	for id, status := range want {
+		if statuses[id] != status {
+			t.Errorf("status of %s = %q, want %q", id, statuses[id], status)
+		}
+	}
+
+	status, err := svc.GetPeerConnectivityStatus(ctx, "net-1", "laptop")
+	if err != nil {
+		t.Fatalf("GetPeerConnectivityStatus: %v", err)
+	}
+	if status.Status != network.PeerStatusStale || status.LastHandshake == nil {
+		t.Errorf("laptop connectivity status = %q (handshake %v), want stale", status.Status, status.LastHandshake)
+	}
+}
+
+func TestPeerLatency_ReportedByJumpAppearsInStatus(t *testing.T) {
+	svc, repo := newTestService()
+	repo.peers["jump-1"] = &network.Peer{ID: "jump-1", Name: "jump", PublicKey: "jump-pub", Address: "10.0.0.1", IsJump: true, UseAgent: true}
+	repo.peers["phone"] = &network.Peer{ID: "phone", Name: "phone", PublicKey: "phone-pub", Address: "10.0.0.2"}
+	repo.peers["laptop"] = &network.Peer{ID: "laptop", Name: "laptop", PublicKey: "laptop-pub", Address: "10.0.0.3"}
+	repo.peers["tablet"] = &network.Peer{ID: "tablet", Name: "tablet", PublicKey: "tablet-pub", Address: "10.0.0.4"}
+	svc.wgLastSeen = make(map[string]time.Time)
+	ctx := context.Background()
+
+	heartbeat := &network.AgentHeartbeat{
+		Hostname:    "jump",
+		PeerLatency: map[string]float64{"phone-pub": 12.5, "laptop-pub": -1},
+	}
+	if err := svc.ProcessAgentHeartbeat(ctx, "net-1", "jump-1", heartbeat); err != nil {
+		t.Fatalf("ProcessAgentHeartbeat: %v", err)
+	}
+	// An agent that does not measure latency keeps the previous report.
+	if err := svc.ProcessAgentHeartbeat(ctx, "net-1", "jump-1", &network.AgentHeartbeat{Hostname: "jump"}); err != nil {
+		t.Fatalf("ProcessAgentHeartbeat: %v", err)
+	}
+
+	session, err := repo.GetSession(ctx, "net-1", "jump-1")
+	if err != nil {
+		t.Fatalf("GetSession: %v", err)
+	}
+	if l := session.PeerLatency["phone-pub"]; l.RTTMs == nil || *l.RTTMs != 12.5 {
+		t.Errorf("phone latency on the session = %v, want 12.5", l.RTTMs)
+	}
+	if l, ok := session.PeerLatency["laptop-pub"]; !ok || l.RTTMs != nil {
+		t.Errorf("unreachable laptop should be stored with an unknown latency, got %+v", l)
+	}
+
+	for id, rtt := range map[string]float64{"phone": 12.5, "laptop": -1, "tablet": -1} {
+		status, err := svc.GetPeerConnectivityStatus(ctx, "net-1", id)
+		if err != nil {
+			t.Fatalf("GetPeerConnectivityStatus(%s): %v", id, err)
+		}
+		switch {
+		case rtt < 0 && status.LatencyMs != nil:
+			t.Errorf("latency of %s = %v, want unknown", id, *status.LatencyMs)
+		case rtt >= 0 && (status.LatencyMs == nil || *status.LatencyMs != rtt):
+			t.Errorf("latency of %s = %v, want %v", id, status.LatencyMs, rtt)
+		}
+	}
+}
+
+func TestJumpHealth_RecordedAndReportedInStatus(t *testing.T) {
+	svc, repo := newTestService()
+	repo.peers["jump-1"] = &network.Peer{ID: "jump-1", Name: "jump", PublicKey: "jump-pub", Address: "10.0.0.1", IsJump: true, UseAgent: true}
+	svc.wgLastSeen = make(map[string]time.Time)
+	ctx := context.Background()
+
+	if err := svc.RecordJumpHealth(ctx, "net-1", "jump-1", &network.JumpHealthReport{ListenPortOK: true, InternetOK: true}); err == nil {
+		t.Fatal("recording a health check without an agent session should fail")
+	}
+
+	if err := svc.ProcessAgentHeartbeat(ctx, "net-1", "jump-1", &network.AgentHeartbeat{Hostname: "jump"}); err != nil {
+		t.Fatalf("ProcessAgentHeartbeat: %v", err)
+	}
+	status, err := svc.GetPeerConnectivityStatus(ctx, "net-1", "jump-1")
+	if err != nil {
+		t.Fatalf("GetPeerConnectivityStatus: %v", err)
+	}
+	if status.JumpHealthy || status.JumpHealthReason == "" {
+		t.Errorf("a jump without a health check result should be unhealthy with a reason, got %+v", status)
+	}
+
+	report := &network.JumpHealthReport{ListenPortOK: true, InternetOK: false, Reason: "no internet access via eth0"}
+	if err := svc.RecordJumpHealth(ctx, "net-1", "jump-1", report); err != nil {
+		t.Fatalf("RecordJumpHealth: %v", err)
+	}
+	// Heartbeats keep the latest result.
+	if err := svc.ProcessAgentHeartbeat(ctx, "net-1", "jump-1", &network.AgentHeartbeat{Hostname: "jump"}); err != nil {
+		t.Fatalf("ProcessAgentHeartbeat: %v", err)
+	}
+	status, err = svc.GetPeerConnectivityStatus(ctx, "net-1", "jump-1")
+	if err != nil {
+		t.Fatalf("GetPeerConnectivityStatus: %v", err)
+	}
+	if status.JumpHealthy || status.JumpHealthReason != report.Reason {
+		t.Errorf("failed check: got healthy=%v reason=%q, want the agent's reason", status.JumpHealthy, status.JumpHealthReason)
+	}
+
+	if err := svc.RecordJumpHealth(ctx, "net-1", "jump-1", &network.JumpHealthReport{ListenPortOK: true, InternetOK: true}); err != nil {
+		t.Fatalf("RecordJumpHealth: %v", err)
+	}
+	status, _ = svc.GetPeerConnectivityStatus(ctx, "net-1", "jump-1")
+	if !status.JumpHealthy || status.JumpHealthReason != "" {
+		t.Errorf("passed check: got healthy=%v reason=%q", status.JumpHealthy, status.JumpHealthReason)
+	}
+
+	session, _ := repo.GetSession(ctx, "net-1", "jump-1")
+	session.JumpHealth.CheckedAt = time.Now().Add(-jumpHealthMaxAge - time.Minute)
+	status, _ = svc.GetPeerConnectivityStatus(ctx, "net-1", "jump-1")
+	if status.JumpHealthy {
+		t.Error("a stale health check should not count as healthy")
+	}
+}
+
+func TestConfigAck_RecordedAndReportedInStatus(t *testing.T) {
+	svc, repo := newTestService()
+	for _, p := range []*network.Peer{
+		{ID: "jump-1", Name: "jump", PublicKey: "jump-pub", Address: "10.0.0.1", IsJump: true, ListenPort: 51820, Endpoint: "203.0.113.1"},
+		{ID: "laptop", Name: "laptop", PublicKey: "laptop-pub", Address: "10.0.0.2", UseAgent: true},
+	} {
+		repo.peers[p.ID] = p
+	}
+	repo.networks["net-1"].Peers = repo.peers
+	svc.wgLastSeen = make(map[string]time.Time)
+	ctx := context.Background()
+
+	status, err := svc.GetPeerConnectivityStatus(ctx, "net-1", "laptop")
+	if err != nil {
+		t.Fatalf("GetPeerConnectivityStatus: %v", err)
+	}
+	if status.ConfigInSync != nil {
+		t.Errorf("config_in_sync = %v before any ack, want null", *status.ConfigInSync)
+	}
+
+	cfg, _, _, err := svc.GeneratePeerConfigWithDNS(ctx, "net-1", "laptop")
+	if err != nil {
+		t.Fatalf("GeneratePeerConfigWithDNS: %v", err)
+	}
+	// The initial config is acknowledged before the first heartbeat.
+	if err := svc.RecordConfigAck(ctx, "net-1", "laptop", wireguard.ConfigHash(cfg)); err != nil {
+		t.Fatalf("RecordConfigAck: %v", err)
+	}
+	if err := svc.ProcessAgentHeartbeat(ctx, "net-1", "laptop", &network.AgentHeartbeat{Hostname: "laptop"}); err != nil {
+		t.Fatalf("ProcessAgentHeartbeat: %v", err)
+	}
+	status, _ = svc.GetPeerConnectivityStatus(ctx, "net-1", "laptop")
+	if status.ConfigInSync == nil || !*status.ConfigInSync {
+		t.Errorf("config_in_sync = %v after acking the current config, want true", status.ConfigInSync)
+	}
+	if s := status.CurrentSession; s.LastAppliedConfigHash != wireguard.ConfigHash(cfg) || s.LastAppliedAt == nil || s.Hostname != "laptop" {
+		t.Errorf("session = %+v, want the acked hash kept across heartbeats", s)
+	}
+
+	repo.peers["jump-1"].Endpoint = "203.0.113.2"
+	status, _ = svc.GetPeerConnectivityStatus(ctx, "net-1", "laptop")
+	if status.ConfigInSync == nil || *status.ConfigInSync {
+		t.Errorf("config_in_sync = %v after the config changed, want false", status.ConfigInSync)
+	}
+}
+
+func TestPlanCIDRChange_MatchesAppliedUpdate(t *testing.T) {
+	svc, repo := newTestService()
+	repo.peers["jump"] = &network.Peer{ID: "jump", Name: "jump", Address: "10.0.0.1", IsJump: true}
+	repo.peers["laptop"] = &network.Peer{ID: "laptop", Name: "laptop", Address: "10.0.0.20", UseAgent: true}
+	repo.peers["server"] = &network.Peer{ID: "server", Name: "server", Address: "10.0.0.9", UseAgent: true}
+	ctx := context.Background()
+
+	plan, err := svc.PlanCIDRChange(ctx, "net-1", "10.9.0.0/16")
+	if err != nil {
+		t.Fatalf("PlanCIDRChange: %v", err)
+	}
+	if len(plan.Blockers) != 0 {
+		t.Fatalf("expected no blockers, got %+v", plan.Blockers)
+	}
+	want := map[string]string{"jump": "10.9.0.1", "server": "10.9.0.2", "laptop": "10.9.0.3"}
+	for _, r := range plan.Reassignments {
+		if r.NewAddress != want[r.PeerID] {
+			t.Errorf("%s planned %s -> %s, want %s", r.PeerID, r.OldAddress, r.NewAddress, want[r.PeerID])
+		}
+	}
+	if repo.peers["laptop"].Address != "10.0.0.20" || repo.networks["net-1"].CIDR != "10.0.0.0/24" {
+		t.Fatal("planning must not change anything")
+	}
+
+	if _, err := svc.UpdateNetwork(ctx, "net-1", &network.NetworkUpdateRequest{CIDR: "10.9.0.0/16"}); err != nil {
+		t.Fatalf("UpdateNetwork: %v", err)
+	}
+	for id, addr := range want {
+		if got := repo.peers[id].Address; got != addr {
+			t.Errorf("%s moved to %s, plan said %s", id, got, addr)
+		}
+	}
+
+	if _, err := svc.PlanCIDRChange(ctx, "net-1", "10.10.0.0/31"); !errors.Is(err, network.ErrInvalidCIDR) {
+		t.Fatalf("expected ErrInvalidCIDR for a CIDR too small for the peers, got %v", err)
+	}
+}
+
+func TestPlanCIDRChange_ReportsStaticPeers(t *testing.T) {
+	svc, repo := newTestService()
+	repo.peers["printer"] = &network.Peer{ID: "printer", Name: "printer", Address: "10.0.0.5"}
+	ctx := context.Background()
+
+	plan, err := svc.PlanCIDRChange(ctx, "net-1", "10.9.0.0/24")
+	if err != nil {
+		t.Fatalf("PlanCIDRChange: %v", err)
+	}
+	if len(plan.Blockers) != 1 || plan.Blockers[0].PeerID != "printer" {
+		t.Fatalf("expected printer to block the change, got %+v", plan.Blockers)
+	}
+
+	if _, err := svc.UpdateNetwork(ctx, "net-1", &network.NetworkUpdateRequest{CIDR: "10.9.0.0/24"}); err == nil || !strings.Contains(err.Error(), "static regular peer 'printer'") {
+		t.Fatalf("expected the static peer to block the update, got %v", err)
+	}
+	if repo.peers["printer"].Address != "10.0.0.5" {
+		t.Fatal("a blocked change must not re-address peers")
+	}
+}
+
+func TestAddPeer_StaticAddress(t *testing.T) {
+	svc, repo := newTestService()
+	ctx := context.Background()
+
+	peer, err := svc.AddPeer(ctx, "net-1", &network.PeerCreateRequest{Name: "nas", Address: "10.0.0.200"}, "")
+	if err != nil {
+		t.Fatalf("AddPeer with a free address: %v", err)
+	}
+	if peer.Address != "10.0.0.200" {
+		t.Fatalf("peer got %s, want the requested 10.0.0.200", peer.Address)
+	}
+
+	_, err = svc.AddPeer(ctx, "net-1", &network.PeerCreateRequest{Name: "nas-2", Address: "10.0.0.200"}, "")
+	if !errors.Is(err, network.ErrIPAllocated) {
+		t.Fatalf("expected ErrIPAllocated for a taken address, got %v", err)
+	}
+
+	for _, address := range []string{"10.0.1.5", "10.0.0.0", "10.0.0.255", "fd00::5", "nas"} {
+		_, err := svc.AddPeer(ctx, "net-1", &network.PeerCreateRequest{Name: "bad", Address: address}, "")
+		if !errors.Is(err, network.ErrIPNotInNetwork) && !errors.Is(err, network.ErrInvalidIP) {
+			t.Errorf("Address %q: expected a validation error, got %v", address, err)
+		}
+	}
+	if len(repo.ipam.specific) != 1 {
+		t.Fatalf("rejected addresses must not reach IPAM, got %v", repo.ipam.specific)
+	}
+
+	peer, err = svc.AddPeer(ctx, "net-1", &network.PeerCreateRequest{Name: "laptop"}, "")
+	if err != nil || peer.Address != "10.0.0.10" {
+		t.Fatalf("without an address the next free one is used, got %v, %v", peer, err)
+	}
+}
+
+func TestPeerExpiry_QuarantinesExpiredPeers(t *testing.T) {
+	svc := newRouteConflictTestService()
+	repo := svc.repo.(*mockFullRepository)
+	for id, p := range repo.networks["net-1"].Peers {
+		repo.peers[id] = p
+	}
+	notifier := &recordingNotifier{}
+	svc.wsNotifier = notifier
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	svc.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	expiresAt := now.Add(time.Hour)
+	repo.peers["laptop"].ExpiresAt = &expiresAt
+	repo.peers["laptop"].AddressV6 = "fd00::a"
+
+	quarantined := func() []string {
+		t.Helper()
+		state, err := svc.GetCaptivePortalSecurityState(ctx, "net-1", "jump-1")
+		if err != nil {
+			t.Fatalf("GetCaptivePortalSecurityState: %v", err)
+		}
+		return state.Quarantined
+	}
+
+	if q := quarantined(); len(q) != 0 {
+		t.Fatalf("peer quarantined before expiry: %v", q)
+	}
+	if err := svc.CleanupExpiredPeers(ctx); err != nil {
+		t.Fatalf("CleanupExpiredPeers: %v", err)
+	}
+	if len(notifier.notified) != 0 {
+		t.Fatalf("notified before expiry: %v", notifier.notified)
+	}
+
+	now = now.Add(2 * time.Hour)
+	if q := quarantined(); len(q) != 2 || q[0] != "10.0.0.10" || q[1] != "fd00::a" {
+		t.Fatalf("expected both addresses of the expired laptop to be quarantined, got %v", q)
+	}
+	for i := 0; i < 2; i++ {
+		if err := svc.CleanupExpiredPeers(ctx); err != nil {
+			t.Fatalf("CleanupExpiredPeers: %v", err)
+		}
+	}
+	if len(notifier.notified) != 1 || notifier.notified[0] != "net-1" {
+		t.Fatalf("expected a single notification for net-1, got %v", notifier.notified)
+	}
+	if _, ok := repo.peers["laptop"]; !ok {
+		t.Fatal("expired peer was deleted")
+	}
+
+	// Extending the expiry restores the peer
+	extended := now.Add(time.Hour)
+	if _, err := svc.UpdatePeer(ctx, "net-1", "laptop", &network.PeerUpdateRequest{ExpiresAt: &extended}); err != nil {
+		t.Fatalf("UpdatePeer: %v", err)
+	}
+	if q := quarantined(); len(q) != 0 {
+		t.Fatalf("peer still quarantined after extension: %v", q)
+	}
+}
+
+func TestAddPeer_RejectsPastExpiry(t *testing.T) {
+	svc, _ := newTestService()
+	past := time.Now().Add(-time.Minute)
+
+	_, err := svc.AddPeer(context.Background(), "net-1", &network.PeerCreateRequest{Name: "contractor", ExpiresAt: &past}, "")
+	if !errors.Is(err, network.ErrPeerExpiryInPast) {
+		t.Fatalf("expected ErrPeerExpiryInPast, got %v", err)
+	}
+}
+
+func TestEnrollmentToken_SingleUseAndExpiry(t *testing.T) {
+	svc, repo := newTestService()
+	expiry := time.Unix(10000, 0)
+	repo.peers["laptop"] = &network.Peer{ID: "laptop", Name: "laptop", Address: "10.0.0.2", Token: "once", TokenSingleUse: true}
+	repo.peers["server"] = &network.Peer{ID: "server", Name: "server", Address: "10.0.0.3", Token: "reusable"}
+	repo.peers["kiosk"] = &network.Peer{ID: "kiosk", Name: "kiosk", Address: "10.0.0.4", Token: "late", TokenExpiresAt: &expiry}
+	now := expiry.Add(-time.Minute)
+	svc.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	// Reusable tokens keep resolving
+	for i := 0; i < 2; i++ {
+		if _, _, err := svc.ResolveAgentToken(ctx, "reusable"); err != nil {
+			t.Fatalf("reusable token, resolve %d: %v", i, err)
+		}
+	}
+
+	if _, peer, err := svc.ResolveAgentToken(ctx, "once"); err != nil || peer.ID != "laptop" {
+		t.Fatalf("single-use token, first resolve: %v", err)
+	}
+	if _, _, err := svc.ResolveAgentToken(ctx, "once"); !errors.Is(err, network.ErrTokenConsumed) {
+		t.Errorf("single-use token, second resolve: got %v, want ErrTokenConsumed", err)
+	}
+	if _, _, err := svc.AuthenticateAgentToken(ctx, "once"); err != nil {
+		t.Errorf("the enrolled agent should still authenticate with its token: %v", err)
+	}
+
+	// Expired before enrollment: rejected everywhere, then revoked by the sweep
+	now = expiry
+	if _, _, err := svc.ResolveAgentToken(ctx, "late"); !errors.Is(err, network.ErrTokenExpired) {
+		t.Errorf("expired token: got %v, want ErrTokenExpired", err)
+	}
+	if _, _, err := svc.AuthenticateAgentToken(ctx, "late"); !errors.Is(err, network.ErrTokenExpired) {
+		t.Errorf("expired token that never enrolled: got %v, want ErrTokenExpired", err)
+	}
+	repo.peers["laptop"].TokenExpiresAt = &expiry
+	if err := svc.CleanupExpiredEnrollmentTokens(ctx); err != nil {
+		t.Fatalf("CleanupExpiredEnrollmentTokens: %v", err)
+	}
+	if repo.peers["kiosk"].Token != "" {
+		t.Error("the sweep should revoke an expired token nobody enrolled with")
+	}
+	if repo.peers["laptop"].Token != "once" || repo.peers["server"].Token != "reusable" {
+		t.Error("the sweep should keep tokens of enrolled agents and tokens without expiry")
+	}
+	if _, _, err := svc.AuthenticateAgentToken(ctx, ""); err == nil {
+		t.Error("an empty token must not match a revoked one")
+	}
+
+	// Rotation gives a fresh single-use token
+	token, err := svc.RegeneratePeerToken(ctx, "net-1", "laptop")
+	if err != nil {
+		t.Fatalf("RegeneratePeerToken: %v", err)
+	}
+	repo.peers["laptop"].TokenExpiresAt = nil
+	if _, _, err := svc.ResolveAgentToken(ctx, token); err != nil {
+		t.Errorf("rotated single-use token: %v", err)
+	}
+}
+
+// countingRepository counts the repository calls config generation makes
+type countingRepository struct {
+	FullRepository
+	calls int
+}
+
+func (r *countingRepository) GetNetwork(ctx context.Context, networkID string) (*network.Network, error) {
+	r.calls++
+	return r.FullRepository.GetNetwork(ctx, networkID)
+}
+
+func (r *countingRepository) GetConnection(ctx context.Context, networkID, peer1ID, peer2ID string) (*network.PeerConnection, error) {
+	r.calls++
+	return r.FullRepository.GetConnection(ctx, networkID, peer1ID, peer2ID)
+}
+
+func (r *countingRepository) GetSession(ctx context.Context, networkID, peerID string) (*network.AgentSession, error) {
+	r.calls++
+	return r.FullRepository.GetSession(ctx, networkID, peerID)
+}
+
+func TestGeneratePeerConfigWithDNS_ServedFromCacheUntilInvalidated(t *testing.T) {
+	svc := newRouteConflictTestService()
+	mock := svc.repo.(*mockFullRepository)
+	repo := &countingRepository{FullRepository: mock}
+	svc.repo = repo
+	svc.SetConfigCacheSize(8)
+	ctx := context.Background()
+	hits := testutil.ToFloat64(metrics.ConfigCacheLookups.WithLabelValues("hit"))
+
+	first, _, _, err := svc.GeneratePeerConfigWithDNS(ctx, "net-1", "laptop")
+	if err != nil {
+		t.Fatalf("GeneratePeerConfigWithDNS returned error: %v", err)
+	}
+	missCalls := repo.calls
+	if missCalls == 0 {
+		t.Fatal("first generation made no repository call")
+	}
+
+	for i := 0; i < 10; i++ {
+		config, _, _, err := svc.GeneratePeerConfigWithDNS(ctx, "net-1", "laptop")
+		if err != nil {
+			t.Fatalf("GeneratePeerConfigWithDNS returned error: %v", err)
+		}
+		if config != first {
+			t.Fatalf("cached config differs:\n%s\nvs\n%s", first, config)
+		}
+	}
+	if repo.calls != missCalls {
+		t.Errorf("cache hits made %d repository calls, want 0", repo.calls-missCalls)
+	}
+	if got := testutil.ToFloat64(metrics.ConfigCacheLookups.WithLabelValues("hit")) - hits; got != 10 {
+		t.Errorf("cache hits = %v, want 10", got)
+	}
+
+	// A change is only picked up once the network version is bumped
+	mock.networks["net-1"].Peers["jump-1"].Endpoint = "198.51.100.1"
+	if config, _, _, _ := svc.GeneratePeerConfigWithDNS(ctx, "net-1", "laptop"); config != first {
+		t.Error("config changed without invalidation")
+	}
+	svc.InvalidateConfigs("net-1")
+	config, _, _, err := svc.GeneratePeerConfigWithDNS(ctx, "net-1", "laptop")
+	if err != nil {
+		t.Fatalf("GeneratePeerConfigWithDNS returned error: %v", err)
+	}
+	if !strings.Contains(config, "Endpoint = 198.51.100.1:51820") {
+		t.Errorf("config after invalidation misses the new endpoint:\n%s", config)
+	}
+	if repo.calls != 2*missCalls {
+		t.Errorf("regeneration made %d repository calls, want %d", repo.calls-missCalls, missCalls)
+	}
+}
+
+func TestConfigCache_BoundedAndVersioned(t *testing.T) {
+	c := newConfigCache(2)
+	for _, peerID := range []string{"a", "b", "c"} {
+		c.put(&cachedConfig{key: configCacheKey{"net-1", peerID, 0}, config: peerID})
+	}
+	if c.lru.Len() != 2 {
+		t.Fatalf("cache holds %d entries, want 2", c.lru.Len())
+	}
+	if _, ok := c.get("net-1", "a", 0); ok {
+		t.Error("least recently used entry was not evicted")
+	}
+
+	c.put(&cachedConfig{key: configCacheKey{"net-2", "a", 0}, config: "other"})
+	c.invalidate("net-1")
+	if _, ok := c.get("net-1", "c", 0); ok {
+		t.Error("entry of an invalidated network is still served")
+	}
+	if _, ok := c.get("net-2", "a", 0); !ok {
+		t.Error("invalidation dropped another network's entry")
+	}
+
+	// A config rendered before the bump must not be stored
+	c.put(&cachedConfig{key: configCacheKey{"net-1", "c", 0}, config: "stale"})
+	if _, ok := c.get("net-1", "c", c.version("net-1")); ok {
+		t.Error("stale config stored under the new version")
+	}
+	if _, ok := c.entries[configCacheKey{"net-1", "c", 0}]; ok {
+		t.Error("stale config stored under its old version")
+	}
+}
+
+func TestGeneratePeerConfigWithDNS_DNSOnlyPeerOnlyInDNS(t *testing.T) {
+	jump := &network.Peer{ID: "jump", Name: "jump", PublicKey: "pk-jump", Address: "10.0.0.1", IsJump: true, Endpoint: "203.0.113.1", ListenPort: 51820}
+	laptop := &network.Peer{ID: "laptop", Name: "laptop", PublicKey: "pk-laptop", Address: "10.0.0.10"}
+	resolver := &network.Peer{ID: "resolver", Name: "resolver", PublicKey: "pk-resolver", Address: "10.0.0.53", DNSOnly: true}
+	repo := newMockFullRepository()
+	repo.networks["net-1"] = &network.Network{
+		ID:    "net-1",
+		Name:  "office",
+		CIDR:  "10.0.0.0/24",
+		Peers: map[string]*network.Peer{jump.ID: jump, laptop.ID: laptop, resolver.ID: resolver},
+	}
+	svc := &Service{repo: repo}
+	ctx := context.Background()
+
+	for _, peerID := range []string{"jump", "laptop"} {
+		config, _, _, err := svc.GeneratePeerConfigWithDNS(ctx, "net-1", peerID)
+		if err != nil {
+			t.Fatalf("GeneratePeerConfigWithDNS(%s): %v", peerID, err)
+		}
+		if strings.Contains(config, "pk-resolver") || peerSection(config, "resolver") != "" {
+			t.Errorf("%s config lists the dns-only peer:\n%s", peerID, config)
+		}
+	}
+	config, _, _, err := svc.GeneratePeerConfigWithDNS(ctx, "net-1", "resolver")
+	if err != nil {
+		t.Fatalf("GeneratePeerConfigWithDNS(resolver): %v", err)
+	}
+	if !strings.Contains(config, "Address = 10.0.0.53") || strings.Contains(config, "[Peer]") {
+		t.Errorf("dns-only peer config should keep its address and list no peer:\n%s", config)
+	}
+
+	_, dns, _, err := svc.GeneratePeerConfigWithDNS(ctx, "net-1", "jump")
+	if err != nil {
+		t.Fatalf("GeneratePeerConfigWithDNS(jump): %v", err)
+	}
+	found := false
+	for _, record := range dns.Peers {
+		if record.Name == "resolver" && record.IP == "10.0.0.53" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("jump DNS config misses the dns-only peer: %+v", dns.Peers)
+	}
+}
+
+func TestSetConnectionAllowedIPs_OverridesOneSection(t *testing.T) {
+	svc := newRouteConflictTestService()
+	repo := svc.repo.(*mockFullRepository)
+	repo.connections = []*network.PeerConnection{
+		{Peer1ID: "jump-1", Peer2ID: "laptop", PresharedKey: "psk-1"},
+		{Peer1ID: "jump-2", Peer2ID: "laptop", PresharedKey: "psk-2"},
+	}
+	ctx := context.Background()
+
+	got, err := svc.SetConnectionAllowedIPs(ctx, "net-1", "laptop", "jump-1", []string{"10.0.0.1", "172.20.0.0/16"})
+	if err != nil {
+		t.Fatalf("SetConnectionAllowedIPs: %v", err)
+	}
+	if strings.Join(got, ",") != "10.0.0.1/32,172.20.0.0/16" {
+		t.Errorf("allowed IPs = %v, want the normalized CIDRs", got)
+	}
+
+	laptopConfig, err := svc.GeneratePeerConfig(ctx, "net-1", "laptop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	section := peerSection(laptopConfig, "jump-1")
+	if !strings.Contains(section, "AllowedIPs = 10.0.0.1/32, 172.20.0.0/16\n") || !strings.Contains(section, "PresharedKey = psk-1\n") {
+		t.Errorf("jump-1 section should carry the override and keep its preshared key:\n%s", section)
+	}
+	if section := peerSection(laptopConfig, "jump-2"); !strings.Contains(section, "10.50.0.0/16") {
+		t.Errorf("jump-2 section should keep its default AllowedIPs:\n%s", section)
+	}
+	jumpConfig, err := svc.GeneratePeerConfig(ctx, "net-1", "jump-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if section := peerSection(jumpConfig, "laptop"); strings.Contains(section, "172.20.0.0/16") {
+		t.Errorf("the reverse section should keep its defaults:\n%s", section)
+	}
+
+	// An empty list restores the defaults
+	if _, err := svc.SetConnectionAllowedIPs(ctx, "net-1", "laptop", "jump-1", nil); err != nil {
+		t.Fatal(err)
+	}
+	if laptopConfig, _ = svc.GeneratePeerConfig(ctx, "net-1", "laptop"); strings.Contains(laptopConfig, "172.20.0.0/16") {
+		t.Errorf("override should be removed:\n%s", laptopConfig)
+	}
+
+	if _, err := svc.SetConnectionAllowedIPs(ctx, "net-1", "laptop", "jump-1", []string{"10.0.0.0/33"}); !errors.Is(err, validation.ErrInvalidAllowedIP) {
+		t.Errorf("invalid CIDR: err = %v, want ErrInvalidAllowedIP", err)
+	}
+	// Regular peers only connect to the jump peers
+	repo.networks["net-1"].Peers["phone"] = &network.Peer{ID: "phone", Name: "phone", PublicKey: "pk-phone", Address: "10.0.0.11"}
+	if _, err := svc.SetConnectionAllowedIPs(ctx, "net-1", "laptop", "phone", []string{"10.0.0.11"}); !errors.Is(err, network.ErrPeersNotConnected) {
+		t.Errorf("unconnected pair: err = %v, want ErrPeersNotConnected", err)
+	}
+}
+
+func TestCheckEndpoint(t *testing.T) {
+	svc := &Service{lookupHost: func(_ context.Context, host string) ([]string, error) {
+		if host == "vpn.example.com" {
+			return []string{"203.0.113.10"}, nil
+		}
+		return nil, errors.New("no such host")
+	}}
+	ctx := context.Background()
+
+	check := svc.CheckEndpoint(ctx, "vpn.example.com")
+	if check.Warning != "" || len(check.Addresses) != 1 || check.Addresses[0] != "203.0.113.10" {
+		t.Errorf("resolvable host: got %+v", check)
+	}
+
+	check = svc.CheckEndpoint(ctx, "[2001:db8::1]:51820")
+	if check.Host != "2001:db8::1" || check.Warning != "" || len(check.Addresses) != 1 {
+		t.Errorf("IPv6 literal: got %+v", check)
+	}
+
+	check = svc.CheckEndpoint(ctx, "gone.example.com")
+	if check.Warning == "" || len(check.Addresses) != 0 {
+		t.Errorf("unresolvable host should only warn, got %+v", check)
+	}
+}
+
+func TestGetPeerDNSQueries_OptIn(t *testing.T) {
+	svc := newRouteConflictTestService()
+	repo := svc.repo.(*mockFullRepository)
+	for id, p := range repo.networks["net-1"].Peers {
+		repo.peers[id] = p
+	}
+	ctx := context.Background()
+	heartbeat := func() {
+		t.Helper()
+		hb := &network.AgentHeartbeat{Hostname: "jump-1", DNSQueries: []network.DNSQuery{
+			{Name: "laptop.test-network.internal", Type: "A", ClientIP: "10.0.0.10", Timestamp: time.Now()},
+		}}
+		if err := svc.ProcessAgentHeartbeat(ctx, "net-1", "jump-1", hb); err != nil {
+			t.Fatalf("ProcessAgentHeartbeat: %v", err)
+		}
+	}
+
+	heartbeat()
+	queryLog, err := svc.GetPeerDNSQueries(ctx, "net-1", "jump-1")
+	if err != nil {
+		t.Fatalf("GetPeerDNSQueries: %v", err)
+	}
+	if queryLog.Enabled || len(queryLog.Queries) != 0 {
+		t.Fatalf("queries kept while logging is off: %+v", queryLog)
+	}
+
+	repo.networks["net-1"].DNSQueryLog = true
+	heartbeat()
+	heartbeat()
+	queryLog, err = svc.GetPeerDNSQueries(ctx, "net-1", "jump-1")
+	if err != nil {
+		t.Fatalf("GetPeerDNSQueries: %v", err)
+	}
+	if !queryLog.Enabled || len(queryLog.Queries) != 2 || queryLog.Queries[0].ClientIP != "10.0.0.10" {
+		t.Fatalf("expected the queries of both heartbeats, got %+v", queryLog)
+	}
+
+	if _, err := svc.GetPeerDNSQueries(ctx, "net-1", "laptop"); !errors.Is(err, network.ErrNotJumpPeer) {
+		t.Errorf("regular peer: err = %v, want ErrNotJumpPeer", err)
+	}
+}
+
+func TestRecordDNSQueries_KeepsLatestWindow(t *testing.T) {
+	existing := &network.AgentSession{DNSQueries: make([]network.DNSQuery, network.MaxDNSQueries)}
+	heartbeat := &network.AgentHeartbeat{DNSQueries: []network.DNSQuery{{Name: "newest"}}}
+
+	got := recordDNSQueries(existing, heartbeat)
+	if len(got) != network.MaxDNSQueries || got[len(got)-1].Name != "newest" {
+		t.Fatalf("expected %d queries ending with the newest, got %d", network.MaxDNSQueries, len(got))
+	}
+}
+
+// blockingRecorderRepository records the denylist entries and quarantines
+// the security detections create.
+type blockingRecorderRepository struct {
+	*mockFullRepository
+	denylisted int
+	quarantine *network.CaptivePortalQuarantine
+}
+
+func (r *blockingRecorderRepository) AddEndpointDenylist(ctx context.Context, e *network.EndpointDenylistEntry) error {
+	r.denylisted++
+	return nil
+}
+
+func (r *blockingRecorderRepository) GetQuarantine(ctx context.Context, networkID, peerID string) (*network.CaptivePortalQuarantine, error) {
+	return r.quarantine, nil
+}
+
+func (r *blockingRecorderRepository) UpsertQuarantine(ctx context.Context, q *network.CaptivePortalQuarantine) error {
+	r.quarantine = q
+	return nil
+}
+
+func TestMaintenanceWindow_SuspendsAutoBlocking(t *testing.T) {
+	repo := &blockingRecorderRepository{mockFullRepository: newMockFullRepository()}
+	now := time.Now()
+	until := now.Add(time.Hour)
+	repo.networks["net-1"] = &network.Network{ID: "net-1", Name: "test-network", MaintenanceUntil: &until}
+	notifier := &recordingIncidentNotifier{}
+	svc := &Service{repo: repo, now: func() time.Time { return now }}
+	svc.SetIncidentNotifier(notifier)
+	ctx := context.Background()
+
+	takeover := []network.EndpointTakeoverReport{{WgIP: "10.0.0.5", AuthenticatedAt: "203.0.113.5:51820", ObservedAt: "198.51.100.7:40000"}}
+	if err := svc.processEndpointTakeovers(ctx, "net-1", "jump-1", takeover); err != nil {
+		t.Fatalf("processEndpointTakeovers: %v", err)
+	}
+	for i := 0; i < network.QuarantineStrikeThreshold; i++ {
+		if err := svc.RecordCaptivePortalAuthFailure(ctx, "net-1", "laptop"); err != nil {
+			t.Fatalf("RecordCaptivePortalAuthFailure: %v", err)
+		}
+	}
+	if repo.denylisted != 0 || repo.quarantine.QuarantinedUntil != nil {
+		t.Fatalf("maintenance should block nobody, got %d denylist entries and quarantine %+v", repo.denylisted, repo.quarantine)
+	}
+	if len(notifier.incidents) != 2 {
+		t.Fatalf("incidents should still be raised during maintenance, got %d", len(notifier.incidents))
+	}
+
+	// Once the window has passed, blocking resumes on its own
+	now = until.Add(time.Second)
+	if err := svc.processEndpointTakeovers(ctx, "net-1", "jump-1", takeover); err != nil {
+		t.Fatalf("processEndpointTakeovers: %v", err)
+	}
+	if err := svc.RecordCaptivePortalAuthFailure(ctx, "net-1", "laptop"); err != nil {
+		t.Fatalf("RecordCaptivePortalAuthFailure: %v", err)
+	}
+	if repo.denylisted != 1 || repo.quarantine.QuarantinedUntil == nil {
+		t.Fatalf("blocking should resume after maintenance, got %d denylist entries and quarantine %+v", repo.denylisted, repo.quarantine)
+	}
+}
+
+func TestUpdateNetwork_MaintenanceWindow(t *testing.T) {
+	svc := newRouteConflictTestService()
+	ctx := context.Background()
+
+	past := time.Now().Add(-time.Minute)
+	if _, err := svc.UpdateNetwork(ctx, "net-1", &network.NetworkUpdateRequest{MaintenanceUntil: &past}); !errors.Is(err, network.ErrMaintenanceInPast) {
+		t.Fatalf("past window: err = %v, want ErrMaintenanceInPast", err)
+	}
+
+	until := time.Now().Add(time.Hour)
+	net, err := svc.UpdateNetwork(ctx, "net-1", &network.NetworkUpdateRequest{MaintenanceUntil: &until})
+	if err != nil {
+		t.Fatalf("UpdateNetwork: %v", err)
+	}
+	if !net.InMaintenance(time.Now()) {
+		t.Fatalf("expected the network to be in maintenance until %s", until)
+	}
+
+	net, err = svc.UpdateNetwork(ctx, "net-1", &network.NetworkUpdateRequest{ClearMaintenance: true})
+	if err != nil {
+		t.Fatalf("UpdateNetwork: %v", err)
+	}
+	if net.MaintenanceUntil != nil {
+		t.Errorf("clear_maintenance should end the window, got %v", net.MaintenanceUntil)
+	}
+}
+
+func TestUpdateNetwork_JumpHooks(t *testing.T) {
+	svc := newRouteConflictTestService()
+	ctx := context.Background()
+
+	bad := "echo {{.Token}}"
+	if _, err := svc.UpdateNetwork(ctx, "net-1", &network.NetworkUpdateRequest{JumpPostUp: &bad}); !errors.Is(err, network.ErrInvalidJumpHook) {
+		t.Fatalf("unknown variable: err = %v, want ErrInvalidJumpHook", err)
+	}
+
+	postUp := "iptables -t nat -A POSTROUTING -o {{.NatInterface}} -j MASQUERADE"
+	iface := "ens3"
+	net, err := svc.UpdateNetwork(ctx, "net-1", &network.NetworkUpdateRequest{JumpPostUp: &postUp, JumpNATInterface: &iface})
+	if err != nil {
+		t.Fatalf("UpdateNetwork: %v", err)
+	}
+	if net.JumpPostUp != postUp || net.JumpNATInterface != iface {
+		t.Fatalf("hooks not saved: post_up %q, nat interface %q", net.JumpPostUp, net.JumpNATInterface)
+	}
+
+	badIface := "ens3 && reboot"
+	if _, err := svc.UpdateNetwork(ctx, "net-1", &network.NetworkUpdateRequest{JumpNATInterface: &badIface}); !errors.Is(err, network.ErrInvalidJumpHook) {
+		t.Fatalf("invalid interface: err = %v, want ErrInvalidJumpHook", err)
+	}
+	if got, _ := svc.GetNetwork(ctx, "net-1"); got.JumpNATInterface != iface {
+		t.Errorf("rejected update changed the interface to %q", got.JumpNATInterface)
+	}
+}
+
+func TestSeverConnection_DropsPeersFromEachOthersConfigs(t *testing.T) {
+	svc := newRouteConflictTestService()
+	repo := svc.repo.(*mockFullRepository)
+	repo.connections = []*network.PeerConnection{
+		{Peer1ID: "jump-1", Peer2ID: "laptop", PresharedKey: "psk-1"},
+		{Peer1ID: "jump-2", Peer2ID: "laptop", PresharedKey: "psk-2"},
+	}
+	ctx := context.Background()
+
+	if err := svc.SeverConnection(ctx, "net-1", "laptop", "jump-9"); !errors.Is(err, network.ErrConnectionNotFound) {
+		t.Fatalf("unknown pair: err = %v, want ErrConnectionNotFound", err)
+	}
+	if err := svc.SeverConnection(ctx, "net-1", "laptop", "jump-1"); err != nil {
+		t.Fatalf("SeverConnection: %v", err)
+	}
+
+	config, err := svc.GeneratePeerConfig(ctx, "net-1", "laptop")
+	if err != nil {
+		t.Fatalf("GeneratePeerConfig(laptop): %v", err)
+	}
+	if peerSection(config, "jump-1") != "" {
+		t.Errorf("laptop config still lists the severed jump-1:\n%s", config)
+	}
+	if section := peerSection(config, "jump-2"); !strings.Contains(section, "PresharedKey = psk-2") {
+		t.Errorf("laptop config lost jump-2:\n%s", config)
+	}
+	config, err = svc.GeneratePeerConfig(ctx, "net-1", "jump-1")
+	if err != nil {
+		t.Fatalf("GeneratePeerConfig(jump-1): %v", err)
+	}
+	if peerSection(config, "laptop") != "" {
+		t.Errorf("jump-1 config still lists the severed laptop:\n%s", config)
+	}
+
+	conns, err := svc.ListMeshConnections(ctx, "net-1")
+	if err != nil {
+		t.Fatalf("ListMeshConnections: %v", err)
+	}
+	if len(conns) != 2 || !conns[0].Severed || conns[0].Peer2Name != "laptop" || conns[1].Severed {
+		t.Errorf("connections = %+v, want jump-1/laptop severed and jump-2/laptop kept", conns)
+	}
+}
+
+func TestReconcileNetworkIPAM_ReleasesOldOrphansOnly(t *testing.T) {
+	svc := newRouteConflictTestService()
+	repo := svc.repo.(*mockFullRepository)
+	for id, p := range repo.networks["net-1"].Peers {
+		repo.peers[id] = p
+	}
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	svc.now = func() time.Time { return now }
+	old := now.Add(-time.Hour)
+	repo.ipam.allocations = []network.IPAllocation{
+		{IP: "10.0.0.1", AllocatedAt: old},                    // jump-1
+		{IP: "10.0.0.10", AllocatedAt: old},                   // laptop
+		{IP: "10.0.0.50", AllocatedAt: old},                   // orphan
+		{IP: "10.0.0.51", AllocatedAt: now.Add(-time.Minute)}, // peer being added
+	}
+
+	result, err := svc.ReconcileNetworkIPAM(context.Background(), "net-1")
+	if err != nil {
+		t.Fatalf("ReconcileNetworkIPAM: %v", err)
+	}
+	if len(result.Released) != 1 || result.Released[0] != "10.0.0.50" {
+		t.Errorf("released %v, want [10.0.0.50]", result.Released)
+	}
+	if len(repo.ipam.released) != 1 || repo.ipam.released[0] != "10.0.0.50" {
+		t.Errorf("IPAM releases = %v, want [10.0.0.50]", repo.ipam.released)
+	}
+
+	if _, err := svc.ReconcileNetworkIPAM(context.Background(), "missing"); !errors.Is(err, network.ErrNetworkNotFound) {
+		t.Errorf("unknown network: err = %v, want ErrNetworkNotFound", err)
+	}
+}
+
+func TestDeleteNetwork_RestoreBeforePurge(t *testing.T) {
+	ctx := context.Background()
+	svc := newRouteConflictTestService()
+	repo := svc.repo.(*mockFullRepository)
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	svc.now = func() time.Time { return now }
+
+	if err := svc.DeleteNetwork(ctx, "net-1"); err != nil {
+		t.Fatalf("DeleteNetwork: %v", err)
+	}
+	if _, err := svc.GetNetwork(ctx, "net-1"); !errors.Is(err, network.ErrNetworkNotFound) {
+		t.Errorf("deleted network served: err = %v", err)
+	}
+	if networks, _ := svc.ListNetworks(ctx); len(networks) != 0 {
+		t.Errorf("deleted network listed: %v", networks)
+	}
+	if len(repo.ipam.deletedPrefixes) != 0 {
+		t.Errorf("CIDR released on soft delete: %v", repo.ipam.deletedPrefixes)
+	}
+
+	// A purge within the window keeps the network.
+	now = now.Add(DefaultNetworkRetention - time.Hour)
+	if err := svc.PurgeDeletedNetworks(ctx); err != nil {
+		t.Fatalf("PurgeDeletedNetworks: %v", err)
+	}
+	net, err := svc.RestoreNetwork(ctx, "net-1")
+	if err != nil {
+		t.Fatalf("RestoreNetwork: %v", err)
+	}
+	if net.DeletedAt != nil || len(net.Peers) != 3 {
+		t.Errorf("restored network = %+v, want live with its 3 peers", net)
+	}
+	if _, err := svc.RestoreNetwork(ctx, "net-1"); !errors.Is(err, network.ErrNetworkNotDeleted) {
+		t.Errorf("restore of live network: err = %v, want ErrNetworkNotDeleted", err)
+	}
+	if _, err := svc.RestoreNetwork(ctx, "missing"); !errors.Is(err, network.ErrNetworkNotFound) {
+		t.Errorf("restore of unknown network: err = %v, want ErrNetworkNotFound", err)
+	}
+}
+
+func TestPurgeDeletedNetworks_AfterRetention(t *testing.T) {
+	ctx := context.Background()
+	svc := newRouteConflictTestService()
+	svc.SetNetworkRetention(24 * time.Hour)
+	repo := svc.repo.(*mockFullRepository)
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	svc.now = func() time.Time { return now }
+
+	if err := svc.DeleteNetwork(ctx, "net-1"); err != nil {
+		t.Fatalf("DeleteNetwork: %v", err)
+	}
+	now = now.Add(24 * time.Hour)
+	if _, err := svc.RestoreNetwork(ctx, "net-1"); !errors.Is(err, network.ErrRestoreWindowExpired) {
+		t.Errorf("restore after window: err = %v, want ErrRestoreWindowExpired", err)
+	}
+	if err := svc.PurgeDeletedNetworks(ctx); err != nil {
+		t.Fatalf("PurgeDeletedNetworks: %v", err)
+	}
+	if _, exists := repo.networks["net-1"]; exists {
+		t.Error("network not purged after the retention window")
+	}
+	if len(repo.ipam.deletedPrefixes) != 1 || repo.ipam.deletedPrefixes[0] != "10.0.0.0/24" {
+		t.Errorf("released prefixes = %v, want [10.0.0.0/24]", repo.ipam.deletedPrefixes)
+	}
+	if _, err := svc.RestoreNetwork(ctx, "net-1"); !errors.Is(err, network.ErrNetworkNotFound) {
+		t.Errorf("restore after purge: err = %v, want ErrNetworkNotFound", err)
+	}
+}
+
+func TestGeneratePeerConfig_Metadata(t *testing.T) {
+	ctx := context.Background()
+	svc := newRouteConflictTestService()
+	repo := svc.repo.(*mockFullRepository)
+	groupRepo := svc.groupRepo.(*mockGroupRepository)
+	authRepo := newMockAuthRepository()
+	authRepo.users["user-1"] = &auth.User{ID: "user-1", Email: "alice@example.com"}
+	svc.authRepo = authRepo
+	repo.networks["net-1"].Peers["laptop"].OwnerID = "user-1"
+	groupRepo.groups["g-high"].PeerIDs = []string{"laptop"}
+
+	config, err := svc.GeneratePeerConfig(ctx, "net-1", "jump-1")
+	if err != nil {
+		t.Fatalf("GeneratePeerConfig: %v", err)
+	}
+	if strings.Contains(config, "# ID:") {
+		t.Errorf("metadata comments without ConfigMetadata:\n%s", config)
+	}
+
+	repo.networks["net-1"].ConfigMetadata = true
+	config, err = svc.GeneratePeerConfig(ctx, "net-1", "jump-1")
+	if err != nil {
+		t.Fatalf("GeneratePeerConfig: %v", err)
+	}
+	want := "# Name: laptop\n# ID: laptop\n# Groups: high\n# Owner: alice@example.com\n"
+	if section := peerSection(config, "laptop"); !strings.Contains(section, want) {
+		t.Errorf("laptop section lacks %q:\n%s", want, section)
+	}
+	if !strings.Contains(config, "[Interface]\n# Name: jump-1\n# ID: jump-1\nAddress") {
+		t.Errorf("interface section lacks the jump's ID:\n%s", config)
+	}
+}
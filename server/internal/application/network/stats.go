@@ -0,0 +1,229 @@
+package network
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"wirety/internal/domain/network"
+)
+
+// recordPeerTransfer returns the session's transfer stats updated with the
+// counters of heartbeat.  Totals carry over from the existing session, so an
+// agent restart (which resets the WireGuard counters) loses no traffic.
+// Peers missing from the report are dropped, as they left the interface.
+func recordPeerTransfer(existing *network.AgentSession, heartbeat *network.AgentHeartbeat, now time.Time) map[string]*network.PeerTransferStats {
+	if len(heartbeat.PeerTransfer) == 0 {
+		// Older agent, or nothing to report: keep what we had.
+		if existing != nil {
+			return existing.PeerTransfer
+		}
+		return nil
+	}
+	out := make(map[string]*network.PeerTransferStats, len(heartbeat.PeerTransfer))
+	for pubKey, counters := range heartbeat.PeerTransfer {
+		stats := &network.PeerTransferStats{}
+		if existing != nil {
+			if prev, ok := existing.PeerTransfer[pubKey]; ok {
+				cp := *prev
+				stats = &cp
+			}
+		}
+		stats.Record(counters, now)
+		if ts, ok := heartbeat.PeerHandshakes[pubKey]; ok && ts > 0 {
+			handshake := time.Unix(ts, 0)
+			stats.LastHandshake = &handshake
+		}
+		out[pubKey] = stats
+	}
+	return out
+}
+
+// GetPeerStats returns the traffic a peer exchanged during its current
+// session.  An agent peer's own report is authoritative; for other peers the
+// jump peers' reports are used, mirrored, since what a jump peer received the
+// peer sent.
+func (s *Service) GetPeerStats(ctx context.Context, networkID, peerID string) (*network.PeerStats, error) {
+	peer, err := s.repo.GetPeer(ctx, networkID, peerID)
+	if err != nil {
+		return nil, fmt.Errorf("peer not found: %w", err)
+	}
+	stats := &network.PeerStats{PeerID: peerID}
+
+	if session, err := s.repo.GetSession(ctx, networkID, peerID); err == nil && len(session.PeerTransfer) > 0 {
+		for _, t := range session.PeerTransfer {
+			addPeerTransfer(stats, t, false)
+		}
+		return stats, nil
+	}
+
+	peers, err := s.repo.ListPeers(ctx, networkID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list peers: %w", err)
+	}
+	for _, jp := range peers {
+		if !jp.IsJump || jp.ID == peerID {
+			continue
+		}
+		session, err := s.repo.GetSession(ctx, networkID, jp.ID)
+		if err != nil {
+			continue
+		}
+		if t, ok := session.PeerTransfer[peer.PublicKey]; ok {
+			addPeerTransfer(stats, t, true)
+		}
+	}
+	return stats, nil
+}
+
+// addPeerTransfer adds t to stats.  mirrored means t was reported by the
+// other end of the tunnel, so its rx is the peer's tx and vice versa.
+func addPeerTransfer(stats *network.PeerStats, t *network.PeerTransferStats, mirrored bool) {
+	if mirrored {
+		stats.RxBytes += t.TxBytes
+		stats.TxBytes += t.RxBytes
+	} else {
+		stats.RxBytes += t.RxBytes
+		stats.TxBytes += t.TxBytes
+	}
+	if t.LastHandshake != nil && (stats.LastHandshake == nil || t.LastHandshake.After(*stats.LastHandshake)) {
+		stats.LastHandshake = t.LastHandshake
+	}
+	if stats.UpdatedAt == nil || t.UpdatedAt.After(*stats.UpdatedAt) {
+		updated := t.UpdatedAt
+		stats.UpdatedAt = &updated
+	}
+}
+
+// DefaultPeerStaleThreshold is the handshake age beyond which a peer is
+// stale.  WireGuard re-handshakes every two minutes on an active tunnel.
+const DefaultPeerStaleThreshold = 3 * time.Minute
+
+func (s *Service) staleThreshold() time.Duration {
+	if s.peerStaleThreshold > 0 {
+		return s.peerStaleThreshold
+	}
+	return DefaultPeerStaleThreshold
+}
+
+// recordPeerHandshakes merges the handshakes of heartbeat into those already
+// on the session, keeping the latest per peer.
+func recordPeerHandshakes(existing *network.AgentSession, heartbeat *network.AgentHeartbeat) map[string]time.Time {
+	out := make(map[string]time.Time)
+	if existing != nil {
+		for pubKey, t := range existing.PeerHandshakes {
+			out[pubKey] = t
+		}
+	}
+	for pubKey, ts := range heartbeat.PeerHandshakes {
+		if ts <= 0 {
+			continue
+		}
+		if t := time.Unix(ts, 0); t.After(out[pubKey]) {
+			out[pubKey] = t
+		}
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
+
+// peerLastHandshakes returns the latest handshake of every peer of the
+// network that ever handshook, by peer ID.  A handshake reported by an agent
+// counts for both ends of the tunnel, so peers without an agent are covered
+// by their jump peers' reports.
+func (s *Service) peerLastHandshakes(ctx context.Context, networkID string) (map[string]time.Time, error) {
+	peers, err := s.repo.ListPeers(ctx, networkID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list peers: %w", err)
+	}
+	sessions, err := s.repo.ListSessions(ctx, networkID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	byKey := make(map[string]string, len(peers))
+	for _, p := range peers {
+		byKey[p.PublicKey] = p.ID
+	}
+
+	out := make(map[string]time.Time)
+	bump := func(peerID string, t time.Time) {
+		if t.After(out[peerID]) {
+			out[peerID] = t
+		}
+	}
+	for _, session := range sessions {
+		for pubKey, t := range session.PeerHandshakes {
+			bump(session.PeerID, t)
+			if id, ok := byKey[pubKey]; ok {
+				bump(id, t)
+			}
+		}
+	}
+	return out, nil
+}
+
+// PeerStatuses returns the handshake status (online, stale or offline) of
+// every peer of the network, by peer ID.
+func (s *Service) PeerStatuses(ctx context.Context, networkID string) (map[string]string, error) {
+	peers, err := s.repo.ListPeers(ctx, networkID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list peers: %w", err)
+	}
+	handshakes, err := s.peerLastHandshakes(ctx, networkID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	out := make(map[string]string, len(peers))
+	for _, p := range peers {
+		out[p.ID] = network.PeerStatusAt(handshakes[p.ID], now, s.staleThreshold())
+	}
+	return out, nil
+}
+
+// recordPeerLatency returns the session's latencies updated with those of
+// heartbeat.  A report replaces the previous one; agents that do not measure
+// latency keep what the session had.
+func recordPeerLatency(existing *network.AgentSession, heartbeat *network.AgentHeartbeat, now time.Time) map[string]network.PeerLatency {
+	if len(heartbeat.PeerLatency) == 0 {
+		if existing != nil {
+			return existing.PeerLatency
+		}
+		return nil
+	}
+	out := make(map[string]network.PeerLatency, len(heartbeat.PeerLatency))
+	for pubKey, ms := range heartbeat.PeerLatency {
+		latency := network.PeerLatency{MeasuredAt: now}
+		if ms >= 0 {
+			rtt := ms
+			latency.RTTMs = &rtt
+		}
+		out[pubKey] = latency
+	}
+	return out
+}
+
+// peerLatency returns the round-trip time to peer most recently measured by
+// any jump agent of the network, or nil when it is unknown: not measured
+// within the staleness threshold, or the latest measurement got no answer.
+func (s *Service) peerLatency(ctx context.Context, networkID string, peer *network.Peer, now time.Time) *float64 {
+	sessions, err := s.repo.ListSessions(ctx, networkID)
+	if err != nil {
+		return nil
+	}
+	var latest *network.PeerLatency
+	for _, session := range sessions {
+		if session.PeerID == peer.ID {
+			continue
+		}
+		if l, ok := session.PeerLatency[peer.PublicKey]; ok && (latest == nil || l.MeasuredAt.After(latest.MeasuredAt)) {
+			latest = &l
+		}
+	}
+	if latest == nil || now.Sub(latest.MeasuredAt) > s.staleThreshold() {
+		return nil
+	}
+	return latest.RTTMs
+}
@@ -0,0 +1,165 @@
+package network
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"wirety/internal/domain/network"
+)
+
+// Topology node types
+const (
+	TopologyNodeJump    = "jump"
+	TopologyNodeRegular = "regular"
+	TopologyNodeDNSOnly = "dns-only"
+)
+
+// Topology edge types
+const (
+	TopologyEdgeConnection = "connection"
+	TopologyEdgeRoute      = "route"
+)
+
+// TopologyNode is a peer of the network in the topology graph.
+type TopologyNode struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Type        string `json:"type"` // jump, regular or dns-only
+	Address     string `json:"address"`
+	AddressV6   string `json:"address_v6,omitempty"`
+	Quarantined bool   `json:"quarantined,omitempty"` // Quarantined or expired: no edges
+}
+
+// TopologyEdge links two peers.  A connection edge is a [Peer] section the
+// two peers have for each other, listed once per pair with Source < Target.
+// A route edge goes from a peer to the jump peer carrying one of its routes.
+type TopologyEdge struct {
+	Type      string   `json:"type"` // connection or route
+	Source    string   `json:"source"`
+	Target    string   `json:"target"`
+	RouteID   string   `json:"route_id,omitempty"`
+	RouteName string   `json:"route_name,omitempty"`
+	CIDRs     []string `json:"cidrs,omitempty"`
+}
+
+// Topology is the network as a graph: its peers and the tunnels and routes
+// between them.
+type Topology struct {
+	Nodes []TopologyNode `json:"nodes"`
+	Edges []TopologyEdge `json:"edges"`
+}
+
+// GetTopology builds the graph of the network from the same data the peer
+// configs are generated from: the allowed peers of each peer minus severed
+// connections, and the routes each peer gets after conflict resolution.
+// Quarantined and expired peers are nodes without edges, as their traffic
+// is blocked.
+func (s *Service) GetTopology(ctx context.Context, networkID string) (*Topology, error) {
+	net, err := s.repo.GetNetwork(ctx, networkID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", network.ErrNetworkNotFound, networkID)
+	}
+	qList, err := s.repo.ListQuarantinedPeers(ctx, networkID)
+	if err != nil {
+		return nil, fmt.Errorf("list quarantined: %w", err)
+	}
+	quarantined := make(map[string]bool, len(qList))
+	for _, q := range qList {
+		quarantined[q.PeerID] = true
+	}
+	now := s.clock()
+
+	peers := make([]*network.Peer, 0, len(net.Peers))
+	for _, p := range net.Peers {
+		peers = append(peers, p)
+	}
+	sort.Slice(peers, func(i, j int) bool {
+		if peers[i].Name != peers[j].Name {
+			return peers[i].Name < peers[j].Name
+		}
+		return peers[i].ID < peers[j].ID
+	})
+
+	topo := &Topology{Nodes: make([]TopologyNode, 0, len(peers)), Edges: []TopologyEdge{}}
+	blocked := make(map[string]bool)
+	for _, p := range peers {
+		node := TopologyNode{ID: p.ID, Name: p.Name, Type: TopologyNodeRegular, Address: p.Address, AddressV6: p.AddressV6}
+		switch {
+		case p.IsJump:
+			node.Type = TopologyNodeJump
+		case p.DNSOnly:
+			node.Type = TopologyNodeDNSOnly
+		}
+		if quarantined[p.ID] || p.IsExpired(now) {
+			node.Quarantined = true
+			blocked[p.ID] = true
+		}
+		topo.Nodes = append(topo.Nodes, node)
+	}
+
+	seen := make(map[[2]string]bool)
+	for _, p := range peers {
+		if blocked[p.ID] {
+			continue
+		}
+		allowed, _ := s.peerConnections(ctx, networkID, p.ID, net.GetAllowedPeersFor(p.ID))
+		for _, other := range allowed {
+			if blocked[other.ID] {
+				continue
+			}
+			pair := [2]string{p.ID, other.ID}
+			if pair[0] > pair[1] {
+				pair[0], pair[1] = pair[1], pair[0]
+			}
+			if seen[pair] {
+				continue
+			}
+			seen[pair] = true
+			topo.Edges = append(topo.Edges, TopologyEdge{Type: TopologyEdgeConnection, Source: pair[0], Target: pair[1]})
+		}
+
+		if p.IsJump || p.DNSOnly {
+			continue
+		}
+		routes, err := s.collectPeerRoutes(ctx, networkID, p)
+		if err != nil {
+			return nil, err
+		}
+		for _, route := range routes {
+			if blocked[route.JumpPeerID] {
+				continue
+			}
+			topo.Edges = append(topo.Edges, TopologyEdge{
+				Type:      TopologyEdgeRoute,
+				Source:    p.ID,
+				Target:    route.JumpPeerID,
+				RouteID:   route.ID,
+				RouteName: route.Name,
+				CIDRs:     appendNonEmpty(nil, route.DestinationCIDR, route.DestinationCIDRv6),
+			})
+		}
+	}
+
+	sort.SliceStable(topo.Edges, func(i, j int) bool {
+		a, b := topo.Edges[i], topo.Edges[j]
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		if a.Source != b.Source {
+			return a.Source < b.Source
+		}
+		return a.Target < b.Target
+	})
+	return topo, nil
+}
+
+// appendNonEmpty appends the non-empty values to out.
+func appendNonEmpty(out []string, values ...string) []string {
+	for _, v := range values {
+		if v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
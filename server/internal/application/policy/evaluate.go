@@ -0,0 +1,137 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"wirety/internal/domain/network"
+)
+
+// packet is a parsed PolicyEvaluateRequest.
+type packet struct {
+	src, dst net.IP
+	protocol string
+	port     int
+}
+
+// EvaluatePolicy tells whether the jump peers would forward a packet sent by
+// a peer, without deploying anything.  It walks the peer's rules in the order
+// GenerateIPTablesRules emits them (active temporary routes, then the
+// policies of its groups by priority) and returns the first match, or the
+// default deny.  Only CIDR rules are enforced, so peer and group targets
+// never match.
+func (s *Service) EvaluatePolicy(ctx context.Context, networkID string, req *network.PolicyEvaluateRequest) (*network.PolicyEvaluation, error) {
+	peer, err := s.peerRepo.GetPeer(ctx, networkID, req.PeerID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", network.ErrPeerNotFound, req.PeerID)
+	}
+	pkt, err := parsePacket(peer, req)
+	if err != nil {
+		return nil, err
+	}
+	if peer.IsJump {
+		return &network.PolicyEvaluation{Verdict: "allow", Reason: "jump peers are not subject to policies"}, nil
+	}
+
+	for _, target := range s.activeTempRouteTargets(ctx, networkID, peer.ID, "") {
+		rule := network.PolicyRule{Direction: "output", Action: "allow", Target: target, TargetType: "cidr"}
+		if ruleMatches(peer, rule, pkt) {
+			return &network.PolicyEvaluation{Verdict: "allow", Reason: "temporary route", Rule: &rule}, nil
+		}
+	}
+
+	policies, err := s.peerPolicies(ctx, networkID, peer.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get peer groups: %w", err)
+	}
+	for _, policy := range policies {
+		for i := range policy.Rules {
+			rule := policy.Rules[i]
+			if !ruleMatches(peer, rule, pkt) {
+				continue
+			}
+			return &network.PolicyEvaluation{
+				Verdict:    rule.Action,
+				Reason:     "matched policy rule",
+				PolicyID:   policy.ID,
+				PolicyName: policy.Name,
+				Rule:       &rule,
+			}, nil
+		}
+	}
+	return &network.PolicyEvaluation{Verdict: "deny", Reason: "no rule matched: default deny"}, nil
+}
+
+// parsePacket validates req, filling in the source from the peer's address
+// in the destination's family when it is left out.
+func parsePacket(peer *network.Peer, req *network.PolicyEvaluateRequest) (*packet, error) {
+	pkt := &packet{protocol: req.Protocol, port: req.Port}
+	if pkt.dst = net.ParseIP(req.Destination); pkt.dst == nil {
+		return nil, fmt.Errorf("%w: destination %q is not an IP address", network.ErrInvalidPacket, req.Destination)
+	}
+	source := req.Source
+	if source == "" {
+		source = stripCIDR(peer.Address)
+		if pkt.dst.To4() == nil {
+			source = stripCIDR(peer.AddressV6)
+		}
+	}
+	if pkt.src = net.ParseIP(source); pkt.src == nil {
+		return nil, fmt.Errorf("%w: source %q is not an IP address", network.ErrInvalidPacket, source)
+	}
+	if (pkt.src.To4() == nil) != (pkt.dst.To4() == nil) {
+		return nil, fmt.Errorf("%w: source and destination must be of the same address family", network.ErrInvalidPacket)
+	}
+
+	switch pkt.protocol {
+	case "":
+		pkt.protocol = network.PolicyProtocolTCP
+	case network.PolicyProtocolTCP, network.PolicyProtocolUDP, network.PolicyProtocolICMP:
+	default:
+		return nil, fmt.Errorf("%w: protocol must be 'tcp', 'udp' or 'icmp'", network.ErrInvalidPacket)
+	}
+	if pkt.port < 0 || pkt.port > 65535 || (pkt.port != 0 && pkt.protocol == network.PolicyProtocolICMP) {
+		return nil, fmt.Errorf("%w: port must be 1-65535, and only with tcp or udp", network.ErrInvalidPacket)
+	}
+	return pkt, nil
+}
+
+// ruleMatches reports whether the FORWARD rule generateIPTablesRulesForPeer
+// derives from rule for peer matches a new connection's packet.  Allow rules
+// and output denials match packets from the peer to the target; input
+// denials match packets from the target to the peer.
+func ruleMatches(peer *network.Peer, rule network.PolicyRule, pkt *packet) bool {
+	if rule.TargetType != "cidr" {
+		return false
+	}
+	_, target, err := net.ParseCIDR(rule.Target)
+	if err != nil {
+		return false
+	}
+	peerIP := net.ParseIP(stripCIDR(peer.Address))
+	if isIPv6CIDR(rule.Target) {
+		peerIP = net.ParseIP(stripCIDR(peer.AddressV6))
+	}
+	if peerIP == nil || !l4Matches(rule, pkt) {
+		return false
+	}
+	if rule.Direction == "input" && rule.Action != "allow" {
+		return target.Contains(pkt.src) && peerIP.Equal(pkt.dst)
+	}
+	return peerIP.Equal(pkt.src) && target.Contains(pkt.dst)
+}
+
+// l4Matches is the packet-side counterpart of l4Match.
+func l4Matches(rule network.PolicyRule, pkt *packet) bool {
+	if rule.MatchesAnyProtocol() {
+		return true
+	}
+	if rule.Protocol != pkt.protocol {
+		return false
+	}
+	if first, last, ok := rule.PortRange(); ok {
+		return pkt.port >= first && pkt.port <= last
+	}
+	return true
+}
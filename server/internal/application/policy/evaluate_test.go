@@ -0,0 +1,159 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"wirety/internal/domain/network"
+)
+
+// evalGroupRepo returns configured groups, by priority, for each peer.
+type evalGroupRepo struct {
+	mockGroupRepository
+	peerGroups map[string][]*network.Group
+}
+
+func (r *evalGroupRepo) GetPeerGroups(ctx context.Context, networkID, peerID string) ([]*network.Group, error) {
+	return r.peerGroups[peerID], nil
+}
+
+// evalPolicyRepo returns configured policies, in order, for each group.
+type evalPolicyRepo struct {
+	mockPolicyRepository
+	groupPolicies map[string][]*network.Policy
+}
+
+func (r *evalPolicyRepo) GetPoliciesForGroup(ctx context.Context, networkID, groupID string) ([]*network.Policy, error) {
+	return r.groupPolicies[groupID], nil
+}
+
+// newEvaluateTestService has peer-1 (10.0.0.5) in group "staff" (priority
+// 50) and group "everyone" (priority 100), with the given policies.
+func newEvaluateTestService(staff, everyone []*network.Policy) *Service {
+	getter := newMockNetworkGetter()
+	getter.peers["peer-1"] = &network.Peer{ID: "peer-1", Name: "laptop", Address: "10.0.0.5"}
+	getter.peers["jump-1"] = &network.Peer{ID: "jump-1", Name: "jump", Address: "10.0.0.1", IsJump: true}
+	groups := &evalGroupRepo{mockGroupRepository: *newMockGroupRepository(), peerGroups: map[string][]*network.Group{
+		"peer-1": {{ID: "staff", Name: "staff", Priority: 50}, {ID: "everyone", Name: "everyone", Priority: 100}},
+	}}
+	policies := &evalPolicyRepo{mockPolicyRepository: *newMockPolicyRepository(), groupPolicies: map[string][]*network.Policy{
+		"staff":    staff,
+		"everyone": everyone,
+	}}
+	return NewService(policies, groups, &networkGetterAdapter{getter: getter}, newMockRouteRepository())
+}
+
+func TestEvaluatePolicy_AllowBeforeDeny(t *testing.T) {
+	svc := newEvaluateTestService(
+		[]*network.Policy{{ID: "web", Name: "web", Rules: []network.PolicyRule{
+			{ID: "https", Direction: "output", Action: "allow", TargetType: "cidr", Target: "10.0.0.9/32", Protocol: "tcp", Ports: "443"},
+		}}},
+		[]*network.Policy{{ID: "lockdown", Name: "lockdown", Rules: []network.PolicyRule{
+			{ID: "all", Direction: "output", Action: "deny", TargetType: "cidr", Target: "10.0.0.0/24"},
+		}}},
+	)
+	ctx := context.Background()
+
+	tests := []struct {
+		name        string
+		req         network.PolicyEvaluateRequest
+		wantVerdict string
+		wantRule    string
+	}{
+		{
+			name:        "allowed by the higher-priority group",
+			req:         network.PolicyEvaluateRequest{PeerID: "peer-1", Source: "10.0.0.5", Destination: "10.0.0.9", Protocol: "tcp", Port: 443},
+			wantVerdict: "allow", wantRule: "https",
+		},
+		{
+			name:        "source defaults to the peer",
+			req:         network.PolicyEvaluateRequest{PeerID: "peer-1", Destination: "10.0.0.9", Port: 443},
+			wantVerdict: "allow", wantRule: "https",
+		},
+		{
+			name:        "other port falls through to the deny",
+			req:         network.PolicyEvaluateRequest{PeerID: "peer-1", Destination: "10.0.0.9", Protocol: "tcp", Port: 22},
+			wantVerdict: "deny", wantRule: "all",
+		},
+		{
+			name:        "other protocol falls through to the deny",
+			req:         network.PolicyEvaluateRequest{PeerID: "peer-1", Destination: "10.0.0.9", Protocol: "udp", Port: 443},
+			wantVerdict: "deny", wantRule: "all",
+		},
+		{
+			name:        "another source matches none of the peer's rules",
+			req:         network.PolicyEvaluateRequest{PeerID: "peer-1", Source: "10.0.0.6", Destination: "10.0.0.9", Port: 443},
+			wantVerdict: "deny",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := svc.EvaluatePolicy(ctx, "net-1", &tt.req)
+			if err != nil {
+				t.Fatalf("EvaluatePolicy: %v", err)
+			}
+			if got.Verdict != tt.wantVerdict {
+				t.Errorf("verdict = %q, want %q (%+v)", got.Verdict, tt.wantVerdict, got)
+			}
+			gotRule := ""
+			if got.Rule != nil {
+				gotRule = got.Rule.ID
+			}
+			if gotRule != tt.wantRule {
+				t.Errorf("rule = %q, want %q", gotRule, tt.wantRule)
+			}
+		})
+	}
+}
+
+func TestEvaluatePolicy_DefaultDeny(t *testing.T) {
+	svc := newEvaluateTestService(nil, []*network.Policy{{ID: "dns", Name: "dns", Rules: []network.PolicyRule{
+		{ID: "resolver", Direction: "output", Action: "allow", TargetType: "cidr", Target: "10.0.0.53/32", Protocol: "udp", Ports: "53"},
+		{ID: "group-target", Direction: "output", Action: "allow", TargetType: "group", Target: "servers"},
+	}}})
+
+	got, err := svc.EvaluatePolicy(context.Background(), "net-1", &network.PolicyEvaluateRequest{PeerID: "peer-1", Destination: "10.0.0.9", Port: 443})
+	if err != nil {
+		t.Fatalf("EvaluatePolicy: %v", err)
+	}
+	if got.Verdict != "deny" || got.Rule != nil || got.PolicyID != "" {
+		t.Errorf("evaluation = %+v, want the default deny", got)
+	}
+}
+
+func TestEvaluatePolicy_InputDenyMatchesInboundTraffic(t *testing.T) {
+	svc := newEvaluateTestService([]*network.Policy{{ID: "shield", Name: "shield", Rules: []network.PolicyRule{
+		{ID: "no-inbound", Direction: "input", Action: "deny", TargetType: "cidr", Target: "10.0.0.0/24"},
+	}}}, nil)
+	ctx := context.Background()
+
+	got, err := svc.EvaluatePolicy(ctx, "net-1", &network.PolicyEvaluateRequest{PeerID: "peer-1", Source: "10.0.0.9", Destination: "10.0.0.5", Port: 22})
+	if err != nil {
+		t.Fatalf("EvaluatePolicy: %v", err)
+	}
+	if got.Verdict != "deny" || got.Rule == nil || got.Rule.ID != "no-inbound" || got.PolicyName != "shield" {
+		t.Errorf("evaluation = %+v, want denied by no-inbound", got)
+	}
+}
+
+func TestEvaluatePolicy_InvalidRequests(t *testing.T) {
+	svc := newEvaluateTestService(nil, nil)
+	ctx := context.Background()
+
+	if _, err := svc.EvaluatePolicy(ctx, "net-1", &network.PolicyEvaluateRequest{PeerID: "missing", Destination: "10.0.0.9"}); !errors.Is(err, network.ErrPeerNotFound) {
+		t.Errorf("unknown peer: err = %v, want ErrPeerNotFound", err)
+	}
+	for name, req := range map[string]network.PolicyEvaluateRequest{
+		"bad destination": {PeerID: "peer-1", Destination: "server"},
+		"mixed families":  {PeerID: "peer-1", Source: "10.0.0.5", Destination: "fd00::9"},
+		"bad protocol":    {PeerID: "peer-1", Destination: "10.0.0.9", Protocol: "sctp"},
+		"port with icmp":  {PeerID: "peer-1", Destination: "10.0.0.9", Protocol: "icmp", Port: 443},
+		"port too high":   {PeerID: "peer-1", Destination: "10.0.0.9", Port: 70000},
+	} {
+		req := req
+		if _, err := svc.EvaluatePolicy(ctx, "net-1", &req); !errors.Is(err, network.ErrInvalidPacket) {
+			t.Errorf("%s: err = %v, want ErrInvalidPacket", name, err)
+		}
+	}
+}
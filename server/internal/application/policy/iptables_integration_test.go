@@ -248,6 +248,37 @@ func TestRuleGen_AllowOutputCIDR(t *testing.T) {
 	}
 }
 
+// TestRuleGen_CreatedPolicyKeepsPorts checks that the protocol and ports of a
+// rule survive CreatePolicy and reach the generated rules.
+func TestRuleGen_CreatedPolicyKeepsPorts(t *testing.T) {
+	f := newRuleGenFixture()
+
+	rule := mustRule("", "output", "allow", "cidr", "192.168.1.0/24")
+	rule.Protocol = "tcp"
+	rule.Ports = "443"
+	pol, err := f.svc.CreatePolicy(context.Background(), f.networkID, &network.PolicyCreateRequest{
+		Name:  "https-only",
+		Rules: []network.PolicyRule{rule},
+	})
+	if err != nil {
+		t.Fatalf("CreatePolicy: %v", err)
+	}
+	if got := pol.Rules[0]; got.Protocol != "tcp" || got.Ports != "443" {
+		t.Fatalf("created rule protocol=%q ports=%q, want tcp 443", got.Protocol, got.Ports)
+	}
+	f.addPeerPolicy(f.peer1ID, "g1", 100, pol)
+
+	rules, err := f.svc.GenerateIPTablesRules(context.Background(), f.networkID, f.jumpPeerID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := "iptables -A FORWARD -s 10.100.0.2 -d 192.168.1.0/24 -p tcp --dport 443 -j ACCEPT"
+	if !containsRule(rules, want) {
+		t.Errorf("missing port-restricted rule %q in:\n%s", want, strings.Join(rules, "\n"))
+	}
+}
+
 // TestRuleGen_DenyOutputCIDR checks that a deny-output rule generates a single DROP rule.
 func TestRuleGen_DenyOutputCIDR(t *testing.T) {
 	f := newRuleGenFixture()
@@ -7,6 +7,7 @@ import (
 	"strings"
 	"time"
 
+	"wirety/internal/audit"
 	"wirety/internal/domain/network"
 
 	"github.com/google/uuid"
@@ -47,11 +48,12 @@ type WebSocketNotifier interface {
 
 // Service implements the business logic for policy management
 type Service struct {
-	policyRepo network.PolicyRepository
-	groupRepo  network.GroupRepository
-	peerRepo   network.Repository
-	routeRepo  network.RouteRepository
-	wsNotifier WebSocketNotifier
+	policyRepo  network.PolicyRepository
+	groupRepo   network.GroupRepository
+	peerRepo    network.Repository
+	routeRepo   network.RouteRepository
+	wsNotifier  WebSocketNotifier
+	auditLogger audit.AuditLogger
 }
 
 // NewService creates a new policy service
@@ -69,6 +71,11 @@ func (s *Service) SetWebSocketNotifier(notifier WebSocketNotifier) {
 	s.wsNotifier = notifier
 }
 
+// SetAuditLogger sets the audit log that records mutating operations
+func (s *Service) SetAuditLogger(l audit.AuditLogger) {
+	s.auditLogger = l
+}
+
 // CreatePolicy creates a new policy with name validation
 func (s *Service) CreatePolicy(ctx context.Context, networkID string, req *network.PolicyCreateRequest) (*network.Policy, error) {
 	// Validate request
@@ -93,6 +100,8 @@ func (s *Service) CreatePolicy(ctx context.Context, networkID string, req *netwo
 			Action:      rule.Action,
 			Target:      rule.Target,
 			TargetType:  rule.TargetType,
+			Protocol:    rule.Protocol,
+			Ports:       rule.Ports,
 			Description: rule.Description,
 		}
 	}
@@ -111,6 +120,8 @@ func (s *Service) CreatePolicy(ctx context.Context, networkID string, req *netwo
 		return nil, fmt.Errorf("failed to create policy: %w", err)
 	}
 
+	audit.Record(ctx, s.auditLogger, "policy.create", networkID, "", policy.ID)
+
 	return policy, nil
 }
 
@@ -154,6 +165,8 @@ func (s *Service) UpdatePolicy(ctx context.Context, networkID, policyID string,
 		s.wsNotifier.NotifyNetworkPeers(networkID)
 	}
 
+	audit.Record(ctx, s.auditLogger, "policy.update", networkID, "", policyID)
+
 	return policy, nil
 }
 
@@ -174,6 +187,8 @@ func (s *Service) DeletePolicy(ctx context.Context, networkID, policyID string)
 		s.wsNotifier.NotifyNetworkPeers(networkID)
 	}
 
+	audit.Record(ctx, s.auditLogger, "policy.delete", networkID, "", policyID)
+
 	return nil
 }
 
@@ -215,6 +230,8 @@ func (s *Service) AddRuleToPolicy(ctx context.Context, networkID, policyID strin
 		s.wsNotifier.NotifyNetworkPeers(networkID)
 	}
 
+	audit.Record(ctx, s.auditLogger, "policy.rule_add", networkID, "", policyID)
+
 	return nil
 }
 
@@ -230,6 +247,8 @@ func (s *Service) RemoveRuleFromPolicy(ctx context.Context, networkID, policyID,
 		s.wsNotifier.NotifyNetworkPeers(networkID)
 	}
 
+	audit.Record(ctx, s.auditLogger, "policy.rule_remove", networkID, "", policyID)
+
 	return nil
 }
 
@@ -263,31 +282,12 @@ func (s *Service) GenerateIPTablesRules(ctx context.Context, networkID, jumpPeer
 			continue // Skip jump peers
 		}
 
-		// Get groups this peer belongs to
-		groups, err := s.groupRepo.GetPeerGroups(ctx, networkID, peer.ID)
+		policies, err := s.peerPolicies(ctx, networkID, peer.ID)
 		if err != nil {
 			// If we can't get groups, skip this peer
 			continue
 		}
 
-		// Collect all policies from peer's groups (groups are ordered by priority)
-		// Lower priority number = higher priority (applied first)
-		// Quarantine groups have priority 0, user groups default to 100
-		policyMap := make(map[string]*network.Policy)
-		for _, group := range groups {
-			policies, err := s.policyRepo.GetPoliciesForGroup(ctx, networkID, group.ID)
-			if err != nil {
-				continue
-			}
-
-			for _, policy := range policies {
-				// Avoid duplicates - first occurrence wins (highest priority group)
-				if _, exists := policyMap[policy.ID]; !exists {
-					policyMap[policy.ID] = policy
-				}
-			}
-		}
-
 		// Generate rules for this peer based on their policies.
 		//
 		// We pass BOTH the peer's IPv4 and IPv6 addresses (when present) — the
@@ -296,7 +296,15 @@ func (s *Service) GenerateIPTablesRules(ctx context.Context, networkID, jumpPeer
 		// iptables rejects with "invalid mask 64" or similar.
 		peerV4 := stripCIDR(peer.Address)
 		peerV6 := stripCIDR(peer.AddressV6)
-		for _, policy := range policyMap {
+
+		// Temporary (break-glass) routes through this jump are allowed ahead
+		// of the peer's policies, which may otherwise deny the destination.
+		for _, target := range s.activeTempRouteTargets(ctx, networkID, peer.ID, jumpPeerID) {
+			rule := network.PolicyRule{Direction: "output", Action: "allow", Target: target, TargetType: "cidr"}
+			rules = append(rules, s.generateIPTablesRulesForPeer(peerV4, peerV6, rule)...)
+		}
+
+		for _, policy := range policies {
 			for _, rule := range policy.Rules {
 				peerRules := s.generateIPTablesRulesForPeer(peerV4, peerV6, rule)
 				rules = append(rules, peerRules...)
@@ -354,6 +362,55 @@ func (s *Service) GenerateIPTablesRules(ctx context.Context, networkID, jumpPeer
 	return rules, nil
 }
 
+// peerPolicies returns the policies applying to a peer, in the order their
+// rules are enforced: groups by priority (lower number first, quarantine
+// groups having priority 0), then each group's policies in their order.  A
+// policy attached to several groups counts once, at its first position.
+func (s *Service) peerPolicies(ctx context.Context, networkID, peerID string) ([]*network.Policy, error) {
+	groups, err := s.groupRepo.GetPeerGroups(ctx, networkID, peerID)
+	if err != nil {
+		return nil, err
+	}
+	var out []*network.Policy
+	seen := make(map[string]bool)
+	for _, group := range groups {
+		policies, err := s.policyRepo.GetPoliciesForGroup(ctx, networkID, group.ID)
+		if err != nil {
+			continue
+		}
+		for _, policy := range policies {
+			if !seen[policy.ID] {
+				seen[policy.ID] = true
+				out = append(out, policy)
+			}
+		}
+	}
+	return out, nil
+}
+
+// activeTempRouteTargets returns the destination CIDRs of a peer's unexpired
+// temporary routes that go through the given jump peer, or through any jump
+// peer when jumpPeerID is empty.
+func (s *Service) activeTempRouteTargets(ctx context.Context, networkID, peerID, jumpPeerID string) []string {
+	grants, err := s.peerRepo.ListTempRoutes(ctx, networkID, peerID)
+	if err != nil {
+		return nil
+	}
+	now := time.Now()
+	var targets []string
+	for _, g := range grants {
+		if (jumpPeerID != "" && g.JumpPeerID != jumpPeerID) || !g.ExpiresAt.After(now) {
+			continue
+		}
+		for _, cidr := range []string{g.DestinationCIDR, g.DestinationCIDRv6} {
+			if cidr != "" {
+				targets = append(targets, cidr)
+			}
+		}
+	}
+	return targets
+}
+
 // generateIPTablesRulesForPeer converts a policy rule to iptables (or ip6tables)
 // commands for a specific peer.  Since the jump peer routes traffic, we use
 // FORWARD chain rules with the peer's IP.
@@ -397,13 +454,13 @@ func (s *Service) generateIPTablesRulesForPeer(peerV4, peerV6 string, rule netwo
 
 			if rule.Action == "allow" {
 				// Outbound: peer → destination
-				rules = append(rules, fmt.Sprintf("%s -A FORWARD -s %s -d %s -j ACCEPT", cmd, peerIP, rule.Target))
+				rules = append(rules, fmt.Sprintf("%s -A FORWARD -s %s -d %s%s -j ACCEPT", cmd, peerIP, rule.Target, l4Match(rule, isV6, "--dport")))
 
 				// Return traffic: destination → peer (established connections only)
-				rules = append(rules, fmt.Sprintf("%s -A FORWARD -d %s -s %s -m state --state RELATED,ESTABLISHED -j ACCEPT", cmd, peerIP, rule.Target))
+				rules = append(rules, fmt.Sprintf("%s -A FORWARD -d %s -s %s%s -m state --state RELATED,ESTABLISHED -j ACCEPT", cmd, peerIP, rule.Target, l4Match(rule, isV6, "--sport")))
 			} else {
 				// Deny inbound from destination to peer
-				rules = append(rules, fmt.Sprintf("%s -A FORWARD -s %s -d %s -j DROP", cmd, rule.Target, peerIP))
+				rules = append(rules, fmt.Sprintf("%s -A FORWARD -s %s -d %s%s -j DROP", cmd, rule.Target, peerIP, l4Match(rule, isV6, "--dport")))
 			}
 		case "output":
 			// "output" means traffic going FROM the peer (peer is sending)
@@ -412,13 +469,13 @@ func (s *Service) generateIPTablesRulesForPeer(peerV4, peerV6 string, rule netwo
 
 			if rule.Action == "allow" {
 				// Allow outbound: peer → destination
-				rules = append(rules, fmt.Sprintf("%s -A FORWARD -s %s -d %s -j ACCEPT", cmd, peerIP, rule.Target))
+				rules = append(rules, fmt.Sprintf("%s -A FORWARD -s %s -d %s%s -j ACCEPT", cmd, peerIP, rule.Target, l4Match(rule, isV6, "--dport")))
 
 				// Allow return traffic: destination → peer (established connections only)
-				rules = append(rules, fmt.Sprintf("%s -A FORWARD -d %s -s %s -m state --state RELATED,ESTABLISHED -j ACCEPT", cmd, peerIP, rule.Target))
+				rules = append(rules, fmt.Sprintf("%s -A FORWARD -d %s -s %s%s -m state --state RELATED,ESTABLISHED -j ACCEPT", cmd, peerIP, rule.Target, l4Match(rule, isV6, "--sport")))
 			} else {
 				// Deny outbound: peer → destination
-				rules = append(rules, fmt.Sprintf("%s -A FORWARD -s %s -d %s -j DROP", cmd, peerIP, rule.Target))
+				rules = append(rules, fmt.Sprintf("%s -A FORWARD -s %s -d %s%s -j DROP", cmd, peerIP, rule.Target, l4Match(rule, isV6, "--dport")))
 			}
 		}
 	case "peer":
@@ -433,3 +490,25 @@ func (s *Service) generateIPTablesRulesForPeer(peerV4, peerV6 string, rule netwo
 
 	return rules
 }
+
+// l4Match returns the iptables protocol/port match for a rule, with a leading
+// space, or "" when the rule matches any protocol. portFlag is "--dport" for
+// traffic towards the rule's ports and "--sport" for the replies.
+func l4Match(rule network.PolicyRule, ipv6 bool, portFlag string) string {
+	if rule.MatchesAnyProtocol() {
+		return ""
+	}
+	proto := rule.Protocol
+	if proto == network.PolicyProtocolICMP && ipv6 {
+		proto = "ipv6-icmp"
+	}
+	match := " -p " + proto
+	if first, last, ok := rule.PortRange(); ok {
+		if first == last {
+			match += fmt.Sprintf(" %s %d", portFlag, first)
+		} else {
+			match += fmt.Sprintf(" %s %d:%d", portFlag, first, last)
+		}
+	}
+	return match
+}
@@ -3,7 +3,9 @@ package policy
 import (
 	"context"
 	"fmt"
+	"strings"
 	"testing"
+	"time"
 
 	"wirety/internal/domain/network"
 
@@ -325,6 +327,18 @@ func (a *networkGetterAdapter) DeleteNetwork(ctx context.Context, networkID stri
 func (a *networkGetterAdapter) ListNetworks(ctx context.Context) ([]*network.Network, error) {
 	return nil, nil
 }
+func (a *networkGetterAdapter) SoftDeleteNetwork(ctx context.Context, networkID string, at time.Time) error {
+	return nil
+}
+func (a *networkGetterAdapter) RestoreNetwork(ctx context.Context, networkID string) error {
+	return nil
+}
+func (a *networkGetterAdapter) ListDeletedNetworks(ctx context.Context) ([]*network.Network, error) {
+	return nil, nil
+}
+func (a *networkGetterAdapter) SearchPeers(ctx context.Context, query string, offset, limit int) ([]*network.PeerSearchResult, int, error) {
+	return nil, 0, nil
+}
 func (a *networkGetterAdapter) CreatePeer(ctx context.Context, networkID string, peer *network.Peer) error {
 	return nil
 }
@@ -365,6 +379,9 @@ func (a *networkGetterAdapter) ListConnections(ctx context.Context, networkID st
 func (a *networkGetterAdapter) DeleteConnection(ctx context.Context, networkID, peer1ID, peer2ID string) error {
 	return nil
 }
+func (a *networkGetterAdapter) UpdateConnection(ctx context.Context, networkID string, conn *network.PeerConnection) error {
+	return nil
+}
 func (a *networkGetterAdapter) CreateOrUpdateSession(ctx context.Context, networkID string, session *network.AgentSession) error {
 	return nil
 }
@@ -380,6 +397,12 @@ func (a *networkGetterAdapter) DeleteSession(ctx context.Context, networkID, ses
 func (a *networkGetterAdapter) ListSessions(ctx context.Context, networkID string) ([]*network.AgentSession, error) {
 	return nil, nil
 }
+func (a *networkGetterAdapter) ListSessionHistory(ctx context.Context, networkID, peerID string) ([]*network.AgentSession, error) {
+	return nil, nil
+}
+func (a *networkGetterAdapter) RevokeSession(ctx context.Context, networkID, sessionID string, revokedAt time.Time) error {
+	return nil
+}
 func (a *networkGetterAdapter) AddCaptivePortalWhitelist(ctx context.Context, networkID, jumpPeerID, peerIP, peerEndpoint string) error {
 	return nil
 }
@@ -456,6 +479,42 @@ func (a *networkGetterAdapter) ListPeerLocalRoutes(ctx context.Context, networkI
 	return nil, nil
 }
 
+func (a *networkGetterAdapter) CreateTempRoute(ctx context.Context, route *network.TempRoute) error {
+	return nil
+}
+
+func (a *networkGetterAdapter) ListTempRoutes(ctx context.Context, networkID, peerID string) ([]*network.TempRoute, error) {
+	return nil, nil
+}
+
+func (a *networkGetterAdapter) DeleteTempRoute(ctx context.Context, networkID, id string) error {
+	return nil
+}
+
+func (a *networkGetterAdapter) ListExpiredTempRoutes(ctx context.Context, before time.Time) ([]*network.TempRoute, error) {
+	return nil, nil
+}
+
+func (a *networkGetterAdapter) CreatePeerProfile(ctx context.Context, profile *network.PeerProfile) error {
+	return nil
+}
+
+func (a *networkGetterAdapter) GetPeerProfile(ctx context.Context, networkID, profileID string) (*network.PeerProfile, error) {
+	return nil, network.ErrPeerProfileNotFound
+}
+
+func (a *networkGetterAdapter) UpdatePeerProfile(ctx context.Context, profile *network.PeerProfile) error {
+	return nil
+}
+
+func (a *networkGetterAdapter) DeletePeerProfile(ctx context.Context, networkID, profileID string) error {
+	return nil
+}
+
+func (a *networkGetterAdapter) ListPeerProfiles(ctx context.Context, networkID string) ([]*network.PeerProfile, error) {
+	return nil, nil
+}
+
 // Generators for property-based testing
 
 func genValidPolicyName() gopter.Gen {
@@ -532,6 +591,14 @@ func genPolicyRule() gopter.Gen {
 	})
 }
 
+func genProtocol() gopter.Gen {
+	return gen.OneConstOf("", "any", "tcp", "udp", "icmp", "sctp")
+}
+
+func genPorts() gopter.Gen {
+	return gen.OneConstOf("", "22", "443", "8000-8100", "0", "65536", "9000-8000", "http", "1-65535")
+}
+
 // Property Tests
 
 // **Feature: network-groups-policies-routing, Property 8: Policy creation completeness**
@@ -1269,3 +1336,97 @@ func TestProperty_DefaultDenyBehavior(t *testing.T) {
 
 	properties.TestingRun(t, gopter.ConsoleReporter(false))
 }
+
+// **Feature: policy-l4-matching, Property 1: Protocol and port validation**
+// **Validates: protocol/ports on PolicyRule**
+func TestProperty_PolicyRuleL4Validation(t *testing.T) {
+	properties := gopter.NewProperties(nil)
+
+	properties.Property("Feature: policy-l4-matching, Property 1: Protocol and port validation",
+		prop.ForAll(
+			func(cidr, protocol, ports string) bool {
+				rule := network.PolicyRule{
+					Direction:  "output",
+					Action:     "allow",
+					Target:     cidr,
+					TargetType: "cidr",
+					Protocol:   protocol,
+					Ports:      ports,
+				}
+				err := rule.Validate()
+
+				validProtocol := protocol == "" || protocol == "any" || protocol == "tcp" || protocol == "udp" || protocol == "icmp"
+				validPorts := ports == "" ||
+					((protocol == "tcp" || protocol == "udp") && ports != "0" && ports != "65536" && ports != "9000-8000" && ports != "http")
+
+				return (err == nil) == (validProtocol && validPorts)
+			},
+			genCIDR(),
+			genProtocol(),
+			genPorts(),
+		))
+
+	properties.TestingRun(t, gopter.ConsoleReporter(false))
+}
+
+// **Feature: policy-l4-matching, Property 2: Layer-4 match generation**
+// **Validates: protocol/ports in generated iptables rules**
+func TestProperty_PolicyRuleL4Generation(t *testing.T) {
+	properties := gopter.NewProperties(nil)
+
+	properties.Property("Feature: policy-l4-matching, Property 2: Layer-4 match generation",
+		prop.ForAll(
+			func(direction, action, cidr, protocol, ports string) bool {
+				rule := network.PolicyRule{
+					Direction:  direction,
+					Action:     action,
+					Target:     cidr,
+					TargetType: "cidr",
+					Protocol:   protocol,
+					Ports:      ports,
+				}
+				if rule.Validate() != nil {
+					return true // only valid rules reach the generator
+				}
+
+				rules := (&Service{}).generateIPTablesRulesForPeer("10.0.0.2", "", rule)
+				if len(rules) == 0 {
+					return false
+				}
+				for _, r := range rules {
+					if rule.MatchesAnyProtocol() {
+						// Rules without protocol keep matching all traffic
+						if strings.Contains(r, " -p ") || strings.Contains(r, "port") {
+							return false
+						}
+						continue
+					}
+					if !strings.Contains(r, " -p "+protocol) {
+						return false
+					}
+					if ports == "" {
+						if strings.Contains(r, "port") {
+							return false
+						}
+						continue
+					}
+					// Traffic towards the ports matches --dport, replies --sport
+					want := " --dport " + strings.Replace(ports, "-", ":", 1)
+					if strings.Contains(r, "RELATED,ESTABLISHED") {
+						want = " --sport " + strings.Replace(ports, "-", ":", 1)
+					}
+					if !strings.Contains(r, want) {
+						return false
+					}
+				}
+				return true
+			},
+			genDirection(),
+			genAction(),
+			genCIDR(),
+			genProtocol(),
+			genPorts(),
+		))
+
+	properties.TestingRun(t, gopter.ConsoleReporter(false))
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"time"
 
+	"wirety/internal/audit"
 	"wirety/internal/domain/network"
 
 	"github.com/google/uuid"
@@ -17,10 +18,11 @@ type WebSocketNotifier interface {
 
 // Service implements the business logic for route management
 type Service struct {
-	routeRepo  network.RouteRepository
-	groupRepo  network.GroupRepository
-	peerRepo   network.Repository
-	wsNotifier WebSocketNotifier
+	routeRepo   network.RouteRepository
+	groupRepo   network.GroupRepository
+	peerRepo    network.Repository
+	wsNotifier  WebSocketNotifier
+	auditLogger audit.AuditLogger
 }
 
 // NewService creates a new route service
@@ -37,6 +39,11 @@ func (s *Service) SetWebSocketNotifier(notifier WebSocketNotifier) {
 	s.wsNotifier = notifier
 }
 
+// SetAuditLogger sets the audit log that records mutating operations
+func (s *Service) SetAuditLogger(l audit.AuditLogger) {
+	s.auditLogger = l
+}
+
 // CreateRoute creates a new route with CIDR and jump peer validation
 func (s *Service) CreateRoute(ctx context.Context, networkID string, req *network.RouteCreateRequest) (*network.Route, error) {
 	// Validate request
@@ -50,13 +57,8 @@ func (s *Service) CreateRoute(ctx context.Context, networkID string, req *networ
 		return nil, fmt.Errorf("network not found: %w", err)
 	}
 
-	// Verify jump peer exists and is a jump peer
-	jumpPeer, err := s.peerRepo.GetPeer(ctx, networkID, req.JumpPeerID)
-	if err != nil {
-		return nil, fmt.Errorf("jump peer not found: %w", err)
-	}
-	if !jumpPeer.IsJump {
-		return nil, fmt.Errorf("peer is not a jump peer")
+	if err := s.checkJumpPeer(ctx, networkID, req.JumpPeerID); err != nil {
+		return nil, err
 	}
 
 	now := time.Now()
@@ -74,6 +76,8 @@ func (s *Service) CreateRoute(ctx context.Context, networkID string, req *networ
 		DestinationCIDRv6: req.DestinationCIDRv6,
 		JumpPeerID:        req.JumpPeerID,
 		DomainSuffix:      domainSuffix,
+		Metric:            req.Metric,
+		GlobalRoute:       req.GlobalRoute,
 		CreatedAt:         now,
 		UpdatedAt:         now,
 	}
@@ -82,9 +86,29 @@ func (s *Service) CreateRoute(ctx context.Context, networkID string, req *networ
 		return nil, fmt.Errorf("failed to create route: %w", err)
 	}
 
+	// A global route reaches every peer right away; others wait for a group
+	if route.GlobalRoute && s.wsNotifier != nil {
+		s.wsNotifier.NotifyNetworkPeers(networkID)
+	}
+
+	audit.Record(ctx, s.auditLogger, "route.create", networkID, "", route.ID)
+
 	return route, nil
 }
 
+// checkJumpPeer verifies that peerID names a jump peer of the network.  A
+// route through any other peer would never get a gateway [Peer] section.
+func (s *Service) checkJumpPeer(ctx context.Context, networkID, peerID string) error {
+	peer, err := s.peerRepo.GetPeer(ctx, networkID, peerID)
+	if err != nil {
+		return fmt.Errorf("%w: no peer %q in the network", network.ErrJumpPeerNotFound, peerID)
+	}
+	if !peer.IsJump {
+		return fmt.Errorf("%w: %q is a regular peer", network.ErrNotJumpPeer, peer.Name)
+	}
+	return nil
+}
+
 // GetRoute retrieves a route by ID
 func (s *Service) GetRoute(ctx context.Context, networkID, routeID string) (*network.Route, error) {
 	route, err := s.routeRepo.GetRoute(ctx, networkID, routeID)
@@ -127,19 +151,20 @@ func (s *Service) UpdateRoute(ctx context.Context, networkID, routeID string, re
 		return nil, fmt.Errorf("validation failed: at least one of destination_cidr or destination_cidr_v6 must remain set")
 	}
 	if req.JumpPeerID != "" {
-		// Verify new jump peer exists and is a jump peer
-		jumpPeer, err := s.peerRepo.GetPeer(ctx, networkID, req.JumpPeerID)
-		if err != nil {
-			return nil, fmt.Errorf("jump peer not found: %w", err)
-		}
-		if !jumpPeer.IsJump {
-			return nil, fmt.Errorf("peer is not a jump peer")
+		if err := s.checkJumpPeer(ctx, networkID, req.JumpPeerID); err != nil {
+			return nil, err
 		}
 		route.JumpPeerID = req.JumpPeerID
 	}
 	if req.DomainSuffix != "" {
 		route.DomainSuffix = req.DomainSuffix
 	}
+	if req.Metric != nil {
+		route.Metric = *req.Metric
+	}
+	if req.GlobalRoute != nil {
+		route.GlobalRoute = *req.GlobalRoute
+	}
 	route.UpdatedAt = time.Now()
 
 	if err := s.routeRepo.UpdateRoute(ctx, networkID, route); err != nil {
@@ -151,6 +176,8 @@ func (s *Service) UpdateRoute(ctx context.Context, networkID, routeID string, re
 		s.wsNotifier.NotifyNetworkPeers(networkID)
 	}
 
+	audit.Record(ctx, s.auditLogger, "route.update", networkID, "", routeID)
+
 	return route, nil
 }
 
@@ -171,6 +198,8 @@ func (s *Service) DeleteRoute(ctx context.Context, networkID, routeID string) er
 		s.wsNotifier.NotifyNetworkPeers(networkID)
 	}
 
+	audit.Record(ctx, s.auditLogger, "route.delete", networkID, "", routeID)
+
 	return nil
 }
 
@@ -190,10 +219,11 @@ func (s *Service) ListRoutes(ctx context.Context, networkID string) ([]*network.
 	return routes, nil
 }
 
-// GetPeerRoutes calculates routes for a peer based on group membership
+// GetPeerRoutes calculates routes for a peer based on group membership, plus
+// the network's global routes for regular peers
 func (s *Service) GetPeerRoutes(ctx context.Context, networkID, peerID string) ([]*network.Route, error) {
 	// Verify peer exists
-	_, err := s.peerRepo.GetPeer(ctx, networkID, peerID)
+	peer, err := s.peerRepo.GetPeer(ctx, networkID, peerID)
 	if err != nil {
 		return nil, fmt.Errorf("peer not found: %w", err)
 	}
@@ -216,6 +246,19 @@ func (s *Service) GetPeerRoutes(ctx context.Context, networkID, peerID string) (
 		}
 	}
 
+	// Global routes reach every regular peer
+	if !peer.IsJump {
+		all, err := s.routeRepo.ListRoutes(ctx, networkID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list routes: %w", err)
+		}
+		for _, route := range all {
+			if route.GlobalRoute {
+				routeMap[route.ID] = route
+			}
+		}
+	}
+
 	// Convert map to slice
 	var routes []*network.Route
 	for _, route := range routeMap {
@@ -2,7 +2,9 @@ package route
 
 import (
 	"context"
+	"errors"
 	"testing"
+	"time"
 
 	"wirety/internal/domain/network"
 
@@ -260,6 +262,18 @@ func (a *networkGetterAdapter) DeleteNetwork(ctx context.Context, networkID stri
 func (a *networkGetterAdapter) ListNetworks(ctx context.Context) ([]*network.Network, error) {
 	return nil, nil
 }
+func (a *networkGetterAdapter) SoftDeleteNetwork(ctx context.Context, networkID string, at time.Time) error {
+	return nil
+}
+func (a *networkGetterAdapter) RestoreNetwork(ctx context.Context, networkID string) error {
+	return nil
+}
+func (a *networkGetterAdapter) ListDeletedNetworks(ctx context.Context) ([]*network.Network, error) {
+	return nil, nil
+}
+func (a *networkGetterAdapter) SearchPeers(ctx context.Context, query string, offset, limit int) ([]*network.PeerSearchResult, int, error) {
+	return nil, 0, nil
+}
 func (a *networkGetterAdapter) CreatePeer(ctx context.Context, networkID string, peer *network.Peer) error {
 	return nil
 }
@@ -296,6 +310,9 @@ func (a *networkGetterAdapter) ListConnections(ctx context.Context, networkID st
 func (a *networkGetterAdapter) DeleteConnection(ctx context.Context, networkID, peer1ID, peer2ID string) error {
 	return nil
 }
+func (a *networkGetterAdapter) UpdateConnection(ctx context.Context, networkID string, conn *network.PeerConnection) error {
+	return nil
+}
 func (a *networkGetterAdapter) CreateOrUpdateSession(ctx context.Context, networkID string, session *network.AgentSession) error {
 	return nil
 }
@@ -311,6 +328,12 @@ func (a *networkGetterAdapter) DeleteSession(ctx context.Context, networkID, ses
 func (a *networkGetterAdapter) ListSessions(ctx context.Context, networkID string) ([]*network.AgentSession, error) {
 	return nil, nil
 }
+func (a *networkGetterAdapter) ListSessionHistory(ctx context.Context, networkID, peerID string) ([]*network.AgentSession, error) {
+	return nil, nil
+}
+func (a *networkGetterAdapter) RevokeSession(ctx context.Context, networkID, sessionID string, revokedAt time.Time) error {
+	return nil
+}
 func (a *networkGetterAdapter) AddCaptivePortalWhitelist(ctx context.Context, networkID, jumpPeerID, peerIP, peerEndpoint string) error {
 	return nil
 }
@@ -387,6 +410,42 @@ func (a *networkGetterAdapter) ListPeerLocalRoutes(ctx context.Context, networkI
 	return nil, nil
 }
 
+func (a *networkGetterAdapter) CreateTempRoute(ctx context.Context, route *network.TempRoute) error {
+	return nil
+}
+
+func (a *networkGetterAdapter) ListTempRoutes(ctx context.Context, networkID, peerID string) ([]*network.TempRoute, error) {
+	return nil, nil
+}
+
+func (a *networkGetterAdapter) DeleteTempRoute(ctx context.Context, networkID, id string) error {
+	return nil
+}
+
+func (a *networkGetterAdapter) ListExpiredTempRoutes(ctx context.Context, before time.Time) ([]*network.TempRoute, error) {
+	return nil, nil
+}
+
+func (a *networkGetterAdapter) CreatePeerProfile(ctx context.Context, profile *network.PeerProfile) error {
+	return nil
+}
+
+func (a *networkGetterAdapter) GetPeerProfile(ctx context.Context, networkID, profileID string) (*network.PeerProfile, error) {
+	return nil, network.ErrPeerProfileNotFound
+}
+
+func (a *networkGetterAdapter) UpdatePeerProfile(ctx context.Context, profile *network.PeerProfile) error {
+	return nil
+}
+
+func (a *networkGetterAdapter) DeletePeerProfile(ctx context.Context, networkID, profileID string) error {
+	return nil
+}
+
+func (a *networkGetterAdapter) ListPeerProfiles(ctx context.Context, networkID string) ([]*network.PeerProfile, error) {
+	return nil, nil
+}
+
 // Generators for property-based testing
 
 func genValidRouteName() gopter.Gen {
@@ -590,7 +649,37 @@ func TestProperty_RouteJumpPeerValidation(t *testing.T) {
 				})
 
 				// Verify that non-jump peer is rejected
-				return err != nil
+				if !errors.Is(err, network.ErrNotJumpPeer) {
+					return false
+				}
+
+				// A jump peer ID that names no peer is rejected too
+				_, err = service.CreateRoute(ctx, networkID, &network.RouteCreateRequest{
+					Name:            "test-route",
+					DestinationCIDR: cidr,
+					JumpPeerID:      "missing-" + regularPeerID,
+				})
+				if !errors.Is(err, network.ErrJumpPeerNotFound) {
+					return false
+				}
+
+				// Moving an existing route to a non-jump peer fails and
+				// leaves the route untouched
+				netGetter.peers["jump"] = &network.Peer{ID: "jump", Name: "jump", IsJump: true}
+				route, err := service.CreateRoute(ctx, networkID, &network.RouteCreateRequest{
+					Name:            "test-route",
+					DestinationCIDR: cidr,
+					JumpPeerID:      "jump",
+				})
+				if err != nil {
+					return false
+				}
+				_, err = service.UpdateRoute(ctx, networkID, route.ID, &network.RouteUpdateRequest{JumpPeerID: regularPeerID})
+				if !errors.Is(err, network.ErrNotJumpPeer) {
+					return false
+				}
+				stored, err := routeRepo.GetRoute(ctx, networkID, route.ID)
+				return err == nil && stored.JumpPeerID == "jump"
 			},
 			genNetworkID(),
 			genValidCIDR(),
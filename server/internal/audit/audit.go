@@ -1,6 +1,7 @@
 package audit
 
 import (
+	"io"
 	"os"
 	"sync"
 
@@ -19,14 +20,20 @@ func init() { logger = zerolog.Nop() }
 func Init(enabled bool) {
 	once.Do(func() {
 		if enabled {
-			logger = zerolog.New(os.Stdout).With().
-				Timestamp().
-				Str("log_type", "audit").
-				Logger()
+			SetOutput(os.Stdout)
 		}
 	})
 }
 
+// SetOutput enables the audit logger and writes events as JSON to w.
+// Tests use it to capture audit events.
+func SetOutput(w io.Writer) {
+	logger = zerolog.New(w).With().
+		Timestamp().
+		Str("log_type", "audit").
+		Logger()
+}
+
 // Server returns a pre-populated event for server-side audit entries.
 // The caller must call .Msg("audit") to emit the event.
 func Server(actorID, actorEmail, remoteIP string) *zerolog.Event {
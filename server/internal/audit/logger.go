@@ -0,0 +1,75 @@
+package audit
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// Entry is a persisted record of a mutating operation.
+type Entry struct {
+	ID         string    `json:"id"`
+	ActorID    string    `json:"actor_id"`
+	ActorEmail string    `json:"actor_email,omitempty"`
+	Action     string    `json:"action"` // e.g. "peer.create", "group.peer_add"
+	NetworkID  string    `json:"network_id,omitempty"`
+	PeerID     string    `json:"peer_id,omitempty"`
+	ResourceID string    `json:"resource_id,omitempty"` // group, policy, route, DNS mapping, ... ID
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// AuditLogger is an append-only store of audit entries.
+type AuditLogger interface {
+	// Record appends an entry.
+	Record(ctx context.Context, entry *Entry) error
+	// List returns a network's entries newest first, skipping offset entries
+	// and returning at most limit, along with the total number of entries.
+	List(ctx context.Context, networkID string, offset, limit int) ([]*Entry, int, error)
+}
+
+type actorKey struct{}
+
+type actor struct {
+	id, email string
+}
+
+// WithActor returns a copy of ctx carrying the user performing the request.
+func WithActor(ctx context.Context, userID, email string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor{id: userID, email: email})
+}
+
+// ActorFromContext returns the user set by WithActor, if any.
+func ActorFromContext(ctx context.Context) (userID, email string) {
+	a, _ := ctx.Value(actorKey{}).(actor)
+	return a.id, a.email
+}
+
+// Record stores an entry for the actor in ctx.  A nil logger is a no-op.
+// The operation being audited has already happened, so a failure to record it
+// is logged rather than returned.
+func Record(ctx context.Context, l AuditLogger, action, networkID, peerID, resourceID string) {
+	if l == nil {
+		return
+	}
+	actorID, actorEmail := ActorFromContext(ctx)
+	entry := &Entry{
+		ID:         uuid.New().String(),
+		ActorID:    actorID,
+		ActorEmail: actorEmail,
+		Action:     action,
+		NetworkID:  networkID,
+		PeerID:     peerID,
+		ResourceID: resourceID,
+		CreatedAt:  time.Now(),
+	}
+	// The request may be cancelled once the response is written.
+	if err := l.Record(context.WithoutCancel(ctx), entry); err != nil {
+		log.Error().Err(err).
+			Str("action", action).
+			Str("network_id", networkID).
+			Str("actor_id", actorID).
+			Msg("failed to record audit entry")
+	}
+}
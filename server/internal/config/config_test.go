@@ -16,8 +16,8 @@ func TestLoadConfig_DefaultValues(t *testing.T) {
 		t.Errorf("Expected HTTPPort to be '8080', got '%s'", config.HTTPPort)
 	}
 
-	if len(config.CORSOrigins) != 1 || config.CORSOrigins[0] != "*" {
-		t.Errorf("Expected CORSOrigins to be ['*'], got %v", config.CORSOrigins)
+	if len(config.CORSOrigins) != 0 || config.CORSAllowAll {
+		t.Errorf("Expected no CORS origin and CORSAllowAll off, got %v and %v", config.CORSOrigins, config.CORSAllowAll)
 	}
 
 	// Test Auth defaults
@@ -305,8 +305,10 @@ func TestAuthConfig_BooleanParsing(t *testing.T) {
 func clearEnvVars() {
 	envVars := []string{
 		"HTTP_PORT",
+		"BIND_ADDRESS",
 		"CORS_ORIGIN",
 		"ALLOWED_ORIGIN",
+		"CORS_ALLOW_ALL",
 		"AUTH_ENABLED",
 		"AUTH_ISSUER_URL",
 		"AUTH_CLIENT_ID",
@@ -321,3 +323,70 @@ func clearEnvVars() {
 		_ = os.Unsetenv(env)
 	}
 }
+
+func TestValidateBindAddress(t *testing.T) {
+	tests := []struct {
+		addr    string
+		wantErr bool
+	}{
+		{"0.0.0.0", false},
+		{"127.0.0.1", false},
+		{"::", false},
+		{"::1", false},
+		{"localhost", false},
+		{"wirety.internal", false},
+		{"", true},
+		{"127.0.0.1:8080", true},
+		{"[::1]", true},
+		{"-bad.example.com", true},
+		{"host name", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			err := ValidateBindAddress(tt.addr)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateBindAddress(%q) error = %v, wantErr %v", tt.addr, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConfig_ListenAddress(t *testing.T) {
+	clearEnvVars()
+	defer clearEnvVars()
+
+	if got := LoadConfig().ListenAddress(); got != "0.0.0.0:8080" {
+		t.Errorf("default ListenAddress() = %q, want 0.0.0.0:8080", got)
+	}
+
+	_ = os.Setenv("BIND_ADDRESS", "::1")
+	_ = os.Setenv("HTTP_PORT", "9090")
+	if got := LoadConfig().ListenAddress(); got != "[::1]:9090" {
+		t.Errorf("ListenAddress() = %q, want [::1]:9090", got)
+	}
+}
+
+func TestValidateCORSOrigins(t *testing.T) {
+	tests := []struct {
+		origins []string
+		wantErr bool
+	}{
+		{nil, false},
+		{[]string{"https://app.example.com", "http://localhost:5173"}, false},
+		{[]string{"https://[2001:db8::1]:8443"}, false},
+		{[]string{"*"}, false},
+		{[]string{"*", "https://app.example.com"}, true},
+		{[]string{"app.example.com"}, true},
+		{[]string{"https://app.example.com/"}, true},
+		{[]string{"https://app.example.com/dashboard"}, true},
+		{[]string{"ftp://app.example.com"}, true},
+	}
+
+	for _, tt := range tests {
+		err := ValidateCORSOrigins(tt.origins)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ValidateCORSOrigins(%v) error = %v, wantErr %v", tt.origins, err, tt.wantErr)
+		}
+	}
+}
@@ -8,14 +8,25 @@ type Role string
 const (
 	RoleAdministrator Role = "administrator"
 	RoleUser          Role = "user"
+	// RoleAuditor can read everything across all networks but change nothing.
+	RoleAuditor Role = "auditor"
 )
 
+// Valid reports whether r is a known role.
+func (r Role) Valid() bool {
+	switch r {
+	case RoleAdministrator, RoleUser, RoleAuditor:
+		return true
+	}
+	return false
+}
+
 // User represents a user in the system
 type User struct {
 	ID                 string    `json:"id"`                  // OIDC subject ID, or generated UUID for locally-created users
 	Email              string    `json:"email"`               // User email
 	Name               string    `json:"name"`                // Display name
-	Role               Role      `json:"role"`                // User role (administrator or user)
+	Role               Role      `json:"role"`                // User role (administrator, user or auditor)
 	AuthorizedNetworks []string  `json:"authorized_networks"` // Network IDs the user can access
 	PasswordHash       string    `json:"-"`                   // bcrypt hash; only set for locally-created users (AUTH_ENABLED=false). Never serialised.
 	CreatedAt          time.Time `json:"created_at"`
@@ -48,9 +59,21 @@ func (u *User) IsAdministrator() bool {
 	return u.Role == RoleAdministrator
 }
 
-// HasNetworkAccess checks if the user has access to a specific network
+// IsAuditor checks if the user has the read-only auditor role
+func (u *User) IsAuditor() bool {
+	return u.Role == RoleAuditor
+}
+
+// CanViewAll reports whether the user may read every resource, regardless of
+// network authorization or ownership: administrators and auditors.
+func (u *User) CanViewAll() bool {
+	return u.IsAdministrator() || u.IsAuditor()
+}
+
+// HasNetworkAccess checks if the user has access to a specific network.
+// Auditors can access every network, read-only.
 func (u *User) HasNetworkAccess(networkID string) bool {
-	if u.IsAdministrator() {
+	if u.CanViewAll() {
 		return true
 	}
 	for _, id := range u.AuthorizedNetworks {
@@ -67,6 +90,9 @@ func (u *User) CanManagePeer(networkID string, peerOwnerID string) bool {
 	if u.IsAdministrator() {
 		return true
 	}
+	if u.IsAuditor() {
+		return false
+	}
 	// Regular users can only manage their own peers in authorized networks
 	return u.HasNetworkAccess(networkID) && peerOwnerID == u.ID
 }
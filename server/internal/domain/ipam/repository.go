@@ -16,4 +16,21 @@ type Repository interface {
 	ListChildPrefixes(ctx context.Context, parentCIDR string) ([]*network.IPAMPrefix, error)
 	AcquireIP(ctx context.Context, cidr string) (string, error)
 	ReleaseIP(ctx context.Context, cidr string, ip string) error
+	// AcquireSpecificIP allocates ip from cidr, failing if it is already taken.
+	AcquireSpecificIP(ctx context.Context, cidr string, ip string) error
+	// ReserveIP takes ip out of cidr's pool for good, so AcquireIP never
+	// returns it.  Reservations are kept apart from peer allocations.
+	ReserveIP(ctx context.Context, cidr string, ip string) error
+	// ReleaseReservation returns a reserved ip to the pool, failing with
+	// network.ErrReservationNotFound if it is not reserved.
+	ReleaseReservation(ctx context.Context, cidr string, ip string) error
+	// ListReservations returns the reserved addresses of cidr.
+	ListReservations(ctx context.Context, cidr string) ([]string, error)
+	// AcquiredIPs returns the number of addresses taken from cidr's pool:
+	// allocations and reservations, plus the addresses the engine blocks
+	// itself (the first address, and the broadcast address for IPv4).
+	AcquiredIPs(ctx context.Context, cidr string) (uint64, error)
+	// ListAllocatedIPs returns the addresses allocated from cidr, without
+	// the reservations.
+	ListAllocatedIPs(ctx context.Context, cidr string) ([]network.IPAllocation, error)
 }
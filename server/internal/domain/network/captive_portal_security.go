@@ -33,6 +33,23 @@ type EndpointDenylistEntry struct {
 // capture-portal flow again.
 const EndpointDenylistDefaultTTL = 24 * time.Hour
 
+// SecurityIncident describes a security event raised by the server: a peer
+// quarantined after repeated captive-portal failures, or a rogue source taking
+// over an authenticated peer's endpoint.  Incidents are not persisted; they are
+// counted in the security_incidents_total metric and delivered to the
+// configured webhook.
+type SecurityIncident struct {
+	ID           string    `json:"id"`
+	IncidentType string    `json:"incident_type"` // "quarantine" or "endpoint_takeover"
+	NetworkID    string    `json:"network_id"`
+	PeerID       string    `json:"peer_id,omitempty"`
+	JumpPeerID   string    `json:"jump_peer_id,omitempty"` // jump peer that reported the incident
+	WgIP         string    `json:"wg_ip,omitempty"`        // targeted peer's WireGuard private IP
+	Endpoints    []string  `json:"endpoints,omitempty"`    // public endpoints involved
+	Details      string    `json:"details"`
+	DetectedAt   time.Time `json:"detected_at"`
+}
+
 // CaptivePortalQuarantine represents the strike state for a peer's captive
 // portal authentication attempts.  After QuarantineStrikeThreshold consecutive
 // failed/abandoned auth attempts (token issued but never converted into a
@@ -23,6 +23,119 @@ type DNSMapping struct {
 	UpdatedAt   time.Time `json:"updated_at"`
 }
 
+// NetworkDNSRecord is a DNS record scoped directly to a network instead of a
+// route, for names that point at addresses no route covers (e.g. a SaaS
+// dashboard reached over the internet).  It resolves in the same
+// <name>.<network>.<suffix> namespace as route mappings and is created and
+// updated with DNSMappingCreateRequest / DNSMappingUpdateRequest.
+type NetworkDNSRecord struct {
+	ID          string `json:"id"`
+	NetworkID   string `json:"network_id"`
+	Name        string `json:"name"`
+	IPAddress   string `json:"ip_address,omitempty"`
+	IPv6Address string `json:"ip_address_v6,omitempty"`
+	// Type is the record type (DNSRecordTypeA when empty).  CNAME and TXT
+	// records leave the addresses empty and carry their target or text in
+	// Value; they are only created by a zone file import.
+	Type      string    `json:"type,omitempty"`
+	Value     string    `json:"value,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Record types of a NetworkDNSRecord
+const (
+	DNSRecordTypeA     = "A" // IPAddress and/or IPv6Address
+	DNSRecordTypeCNAME = "CNAME"
+	DNSRecordTypeTXT   = "TXT"
+)
+
+// RecordType returns the record type, DNSRecordTypeA when unset.
+func (d *NetworkDNSRecord) RecordType() string {
+	if d.Type == "" {
+		return DNSRecordTypeA
+	}
+	return d.Type
+}
+
+// IsAddress reports whether the record resolves to its IP addresses.
+func (d *NetworkDNSRecord) IsAddress() bool {
+	return d.RecordType() == DNSRecordTypeA
+}
+
+// Validate checks the name of the record and the fields its type uses.
+func (d *NetworkDNSRecord) Validate() error {
+	if err := validateDNSName(d.Name); err != nil {
+		return err
+	}
+	switch d.RecordType() {
+	case DNSRecordTypeA:
+		return (&DNSMappingCreateRequest{Name: d.Name, IPAddress: d.IPAddress, IPv6Address: d.IPv6Address}).Validate()
+	case DNSRecordTypeCNAME:
+		if d.IPAddress != "" || d.IPv6Address != "" {
+			return errors.New("a CNAME record has no IP address")
+		}
+		return validateDNSTarget(d.Value)
+	case DNSRecordTypeTXT:
+		if d.IPAddress != "" || d.IPv6Address != "" {
+			return errors.New("a TXT record has no IP address")
+		}
+		if d.Value == "" {
+			return errors.New("TXT record text cannot be empty")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported DNS record type %q", d.Type)
+	}
+}
+
+// ConflictsWith reports whether the record cannot coexist with other in the
+// same network: a name holds one address record, one CNAME and nothing
+// else, or any number of distinct TXT records.
+func (d *NetworkDNSRecord) ConflictsWith(other *NetworkDNSRecord) bool {
+	if d.ID == other.ID || d.NetworkID != other.NetworkID || d.Name != other.Name {
+		return false
+	}
+	t, ot := d.RecordType(), other.RecordType()
+	switch {
+	case t == DNSRecordTypeCNAME || ot == DNSRecordTypeCNAME:
+		return true
+	case t != ot:
+		return false
+	case t == DNSRecordTypeTXT:
+		return d.Value == other.Value
+	default:
+		return true
+	}
+}
+
+// validateDNSTarget validates the fully qualified target of a CNAME record,
+// with or without the trailing dot.
+func validateDNSTarget(target string) error {
+	name := strings.TrimSuffix(target, ".")
+	if name == "" || len(name) > 253 {
+		return fmt.Errorf("invalid CNAME target %q", target)
+	}
+	for _, label := range strings.Split(name, ".") {
+		if label == "" || len(label) > 63 || strings.HasPrefix(label, "-") || strings.HasSuffix(label, "-") {
+			return fmt.Errorf("invalid CNAME target %q", target)
+		}
+		for _, ch := range label {
+			if (ch < 'a' || ch > 'z') && (ch < 'A' || ch > 'Z') &&
+				(ch < '0' || ch > '9') && ch != '-' && ch != '_' {
+				return fmt.Errorf("invalid CNAME target %q", target)
+			}
+		}
+	}
+	return nil
+}
+
+// GetFQDN returns the fully qualified domain name of the record, in the same
+// format as DNSMapping.GetFQDN.
+func (d *NetworkDNSRecord) GetFQDN(network *Network) string {
+	return (&DNSMapping{Name: d.Name}).GetFQDN(network)
+}
+
 // DNSMappingCreateRequest represents the data needed to create a new DNS
 // mapping.  At least one of IPAddress / IPv6Address must be provided.
 type DNSMappingCreateRequest struct {
@@ -13,4 +13,11 @@ type DNSRepository interface {
 
 	// Get all DNS mappings for a network (for DNS server configuration)
 	GetNetworkDNSMappings(ctx context.Context, networkID string) ([]*DNSMapping, error)
+
+	// Network-scoped DNS record CRUD operations
+	CreateNetworkDNSRecord(ctx context.Context, record *NetworkDNSRecord) error
+	GetNetworkDNSRecord(ctx context.Context, networkID, recordID string) (*NetworkDNSRecord, error)
+	UpdateNetworkDNSRecord(ctx context.Context, record *NetworkDNSRecord) error
+	DeleteNetworkDNSRecord(ctx context.Context, networkID, recordID string) error
+	ListNetworkDNSRecords(ctx context.Context, networkID string) ([]*NetworkDNSRecord, error)
 }
@@ -0,0 +1,66 @@
+package network
+
+// NetworkDocument is a complete proposed network definition (network, peers,
+// groups, routes and policies) that can be validated as a whole before
+// anything is created, and imported. Since nothing exists yet, entities
+// reference each other by name rather than by ID: routes name their jump peer
+// in Peers, and policy rules with target_type "peer" or "group" name their
+// target.
+type NetworkDocument struct {
+	Network  NetworkCreateRequest  `json:"network"`
+	Peers    []PeerCreateRequest   `json:"peers"`
+	Groups   []GroupDocument       `json:"groups,omitempty"`
+	Routes   []RouteCreateRequest  `json:"routes"`
+	Policies []PolicyCreateRequest `json:"policies"`
+}
+
+// GroupDocument is a group of a NetworkDocument with its members, by name.
+// Policies are attached in the listed order.
+type GroupDocument struct {
+	GroupCreateRequest
+	Peers    []string `json:"peers,omitempty"`
+	Policies []string `json:"policies,omitempty"`
+	Routes   []string `json:"routes,omitempty"`
+}
+
+// Validation issue severities. Errors would make creation fail or leave the
+// network unusable; warnings are accepted but probably not intended.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// ValidationIssue is a single problem found in a NetworkDocument. Path points
+// at the offending entry, e.g. "network.cidr" or "routes[2]".
+type ValidationIssue struct {
+	Severity string `json:"severity"`
+	Path     string `json:"path"`
+	Message  string `json:"message"`
+}
+
+// ValidationReport is the consolidated result of validating a NetworkDocument.
+// Valid is false as soon as one issue has SeverityError.
+type ValidationReport struct {
+	Valid  bool              `json:"valid"`
+	Issues []ValidationIssue `json:"issues"`
+}
+
+// NetworkImportResult is the outcome of importing a NetworkDocument. Counts
+// are of the entities created; entities that failed, and those that depended
+// on them, are listed in Errors while the rest of the network is still
+// created.
+type NetworkImportResult struct {
+	Network  *Network      `json:"network"`
+	Peers    int           `json:"peers"`
+	Groups   int           `json:"groups"`
+	Policies int           `json:"policies"`
+	Routes   int           `json:"routes"`
+	Errors   []ImportError `json:"errors"`
+}
+
+// ImportError is an entry of a NetworkDocument that could not be imported.
+// Path points at it the same way ValidationIssue.Path does.
+type ImportError struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
@@ -14,6 +14,7 @@ var (
 	ErrPolicyNotFound      = errors.New("policy not found")
 	ErrDuplicatePolicyName = errors.New("policy name already exists in network")
 	ErrPolicyNotAttached   = errors.New("policy not attached to group")
+	ErrInvalidPacket       = errors.New("invalid packet to evaluate")
 )
 
 // Route errors
@@ -25,6 +26,8 @@ var (
 	ErrJumpPeerNotFound     = errors.New("jump peer not found")
 	ErrNotJumpPeer          = errors.New("peer is not a jump peer")
 	ErrCannotDeleteLastJump = errors.New("cannot delete route: jump peer is last in network")
+	ErrRouteConflict        = errors.New("conflicting routes for the same destination via different jump peers")
+	ErrTempRouteNotFound    = errors.New("temporary route not found")
 )
 
 // DNS errors
@@ -32,16 +35,65 @@ var (
 	ErrDNSMappingNotFound = errors.New("DNS mapping not found")
 	ErrDuplicateDNSName   = errors.New("DNS name already exists for route")
 	ErrIPNotInRouteCIDR   = errors.New("IP address not in route CIDR")
+
+	ErrNetworkDNSRecordNotFound = errors.New("network DNS record not found")
+	ErrDuplicateNetworkDNSName  = errors.New("DNS name already exists in network")
+	ErrInvalidZoneFile          = errors.New("invalid zone file")
 )
 
 // Network errors
 var (
-	ErrNetworkNotFound = errors.New("network not found")
+	ErrNetworkNotFound             = errors.New("network not found")
+	ErrInvalidConditionalForwarder = errors.New("conditional forwarders must map a domain name to one or more resolver IPs (optionally with a port)")
+	ErrMaintenanceInPast           = errors.New("maintenance window must end in the future")
+	ErrInvalidJumpHook             = errors.New("invalid jump PostUp/PostDown template")
+	ErrNetworkNotDeleted           = errors.New("network is not deleted")
+	ErrRestoreWindowExpired        = errors.New("network retention window has expired")
+)
+
+// Label errors
+var (
+	ErrInvalidLabel = errors.New("label keys must be 1-63 letters, digits, '.', '_', '-' or '/', and values at most 63 of the same characters")
 )
 
 // Peer errors
 var (
-	ErrPeerNotFound = errors.New("peer not found")
+	ErrPeerNotFound        = errors.New("peer not found")
+	ErrPeerNamePattern     = errors.New("peer name does not match the network naming convention")
+	ErrPeerExpiryInPast    = errors.New("peer expiry must be in the future")
+	ErrInvalidRoutingTable = errors.New("routing_table must be off, auto or a table number from 1 to 4294967295")
+	ErrInvalidFwMark       = errors.New("fwmark must be a 32-bit number in decimal or 0x-prefixed hex")
+	ErrTokenExpiryInPast   = errors.New("token expiry must be in the future")
+	ErrTokenExpired        = errors.New("enrollment token expired")
+	ErrTokenConsumed       = errors.New("enrollment token already used")
+	ErrInvalidPublicKey    = errors.New("public_key must be a 44-character base64 WireGuard key")
+	ErrPublicKeyInUse      = errors.New("public key is already used by another peer of the network")
+	ErrDNSOnlyJumpPeer     = errors.New("a jump peer cannot be dns-only")
+	ErrPeersNotConnected   = errors.New("peers are not connected in the network topology")
+	ErrConnectionNotFound  = errors.New("connection not found")
+	ErrEmptySearchQuery    = errors.New("search query is required")
+)
+
+// Peer profile errors
+var (
+	ErrPeerProfileNotFound      = errors.New("peer profile not found")
+	ErrDuplicatePeerProfileName = errors.New("peer profile name already exists in network")
+	ErrPeerProfileInUse         = errors.New("peer profile is assigned to peers")
+)
+
+// Session errors
+var (
+	ErrSessionNotFound = errors.New("session not found")
+)
+
+// IPAM errors
+var (
+	ErrInvalidIP      = errors.New("invalid IP address")
+	ErrIPNotInNetwork = errors.New("IP address not in network CIDR")
+	ErrIPInUse        = errors.New("IP address is held by a live peer")
+
+	ErrIPAllocated         = errors.New("IP address is already allocated")
+	ErrReservationNotFound = errors.New("IP reservation not found")
 )
 
 // Authorization errors
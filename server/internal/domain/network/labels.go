@@ -0,0 +1,55 @@
+package network
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxLabelLength bounds label keys and values.
+const maxLabelLength = 63
+
+// ValidateLabels checks the labels of a network or peer.  Keys are required;
+// values may be empty.
+func ValidateLabels(labels map[string]string) error {
+	for key, value := range labels {
+		if key == "" || !isLabelText(key) {
+			return fmt.Errorf("%w: key %q", ErrInvalidLabel, key)
+		}
+		if !isLabelText(value) {
+			return fmt.Errorf("%w: value %q of %q", ErrInvalidLabel, value, key)
+		}
+	}
+	return nil
+}
+
+func isLabelText(s string) bool {
+	if len(s) > maxLabelLength {
+		return false
+	}
+	for _, c := range s {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '.', c == '_', c == '-', c == '/':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// MatchLabels reports whether labels satisfy every selector.  A selector is
+// "key=value", which needs that exact value, or a bare "key", which only
+// needs the key to be set.
+func MatchLabels(labels map[string]string, selectors []string) bool {
+	for _, selector := range selectors {
+		if selector == "" {
+			continue
+		}
+		key, want, hasValue := strings.Cut(selector, "=")
+		got, ok := labels[key]
+		if !ok || (hasValue && got != want) {
+			return false
+		}
+	}
+	return true
+}
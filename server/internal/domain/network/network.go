@@ -1,29 +1,113 @@
 package network
 
-import "time"
+import (
+	"slices"
+	"sort"
+	"time"
+)
 
 // Network represents a WireGuard mesh network
 type Network struct {
 	ID              string           `json:"id"`
 	Name            string           `json:"name"`
-	CIDR            string           `json:"cidr"`               // IPv4 network CIDR (e.g., "10.0.0.0/16")
-	CIDRv6          string           `json:"cidr_v6,omitempty"`  // IPv6 network CIDR (e.g., "fd00::/64"), optional
-	Peers           map[string]*Peer `json:"-"`                  // Peer ID -> Peer
-	PeerCount       int              `json:"peer_count"`         // Computed number of peers for lightweight listing
-	DNS             []string         `json:"dns"`                // Additional DNS servers for peers
-	DomainSuffix    string           `json:"domain_suffix"`      // Custom domain (default: .internal)
-	DefaultGroupIDs []string         `json:"default_group_ids"`  // Groups for non-admin peers
+	CIDR            string           `json:"cidr"`                        // IPv4 network CIDR (e.g., "10.0.0.0/16")
+	CIDRv6          string           `json:"cidr_v6,omitempty"`           // IPv6 network CIDR (e.g., "fd00::/64"), optional
+	Peers           map[string]*Peer `json:"-"`                           // Peer ID -> Peer
+	PeerCount       int              `json:"peer_count"`                  // Computed number of peers for lightweight listing
+	DNS             []string         `json:"dns"`                         // Additional DNS servers for peers
+	DomainSuffix    string           `json:"domain_suffix"`               // Custom domain (default: .internal)
+	DefaultGroupIDs []string         `json:"default_group_ids"`           // Groups for non-admin peers
+	PeerNamePattern string           `json:"peer_name_pattern,omitempty"` // Optional regex every peer name must fully match
+	Topology        string           `json:"topology"`                    // TopologyMesh (default) or TopologyHub
 	CreatedAt       time.Time        `json:"created_at"`
 	UpdatedAt       time.Time        `json:"updated_at"`
+
+	// MultiJumpFailover lists the routed CIDRs on every jump peer section of
+	// a regular peer's config, instead of only on each route's gateway, so
+	// another jump can take over when a gateway is down.  The overlapping
+	// AllowedIPs rely on section ordering, hence opt-in.
+	MultiJumpFailover bool `json:"multi_jump_failover"`
+
+	// OmitPrivateKeys leaves the PrivateKey out of the configs sent to
+	// agents, which then supply their own (--private-key-file).  The server
+	// still hands the key out through the peer config download, so it can
+	// be installed on the device once.
+	OmitPrivateKeys bool `json:"omit_private_keys"`
+
+	// ConditionalForwarders sends queries for a domain and its subdomains
+	// to dedicated resolvers (split-horizon DNS) instead of DNS, e.g.
+	// {"corp.example.com": ["10.1.0.53"]}.  The longest matching domain wins.
+	ConditionalForwarders map[string][]string `json:"conditional_forwarders,omitempty"`
+
+	// DNSQueryLog has the jump peers' DNS servers record the queries they
+	// receive and report them, for troubleshooting.  Off by default since
+	// the queries reveal what peers look up.
+	DNSQueryLog bool `json:"dns_query_log"`
+
+	// MaintenanceUntil is the end of a planned maintenance window.  Until
+	// then, security detections (endpoint takeovers, captive-portal strikes)
+	// still raise incidents but no longer denylist or quarantine peers.
+	MaintenanceUntil *time.Time `json:"maintenance_until,omitempty"`
+
+	// JumpPostUp and JumpPostDown are Go templates rendered into the PostUp
+	// and PostDown lines of the jump peers' configs, e.g.
+	// "iptables -t nat -A POSTROUTING -s {{.CIDR}} -o {{.NatInterface}} -j MASQUERADE".
+	// JumpNATInterface fills {{.NatInterface}} (default eth0).  Empty
+	// templates leave the lines out: the agent firewall handles forwarding.
+	JumpPostUp       string `json:"jump_post_up,omitempty"`
+	JumpPostDown     string `json:"jump_post_down,omitempty"`
+	JumpNATInterface string `json:"jump_nat_interface,omitempty"`
+
+	// ConfigMetadata adds "# ID:", "# Groups:" and "# Owner:" comments to
+	// every section of the generated configs, so that a raw config can be
+	// matched with the peers it lists.  Off by default to keep configs
+	// minimal.
+	ConfigMetadata bool `json:"config_metadata"`
+
+	// DeletedAt is set when the network is deleted.  It is kept, peers and
+	// all, until the retention window has passed, and can be restored until
+	// then; the repositories no longer serve it from GetNetwork and
+	// ListNetworks.
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+
+	// Labels are free-form key/value pairs for organizing networks, e.g.
+	// {"env": "prod"}.  The network list can be filtered on them.
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// SearchDomains are extra DNS search domains, e.g. "corp.internal",
+	// tried after the network's own domain (see SearchList).
+	SearchDomains []string `json:"search_domains,omitempty"`
 }
 
 // NetworkCreateRequest represents the data needed to create a new network
 type NetworkCreateRequest struct {
 	Name         string   `json:"name" binding:"required"`
-	CIDR         string   `json:"cidr"`                    // IPv4 CIDR (at least one of CIDR / CIDRv6 must be set)
-	CIDRv6       string   `json:"cidr_v6,omitempty"`       // IPv6 CIDR (optional)
+	CIDR         string   `json:"cidr"`              // IPv4 CIDR (at least one of CIDR / CIDRv6 must be set)
+	CIDRv6       string   `json:"cidr_v6,omitempty"` // IPv6 CIDR (optional)
 	DNS          []string `json:"dns,omitempty"`
 	DomainSuffix string   `json:"domain_suffix,omitempty"` // Custom domain (default: .internal)
+	// PeerNamePattern is an optional naming convention (Go regexp, matched
+	// against the whole name) enforced on top of DNS label validation.
+	PeerNamePattern string `json:"peer_name_pattern,omitempty"`
+	Topology        string `json:"topology,omitempty" binding:"omitempty,oneof=mesh hub"` // default: mesh
+	// MultiJumpFailover opts in to overlapping jump AllowedIPs (see Network).
+	MultiJumpFailover bool `json:"multi_jump_failover,omitempty"`
+	// OmitPrivateKeys keeps private keys out of agent configs (see Network).
+	OmitPrivateKeys bool `json:"omit_private_keys,omitempty"`
+	// ConditionalForwarders maps domains to their resolvers (see Network).
+	ConditionalForwarders map[string][]string `json:"conditional_forwarders,omitempty"`
+	Labels                map[string]string   `json:"labels,omitempty"`
+	// DNSQueryLog turns on DNS query logging (see Network).
+	DNSQueryLog bool `json:"dns_query_log,omitempty"`
+	// JumpPostUp, JumpPostDown and JumpNATInterface template the jump
+	// peers' PostUp/PostDown lines (see Network).
+	JumpPostUp       string `json:"jump_post_up,omitempty"`
+	JumpPostDown     string `json:"jump_post_down,omitempty"`
+	JumpNATInterface string `json:"jump_nat_interface,omitempty"`
+	// ConfigMetadata adds peer metadata comments to configs (see Network).
+	ConfigMetadata bool `json:"config_metadata,omitempty"`
+	// SearchDomains adds DNS search domains (see Network).
+	SearchDomains []string `json:"search_domains,omitempty"`
 }
 
 // NetworkUpdateRequest represents the data that can be updated for a network
@@ -34,6 +118,96 @@ type NetworkUpdateRequest struct {
 	DNS             []string `json:"dns,omitempty"`
 	DomainSuffix    string   `json:"domain_suffix,omitempty"`
 	DefaultGroupIDs []string `json:"default_group_ids,omitempty"`
+	// PeerNamePattern replaces the naming convention when set; an empty
+	// string removes it.  Existing peers are not re-validated.
+	PeerNamePattern *string `json:"peer_name_pattern,omitempty"`
+	Topology        string  `json:"topology,omitempty" binding:"omitempty,oneof=mesh hub"`
+	// MultiJumpFailover turns multi-jump failover on or off when set.
+	MultiJumpFailover *bool `json:"multi_jump_failover,omitempty"`
+	// OmitPrivateKeys turns private keys in agent configs off or on when set.
+	OmitPrivateKeys *bool `json:"omit_private_keys,omitempty"`
+	// ConditionalForwarders replaces the forwarders when set; send {} to
+	// remove them all.
+	ConditionalForwarders map[string][]string `json:"conditional_forwarders,omitempty"`
+	// DNSQueryLog turns DNS query logging on or off when set.
+	DNSQueryLog *bool `json:"dns_query_log,omitempty"`
+	// MaintenanceUntil starts or moves a maintenance window (see Network);
+	// ClearMaintenance ends it early.
+	MaintenanceUntil *time.Time `json:"maintenance_until,omitempty"`
+	ClearMaintenance bool       `json:"clear_maintenance,omitempty"`
+	// JumpPostUp, JumpPostDown and JumpNATInterface replace the jump hook
+	// settings when set; an empty string removes them.
+	JumpPostUp       *string `json:"jump_post_up,omitempty"`
+	JumpPostDown     *string `json:"jump_post_down,omitempty"`
+	JumpNATInterface *string `json:"jump_nat_interface,omitempty"`
+	// ConfigMetadata turns the peer metadata comments on or off when set.
+	ConfigMetadata *bool `json:"config_metadata,omitempty"`
+	// Labels replaces the labels when set; send {} to remove them all.
+	Labels map[string]string `json:"labels,omitempty"`
+	// SearchDomains replaces the search domains when set; send [] to
+	// remove them all.
+	SearchDomains []string `json:"search_domains,omitempty"`
+}
+
+// NetworkCloneRequest names the network a clone creates and its IPv4 CIDR.
+type NetworkCloneRequest struct {
+	Name string `json:"name" binding:"required"`
+	CIDR string `json:"cidr" binding:"required"`
+}
+
+// CIDRChangePlan describes what changing a network's IPv4 CIDR would do:
+// the address every peer would move to, and the peers that prevent the
+// change.  A plan with blockers cannot be applied.
+type CIDRChangePlan struct {
+	NetworkID     string              `json:"network_id"`
+	OldCIDR       string              `json:"old_cidr"`
+	NewCIDR       string              `json:"new_cidr"`
+	Reassignments []PeerReassignment  `json:"reassignments"`
+	Blockers      []CIDRChangeBlocker `json:"blockers"`
+}
+
+// PeerReassignment is a peer's address before and after a CIDR change.
+type PeerReassignment struct {
+	PeerID     string `json:"peer_id"`
+	PeerName   string `json:"peer_name"`
+	OldAddress string `json:"old_address"`
+	NewAddress string `json:"new_address"`
+}
+
+// CIDRChangeBlocker is a peer that must be dealt with before the CIDR can change.
+type CIDRChangeBlocker struct {
+	PeerID   string `json:"peer_id"`
+	PeerName string `json:"peer_name"`
+	Reason   string `json:"reason"`
+}
+
+// Network topologies.  They control which peer pairs get a preshared-key
+// connection; which peers appear in each other's configs is decided by
+// GetAllowedPeersFor and is the same for both.
+const (
+	// TopologyMesh creates a connection between every pair of peers.
+	TopologyMesh = "mesh"
+	// TopologyHub only creates connections that involve a jump peer, since
+	// regular peers never talk to each other directly.
+	TopologyHub = "hub"
+)
+
+// NeedsConnection reports whether a and b should share a preshared-key
+// connection under the network's topology.
+func (n *Network) NeedsConnection(a, b *Peer) bool {
+	if a.ID == b.ID {
+		return false
+	}
+	if n.Topology == TopologyHub {
+		return a.IsJump || b.IsJump
+	}
+	return true
+}
+
+// InMaintenance reports whether the network's maintenance window is still
+// open at now.
+func (n *Network) InMaintenance(now time.Time) bool {
+	return n.MaintenanceUntil != nil && now.Before(*n.MaintenanceUntil)
 }
 
 // AddPeer adds a peer to the network
@@ -67,25 +241,28 @@ func (n *Network) GetAllPeers() []*Peer {
 }
 
 // GetAllowedPeersFor returns peers to include in WireGuard config for peerID.
-// Regular peers: only jump peers are listed (tunnel hub pattern). All peer-to-peer
-// communication goes through jump servers.
+// Regular peers (clients and resources): only jump peers are listed (tunnel hub
+// pattern). All peer-to-peer communication goes through jump servers.
 // Jump peers: all other peers are listed, with ACL filtering (isolation enforced via jump iptables).
+// DNS-only peers are never listed, and list no peers themselves.
+// Peers are sorted by name so the generated configs are stable.
 func (n *Network) GetAllowedPeersFor(peerID string) []*Peer {
 	result := make([]*Peer, 0)
 
 	peer, exists := n.Peers[peerID]
-	if !exists {
+	if !exists || peer.DNSOnly {
 		return result
 	}
 
 	// If this is a jump peer, include all other peers
 	if peer.IsJump {
 		for _, other := range n.Peers {
-			if other.ID == peerID {
+			if other.ID == peerID || other.DNSOnly {
 				continue
 			}
 			result = append(result, other)
 		}
+		sortPeers(result)
 		return result
 	}
 
@@ -96,9 +273,21 @@ func (n *Network) GetAllowedPeersFor(peerID string) []*Peer {
 		}
 		result = append(result, other)
 	}
+	sortPeers(result)
 	return result
 }
 
+// sortPeers orders peers by name, then ID, so configs built from the Peers
+// map come out the same every time.
+func sortPeers(peers []*Peer) {
+	sort.Slice(peers, func(i, j int) bool {
+		if peers[i].Name != peers[j].Name {
+			return peers[i].Name < peers[j].Name
+		}
+		return peers[i].ID < peers[j].ID
+	})
+}
+
 // HasJumpServer checks if the network has at least one jump server
 func (n *Network) HasJumpServer() bool {
 	for _, peer := range n.Peers {
@@ -128,3 +317,16 @@ func (n *Network) GetDomain() string {
 	}
 	return n.Name + "." + suffix
 }
+
+// SearchList returns the DNS search domains of the network: its own domain
+// first, the primary suffix peer names are qualified with, then
+// SearchDomains.
+func (n *Network) SearchList() []string {
+	list := []string{n.GetDomain()}
+	for _, d := range n.SearchDomains {
+		if !slices.Contains(list, d) {
+			list = append(list, d)
+		}
+	}
+	return list
+}
@@ -1,6 +1,7 @@
 package network
 
 import (
+	"encoding/json"
 	"testing"
 	"time"
 )
@@ -216,6 +217,30 @@ func TestNetwork_GetAllowedPeersFor(t *testing.T) {
 	}
 }
 
+func TestNetwork_GetAllowedPeersFor_DNSOnly(t *testing.T) {
+	network := &Network{
+		ID:   "net1",
+		Name: "test-network",
+		Peers: map[string]*Peer{
+			"jump1":    {ID: "jump1", Name: "jump-server", IsJump: true},
+			"peer1":    {ID: "peer1", Name: "regular-peer"},
+			"resolver": {ID: "resolver", Name: "resolver", DNSOnly: true},
+		},
+	}
+
+	// The jump should not list the dns-only peer
+	for _, peer := range network.GetAllowedPeersFor("jump1") {
+		if peer.ID == "resolver" {
+			t.Error("Expected jump peer not to connect to the dns-only peer")
+		}
+	}
+
+	// The dns-only peer should list no peer at all
+	if allowed := network.GetAllowedPeersFor("resolver"); len(allowed) != 0 {
+		t.Errorf("Expected 0 allowed peers for dns-only peer, got %d", len(allowed))
+	}
+}
+
 func TestNetwork_HasJumpServer(t *testing.T) {
 	// Test network with jump server
 	networkWithJump := &Network{
@@ -353,3 +378,33 @@ func TestNetwork_GetDomain(t *testing.T) {
 		})
 	}
 }
+
+func TestPeer_MarshalJSONExpiresIn(t *testing.T) {
+	decode := func(p Peer) map[string]interface{} {
+		t.Helper()
+		data, err := json.Marshal(&p)
+		if err != nil {
+			t.Fatalf("marshal: %v", err)
+		}
+		var out map[string]interface{}
+		if err := json.Unmarshal(data, &out); err != nil {
+			t.Fatalf("unmarshal: %v", err)
+		}
+		return out
+	}
+
+	if out := decode(Peer{ID: "p1"}); out["expires_in_seconds"] != nil || out["id"] != "p1" {
+		t.Errorf("peer without expiry: %v", out)
+	}
+
+	future := time.Now().Add(time.Hour)
+	out := decode(Peer{ID: "p1", ExpiresAt: &future})
+	if left, ok := out["expires_in_seconds"].(float64); !ok || left < 3500 || left > 3600 {
+		t.Errorf("expires_in_seconds = %v, want about 3600", out["expires_in_seconds"])
+	}
+
+	past := time.Now().Add(-time.Hour)
+	if out := decode(Peer{ID: "p1", ExpiresAt: &past}); out["expires_in_seconds"] != float64(0) {
+		t.Errorf("expired peer: expires_in_seconds = %v, want 0", out["expires_in_seconds"])
+	}
+}
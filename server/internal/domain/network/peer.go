@@ -1,6 +1,14 @@
 package network
 
-import "time"
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
 
 // Peer represents a network participant in the WireGuard mesh
 // Two types of peers exist:
@@ -13,7 +21,7 @@ type Peer struct {
 	PrivateKey           string    `json:"-"`                                // Never expose private key in API responses (only used for config generation)
 	Address              string    `json:"address"`                          // IPv4 address in the network CIDR
 	AddressV6            string    `json:"address_v6,omitempty"`             // IPv6 address in the network CIDRv6 (optional)
-	Endpoint             string    `json:"endpoint,omitempty"`               // External endpoint (IP:port)
+	Endpoint             string    `json:"endpoint,omitempty"`               // External endpoint host (hostname, IPv4 or IPv6); port is ListenPort
 	ListenPort           int       `json:"listen_port,omitempty"`            // WireGuard listen port (mainly for jump peers)
 	AdditionalAllowedIPs []string  `json:"additional_allowed_ips,omitempty"` // Additional IPs this peer can route to
 	Token                string    `json:"token,omitempty"`                  // Agent enrollment token (secret)
@@ -21,8 +29,190 @@ type Peer struct {
 	UseAgent             bool      `json:"use_agent"`                        // Whether this peer uses the agent (dynamic) or static config
 	OwnerID              string    `json:"owner_id,omitempty"`               // User ID who owns this peer (empty for admin-created peers)
 	GroupIDs             []string  `json:"group_ids"`                        // Groups this peer belongs to
+	Role                 string    `json:"role"`                             // PeerRoleClient (default) or PeerRoleResource; jump peers report PeerRoleJump
 	CreatedAt            time.Time `json:"created_at"`
 	UpdatedAt            time.Time `json:"updated_at"`
+
+	// SplitTunnelExclusions are CIDRs that bypass the tunnel (e.g. a home LAN)
+	// even when a route through a jump peer, such as 0.0.0.0/0, covers them.
+	// Nil inherits the exclusions of the peer's profile; empty excludes nothing.
+	SplitTunnelExclusions []string `json:"split_tunnel_exclusions"`
+
+	// ProfileID assigns a PeerProfile; the settings below override it.  Unset
+	// settings (0 MTU, nil keepalive, empty DNS) come from the profile, then
+	// from the server's PeerDefaults.
+	ProfileID           string   `json:"profile_id,omitempty"`
+	MTU                 int      `json:"mtu,omitempty"`
+	PersistentKeepalive *int     `json:"persistent_keepalive,omitempty"` // seconds, 0 disables keepalive
+	DNS                 []string `json:"dns,omitempty"`                  // resolvers replacing the jump peer's DNS server, or [DNSNone]
+
+	// RoutingTable is the wg-quick Table setting: RoutingTableOff for peers
+	// that manage their own routes, RoutingTableAuto or a table number.
+	// Empty leaves it to wg-quick (auto).
+	RoutingTable string `json:"routing_table,omitempty"`
+
+	// FwMark marks the tunnel's outgoing packets for policy routing, as
+	// written by the admin (hex like 0xca6c or decimal).  Empty sets none.
+	FwMark string `json:"fwmark,omitempty"`
+
+	// ExpiresAt is an optional deadline after which the peer is blocked on
+	// the jump peers until an admin extends or clears it.  The peer itself
+	// is kept.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+
+	// TokenSingleUse makes Token good for one enrollment: once an agent
+	// resolved it, it only authenticates that agent's WebSocket.
+	// TokenExpiresAt is an optional deadline to enroll with the token.
+	// TokenUsedAt is when an agent first resolved it.
+	TokenSingleUse bool       `json:"token_single_use,omitempty"`
+	TokenExpiresAt *time.Time `json:"token_expires_at,omitempty"`
+	TokenUsedAt    *time.Time `json:"token_used_at,omitempty"`
+
+	// Labels are free-form key/value pairs for organizing peers.  They have
+	// no effect on the peer's config.
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// DNSOnly peers, such as resolver appliances, keep an address and a DNS
+	// record served by the jump peers but take no part in the mesh: no
+	// config has a [Peer] section for them, nor do theirs list any peer.
+	DNSOnly bool `json:"dns_only,omitempty"`
+
+	// Status is the peer's handshake status (see PeerStatusAt).  Computed
+	// when listing peers, never stored.
+	Status string `json:"status,omitempty"`
+}
+
+// MarshalJSON adds expires_in_seconds, the time left before ExpiresAt (0 once
+// expired), to peers that have an expiry.
+func (p Peer) MarshalJSON() ([]byte, error) {
+	type plain Peer
+	out := struct {
+		plain
+		ExpiresInSeconds *int64 `json:"expires_in_seconds,omitempty"`
+	}{plain: plain(p)}
+	if p.ExpiresAt != nil {
+		left := int64(time.Until(*p.ExpiresAt) / time.Second)
+		if left < 0 {
+			left = 0
+		}
+		out.ExpiresInSeconds = &left
+	}
+	return json.Marshal(out)
+}
+
+// IsExpired reports whether the peer's expiry has passed at now.
+func (p *Peer) IsExpired(now time.Time) bool {
+	return p.ExpiresAt != nil && !now.Before(*p.ExpiresAt)
+}
+
+// IsTokenExpired reports whether the enrollment token's expiry has passed
+// at now.
+func (p *Peer) IsTokenExpired(now time.Time) bool {
+	return p.TokenExpiresAt != nil && !now.Before(*p.TokenExpiresAt)
+}
+
+// wg-quick Table values besides a table number.
+const (
+	RoutingTableOff  = "off"
+	RoutingTableAuto = "auto"
+)
+
+// ValidateRoutingTable checks a RoutingTable value: empty, RoutingTableOff,
+// RoutingTableAuto or a table number from 1 to 2^32-1.
+func ValidateRoutingTable(table string) error {
+	if table == "" || table == RoutingTableOff || table == RoutingTableAuto {
+		return nil
+	}
+	if n, err := strconv.ParseUint(table, 10, 32); err != nil || n == 0 {
+		return fmt.Errorf("%w: %q", ErrInvalidRoutingTable, table)
+	}
+	return nil
+}
+
+// ValidateFwMark checks that a FwMark value is empty or a 32-bit number,
+// in hex with a 0x prefix or in decimal.
+func ValidateFwMark(mark string) error {
+	if mark == "" {
+		return nil
+	}
+	digits, base := mark, 10
+	if strings.HasPrefix(mark, "0x") || strings.HasPrefix(mark, "0X") {
+		digits, base = mark[2:], 16
+	}
+	if _, err := strconv.ParseUint(digits, base, 32); err != nil {
+		return fmt.Errorf("%w: %q", ErrInvalidFwMark, mark)
+	}
+	return nil
+}
+
+// ValidatePublicKey checks that key is a WireGuard public key: 32 bytes in
+// standard base64, 44 characters.
+func ValidatePublicKey(key string) error {
+	if len(key) != 44 {
+		return fmt.Errorf("%w: %q", ErrInvalidPublicKey, key)
+	}
+	if raw, err := base64.StdEncoding.DecodeString(key); err != nil || len(raw) != 32 {
+		return fmt.Errorf("%w: %q", ErrInvalidPublicKey, key)
+	}
+	return nil
+}
+
+// Peer roles. The role tunes the AllowedIPs a peer is given: clients route
+// through the jump peers (including any gateway routes), while resources only
+// serve traffic and are restricted to the overlay network itself.
+const (
+	PeerRoleClient   = "client"
+	PeerRoleResource = "resource"
+	PeerRoleJump     = "jump"
+)
+
+// EffectiveRole returns the role the peer actually plays: jump peers are always
+// PeerRoleJump and an unset role means PeerRoleClient.
+func (p *Peer) EffectiveRole() string {
+	if p.IsJump {
+		return PeerRoleJump
+	}
+	if p.Role == PeerRoleResource {
+		return PeerRoleResource
+	}
+	return PeerRoleClient
+}
+
+// IsResource reports whether the peer is a resource (non-routing) peer.
+func (p *Peer) IsResource() bool {
+	return p.EffectiveRole() == PeerRoleResource
+}
+
+// InterfaceAddresses returns the addresses assigned to the peer across address
+// families, IPv4 first. IPv6-only peers have no IPv4 entry.
+func (p *Peer) InterfaceAddresses() []string {
+	var addrs []string
+	for _, addr := range []string{p.Address, p.AddressV6} {
+		if addr != "" {
+			addrs = append(addrs, addr)
+		}
+	}
+	return addrs
+}
+
+// PeerSearchResult is a peer found by a search across networks, with the
+// network it belongs to.
+type PeerSearchResult struct {
+	NetworkID   string `json:"network_id"`
+	NetworkName string `json:"network_name"`
+	Peer        *Peer  `json:"peer"`
+}
+
+// MatchesSearch reports whether the peer's name, addresses, public key or ID
+// contain query, ignoring case.
+func (p *Peer) MatchesSearch(query string) bool {
+	query = strings.ToLower(query)
+	for _, field := range []string{p.Name, p.Address, p.AddressV6, p.PublicKey, p.ID} {
+		if strings.Contains(strings.ToLower(field), query) {
+			return true
+		}
+	}
+	return false
 }
 
 // PeerConnection represents a preshared key between two peers
@@ -31,6 +221,26 @@ type PeerConnection struct {
 	Peer2ID      string    `json:"peer2_id"`
 	PresharedKey string    `json:"preshared_key"`
 	CreatedAt    time.Time `json:"created_at"`
+
+	// AllowedIPs, when set, replace the AllowedIPs derived from the topology
+	// and routes for the [Peer] section of AllowedIPsPeerID, in the config of
+	// the other peer of the pair.  The reverse section keeps its defaults.
+	AllowedIPs       []string `json:"allowed_ips,omitempty"`
+	AllowedIPsPeerID string   `json:"allowed_ips_peer_id,omitempty"`
+
+	// Severed pairs are left out of each other's configs even though the
+	// topology connects them.  The connection is kept so it is not
+	// recreated.
+	Severed bool `json:"severed,omitempty"`
+}
+
+// AllowedIPsFor returns the AllowedIPs override of peerID's [Peer] section,
+// or nil when that section keeps its defaults.
+func (c *PeerConnection) AllowedIPsFor(peerID string) []string {
+	if c == nil || c.AllowedIPsPeerID != peerID {
+		return nil
+	}
+	return c.AllowedIPs
 }
 
 // PeerCreateRequest represents the data needed to create a new peer
@@ -42,6 +252,50 @@ type PeerCreateRequest struct {
 	UseAgent             bool     `json:"use_agent"`
 	OwnerID              string   `json:"owner_id,omitempty"` // Admin can assign any owner; non-admins are forced to their own ID in the handler
 	AdditionalAllowedIPs []string `json:"additional_allowed_ips,omitempty"`
+	Role                 string   `json:"role,omitempty" binding:"omitempty,oneof=client resource"` // Ignored for jump peers
+
+	// SplitTunnelExclusions are CIDRs routed outside the tunnel; ignored for jump peers
+	SplitTunnelExclusions []string `json:"split_tunnel_exclusions,omitempty"`
+
+	ProfileID           string   `json:"profile_id,omitempty"`
+	MTU                 int      `json:"mtu,omitempty"`
+	PersistentKeepalive *int     `json:"persistent_keepalive,omitempty"`
+	DNS                 []string `json:"dns,omitempty"`
+	RoutingTable        string   `json:"routing_table,omitempty"`
+	FwMark              string   `json:"fwmark,omitempty"`
+
+	// Address pins the peer to a specific IPv4 address of the network's CIDR
+	// instead of the next free one.
+	Address string `json:"address,omitempty"`
+
+	// ExpiresAt cuts the peer off after this time; must be in the future.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+
+	// TokenSingleUse and TokenExpiresAt restrict the enrollment token; the
+	// default token is reusable and never expires.
+	TokenSingleUse bool       `json:"token_single_use,omitempty"`
+	TokenExpiresAt *time.Time `json:"token_expires_at,omitempty"`
+
+	// PublicKey imports a key pair generated on the device, which keeps the
+	// private key: the server stores no private key and the peer's config
+	// has no PrivateKey line.  Empty generates a key pair.
+	PublicKey string `json:"public_key,omitempty"`
+
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// DNSOnly creates a peer that only gets an address and a DNS record;
+	// jump peers cannot be DNS-only.
+	DNSOnly bool `json:"dns_only,omitempty"`
+
+	// GroupIDs places the peer in these groups of the network at creation;
+	// admins only.  They are joined to the default groups non-admin owners
+	// get.
+	GroupIDs []string `json:"group_ids,omitempty"`
+}
+
+// PeerBulkCreateRequest represents a batch of peers to create in one call
+type PeerBulkCreateRequest struct {
+	Peers []PeerCreateRequest `json:"peers" binding:"required,min=1,max=100"`
 }
 
 // PeerUpdateRequest represents the data that can be updated for a peer
@@ -51,4 +305,62 @@ type PeerUpdateRequest struct {
 	ListenPort           int      `json:"listen_port,omitempty"`
 	AdditionalAllowedIPs []string `json:"additional_allowed_ips,omitempty"`
 	OwnerID              string   `json:"owner_id,omitempty"` // Admin can change owner
+	Role                 string   `json:"role,omitempty" binding:"omitempty,oneof=client resource"`
+
+	// SplitTunnelExclusions replaces the peer's exclusions when set; send []
+	// to exclude nothing, even if the profile excludes some CIDRs
+	SplitTunnelExclusions []string `json:"split_tunnel_exclusions,omitempty"`
+
+	// ProfileID assigns a profile when set; send "" to unassign it.  MTU,
+	// PersistentKeepalive and DNS are left unchanged when nil.  A keepalive of
+	// 0 disables keepalive; an MTU of 0 or an empty DNS list unsets them.
+	ProfileID           *string  `json:"profile_id,omitempty"`
+	MTU                 *int     `json:"mtu,omitempty"`
+	PersistentKeepalive *int     `json:"persistent_keepalive,omitempty"`
+	DNS                 []string `json:"dns,omitempty"`
+
+	// Inherit unsets the named settings (PeerSettingMTU, ...) so that they
+	// come from the profile or the server defaults again.
+	Inherit []string `json:"inherit,omitempty"`
+
+	// RoutingTable replaces the peer's Table setting when set; send "" to
+	// go back to the wg-quick default.
+	RoutingTable *string `json:"routing_table,omitempty"`
+	// FwMark replaces the peer's fwmark when set; send "" to remove it.
+	FwMark *string `json:"fwmark,omitempty"`
+
+	// ExpiresAt moves the peer's expiry when set (admin only); ClearExpiry
+	// removes it so the peer never expires.
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	ClearExpiry bool       `json:"clear_expiry,omitempty"`
+
+	// TokenSingleUse changes whether the enrollment token is single-use when
+	// set.  TokenExpiresAt moves the token's expiry; ClearTokenExpiry
+	// removes it.
+	TokenSingleUse   *bool      `json:"token_single_use,omitempty"`
+	TokenExpiresAt   *time.Time `json:"token_expires_at,omitempty"`
+	ClearTokenExpiry bool       `json:"clear_token_expiry,omitempty"`
+
+	// Labels replaces the peer's labels when set; send {} to remove them.
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// DNSOnly takes the peer out of the mesh, or back in, when set.
+	DNSOnly *bool `json:"dns_only,omitempty"`
+}
+
+// RenameOnly reports whether the request changes nothing but the peer's name.
+// A rename only changes the DNS records served by jump peers, so the rest of
+// the network's WireGuard configs need not be regenerated.
+func (r *PeerUpdateRequest) RenameOnly() bool {
+	rest := *r
+	rest.Name = ""
+	return r.Name != "" && reflect.DeepEqual(rest, PeerUpdateRequest{})
+}
+
+// LabelsOnly reports whether the request changes nothing but the peer's
+// labels, which appear in no WireGuard config.
+func (r *PeerUpdateRequest) LabelsOnly() bool {
+	rest := *r
+	rest.Labels = nil
+	return r.Labels != nil && reflect.DeepEqual(rest, PeerUpdateRequest{})
 }
@@ -3,6 +3,7 @@ package network
 import (
 	"errors"
 	"net"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -21,13 +22,70 @@ type Policy struct {
 // PolicyRule represents a specific allow or deny iptables rule for IP ranges or peer traffic
 type PolicyRule struct {
 	ID          string `json:"id"`
-	Direction   string `json:"direction"`   // "input" or "output"
-	Action      string `json:"action"`      // "allow" or "deny"
-	Target      string `json:"target"`      // IP/CIDR, peer ID, or group ID
-	TargetType  string `json:"target_type"` // "cidr", "peer", "group"
+	Direction   string `json:"direction"`          // "input" or "output"
+	Action      string `json:"action"`             // "allow" or "deny"
+	Target      string `json:"target"`             // IP/CIDR, peer ID, or group ID
+	TargetType  string `json:"target_type"`        // "cidr", "peer", "group"
+	Protocol    string `json:"protocol,omitempty"` // "tcp", "udp", "icmp" or "any" (empty means any)
+	Ports       string `json:"ports,omitempty"`    // destination port or range, e.g. "443" or "8000-8100" (tcp/udp only)
 	Description string `json:"description"`
 }
 
+// Policy rule protocols
+const (
+	PolicyProtocolAny  = "any"
+	PolicyProtocolTCP  = "tcp"
+	PolicyProtocolUDP  = "udp"
+	PolicyProtocolICMP = "icmp"
+)
+
+// MatchesAnyProtocol reports whether the rule applies to every protocol, in
+// which case no layer-4 match is generated for it.
+func (r *PolicyRule) MatchesAnyProtocol() bool {
+	return r.Protocol == "" || r.Protocol == PolicyProtocolAny
+}
+
+// PortRange returns the rule's destination port range. first and last are
+// equal for a single port; ok is false when the rule has no port match.
+func (r *PolicyRule) PortRange() (first, last int, ok bool) {
+	if r.Ports == "" {
+		return 0, 0, false
+	}
+	lo, hi, isRange := strings.Cut(r.Ports, "-")
+	first, err := strconv.Atoi(strings.TrimSpace(lo))
+	if err != nil {
+		return 0, 0, false
+	}
+	last = first
+	if isRange {
+		if last, err = strconv.Atoi(strings.TrimSpace(hi)); err != nil {
+			return 0, 0, false
+		}
+	}
+	return first, last, true
+}
+
+// PolicyEvaluateRequest describes a hypothetical packet sent by a peer, to
+// be checked against the policies of the peer's groups.
+type PolicyEvaluateRequest struct {
+	PeerID      string `json:"peer_id" binding:"required"`
+	Source      string `json:"source,omitempty"` // default: the peer's address in the destination's family
+	Destination string `json:"destination" binding:"required"`
+	Protocol    string `json:"protocol,omitempty"` // "tcp", "udp" or "icmp" (default "tcp")
+	Port        int    `json:"port,omitempty"`     // destination port (tcp/udp only)
+}
+
+// PolicyEvaluation is the verdict of the jump peers' firewall on a packet.
+// Rule is the first rule the packet matches, nil when the default deny
+// drops it.
+type PolicyEvaluation struct {
+	Verdict    string      `json:"verdict"` // "allow" or "deny"
+	Reason     string      `json:"reason"`
+	PolicyID   string      `json:"policy_id,omitempty"`
+	PolicyName string      `json:"policy_name,omitempty"`
+	Rule       *PolicyRule `json:"rule,omitempty"`
+}
+
 // PolicyCreateRequest represents the data needed to create a new policy
 type PolicyCreateRequest struct {
 	Name        string       `json:"name" binding:"required"`
@@ -93,6 +151,22 @@ func (r *PolicyRule) Validate() error {
 		}
 	}
 
+	// Validate the optional layer-4 match
+	switch r.Protocol {
+	case "", PolicyProtocolAny, PolicyProtocolTCP, PolicyProtocolUDP, PolicyProtocolICMP:
+	default:
+		return errors.New("policy rule protocol must be 'tcp', 'udp', 'icmp' or 'any'")
+	}
+	if r.Ports != "" {
+		if r.Protocol != PolicyProtocolTCP && r.Protocol != PolicyProtocolUDP {
+			return errors.New("policy rule ports require protocol 'tcp' or 'udp'")
+		}
+		first, last, ok := r.PortRange()
+		if !ok || first < 1 || last > 65535 || first > last {
+			return errors.New("policy rule ports must be a port (1-65535) or a range like '8000-8100'")
+		}
+	}
+
 	return nil
 }
 
@@ -0,0 +1,155 @@
+package network
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+// PeerProfile is a named, network-wide bundle of client settings (e.g.
+// "mobile", "datacenter") that peers can be assigned instead of setting each
+// field individually.  Settings on the peer itself override its profile, and
+// settings neither of them sets come from the server's PeerDefaults.  A 0 MTU,
+// a nil keepalive and nil lists mean "not set".
+type PeerProfile struct {
+	ID                    string    `json:"id"`
+	NetworkID             string    `json:"network_id"`
+	Name                  string    `json:"name"`
+	Description           string    `json:"description,omitempty"`
+	MTU                   int       `json:"mtu,omitempty"`
+	PersistentKeepalive   *int      `json:"persistent_keepalive,omitempty"` // seconds, 0 disables keepalive
+	DNS                   []string  `json:"dns,omitempty"`                  // resolvers replacing the jump peer's DNS server
+	SplitTunnelExclusions []string  `json:"split_tunnel_exclusions,omitempty"`
+	CreatedAt             time.Time `json:"created_at"`
+	UpdatedAt             time.Time `json:"updated_at"`
+}
+
+// PeerProfileRequest creates a profile, or replaces all of its settings on
+// update.
+type PeerProfileRequest struct {
+	Name                  string   `json:"name" binding:"required"`
+	Description           string   `json:"description"`
+	MTU                   int      `json:"mtu,omitempty"`
+	PersistentKeepalive   *int     `json:"persistent_keepalive,omitempty"`
+	DNS                   []string `json:"dns,omitempty"`
+	SplitTunnelExclusions []string `json:"split_tunnel_exclusions,omitempty"`
+}
+
+// Bounds of the per-peer WireGuard settings.  1280 is the minimum MTU IPv6
+// allows on a link.
+const (
+	MinMTU                 = 1280
+	MaxMTU                 = 9000
+	MaxPersistentKeepalive = 65535
+)
+
+// DefaultPersistentKeepalive is the keepalive, in seconds, of peers that get
+// none from themselves, their profile or the server's PeerDefaults.
+const DefaultPersistentKeepalive = 25
+
+// Names of the peer settings a PeerUpdateRequest can Inherit.
+const (
+	PeerSettingMTU                   = "mtu"
+	PeerSettingPersistentKeepalive   = "persistent_keepalive"
+	PeerSettingDNS                   = "dns"
+	PeerSettingSplitTunnelExclusions = "split_tunnel_exclusions"
+)
+
+// Validate validates the profile request
+func (r *PeerProfileRequest) Validate() error {
+	if r.Name == "" {
+		return errors.New("profile name cannot be empty")
+	}
+	return ValidatePeerSettings(r.MTU, r.PersistentKeepalive, r.DNS, r.SplitTunnelExclusions)
+}
+
+// DNSNone, as the only DNS entry of a peer or profile, leaves the DNS line
+// out of the config: the device keeps its own resolver.
+const DNSNone = "none"
+
+// SuppressesDNS reports whether dns opts out of DNS with DNSNone.
+func SuppressesDNS(dns []string) bool {
+	return len(dns) == 1 && dns[0] == DNSNone
+}
+
+// ValidatePeerSettings checks the settings shared by peers and profiles.
+// Unset values are always valid.
+func ValidatePeerSettings(mtu int, keepalive *int, dns, exclusions []string) error {
+	if mtu != 0 && (mtu < MinMTU || mtu > MaxMTU) {
+		return fmt.Errorf("mtu must be between %d and %d", MinMTU, MaxMTU)
+	}
+	if keepalive != nil && (*keepalive < 0 || *keepalive > MaxPersistentKeepalive) {
+		return fmt.Errorf("persistent_keepalive must be between 0 and %d", MaxPersistentKeepalive)
+	}
+	if SuppressesDNS(dns) {
+		return nil
+	}
+	for _, ip := range dns {
+		if ip == DNSNone {
+			return fmt.Errorf("DNS server %q cannot be combined with resolvers", DNSNone)
+		}
+		if net.ParseIP(ip) == nil {
+			return fmt.Errorf("invalid DNS server %q", ip)
+		}
+	}
+	for _, cidr := range exclusions {
+		if err := ValidateCIDR(cidr); err != nil {
+			return fmt.Errorf("invalid split-tunnel exclusion %q: %w", cidr, err)
+		}
+	}
+	return nil
+}
+
+// WithProfile returns a copy of the peer with every setting it does not set
+// itself taken from profile.  A nil profile returns the peer unchanged.
+func (p *Peer) WithProfile(profile *PeerProfile) *Peer {
+	if profile == nil {
+		return p
+	}
+	resolved := *p
+	if resolved.MTU == 0 {
+		resolved.MTU = profile.MTU
+	}
+	if resolved.PersistentKeepalive == nil {
+		resolved.PersistentKeepalive = profile.PersistentKeepalive
+	}
+	if len(resolved.DNS) == 0 {
+		resolved.DNS = profile.DNS
+	}
+	if resolved.SplitTunnelExclusions == nil {
+		resolved.SplitTunnelExclusions = profile.SplitTunnelExclusions
+	}
+	return &resolved
+}
+
+// PeerDefaults are the server-wide settings of peers that set them neither
+// themselves nor through their profile.  A 0 MTU emits no MTU line and an
+// empty DNS list points peers at their jump peer's DNS server.
+type PeerDefaults struct {
+	MTU                 int
+	PersistentKeepalive int // seconds, 0 disables keepalive
+	DNS                 []string
+}
+
+// Validate checks the defaults against the same bounds as peer settings.
+func (d PeerDefaults) Validate() error {
+	return ValidatePeerSettings(d.MTU, &d.PersistentKeepalive, d.DNS, nil)
+}
+
+// WithDefaults returns a copy of the peer with every setting still unset
+// taken from defaults.  Call it after WithProfile.
+func (p *Peer) WithDefaults(defaults PeerDefaults) *Peer {
+	resolved := *p
+	if resolved.MTU == 0 {
+		resolved.MTU = defaults.MTU
+	}
+	if resolved.PersistentKeepalive == nil {
+		keepalive := defaults.PersistentKeepalive
+		resolved.PersistentKeepalive = &keepalive
+	}
+	if len(resolved.DNS) == 0 {
+		resolved.DNS = defaults.DNS
+	}
+	return &resolved
+}
@@ -2,6 +2,7 @@ package network
 
 import (
 	"context"
+	"time"
 )
 
 // IPAMPrefix holds minimal information about an allocated prefix
@@ -11,6 +12,39 @@ type IPAMPrefix struct {
 	UsableHosts int    `json:"usable_hosts"`
 }
 
+// IPAllocation is an address handed out from an IPAM prefix, reservations
+// aside.
+type IPAllocation struct {
+	IP          string    `json:"ip"`
+	AllocatedAt time.Time `json:"allocated_at"`
+}
+
+// IPAMReconcileResult lists the addresses an IPAM reconciliation returned
+// to the pool.
+type IPAMReconcileResult struct {
+	NetworkID string   `json:"network_id"`
+	Released  []string `json:"released"`
+}
+
+// IPAMUsage reports how full the address pools of a network are, one entry
+// per address family the network has
+type IPAMUsage struct {
+	NetworkID string           `json:"network_id"`
+	IPv4      *IPAMFamilyUsage `json:"ipv4,omitempty"`
+	IPv6      *IPAMFamilyUsage `json:"ipv6,omitempty"`
+}
+
+// IPAMFamilyUsage counts the addresses of one network CIDR.  Total and Free
+// are omitted for prefixes too large for a count to be meaningful, e.g. an
+// IPv6 /64: only the allocations are reported then.
+type IPAMFamilyUsage struct {
+	CIDR      string  `json:"cidr"`
+	Total     *uint64 `json:"total,omitempty"` // usable host addresses
+	Allocated uint64  `json:"allocated"`       // addresses held by peers (or leaked allocations)
+	Reserved  uint64  `json:"reserved"`        // addresses held back from peers
+	Free      *uint64 `json:"free,omitempty"`  // addresses AcquireIP can still hand out
+}
+
 // Repository defines the interface for network data persistence
 type Repository interface {
 	// Network operations
@@ -20,6 +54,12 @@ type Repository interface {
 	DeleteNetwork(ctx context.Context, networkID string) error
 	ListNetworks(ctx context.Context) ([]*Network, error)
 
+	// Soft deletion: a soft-deleted network keeps its rows but is left out
+	// of GetNetwork and ListNetworks.  DeleteNetwork removes it for good.
+	SoftDeleteNetwork(ctx context.Context, networkID string, at time.Time) error
+	RestoreNetwork(ctx context.Context, networkID string) error
+	ListDeletedNetworks(ctx context.Context) ([]*Network, error)
+
 	// Peer operations
 	CreatePeer(ctx context.Context, networkID string, peer *Peer) error
 	GetPeer(ctx context.Context, networkID, peerID string) (*Peer, error)
@@ -27,6 +67,10 @@ type Repository interface {
 	UpdatePeer(ctx context.Context, networkID string, peer *Peer) error
 	DeletePeer(ctx context.Context, networkID, peerID string) error
 	ListPeers(ctx context.Context, networkID string) ([]*Peer, error)
+	// SearchPeers returns the peers of the live networks matching query
+	// (see Peer.MatchesSearch), ordered by network name then peer name,
+	// with the total number of matches.
+	SearchPeers(ctx context.Context, query string, offset, limit int) ([]*PeerSearchResult, int, error)
 
 	// ACL operations
 	CreateACL(ctx context.Context, networkID string, acl *ACL) error
@@ -37,6 +81,7 @@ type Repository interface {
 	CreateConnection(ctx context.Context, networkID string, conn *PeerConnection) error
 	GetConnection(ctx context.Context, networkID, peer1ID, peer2ID string) (*PeerConnection, error)
 	ListConnections(ctx context.Context, networkID string) ([]*PeerConnection, error)
+	UpdateConnection(ctx context.Context, networkID string, conn *PeerConnection) error
 	DeleteConnection(ctx context.Context, networkID, peer1ID, peer2ID string) error
 
 	// Agent session operations
@@ -45,6 +90,8 @@ type Repository interface {
 	GetActiveSessionsForPeer(ctx context.Context, networkID, peerID string) ([]*AgentSession, error)
 	DeleteSession(ctx context.Context, networkID, sessionID string) error
 	ListSessions(ctx context.Context, networkID string) ([]*AgentSession, error)
+	ListSessionHistory(ctx context.Context, networkID, peerID string) ([]*AgentSession, error)
+	RevokeSession(ctx context.Context, networkID, sessionID string, revokedAt time.Time) error
 
 	// Captive portal whitelist operations
 	AddCaptivePortalWhitelist(ctx context.Context, networkID, jumpPeerID, peerIP, peerEndpoint string) error
@@ -101,4 +148,19 @@ type Repository interface {
 	UpsertPeerLocalRoutes(ctx context.Context, networkID, peerID string, allowedIPs []string) error
 	GetPeerLocalRoutes(ctx context.Context, networkID, peerID string) ([]string, error)
 	ListPeerLocalRoutes(ctx context.Context, networkID string) (map[string][]string, error) // peerID -> CIDRs
+
+	// Temporary (break-glass) per-peer routes.  ListTempRoutes returns every
+	// grant of the network when peerID is empty, expired ones included; the
+	// caller filters on ExpiresAt.
+	CreateTempRoute(ctx context.Context, route *TempRoute) error
+	ListTempRoutes(ctx context.Context, networkID, peerID string) ([]*TempRoute, error)
+	DeleteTempRoute(ctx context.Context, networkID, id string) error
+	ListExpiredTempRoutes(ctx context.Context, before time.Time) ([]*TempRoute, error)
+
+	// Peer profile operations
+	CreatePeerProfile(ctx context.Context, profile *PeerProfile) error
+	GetPeerProfile(ctx context.Context, networkID, profileID string) (*PeerProfile, error)
+	UpdatePeerProfile(ctx context.Context, profile *PeerProfile) error
+	DeletePeerProfile(ctx context.Context, networkID, profileID string) error
+	ListPeerProfiles(ctx context.Context, networkID string) ([]*PeerProfile, error)
 }
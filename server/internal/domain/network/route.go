@@ -17,6 +17,9 @@ import (
 // AllowedIPs — useful for a single "full tunnel" or "internet" route that
 // covers both address families with one entity instead of two parallel rows.
 // Migration 027 enforces at the DB level that at least one is set.
+//
+// A GlobalRoute reaches every regular peer of the network, whatever its
+// groups, so a network-wide route needs no group of its own.
 type Route struct {
 	ID                string    `json:"id"`
 	NetworkID         string    `json:"network_id"`
@@ -26,6 +29,8 @@ type Route struct {
 	DestinationCIDRv6 string    `json:"destination_cidr_v6,omitempty"` // IPv6 CIDR (optional if v4 is set)
 	JumpPeerID        string    `json:"jump_peer_id"`                  // Gateway jump peer
 	DomainSuffix      string    `json:"domain_suffix"`                 // Custom domain (default: .internal)
+	Metric            int       `json:"metric"`                        // Lower wins when routes share a destination (default: 0)
+	GlobalRoute       bool      `json:"global_route"`                  // Advertised to every peer, not only attached groups
 	CreatedAt         time.Time `json:"created_at"`
 	UpdatedAt         time.Time `json:"updated_at"`
 }
@@ -39,6 +44,8 @@ type RouteCreateRequest struct {
 	DestinationCIDRv6 string `json:"destination_cidr_v6,omitempty"`
 	JumpPeerID        string `json:"jump_peer_id" binding:"required"`
 	DomainSuffix      string `json:"domain_suffix"`
+	Metric            int    `json:"metric,omitempty"`
+	GlobalRoute       bool   `json:"global_route,omitempty"`
 }
 
 // RouteUpdateRequest represents the data that can be updated for a route.
@@ -51,6 +58,8 @@ type RouteUpdateRequest struct {
 	DestinationCIDRv6 string `json:"destination_cidr_v6,omitempty"`
 	JumpPeerID        string `json:"jump_peer_id,omitempty"`
 	DomainSuffix      string `json:"domain_suffix,omitempty"`
+	Metric            *int   `json:"metric,omitempty"`       // Set to change; 0 is a valid metric
+	GlobalRoute       *bool  `json:"global_route,omitempty"` // Set to change
 }
 
 // Validate validates the route creation request
@@ -80,7 +89,7 @@ func (r *RouteCreateRequest) Validate() error {
 			return err
 		}
 	}
-	return nil
+	return validateRouteMetric(r.Metric)
 }
 
 // Validate validates the route update request.  Note: this checks the SHAPE
@@ -108,6 +117,18 @@ func (r *RouteUpdateRequest) Validate() error {
 			return err
 		}
 	}
+	if r.Metric != nil {
+		return validateRouteMetric(*r.Metric)
+	}
+	return nil
+}
+
+// validateRouteMetric rejects negative metrics, which would outrank the
+// temporary route grants (metric 0) meant to override every route.
+func validateRouteMetric(metric int) error {
+	if metric < 0 {
+		return errors.New("metric cannot be negative")
+	}
 	return nil
 }
 
@@ -138,10 +159,10 @@ func ValidateCIDRFamily(cidr string, wantV6 bool) error {
 	}
 	isV6 := ip.To4() == nil
 	if wantV6 && !isV6 {
-		return errors.New("expected an IPv6 CIDR")
+		return errors.New("expected an IPv6 CIDR (IPv4 destinations go in destination_cidr)")
 	}
 	if !wantV6 && isV6 {
-		return errors.New("expected an IPv4 CIDR")
+		return errors.New("expected an IPv4 CIDR (IPv6 destinations go in destination_cidr_v6)")
 	}
 	return nil
 }
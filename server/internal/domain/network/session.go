@@ -4,16 +4,185 @@ import "time"
 
 // AgentSession represents an active agent session with system information
 type AgentSession struct {
-	PeerID           string    `json:"peer_id"`           // Peer ID this session belongs to
-	Hostname         string    `json:"hostname"`          // Agent hostname
-	SystemUptime     int64     `json:"system_uptime"`     // Host uptime in seconds
-	WireGuardUptime  int64     `json:"wireguard_uptime"`  // WireGuard interface uptime in seconds
-	ReportedEndpoint string    `json:"reported_endpoint"` // Endpoint as reported by other agents
-	LastSeen         time.Time `json:"last_seen"`         // Last heartbeat timestamp
-	FirstSeen        time.Time `json:"first_seen"`        // First connection timestamp
-	SessionID        string    `json:"session_id"`        // Unique session identifier
+	PeerID           string     `json:"peer_id"`                    // Peer ID this session belongs to
+	Hostname         string     `json:"hostname"`                   // Agent hostname
+	SystemUptime     int64      `json:"system_uptime"`              // Host uptime in seconds
+	WireGuardUptime  int64      `json:"wireguard_uptime"`           // WireGuard interface uptime in seconds
+	ReportedEndpoint string     `json:"reported_endpoint"`          // Endpoint as reported by other agents
+	LastSeen         time.Time  `json:"last_seen"`                  // Last heartbeat timestamp
+	FirstSeen        time.Time  `json:"first_seen"`                 // First connection timestamp
+	SessionID        string     `json:"session_id"`                 // Unique session identifier
+	FirewallBackend  string     `json:"firewall_backend,omitempty"` // Firewall backend reported by the agent (see FirewallBackend* constants)
+	RevokedAt        *time.Time `json:"revoked_at,omitempty"`       // Set when an admin revoked the session; revoked sessions are kept for forensics only
+
+	// PeerTransfer holds the traffic this agent exchanged with each of its
+	// WireGuard peers, keyed by peer public key.
+	PeerTransfer map[string]*PeerTransferStats `json:"peer_transfer,omitempty"`
+
+	// PeerHandshakes holds the latest WireGuard handshake this agent
+	// completed with each of its peers, keyed by peer public key.  Peers it
+	// never handshook with are absent.
+	PeerHandshakes map[string]time.Time `json:"peer_handshakes,omitempty"`
+
+	// PeerLatency holds the round-trip time this agent last measured to each
+	// of its peers, keyed by peer public key.  Only jump agents measure it.
+	PeerLatency map[string]PeerLatency `json:"peer_latency,omitempty"`
+
+	// JumpHealth is the result of the latest health check the server asked
+	// this agent to run.  Only jump agents are asked.
+	JumpHealth *JumpHealth `json:"jump_health,omitempty"`
+
+	// DNSQueries is the latest window of queries this agent's DNS server
+	// received, oldest first, at most MaxDNSQueries.  Only jump agents of
+	// networks with DNSQueryLog set report them.
+	DNSQueries []DNSQuery `json:"dns_queries,omitempty"`
+
+	// LastAppliedConfigHash is the hash (see wireguard.ConfigHash) of the
+	// latest config the agent acknowledged applying, at LastAppliedAt.
+	// Agents that predate config acks leave both empty.
+	LastAppliedConfigHash string     `json:"last_applied_config_hash,omitempty"`
+	LastAppliedAt         *time.Time `json:"last_applied_at,omitempty"`
 }
 
+// MaxDNSQueries is the number of DNS queries a session keeps; older ones
+// are dropped as new ones are reported.
+const MaxDNSQueries = 1000
+
+// DNSQuery is a query received by a jump agent's DNS server.
+type DNSQuery struct {
+	Name      string    `json:"name"`
+	Type      string    `json:"type"` // e.g. "A", "AAAA"
+	ClientIP  string    `json:"client_ip"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// JumpHealth is the outcome of a jump agent's self check: whether WireGuard
+// holds its listen port and whether the agent reaches the internet through
+// its NAT interface.
+type JumpHealth struct {
+	ListenPortOK bool      `json:"listen_port_ok"`
+	InternetOK   bool      `json:"internet_ok"`
+	Reason       string    `json:"reason,omitempty"` // why a check failed, as reported by the agent
+	CheckedAt    time.Time `json:"checked_at"`
+}
+
+// Healthy reports whether every check passed.
+func (h *JumpHealth) Healthy() bool {
+	return h.ListenPortOK && h.InternetOK
+}
+
+// AgentMessageTypeJumpHealth is the type of the message a jump agent sends
+// back after a health check request.  Agent messages without a type are
+// heartbeats.
+const AgentMessageTypeJumpHealth = "jump_health"
+
+// JumpHealthReport is the message a jump agent sends in reply to a health
+// check request.
+type JumpHealthReport struct {
+	Type         string `json:"type"` // AgentMessageTypeJumpHealth
+	ListenPortOK bool   `json:"listen_port_ok"`
+	InternetOK   bool   `json:"internet_ok"`
+	Reason       string `json:"reason,omitempty"`
+}
+
+// AgentMessageTypeConfigAck is the type of the message an agent sends once
+// it applied a config pushed by the server.
+const AgentMessageTypeConfigAck = "config_ack"
+
+// ConfigAck is the message an agent sends after applying a config, carrying
+// the hash of the config it applied.
+type ConfigAck struct {
+	Type       string `json:"type"` // AgentMessageTypeConfigAck
+	ConfigHash string `json:"config_hash"`
+}
+
+// PeerLatency is a round-trip time measured by an agent to one peer.
+type PeerLatency struct {
+	RTTMs      *float64  `json:"rtt_ms"` // nil when the peer did not answer
+	MeasuredAt time.Time `json:"measured_at"`
+}
+
+// Peer statuses derived from the age of the latest WireGuard handshake.
+const (
+	PeerStatusOnline  = "online"  // handshake within the staleness threshold
+	PeerStatusStale   = "stale"   // handshook before, but not within the threshold
+	PeerStatusOffline = "offline" // never handshook
+)
+
+// PeerStatusAt returns the status of a peer whose latest handshake is
+// lastHandshake (zero if it never handshook).
+func PeerStatusAt(lastHandshake, now time.Time, staleAfter time.Duration) string {
+	switch {
+	case lastHandshake.IsZero():
+		return PeerStatusOffline
+	case now.Sub(lastHandshake) > staleAfter:
+		return PeerStatusStale
+	default:
+		return PeerStatusOnline
+	}
+}
+
+// PeerTransfer is a pair of WireGuard transfer counters, as reported by
+// `wg show <iface> transfer`.  Counters restart from zero when the interface
+// is recreated (e.g. agent restart).
+type PeerTransfer struct {
+	RxBytes int64 `json:"rx_bytes"`
+	TxBytes int64 `json:"tx_bytes"`
+}
+
+// PeerTransferStats accumulates the transfer counters an agent reported for
+// one WireGuard peer over the lifetime of its session.
+type PeerTransferStats struct {
+	PeerTransfer               // totals for the session; never decrease
+	Counters      PeerTransfer `json:"counters"`                 // raw counters from the latest heartbeat
+	LastHandshake *time.Time   `json:"last_handshake,omitempty"` // latest WireGuard handshake, if any
+	UpdatedAt     time.Time    `json:"updated_at"`
+}
+
+// Record adds the traffic since the previous report to the totals.  A counter
+// lower than the previous one means it was reset, in which case everything it
+// counted since the reset is new traffic; the totals never go backwards.
+func (s *PeerTransferStats) Record(counters PeerTransfer, now time.Time) {
+	s.RxBytes += counterDelta(s.Counters.RxBytes, counters.RxBytes)
+	s.TxBytes += counterDelta(s.Counters.TxBytes, counters.TxBytes)
+	s.Counters = counters
+	s.UpdatedAt = now
+}
+
+func counterDelta(previous, current int64) int64 {
+	if current < 0 {
+		return 0
+	}
+	if current < previous {
+		return current
+	}
+	return current - previous
+}
+
+// PeerStats is the traffic a peer exchanged with the network, from the peer's
+// point of view.
+type PeerStats struct {
+	PeerID        string     `json:"peer_id"`
+	RxBytes       int64      `json:"rx_bytes"`
+	TxBytes       int64      `json:"tx_bytes"`
+	LastHandshake *time.Time `json:"last_handshake,omitempty"`
+	UpdatedAt     *time.Time `json:"updated_at,omitempty"` // latest report the stats are based on; nil when no agent reported any
+}
+
+// IsRevoked reports whether the session was revoked by an administrator
+func (s *AgentSession) IsRevoked() bool {
+	return s.RevokedAt != nil
+}
+
+// Firewall backends an agent can report.  iptables-nft speaks iptables syntax
+// on top of nf_tables, so only FirewallBackendNftables changes the rule format
+// the server delivers.
+const (
+	FirewallBackendIPTables    = "iptables"     // legacy xtables
+	FirewallBackendIPTablesNft = "iptables-nft" // iptables CLI over nf_tables
+	FirewallBackendNftables    = "nftables"     // native nft, no iptables CLI
+)
+
 // AgentHeartbeat represents a heartbeat message from an agent
 type AgentHeartbeat struct {
 	Hostname        string            `json:"hostname"`
@@ -53,6 +222,26 @@ type AgentHeartbeat struct {
 	// Only jump-peer agents populate this field (they are the only agents whose
 	// `wg show endpoints` lists other peers).
 	EndpointTakeovers []EndpointTakeoverReport `json:"endpoint_takeovers,omitempty"`
+
+	// FirewallBackend is the firewall backend the agent detected on its host
+	// (one of the FirewallBackend* constants).  Stored on the session so that
+	// config generation can deliver jump-peer rules in a format the agent can
+	// apply.  Empty for older agents, which are assumed to speak iptables.
+	FirewallBackend string `json:"firewall_backend,omitempty"`
+
+	// PeerTransfer holds the `wg show <iface> transfer` counters for each
+	// peer, keyed by peer public key.  Absent for older agents.
+	PeerTransfer map[string]PeerTransfer `json:"peer_transfer,omitempty"`
+
+	// PeerLatency holds the round-trip time in milliseconds to each peer,
+	// keyed by peer public key.  A negative value means the peer did not
+	// answer.  Reported by jump agents only.
+	PeerLatency map[string]float64 `json:"peer_latency,omitempty"`
+
+	// DNSQueries holds the queries the agent's DNS server received since the
+	// previous heartbeat.  Reported by jump agents of networks that log DNS
+	// queries only.
+	DNSQueries []DNSQuery `json:"dns_queries,omitempty"`
 }
 
 // EndpointTakeoverReport is a single rogue-source observation reported by the
@@ -79,4 +268,24 @@ type PeerConnectivityStatus struct {
 	//   "quarantined"    — peer exceeded auth-failure threshold; access blocked
 	//   ""               — no auth record (new / un-authenticated peer)
 	CaptivePortalState string `json:"captive_portal_state,omitempty"`
+
+	// Status is online, stale or offline depending on the age of LastHandshake,
+	// the peer's most recent WireGuard handshake seen by any agent.
+	Status        string     `json:"status"`
+	LastHandshake *time.Time `json:"last_handshake,omitempty"`
+
+	// LatencyMs is the round-trip time to the peer most recently measured by
+	// a jump agent.  It is null (unknown) when no jump measured it within the
+	// staleness threshold or the peer did not answer.
+	LatencyMs *float64 `json:"latency_ms"`
+
+	// JumpHealthy is set for jump peers only: true when the latest health
+	// check passed and is recent.  JumpHealthReason says why it is false.
+	JumpHealthy      bool   `json:"jump_healthy"`
+	JumpHealthReason string `json:"jump_health_reason,omitempty"`
+
+	// ConfigInSync tells whether the config the agent last acknowledged is
+	// the peer's current config.  It is null when the agent never
+	// acknowledged one.
+	ConfigInSync *bool `json:"config_in_sync,omitempty"`
 }
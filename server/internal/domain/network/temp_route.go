@@ -0,0 +1,92 @@
+package network
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// TempRoute is a time-bounded route granted directly to a single peer, used
+// for break-glass access (e.g. on-call needing a subnet for an hour).  Unlike a
+// Route it is not attached through a group: it only affects the target peer's
+// config and the jump peer's firewall, and it is removed by a background sweep
+// once ExpiresAt has passed.
+type TempRoute struct {
+	ID                string    `json:"id"`
+	NetworkID         string    `json:"network_id"`
+	PeerID            string    `json:"peer_id"`
+	RouteID           string    `json:"route_id,omitempty"`            // set when an existing route was granted
+	DestinationCIDR   string    `json:"destination_cidr,omitempty"`    // IPv4 CIDR (optional if v6 is set)
+	DestinationCIDRv6 string    `json:"destination_cidr_v6,omitempty"` // IPv6 CIDR (optional if v4 is set)
+	JumpPeerID        string    `json:"jump_peer_id"`
+	Reason            string    `json:"reason,omitempty"`
+	CreatedBy         string    `json:"created_by,omitempty"` // user ID of the granting admin
+	CreatedAt         time.Time `json:"created_at"`
+	ExpiresAt         time.Time `json:"expires_at"`
+}
+
+// TempRouteCreateRequest grants a temporary route to a peer.  Either RouteID
+// (reuse an existing route's CIDRs and jump peer) or JumpPeerID plus at least
+// one destination CIDR must be provided.
+type TempRouteCreateRequest struct {
+	RouteID           string `json:"route_id,omitempty"`
+	DestinationCIDR   string `json:"destination_cidr,omitempty"`
+	DestinationCIDRv6 string `json:"destination_cidr_v6,omitempty"`
+	JumpPeerID        string `json:"jump_peer_id,omitempty"`
+	DurationMinutes   int    `json:"duration_minutes" binding:"required,min=1"`
+	Reason            string `json:"reason,omitempty"`
+}
+
+// TempRouteMaxDuration caps how long a break-glass grant may last.  Anything
+// longer should be a regular route attached through a group.
+const TempRouteMaxDuration = 24 * time.Hour
+
+// AsRoute converts the grant to a Route so config generation can treat it like
+// any group route.
+func (t *TempRoute) AsRoute() *Route {
+	return &Route{
+		ID:                "temp:" + t.ID,
+		NetworkID:         t.NetworkID,
+		Name:              "temp-" + t.ID,
+		Description:       t.Reason,
+		DestinationCIDR:   t.DestinationCIDR,
+		DestinationCIDRv6: t.DestinationCIDRv6,
+		JumpPeerID:        t.JumpPeerID,
+		CreatedAt:         t.CreatedAt,
+		UpdatedAt:         t.CreatedAt,
+	}
+}
+
+// Validate checks the shape of the request.  Whether RouteID and JumpPeerID
+// refer to existing entities is checked by the service.
+func (r *TempRouteCreateRequest) Validate() error {
+	if r.DurationMinutes <= 0 {
+		return errors.New("duration_minutes must be positive")
+	}
+	if time.Duration(r.DurationMinutes)*time.Minute > TempRouteMaxDuration {
+		return fmt.Errorf("duration_minutes cannot exceed %d", int(TempRouteMaxDuration/time.Minute))
+	}
+	if r.RouteID != "" {
+		if r.DestinationCIDR != "" || r.DestinationCIDRv6 != "" || r.JumpPeerID != "" {
+			return errors.New("route_id cannot be combined with destination CIDRs or jump_peer_id")
+		}
+		return nil
+	}
+	if r.DestinationCIDR == "" && r.DestinationCIDRv6 == "" {
+		return errors.New("either route_id or at least one of destination_cidr / destination_cidr_v6 must be set")
+	}
+	if r.DestinationCIDR != "" {
+		if err := ValidateCIDRFamily(r.DestinationCIDR, false); err != nil {
+			return fmt.Errorf("destination_cidr: %w", err)
+		}
+	}
+	if r.DestinationCIDRv6 != "" {
+		if err := ValidateCIDRFamily(r.DestinationCIDRv6, true); err != nil {
+			return fmt.Errorf("destination_cidr_v6: %w", err)
+		}
+	}
+	if r.JumpPeerID == "" {
+		return errors.New("jump_peer_id is required when destination CIDRs are given")
+	}
+	return nil
+}
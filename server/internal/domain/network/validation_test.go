@@ -1,6 +1,8 @@
 package network
 
 import (
+	"errors"
+	"strings"
 	"testing"
 )
 
@@ -516,6 +518,34 @@ func TestRouteCreateRequest_Validate(t *testing.T) {
 			},
 			expectError: true,
 		},
+		{
+			name: "IPv6 only",
+			request: &RouteCreateRequest{
+				Name:              "test-route",
+				DestinationCIDRv6: "2001:db8:1::/48",
+				JumpPeerID:        "jump-peer-1",
+			},
+			expectError: false,
+		},
+		{
+			name: "dual-stack",
+			request: &RouteCreateRequest{
+				Name:              "test-route",
+				DestinationCIDR:   "192.168.1.0/24",
+				DestinationCIDRv6: "2001:db8:1::/48",
+				JumpPeerID:        "jump-peer-1",
+			},
+			expectError: false,
+		},
+		{
+			name: "IPv6 CIDR in destination_cidr",
+			request: &RouteCreateRequest{
+				Name:            "test-route",
+				DestinationCIDR: "2001:db8:1::/48",
+				JumpPeerID:      "jump-peer-1",
+			},
+			expectError: true,
+		},
 		{
 			name: "empty jump peer ID",
 			request: &RouteCreateRequest{
@@ -606,3 +636,102 @@ func TestGroupCreateRequest_Validate(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateRoutingTable(t *testing.T) {
+	for _, table := range []string{"", "off", "auto", "1", "1234", "4294967295"} {
+		if err := ValidateRoutingTable(table); err != nil {
+			t.Errorf("ValidateRoutingTable(%q) = %v, want nil", table, err)
+		}
+	}
+	for _, table := range []string{"0", "4294967296", "-1", "main", "Off", "12a"} {
+		if err := ValidateRoutingTable(table); !errors.Is(err, ErrInvalidRoutingTable) {
+			t.Errorf("ValidateRoutingTable(%q) = %v, want ErrInvalidRoutingTable", table, err)
+		}
+	}
+}
+
+func TestValidatePublicKey(t *testing.T) {
+	if err := ValidatePublicKey("xTIBA5rboUvnH4htodjb6e697QjLERt1NAB4mZqp8Dg="); err != nil {
+		t.Errorf("valid key rejected: %v", err)
+	}
+	for _, key := range []string{
+		"",
+		"xTIBA5rboUvnH4htodjb6e697QjLERt1NAB4mZqp8Dg",   // no padding
+		"xTIBA5rboUvnH4htodjb6e697QjLERt1NAB4mZqp8Dg==", // too long
+		"xTIBA5rboUvnH4htodjb6e697QjLERt1NAB4mZqp8D_=",  // URL alphabet
+		"xTIBA5rboUvnH4htodjb6e697QjLERt1NAB4mZqp8D==",  // 31 bytes
+	} {
+		if err := ValidatePublicKey(key); !errors.Is(err, ErrInvalidPublicKey) {
+			t.Errorf("ValidatePublicKey(%q) = %v, want ErrInvalidPublicKey", key, err)
+		}
+	}
+}
+
+func TestValidateFwMark(t *testing.T) {
+	for _, mark := range []string{"", "0", "51820", "0xca6c", "0XCA6C", "4294967295", "0xffffffff"} {
+		if err := ValidateFwMark(mark); err != nil {
+			t.Errorf("ValidateFwMark(%q) = %v, want nil", mark, err)
+		}
+	}
+	for _, mark := range []string{"4294967296", "0x100000000", "-1", "0x", "ca6c", "off", " 1"} {
+		if err := ValidateFwMark(mark); !errors.Is(err, ErrInvalidFwMark) {
+			t.Errorf("ValidateFwMark(%q) = %v, want ErrInvalidFwMark", mark, err)
+		}
+	}
+}
+
+func TestValidateLabels(t *testing.T) {
+	if err := ValidateLabels(map[string]string{"env": "prod", "team/owner": "net-ops", "tier": ""}); err != nil {
+		t.Errorf("valid labels rejected: %v", err)
+	}
+	for _, labels := range []map[string]string{
+		{"": "prod"},
+		{"env var": "prod"},
+		{"env": "prod,dev"},
+		{strings.Repeat("k", 64): "v"},
+	} {
+		if err := ValidateLabels(labels); !errors.Is(err, ErrInvalidLabel) {
+			t.Errorf("ValidateLabels(%v) = %v, want ErrInvalidLabel", labels, err)
+		}
+	}
+}
+
+func TestMatchLabels(t *testing.T) {
+	labels := map[string]string{"env": "prod", "tier": ""}
+	tests := []struct {
+		selectors []string
+		want      bool
+	}{
+		{nil, true},
+		{[]string{"env=prod"}, true},
+		{[]string{"env"}, true},
+		{[]string{"tier="}, true},
+		{[]string{"env=prod", "tier"}, true},
+		{[]string{"env=dev"}, false},
+		{[]string{"env=prod", "region"}, false},
+		{[]string{"env=prod", ""}, true},
+	}
+	for _, tt := range tests {
+		if got := MatchLabels(labels, tt.selectors); got != tt.want {
+			t.Errorf("MatchLabels(%v) = %v, want %v", tt.selectors, got, tt.want)
+		}
+	}
+}
+
+func TestValidatePeerSettings_DNS(t *testing.T) {
+	tests := []struct {
+		dns     []string
+		wantErr bool
+	}{
+		{nil, false},
+		{[]string{"10.0.0.53", "2001:db8::53"}, false},
+		{[]string{DNSNone}, false},
+		{[]string{DNSNone, "10.0.0.53"}, true},
+		{[]string{"resolver.example.com"}, true},
+	}
+	for _, tt := range tests {
+		if err := ValidatePeerSettings(0, nil, tt.dns, nil); (err != nil) != tt.wantErr {
+			t.Errorf("ValidatePeerSettings(dns %v) = %v, wantErr %v", tt.dns, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+// ErrInvalidAllowedIP indicates a malformed AllowedIPs entry
+var ErrInvalidAllowedIP = errors.New("invalid allowed IP")
+
+// NormalizeAllowedIPs validates WireGuard AllowedIPs entries and returns them
+// in canonical form.  Each entry must be a CIDR or a bare IP address, which
+// is turned into a single-host /32 or /128.  Exact duplicates are dropped;
+// overlapping ranges are kept since WireGuard handles them.  A nil or empty
+// list is returned as an empty, non-nil slice.
+func NormalizeAllowedIPs(entries []string) ([]string, error) {
+	out := make([]string, 0, len(entries))
+	seen := make(map[netip.Prefix]struct{}, len(entries))
+	for _, entry := range entries {
+		prefix, err := parseAllowedIP(strings.TrimSpace(entry))
+		if err != nil {
+			return nil, err
+		}
+		if _, dup := seen[prefix]; dup {
+			continue
+		}
+		seen[prefix] = struct{}{}
+		out = append(out, prefix.String())
+	}
+	return out, nil
+}
+
+func parseAllowedIP(entry string) (netip.Prefix, error) {
+	if entry == "" {
+		return netip.Prefix{}, fmt.Errorf("%w: entry cannot be empty", ErrInvalidAllowedIP)
+	}
+	if !strings.Contains(entry, "/") {
+		addr, err := netip.ParseAddr(entry)
+		if err != nil || addr.Zone() != "" {
+			return netip.Prefix{}, fmt.Errorf("%w: %q is neither a CIDR nor an IP address", ErrInvalidAllowedIP, entry)
+		}
+		addr = addr.Unmap()
+		return netip.PrefixFrom(addr, addr.BitLen()), nil
+	}
+	prefix, err := netip.ParsePrefix(entry)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("%w: %q is not a valid CIDR", ErrInvalidAllowedIP, entry)
+	}
+	return prefix, nil
+}
@@ -0,0 +1,51 @@
+package validation
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeAllowedIPs(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      []string
+		want    []string
+		wantErr bool
+	}{
+		{name: "nil", in: nil, want: []string{}},
+		{name: "ipv4 cidr", in: []string{"192.168.1.0/24"}, want: []string{"192.168.1.0/24"}},
+		{name: "bare ipv4", in: []string{"10.0.0.5"}, want: []string{"10.0.0.5/32"}},
+		{name: "ipv6 cidr", in: []string{"fd00:0:0::/64"}, want: []string{"fd00::/64"}},
+		{name: "bare ipv6", in: []string{"2001:db8::1"}, want: []string{"2001:db8::1/128"}},
+		{name: "surrounding spaces", in: []string{" 10.1.0.0/16 "}, want: []string{"10.1.0.0/16"}},
+		{name: "overlapping ranges kept", in: []string{"10.0.0.0/8", "10.1.0.0/16"}, want: []string{"10.0.0.0/8", "10.1.0.0/16"}},
+		{name: "duplicates dropped", in: []string{"10.0.0.5", "10.0.0.5/32"}, want: []string{"10.0.0.5/32"}},
+		{name: "default routes", in: []string{"0.0.0.0/0", "::/0"}, want: []string{"0.0.0.0/0", "::/0"}},
+		{name: "ipv4 prefix too long", in: []string{"10.0.0.0/33"}, wantErr: true},
+		{name: "ipv6 prefix too long", in: []string{"fd00::/129"}, wantErr: true},
+		{name: "empty string", in: []string{""}, wantErr: true},
+		{name: "blank string", in: []string{"  "}, wantErr: true},
+		{name: "garbage", in: []string{"10.0.0.256"}, wantErr: true},
+		{name: "hostname", in: []string{"nas.internal"}, wantErr: true},
+		{name: "one bad entry fails all", in: []string{"10.0.0.0/24", "10.0.0.0/"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NormalizeAllowedIPs(tt.in)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidAllowedIP) {
+					t.Errorf("NormalizeAllowedIPs(%q) error = %v, want ErrInvalidAllowedIP", tt.in, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NormalizeAllowedIPs(%q) unexpected error: %v", tt.in, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NormalizeAllowedIPs(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
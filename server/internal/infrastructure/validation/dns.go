@@ -150,3 +150,14 @@ func SanitizeDNSName(name string) string {
 
 	return name
 }
+
+// CompilePeerNamePattern compiles a network's peer naming convention.  The
+// pattern is anchored so that it must match the whole name, e.g.
+// `[a-z]+-(web|db)-[0-9]+` accepts "par-web-1" but not "par-web-1-old".
+func CompilePeerNamePattern(pattern string) (*regexp.Regexp, error) {
+	re, err := regexp.Compile(`^(?:` + pattern + `)$`)
+	if err != nil {
+		return nil, fmt.Errorf("invalid peer name pattern: %w", err)
+	}
+	return re, nil
+}
@@ -0,0 +1,45 @@
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// ValidateEndpointHost validates the host part of a peer's public endpoint.
+// It accepts an IPv4 literal, an IPv6 literal (with or without surrounding
+// brackets) or a DNS hostname.  The port is configured separately through
+// ListenPort, so "host:port" forms are rejected with a hint.
+func ValidateEndpointHost(host string) error {
+	if host == "" {
+		return errors.New("endpoint cannot be empty")
+	}
+
+	bare := strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	if ip := net.ParseIP(bare); ip != nil {
+		return nil
+	}
+	if bare != host {
+		return fmt.Errorf("endpoint %q is not a valid IPv6 address", host)
+	}
+
+	if _, _, err := net.SplitHostPort(host); err == nil {
+		return fmt.Errorf("endpoint %q must not include a port, use listen_port instead", host)
+	}
+
+	if err := ValidateDNSHostname(strings.ToLower(host)); err != nil {
+		return fmt.Errorf("endpoint %q is neither an IP address nor a valid hostname: %w", host, err)
+	}
+	return nil
+}
+
+// EndpointHost returns the bare host of endpoint, dropping a port if one is
+// present and the brackets around an IPv6 literal, so "[2001:db8::1]:51820",
+// "[2001:db8::1]" and "2001:db8::1" all yield "2001:db8::1".
+func EndpointHost(endpoint string) string {
+	if host, _, err := net.SplitHostPort(endpoint); err == nil {
+		return host
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(endpoint, "["), "]")
+}
@@ -0,0 +1,53 @@
+package validation
+
+import "testing"
+
+func TestValidateEndpointHost(t *testing.T) {
+	tests := []struct {
+		host    string
+		wantErr bool
+	}{
+		{"vpn.example.com", false},
+		{"VPN.Example.com", false},
+		{"203.0.113.1", false},
+		{"2001:db8::1", false},
+		{"[2001:db8::1]", false},
+		{"", true},
+		{"203.0.113.1:51820", true},
+		{"[2001:db8::1]:51820", true},
+		{"[vpn.example.com]", true},
+		{"vpn_example.com", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			err := ValidateEndpointHost(tt.host)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateEndpointHost(%q) error = %v, wantErr %v", tt.host, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEndpointHost(t *testing.T) {
+	tests := []struct {
+		endpoint string
+		want     string
+	}{
+		{"vpn.example.com", "vpn.example.com"},
+		{"vpn.example.com:51820", "vpn.example.com"},
+		{"203.0.113.10", "203.0.113.10"},
+		{"203.0.113.10:51820", "203.0.113.10"},
+		{"2001:db8::1", "2001:db8::1"},
+		{"[2001:db8::1]", "2001:db8::1"},
+		{"[2001:db8::1]:51820", "2001:db8::1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.endpoint, func(t *testing.T) {
+			if got := EndpointHost(tt.endpoint); got != tt.want {
+				t.Errorf("EndpointHost(%q) = %q, want %q", tt.endpoint, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+// Package webhook delivers security incidents to an external HTTP endpoint
+// (SOC pager, chat bridge, SIEM, ...).
+//
+// Each incident is POSTed as JSON.  When a secret is configured the body is
+// signed with HMAC-SHA256 and the hex digest sent in SignatureHeader as
+// "sha256=<digest>", so receivers can authenticate the payload.  Delivery is
+// asynchronous: NotifyIncident only enqueues, and a background worker retries
+// non-2xx responses with exponential backoff.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"wirety/internal/domain/network"
+
+	"github.com/rs/zerolog/log"
+)
+
+// SignatureHeader carries the HMAC-SHA256 signature of the request body.
+const SignatureHeader = "X-Wirety-Signature"
+
+const (
+	queueSize      = 100
+	maxAttempts    = 5
+	initialBackoff = time.Second
+	requestTimeout = 10 * time.Second
+)
+
+// Notifier posts security incidents to a webhook URL.
+type Notifier struct {
+	url     string
+	secret  string
+	client  *http.Client
+	queue   chan *network.SecurityIncident
+	backoff time.Duration // delay before the first retry, doubled on each one
+}
+
+// NewNotifier creates a Notifier for url and starts its delivery worker.
+// secret may be empty, in which case payloads are not signed.
+func NewNotifier(url, secret string) *Notifier {
+	n := &Notifier{
+		url:     url,
+		secret:  secret,
+		client:  &http.Client{Timeout: requestTimeout},
+		queue:   make(chan *network.SecurityIncident, queueSize),
+		backoff: initialBackoff,
+	}
+	go n.run()
+	return n
+}
+
+// NotifyIncident queues an incident for delivery without blocking.  When the
+// queue is full (the endpoint has been down for a while) the incident is
+// dropped and logged.
+func (n *Notifier) NotifyIncident(incident *network.SecurityIncident) {
+	select {
+	case n.queue <- incident:
+	default:
+		log.Warn().
+			Str("incident_id", incident.ID).
+			Str("incident_type", incident.IncidentType).
+			Msg("webhook queue full, dropping security incident")
+	}
+}
+
+func (n *Notifier) run() {
+	for incident := range n.queue {
+		n.deliver(incident)
+	}
+}
+
+// deliver posts an incident, retrying until it is accepted or maxAttempts is
+// reached.
+func (n *Notifier) deliver(incident *network.SecurityIncident) {
+	body, err := json.Marshal(incident)
+	if err != nil {
+		log.Error().Err(err).Str("incident_id", incident.ID).Msg("failed to encode security incident")
+		return
+	}
+	backoff := n.backoff
+	for attempt := 1; ; attempt++ {
+		err = n.post(body)
+		if err == nil {
+			return
+		}
+		if attempt == maxAttempts {
+			break
+		}
+		log.Debug().Err(err).Int("attempt", attempt).Str("incident_id", incident.ID).Msg("webhook delivery failed, retrying")
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+	log.Error().Err(err).
+		Str("incident_id", incident.ID).
+		Str("incident_type", incident.IncidentType).
+		Int("attempts", maxAttempts).
+		Msg("webhook delivery failed")
+}
+
+func (n *Notifier) post(body []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if n.secret != "" {
+		req.Header.Set(SignatureHeader, "sha256="+Sign(n.secret, body))
+	}
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// Sign returns the hex-encoded HMAC-SHA256 of body keyed with secret.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,51 @@
+package webhook
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"wirety/internal/domain/network"
+)
+
+func TestNotifier_SignsAndRetries(t *testing.T) {
+	var calls atomic.Int32
+	received := make(chan *network.SecurityIncident, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if got, want := r.Header.Get(SignatureHeader), "sha256="+Sign("s3cret", body); got != want {
+			t.Errorf("signature = %q, want %q", got, want)
+		}
+		// Fail the first two attempts.
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		var inc network.SecurityIncident
+		if err := json.Unmarshal(body, &inc); err != nil {
+			t.Errorf("decode payload: %v", err)
+		}
+		received <- &inc
+	}))
+	defer srv.Close()
+
+	n := NewNotifier(srv.URL, "s3cret")
+	n.backoff = time.Millisecond
+	n.NotifyIncident(&network.SecurityIncident{ID: "inc1", IncidentType: "quarantine", NetworkID: "net1", PeerID: "peer1"})
+
+	select {
+	case inc := <-received:
+		if inc.ID != "inc1" || inc.PeerID != "peer1" {
+			t.Errorf("unexpected payload %+v", inc)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("incident was not delivered")
+	}
+	if got := calls.Load(); got != 3 {
+		t.Errorf("attempts = %d, want 3", got)
+	}
+}
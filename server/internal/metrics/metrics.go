@@ -0,0 +1,94 @@
+// Package metrics exposes the server's Prometheus metrics.
+//
+// Counters live on the default registry and are incremented directly by the
+// application services. Gauges (networks, peers, connected agents) are
+// computed at scrape time through RegisterGauges so they always reflect the
+// current state instead of drifting from it.
+package metrics
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const namespace = "wirety"
+
+// Security incident kinds used as the "kind" label of SecurityIncidents.
+const (
+	IncidentEndpointTakeover = "endpoint_takeover"
+	IncidentQuarantine       = "quarantine"
+)
+
+var (
+	// PeersCreated counts peers successfully added to a network.
+	PeersCreated = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "peers_created_total",
+		Help:      "Total number of peers created.",
+	})
+
+	// ConfigGenerations counts WireGuard configurations rendered for peers.
+	// Use rate() to get generations per minute.
+	ConfigGenerations = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "config_generations_total",
+		Help:      "Total number of peer WireGuard configurations generated.",
+	})
+
+	// ConfigCacheLookups counts agent config lookups in the config cache,
+	// by result ("hit" or "miss").  Hits cost no repository round-trip.
+	ConfigCacheLookups = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "config_cache_lookups_total",
+		Help:      "Total number of agent config cache lookups, by result.",
+	}, []string{"result"})
+
+	// SecurityIncidents counts security events raised by the server, by kind.
+	SecurityIncidents = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "security_incidents_total",
+		Help:      "Total number of security incidents raised, by kind.",
+	}, []string{"kind"})
+)
+
+func init() {
+	prometheus.MustRegister(PeersCreated, ConfigGenerations, ConfigCacheLookups, SecurityIncidents)
+}
+
+// Inventory reports the current number of networks and peers.
+type Inventory func() (networks, peers int)
+
+var registerGaugesOnce sync.Once
+
+// RegisterGauges registers the scrape-time gauges on the default registry.
+// Only the first call has an effect.
+func RegisterGauges(inventory Inventory, connectedAgents func() int) {
+	registerGaugesOnce.Do(func() {
+		prometheus.MustRegister(
+			prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+				Namespace: namespace,
+				Name:      "networks",
+				Help:      "Current number of networks.",
+			}, func() float64 {
+				networks, _ := inventory()
+				return float64(networks)
+			}),
+			prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+				Namespace: namespace,
+				Name:      "peers",
+				Help:      "Current number of peers across all networks.",
+			}, func() float64 {
+				_, peers := inventory()
+				return float64(peers)
+			}),
+			prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+				Namespace: namespace,
+				Name:      "connected_agents",
+				Help:      "Current number of agents connected over WebSocket.",
+			}, func() float64 {
+				return float64(connectedAgents())
+			}),
+		)
+	})
+}
@@ -0,0 +1,79 @@
+// Package tracing sets up OpenTelemetry tracing and exports spans to an
+// OTLP/HTTP collector.
+//
+// Until Init is called with an endpoint, the global TracerProvider is
+// OpenTelemetry's no-op one: Start returns non-recording spans, so
+// instrumented code pays next to nothing.  Spans are linked through the
+// context, and incoming W3C traceparent headers are honoured by the HTTP
+// middleware, so the server's spans join a caller's trace.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName is the instrumentation scope of the server's own spans.
+const tracerName = "wirety"
+
+var provider atomic.Pointer[sdktrace.TracerProvider]
+
+// Init installs a TracerProvider batching spans to the OTLP/HTTP collector at
+// endpoint (e.g. http://otel-collector:4318, to which /v1/traces is
+// appended), under serviceName.  An empty endpoint leaves tracing off.
+func Init(ctx context.Context, endpoint, serviceName string) error {
+	if endpoint == "" {
+		return nil
+	}
+	exp, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(strings.TrimSuffix(endpoint, "/")+"/v1/traces"))
+	if err != nil {
+		return fmt.Errorf("create OTLP exporter: %w", err)
+	}
+	res := resource.NewSchemaless(attribute.String("service.name", serviceName))
+	tp := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exp), sdktrace.WithResource(res))
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	provider.Store(tp)
+	return nil
+}
+
+// Shutdown exports the spans still queued and stops the exporter.
+func Shutdown(ctx context.Context) error {
+	tp := provider.Swap(nil)
+	if tp == nil {
+		return nil
+	}
+	return tp.Shutdown(ctx)
+}
+
+// Enabled reports whether spans are being exported, for instrumentation that
+// has to do some work to describe a span.
+func Enabled() bool {
+	return provider.Load() != nil
+}
+
+// Start begins a span named name, child of the span in ctx if any, and
+// returns a context carrying it.
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, opts...)
+}
+
+// RecordError marks span as failed with err.  A nil err is ignored.
+func RecordError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
@@ -0,0 +1,97 @@
+package tracing
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestStart_NoopWhenDisabled(t *testing.T) {
+	if Enabled() {
+		t.Fatal("tracing enabled before Init")
+	}
+	_, span := Start(context.Background(), "op")
+	if span.IsRecording() {
+		t.Error("span is recording while tracing is off")
+	}
+	span.SetAttributes(attribute.String("k", "v"))
+	RecordError(span, errors.New("boom"))
+	span.End()
+}
+
+func TestExport_LinksSpansAndContinuesRemoteTrace(t *testing.T) {
+	received := make(chan *coltracepb.ExportTraceServiceRequest, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" {
+			t.Errorf("path = %q, want /v1/traces", r.URL.Path)
+		}
+		body, _ := io.ReadAll(r.Body)
+		req := new(coltracepb.ExportTraceServiceRequest)
+		if err := proto.Unmarshal(body, req); err != nil {
+			t.Errorf("decode payload: %v", err)
+		}
+		received <- req
+	}))
+	defer srv.Close()
+
+	if err := Init(context.Background(), srv.URL+"/", "test-service"); err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+	const remoteTrace = "4bf92f3577b34da6a3ce929d0e0e4736"
+	carrier := propagation.HeaderCarrier{"Traceparent": {"00-" + remoteTrace + "-00f067aa0ba902b7-01"}}
+	ctx := otel.GetTextMapPropagator().Extract(context.Background(), carrier)
+	ctx, root := Start(ctx, "GET /api/v1/networks")
+	_, child := Start(ctx, "network.AddPeer")
+	child.SetAttributes(attribute.String("network.id", "net1"))
+	RecordError(child, errors.New("boom"))
+	child.End()
+	root.End()
+	if err := Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	if Enabled() {
+		t.Error("tracing still enabled after Shutdown")
+	}
+
+	req := <-received
+	if len(req.ResourceSpans) != 1 || len(req.ResourceSpans[0].ScopeSpans) != 1 {
+		t.Fatalf("unexpected payload shape: %v", req)
+	}
+	var service string
+	for _, kv := range req.ResourceSpans[0].Resource.Attributes {
+		if kv.Key == "service.name" {
+			service = kv.Value.GetStringValue()
+		}
+	}
+	if service != "test-service" {
+		t.Errorf("service.name = %q, want test-service", service)
+	}
+	spans := req.ResourceSpans[0].ScopeSpans[0].Spans
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(spans))
+	}
+	gotChild, gotRoot := spans[0], spans[1]
+	if hex.EncodeToString(gotRoot.TraceId) != remoteTrace || hex.EncodeToString(gotChild.TraceId) != remoteTrace {
+		t.Errorf("trace IDs = %x/%x, want %s", gotRoot.TraceId, gotChild.TraceId, remoteTrace)
+	}
+	if hex.EncodeToString(gotRoot.ParentSpanId) != "00f067aa0ba902b7" {
+		t.Errorf("root parent = %x, want the remote span", gotRoot.ParentSpanId)
+	}
+	if string(gotChild.ParentSpanId) != string(gotRoot.SpanId) {
+		t.Errorf("child parent = %x, want root %x", gotChild.ParentSpanId, gotRoot.SpanId)
+	}
+	if gotChild.Status.GetCode() != tracepb.Status_STATUS_CODE_ERROR || gotChild.Status.GetMessage() != "boom" {
+		t.Errorf("child status = %v, want error boom", gotChild.Status)
+	}
+}
@@ -0,0 +1,42 @@
+package firewall
+
+import (
+	"fmt"
+	"strings"
+)
+
+// NftTable is the inet table the agent creates for wirety rules when running
+// on an nftables-only host.  Chains are named after the iptables built-ins
+// (forward, input, output) so translated rules map one-to-one.
+const NftTable = "wirety"
+
+// TranslateToNft converts the iptables command strings produced by the policy
+// service into equivalent `nft add rule` commands.  Comment lines are passed
+// through unchanged.  Rules using flags the translator does not understand are
+// emitted as comments so the agent never applies a partially translated rule.
+func TranslateToNft(rules []string) []string {
+	out := make([]string, 0, len(rules))
+	for _, rule := range rules {
+		trimmed := strings.TrimSpace(rule)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			out = append(out, rule)
+			continue
+		}
+		nft, err := translateRule(trimmed)
+		if err != nil {
+			out = append(out, fmt.Sprintf("# untranslatable rule (%v): %s", err, trimmed))
+			continue
+		}
+		out = append(out, nft)
+	}
+	return out
+}
+
+// translateRule converts a single iptables/ip6tables command.
+func translateRule(rule string) (string, error) {
+	r, err := ParseRule(rule)
+	if err != nil {
+		return "", err
+	}
+	return r.Nft(), nil
+}
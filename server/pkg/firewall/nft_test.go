@@ -0,0 +1,73 @@
+package firewall
+
+import "testing"
+
+func TestTranslateToNft(t *testing.T) {
+	tests := []struct {
+		name string
+		rule string
+		want string
+	}{
+		{
+			name: "forward accept",
+			rule: "iptables -A FORWARD -s 10.0.0.2 -d 10.0.1.0/24 -j ACCEPT",
+			want: "nft add rule inet wirety forward ip saddr 10.0.0.2 ip daddr 10.0.1.0/24 accept",
+		},
+		{
+			name: "established return traffic",
+			rule: "iptables -A FORWARD -d 10.0.0.2 -s 10.0.1.0/24 -m state --state RELATED,ESTABLISHED -j ACCEPT",
+			want: "nft add rule inet wirety forward ip saddr 10.0.1.0/24 ip daddr 10.0.0.2 ct state related,established accept",
+		},
+		{
+			name: "ipv6 dns",
+			rule: "ip6tables -A INPUT -s fd00::2 -p udp --dport 53 -j ACCEPT",
+			want: "nft add rule inet wirety input ip6 saddr fd00::2 udp dport 53 accept",
+		},
+		{
+			name: "default drop",
+			rule: "iptables -A FORWARD -j DROP",
+			want: "nft add rule inet wirety forward meta nfproto ipv4 drop",
+		},
+		{
+			name: "comment passes through",
+			rule: "# Group-based rule for group g1 (requires IP resolution)",
+			want: "# Group-based rule for group g1 (requires IP resolution)",
+		},
+		{
+			name: "unsupported option becomes comment",
+			rule: "iptables -A FORWARD -i wg0 -j ACCEPT",
+			want: "# untranslatable rule (unsupported option -i): iptables -A FORWARD -i wg0 -j ACCEPT",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := TranslateToNft([]string{tt.rule})
+			if len(got) != 1 || got[0] != tt.want {
+				t.Errorf("TranslateToNft(%q) = %q, want %q", tt.rule, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRules(t *testing.T) {
+	rules := ParseRules([]string{
+		"# Peer-based rule for peer p1 (requires IP resolution)",
+		"ip6tables -A FORWARD -s fd00::2 -d fd00:1::/64 -p tcp --dport 443 -j ACCEPT",
+		"iptables -A FORWARD -i wg0 -j ACCEPT",
+	})
+
+	if len(rules) != 3 {
+		t.Fatalf("got %d rules, want 3: %+v", len(rules), rules)
+	}
+	if rules[0].Comment != "Peer-based rule for peer p1 (requires IP resolution)" || rules[0].Action != "" {
+		t.Errorf("comment line parsed as %+v", rules[0])
+	}
+	want := Rule{Family: FamilyIPv6, Chain: "forward", Source: "fd00::2", Destination: "fd00:1::/64", Protocol: "tcp", DestPort: "443", Action: "accept"}
+	if rules[1] != want {
+		t.Errorf("rule parsed as %+v, want %+v", rules[1], want)
+	}
+	if rules[2].Action != "" || rules[2].Comment == "" {
+		t.Errorf("unsupported rule should become a comment, got %+v", rules[2])
+	}
+}
@@ -0,0 +1,166 @@
+package firewall
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Address families of a Rule.
+const (
+	FamilyIPv4 = "ipv4"
+	FamilyIPv6 = "ipv6"
+)
+
+// Rule is a backend-neutral firewall rule.  The policy service generates
+// iptables command strings; ParseRules turns them into Rules that an agent can
+// render for whichever firewall backend its host uses (iptables or nft).
+//
+// A Rule with only Comment set carries a comment line and matches nothing.
+type Rule struct {
+	Family      string `json:"family,omitempty"`      // FamilyIPv4 or FamilyIPv6
+	Chain       string `json:"chain,omitempty"`       // lowercase built-in chain: forward, input, output
+	Source      string `json:"source,omitempty"`      // IP or CIDR
+	Destination string `json:"destination,omitempty"` // IP or CIDR
+	Protocol    string `json:"protocol,omitempty"`    // tcp, udp, ...
+	SourcePort  string `json:"source_port,omitempty"`
+	DestPort    string `json:"dest_port,omitempty"`
+	State       string `json:"state,omitempty"`  // lowercase conntrack states, e.g. "related,established"
+	Action      string `json:"action,omitempty"` // accept or drop
+	Comment     string `json:"comment,omitempty"`
+}
+
+// ParseRules converts iptables command strings into Rules.  Comment lines
+// become comment Rules; rules the parser does not understand become comment
+// Rules too, so an agent never applies a partially understood rule.
+func ParseRules(rules []string) []Rule {
+	out := make([]Rule, 0, len(rules))
+	for _, rule := range rules {
+		trimmed := strings.TrimSpace(rule)
+		if trimmed == "" {
+			continue
+		}
+		if strings.HasPrefix(trimmed, "#") {
+			out = append(out, Rule{Comment: strings.TrimSpace(strings.TrimPrefix(trimmed, "#"))})
+			continue
+		}
+		r, err := ParseRule(trimmed)
+		if err != nil {
+			out = append(out, Rule{Comment: fmt.Sprintf("untranslatable rule (%v): %s", err, trimmed)})
+			continue
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+// ParseRule parses a single iptables/ip6tables command as generated by the
+// policy service.
+func ParseRule(rule string) (Rule, error) {
+	fields := strings.Fields(rule)
+	if len(fields) == 0 {
+		return Rule{}, fmt.Errorf("empty rule")
+	}
+
+	var r Rule
+	switch fields[0] {
+	case "iptables":
+		r.Family = FamilyIPv4
+	case "ip6tables":
+		r.Family = FamilyIPv6
+	default:
+		return Rule{}, fmt.Errorf("unknown command %q", fields[0])
+	}
+
+	for i := 1; i < len(fields); i++ {
+		flag := fields[i]
+		if flag == "-m" {
+			// Match modules are implied by the options that follow them
+			i++
+			continue
+		}
+		if i+1 >= len(fields) {
+			return Rule{}, fmt.Errorf("missing value for %s", flag)
+		}
+		value := fields[i+1]
+		i++
+		switch flag {
+		case "-A":
+			r.Chain = strings.ToLower(value)
+		case "-s":
+			r.Source = value
+		case "-d":
+			r.Destination = value
+		case "-p":
+			r.Protocol = value
+		case "--sport":
+			r.SourcePort = value
+		case "--dport":
+			r.DestPort = value
+		case "--state", "--ctstate":
+			r.State = strings.ToLower(value)
+		case "-j":
+			r.Action = strings.ToLower(value)
+		default:
+			return Rule{}, fmt.Errorf("unsupported option %s", flag)
+		}
+	}
+
+	if r.Chain == "" || r.Action == "" {
+		return Rule{}, fmt.Errorf("rule has no chain or target")
+	}
+	if r.Action != "accept" && r.Action != "drop" {
+		return Rule{}, fmt.Errorf("unsupported target %s", r.Action)
+	}
+	if (r.SourcePort != "" || r.DestPort != "") && r.Protocol == "" {
+		return Rule{}, fmt.Errorf("port match without protocol")
+	}
+	return r, nil
+}
+
+// Nft renders the rule as an `nft add rule` command in NftTable.  Comment
+// rules render as "# comment".
+func (r Rule) Nft() string {
+	if r.Action == "" {
+		return "# " + r.Comment
+	}
+
+	family := "ip"
+	if r.Family == FamilyIPv6 {
+		family = "ip6"
+	}
+
+	parts := []string{"nft", "add", "rule", "inet", NftTable, r.Chain}
+	if r.Source != "" {
+		parts = append(parts, family, "saddr", r.Source)
+	}
+	if r.Destination != "" {
+		parts = append(parts, family, "daddr", r.Destination)
+	}
+	if r.Protocol != "" {
+		if r.SourcePort == "" && r.DestPort == "" {
+			parts = append(parts, "meta", "l4proto", r.Protocol)
+		}
+		if r.SourcePort != "" {
+			parts = append(parts, r.Protocol, "sport", nftPorts(r.SourcePort))
+		}
+		if r.DestPort != "" {
+			parts = append(parts, r.Protocol, "dport", nftPorts(r.DestPort))
+		}
+	}
+	if r.State != "" {
+		parts = append(parts, "ct", "state", r.State)
+	}
+	if r.Source == "" && r.Destination == "" {
+		// Family-wide rules (e.g. the final FORWARD DROP) must stay limited to
+		// the family of the original command since the table is inet.
+		parts = append(parts, "meta", "nfproto", r.Family)
+	}
+	parts = append(parts, r.Action)
+
+	return strings.Join(parts, " ")
+}
+
+// nftPorts converts an iptables port range ("8000:8100") to nft syntax.
+func nftPorts(ports string) string {
+	return strings.Replace(ports, ":", "-", 1)
+}
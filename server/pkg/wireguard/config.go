@@ -3,75 +3,165 @@ package wireguard
 import (
 	"fmt"
 	"net"
+	"sort"
+	"strconv"
 	"strings"
 
 	domain "wirety/internal/domain/network"
 )
 
-// GenerateConfig generates a WireGuard configuration file for a peer
-func GenerateConfig(peer *domain.Peer, allowedPeers []*domain.Peer, network *domain.Network, presharedKeys map[string]string, routes []*domain.Route) string {
+// PrivateKeyPlaceholder stands in for the PrivateKey line of configs
+// generated without the private key.
+const PrivateKeyPlaceholder = "# PrivateKey omitted: supplied by the device"
+
+// PeerMetadata is what the "# Groups:" and "# Owner:" comments of a config
+// section say about its peer.
+type PeerMetadata struct {
+	Groups []string // names of the groups the peer belongs to
+	Owner  string   // email (or ID) of the user owning the peer
+}
+
+// ConfigOptions tunes GenerateConfigWithOptions.
+type ConfigOptions struct {
+	// OmitPrivateKey writes PrivateKeyPlaceholder in place of the private
+	// key, for devices that hold their own key.
+	OmitPrivateKey bool
+
+	// Metadata, keyed by peer ID, adds "# ID:", "# Groups:" and "# Owner:"
+	// comments under the "# Name:" one of every section.  nil leaves them
+	// out.
+	Metadata map[string]PeerMetadata
+}
+
+// GenerateConfig generates a WireGuard configuration file for a peer.
+// connections maps the ID of each allowed peer to its connection with peer,
+// which carries the preshared key and any AllowedIPs override.
+func GenerateConfig(peer *domain.Peer, allowedPeers []*domain.Peer, network *domain.Network, connections map[string]*domain.PeerConnection, routes []*domain.Route) string {
+	return GenerateConfigWithOptions(peer, allowedPeers, network, connections, routes, ConfigOptions{})
+}
+
+// GenerateConfigWithoutPrivateKey is GenerateConfig with PrivateKeyPlaceholder
+// in place of the private key, for devices that hold their own key.
+func GenerateConfigWithoutPrivateKey(peer *domain.Peer, allowedPeers []*domain.Peer, network *domain.Network, connections map[string]*domain.PeerConnection, routes []*domain.Route) string {
+	return GenerateConfigWithOptions(peer, allowedPeers, network, connections, routes, ConfigOptions{OmitPrivateKey: true})
+}
+
+// GenerateConfigWithOptions is GenerateConfig with its output tuned by opts.
+func GenerateConfigWithOptions(peer *domain.Peer, allowedPeers []*domain.Peer, network *domain.Network, connections map[string]*domain.PeerConnection, routes []*domain.Route, opts ConfigOptions) string {
 	var sb strings.Builder
 
 	// [Interface] section
 	sb.WriteString("[Interface]\n")
 	fmt.Fprintf(&sb, "# Name: %s\n", peer.Name)
-	fmt.Fprintf(&sb, "PrivateKey = %s\n", peer.PrivateKey)
-	// Address — comma-separated dual-stack when the peer has both IPv4 and IPv6.
-	if peer.AddressV6 != "" {
-		fmt.Fprintf(&sb, "Address = %s, %s\n", peer.Address, peer.AddressV6)
-	} else {
-		fmt.Fprintf(&sb, "Address = %s\n", peer.Address)
+	writeMetadata(&sb, peer, opts.Metadata)
+	// A peer with an imported public key keeps its private key on the device
+	switch {
+	case opts.OmitPrivateKey:
+		sb.WriteString(PrivateKeyPlaceholder + "\n")
+	case peer.PrivateKey != "":
+		fmt.Fprintf(&sb, "PrivateKey = %s\n", peer.PrivateKey)
 	}
+	// Address — one comma-separated entry per assigned address family.
+	fmt.Fprintf(&sb, "Address = %s\n", strings.Join(peer.InterfaceAddresses(), ", "))
 	if peer.ListenPort > 0 {
 		fmt.Fprintf(&sb, "ListenPort = %d\n", peer.ListenPort)
 	}
+	if peer.MTU > 0 {
+		fmt.Fprintf(&sb, "MTU = %d\n", peer.MTU)
+	}
+	// Table = off keeps wg-quick from installing routes for AllowedIPs
+	if peer.RoutingTable != "" {
+		fmt.Fprintf(&sb, "Table = %s\n", peer.RoutingTable)
+	}
+	if peer.FwMark != "" {
+		fmt.Fprintf(&sb, "FwMark = %s\n", peer.FwMark)
+	}
 
 	// Add DNS configuration
 	// For peers with internal domain support, use jump server DNS only
 	// The jump server will forward external queries to upstream DNS servers
-	if !peer.IsJump {
-		dns := ""
+	// unless the peer (or its profile) names its own resolvers or opts out
+	// with DNSNone.
+	if !peer.IsJump && !domain.SuppressesDNS(peer.DNS) {
+		dns := strings.Join(peer.DNS, ", ")
 
 		for _, allowedPeer := range allowedPeers {
-			if allowedPeer.IsJump {
+			if dns == "" && allowedPeer.IsJump {
 				dns = allowedPeer.Address
 			}
 		}
 
+		// Non-IP entries are search domains to wg-quick
+		if dns != "" && network != nil && len(network.SearchDomains) > 0 {
+			dns += ", " + strings.Join(network.SearchList(), ", ")
+		}
+
 		if dns != "" {
 			fmt.Fprintf(&sb, "DNS = %s\n", dns)
 		}
 	}
 
 	// Jump server packet filtering & forwarding now handled dynamically by agent firewall adapter.
-	// (No PostUp/PostDown iptables rules embedded in config.)
+	// (No PostUp/PostDown iptables rules embedded in config unless the
+	// network templates them.  Templates are validated when saved, so a
+	// render error only skips the line.)
+	if peer.IsJump && network != nil {
+		data := jumpHookData(peer, network)
+		if network.JumpPostUp != "" {
+			if line, err := renderHook(network.JumpPostUp, data); err == nil {
+				fmt.Fprintf(&sb, "PostUp = %s\n", line)
+			}
+		}
+		if network.JumpPostDown != "" {
+			if line, err := renderHook(network.JumpPostDown, data); err == nil {
+				fmt.Fprintf(&sb, "PostDown = %s\n", line)
+			}
+		}
+	}
 
 	sb.WriteString("\n")
 
+	keepalive := domain.DefaultPersistentKeepalive
+	if peer.PersistentKeepalive != nil {
+		keepalive = *peer.PersistentKeepalive
+	}
+
+	if multiJumpFailover(peer, network) {
+		allowedPeers = orderFailoverJumps(allowedPeers, routes)
+	}
+
 	// [Peer] sections for each allowed peer
 	for _, allowedPeer := range allowedPeers {
 		sb.WriteString("[Peer]\n")
 		fmt.Fprintf(&sb, "# Name: %s\n", allowedPeer.Name)
+		writeMetadata(&sb, allowedPeer, opts.Metadata)
 		fmt.Fprintf(&sb, "PublicKey = %s\n", allowedPeer.PublicKey)
 
 		// Look up preshared key for this connection
-		if psk, exists := presharedKeys[allowedPeer.ID]; exists && psk != "" {
-			fmt.Fprintf(&sb, "PresharedKey = %s\n", psk)
+		conn := connections[allowedPeer.ID]
+		if conn != nil && conn.PresharedKey != "" {
+			fmt.Fprintf(&sb, "PresharedKey = %s\n", conn.PresharedKey)
 		}
 
-		// Determine AllowedIPs based on peer type and routes
-		allowedIPs := determineAllowedIPs(peer, allowedPeer, network, routes)
+		// Determine AllowedIPs based on peer type and routes, unless the
+		// connection overrides them
+		allowedIPs := conn.AllowedIPsFor(allowedPeer.ID)
+		if len(allowedIPs) == 0 {
+			allowedIPs = determineAllowedIPs(peer, allowedPeer, network, routes)
+		}
 		fmt.Fprintf(&sb, "AllowedIPs = %s\n", strings.Join(allowedIPs, ", "))
 
 		// Add endpoint if the allowed peer is a jump server or has an endpoint
 		if allowedPeer.Endpoint != "" {
-			fmt.Fprintf(&sb, "Endpoint = %s:%d\n", allowedPeer.Endpoint, allowedPeer.ListenPort)
-			sb.WriteString("PersistentKeepalive = 25\n")
-		} else if peer.IsJump && !allowedPeer.IsJump {
+			fmt.Fprintf(&sb, "Endpoint = %s\n", FormatEndpoint(allowedPeer.Endpoint, allowedPeer.ListenPort))
+			if keepalive > 0 {
+				fmt.Fprintf(&sb, "PersistentKeepalive = %d\n", keepalive)
+			}
+		} else if peer.IsJump && !allowedPeer.IsJump && keepalive > 0 {
 			// Jump server connecting to regular peer (no endpoint)
 			// Add keepalive so jump server can initiate handshakes and maintain connection
 			// This is critical for mobile peers behind NAT
-			sb.WriteString("PersistentKeepalive = 25\n")
+			fmt.Fprintf(&sb, "PersistentKeepalive = %d\n", keepalive)
 		}
 
 		sb.WriteString("\n")
@@ -80,6 +170,55 @@ func GenerateConfig(peer *domain.Peer, allowedPeers []*domain.Peer, network *dom
 	return sb.String()
 }
 
+// writeMetadata writes the metadata comments of peer's section, if any.
+// Line breaks are dropped from the values so that every comment stays on
+// its line.
+func writeMetadata(sb *strings.Builder, peer *domain.Peer, metadata map[string]PeerMetadata) {
+	if metadata == nil {
+		return
+	}
+	oneLine := strings.NewReplacer("\r", "", "\n", " ")
+	fmt.Fprintf(sb, "# ID: %s\n", peer.ID)
+	meta := metadata[peer.ID]
+	if len(meta.Groups) > 0 {
+		fmt.Fprintf(sb, "# Groups: %s\n", oneLine.Replace(strings.Join(meta.Groups, ", ")))
+	}
+	if meta.Owner != "" {
+		fmt.Fprintf(sb, "# Owner: %s\n", oneLine.Replace(meta.Owner))
+	}
+}
+
+// multiJumpFailover reports whether peer's config lists the routed CIDRs on
+// every jump peer section rather than only on each route's gateway.
+func multiJumpFailover(peer *domain.Peer, network *domain.Network) bool {
+	return network != nil && network.MultiJumpFailover && !peer.IsJump && !peer.IsResource()
+}
+
+// orderFailoverJumps orders the [Peer] sections of a failover config.  When
+// several peers list the same prefix, WireGuard routes it to the last one, so
+// jumps that are the gateway of one of the peer's routes come last and keep
+// their routes; the other jumps come first, as fallbacks that take over the
+// prefixes once a gateway is removed from the config.  Both groups are sorted
+// by name so the output is stable.
+func orderFailoverJumps(allowedPeers []*domain.Peer, routes []*domain.Route) []*domain.Peer {
+	gateway := make(map[string]bool)
+	for _, route := range routes {
+		gateway[route.JumpPeerID] = true
+	}
+	ordered := append([]*domain.Peer(nil), allowedPeers...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		a, b := ordered[i], ordered[j]
+		if a.IsJump != b.IsJump {
+			return !a.IsJump
+		}
+		if gateway[a.ID] != gateway[b.ID] {
+			return !gateway[a.ID]
+		}
+		return a.Name < b.Name
+	})
+	return ordered
+}
+
 // hostPrefix returns an IP address with a /32 (IPv4) or /128 (IPv6) host-route
 // prefix so that WireGuard AllowedIPs routes traffic to exactly that address.
 func hostPrefix(ip string) string {
@@ -146,20 +285,42 @@ func determineAllowedIPs(peer, allowedPeer *domain.Peer, network *domain.Network
 		return allowedIPs
 	}
 
+	// Resource peers only serve traffic: they never send gateway route CIDRs
+	// (e.g. a default route) into the tunnel, only the jump peer itself and
+	// the prefixes the jump peer explicitly advertises.
+	if peer.IsResource() && allowedPeer.IsJump {
+		allowedIPs = peerHostPrefixes(allowedPeer)
+		return append(allowedIPs, allowedPeer.AdditionalAllowedIPs...)
+	}
+
 	// For regular peers connecting to a jump peer
 	if allowedPeer.IsJump {
 		allowedIPs = peerHostPrefixes(allowedPeer)
 
 		// Include route CIDRs (both families when dual-stack) that use this
-		// jump peer as gateway.
+		// jump peer as gateway.  With multi-jump failover every jump carries
+		// the routes of all of them (see orderFailoverJumps).
+		failover := multiJumpFailover(peer, network)
+		var routed []string
 		for _, route := range routes {
-			if route.JumpPeerID == allowedPeer.ID {
-				allowedIPs = appendRouteCIDRs(allowedIPs, route)
+			if route.JumpPeerID == allowedPeer.ID || failover {
+				routed = appendRouteCIDRs(routed, route)
 			}
 		}
 
 		// Include any additional allowed IPs configured for the jump peer
-		allowedIPs = append(allowedIPs, allowedPeer.AdditionalAllowedIPs...)
+		if failover {
+			for _, jump := range orderFailoverJumps(jumpPeers(network), routes) {
+				routed = append(routed, jump.AdditionalAllowedIPs...)
+			}
+			routed = dedupe(routed)
+		} else {
+			routed = append(routed, allowedPeer.AdditionalAllowedIPs...)
+		}
+
+		// Split-tunnel exceptions bypass the tunnel even when a route (e.g.
+		// 0.0.0.0/0) covers them.  The jump peer's own address is kept.
+		allowedIPs = append(allowedIPs, excludeCIDRs(routed, peer.SplitTunnelExclusions)...)
 	} else {
 		// Regular peer to regular peer: host routes to the peer's address(es)
 		allowedIPs = peerHostPrefixes(allowedPeer)
@@ -168,6 +329,30 @@ func determineAllowedIPs(peer, allowedPeer *domain.Peer, network *domain.Network
 	return allowedIPs
 }
 
+// jumpPeers returns the jump peers of the network.
+func jumpPeers(network *domain.Network) []*domain.Peer {
+	var out []*domain.Peer
+	for _, p := range network.Peers {
+		if p.IsJump {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// dedupe removes repeated entries, keeping the first occurrence.
+func dedupe(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	out := values[:0]
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
 // AllocateIP allocates a new IP address from the network CIDR
 func AllocateIP(cidr string, usedIPs []string) (string, error) {
 	ip, ipnet, err := net.ParseCIDR(cidr)
@@ -239,3 +424,11 @@ func isNetworkOrBroadcast(ip net.IP, ipnet *net.IPNet) bool {
 
 // 	return ip.String()
 // }
+
+// FormatEndpoint joins an endpoint host and port for a WireGuard "Endpoint ="
+// line.  IPv6 literals are wrapped in brackets ([2001:db8::1]:51820); a host
+// that is already bracketed is not wrapped twice.
+func FormatEndpoint(host string, port int) string {
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	return net.JoinHostPort(host, strconv.Itoa(port))
+}
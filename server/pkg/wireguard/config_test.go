@@ -14,7 +14,7 @@ func TestGenerateConfig(t *testing.T) {
 		peer          *domain.Peer
 		allowedPeers  []*domain.Peer
 		network       *domain.Network
-		presharedKeys map[string]string
+		connections   map[string]*domain.PeerConnection
 		routes        []*domain.Route
 		expectedParts []string
 		notExpected   []string
@@ -42,8 +42,8 @@ func TestGenerateConfig(t *testing.T) {
 			network: &domain.Network{
 				CIDR: "10.0.0.0/16",
 			},
-			presharedKeys: map[string]string{
-				"jump1": "preshared-key-123",
+			connections: map[string]*domain.PeerConnection{
+				"jump1": {Peer1ID: "jump1", Peer2ID: "peer1", PresharedKey: "preshared-key-123"},
 			},
 			routes: []*domain.Route{
 				{
@@ -89,7 +89,7 @@ func TestGenerateConfig(t *testing.T) {
 			network: &domain.Network{
 				CIDR: "10.0.0.0/16",
 			},
-			presharedKeys: map[string]string{},
+			connections: map[string]*domain.PeerConnection{},
 			routes: []*domain.Route{
 				{
 					ID:              "route1",
@@ -140,8 +140,8 @@ func TestGenerateConfig(t *testing.T) {
 			network: &domain.Network{
 				CIDR: "10.0.0.0/16",
 			},
-			presharedKeys: map[string]string{},
-			routes:        []*domain.Route{},
+			connections: map[string]*domain.PeerConnection{},
+			routes:      []*domain.Route{},
 			expectedParts: []string{
 				"AllowedIPs = 10.0.0.1/32, 203.0.113.0/24",
 			},
@@ -167,17 +167,101 @@ func TestGenerateConfig(t *testing.T) {
 			network: &domain.Network{
 				CIDR: "10.0.0.0/16",
 			},
-			presharedKeys: map[string]string{},
-			routes:        []*domain.Route{},
+			connections: map[string]*domain.PeerConnection{},
+			routes:      []*domain.Route{},
 			expectedParts: []string{
 				"AllowedIPs = 10.0.0.11/32",
 			},
 		},
+		{
+			name: "connection AllowedIPs override the derived ones",
+			peer: &domain.Peer{
+				ID:         "peer1",
+				Name:       "client-peer",
+				PrivateKey: "private-key-1",
+				Address:    "10.0.0.10",
+			},
+			allowedPeers: []*domain.Peer{
+				{ID: "jump1", Name: "jump-server", PublicKey: "public-key-jump", Address: "10.0.0.1", IsJump: true, Endpoint: "jump.example.com", ListenPort: 51820},
+			},
+			network: &domain.Network{
+				CIDR: "10.0.0.0/16",
+			},
+			connections: map[string]*domain.PeerConnection{
+				"jump1": {Peer1ID: "jump1", Peer2ID: "peer1", AllowedIPsPeerID: "jump1", AllowedIPs: []string{"10.0.0.1/32", "172.20.0.0/16"}},
+			},
+			routes: []*domain.Route{
+				{ID: "route1", DestinationCIDR: "192.168.1.0/24", JumpPeerID: "jump1"},
+			},
+			expectedParts: []string{
+				"AllowedIPs = 10.0.0.1/32, 172.20.0.0/16",
+			},
+			notExpected: []string{
+				"192.168.1.0/24",
+			},
+		},
+		{
+			name: "connection AllowedIPs leave the reverse section alone",
+			peer: &domain.Peer{
+				ID:         "jump1",
+				Name:       "jump-server",
+				PrivateKey: "private-key-jump",
+				Address:    "10.0.0.1",
+				IsJump:     true,
+				ListenPort: 51820,
+			},
+			allowedPeers: []*domain.Peer{
+				{ID: "peer1", Name: "client-peer", PublicKey: "public-key-1", Address: "10.0.0.10"},
+			},
+			network: &domain.Network{
+				CIDR: "10.0.0.0/16",
+			},
+			connections: map[string]*domain.PeerConnection{
+				"peer1": {Peer1ID: "jump1", Peer2ID: "peer1", AllowedIPsPeerID: "jump1", AllowedIPs: []string{"10.0.0.1/32", "172.20.0.0/16"}},
+			},
+			routes: []*domain.Route{},
+			expectedParts: []string{
+				"AllowedIPs = 10.0.0.10/32",
+			},
+			notExpected: []string{
+				"172.20.0.0/16",
+			},
+		},
+		{
+			name: "jump endpoints with hostname, IPv4 and IPv6 hosts",
+			peer: &domain.Peer{
+				ID:         "peer1",
+				Name:       "client-peer",
+				PrivateKey: "private-key-1",
+				Address:    "10.0.0.10",
+			},
+			allowedPeers: []*domain.Peer{
+				{ID: "jump-host", Name: "jump-host", PublicKey: "pk-host", Address: "10.0.0.1", IsJump: true, Endpoint: "vpn.example.com", ListenPort: 51820},
+				{ID: "jump-v4", Name: "jump-v4", PublicKey: "pk-v4", Address: "10.0.0.2", IsJump: true, Endpoint: "203.0.113.1", ListenPort: 51821},
+				{ID: "jump-v6", Name: "jump-v6", PublicKey: "pk-v6", Address: "10.0.0.3", IsJump: true, Endpoint: "2001:db8::1", ListenPort: 51822},
+				{ID: "jump-v6-bracketed", Name: "jump-v6-bracketed", PublicKey: "pk-v6b", Address: "10.0.0.4", IsJump: true, Endpoint: "[2001:db8::2]", ListenPort: 51823},
+			},
+			network: &domain.Network{
+				CIDR: "10.0.0.0/16",
+			},
+			connections: map[string]*domain.PeerConnection{},
+			routes:      []*domain.Route{},
+			expectedParts: []string{
+				"Endpoint = vpn.example.com:51820",
+				"Endpoint = 203.0.113.1:51821",
+				"Endpoint = [2001:db8::1]:51822",
+				"Endpoint = [2001:db8::2]:51823",
+			},
+			notExpected: []string{
+				"Endpoint = 2001:db8::1:51822",
+				"[[2001:db8::2]]",
+			},
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			config := GenerateConfig(tt.peer, tt.allowedPeers, tt.network, tt.presharedKeys, tt.routes)
+			config := GenerateConfig(tt.peer, tt.allowedPeers, tt.network, tt.connections, tt.routes)
 
 			// Check that all expected parts are present
 			for _, expected := range tt.expectedParts {
@@ -300,6 +384,27 @@ func TestDetermineAllowedIPs(t *testing.T) {
 			routes:   []*domain.Route{},
 			expected: []string{"10.0.0.1/32", "172.16.0.0/16"},
 		},
+		{
+			name: "resource peer to jump peer ignores routes",
+			peer: &domain.Peer{
+				ID:   "db1",
+				Role: domain.PeerRoleResource,
+			},
+			allowedPeer: &domain.Peer{
+				ID:                   "jump1",
+				Address:              "10.0.0.1",
+				IsJump:               true,
+				AdditionalAllowedIPs: []string{"172.16.0.0/16"},
+			},
+			routes: []*domain.Route{
+				{
+					ID:              "internet",
+					DestinationCIDR: "0.0.0.0/0",
+					JumpPeerID:      "jump1",
+				},
+			},
+			expected: []string{"10.0.0.1/32", "172.16.0.0/16"},
+		},
 	}
 
 	for _, tt := range tests {
@@ -338,6 +443,371 @@ func TestDetermineAllowedIPs(t *testing.T) {
 	}
 }
 
+func TestGenerateConfig_ResourcePeerNarrowerThanClient(t *testing.T) {
+	network := &domain.Network{CIDR: "10.0.0.0/16"}
+	jump := &domain.Peer{ID: "jump1", Name: "jump", PublicKey: "jump-pub", Address: "10.0.0.1", IsJump: true}
+	routes := []*domain.Route{
+		{ID: "internet", DestinationCIDR: "0.0.0.0/0", DestinationCIDRv6: "::/0", JumpPeerID: "jump1"},
+		{ID: "office", DestinationCIDR: "192.168.10.0/24", JumpPeerID: "jump1"},
+	}
+
+	allowedIPsLine := func(peer *domain.Peer) string {
+		config := GenerateConfig(peer, []*domain.Peer{jump}, network, nil, routes)
+		for _, line := range strings.Split(config, "\n") {
+			if strings.HasPrefix(line, "AllowedIPs = ") {
+				return line
+			}
+		}
+		t.Fatalf("no AllowedIPs in config:\n%s", config)
+		return ""
+	}
+
+	client := allowedIPsLine(&domain.Peer{ID: "laptop", Name: "laptop", Address: "10.0.0.2"})
+	resource := allowedIPsLine(&domain.Peer{ID: "db", Name: "db", Address: "10.0.0.3", Role: domain.PeerRoleResource})
+
+	if want := "AllowedIPs = 10.0.0.1/32, 0.0.0.0/0, ::/0, 192.168.10.0/24"; client != want {
+		t.Errorf("client %q, want %q", client, want)
+	}
+	if want := "AllowedIPs = 10.0.0.1/32"; resource != want {
+		t.Errorf("resource %q, want %q", resource, want)
+	}
+}
+
+func TestGenerateConfig_SplitTunnelExclusions(t *testing.T) {
+	network := &domain.Network{CIDR: "10.0.0.0/16"}
+	jump := &domain.Peer{ID: "jump1", Name: "jump", PublicKey: "jump-pub", Address: "10.0.0.1", IsJump: true}
+	routes := []*domain.Route{
+		{ID: "internet", DestinationCIDR: "0.0.0.0/0", DestinationCIDRv6: "::/0", JumpPeerID: "jump1"},
+	}
+	peer := &domain.Peer{ID: "laptop", Name: "laptop", Address: "10.0.0.2", SplitTunnelExclusions: []string{"192.168.1.0/24"}}
+
+	config := GenerateConfig(peer, []*domain.Peer{jump}, network, nil, routes)
+	var allowed []string
+	for _, line := range strings.Split(config, "\n") {
+		if rest, ok := strings.CutPrefix(line, "AllowedIPs = "); ok {
+			allowed = strings.Split(rest, ", ")
+		}
+	}
+	if len(allowed) < 3 || allowed[0] != "10.0.0.1/32" || allowed[len(allowed)-1] != "::/0" {
+		t.Fatalf("expected the jump host route first and ::/0 untouched, got %v", allowed)
+	}
+
+	// The IPv4 routes must cover every address except the excluded subnet,
+	// each exactly once.
+	var covered uint64
+	var nets []*net.IPNet
+	for _, cidr := range allowed[1 : len(allowed)-1] {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			t.Fatalf("invalid AllowedIPs entry %q: %v", cidr, err)
+		}
+		ones, bits := ipNet.Mask.Size()
+		covered += 1 << (bits - ones)
+		nets = append(nets, ipNet)
+	}
+	if want := uint64(1<<32 - 256); covered != want {
+		t.Errorf("IPv4 routes cover %d addresses, want %d", covered, want)
+	}
+	contains := func(ip string) bool {
+		for _, n := range nets {
+			if n.Contains(net.ParseIP(ip)) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, ip := range []string{"0.0.0.0", "8.8.8.8", "192.168.0.255", "192.168.2.0", "255.255.255.255"} {
+		if !contains(ip) {
+			t.Errorf("%s should go through the tunnel", ip)
+		}
+	}
+	for _, ip := range []string{"192.168.1.0", "192.168.1.42", "192.168.1.255"} {
+		if contains(ip) {
+			t.Errorf("%s should bypass the tunnel", ip)
+		}
+	}
+}
+
+func TestGenerateConfig_MultiJumpFailover(t *testing.T) {
+	gateway := &domain.Peer{ID: "jump-a", Name: "zeta", PublicKey: "pub-a", Address: "10.0.0.1", IsJump: true, Endpoint: "203.0.113.1", ListenPort: 51820}
+	backup1 := &domain.Peer{ID: "jump-b", Name: "beta", PublicKey: "pub-b", Address: "10.0.0.2", IsJump: true, Endpoint: "203.0.113.2", ListenPort: 51820}
+	backup2 := &domain.Peer{ID: "jump-c", Name: "alpha", PublicKey: "pub-c", Address: "10.0.0.3", IsJump: true, Endpoint: "203.0.113.3", ListenPort: 51820}
+	peer := &domain.Peer{ID: "laptop", Name: "laptop", Address: "10.0.0.10"}
+	network := &domain.Network{
+		CIDR:  "10.0.0.0/24",
+		Peers: map[string]*domain.Peer{gateway.ID: gateway, backup1.ID: backup1, backup2.ID: backup2, peer.ID: peer},
+	}
+	routes := []*domain.Route{{ID: "lan", DestinationCIDR: "192.168.1.0/24", JumpPeerID: "jump-a"}}
+	allowedPeers := []*domain.Peer{gateway, backup1, backup2}
+
+	sections := func(config string) (names []string, allowed map[string]string) {
+		allowed = make(map[string]string)
+		var current string
+		for _, line := range strings.Split(config, "\n") {
+			if name, ok := strings.CutPrefix(line, "# Name: "); ok {
+				current = name
+				names = append(names, name)
+			}
+			if ips, ok := strings.CutPrefix(line, "AllowedIPs = "); ok {
+				allowed[current] = ips
+			}
+		}
+		return names[1:], allowed // skip the [Interface] name
+	}
+
+	// Without the flag only the route's gateway carries its CIDR.
+	_, allowed := sections(GenerateConfig(peer, allowedPeers, network, nil, routes))
+	if allowed["zeta"] != "10.0.0.1/32, 192.168.1.0/24" || allowed["beta"] != "10.0.0.2/32" {
+		t.Errorf("unexpected AllowedIPs without failover: %v", allowed)
+	}
+
+	// With it every jump carries the CIDR.  The gateway comes last so
+	// WireGuard routes the shared prefix to it; fallbacks are sorted by name.
+	network.MultiJumpFailover = true
+	names, allowed := sections(GenerateConfig(peer, allowedPeers, network, nil, routes))
+	if want := []string{"alpha", "beta", "zeta"}; strings.Join(names, ",") != strings.Join(want, ",") {
+		t.Errorf("section order = %v, want %v", names, want)
+	}
+	for name, want := range map[string]string{
+		"alpha": "10.0.0.3/32, 192.168.1.0/24",
+		"beta":  "10.0.0.2/32, 192.168.1.0/24",
+		"zeta":  "10.0.0.1/32, 192.168.1.0/24",
+	} {
+		if allowed[name] != want {
+			t.Errorf("AllowedIPs of %s = %q, want %q", name, allowed[name], want)
+		}
+	}
+
+	// Jump peers themselves are unaffected.
+	jumpConfig := GenerateConfig(gateway, []*domain.Peer{peer}, network, nil, routes)
+	if strings.Count(jumpConfig, "[Peer]") != 1 {
+		t.Errorf("jump config should be unchanged by failover:\n%s", jumpConfig)
+	}
+}
+
+func TestGenerateConfig_PeerProfiles(t *testing.T) {
+	network := &domain.Network{CIDR: "10.0.0.0/16"}
+	jump := &domain.Peer{ID: "jump1", Name: "jump", PublicKey: "jump-pub", Address: "10.0.0.1", Endpoint: "vpn.example.com", ListenPort: 51820, IsJump: true}
+	seconds := func(n int) *int { return &n }
+	mobile := &domain.PeerProfile{ID: "mobile", MTU: 1280, PersistentKeepalive: seconds(15), DNS: []string{"10.0.0.53"}}
+	datacenter := &domain.PeerProfile{ID: "datacenter", MTU: 9000, PersistentKeepalive: seconds(120)}
+	defaults := &domain.PeerDefaults{MTU: 1420, PersistentKeepalive: 30, DNS: []string{"10.0.0.254"}}
+
+	tests := []struct {
+		name       string
+		peer       *domain.Peer
+		profile    *domain.PeerProfile
+		defaults   *domain.PeerDefaults
+		expected   []string
+		unexpected []string
+	}{
+		{
+			name:     "mobile profile",
+			peer:     &domain.Peer{ID: "phone", Name: "phone", Address: "10.0.0.2", ProfileID: "mobile"},
+			profile:  mobile,
+			expected: []string{"MTU = 1280\n", "DNS = 10.0.0.53\n", "PersistentKeepalive = 15\n"},
+		},
+		{
+			name:     "datacenter profile",
+			peer:     &domain.Peer{ID: "server", Name: "server", Address: "10.0.0.3", ProfileID: "datacenter"},
+			profile:  datacenter,
+			expected: []string{"MTU = 9000\n", "DNS = 10.0.0.1\n", "PersistentKeepalive = 120\n"},
+		},
+		{
+			name:     "peer overrides its profile",
+			peer:     &domain.Peer{ID: "tablet", Name: "tablet", Address: "10.0.0.4", ProfileID: "mobile", MTU: 1380, DNS: []string{"1.1.1.1", "9.9.9.9"}},
+			profile:  mobile,
+			expected: []string{"MTU = 1380\n", "DNS = 1.1.1.1, 9.9.9.9\n", "PersistentKeepalive = 15\n"},
+		},
+		{
+			name:       "peer disables its profile's keepalive",
+			peer:       &domain.Peer{ID: "sensor", Name: "sensor", Address: "10.0.0.6", ProfileID: "mobile", PersistentKeepalive: seconds(0)},
+			profile:    mobile,
+			defaults:   defaults,
+			expected:   []string{"MTU = 1280\n"},
+			unexpected: []string{"PersistentKeepalive"},
+		},
+		{
+			name:       "no profile",
+			peer:       &domain.Peer{ID: "laptop", Name: "laptop", Address: "10.0.0.5"},
+			expected:   []string{"DNS = 10.0.0.1\n", "PersistentKeepalive = 25\n"},
+			unexpected: []string{"MTU ="},
+		},
+		{
+			name:     "server defaults",
+			peer:     &domain.Peer{ID: "desktop", Name: "desktop", Address: "10.0.0.7"},
+			defaults: defaults,
+			expected: []string{"MTU = 1420\n", "DNS = 10.0.0.254\n", "PersistentKeepalive = 30\n"},
+		},
+		{
+			name:     "profile beats server defaults",
+			peer:     &domain.Peer{ID: "server2", Name: "server2", Address: "10.0.0.8", ProfileID: "datacenter"},
+			profile:  datacenter,
+			defaults: defaults,
+			expected: []string{"MTU = 9000\n", "DNS = 10.0.0.254\n", "PersistentKeepalive = 120\n"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			peer := tt.peer.WithProfile(tt.profile)
+			if tt.defaults != nil {
+				peer = peer.WithDefaults(*tt.defaults)
+			}
+			config := GenerateConfig(peer, []*domain.Peer{jump}, network, nil, nil)
+			for _, want := range tt.expected {
+				if !strings.Contains(config, want) {
+					t.Errorf("expected %q in config:\n%s", want, config)
+				}
+			}
+			for _, unwanted := range tt.unexpected {
+				if strings.Contains(config, unwanted) {
+					t.Errorf("unexpected %q in config:\n%s", unwanted, config)
+				}
+			}
+		})
+	}
+
+	// Resolving must not modify the stored peer.
+	peer := tests[0].peer
+	if peer.WithProfile(mobile).WithDefaults(*defaults); peer.MTU != 0 || peer.PersistentKeepalive != nil {
+		t.Errorf("resolving modified the peer: MTU = %d, keepalive = %v", peer.MTU, peer.PersistentKeepalive)
+	}
+
+	// An empty exclusion list on the peer beats its profile's; nil inherits it.
+	lan := &domain.PeerProfile{ID: "lan", SplitTunnelExclusions: []string{"192.168.0.0/16"}}
+	if got := (&domain.Peer{}).WithProfile(lan).SplitTunnelExclusions; len(got) != 1 {
+		t.Errorf("peer without exclusions: got %v, want the profile's", got)
+	}
+	if got := (&domain.Peer{SplitTunnelExclusions: []string{}}).WithProfile(lan).SplitTunnelExclusions; len(got) != 0 {
+		t.Errorf("peer excluding nothing: got %v, want none", got)
+	}
+}
+
+func TestGenerateConfig_PeerDNS(t *testing.T) {
+	network := &domain.Network{CIDR: "10.0.0.0/16", DNS: []string{"1.1.1.1"}}
+	jump := &domain.Peer{ID: "jump1", Name: "jump", PublicKey: "jump-pub", Address: "10.0.0.1", Endpoint: "vpn.example.com", ListenPort: 51820, IsJump: true}
+
+	tests := []struct {
+		name string
+		dns  []string
+		want string // DNS line, empty when there must be none
+	}{
+		{name: "inherits the jump resolver", want: "DNS = 10.0.0.1\n"},
+		{name: "overrides with its own resolvers", dns: []string{"192.168.1.1"}, want: "DNS = 192.168.1.1\n"},
+		{name: "suppresses DNS", dns: []string{domain.DNSNone}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			peer := &domain.Peer{ID: "laptop", Name: "laptop", Address: "10.0.0.5", DNS: tt.dns}
+			config := GenerateConfig(peer, []*domain.Peer{jump}, network, nil, nil)
+			if tt.want == "" {
+				if strings.Contains(config, "DNS =") {
+					t.Errorf("expected no DNS line:\n%s", config)
+				}
+				return
+			}
+			if !strings.Contains(config, tt.want) {
+				t.Errorf("expected %q in config:\n%s", tt.want, config)
+			}
+		})
+	}
+}
+
+func TestGenerateConfig_InterfaceAddresses(t *testing.T) {
+	network := &domain.Network{CIDR: "10.0.0.0/16", CIDRv6: "fd00::/64"}
+
+	tests := []struct {
+		name string
+		peer *domain.Peer
+		want string
+	}{
+		{
+			name: "ipv4 only",
+			peer: &domain.Peer{Name: "v4", Address: "10.0.0.2"},
+			want: "Address = 10.0.0.2",
+		},
+		{
+			name: "dual-stack",
+			peer: &domain.Peer{Name: "dual", Address: "10.0.0.2", AddressV6: "fd00::2"},
+			want: "Address = 10.0.0.2, fd00::2",
+		},
+		{
+			name: "ipv6 only",
+			peer: &domain.Peer{Name: "v6", AddressV6: "fd00::2"},
+			want: "Address = fd00::2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := GenerateConfig(tt.peer, nil, network, nil, nil)
+			var got []string
+			for _, line := range strings.Split(config, "\n") {
+				if strings.HasPrefix(line, "Address = ") {
+					got = append(got, line)
+				}
+			}
+			if len(got) != 1 || got[0] != tt.want {
+				t.Errorf("Address lines = %q, want [%q]", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateConfigWithoutPrivateKey(t *testing.T) {
+	network := &domain.Network{CIDR: "10.0.0.0/16"}
+	peer := &domain.Peer{Name: "laptop", Address: "10.0.0.2", PrivateKey: "secret-key"}
+	jump := &domain.Peer{ID: "jump", Name: "jump", PublicKey: "jump-pub", Address: "10.0.0.1", IsJump: true, Endpoint: "vpn.example.com", ListenPort: 51820}
+
+	config := GenerateConfigWithoutPrivateKey(peer, []*domain.Peer{jump}, network, nil, nil)
+	if strings.Contains(config, "secret-key") {
+		t.Errorf("config contains the private key:\n%s", config)
+	}
+	if !strings.Contains(config, "[Interface]\n# Name: laptop\n"+PrivateKeyPlaceholder+"\n") {
+		t.Errorf("config lacks the private key placeholder:\n%s", config)
+	}
+	// Everything else matches the full config
+	full := GenerateConfig(peer, []*domain.Peer{jump}, network, nil, nil)
+	if got := strings.Replace(full, "PrivateKey = secret-key", PrivateKeyPlaceholder, 1); got != config {
+		t.Errorf("configs differ beyond the private key:\nfull:\n%s\nwithout key:\n%s", full, config)
+	}
+}
+
+func TestGenerateConfigWithOptions_Metadata(t *testing.T) {
+	network := &domain.Network{CIDR: "10.0.0.0/16"}
+	peer := &domain.Peer{ID: "laptop-id", Name: "laptop", Address: "10.0.0.2", PrivateKey: "secret-key"}
+	jump := &domain.Peer{ID: "jump-id", Name: "jump", PublicKey: "jump-pub", Address: "10.0.0.1", IsJump: true, Endpoint: "vpn.example.com", ListenPort: 51820}
+	metadata := map[string]PeerMetadata{
+		"laptop-id": {Groups: []string{"staff", "laptops"}, Owner: "alice@example.com"},
+	}
+
+	config := GenerateConfigWithOptions(peer, []*domain.Peer{jump}, network, nil, nil, ConfigOptions{Metadata: metadata})
+	for _, want := range []string{
+		"[Interface]\n# Name: laptop\n# ID: laptop-id\n# Groups: staff, laptops\n# Owner: alice@example.com\nPrivateKey = secret-key\n",
+		"[Peer]\n# Name: jump\n# ID: jump-id\nPublicKey = jump-pub\n",
+	} {
+		if !strings.Contains(config, want) {
+			t.Errorf("config missing %q:\n%s", want, config)
+		}
+	}
+
+	// Without metadata, the config is the plain one
+	if got, want := GenerateConfigWithOptions(peer, []*domain.Peer{jump}, network, nil, nil, ConfigOptions{}), GenerateConfig(peer, []*domain.Peer{jump}, network, nil, nil); got != want {
+		t.Errorf("config without metadata differs from GenerateConfig:\n%s\nwant:\n%s", got, want)
+	}
+	if strings.Contains(GenerateConfig(peer, []*domain.Peer{jump}, network, nil, nil), "# ID:") {
+		t.Error("GenerateConfig writes metadata comments")
+	}
+
+	// A value cannot break out of its comment line
+	metadata["laptop-id"] = PeerMetadata{Owner: "mallory\nPostUp = reboot"}
+	config = GenerateConfigWithOptions(peer, []*domain.Peer{jump}, network, nil, nil, ConfigOptions{Metadata: metadata})
+	if !strings.Contains(config, "# Owner: mallory PostUp = reboot\n") || strings.Contains(config, "\nPostUp") {
+		t.Errorf("owner comment spans several lines:\n%s", config)
+	}
+}
+
 func TestAllocateIP(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -527,3 +997,68 @@ func TestIsNetworkOrBroadcast(t *testing.T) {
 		})
 	}
 }
+
+func TestGenerateConfig_RoutingTable(t *testing.T) {
+	network := &domain.Network{CIDR: "10.0.0.0/24"}
+	jump := &domain.Peer{ID: "jump1", Name: "jump", PublicKey: "jump-pub", Address: "10.0.0.1", IsJump: true, Endpoint: "vpn.example.com", ListenPort: 51820}
+
+	// Unset: no Table line, wg-quick installs the AllowedIPs routes itself
+	config := GenerateConfig(&domain.Peer{ID: "laptop", Name: "laptop", Address: "10.0.0.2"}, []*domain.Peer{jump}, network, nil, nil)
+	if strings.Contains(config, "Table") {
+		t.Errorf("unexpected Table line:\n%s", config)
+	}
+
+	// off: the peer installs its own routes, so wg-quick must not
+	router := &domain.Peer{ID: "router", Name: "router", Address: "10.0.0.3", RoutingTable: domain.RoutingTableOff}
+	config = GenerateConfig(router, []*domain.Peer{jump}, network, nil, nil)
+	iface := config[:strings.Index(config, "[Peer]")]
+	if !strings.Contains(iface, "Table = off\n") {
+		t.Errorf("expected Table = off in [Interface]:\n%s", config)
+	}
+	if !strings.Contains(config, "AllowedIPs = 10.0.0.1/32\n") {
+		t.Errorf("Table = off must not change AllowedIPs:\n%s", config)
+	}
+
+	router.RoutingTable = "1234"
+	if config := GenerateConfig(router, []*domain.Peer{jump}, network, nil, nil); !strings.Contains(config, "Table = 1234\n") {
+		t.Errorf("expected Table = 1234:\n%s", config)
+	}
+}
+
+func TestGenerateConfig_FwMark(t *testing.T) {
+	network := &domain.Network{CIDR: "10.0.0.0/24"}
+	jump := &domain.Peer{ID: "jump1", Name: "jump", PublicKey: "jump-pub", Address: "10.0.0.1", IsJump: true, Endpoint: "vpn.example.com", ListenPort: 51820}
+	peer := &domain.Peer{ID: "laptop", Name: "laptop", Address: "10.0.0.2"}
+
+	unset := GenerateConfig(peer, []*domain.Peer{jump}, network, nil, nil)
+	if strings.Contains(unset, "FwMark") {
+		t.Errorf("unexpected FwMark line:\n%s", unset)
+	}
+
+	peer.FwMark = "0xca6c"
+	config := GenerateConfig(peer, []*domain.Peer{jump}, network, nil, nil)
+	iface := config[:strings.Index(config, "[Peer]")]
+	if !strings.Contains(iface, "FwMark = 0xca6c\n") {
+		t.Errorf("expected FwMark = 0xca6c in [Interface]:\n%s", config)
+	}
+	if strings.Replace(config, "FwMark = 0xca6c\n", "", 1) != unset {
+		t.Errorf("FwMark must only add its own line:\n%s", config)
+	}
+}
+
+func TestGenerateConfig_ImportedKeyOmitsPrivateKey(t *testing.T) {
+	network := &domain.Network{CIDR: "10.0.0.0/24"}
+	jump := &domain.Peer{ID: "jump1", Name: "jump", PublicKey: "jump-pub", Address: "10.0.0.1", IsJump: true, Endpoint: "vpn.example.com", ListenPort: 51820}
+	peer := &domain.Peer{ID: "laptop", Name: "laptop", Address: "10.0.0.2", PublicKey: "laptop-pub"}
+
+	config := GenerateConfig(peer, []*domain.Peer{jump}, network, nil, nil)
+	if strings.Contains(config, "PrivateKey") {
+		t.Errorf("expected no PrivateKey line for a peer without a stored private key:\n%s", config)
+	}
+
+	peer.PrivateKey = "laptop-priv"
+	config = GenerateConfig(peer, []*domain.Peer{jump}, network, nil, nil)
+	if !strings.Contains(config, "[Interface]\n# Name: laptop\nPrivateKey = laptop-priv\n") {
+		t.Errorf("expected the PrivateKey line:\n%s", config)
+	}
+}
@@ -0,0 +1,131 @@
+package wireguard
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// PeerDelta lists the [Peer] sections that differ between two configs of the
+// same peer.  Agents apply it with `wg set` instead of re-syncing the whole
+// interface, so sessions with unrelated peers are not disturbed.
+type PeerDelta struct {
+	// Base is the ConfigHash of the config the delta applies to.  An agent
+	// whose last applied config hashes differently must apply the full
+	// config instead.
+	Base    string   `json:"base"`
+	Added   []string `json:"added,omitempty"`   // new [Peer] sections
+	Changed []string `json:"changed,omitempty"` // [Peer] sections whose settings changed
+	Removed []string `json:"removed,omitempty"` // public keys of removed peers
+}
+
+// Empty reports whether the delta changes no peer.
+func (d *PeerDelta) Empty() bool {
+	return len(d.Added) == 0 && len(d.Changed) == 0 && len(d.Removed) == 0
+}
+
+// ConfigHash identifies a config in a PeerDelta.
+func ConfigHash(cfg string) string {
+	sum := sha256.Sum256([]byte(cfg))
+	return hex.EncodeToString(sum[:])
+}
+
+// DiffConfigs computes the delta that turns the previous config into next.
+// It returns false when no delta can describe the change: there is no
+// previous config, the [Interface] section changed, or a [Peer] section has
+// no (or a duplicate) public key.  Callers then send the full config.
+func DiffConfigs(previous, next string) (*PeerDelta, bool) {
+	if previous == "" {
+		return nil, false
+	}
+	prevIface, prevPeers, ok := splitConfig(previous)
+	if !ok {
+		return nil, false
+	}
+	nextIface, nextPeers, ok := splitConfig(next)
+	if !ok || prevIface != nextIface {
+		return nil, false
+	}
+
+	delta := &PeerDelta{Base: ConfigHash(previous)}
+	prevByKey := make(map[string]string, len(prevPeers))
+	for _, p := range prevPeers {
+		prevByKey[p.publicKey] = p.section
+	}
+	nextKeys := make(map[string]bool, len(nextPeers))
+	for _, p := range nextPeers {
+		nextKeys[p.publicKey] = true
+		old, existed := prevByKey[p.publicKey]
+		switch {
+		case !existed:
+			delta.Added = append(delta.Added, p.section)
+		case old != p.section:
+			delta.Changed = append(delta.Changed, p.section)
+		}
+	}
+	for _, p := range prevPeers {
+		if !nextKeys[p.publicKey] {
+			delta.Removed = append(delta.Removed, p.publicKey)
+		}
+	}
+	return delta, true
+}
+
+type peerSection struct {
+	publicKey string
+	section   string
+}
+
+// splitConfig splits a config into its [Interface] section and its [Peer]
+// sections.  Sections are compared as text, with surrounding blank lines
+// trimmed.
+func splitConfig(cfg string) (string, []peerSection, bool) {
+	var iface string
+	var peers []peerSection
+	var current []string
+	inPeer := false
+	flush := func() bool {
+		section := strings.TrimSpace(strings.Join(current, "\n"))
+		current = nil
+		if !inPeer {
+			iface = section
+			return true
+		}
+		key := sectionPublicKey(section)
+		if key == "" {
+			return false
+		}
+		for _, p := range peers {
+			if p.publicKey == key {
+				return false
+			}
+		}
+		peers = append(peers, peerSection{publicKey: key, section: section})
+		return true
+	}
+
+	for _, line := range strings.Split(cfg, "\n") {
+		if strings.TrimSpace(line) == "[Peer]" {
+			if !flush() {
+				return "", nil, false
+			}
+			inPeer = true
+		}
+		current = append(current, line)
+	}
+	if !flush() {
+		return "", nil, false
+	}
+	return iface, peers, true
+}
+
+// sectionPublicKey returns the PublicKey of a [Peer] section.
+func sectionPublicKey(section string) string {
+	for _, line := range strings.Split(section, "\n") {
+		key, value, found := strings.Cut(line, "=")
+		if found && strings.TrimSpace(key) == "PublicKey" {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,55 @@
+package wireguard
+
+import (
+	"reflect"
+	"testing"
+)
+
+const deltaIface = "[Interface]\n# Name: laptop\nPrivateKey = priv\nAddress = 10.0.0.2/24\n\n"
+
+func deltaPeer(name, key, allowedIPs string) string {
+	return "[Peer]\n# Name: " + name + "\nPublicKey = " + key + "\nAllowedIPs = " + allowedIPs + "\n\n"
+}
+
+func TestDiffConfigs(t *testing.T) {
+	jump := deltaPeer("jump", "pk-jump=", "10.0.0.0/24")
+	db := deltaPeer("db", "pk-db=", "10.0.0.3/32")
+	previous := deltaIface + jump + db
+
+	next := deltaIface + deltaPeer("jump", "pk-jump=", "10.0.0.0/24, 192.168.1.0/24") + deltaPeer("web", "pk-web=", "10.0.0.4/32")
+	delta, ok := DiffConfigs(previous, next)
+	if !ok {
+		t.Fatal("expected a delta")
+	}
+	if delta.Base != ConfigHash(previous) {
+		t.Errorf("Base = %q, want the hash of the previous config", delta.Base)
+	}
+	want := &PeerDelta{
+		Base:    delta.Base,
+		Added:   []string{"[Peer]\n# Name: web\nPublicKey = pk-web=\nAllowedIPs = 10.0.0.4/32"},
+		Changed: []string{"[Peer]\n# Name: jump\nPublicKey = pk-jump=\nAllowedIPs = 10.0.0.0/24, 192.168.1.0/24"},
+		Removed: []string{"pk-db="},
+	}
+	if !reflect.DeepEqual(delta, want) {
+		t.Errorf("delta = %+v, want %+v", delta, want)
+	}
+
+	if delta, ok := DiffConfigs(previous, previous); !ok || !delta.Empty() {
+		t.Errorf("identical configs: delta %+v, ok %v; want an empty delta", delta, ok)
+	}
+}
+
+func TestDiffConfigs_FallsBackToFullConfig(t *testing.T) {
+	jump := deltaPeer("jump", "pk-jump=", "10.0.0.0/24")
+	tests := map[string]struct{ previous, next string }{
+		"nothing sent yet":  {"", deltaIface + jump},
+		"interface changed": {deltaIface + jump, "[Interface]\nPrivateKey = priv\nAddress = 10.0.0.9/24\n\n" + jump},
+		"peer without key":  {deltaIface + jump, deltaIface + "[Peer]\nAllowedIPs = 10.0.0.5/32\n"},
+		"duplicate key":     {deltaIface + jump, deltaIface + jump + jump},
+	}
+	for name, tc := range tests {
+		if delta, ok := DiffConfigs(tc.previous, tc.next); ok {
+			t.Errorf("%s: got delta %+v, want a full config", name, delta)
+		}
+	}
+}
